@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/metalmatze/alertmanager-bot/pkg/admin"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ctl are the flags and commands of alertmanager-bot ctl managing a running bot via its admin HTTP API.
+var ctl struct {
+	URL     *url.URL      `name:"url" env:"ALERTMANAGER_BOT_URL" default:"http://localhost:8080" help:"The URL of the bot's --listen.addr"`
+	Token   string        `name:"admin.token" env:"ADMIN_TOKEN" help:"The bot's --admin.token"`
+	Tenant  string        `name:"tenant" default:"telegram" help:"The tenant of the bot to manage"`
+	Timeout time.Duration `name:"timeout" default:"30s" help:"How long to wait for the bot to respond"`
+
+	ListChats ctlListChats `cmd:"" name:"list-chats" help:"List the subscribed chats"`
+	AddChat   ctlAddChat   `cmd:"" name:"add-chat" help:"Subscribe a chat"`
+	PurgeChat ctlPurgeChat `cmd:"" name:"purge-chat" help:"Remove everything stored about a chat and its user"`
+	Broadcast ctlBroadcast `cmd:"" name:"broadcast" help:"Send a message to the chats"`
+	Reload    ctlReload    `cmd:"" name:"reload" help:"Read the template files again and apply them if they changed"`
+}
+
+type ctlListChats struct{}
+
+func (c *ctlListChats) Run(client *admin.Client) error {
+	ctx, cancel := ctlContext()
+	defer cancel()
+
+	chats, err := client.ListChats(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tNAME")
+	for _, chat := range chats {
+		name := chat.Title
+		if name == "" {
+			name = "@" + chat.Username
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", chat.ID, chat.Type, name)
+	}
+	return w.Flush()
+}
+
+type ctlAddChat struct {
+	ID    int64  `arg:"" help:"The chat's ID, negative for groups"`
+	Type  string `name:"type" enum:",private,group,supergroup,channel" help:"The chat's type, private or group by the sign of the ID if not set"`
+	Title string `name:"title" help:"The title of a group chat"`
+}
+
+func (c *ctlAddChat) Run(client *admin.Client) error {
+	ctx, cancel := ctlContext()
+	defer cancel()
+
+	chat, err := client.AddChat(ctx, &telebot.Chat{ID: c.ID, Type: telebot.ChatType(c.Type), Title: c.Title})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Subscribed the %s chat %d.\n", chat.Type, chat.ID)
+	return nil
+}
+
+type ctlPurgeChat struct {
+	ID  int64 `arg:"" help:"The chat's ID, negative for groups"`
+	Yes bool  `name:"yes" short:"y" help:"Don't ask for confirmation"`
+}
+
+func (c *ctlPurgeChat) Run(client *admin.Client) error {
+	if !c.Yes {
+		fmt.Printf("Remove everything stored about chat %d? This can't be undone. [y/N] ", c.ID)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	ctx, cancel := ctlContext()
+	defer cancel()
+
+	result, err := client.PurgeChat(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed chat %d: subscribed %t, %d alerts, %d history entries, %d incidents, %d deliveries, %d groups, %d webhooks, %d acknowledgements.\n",
+		result.ChatID, result.Subscribed, result.Alerts, result.History, result.Incidents, result.Deliveries, result.Groups, result.Webhooks, result.Acks,
+	)
+	return nil
+}
+
+type ctlBroadcast struct {
+	Text   string  `arg:"" help:"The message"`
+	Chats  []int64 `name:"chat" help:"The chats to send the message to, all subscribed chats if neither chats nor a group are set"`
+	Group  string  `name:"group" help:"The chat group to send the message to"`
+	Silent bool    `name:"silent" help:"Send the message without a notification sound"`
+}
+
+func (c *ctlBroadcast) Run(client *admin.Client) error {
+	ctx, cancel := ctlContext()
+	defer cancel()
+
+	result, err := client.Broadcast(ctx, telegram.Broadcast{Text: c.Text, Chats: c.Chats, Group: c.Group, Silent: c.Silent})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Sent the message to %d chats.\n", result.Sent)
+	if len(result.Failed) == 0 {
+		return nil
+	}
+	failed := make([]string, 0, len(result.Failed))
+	for _, id := range result.Failed {
+		failed = append(failed, fmt.Sprint(id))
+	}
+	// A failed broadcast fails the command, so that scripts and CI notice.
+	return fmt.Errorf("failed to send the message to %d chats, %d of them rate limited: %s", len(failed), result.RateLimited, strings.Join(failed, ", "))
+}
+
+type ctlReload struct{}
+
+func (c *ctlReload) Run(client *admin.Client) error {
+	ctx, cancel := ctlContext()
+	defer cancel()
+
+	result, err := client.Reload(ctx)
+	if err != nil {
+		return err
+	}
+	if !result.Changed {
+		fmt.Printf("The configuration didn't change, it's still v%d.\n", result.Version)
+		return nil
+	}
+	fmt.Printf("Reloaded the configuration, it's now v%d.\n", result.Version)
+	return nil
+}
+
+// runCtl parses the arguments following ctl and runs the command against the bot.
+func runCtl(args []string) {
+	parser := kong.Must(&ctl,
+		kong.Name("alertmanager-bot ctl"),
+		kong.Description("Manage a running bot via its admin HTTP API."),
+		kong.UsageOnError(),
+	)
+	kctx, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+
+	client, err := admin.NewClient(ctl.URL, ctl.Token, ctl.Tenant)
+	parser.FatalIfErrorf(err)
+
+	parser.FatalIfErrorf(kctx.Run(client))
+}
+
+// ctlContext returns the context of a command's requests to the bot.
+func ctlContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), ctl.Timeout)
+}
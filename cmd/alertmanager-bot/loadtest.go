@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/metalmatze/alertmanager-bot/pkg/admin"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// loadtest are the flags of alertmanager-bot loadtest sending synthetic webhooks to a running bot.
+var loadtest struct {
+	URL    *url.URL `name:"url" env:"ALERTMANAGER_BOT_URL" default:"http://localhost:8080" help:"The URL of the bot's --listen.addr"`
+	Token  string   `name:"admin.token" env:"ADMIN_TOKEN" help:"The bot's --admin.token to read the deliveries of the webhooks"`
+	Tenant string   `name:"tenant" default:"telegram" help:"The tenant of the bot to send the webhooks to"`
+	Target string   `arg:"" help:"The ID of a subscribed chat or the name of a chat group to send the webhooks to"`
+
+	Rate        float64       `name:"rate" default:"10" help:"The number of webhooks sent per second"`
+	Duration    time.Duration `name:"duration" default:"1m" help:"How long to send webhooks"`
+	Alerts      int           `name:"alerts" default:"5" help:"The number of alerts of every webhook"`
+	Cardinality int           `name:"cardinality" default:"1000" help:"The number of different alerts the alerts of the webhooks are picked from"`
+	Timeout     time.Duration `name:"timeout" default:"1m" help:"How long to wait for the bot to deliver the webhooks after the last one was sent"`
+}
+
+// runLoadtest parses the arguments following loadtest, sends the webhooks and reports the latency of their deliveries.
+func runLoadtest(args []string) {
+	parser := kong.Must(&loadtest,
+		kong.Name("alertmanager-bot loadtest"),
+		kong.Description("Send synthetic webhooks to a bot running with --telegram.dry-run and report how long it takes to deliver them."),
+		kong.UsageOnError(),
+	)
+	_, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+	if loadtest.Rate <= 0 || loadtest.Alerts <= 0 || loadtest.Cardinality <= 0 {
+		parser.Fatalf("the rate, alerts and cardinality must be positive")
+	}
+
+	client, err := admin.NewClient(loadtest.URL, loadtest.Token, loadtest.Tenant)
+	parser.FatalIfErrorf(err)
+
+	// The group keys of the webhooks contain the run, so that its deliveries can be told apart from others.
+	run := strconv.FormatInt(time.Now().UnixNano(), 36)
+	sent, failed := sendLoad(client, run)
+	fmt.Printf("Sent %d webhooks with %d alerts each in %s, %d failed.\n", len(sent), loadtest.Alerts, loadtest.Duration, failed)
+	if len(sent) == 0 {
+		os.Exit(1)
+	}
+
+	deliveries, err := waitDeliveries(client, run, sent)
+	parser.FatalIfErrorf(err)
+
+	statuses := map[telegram.DeliveryStatus]int{}
+	delivered := map[string]bool{}
+	var latencies []time.Duration
+	for _, d := range deliveries {
+		start, ok := sent[d.GroupKey]
+		if !ok {
+			continue
+		}
+		delivered[d.GroupKey] = true
+		statuses[d.Status]++
+		if !d.SentAt.IsZero() {
+			latencies = append(latencies, d.SentAt.Sub(start))
+		}
+	}
+	missing := len(sent) - len(delivered)
+
+	fmt.Printf("Deliveries: %d sent, %d retried, %d failed, %d buffered, %d filtered, %d webhooks not delivered.\n",
+		statuses[telegram.DeliverySent], statuses[telegram.DeliveryRetried], statuses[telegram.DeliveryFailed],
+		statuses[telegram.DeliveryBuffered], statuses[telegram.DeliveryFiltered], missing,
+	)
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Printf("Latency: p50 %s, p90 %s, p99 %s, max %s.\n",
+			percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99), latencies[len(latencies)-1],
+		)
+	}
+	if missing > 0 || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// sendLoad sends webhooks at the rate for the duration and returns when each webhook the bot accepted was sent by its group key,
+// and how many webhooks the bot didn't accept.
+func sendLoad(client *admin.Client, run string) (map[string]time.Time, int) {
+	var (
+		mu     sync.Mutex
+		sent   = map[string]time.Time{}
+		failed int
+		wg     sync.WaitGroup
+	)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / loadtest.Rate))
+	defer ticker.Stop()
+	end := time.After(loadtest.Duration)
+
+	for seq := 0; ; seq++ {
+		select {
+		case <-end:
+			wg.Wait()
+			return sent, failed
+		case <-ticker.C:
+		}
+
+		m := loadtestWebhook(run, seq, time.Now())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), loadtest.Timeout)
+			defer cancel()
+			start := time.Now()
+			err := client.SendWebhook(ctx, loadtest.Target, m)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to send webhook: %v\n", err)
+				failed++
+				return
+			}
+			sent[m.GroupKey] = start
+		}()
+	}
+}
+
+// loadtestWebhook returns the seq-th webhook of the run with its alerts picked from the cardinality in turn.
+func loadtestWebhook(run string, seq int, now time.Time) webhook.Message {
+	alerts := make(template.Alerts, 0, loadtest.Alerts)
+	for i := 0; i < loadtest.Alerts; i++ {
+		instance := fmt.Sprintf("instance-%d", (seq*loadtest.Alerts+i)%loadtest.Cardinality)
+		alerts = append(alerts, template.Alert{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "LoadTest", "instance": instance, "severity": "warning"},
+			Annotations: template.KV{"message": "A synthetic alert of alertmanager-bot loadtest"},
+			StartsAt:    now,
+		})
+	}
+
+	return webhook.Message{
+		Data: &template.Data{
+			Receiver:          "loadtest",
+			Status:            "firing",
+			Alerts:            alerts,
+			GroupLabels:       template.KV{"alertname": "LoadTest"},
+			CommonLabels:      template.KV{"alertname": "LoadTest", "severity": "warning"},
+			CommonAnnotations: template.KV{"message": "A synthetic alert of alertmanager-bot loadtest"},
+		},
+		Version:  "4",
+		GroupKey: fmt.Sprintf(`{}:{loadtest=%q,seq="%d"}`, run, seq),
+	}
+}
+
+// waitDeliveries waits until every sent webhook of the run was delivered or the timeout passed and returns the run's deliveries.
+func waitDeliveries(client *admin.Client, run string, sent map[string]time.Time) ([]*telegram.Delivery, error) {
+	deadline := time.Now().Add(loadtest.Timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), loadtest.Timeout)
+		deliveries, err := client.Deliveries(ctx, run)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		delivered := map[string]bool{}
+		for _, d := range deliveries {
+			delivered[d.GroupKey] = true
+		}
+		if len(delivered) >= len(sent) || time.Now().After(deadline) {
+			return deliveries, nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
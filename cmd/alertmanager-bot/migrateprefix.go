@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/docker/libkv/store"
+	"github.com/metalmatze/alertmanager-bot/pkg/backup"
+	"github.com/metalmatze/alertmanager-bot/pkg/namespace"
+)
+
+// migratePrefix are the flags of alertmanager-bot migrate-prefix moving the keys of a bot below another --store.prefix.
+var migratePrefix struct {
+	To     string   `arg:"" help:"The new --store.prefix of the bot"`
+	From   string   `name:"from" help:"The --store.prefix the keys are below now, none if not set"`
+	Keys   []string `name:"keys" default:"telegram" help:"The prefixes of the keys to move, the store prefixes of all tenants of the bot"`
+	Delete bool     `name:"delete" default:"false" help:"Delete the keys below the old prefix once they are copied"`
+
+	Store string `required:"true" name:"store" enum:"bolt,consul,etcd" help:"The store of the bot"`
+	cliBolt
+	cliConsul
+	cliEtcd
+}
+
+// runMigratePrefix parses the arguments following migrate-prefix and copies the keys to the new prefix.
+// The bot should be stopped while migrating and started with the new --store.prefix afterwards.
+func runMigratePrefix(args []string) {
+	parser := kong.Must(&migratePrefix,
+		kong.Name("alertmanager-bot migrate-prefix"),
+		kong.Description("Move the keys of the bot below another --store.prefix, while the bot isn't running."),
+		kong.UsageOnError(),
+	)
+	_, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+	if migratePrefix.To == migratePrefix.From {
+		parser.Fatalf("the new prefix has to be different from --from")
+	}
+
+	kvStore, err := newStore(migratePrefix.Store, migratePrefix.cliBolt, migratePrefix.cliConsul, migratePrefix.cliEtcd)
+	parser.FatalIfErrorf(err)
+	defer kvStore.Close()
+
+	from, err := prefixedStore(kvStore, migratePrefix.From)
+	parser.FatalIfErrorf(err)
+	to, err := prefixedStore(kvStore, migratePrefix.To)
+	parser.FatalIfErrorf(err)
+
+	// The values are copied as they are, encrypted values authenticate the key without the prefix.
+	s, err := backup.Take(from, migratePrefix.Keys, time.Now())
+	parser.FatalIfErrorf(err)
+	parser.FatalIfErrorf(s.Restore(to))
+
+	if migratePrefix.Delete {
+		for _, prefix := range migratePrefix.Keys {
+			if err := from.DeleteTree(prefix); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				parser.Fatalf("failed to delete %s: %v", prefix, err)
+			}
+		}
+	}
+
+	fmt.Printf("Copied %d keys from %q to %q.\n", len(s.Pairs), migratePrefix.From, migratePrefix.To)
+}
+
+// prefixedStore returns the store with its keys below the prefix, the store itself if the prefix is empty.
+func prefixedStore(kv store.Store, prefix string) (store.Store, error) {
+	if prefix == "" {
+		return kv, nil
+	}
+	return namespace.NewStore(kv, prefix)
+}
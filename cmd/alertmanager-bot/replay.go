@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+)
+
+// replayRecording replays the recording against the bots and prints how the messages they sent differ from the recorded ones.
+// It returns the exit code, 1 if the messages differ.
+func replayRecording(ctx context.Context, logger log.Logger, path string, bots map[string]*telegram.Bot, replayers map[string]*telegram.ReplayTelegram) int {
+	f, err := os.Open(path)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to open recording", "err", err)
+		return 2
+	}
+	events, err := telegram.ReadRecording(f)
+	f.Close()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read recording", "err", err)
+		return 2
+	}
+
+	recorded := map[string][]telegram.SentMessage{}
+	for _, e := range events {
+		if _, ok := recorded[e.Tenant]; !ok {
+			recorded[e.Tenant] = []telegram.SentMessage{}
+		}
+		if e.Sent != nil {
+			recorded[e.Tenant] = append(recorded[e.Tenant], *e.Sent)
+		}
+	}
+	var tenants []string
+	for tenant := range recorded {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	code := 0
+	for _, tenant := range tenants {
+		tlogger := log.With(logger, "tenant", tenant)
+		bot, ok := bots[tenant]
+		if !ok {
+			level.Error(tlogger).Log("msg", "recorded tenant isn't configured")
+			code = 2
+			continue
+		}
+
+		replayed, err := bot.ReplayRecording(ctx, replayers[tenant], tenant, events)
+		if err != nil {
+			level.Error(tlogger).Log("msg", "failed to replay recording", "err", err)
+			code = 2
+			continue
+		}
+
+		diff := telegram.DiffSent(recorded[tenant], replayed)
+		if len(diff) == 0 {
+			level.Info(tlogger).Log("msg", "replayed messages match the recording", "messages", len(replayed))
+			continue
+		}
+		level.Warn(tlogger).Log("msg", "replayed messages differ from the recording", "recorded", len(recorded[tenant]), "replayed", len(replayed))
+		fmt.Printf("--- %s recorded\n+++ %s replayed\n%s\n", tenant, tenant, strings.Join(diff, "\n"))
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/metalmatze/alertmanager-bot/pkg/backup"
+)
+
+// restore are the flags of alertmanager-bot restore writing a backup of --backup.url into the store.
+var restore struct {
+	Snapshot string        `arg:"" optional:"" help:"The name of the snapshot to restore, the latest one if not set"`
+	Timeout  time.Duration `name:"timeout" default:"1m" help:"How long to wait for the bucket"`
+
+	cliBackup
+
+	Store          string `required:"true" name:"store" enum:"bolt,consul,etcd" help:"The store to restore the backup into"`
+	StoreNamespace string `name:"store.prefix" help:"The --store.prefix of the bot, the keys are restored below it"`
+	cliBolt
+	cliConsul
+	cliEtcd
+}
+
+// runRestore parses the arguments following restore and writes the snapshot into the store.
+// Keys that aren't in the snapshot are kept, the bot should be stopped while restoring.
+func runRestore(args []string) {
+	parser := kong.Must(&restore,
+		kong.Name("alertmanager-bot restore"),
+		kong.Description("Restore the store from a backup of --backup.url, while the bot isn't running."),
+		kong.UsageOnError(),
+	)
+	_, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
+	if restore.cliBackup.URL == nil {
+		parser.Fatalf("--backup.url is required")
+	}
+
+	bucket, err := newBucket(restore.cliBackup)
+	parser.FatalIfErrorf(err)
+	kvStore, err := newStore(restore.Store, restore.cliBolt, restore.cliConsul, restore.cliEtcd)
+	parser.FatalIfErrorf(err)
+	defer kvStore.Close()
+	kvStore, err = prefixedStore(kvStore, restore.StoreNamespace)
+	parser.FatalIfErrorf(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), restore.Timeout)
+	defer cancel()
+	s, err := backup.Download(ctx, bucket, restore.Snapshot)
+	parser.FatalIfErrorf(err)
+	parser.FatalIfErrorf(s.Restore(kvStore))
+
+	fmt.Printf("Restored %d keys of %s taken at %s.\n", len(s.Pairs), s.Name(), s.Time.Format(time.RFC3339))
+}
+
+// newBucket returns the bucket of --backup.url.
+func newBucket(c cliBackup) (*backup.S3Bucket, error) {
+	return backup.NewS3Bucket(c.URL, c.Region, c.AccessKeyID, c.SecretAccessKey)
+}
@@ -0,0 +1,130 @@
+// Package admin is a client of the admin HTTP API of a running bot.
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Client sends requests for a tenant's bot to the admin endpoints, authenticated with the admin token.
+type Client struct {
+	url    *url.URL
+	token  string
+	tenant string
+	http   *http.Client
+}
+
+// NewClient returns a client of the bot listening on the URL.
+func NewClient(url *url.URL, token, tenant string) (*Client, error) {
+	if token == "" {
+		return nil, errors.New("the admin token is missing")
+	}
+	return &Client{url: url, token: token, tenant: tenant, http: http.DefaultClient}, nil
+}
+
+// ListChats returns the subscribed chats.
+func (c *Client) ListChats(ctx context.Context) ([]*telebot.Chat, error) {
+	var chats []*telebot.Chat
+	err := c.do(ctx, http.MethodGet, "/api/v1/chats", nil, nil, &chats)
+	return chats, err
+}
+
+// AddChat subscribes a chat.
+func (c *Client) AddChat(ctx context.Context, chat *telebot.Chat) (*telebot.Chat, error) {
+	var added *telebot.Chat
+	err := c.do(ctx, http.MethodPost, "/api/v1/chats", nil, chat, &added)
+	return added, err
+}
+
+// PurgeChat removes everything the bot stored about a chat.
+func (c *Client) PurgeChat(ctx context.Context, id int64) (telegram.PurgeResult, error) {
+	var result telegram.PurgeResult
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/chats/%d/data", id), nil, nil, &result)
+	return result, err
+}
+
+// Broadcast sends a message to chats.
+func (c *Client) Broadcast(ctx context.Context, bc telegram.Broadcast) (telegram.BroadcastResult, error) {
+	var result telegram.BroadcastResult
+	err := c.do(ctx, http.MethodPost, "/api/v1/broadcast", nil, bc, &result)
+	return result, err
+}
+
+// Reload reads the bot's templates again and applies them if they changed.
+func (c *Client) Reload(ctx context.Context) (telegram.ReloadResult, error) {
+	var result telegram.ReloadResult
+	err := c.do(ctx, http.MethodPost, "/-/reload", nil, nil, &result)
+	return result, err
+}
+
+// Deliveries returns the deliveries of the alert groups whose key contains the query, all deliveries if it's empty.
+func (c *Client) Deliveries(ctx context.Context, groupKey string) ([]*telegram.Delivery, error) {
+	var deliveries []*telegram.Delivery
+	err := c.do(ctx, http.MethodGet, "/-/deliveries", url.Values{"groupKey": {groupKey}}, nil, &deliveries)
+	return deliveries, err
+}
+
+// SendWebhook sends an Alertmanager webhook to a chat by its ID or to a chat group by its name.
+func (c *Client) SendWebhook(ctx context.Context, target string, m webhook.Message) error {
+	return c.do(ctx, http.MethodPost, "/webhooks/"+c.tenant+"/"+target, nil, m, nil)
+}
+
+// do sends the request with the query and in as JSON body and decodes the response into out, if out isn't nil.
+// The error message of the API is returned for responses with an error status.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := *c.url
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("tenant", c.tenant)
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testClient returns a client of a server responding with the handler and a function closing the server.
+func testClient(t *testing.T, handler http.HandlerFunc) (*Client, func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "team-a", r.URL.Query().Get("tenant"))
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u, "secret", "team-a")
+	require.NoError(t, err)
+	return client, s.Close
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(&url.URL{}, "", "telegram")
+	require.EqualError(t, err, "the admin token is missing")
+}
+
+func TestClientListChats(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "/api/v1/chats", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"id":-1234,"type":"group","title":"sre"}]`))
+	})
+	defer closeServer()
+
+	chats, err := client.ListChats(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*telebot.Chat{{ID: -1234, Type: telebot.ChatGroup, Title: "sre"}}, chats)
+}
+
+func TestClientAddChat(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/api/v1/chats", r.URL.Path)
+		var chat telebot.Chat
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&chat))
+		if chat.ID == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"chat id is missing"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(chat)
+	})
+	defer closeServer()
+
+	chat, err := client.AddChat(context.Background(), &telebot.Chat{ID: 123, Type: telebot.ChatPrivate})
+	require.NoError(t, err)
+	require.Equal(t, &telebot.Chat{ID: 123, Type: telebot.ChatPrivate}, chat)
+
+	_, err = client.AddChat(context.Background(), &telebot.Chat{})
+	require.EqualError(t, err, "POST /api/v1/chats: chat id is missing")
+}
+
+func TestClientPurgeChat(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		require.Equal(t, "/api/v1/chats/222/data", r.URL.Path)
+		_, _ = w.Write([]byte(`{"chatID":222,"subscribed":true,"alerts":1,"history":3,"incidents":0,"deliveries":2,"groups":1,"webhooks":0,"acks":1}`))
+	})
+	defer closeServer()
+
+	result, err := client.PurgeChat(context.Background(), 222)
+	require.NoError(t, err)
+	require.Equal(t, telegram.PurgeResult{ChatID: 222, Subscribed: true, Alerts: 1, History: 3, Deliveries: 2, Groups: 1, Acks: 1}, result)
+}
+
+func TestClientBroadcast(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/broadcast", r.URL.Path)
+		var bc telegram.Broadcast
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&bc))
+		require.Equal(t, telegram.Broadcast{Text: "Maintenance at 10:00", Group: "sre", Silent: true}, bc)
+		_, _ = w.Write([]byte(`{"sent":2,"rateLimited":1,"failed":[-1]}`))
+	})
+	defer closeServer()
+
+	result, err := client.Broadcast(context.Background(), telegram.Broadcast{Text: "Maintenance at 10:00", Group: "sre", Silent: true})
+	require.NoError(t, err)
+	require.Equal(t, telegram.BroadcastResult{Sent: 2, RateLimited: 1, Failed: []int64{-1}}, result)
+}
+
+func TestClientReload(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/-/reload", r.URL.Path)
+		_, _ = w.Write([]byte(`{"version":3,"changed":true}`))
+	})
+	defer closeServer()
+
+	result, err := client.Reload(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telegram.ReloadResult{Version: 3, Changed: true}, result)
+}
+
+func TestClientDeliveries(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/-/deliveries", r.URL.Path)
+		require.Equal(t, "loadtest", r.URL.Query().Get("groupKey"))
+		_, _ = w.Write([]byte(`[{"groupKey":"{}:{loadtest=\"1\"}","chatID":-1234,"status":"sent","attempts":1,"alerts":2,"at":"2020-01-01T10:00:00Z","sentAt":"2020-01-01T10:00:01Z"}]`))
+	})
+	defer closeServer()
+
+	deliveries, err := client.Deliveries(context.Background(), "loadtest")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, telegram.DeliverySent, deliveries[0].Status)
+	require.Equal(t, time.Second, deliveries[0].SentAt.Sub(deliveries[0].At))
+}
+
+func TestClientSendWebhook(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/webhooks/team-a/sre", r.URL.Path)
+		var m webhook.Message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		require.Equal(t, "{}:{alertname=\"Fire\"}", m.GroupKey)
+	})
+	defer closeServer()
+
+	require.NoError(t, client.SendWebhook(context.Background(), "sre", webhook.Message{GroupKey: "{}:{alertname=\"Fire\"}"}))
+}
+
+func TestClientUnauthorized(t *testing.T) {
+	client, closeServer := testClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	defer closeServer()
+	client.token = "wrong"
+
+	_, err := client.ListChats(context.Background())
+	require.EqualError(t, err, "GET /api/v1/chats: 401 Unauthorized")
+}
@@ -5,11 +5,36 @@ import (
 	"time"
 
 	"github.com/prometheus/alertmanager/api/v2/client/alert"
+	"github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 )
 
+// Alert is an alert with the silences and alerts suppressing it in Alertmanager.
+type Alert struct {
+	*types.Alert
+	// SilencedBy are the IDs of the silences matching the alert.
+	SilencedBy []string
+	// InhibitedBy are the fingerprints of the alerts inhibiting the alert.
+	InhibitedBy []string
+}
+
 func (c *Client) ListAlerts(ctx context.Context, receiver string, silenced bool) ([]*types.Alert, error) {
+	statuses, err := c.ListAlertStatuses(ctx, receiver, silenced)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*types.Alert, 0, len(statuses))
+	for _, a := range statuses {
+		alerts = append(alerts, a.Alert)
+	}
+	return alerts, nil
+}
+
+// ListAlertStatuses lists the alerts of the receiver with the silences and alerts suppressing them.
+// Inhibited alerts are always listed, silenced alerts only if silenced is true.
+func (c *Client) ListAlertStatuses(ctx context.Context, receiver string, silenced bool) ([]Alert, error) {
 	getAlerts, err := c.alertmanager.Alert.GetAlerts(alert.NewGetAlertsParams().WithContext(ctx).
 		WithReceiver(&receiver).
 		WithSilenced(&silenced),
@@ -18,38 +43,47 @@ func (c *Client) ListAlerts(ctx context.Context, receiver string, silenced bool)
 		return nil, err
 	}
 
-	alerts := make([]*types.Alert, 0, len(getAlerts.Payload))
+	alerts := make([]Alert, 0, len(getAlerts.Payload))
 	for _, a := range getAlerts.Payload {
-		labels := make(model.LabelSet, len(a.Labels))
-		for name, value := range a.Labels {
-			labels[model.LabelName(name)] = model.LabelValue(value)
-		}
-		annotations := make(model.LabelSet, len(a.Annotations))
-		for name, value := range a.Annotations {
-			annotations[model.LabelName(name)] = model.LabelValue(value)
+		status := Alert{Alert: convertAlert(a)}
+		if a.Status != nil {
+			status.SilencedBy = a.Status.SilencedBy
+			status.InhibitedBy = a.Status.InhibitedBy
 		}
+		alerts = append(alerts, status)
+	}
 
-		endsAt := time.Time{}
-		if a.EndsAt != nil {
-			endsAt = time.Time(*a.EndsAt)
-		}
-		updatedAt := time.Time{}
-		if a.UpdatedAt != nil {
-			updatedAt = time.Time(*a.UpdatedAt)
-		}
+	return alerts, nil
+}
 
-		alerts = append(alerts, &types.Alert{
-			Alert: model.Alert{
-				Labels:       labels,
-				Annotations:  annotations,
-				StartsAt:     time.Time(*a.StartsAt),
-				EndsAt:       endsAt,
-				GeneratorURL: a.GeneratorURL.String(),
-			},
-			UpdatedAt: updatedAt,
-			Timeout:   false,
-		})
+func convertAlert(a *models.GettableAlert) *types.Alert {
+	labels := make(model.LabelSet, len(a.Labels))
+	for name, value := range a.Labels {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+	annotations := make(model.LabelSet, len(a.Annotations))
+	for name, value := range a.Annotations {
+		annotations[model.LabelName(name)] = model.LabelValue(value)
 	}
 
-	return alerts, nil
+	endsAt := time.Time{}
+	if a.EndsAt != nil {
+		endsAt = time.Time(*a.EndsAt)
+	}
+	updatedAt := time.Time{}
+	if a.UpdatedAt != nil {
+		updatedAt = time.Time(*a.UpdatedAt)
+	}
+
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels:       labels,
+			Annotations:  annotations,
+			StartsAt:     time.Time(*a.StartsAt),
+			EndsAt:       endsAt,
+			GeneratorURL: a.GeneratorURL.String(),
+		},
+		UpdatedAt: updatedAt,
+		Timeout:   false,
+	}
 }
@@ -0,0 +1,108 @@
+package alertmanager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Allowlist only lets requests from the allowed networks through, e.g. to the webhook endpoints.
+type Allowlist struct {
+	allowed []*net.IPNet
+	// trusted are the proxies whose X-Forwarded-For header is used to find the client's address.
+	trusted []*net.IPNet
+}
+
+// NewAllowlist parses the CIDRs of the allowed networks and the trusted proxies, e.g. 10.0.0.0/8.
+// Single addresses like 10.0.0.1 are allowed as well.
+func NewAllowlist(allowed, trustedProxies []string) (*Allowlist, error) {
+	a := &Allowlist{}
+	var err error
+	if a.allowed, err = parseCIDRs(allowed); err != nil {
+		return nil, err
+	}
+	if a.trusted, err = parseCIDRs(trustedProxies); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address the request was sent from. If it was sent by a trusted proxy,
+// it's the rightmost address of the X-Forwarded-For header that isn't a trusted proxy,
+// as the addresses left of it could have been sent by the client.
+func (a *Allowlist) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	var forwarded []string
+	for _, header := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0 && ip != nil && contains(a.trusted, ip); i-- {
+		ip = net.ParseIP(strings.TrimSpace(forwarded[i]))
+	}
+	return ip
+}
+
+// Allowed returns whether the request was sent from an allowed network.
+func (a *Allowlist) Allowed(r *http.Request) bool {
+	ip := a.clientIP(r)
+	return ip != nil && contains(a.allowed, ip)
+}
+
+// Handler responds with 403 Forbidden to requests that weren't sent from an allowed network.
+func (a *Allowlist) Handler(logger log.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Allowed(r) {
+			level.Warn(logger).Log(
+				"msg", "dropping request from forbidden address",
+				"path", r.URL.Path,
+				"remote_addr", r.RemoteAddr,
+				"forwarded_for", r.Header.Get("X-Forwarded-For"),
+			)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package alertmanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowlist(t *testing.T) {
+	allowlist, err := NewAllowlist([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"}, []string{"172.16.0.0/12"})
+	assert.NoError(t, err)
+
+	h := allowlist.Handler(log.NewNopLogger(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testcases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		status     int
+	}{{
+		name:       "AllowedNetwork",
+		remoteAddr: "10.1.2.3:51234",
+		status:     http.StatusOK,
+	}, {
+		name:       "AllowedAddress",
+		remoteAddr: "192.168.1.10:51234",
+		status:     http.StatusOK,
+	}, {
+		name:       "AllowedIPv6",
+		remoteAddr: "[fd00::1]:51234",
+		status:     http.StatusOK,
+	}, {
+		name:       "Forbidden",
+		remoteAddr: "192.168.1.11:51234",
+		status:     http.StatusForbidden,
+	}, {
+		name:       "ForwardedByUntrustedProxy",
+		remoteAddr: "192.168.1.11:51234",
+		forwarded:  []string{"10.1.2.3"},
+		status:     http.StatusForbidden,
+	}, {
+		name:       "ForwardedByTrustedProxy",
+		remoteAddr: "172.16.0.1:51234",
+		forwarded:  []string{"10.1.2.3"},
+		status:     http.StatusOK,
+	}, {
+		name:       "ForwardedByTrustedProxies",
+		remoteAddr: "172.16.0.1:51234",
+		forwarded:  []string{"10.1.2.3, 172.16.0.2", "172.16.0.3"},
+		status:     http.StatusOK,
+	}, {
+		name:       "SpoofedForwardedFor",
+		remoteAddr: "172.16.0.1:51234",
+		forwarded:  []string{"10.1.2.3, 192.168.1.11"},
+		status:     http.StatusForbidden,
+	}, {
+		name:       "InvalidForwardedFor",
+		remoteAddr: "172.16.0.1:51234",
+		forwarded:  []string{"unknown"},
+		status:     http.StatusForbidden,
+	}, {
+		name:       "TrustedProxyOnly",
+		remoteAddr: "172.16.0.1:51234",
+		status:     http.StatusForbidden,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/telegram/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, f := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", f)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestNewAllowlistInvalid(t *testing.T) {
+	_, err := NewAllowlist([]string{"10.0.0.0/33"}, nil)
+	assert.EqualError(t, err, `invalid CIDR "10.0.0.0/33"`)
+
+	_, err = NewAllowlist([]string{"10.0.0.0/8"}, []string{"proxy"})
+	assert.EqualError(t, err, `invalid address "proxy"`)
+}
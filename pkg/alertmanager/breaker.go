@@ -0,0 +1,183 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/prometheus/alertmanager/api/v2/client/alert"
+	"github.com/prometheus/alertmanager/api/v2/client/silence"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// UnavailableError is returned by a Breaker while it's open, without asking Alertmanager.
+type UnavailableError struct {
+	// LastSuccess is when a request to Alertmanager succeeded the last time, zero if none did.
+	LastSuccess time.Time
+	// Err is the error of the last request.
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("alertmanager is unavailable: %v", e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Breaker retries the requests to an Alertmanager that failed with a network error or a 5xx response with exponential backoff.
+// Once the requests failed threshold times in a row it's open and fails right away with an UnavailableError
+// for the cooldown, instead of waiting for Alertmanager to time out again. After the cooldown the next request tries again.
+// Other errors, like a 400 response to an invalid silence, are returned right away and don't count as failures.
+type Breaker struct {
+	peer      Peer
+	retries   int
+	backoff   time.Duration
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	failures    int
+	openUntil   time.Time
+	lastSuccess time.Time
+	lastErr     error
+}
+
+// NewBreaker retries the failed requests to the peer up to retries times, waiting backoff before the first retry
+// and twice as long before each next one, and opens after threshold failed requests for the cooldown.
+func NewBreaker(peer Peer, retries int, backoff time.Duration, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		peer:      peer,
+		retries:   retries,
+		backoff:   backoff,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow returns an UnavailableError if the breaker is open.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold && time.Now().Before(b.openUntil) {
+		return &UnavailableError{LastSuccess: b.lastSuccess, Err: b.lastErr}
+	}
+	return nil
+}
+
+// unavailable returns whether the error is a network error, including timeouts, or a 5xx response, which a retry might not get.
+func unavailable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var apiErr *runtime.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= 500
+	}
+	var (
+		alertsErr   *alert.GetAlertsInternalServerError
+		silencesErr *silence.GetSilencesInternalServerError
+	)
+	return errors.As(err, &alertsErr) || errors.As(err, &silencesErr)
+}
+
+// done records the result of a request, opening the breaker if too many failed in a row.
+// A request that failed with a response showing Alertmanager is available, e.g. a 400, resets the failures.
+func (b *Breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil || !unavailable(err) {
+		b.failures = 0
+		b.lastSuccess = time.Now()
+		return
+	}
+	b.failures++
+	b.lastErr = err
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// do runs the request, retrying it with backoff while Alertmanager is unavailable
+// until it succeeds, the retries are used up or the context is done.
+func (b *Breaker) do(ctx context.Context, retries int, request func(context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	backoff := b.backoff
+	err := request(ctx)
+	for attempt := 0; unavailable(err) && attempt < retries; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			b.done(err)
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		err = request(ctx)
+	}
+	b.done(err)
+	return err
+}
+
+// ListAlerts lists the alerts of the receiver.
+func (b *Breaker) ListAlerts(ctx context.Context, receiver string, silenced bool) ([]*types.Alert, error) {
+	statuses, err := b.ListAlertStatuses(ctx, receiver, silenced)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*types.Alert, 0, len(statuses))
+	for _, a := range statuses {
+		alerts = append(alerts, a.Alert)
+	}
+	return alerts, nil
+}
+
+func (b *Breaker) ListAlertStatuses(ctx context.Context, receiver string, silenced bool) ([]Alert, error) {
+	var alerts []Alert
+	err := b.do(ctx, b.retries, func(ctx context.Context) (err error) {
+		alerts, err = b.peer.ListAlertStatuses(ctx, receiver, silenced)
+		return err
+	})
+	return alerts, err
+}
+
+func (b *Breaker) ListSilences(ctx context.Context) ([]*types.Silence, error) {
+	var silences []*types.Silence
+	err := b.do(ctx, b.retries, func(ctx context.Context) (err error) {
+		silences, err = b.peer.ListSilences(ctx)
+		return err
+	})
+	return silences, err
+}
+
+func (b *Breaker) Status(ctx context.Context) (*models.AlertmanagerStatus, error) {
+	var status *models.AlertmanagerStatus
+	err := b.do(ctx, b.retries, func(ctx context.Context) (err error) {
+		status, err = b.peer.Status(ctx)
+		return err
+	})
+	return status, err
+}
+
+// CreateSilence creates the silence without retrying, as Alertmanager might have created it before the request failed
+// and a retry would create a second one.
+func (b *Breaker) CreateSilence(ctx context.Context, matchers []*Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error) {
+	var id string
+	err := b.do(ctx, 0, func(ctx context.Context) (err error) {
+		id, err = b.peer.CreateSilence(ctx, matchers, startsAt, endsAt, createdBy, comment)
+		return err
+	})
+	return id, err
+}
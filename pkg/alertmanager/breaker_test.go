@@ -0,0 +1,111 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+)
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+// flakyPeer fails the first failures requests with err, a refused connection if not set.
+type flakyPeer struct {
+	testPeer
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *flakyPeer) fail() error {
+	p.calls++
+	if p.calls > p.failures {
+		return nil
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return errRefused
+}
+
+func (p *flakyPeer) Status(context.Context) (*models.AlertmanagerStatus, error) {
+	if err := p.fail(); err != nil {
+		return nil, err
+	}
+	return &models.AlertmanagerStatus{}, nil
+}
+
+func (p *flakyPeer) CreateSilence(context.Context, []*Matcher, time.Time, time.Time, string, string) (string, error) {
+	if err := p.fail(); err != nil {
+		return "", err
+	}
+	return "silence", nil
+}
+
+func TestBreakerRetries(t *testing.T) {
+	for _, err := range []error{errRefused, context.DeadlineExceeded, runtime.NewAPIError("unknown error", nil, 503)} {
+		peer := &flakyPeer{failures: 2, err: err}
+		b := NewBreaker(peer, 2, time.Millisecond, 3, time.Hour)
+
+		_, err := b.Status(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 3, peer.calls)
+	}
+}
+
+func TestBreakerClientErrors(t *testing.T) {
+	peer := &flakyPeer{failures: 3, err: runtime.NewAPIError("unknown error", nil, 404)}
+	b := NewBreaker(peer, 2, time.Millisecond, 1, time.Hour)
+
+	// A response showing Alertmanager is available is neither retried nor opens the breaker.
+	for i := 0; i < 2; i++ {
+		_, err := b.Status(context.Background())
+		require.EqualError(t, err, "unknown error (status 404): <nil> ")
+	}
+	require.Equal(t, 2, peer.calls)
+}
+
+func TestBreakerCreateSilence(t *testing.T) {
+	peer := &flakyPeer{failures: 1}
+	b := NewBreaker(peer, 2, time.Millisecond, 3, time.Hour)
+
+	// Alertmanager might have created the silence before the connection broke, so it isn't created again.
+	_, err := b.CreateSilence(context.Background(), EqualMatchers(map[string]string{"alertname": "fire"}), time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.Equal(t, errRefused, err)
+	require.Equal(t, 1, peer.calls)
+
+	id, err := b.CreateSilence(context.Background(), EqualMatchers(map[string]string{"alertname": "fire"}), time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.NoError(t, err)
+	require.Equal(t, "silence", id)
+}
+
+func TestBreakerOpens(t *testing.T) {
+	peer := &flakyPeer{failures: 3}
+	b := NewBreaker(peer, 0, time.Millisecond, 2, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		_, err := b.Status(context.Background())
+		require.EqualError(t, err, "dial tcp: connection refused")
+	}
+
+	_, err := b.Status(context.Background())
+	var unavailable *UnavailableError
+	require.True(t, errors.As(err, &unavailable))
+	require.True(t, unavailable.LastSuccess.IsZero())
+	require.EqualError(t, unavailable.Err, "dial tcp: connection refused")
+	require.Equal(t, 2, peer.calls)
+
+	// After the cooldown the next request asks Alertmanager again.
+	b.cooldown = 0
+	b.openUntil = time.Now()
+	_, err = b.Status(context.Background())
+	require.EqualError(t, err, "dial tcp: connection refused")
+	_, err = b.Status(context.Background())
+	require.NoError(t, err)
+	require.False(t, b.lastSuccess.IsZero())
+}
@@ -0,0 +1,141 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Cache keeps the alerts, silences and status of an Alertmanager for the ttl,
+// so that many users sending the same command during an incident only ask Alertmanager once.
+// Concurrent requests for the same data wait for the one request to Alertmanager.
+// The cached results are shared, callers must not modify them.
+type Cache struct {
+	peer Peer
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is the result of a request, done is closed once it's there.
+type cacheEntry struct {
+	done    chan struct{}
+	expires time.Time
+	value   interface{}
+	err     error
+}
+
+// NewCache caches the alerts, silences and status of the peer for the ttl.
+func NewCache(peer Peer, ttl time.Duration) *Cache {
+	return &Cache{
+		peer:    peer,
+		ttl:     ttl,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+// get returns the cached result of key or the result of the request, which is cached if it succeeds.
+func (c *Cache) get(ctx context.Context, key string, request func(context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		select {
+		case <-e.done:
+			if time.Now().Before(e.expires) {
+				c.mu.Unlock()
+				return e.value, nil
+			}
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		e = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+		c.mu.Unlock()
+
+		e.value, e.err = request(ctx)
+		c.mu.Lock()
+		if e.err != nil {
+			delete(c.entries, key)
+		}
+		e.expires = time.Now().Add(c.ttl)
+		close(e.done)
+		c.mu.Unlock()
+		return e.value, e.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.done:
+		return e.value, e.err
+	}
+}
+
+// ListAlerts lists the alerts of the receiver.
+func (c *Cache) ListAlerts(ctx context.Context, receiver string, silenced bool) ([]*types.Alert, error) {
+	statuses, err := c.ListAlertStatuses(ctx, receiver, silenced)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*types.Alert, 0, len(statuses))
+	for _, a := range statuses {
+		alerts = append(alerts, a.Alert)
+	}
+	return alerts, nil
+}
+
+func (c *Cache) ListAlertStatuses(ctx context.Context, receiver string, silenced bool) ([]Alert, error) {
+	v, err := c.get(ctx, fmt.Sprintf("alerts/%s/%t", receiver, silenced), func(ctx context.Context) (interface{}, error) {
+		return c.peer.ListAlertStatuses(ctx, receiver, silenced)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Alert), nil
+}
+
+func (c *Cache) ListSilences(ctx context.Context) ([]*types.Silence, error) {
+	v, err := c.get(ctx, "silences", func(ctx context.Context) (interface{}, error) {
+		return c.peer.ListSilences(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]*types.Silence), nil
+}
+
+func (c *Cache) Status(ctx context.Context) (*models.AlertmanagerStatus, error) {
+	v, err := c.get(ctx, "status", func(ctx context.Context) (interface{}, error) {
+		return c.peer.Status(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.AlertmanagerStatus), nil
+}
+
+// CreateSilence creates the silence and drops the cached alerts and silences, so that the next /silences lists it.
+func (c *Cache) CreateSilence(ctx context.Context, matchers []*Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error) {
+	id, err := c.peer.CreateSilence(ctx, matchers, startsAt, endsAt, createdBy, comment)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key != "status" {
+			delete(c.entries, key)
+		}
+	}
+	return id, nil
+}
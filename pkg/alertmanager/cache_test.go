@@ -0,0 +1,49 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+	"github.com/stretchr/testify/require"
+)
+
+// countingPeer counts the requests for its silences.
+type countingPeer struct {
+	testPeer
+	calls int
+}
+
+func (p *countingPeer) ListSilences(ctx context.Context) ([]*types.Silence, error) {
+	p.calls++
+	return p.testPeer.ListSilences(ctx)
+}
+
+func TestCache(t *testing.T) {
+	peer := &countingPeer{testPeer: testPeer{silences: []*types.Silence{{ID: "a"}}}}
+	c := NewCache(peer, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		silences, err := c.ListSilences(context.Background())
+		require.NoError(t, err)
+		require.Len(t, silences, 1)
+	}
+	require.Equal(t, 1, peer.calls)
+
+	// Creating a silence drops the cached silences.
+	_, err := c.CreateSilence(context.Background(), EqualMatchers(map[string]string{"alertname": "DiskFull"}), time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.NoError(t, err)
+	_, err = c.ListSilences(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, peer.calls)
+
+	// Expired results are requested again.
+	c.ttl = 0
+	c.entries = map[string]*cacheEntry{}
+	for i := 0; i < 2; i++ {
+		_, err = c.ListSilences(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, 4, peer.calls)
+}
@@ -2,6 +2,7 @@ package alertmanager
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -198,3 +199,84 @@ func TestClient(t *testing.T) {
 		require.Equal(t, expected, alerts)
 	}
 }
+
+func TestListAlertStatuses(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/alerts", r.URL.Path)
+		require.Equal(t, "true", r.URL.Query().Get("silenced"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{
+  "annotations": {},
+  "endsAt": "2021-02-22T00:52:37.000Z",
+  "fingerprint": "1b2f0b4e6a5c0d6e",
+  "receivers": [{"name": "admin"}],
+  "startsAt": "2021-01-27T16:56:37.000Z",
+  "status": {
+    "inhibitedBy": ["7a90bbdd1d39f61b"],
+    "silencedBy": ["34f5f82b-b66f-456b-aff7-b556a7eafe81"],
+    "state": "suppressed"
+  },
+  "updatedAt": "2021-02-22T00:48:37.000Z",
+  "labels": {"alertname": "KubePodNotReady"}
+}]`))
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	alerts, err := client.ListAlertStatuses(context.Background(), "admin", true)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelValue("KubePodNotReady"), alerts[0].Labels["alertname"])
+	require.Equal(t, []string{"34f5f82b-b66f-456b-aff7-b556a7eafe81"}, alerts[0].SilencedBy)
+	require.Equal(t, []string{"7a90bbdd1d39f61b"}, alerts[0].InhibitedBy)
+}
+
+func TestClientCreateSilence(t *testing.T) {
+	version := "0.22.2"
+	var posted struct {
+		Matchers  []postableMatcher `json:"matchers"`
+		CreatedBy string            `json:"createdBy"`
+	}
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v2/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"versionInfo":{"version":"` + version + `"}}`))
+	})
+	m.HandleFunc("/api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"silenceID":"7e9a"}`))
+	})
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	matchers, err := ParseMatchers(`alertname=DiskFull team!=db instance=~"db-.+" severity!~"info|none"`)
+	require.NoError(t, err)
+	id, err := client.CreateSilence(context.Background(), matchers, time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.NoError(t, err)
+	require.Equal(t, "7e9a", id)
+	require.Equal(t, "elliot", posted.CreatedBy)
+	require.Equal(t, []postableMatcher{
+		{Name: "alertname", Value: "DiskFull", IsRegex: false, IsEqual: true},
+		{Name: "team", Value: "db", IsRegex: false, IsEqual: false},
+		{Name: "instance", Value: "db-.+", IsRegex: true, IsEqual: true},
+		{Name: "severity", Value: "info|none", IsRegex: true, IsEqual: false},
+	}, posted.Matchers)
+
+	// Older versions would silence the alerts matching != and !~ instead.
+	version = "0.21.0"
+	_, err = client.CreateSilence(context.Background(), matchers, time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.EqualError(t, err, "Alertmanager 0.21.0 doesn't support silences with != or !~, they need v0.22 or later")
+
+	id, err = client.CreateSilence(context.Background(), matchers[2:3], time.Now(), time.Now().Add(time.Hour), "elliot", "")
+	require.NoError(t, err)
+	require.Equal(t, "7e9a", id)
+}
@@ -0,0 +1,179 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Peer is an Alertmanager whose answers a Cluster merges, e.g. a Client or another Cluster.
+type Peer interface {
+	ListAlertStatuses(context.Context, string, bool) ([]Alert, error)
+	ListSilences(context.Context) ([]*types.Silence, error)
+	Status(context.Context) (*models.AlertmanagerStatus, error)
+	CreateSilence(ctx context.Context, matchers []*Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error)
+}
+
+// Cluster talks to all peers of an Alertmanager cluster. Each peer receives the alerts from Prometheus itself,
+// so a peer that restarted or is partitioned can miss alerts the others have. Listing alerts and silences
+// merges the answers of all peers that can be reached, the others are skipped unless none can be reached.
+type Cluster struct {
+	peers []Peer
+}
+
+// NewClusterOf returns a Cluster of the peers, e.g. of the Clusters of several Alertmanager clusters
+// to list the alerts and silences of all of them.
+func NewClusterOf(peers ...Peer) *Cluster {
+	return &Cluster{peers: peers}
+}
+
+// NewCluster returns a Cluster of the peers at the URLs.
+func NewCluster(urls []*url.URL) (*Cluster, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("a cluster needs at least one peer")
+	}
+	c := &Cluster{}
+	for _, u := range urls {
+		client, err := NewClient(u)
+		if err != nil {
+			return nil, fmt.Errorf("peer %s: %w", u, err)
+		}
+		c.peers = append(c.peers, client)
+	}
+	return c, nil
+}
+
+// ListAlerts lists the alerts of the receiver firing in any peer.
+func (c *Cluster) ListAlerts(ctx context.Context, receiver string, silenced bool) ([]*types.Alert, error) {
+	statuses, err := c.ListAlertStatuses(ctx, receiver, silenced)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*types.Alert, 0, len(statuses))
+	for _, a := range statuses {
+		alerts = append(alerts, a.Alert)
+	}
+	return alerts, nil
+}
+
+// ListAlertStatuses lists the alerts of the receiver firing in any peer once,
+// with the latest update of the alert and the silences and alerts suppressing it in any peer.
+func (c *Cluster) ListAlertStatuses(ctx context.Context, receiver string, silenced bool) ([]Alert, error) {
+	var (
+		alerts  []Alert
+		indexes = map[string]int{}
+		lastErr error
+		reached bool
+	)
+	for _, p := range c.peers {
+		statuses, err := p.ListAlertStatuses(ctx, receiver, silenced)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reached = true
+
+		for _, a := range statuses {
+			fingerprint := a.Fingerprint().String()
+			i, ok := indexes[fingerprint]
+			if !ok {
+				indexes[fingerprint] = len(alerts)
+				alerts = append(alerts, a)
+				continue
+			}
+			merged := alerts[i]
+			if a.UpdatedAt.After(merged.UpdatedAt) {
+				merged.Alert = a.Alert
+			}
+			merged.SilencedBy = union(merged.SilencedBy, a.SilencedBy)
+			merged.InhibitedBy = union(merged.InhibitedBy, a.InhibitedBy)
+			alerts[i] = merged
+		}
+	}
+	if !reached {
+		return nil, fmt.Errorf("no peer reachable: %w", lastErr)
+	}
+	return alerts, nil
+}
+
+// ListSilences lists the silences of all peers once, with their latest update.
+// Silences are gossiped between the peers, but a peer that just joined may not know all of them yet.
+func (c *Cluster) ListSilences(ctx context.Context) ([]*types.Silence, error) {
+	var (
+		silences []*types.Silence
+		indexes  = map[string]int{}
+		lastErr  error
+		reached  bool
+	)
+	for _, p := range c.peers {
+		peerSilences, err := p.ListSilences(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reached = true
+
+		for _, s := range peerSilences {
+			i, ok := indexes[s.ID]
+			if !ok {
+				indexes[s.ID] = len(silences)
+				silences = append(silences, s)
+				continue
+			}
+			if s.UpdatedAt.After(silences[i].UpdatedAt) {
+				silences[i] = s
+			}
+		}
+	}
+	if !reached {
+		return nil, fmt.Errorf("no peer reachable: %w", lastErr)
+	}
+	return silences, nil
+}
+
+// Status returns the status of the first peer that can be reached.
+func (c *Cluster) Status(ctx context.Context) (*models.AlertmanagerStatus, error) {
+	var lastErr error
+	for _, p := range c.peers {
+		status, err := p.Status(ctx)
+		if err == nil {
+			return status, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no peer reachable: %w", lastErr)
+}
+
+// CreateSilence creates the silence in the first peer that can be reached, it's gossiped to the others.
+func (c *Cluster) CreateSilence(ctx context.Context, matchers []*Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error) {
+	var lastErr error
+	for _, p := range c.peers {
+		id, err := p.CreateSilence(ctx, matchers, startsAt, endsAt, createdBy, comment)
+		if err == nil {
+			return id, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("no peer reachable: %w", lastErr)
+}
+
+// union returns the values of a followed by the values of b that aren't in a.
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, v := range a {
+		seen[v] = true
+	}
+	for _, v := range b {
+		if !seen[v] {
+			seen[v] = true
+			a = append(a, v)
+		}
+	}
+	return a
+}
@@ -0,0 +1,99 @@
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+type testPeer struct {
+	alerts   []Alert
+	silences []*types.Silence
+	err      error
+	created  []string
+}
+
+func (p *testPeer) ListAlertStatuses(context.Context, string, bool) ([]Alert, error) {
+	return p.alerts, p.err
+}
+
+func (p *testPeer) ListSilences(context.Context) ([]*types.Silence, error) {
+	return p.silences, p.err
+}
+
+func (p *testPeer) Status(context.Context) (*models.AlertmanagerStatus, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.AlertmanagerStatus{}, nil
+}
+
+func (p *testPeer) CreateSilence(_ context.Context, _ []*Matcher, _, _ time.Time, _, comment string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.created = append(p.created, comment)
+	return "silence", nil
+}
+
+func testAlert(name string, updatedAt time.Time, silencedBy ...string) Alert {
+	return Alert{
+		Alert: &types.Alert{
+			Alert:     model.Alert{Labels: model.LabelSet{"alertname": model.LabelValue(name)}},
+			UpdatedAt: updatedAt,
+		},
+		SilencedBy: silencedBy,
+	}
+}
+
+func TestClusterListAlertStatuses(t *testing.T) {
+	now := time.Now()
+	c := &Cluster{peers: []Peer{
+		&testPeer{alerts: []Alert{testAlert("DiskFull", now.Add(-time.Minute), "a"), testAlert("Down", now)}},
+		&testPeer{err: errors.New("connection refused")},
+		&testPeer{alerts: []Alert{testAlert("DiskFull", now, "b"), testAlert("Lag", now)}},
+	}}
+
+	alerts, err := c.ListAlertStatuses(context.Background(), "telegram", false)
+	require.NoError(t, err)
+	require.Len(t, alerts, 3)
+	require.Equal(t, model.LabelValue("DiskFull"), alerts[0].Labels["alertname"])
+	require.Equal(t, now, alerts[0].UpdatedAt, "the latest update of the alert")
+	require.Equal(t, []string{"a", "b"}, alerts[0].SilencedBy)
+	require.Equal(t, model.LabelValue("Down"), alerts[1].Labels["alertname"])
+	require.Equal(t, model.LabelValue("Lag"), alerts[2].Labels["alertname"])
+
+	c = &Cluster{peers: []Peer{&testPeer{err: errors.New("connection refused")}}}
+	_, err = c.ListAlertStatuses(context.Background(), "telegram", false)
+	require.EqualError(t, err, "no peer reachable: connection refused")
+}
+
+func TestClusterListSilences(t *testing.T) {
+	now := time.Now()
+	c := &Cluster{peers: []Peer{
+		&testPeer{silences: []*types.Silence{{ID: "a", Comment: "old", UpdatedAt: now.Add(-time.Minute)}}},
+		&testPeer{silences: []*types.Silence{{ID: "a", Comment: "new", UpdatedAt: now}, {ID: "b", UpdatedAt: now}}},
+	}}
+
+	silences, err := c.ListSilences(context.Background())
+	require.NoError(t, err)
+	require.Len(t, silences, 2)
+	require.Equal(t, "new", silences[0].Comment)
+	require.Equal(t, "b", silences[1].ID)
+}
+
+func TestClusterCreateSilence(t *testing.T) {
+	down, up := &testPeer{err: errors.New("connection refused")}, &testPeer{}
+	c := &Cluster{peers: []Peer{down, up}}
+
+	id, err := c.CreateSilence(context.Background(), EqualMatchers(map[string]string{"alertname": "DiskFull"}), time.Now(), time.Now().Add(time.Hour), "elliot", "cleaning up")
+	require.NoError(t, err)
+	require.Equal(t, "silence", id)
+	require.Equal(t, []string{"cleaning up"}, up.created)
+}
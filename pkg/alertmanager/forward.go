@@ -0,0 +1,107 @@
+package alertmanager
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// ForwardRule forwards the alerts matching all its matchers to the webhooks of another bot.
+type ForwardRule struct {
+	Matchers []*Matcher
+	// Tenant is the name of the bot receiving the Webhooks.
+	Tenant   string
+	Webhooks chan<- TelegramWebhook
+}
+
+func (r ForwardRule) matches(labels template.KV) bool {
+	for _, m := range r.Matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// forwardedWebhook is a part of a webhook with the index of the rule it's forwarded by, -1 if it isn't forwarded.
+type forwardedWebhook struct {
+	rule    int
+	webhook TelegramWebhook
+}
+
+// Forward sends the webhooks received on in to out, except for the alerts matching a rule.
+// Those are sent to the webhooks of the first matching rule with the same chat or group instead,
+// e.g. to send critical alerts with another bot whose notifications are louder.
+func Forward(ctx context.Context, logger log.Logger, in <-chan TelegramWebhook, out chan<- TelegramWebhook, rules []ForwardRule) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case w := <-in:
+			for _, f := range splitWebhook(w, rules) {
+				webhooks := out
+				if f.rule >= 0 {
+					webhooks = rules[f.rule].Webhooks
+					level.Debug(logger).Log(
+						"msg", "forwarding alerts",
+						"tenant", rules[f.rule].Tenant,
+						"chat_id", f.webhook.ChatID,
+						"group", f.webhook.Group,
+						"alerts", len(f.webhook.Message.Alerts),
+					)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case webhooks <- f.webhook:
+				}
+			}
+		}
+	}
+}
+
+// splitWebhook splits the alerts of a webhook by the first rule they match.
+// A webhook whose alerts all go to the same bot is kept as it is, including its payload.
+func splitWebhook(w TelegramWebhook, rules []ForwardRule) []forwardedWebhook {
+	if w.Message.Data == nil {
+		return []forwardedWebhook{{rule: -1, webhook: w}}
+	}
+
+	var order []int
+	alerts := map[int]template.Alerts{}
+	for _, a := range w.Message.Alerts {
+		rule := -1
+		for i, r := range rules {
+			if r.matches(a.Labels) {
+				rule = i
+				break
+			}
+		}
+		if _, ok := alerts[rule]; !ok {
+			order = append(order, rule)
+		}
+		alerts[rule] = append(alerts[rule], a)
+	}
+
+	switch len(order) {
+	case 0:
+		return []forwardedWebhook{{rule: -1, webhook: w}}
+	case 1:
+		return []forwardedWebhook{{rule: order[0], webhook: w}}
+	}
+
+	split := make([]forwardedWebhook, 0, len(order))
+	for _, rule := range order {
+		data := *w.Message.Data
+		data.Alerts = alerts[rule]
+
+		part := w
+		part.Message.Data = &data
+		// The payload is marshaled again from the message with only the alerts of this part.
+		part.Payload = nil
+		split = append(split, forwardedWebhook{rule: rule, webhook: part})
+	}
+	return split
+}
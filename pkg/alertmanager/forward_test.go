@@ -0,0 +1,68 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/require"
+)
+
+func forwardWebhook(severities ...string) TelegramWebhook {
+	data := &template.Data{Receiver: "telegram", Status: "firing", GroupLabels: template.KV{"alertname": "DiskFull"}}
+	for _, s := range severities {
+		data.Alerts = append(data.Alerts, template.Alert{Status: "firing", Labels: template.KV{"alertname": "DiskFull", "severity": s}})
+	}
+	return TelegramWebhook{ChatID: -1234, Message: webhook.Message{Data: data, GroupKey: "{}:{alertname=\"DiskFull\"}"}, Payload: []byte("{}")}
+}
+
+func TestSplitWebhook(t *testing.T) {
+	critical, err := ParseMatcher("severity=critical")
+	require.NoError(t, err)
+	rules := []ForwardRule{{Matchers: []*Matcher{critical}, Tenant: "critical"}}
+
+	split := splitWebhook(forwardWebhook("warning", "warning"), rules)
+	require.Len(t, split, 1)
+	require.Equal(t, -1, split[0].rule)
+	require.Equal(t, []byte("{}"), split[0].webhook.Payload, "the payload of a webhook that isn't split is kept")
+
+	split = splitWebhook(forwardWebhook("critical"), rules)
+	require.Len(t, split, 1)
+	require.Equal(t, 0, split[0].rule)
+
+	w := forwardWebhook("warning", "critical", "warning")
+	split = splitWebhook(w, rules)
+	require.Len(t, split, 2)
+	require.Equal(t, -1, split[0].rule)
+	require.Len(t, split[0].webhook.Message.Alerts, 2)
+	require.Nil(t, split[0].webhook.Payload)
+	require.Equal(t, 0, split[1].rule)
+	require.Len(t, split[1].webhook.Message.Alerts, 1)
+	require.Equal(t, "critical", split[1].webhook.Message.Alerts[0].Labels["severity"])
+	require.Equal(t, int64(-1234), split[1].webhook.ChatID)
+	require.Equal(t, w.Message.GroupKey, split[1].webhook.Message.GroupKey)
+	require.Len(t, w.Message.Alerts, 3, "the received webhook isn't changed")
+}
+
+func TestForward(t *testing.T) {
+	critical, err := ParseMatcher("severity=critical")
+	require.NoError(t, err)
+
+	in := make(chan TelegramWebhook, 1)
+	out := make(chan TelegramWebhook, 1)
+	forwarded := make(chan TelegramWebhook, 1)
+	rules := []ForwardRule{{Matchers: []*Matcher{critical}, Tenant: "critical", Webhooks: forwarded}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Forward(ctx, log.NewNopLogger(), in, out, rules) }()
+
+	in <- forwardWebhook("warning", "critical")
+	require.Equal(t, "warning", (<-out).Message.Alerts[0].Labels["severity"])
+	require.Equal(t, "critical", (<-forwarded).Message.Alerts[0].Labels["severity"])
+
+	cancel()
+	require.NoError(t, <-done)
+}
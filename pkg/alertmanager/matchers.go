@@ -0,0 +1,180 @@
+package alertmanager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// MatchType is the operator of a matcher.
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// matchTypes are ordered so that the longer operators are found before =.
+var matchTypes = []MatchType{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual}
+
+// Matcher matches the value of a label like the matchers of amtool, e.g. severity=~"critical|page".
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewMatcher returns a matcher, regular expressions are anchored like in Alertmanager.
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Type: t, Name: name, Value: value}
+	if !model.LabelName(name).IsValid() {
+		return nil, fmt.Errorf("label name %q is invalid", name)
+	}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches returns whether the matcher matches the label value.
+// Missing labels have an empty value.
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+// EqualMatchers returns equality matchers for the labels sorted by name, e.g. to silence an alert.
+func EqualMatchers(labels map[string]string) []*Matcher {
+	matchers := make([]*Matcher, 0, len(labels))
+	for name, value := range labels {
+		matchers = append(matchers, &Matcher{Type: MatchEqual, Name: name, Value: value})
+	}
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].Name < matchers[j].Name })
+	return matchers
+}
+
+// ParseMatcher parses a matcher like team=db, severity!="info" or instance=~"node-[0-9]+".
+// Values may be quoted to contain spaces, commas or quotes.
+func ParseMatcher(s string) (*Matcher, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.IndexAny(s, "=!~")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid matcher %q: operator is missing, expected one of =, !=, =~, !~", s)
+	}
+	name := strings.TrimSpace(s[:i])
+	if name == "" {
+		return nil, fmt.Errorf("invalid matcher %q: label name is missing", s)
+	}
+
+	var t MatchType
+	for _, mt := range matchTypes {
+		if strings.HasPrefix(s[i:], string(mt)) {
+			t = mt
+			break
+		}
+	}
+	if t == "" {
+		return nil, fmt.Errorf("invalid matcher %q: unknown operator, expected one of =, !=, =~, !~", s)
+	}
+
+	value := strings.TrimSpace(s[i+len(t):])
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: value isn't quoted correctly", s)
+		}
+		value = unquoted
+	}
+
+	m, err := NewMatcher(t, name, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matcher %q: %w", s, err)
+	}
+	return m, nil
+}
+
+// ParseMatchers parses matchers separated by commas or spaces, optionally in braces like {team="db", severity!="info"}.
+func ParseMatchers(s string) ([]*Matcher, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("invalid matchers %q: closing brace is missing", s)
+		}
+		s = s[1 : len(s)-1]
+	}
+
+	parts, err := splitMatchers(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matchers %q: %w", s, err)
+	}
+
+	matchers := make([]*Matcher, 0, len(parts))
+	for _, part := range parts {
+		m, err := ParseMatcher(part)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// splitMatchers splits at commas and spaces outside of quoted values.
+func splitMatchers(s string) ([]string, error) {
+	var (
+		parts   []string
+		current strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ',' || r == ' ' || r == '\t'):
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("value isn't quoted correctly")
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts, nil
+}
@@ -0,0 +1,99 @@
+package alertmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatchers(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		matchers []string
+		err      string
+	}{{
+		name:     "Equal",
+		input:    "team=db",
+		matchers: []string{`team="db"`},
+	}, {
+		name:     "Operators",
+		input:    `team!=db severity=~"critical|page" instance!~node-.*`,
+		matchers: []string{`team!="db"`, `severity=~"critical|page"`, `instance!~"node-.*"`},
+	}, {
+		name:     "Braces",
+		input:    `{team="db", env="prod"}`,
+		matchers: []string{`team="db"`, `env="prod"`},
+	}, {
+		name:     "QuotedSpacesAndCommas",
+		input:    `summary="disk is full, again" quote="say \"hi\""`,
+		matchers: []string{`summary="disk is full, again"`, `quote="say \"hi\""`},
+	}, {
+		name:     "EmptyValue",
+		input:    `team=""`,
+		matchers: []string{`team=""`},
+	}, {
+		name:  "OperatorMissing",
+		input: "critical",
+		err:   `invalid matcher "critical": operator is missing, expected one of =, !=, =~, !~`,
+	}, {
+		name:  "NameMissing",
+		input: "=db",
+		err:   `invalid matcher "=db": label name is missing`,
+	}, {
+		name:  "NameInvalid",
+		input: "te-am=db",
+		err:   `invalid matcher "te-am=db": label name "te-am" is invalid`,
+	}, {
+		name:  "UnknownOperator",
+		input: "team~db",
+		err:   `invalid matcher "team~db": unknown operator, expected one of =, !=, =~, !~`,
+	}, {
+		name:  "Unterminated",
+		input: `team="db`,
+		err:   `invalid matchers "team=\"db": value isn't quoted correctly`,
+	}, {
+		name:  "RegexpInvalid",
+		input: "team=~(db",
+		err:   "invalid matcher \"team=~(db\": invalid regular expression \"(db\": error parsing regexp: missing closing ): `^(?:(db)$`",
+	}, {
+		name:  "BraceMissing",
+		input: "{team=db",
+		err:   `invalid matchers "{team=db": closing brace is missing`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			matchers, err := ParseMatchers(tc.input)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var strs []string
+			for _, m := range matchers {
+				strs = append(strs, m.String())
+			}
+			require.Equal(t, tc.matchers, strs)
+		})
+	}
+}
+
+func TestMatcherMatches(t *testing.T) {
+	for _, tc := range []struct {
+		matcher string
+		value   string
+		matches bool
+	}{
+		{matcher: "team=db", value: "db", matches: true},
+		{matcher: "team=db", value: "dba", matches: false},
+		{matcher: "team!=db", value: "", matches: true},
+		{matcher: "team=~d.", value: "db", matches: true},
+		{matcher: "team=~d", value: "db", matches: false},
+		{matcher: "team!~d.", value: "sre", matches: true},
+		{matcher: "team!~d.", value: "db", matches: false},
+	} {
+		m, err := ParseMatcher(tc.matcher)
+		require.NoError(t, err)
+		require.Equal(t, tc.matches, m.Matches(tc.value), "%s with %q", tc.matcher, tc.value)
+	}
+}
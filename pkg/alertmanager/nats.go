@@ -0,0 +1,255 @@
+package alertmanager
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// natsMaxPayload limits the size of a message read from NATS if the server doesn't announce its max_payload.
+const natsMaxPayload = 1 << 20
+
+// NATSConsumer reads webhooks of Alertmanager from NATS, for setups that buffer the notifications in a message bus
+// instead of sending them to the bot directly. A webhook published to <subject>.<tenant>.<chat> or
+// <subject>.<tenant>.<group> is handled like one sent to /webhooks/<tenant>/<chat> or /webhooks/<tenant>/<group>.
+// It speaks the text protocol of NATS core, messages published while it isn't connected are missed.
+// Kafka isn't supported, as the bot has no Kafka client, topics need to be bridged to NATS or sent as webhooks.
+type NATSConsumer struct {
+	url     *url.URL
+	tls     *tls.Config
+	subject string
+	queue   string
+	tenants map[string]chan<- TelegramWebhook
+	counter prometheus.Counter
+	logger  log.Logger
+}
+
+// NewNATSConsumer subscribes to the webhooks of the tenants below the subject on the NATS server, e.g. nats://nats:4222.
+// Connections to tls:// URLs and servers requiring TLS are encrypted, verified with the tlsConfig if it isn't nil.
+// Consumers with the same queue group share the webhooks, each one is only received by one of them.
+func NewNATSConsumer(u *url.URL, tlsConfig *tls.Config, subject, queue string, tenants map[string]chan<- TelegramWebhook, counter prometheus.Counter, logger log.Logger) (*NATSConsumer, error) {
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q, only nats:// and tls:// are supported", u.Scheme)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid NATS subject %q", subject)
+	}
+	return &NATSConsumer{url: u, tls: tlsConfig, subject: subject, queue: queue, tenants: tenants, counter: counter, logger: logger}, nil
+}
+
+// Run consumes the webhooks until the context is done, reconnecting with backoff if the connection fails.
+func (c *NATSConsumer) Run(ctx context.Context) error {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		level.Warn(c.logger).Log("msg", "connection to nats failed", "err", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// natsInfo is the part of the INFO message of the server the client needs to honour.
+type natsInfo struct {
+	TLSRequired  bool `json:"tls_required"`
+	AuthRequired bool `json:"auth_required"`
+	MaxPayload   int  `json:"max_payload"`
+}
+
+// natsConnect is the CONNECT message of the client, see https://docs.nats.io/reference/reference-protocols/nats-protocol.
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	TLS      bool   `json:"tls_required"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// consume connects to the server, subscribes and handles the messages until the connection fails.
+func (c *NATSConsumer) consume(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.url.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Closing the connection stops reading from it once the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read server info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting from server: %q", strings.TrimSpace(line))
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return fmt.Errorf("failed to decode server info: %w", err)
+	}
+	if info.MaxPayload <= 0 {
+		info.MaxPayload = natsMaxPayload
+	}
+
+	// The TLS handshake starts right after the INFO message, so nothing else is buffered yet.
+	var rw io.ReadWriter = conn
+	if c.url.Scheme == "tls" || info.TLSRequired {
+		if !info.TLSRequired {
+			return fmt.Errorf("nats server at %s doesn't support TLS", c.url.Host)
+		}
+		config := &tls.Config{}
+		if c.tls != nil {
+			config = c.tls.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = c.url.Hostname()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("failed to connect with TLS: %w", err)
+		}
+		rw, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect := natsConnect{Name: "alertmanager-bot", Lang: "go", Version: "1", TLS: info.TLSRequired}
+	if u := c.url.User; u != nil {
+		if pass, ok := u.Password(); ok {
+			connect.User, connect.Pass = u.Username(), pass
+		} else {
+			connect.Token = u.Username()
+		}
+	}
+	if info.AuthRequired && c.url.User == nil {
+		return fmt.Errorf("nats server at %s requires authentication, but the URL has neither user and password nor a token", c.url.Host)
+	}
+	options, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	subscribe := fmt.Sprintf("SUB %s.> 1\r\n", c.subject)
+	if c.queue != "" {
+		subscribe = fmt.Sprintf("SUB %s.> %s 1\r\n", c.subject, c.queue)
+	}
+	if _, err := fmt.Fprintf(rw, "CONNECT %s\r\nPING\r\n%s", options, subscribe); err != nil {
+		return err
+	}
+	level.Debug(c.logger).Log("msg", "subscribed to nats", "host", c.url.Host, "subject", c.subject+".>")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(rw, "PONG\r\n"); err != nil {
+				return err
+			}
+		case line == "PONG" || line == "+OK":
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG "):
+			subject, payload, err := readNATSMessage(r, line, info.MaxPayload)
+			if err != nil {
+				return err
+			}
+			c.handle(ctx, subject, payload)
+		}
+	}
+}
+
+// readNATSMessage reads the payload of the message with the header line MSG <subject> <sid> [reply-to] <#bytes>,
+// which mustn't be larger than the max payload of the server.
+func readNATSMessage(r *bufio.Reader, line string, maxPayload int) (string, []byte, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 && len(fields) != 5 {
+		return "", nil, fmt.Errorf("invalid message header %q", line)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 || size > maxPayload {
+		return "", nil, fmt.Errorf("invalid message size in %q", line)
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", nil, err
+	}
+	return fields[1], payload[:size], nil
+}
+
+// handle passes the webhook in the payload to the tenant of the subject.
+func (c *NATSConsumer) handle(ctx context.Context, subject string, payload []byte) {
+	target := strings.Split(strings.TrimPrefix(subject, c.subject+"."), ".")
+	if len(target) != 2 {
+		level.Warn(c.logger).Log("msg", "dropping nats message without tenant and chat in its subject", "subject", subject)
+		return
+	}
+	webhooks, ok := c.tenants[target[0]]
+	if !ok {
+		level.Warn(c.logger).Log("msg", "dropping nats message of unknown tenant", "subject", subject)
+		return
+	}
+	chatID, group, ok := webhookTarget(target[1])
+	if !ok {
+		level.Warn(c.logger).Log("msg", "dropping nats message without chat ID or chat group name", "subject", subject)
+		return
+	}
+
+	var message webhook.Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to decode webhook message", "subject", subject, "err", err)
+		return
+	}
+
+	level.Debug(c.logger).Log(
+		"msg", "received webhook",
+		"alerts", len(message.Alerts),
+		"chat_id", chatID,
+		"group", group,
+	)
+
+	select {
+	case <-ctx.Done():
+	case webhooks <- TelegramWebhook{ChatID: chatID, Group: group, Message: message, Payload: payload, ReceivedAt: time.Now()}:
+		c.counter.Inc()
+	}
+}
@@ -0,0 +1,161 @@
+package alertmanager
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// testNATSInfo is the INFO message of a server without TLS and authentication.
+const testNATSInfo = `{"server_id":"test","max_payload":1048576}`
+
+// serveNATS accepts a single client, greets it with the info, expects its subscription and publishes the messages to it.
+// With a tlsConfig the connection is upgraded to TLS after the info, as servers requiring TLS do.
+func serveNATS(t *testing.T, l net.Listener, info string, tlsConfig *tls.Config, subscription string, messages map[string]string) {
+	c, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer c.Close()
+
+	_, _ = fmt.Fprintf(c, "INFO %s\r\n", info)
+	var conn io.ReadWriter = c
+	if tlsConfig != nil {
+		conn = tls.Server(c, tlsConfig)
+	}
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(line, "SUB ") {
+			if got := strings.TrimSpace(line); got != subscription {
+				t.Errorf("expected subscription %q, got %q", subscription, got)
+				return
+			}
+			break
+		}
+	}
+
+	for subject, payload := range messages {
+		_, _ = fmt.Fprintf(conn, "PING\r\nMSG %s 1 %d\r\n%s\r\n", subject, len(payload), payload)
+	}
+	// Keep the connection open until the client disconnects.
+	_, _ = io.Copy(ioutil.Discard, r)
+}
+
+func TestNATSConsumer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go serveNATS(t, l, testNATSInfo, nil, "SUB alertmanager.webhooks.> bots 1", map[string]string{
+		"alertmanager.webhooks.telegram.-1234": validWebhook,
+		"alertmanager.webhooks.unknown.-1234":  validWebhook,
+	})
+
+	webhooks := make(chan TelegramWebhook, 2)
+	u, err := url.Parse("nats://" + l.Addr().String())
+	require.NoError(t, err)
+	counter := prometheus.NewCounter(prometheus.CounterOpts{})
+	c, err := NewNATSConsumer(u, nil, "alertmanager.webhooks", "bots", map[string]chan<- TelegramWebhook{"telegram": webhooks}, counter, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+
+	select {
+	case w := <-webhooks:
+		require.Equal(t, int64(-1234), w.ChatID)
+		require.Equal(t, "Fire", w.Message.GroupLabels["alertname"])
+		require.Equal(t, validWebhook, string(w.Payload))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received from nats")
+	}
+
+	_, err = NewNATSConsumer(u, nil, "alertmanager.*", "", nil, counter, log.NewNopLogger())
+	require.Error(t, err)
+	_, err = NewNATSConsumer(&url.URL{Scheme: "kafka", Host: "kafka:9092"}, nil, "alertmanager", "", nil, counter, log.NewNopLogger())
+	require.Error(t, err)
+}
+
+func TestNATSConsumerTLS(t *testing.T) {
+	// The certificate of the httptest server is valid for 127.0.0.1 and trusted by its client.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	serverTLS := server.TLS
+	clientTLS := server.Client().Transport.(*http.Transport).TLSClientConfig
+	server.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go serveNATS(t, l, `{"server_id":"test","tls_required":true,"auth_required":true}`, serverTLS, "SUB alertmanager.webhooks.> 1", map[string]string{
+		"alertmanager.webhooks.telegram.-1234": validWebhook,
+	})
+
+	webhooks := make(chan TelegramWebhook, 1)
+	u, err := url.Parse("nats://bot:secret@" + l.Addr().String())
+	require.NoError(t, err)
+	c, err := NewNATSConsumer(u, clientTLS, "alertmanager.webhooks", "", map[string]chan<- TelegramWebhook{"telegram": webhooks}, prometheus.NewCounter(prometheus.CounterOpts{}), log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+
+	select {
+	case w := <-webhooks:
+		require.Equal(t, int64(-1234), w.ChatID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received from nats")
+	}
+}
+
+func TestNATSConsumerRefusesServer(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		url  string
+		info string
+		err  string
+	}{{
+		name: "AuthRequired",
+		url:  "nats://%s",
+		info: `{"server_id":"test","auth_required":true}`,
+		err:  "nats server at %s requires authentication, but the URL has neither user and password nor a token",
+	}, {
+		name: "TLSUnsupported",
+		url:  "tls://%s",
+		info: testNATSInfo,
+		err:  "nats server at %s doesn't support TLS",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer l.Close()
+			go serveNATS(t, l, tc.info, nil, "", nil)
+
+			u, err := url.Parse(fmt.Sprintf(tc.url, l.Addr()))
+			require.NoError(t, err)
+			c, err := NewNATSConsumer(u, nil, "alertmanager.webhooks", "", nil, prometheus.NewCounter(prometheus.CounterOpts{}), log.NewNopLogger())
+			require.NoError(t, err)
+			require.EqualError(t, c.consume(context.Background()), fmt.Sprintf(tc.err, l.Addr()))
+		})
+	}
+}
@@ -3,9 +3,12 @@ package alertmanager
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
 	"github.com/hako/durafmt"
 	"github.com/prometheus/alertmanager/api/v2/client/silence"
 	"github.com/prometheus/alertmanager/types"
@@ -45,6 +48,90 @@ func (c *Client) ListSilences(ctx context.Context) ([]*types.Silence, error) {
 	return silences, nil
 }
 
+// postableMatcher is a matcher of a silence with isEqual, which the API client of Alertmanager v0.21 is missing.
+type postableMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type postableSilence struct {
+	Matchers  []postableMatcher `json:"matchers"`
+	StartsAt  strfmt.DateTime   `json:"startsAt"`
+	EndsAt    strfmt.DateTime   `json:"endsAt"`
+	CreatedBy string            `json:"createdBy"`
+	Comment   string            `json:"comment"`
+}
+
+// CreateSilence creates a silence for the matchers and returns its ID.
+// Alertmanager only supports != and !~ since v0.22, older versions would silence the alerts that match instead,
+// so such silences are refused for them.
+func (c *Client) CreateSilence(ctx context.Context, matchers []*Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error) {
+	s := postableSilence{
+		Matchers:  make([]postableMatcher, 0, len(matchers)),
+		StartsAt:  strfmt.DateTime(startsAt),
+		EndsAt:    strfmt.DateTime(endsAt),
+		CreatedBy: createdBy,
+		Comment:   comment,
+	}
+	negative := false
+	for _, m := range matchers {
+		pm := postableMatcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.Type == MatchRegexp || m.Type == MatchNotRegexp,
+			IsEqual: m.Type == MatchEqual || m.Type == MatchRegexp,
+		}
+		negative = negative || !pm.IsEqual
+		s.Matchers = append(s.Matchers, pm)
+	}
+	if negative {
+		if err := c.supportsNegativeMatchers(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	// The request is the one of the API client, only with the body including isEqual.
+	result, err := c.alertmanager.Transport.Submit(&runtime.ClientOperation{
+		ID:                 "postSilences",
+		Method:             http.MethodPost,
+		PathPattern:        "/silences",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params: runtime.ClientRequestWriterFunc(func(r runtime.ClientRequest, _ strfmt.Registry) error {
+			return r.SetBodyParam(s)
+		}),
+		Reader:  &silence.PostSilencesReader{},
+		Context: ctx,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(*silence.PostSilencesOK).Payload.SilenceID, nil
+}
+
+// supportsNegativeMatchers returns an error unless the version of Alertmanager supports != and !~ in silences.
+func (c *Client) supportsNegativeMatchers(ctx context.Context) error {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var version string
+	if status.VersionInfo != nil && status.VersionInfo.Version != nil {
+		version = *status.VersionInfo.Version
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(version, "v"), "%d.%d", &major, &minor); err != nil {
+		return fmt.Errorf("unknown Alertmanager version %q, silences with != or !~ need v0.22 or later", version)
+	}
+	if major == 0 && minor < 22 {
+		return fmt.Errorf("Alertmanager %s doesn't support silences with != or !~, they need v0.22 or later", version)
+	}
+	return nil
+}
+
 // SilenceMessage converts a silences to a message string.
 func SilenceMessage(s *types.Silence) string {
 	var alertname, emoji, matchers, duration string
@@ -2,9 +2,12 @@ package alertmanager
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -13,12 +16,39 @@ import (
 )
 
 type TelegramWebhook struct {
-	ChatID  int64
+	ChatID int64
+	// Group is the name of the chat group the webhook is sent to instead of a single chat.
+	Group   string
 	Message webhook.Message
+	// Payload is the raw body of the webhook request.
+	Payload []byte
+	// ReceivedAt is when the webhook was received, zero for replayed webhooks.
+	ReceivedAt time.Time
+}
+
+var groupName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// webhookTarget returns the chat ID or the name of the chat group a webhook is sent to, false if target is neither.
+func webhookTarget(target string) (int64, string, bool) {
+	if chatID, err := strconv.ParseInt(target, 10, 64); err == nil {
+		return chatID, "", true
+	}
+	if !groupName.MatchString(target) {
+		return 0, "", false
+	}
+	return 0, target, true
 }
 
 // HandleTelegramWebhook returns a HandlerFunc that forwards webhooks to all bots via a channel.
 func HandleTelegramWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- TelegramWebhook) http.HandlerFunc {
+	return HandleTenantWebhook(logger, counter, "telegram", webhooks)
+}
+
+// HandleTenantWebhook returns a HandlerFunc that forwards webhooks sent to
+// /webhooks/<tenant>/<chat> or /webhooks/<tenant>/<group> via a channel.
+func HandleTenantWebhook(logger log.Logger, counter prometheus.Counter, tenant string, webhooks chan<- TelegramWebhook) http.HandlerFunc {
+	prefix := "/webhooks/" + tenant + "/"
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -31,16 +61,22 @@ func HandleTelegramWebhook(logger log.Logger, counter prometheus.Counter, webhoo
 		}
 		defer r.Body.Close()
 
-		chatID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/webhooks/telegram/"), 10, 64)
+		chatID, group, ok := webhookTarget(strings.TrimPrefix(r.URL.Path, prefix))
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unable to parse chat ID to int64 or chat group name"}`))
+			return
+		}
+
+		payload, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(`{"error":"unable to parse chat ID to int64"}`))
 			return
 		}
 
 		var message webhook.Message
 
-		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		if err := json.Unmarshal(payload, &message); err != nil {
 			level.Warn(logger).Log(
 				"msg", "failed to decode webhook message",
 				"err", err,
@@ -53,9 +89,10 @@ func HandleTelegramWebhook(logger log.Logger, counter prometheus.Counter, webhoo
 			"msg", "received webhook",
 			"alerts", len(message.Alerts),
 			"chat_id", chatID,
+			"group", group,
 		)
 
-		webhooks <- TelegramWebhook{ChatID: chatID, Message: message}
+		webhooks <- TelegramWebhook{ChatID: chatID, Group: group, Message: message, Payload: payload, ReceivedAt: time.Now()}
 		counter.Inc()
 	}
 }
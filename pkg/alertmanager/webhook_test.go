@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
@@ -89,7 +90,11 @@ func TestHandleWebhook(t *testing.T) {
 					}
 
 					webhook := <-webhooks
-					if !assert.Equal(t, TelegramWebhook{ChatID: 123, Message: expected}, webhook) {
+					if !assert.False(t, webhook.ReceivedAt.IsZero()) {
+						return errors.New("")
+					}
+					webhook.ReceivedAt = time.Time{}
+					if !assert.Equal(t, TelegramWebhook{ChatID: 123, Message: expected, Payload: []byte(validWebhook)}, webhook) {
 						return errors.New("")
 					}
 					return nil
@@ -112,7 +117,11 @@ func TestHandleWebhook(t *testing.T) {
 					}
 
 					webhook := <-webhooks
-					if !assert.Equal(t, TelegramWebhook{ChatID: -1234, Message: expected}, webhook) {
+					if !assert.False(t, webhook.ReceivedAt.IsZero()) {
+						return errors.New("")
+					}
+					webhook.ReceivedAt = time.Time{}
+					if !assert.Equal(t, TelegramWebhook{ChatID: -1234, Message: expected, Payload: []byte(validWebhook)}, webhook) {
 						return errors.New("")
 					}
 					return nil
@@ -134,3 +143,37 @@ func TestHandleWebhook(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleTenantWebhook(t *testing.T) {
+	webhooks := make(chan TelegramWebhook, 1)
+	h := HandleTenantWebhook(log.NewNopLogger(), prometheus.NewCounter(prometheus.CounterOpts{}), "team-a", webhooks)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks/team-a/-1234", bytes.NewBufferString(validWebhook))
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	assert.Equal(t, int64(-1234), (<-webhooks).ChatID)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/webhooks/telegram/-1234", bytes.NewBufferString(validWebhook))
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+}
+
+func TestHandleGroupWebhook(t *testing.T) {
+	webhooks := make(chan TelegramWebhook, 1)
+	h := HandleTelegramWebhook(log.NewNopLogger(), prometheus.NewCounter(prometheus.CounterOpts{}), webhooks)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks/telegram/team-a", bytes.NewBufferString(validWebhook))
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	webhook := <-webhooks
+	assert.Equal(t, int64(0), webhook.ChatID)
+	assert.Equal(t, "team-a", webhook.Group)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/webhooks/telegram/team a", bytes.NewBufferString(validWebhook))
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+}
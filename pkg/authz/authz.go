@@ -0,0 +1,68 @@
+// Package authz decides whether Telegram users are allowed to command the bot
+// by looking up their membership in a group of the organization's directory.
+package authz
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Checker looks up whether a user of the organization's directory is a member of the allowed group.
+type Checker interface {
+	IsMember(ctx context.Context, identity string) (bool, error)
+}
+
+// Authorizer maps Telegram users to their identities and checks their group membership.
+// Decisions are cached, so that not every message needs a lookup.
+type Authorizer struct {
+	identities map[int]string
+	checker    Checker
+	ttl        time.Duration
+
+	mu        sync.Mutex
+	decisions map[int]decision
+}
+
+type decision struct {
+	allowed bool
+	at      time.Time
+}
+
+// New returns an Authorizer checking the identities of Telegram user IDs with the checker.
+// Decisions are cached for ttl, a ttl of 0 checks every time.
+func New(identities map[int]string, checker Checker, ttl time.Duration) *Authorizer {
+	return &Authorizer{
+		identities: identities,
+		checker:    checker,
+		ttl:        ttl,
+		decisions:  map[int]decision{},
+	}
+}
+
+// Authorized returns whether the Telegram user is a member of the allowed group.
+// Users without an identity are never authorized.
+func (a *Authorizer) Authorized(ctx context.Context, userID int) (bool, error) {
+	identity, ok := a.identities[userID]
+	if !ok {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	d, ok := a.decisions[userID]
+	a.mu.Unlock()
+	if ok && time.Since(d.at) < a.ttl {
+		return d.allowed, nil
+	}
+
+	allowed, err := a.checker.IsMember(ctx, identity)
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.Lock()
+	a.decisions[userID] = decision{allowed: allowed, at: time.Now()}
+	a.mu.Unlock()
+
+	return allowed, nil
+}
@@ -0,0 +1,191 @@
+package authz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testChecker struct {
+	members map[string]bool
+	checks  int
+}
+
+func (c *testChecker) IsMember(_ context.Context, identity string) (bool, error) {
+	c.checks++
+	return c.members[identity], nil
+}
+
+func TestAuthorizer(t *testing.T) {
+	checker := &testChecker{members: map[string]bool{"alice": true}}
+	a := New(map[int]string{1: "alice", 2: "bob"}, checker, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		allowed, err := a.Authorized(context.Background(), 1)
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+	require.Equal(t, 1, checker.checks)
+
+	allowed, err := a.Authorized(context.Background(), 2)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	allowed, err = a.Authorized(context.Background(), 3)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, 2, checker.checks)
+}
+
+func TestOAuth2(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"secret","token_type":"bearer","expires_in":3600}`))
+	})
+	m.HandleFunc("/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"sub":"alice","groups":["sre","dev"]}`))
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	o, err := NewOAuth2(server.URL+"/token", "bot", "password", nil, server.URL+"/users/{user}", "", "sre")
+	require.NoError(t, err)
+
+	member, err := o.IsMember(context.Background(), "alice")
+	require.NoError(t, err)
+	require.True(t, member)
+
+	member, err = o.IsMember(context.Background(), "bob")
+	require.NoError(t, err)
+	require.False(t, member)
+}
+
+// testLDAPServer answers the bind and returns an entry if the search filter contains alice.
+// With startTLS it expects the StartTLS request first, without it the connection is TLS from the start.
+// If refuseStartTLS is set, the StartTLS request fails and the server records whether a bind followed.
+type testLDAPServer struct {
+	listener       net.Listener
+	tls            *tls.Config
+	startTLS       bool
+	refuseStartTLS bool
+	plainBind      chan bool
+}
+
+func (s *testLDAPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	result := func(code int) []byte {
+		return concat(berInt(tagEnumerated, code), berTLV(tagOctetString, nil), berTLV(tagOctetString, nil))
+	}
+
+	if s.startTLS {
+		op, err := readLDAPMessage(&connByteReader{conn: conn})
+		if err != nil || op.tag != tagExtendedRequest || !bytes.Contains(op.content, []byte(oidStartTLS)) {
+			return
+		}
+		if s.refuseStartTLS {
+			_, _ = conn.Write(ldapMessage(1, berTLV(tagExtendedResponse, result(2))))
+			op, err := readLDAPMessage(bufio.NewReader(conn))
+			s.plainBind <- err == nil && op.tag == tagBindRequest
+			return
+		}
+		_, _ = conn.Write(ldapMessage(1, berTLV(tagExtendedResponse, result(0))))
+	}
+	conn = tls.Server(conn, s.tls)
+	r := bufio.NewReader(conn)
+
+	op, err := readLDAPMessage(r)
+	if err != nil || op.tag != tagBindRequest {
+		return
+	}
+	_, _ = conn.Write(ldapMessage(2, berTLV(tagBindResponse, result(0))))
+
+	op, err = readLDAPMessage(r)
+	if err != nil || op.tag != tagSearchRequest {
+		return
+	}
+	if bytes.Contains(op.content, []byte("alice")) {
+		entry := concat(berTLV(tagOctetString, []byte("uid=alice,ou=people,dc=example,dc=com")), berTLV(tagSequence, nil))
+		_, _ = conn.Write(ldapMessage(3, berTLV(tagSearchEntry, entry)))
+	}
+	_, _ = conn.Write(ldapMessage(3, berTLV(tagSearchDone, result(0))))
+}
+
+func TestLDAP(t *testing.T) {
+	// The certificate of the httptest server is valid for 127.0.0.1 and trusted by its client.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	serverTLS := server.TLS
+	clientTLS := server.Client().Transport.(*http.Transport).TLSClientConfig
+	server.Close()
+
+	for _, tc := range []struct {
+		name   string
+		scheme string
+	}{{
+		name:   "StartTLS",
+		scheme: "ldap",
+	}, {
+		name:   "LDAPS",
+		scheme: "ldaps",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer l.Close()
+			go (&testLDAPServer{listener: l, tls: serverTLS, startTLS: tc.scheme == "ldap"}).serve()
+
+			u, err := url.Parse(tc.scheme + "://" + l.Addr().String())
+			require.NoError(t, err)
+			checker := &LDAP{
+				URL:       u,
+				TLSConfig: clientTLS,
+				BindDN:    "cn=bot,dc=example,dc=com",
+				BaseDN:    "ou=people,dc=example,dc=com",
+				GroupDN:   "cn=sre,ou=groups,dc=example,dc=com",
+				Timeout:   time.Second,
+			}
+
+			member, err := checker.IsMember(context.Background(), "alice")
+			require.NoError(t, err)
+			require.True(t, member)
+		})
+	}
+}
+
+func TestLDAPWithoutStartTLS(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	server := &testLDAPServer{listener: l, startTLS: true, refuseStartTLS: true, plainBind: make(chan bool, 1)}
+	go server.serve()
+
+	u, err := url.Parse("ldap://" + l.Addr().String())
+	require.NoError(t, err)
+	checker := &LDAP{URL: u, BindDN: "cn=bot,dc=example,dc=com", BindPassword: "secret", Timeout: time.Second}
+
+	_, err = checker.IsMember(context.Background(), "alice")
+	require.EqualError(t, err, "failed to connect to ldap: ldap server doesn't support StartTLS, refusing to bind without TLS: result code 2")
+	require.False(t, <-server.plainBind)
+}
+
+func TestBERLongLength(t *testing.T) {
+	content := bytes.Repeat([]byte{'a'}, 300)
+	e, err := readBER(bytes.NewReader(berTLV(tagOctetString, content)))
+	require.NoError(t, err)
+	require.Equal(t, byte(tagOctetString), e.tag)
+	require.Equal(t, content, e.content)
+}
@@ -0,0 +1,374 @@
+package authz
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// BER tags of the few LDAP messages needed to check a group membership, see RFC 4511.
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagBoolean     = 0x01
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+
+	tagBindRequest      = 0x60
+	tagBindResponse     = 0x61
+	tagUnbindRequest    = 0x42
+	tagSearchRequest    = 0x63
+	tagSearchEntry      = 0x64
+	tagSearchDone       = 0x65
+	tagExtendedRequest  = 0x77
+	tagExtendedResponse = 0x78
+	tagExtendedName     = 0x80
+	tagSimpleAuth       = 0x80
+	tagFilterAnd        = 0xa0
+	tagFilterEqualityOf = 0xa3
+
+	ldapResultSuccess           = 0
+	ldapResultSizeLimitExceeded = 4
+
+	// oidStartTLS is the name of the extended operation upgrading a connection to TLS, see RFC 4511 section 4.14.
+	oidStartTLS = "1.3.6.1.4.1.1466.20037"
+)
+
+// LDAP checks if a user is a member of a group by searching for the user with the group in its memberOf attribute.
+// The bind password is only ever sent over TLS, ldap:// connections are upgraded with StartTLS before binding
+// and fail if the server doesn't support it.
+type LDAP struct {
+	// URL of the server, either ldap:// or ldaps://.
+	URL *url.URL
+	// TLSConfig verifies the server, e.g. with the RootCAs of a private CA.
+	// The ServerName defaults to the host of the URL.
+	TLSConfig    *tls.Config
+	BindDN       string
+	BindPassword string
+	// BaseDN is where the users are searched.
+	BaseDN string
+	// UserAttribute holds the identity of users, e.g. uid or sAMAccountName.
+	UserAttribute string
+	// GroupDN is the distinguished name of the group users need to be a member of.
+	GroupDN string
+	Timeout time.Duration
+}
+
+// IsMember searches for the user as a member of the group.
+func (l *LDAP) IsMember(ctx context.Context, identity string) (bool, error) {
+	deadline, ok := ctx.Deadline()
+	if l.Timeout > 0 && (!ok || time.Now().Add(l.Timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(l.Timeout), true
+	}
+	conn, err := l.dial(ctx, deadline, ok)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	bind := berTLV(tagBindRequest, concat(
+		berInt(tagInteger, 3),
+		berTLV(tagOctetString, []byte(l.BindDN)),
+		berTLV(tagSimpleAuth, []byte(l.BindPassword)),
+	))
+	if _, err := conn.Write(ldapMessage(2, bind)); err != nil {
+		return false, err
+	}
+	op, err := readLDAPMessage(r)
+	if err != nil {
+		return false, err
+	}
+	if op.tag != tagBindResponse {
+		return false, fmt.Errorf("unexpected ldap response %#x to bind", op.tag)
+	}
+	if code, err := resultCode(op); err != nil || code != ldapResultSuccess {
+		return false, fmt.Errorf("failed to bind to ldap: result code %d %v", code, err)
+	}
+
+	attribute := l.UserAttribute
+	if attribute == "" {
+		attribute = "uid"
+	}
+	search := berTLV(tagSearchRequest, concat(
+		berTLV(tagOctetString, []byte(l.BaseDN)),
+		berInt(tagEnumerated, 2), // whole subtree
+		berInt(tagEnumerated, 0), // never deref aliases
+		berInt(tagInteger, 1),    // size limit
+		berInt(tagInteger, int(l.Timeout/time.Second)),
+		berTLV(tagBoolean, []byte{0x00}),
+		berTLV(tagFilterAnd, concat(
+			berTLV(tagFilterEqualityOf, concat(
+				berTLV(tagOctetString, []byte(attribute)),
+				berTLV(tagOctetString, []byte(identity)),
+			)),
+			berTLV(tagFilterEqualityOf, concat(
+				berTLV(tagOctetString, []byte("memberOf")),
+				berTLV(tagOctetString, []byte(l.GroupDN)),
+			)),
+		)),
+		// Only return the DN, no attributes.
+		berTLV(tagSequence, berTLV(tagOctetString, []byte("1.1"))),
+	))
+	if _, err := conn.Write(ldapMessage(3, search)); err != nil {
+		return false, err
+	}
+
+	found := false
+	for {
+		op, err := readLDAPMessage(r)
+		if err != nil {
+			return false, err
+		}
+		switch op.tag {
+		case tagSearchEntry:
+			found = true
+		case tagSearchDone:
+			code, err := resultCode(op)
+			if err != nil {
+				return false, err
+			}
+			if code != ldapResultSuccess && code != ldapResultSizeLimitExceeded {
+				return false, fmt.Errorf("failed to search ldap: result code %d", code)
+			}
+			_, _ = conn.Write(ldapMessage(4, berTLV(tagUnbindRequest, nil)))
+			return found, nil
+		}
+	}
+}
+
+// dial connects to the server with TLS, either directly for ldaps:// or with StartTLS for ldap://.
+func (l *LDAP) dial(ctx context.Context, deadline time.Time, hasDeadline bool) (net.Conn, error) {
+	d := &net.Dialer{Timeout: l.Timeout}
+	var address string
+	switch l.URL.Scheme {
+	case "ldap":
+		address = hostPort(l.URL, "389")
+	case "ldaps":
+		address = hostPort(l.URL, "636")
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", l.URL.Scheme)
+	}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if hasDeadline {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if l.URL.Scheme == "ldap" {
+		if err := startTLS(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	config := &tls.Config{}
+	if l.TLSConfig != nil {
+		config = l.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = l.URL.Hostname()
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// startTLS asks the server to upgrade the connection to TLS, refusing to continue without it
+// as the bind would send the password in plain text.
+func startTLS(conn net.Conn) error {
+	req := berTLV(tagExtendedRequest, berTLV(tagExtendedName, []byte(oidStartTLS)))
+	if _, err := conn.Write(ldapMessage(1, req)); err != nil {
+		return err
+	}
+	// The response is read byte by byte, so that nothing of the TLS handshake is buffered.
+	op, err := readLDAPMessage(&connByteReader{conn: conn})
+	if err != nil {
+		return err
+	}
+	if op.tag != tagExtendedResponse {
+		return fmt.Errorf("unexpected ldap response %#x to StartTLS", op.tag)
+	}
+	code, err := resultCode(op)
+	if err != nil {
+		return err
+	}
+	if code != ldapResultSuccess {
+		return fmt.Errorf("ldap server doesn't support StartTLS, refusing to bind without TLS: result code %d", code)
+	}
+	return nil
+}
+
+// connByteReader reads a connection without buffering.
+type connByteReader struct {
+	conn net.Conn
+	b    [1]byte
+}
+
+func (r *connByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.conn, r.b[:]); err != nil {
+		return 0, err
+	}
+	return r.b[0], nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// berTLV encodes a BER element with a definite length.
+func berTLV(tag byte, content []byte) []byte {
+	n := len(content)
+	var length []byte
+	switch {
+	case n < 0x80:
+		length = []byte{byte(n)}
+	default:
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		length = append([]byte{0x80 | byte(len(length))}, length...)
+	}
+	return concat([]byte{tag}, length, content)
+}
+
+// berInt encodes a non-negative integer or enumerated value.
+func berInt(tag byte, v int) []byte {
+	content := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		content = append([]byte{byte(v)}, content...)
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0x00}, content...)
+	}
+	return berTLV(tag, content)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func ldapMessage(id int, op []byte) []byte {
+	return berTLV(tagSequence, concat(berInt(tagInteger, id), op))
+}
+
+// berElement is a decoded BER element.
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+// readBER reads a single BER element, accepting all definite length forms servers use.
+func readBER(r io.ByteReader) (berElement, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return berElement{}, err
+	}
+	l, err := r.ReadByte()
+	if err != nil {
+		return berElement{}, err
+	}
+	length := int(l)
+	if l&0x80 != 0 {
+		n := int(l & 0x7f)
+		if n == 0 || n > 4 {
+			return berElement{}, errors.New("unsupported ber length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return berElement{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	content := make([]byte, length)
+	for i := range content {
+		if content[i], err = r.ReadByte(); err != nil {
+			return berElement{}, err
+		}
+	}
+	return berElement{tag: tag, content: content}, nil
+}
+
+// children decodes the elements of a constructed element.
+func (e berElement) children() ([]berElement, error) {
+	var children []berElement
+	r := &byteReader{b: e.content}
+	for r.i < len(r.b) {
+		c, err := readBER(r)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, c)
+	}
+	return children, nil
+}
+
+type byteReader struct {
+	b []byte
+	i int
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if r.i >= len(r.b) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.i++
+	return r.b[r.i-1], nil
+}
+
+// readLDAPMessage reads a message and returns its protocol operation.
+func readLDAPMessage(r io.ByteReader) (berElement, error) {
+	msg, err := readBER(r)
+	if err != nil {
+		return berElement{}, err
+	}
+	if msg.tag != tagSequence {
+		return berElement{}, fmt.Errorf("unexpected ldap message tag %#x", msg.tag)
+	}
+	children, err := msg.children()
+	if err != nil {
+		return berElement{}, err
+	}
+	if len(children) < 2 {
+		return berElement{}, errors.New("ldap message without protocol operation")
+	}
+	return children[1], nil
+}
+
+// resultCode returns the result code of an LDAP response.
+func resultCode(op berElement) (int, error) {
+	children, err := op.children()
+	if err != nil {
+		return 0, err
+	}
+	if len(children) == 0 || children[0].tag != tagEnumerated {
+		return 0, errors.New("ldap response without result code")
+	}
+	code := 0
+	for _, b := range children[0].content {
+		code = code<<8 | int(b)
+	}
+	return code, nil
+}
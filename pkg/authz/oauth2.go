@@ -0,0 +1,80 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2 checks if a user is a member of a group by reading the groups claim of the user
+// from an identity provider's API, authenticated with the client credentials of the bot.
+type OAuth2 struct {
+	client *http.Client
+	// userURL returns the user's claims, {user} is replaced with the identity.
+	userURL string
+	claim   string
+	group   string
+}
+
+// NewOAuth2 returns an OAuth2 checker getting its tokens from tokenURL.
+// userURL has to contain {user}, which is replaced with the user's identity,
+// and has to respond with a JSON object whose claim is the list of the user's groups.
+func NewOAuth2(tokenURL, clientID, clientSecret string, scopes []string, userURL, claim, group string) (*OAuth2, error) {
+	if !strings.Contains(userURL, "{user}") {
+		return nil, fmt.Errorf("user URL %q doesn't contain {user}", userURL)
+	}
+	if claim == "" {
+		claim = "groups"
+	}
+	c := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       scopes,
+	}
+	return &OAuth2{
+		client:  c.Client(context.Background()),
+		userURL: userURL,
+		claim:   claim,
+		group:   group,
+	}, nil
+}
+
+// IsMember looks for the group in the user's groups claim.
+func (o *OAuth2) IsMember(ctx context.Context, identity string) (bool, error) {
+	u := strings.ReplaceAll(o.userURL, "{user}", url.PathEscape(identity))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %s looking up user %s", resp.Status, identity)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return false, err
+	}
+	groups, _ := claims[o.claim].([]interface{})
+	for _, g := range groups {
+		if name, ok := g.(string); ok && name == o.group {
+			return true, nil
+		}
+	}
+	return false, nil
+}
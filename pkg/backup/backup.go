@@ -0,0 +1,225 @@
+// Package backup snapshots the bot's state in its store to object storage and restores it,
+// e.g. for deployments whose bolt store is lost together with the pod.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// snapshotPrefix starts the names of the snapshots in the bucket.
+	snapshotPrefix = "snapshot-"
+	// snapshotSuffix ends the names of the snapshots, they are gzipped JSON.
+	snapshotSuffix = ".json.gz"
+	// snapshotTimeFormat is the time in the names of the snapshots, sorting them by the time they were taken.
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+// Bucket stores the snapshots as objects by name.
+type Bucket interface {
+	Put(ctx context.Context, name string, content []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	// List returns the names of the objects starting with the prefix in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Pair is a key of the store with its value.
+type Pair struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Snapshot is the bot's state at a point in time: the pairs of all keys under the backed up prefixes.
+type Snapshot struct {
+	Time  time.Time `json:"time"`
+	Pairs []Pair    `json:"pairs"`
+}
+
+// Take a snapshot of all keys in the store starting with one of the prefixes.
+func Take(kv store.Store, prefixes []string, now time.Time) (*Snapshot, error) {
+	pairs := map[string][]byte{}
+	for _, prefix := range prefixes {
+		list, err := kv.List(prefix)
+		if err != nil {
+			if errors.Is(err, store.ErrKeyNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		for _, p := range list {
+			pairs[p.Key] = p.Value
+		}
+	}
+
+	s := &Snapshot{Time: now.UTC(), Pairs: make([]Pair, 0, len(pairs))}
+	for key, value := range pairs {
+		s.Pairs = append(s.Pairs, Pair{Key: key, Value: value})
+	}
+	sort.Slice(s.Pairs, func(i, j int) bool { return s.Pairs[i].Key < s.Pairs[j].Key })
+	return s, nil
+}
+
+// Restore writes the snapshot's pairs into the store, keys that aren't in the snapshot are kept as they are.
+func (s *Snapshot) Restore(kv store.Store) error {
+	for _, p := range s.Pairs {
+		if err := kv.Put(p.Key, p.Value, nil); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", p.Key, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the snapshot's object in the bucket.
+func (s *Snapshot) Name() string {
+	return snapshotPrefix + s.Time.UTC().Format(snapshotTimeFormat) + snapshotSuffix
+}
+
+// Upload the snapshot to the bucket as gzipped JSON.
+func (s *Snapshot) Upload(ctx context.Context, bucket Bucket) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(s); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return bucket.Put(ctx, s.Name(), buf.Bytes())
+}
+
+// Download the snapshot with the name from the bucket, the latest snapshot if the name is empty.
+func Download(ctx context.Context, bucket Bucket, name string) (*Snapshot, error) {
+	if name == "" {
+		names, err := snapshots(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, errors.New("there are no snapshots in the bucket")
+		}
+		name = names[len(names)-1]
+	}
+
+	content, err := bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", name, err)
+	}
+	defer gz.Close()
+
+	var s Snapshot
+	if err := json.NewDecoder(gz).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// snapshots returns the names of the snapshots in the bucket, the oldest first.
+func snapshots(ctx context.Context, bucket Bucket) ([]string, error) {
+	objects, err := bucket.List(ctx, snapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range objects {
+		if strings.HasSuffix(name, snapshotSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Backuper periodically uploads snapshots of the store to a bucket and deletes the snapshots older than the retention.
+type Backuper struct {
+	kv        store.Store
+	prefixes  []string
+	bucket    Bucket
+	retention time.Duration
+	logger    log.Logger
+}
+
+// NewBackuper backs up the keys starting with the prefixes to the bucket, a retention of 0 keeps all snapshots.
+func NewBackuper(kv store.Store, prefixes []string, bucket Bucket, retention time.Duration, logger log.Logger) *Backuper {
+	return &Backuper{kv: kv, prefixes: prefixes, bucket: bucket, retention: retention, logger: logger}
+}
+
+// Run backs up the store every interval until the context is done.
+// The first backup is only taken after an interval, so that a fresh store isn't backed up before it was restored.
+func (b *Backuper) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := b.Backup(ctx, time.Now()); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to back up store", "err", err)
+			}
+		}
+	}
+}
+
+// Backup uploads a snapshot of the store and deletes the snapshots older than the retention.
+// An empty store isn't backed up, so that it doesn't become the latest snapshot.
+func (b *Backuper) Backup(ctx context.Context, now time.Time) (*Snapshot, error) {
+	s, err := Take(b.kv, b.prefixes, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Pairs) == 0 {
+		level.Debug(b.logger).Log("msg", "not backing up empty store")
+		return s, nil
+	}
+	if err := s.Upload(ctx, b.bucket); err != nil {
+		return nil, fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+	level.Info(b.logger).Log("msg", "backed up store", "snapshot", s.Name(), "keys", len(s.Pairs))
+
+	if b.retention > 0 {
+		if err := b.prune(ctx, now.Add(-b.retention)); err != nil {
+			return s, fmt.Errorf("failed to delete old snapshots: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// prune deletes the snapshots taken before the time, except for the latest one.
+func (b *Backuper) prune(ctx context.Context, before time.Time) error {
+	names, err := snapshots(ctx, b.bucket)
+	if err != nil {
+		return err
+	}
+	for i, name := range names {
+		if i == len(names)-1 {
+			break
+		}
+		taken, err := time.Parse(snapshotTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix))
+		if err != nil || !taken.Before(before) {
+			continue
+		}
+		if err := b.bucket.Delete(ctx, name); err != nil {
+			return err
+		}
+		level.Debug(b.logger).Log("msg", "deleted old snapshot", "snapshot", name)
+	}
+	return nil
+}
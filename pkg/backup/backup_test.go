@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/kvtest"
+	"github.com/stretchr/testify/require"
+)
+
+// testBucket keeps the objects in memory.
+type testBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *testBucket) Put(_ context.Context, name string, content []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[name] = content
+	return nil
+}
+
+func (b *testBucket) Get(_ context.Context, name string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.objects[name], nil
+}
+
+func (b *testBucket) List(_ context.Context, prefix string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var names []string
+	for name := range b.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *testBucket) Delete(_ context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, name)
+	return nil
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+	require.NoError(t, kv.Put("telegram/history/1", []byte(`{"fingerprint":"a"}`), nil))
+	require.NoError(t, kv.Put("other/chats/2", []byte(`{"id":2}`), nil))
+
+	bucket := &testBucket{objects: map[string][]byte{}}
+	backuper := NewBackuper(kv, []string{"telegram", "telegram/chats", "team-a"}, bucket, 48*time.Hour, log.NewNopLogger())
+	ctx := context.Background()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	s, err := backuper.Backup(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, "snapshot-20210301T120000Z.json.gz", s.Name())
+	require.Equal(t, []Pair{
+		{Key: "telegram/chats/1", Value: []byte(`{"id":1}`)},
+		{Key: "telegram/history/1", Value: []byte(`{"fingerprint":"a"}`)},
+	}, s.Pairs)
+
+	// Snapshots older than the retention are deleted.
+	_, err = backuper.Backup(ctx, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	_, err = backuper.Backup(ctx, now.Add(72*time.Hour))
+	require.NoError(t, err)
+	names, err := snapshots(ctx, bucket)
+	require.NoError(t, err)
+	require.Equal(t, []string{"snapshot-20210302T120000Z.json.gz", "snapshot-20210304T120000Z.json.gz"}, names)
+
+	restored, closeRestored := kvtest.New(t)
+	defer closeRestored()
+	require.NoError(t, restored.Put("telegram/chats/3", []byte(`{"id":3}`), nil))
+
+	latest, err := Download(ctx, bucket, "")
+	require.NoError(t, err)
+	require.Equal(t, now.Add(72*time.Hour), latest.Time)
+	require.NoError(t, latest.Restore(restored))
+
+	pairs, err := restored.List("telegram")
+	require.NoError(t, err)
+	require.Len(t, pairs, 3)
+	pair, err := restored.Get("telegram/history/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"fingerprint":"a"}`), pair.Value)
+}
+
+func TestBackupEmptyStore(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+
+	bucket := &testBucket{objects: map[string][]byte{}}
+	_, err := NewBackuper(kv, []string{"telegram"}, bucket, 0, log.NewNopLogger()).Backup(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Empty(t, bucket.objects)
+
+	_, err = Download(context.Background(), bucket, "")
+	require.EqualError(t, err, "there are no snapshots in the bucket")
+}
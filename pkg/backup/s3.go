@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/sigv4"
+)
+
+// S3Bucket is a bucket of an S3 compatible object storage, like AWS S3, Google Cloud Storage with HMAC keys or MinIO.
+// Requests are addressed path-style and signed with AWS Signature Version 4.
+type S3Bucket struct {
+	endpoint *url.URL
+	bucket   string
+	// prefix is prepended to the names of the objects.
+	prefix string
+
+	signer sigv4.Signer
+	http   *http.Client
+	now    func() time.Time
+}
+
+// NewS3Bucket returns the bucket named by the first path segment of the URL, the rest of the path prefixes the objects' names,
+// e.g. https://s3.eu-central-1.amazonaws.com/bucket/alertmanager-bot or https://storage.googleapis.com/bucket/alertmanager-bot.
+func NewS3Bucket(u *url.URL, region, accessKeyID, secretAccessKey string) (*S3Bucket, error) {
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		return nil, errors.New("the URL of the bucket has no bucket name in its path")
+	}
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, errors.New("the access key ID and secret access key of the bucket are missing")
+	}
+
+	b := &S3Bucket{
+		endpoint: &url.URL{Scheme: u.Scheme, Host: u.Host},
+		signer:   sigv4.Signer{Region: region, Service: "s3", AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey},
+		http:     http.DefaultClient,
+		now:      time.Now,
+	}
+	b.bucket = path
+	if i := strings.Index(path, "/"); i >= 0 {
+		b.bucket, b.prefix = path[:i], path[i+1:]+"/"
+	}
+	return b, nil
+}
+
+// Put uploads the object.
+func (b *S3Bucket) Put(ctx context.Context, name string, content []byte) error {
+	header := http.Header{"Content-Type": {"application/gzip"}}
+	_, err := b.do(ctx, http.MethodPut, b.prefix+name, nil, header, content)
+	return err
+}
+
+// Get downloads the object.
+func (b *S3Bucket) Get(ctx context.Context, name string) ([]byte, error) {
+	return b.do(ctx, http.MethodGet, b.prefix+name, nil, nil, nil)
+}
+
+// Delete deletes the object.
+func (b *S3Bucket) Delete(ctx context.Context, name string) error {
+	_, err := b.do(ctx, http.MethodDelete, b.prefix+name, nil, nil, nil)
+	return err
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the names of the objects starting with the prefix, following the pages of the listing.
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	query := url.Values{"list-type": {"2"}, "prefix": {b.prefix + prefix}}
+	for {
+		body, err := b.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to read listing of bucket %s: %w", b.bucket, err)
+		}
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, b.prefix))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// s3Error is the body of error responses.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends the signed request for the object, the bucket itself if the key is empty, and returns the response's body.
+func (b *S3Bucket) do(ctx context.Context, method, key string, query url.Values, header http.Header, content []byte) ([]byte, error) {
+	u := *b.endpoint
+	u.Path = "/" + b.bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = sigv4.URIEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	b.signer.Sign(req, content, b.now())
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		target := "bucket " + b.bucket
+		if key != "" {
+			target = key + " in " + target
+		}
+		var e s3Error
+		if xml.Unmarshal(respBody, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("%s %s failed with %s: %s", method, target, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("%s %s failed with %s", method, target, resp.Status)
+	}
+	return respBody, nil
+}
+
+// canonicalQuery encodes the query sorted by key the way it is signed.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, sigv4.URIEncode(key, true)+"="+sigv4.URIEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewS3Bucket(t *testing.T) {
+	u, _ := url.Parse("https://storage.googleapis.com/")
+	_, err := NewS3Bucket(u, "auto", "id", "secret")
+	require.EqualError(t, err, "the URL of the bucket has no bucket name in its path")
+
+	u, _ = url.Parse("https://storage.googleapis.com/backups/alertmanager-bot/")
+	_, err = NewS3Bucket(u, "auto", "", "")
+	require.EqualError(t, err, "the access key ID and secret access key of the bucket are missing")
+
+	b, err := NewS3Bucket(u, "auto", "id", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "backups", b.bucket)
+	require.Equal(t, "alertmanager-bot/", b.prefix)
+}
+
+// testS3 is an S3 server keeping the objects of a bucket in memory, listing them one per page.
+type testS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *testS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/backups" {
+		var keys []string
+		for key := range s.objects {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("continuation-token") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			fmt.Fprint(w, `<ListBucketResult></ListBucketResult>`)
+			return
+		}
+		fmt.Fprintf(w, `<ListBucketResult><Contents><Key>%s</Key></Contents><IsTruncated>%t</IsTruncated><NextContinuationToken>%s</NextContinuationToken></ListBucketResult>`,
+			keys[0], len(keys) > 1, keys[0],
+		)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/backups/")
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key], _ = ioutil.ReadAll(r.Body)
+	case http.MethodGet:
+		content, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		_, _ = w.Write(content)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Bucket(t *testing.T) {
+	s3 := &testS3{objects: map[string][]byte{"other/snapshot-1": nil}}
+	s := httptest.NewServer(s3)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/backups/bot")
+	b, err := NewS3Bucket(u, "us-east-1", "id", "secret")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, b.Put(ctx, "snapshot-1", []byte("first")))
+	require.NoError(t, b.Put(ctx, "snapshot-2", []byte("second")))
+	require.Equal(t, []byte("first"), s3.objects["bot/snapshot-1"])
+
+	names, err := b.List(ctx, "snapshot-")
+	require.NoError(t, err)
+	require.Equal(t, []string{"snapshot-1", "snapshot-2"}, names)
+
+	content, err := b.Get(ctx, "snapshot-2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), content)
+
+	require.NoError(t, b.Delete(ctx, "snapshot-1"))
+	names, err = b.List(ctx, "snapshot-")
+	require.NoError(t, err)
+	require.Equal(t, []string{"snapshot-2"}, names)
+
+	_, err = b.Get(ctx, "snapshot-1")
+	require.EqualError(t, err, "GET bot/snapshot-1 in bucket backups failed with NoSuchKey: The specified key does not exist.")
+
+	b.signer.AccessKeyID = "other"
+	_, err = b.List(ctx, "snapshot-")
+	require.EqualError(t, err, "GET bucket backups failed with AccessDenied: Access Denied")
+}
@@ -0,0 +1,265 @@
+// Package calendar reads the events of iCalendar feeds,
+// e.g. the secret address in iCal format of a Google Calendar.
+package calendar
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT of a calendar. Recurring events only have their first occurrence.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+// Feed downloads the events of a calendar from its iCal URL.
+type Feed struct {
+	url    string
+	client *http.Client
+}
+
+// NewFeed returns a Feed downloading the calendar at url, waiting at most timeout for a response.
+func NewFeed(url string, timeout time.Duration) *Feed {
+	return &Feed{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Events downloads the calendar and returns its events.
+func (f *Feed) Events(ctx context.Context) ([]Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return Parse(resp.Body)
+}
+
+// property is a content line of a calendar, e.g. DTSTART;TZID=Europe/Berlin:20240701T220000.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse returns the events of an iCalendar, see RFC 5545.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []Event
+		event  map[string]property
+		// nested counts the components within the current event, e.g. VALARM, whose properties are skipped.
+		nested int
+	)
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			event = map[string]property{}
+		case event == nil:
+		case p.name == "BEGIN":
+			nested++
+		case p.name == "END" && nested > 0:
+			nested--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			e, err := parseEvent(event)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+			event = nil
+		case nested == 0:
+			event[p.name] = p
+		}
+	}
+	return events, nil
+}
+
+// unfold joins the lines continued on the next line, which start with a space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	// The value starts after the first colon that isn't within a quoted parameter value.
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, nil
+}
+
+func parseEvent(props map[string]property) (Event, error) {
+	e := Event{
+		UID:         unescape(props["UID"].value),
+		Summary:     unescape(props["SUMMARY"].value),
+		Description: unescape(props["DESCRIPTION"].value),
+	}
+	if e.UID == "" {
+		return e, fmt.Errorf("event %q has no UID", e.Summary)
+	}
+
+	start, ok := props["DTSTART"]
+	if !ok {
+		return e, fmt.Errorf("event %s has no DTSTART", e.UID)
+	}
+	var err error
+	if e.Start, err = parseTime(start); err != nil {
+		return e, fmt.Errorf("event %s: invalid DTSTART: %w", e.UID, err)
+	}
+
+	if end, ok := props["DTEND"]; ok {
+		if e.End, err = parseTime(end); err != nil {
+			return e, fmt.Errorf("event %s: invalid DTEND: %w", e.UID, err)
+		}
+	} else if duration, ok := props["DURATION"]; ok {
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			return e, fmt.Errorf("event %s: invalid DURATION: %w", e.UID, err)
+		}
+		e.End = e.Start.Add(d)
+	} else if start.params["VALUE"] == "DATE" {
+		// An all-day event without an end lasts the day it starts.
+		e.End = e.Start.AddDate(0, 0, 1)
+	} else {
+		e.End = e.Start
+	}
+	return e, nil
+}
+
+// parseTime parses a date or a date with a time, which is in UTC, in the time zone of its TZID or local time.
+func parseTime(p property) (time.Time, error) {
+	loc := time.Local
+	if tzid, ok := p.params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	switch {
+	case p.params["VALUE"] == "DATE" || len(p.value) == len("20060102"):
+		return time.ParseInLocation("20060102", p.value, loc)
+	case strings.HasSuffix(p.value, "Z"):
+		return time.Parse("20060102T150405Z", p.value)
+	default:
+		return time.ParseInLocation("20060102T150405", p.value, loc)
+	}
+}
+
+// parseDuration parses durations like P1W, P1D, PT4H30M or P1DT12H.
+func parseDuration(s string) (time.Duration, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var (
+		d      time.Duration
+		number string
+		inTime bool
+	)
+	for _, r := range s[1:] {
+		if r >= '0' && r <= '9' {
+			number += string(r)
+			continue
+		}
+		if r == 'T' {
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		number = ""
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+// unescape replaces the escaped characters of text values.
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
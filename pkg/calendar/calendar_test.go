@@ -0,0 +1,111 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20240701T200000Z\r\n" +
+	"DTEND:20240702T000000Z\r\n" +
+	"UID:a1b2c3@google.com\r\n" +
+	"SUMMARY:DB upgrade\r\n" +
+	"DESCRIPTION:Upgrading to Postgres 16\\, see the runbook.\\ninstance=db-1\\nj\r\n" +
+	" ob=postgres\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:This is an event reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240705T220000\r\n" +
+	"DURATION:PT2H30M\r\n" +
+	"UID:d4e5f6@google.com\r\n" +
+	"SUMMARY:Network maintenance\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240710\r\n" +
+	"UID:g7h8i9@google.com\r\n" +
+	"SUMMARY:Datacenter move\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	events, err := Parse(strings.NewReader(testCalendar))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.Equal(t, Event{
+		UID:         "a1b2c3@google.com",
+		Summary:     "DB upgrade",
+		Description: "Upgrading to Postgres 16, see the runbook.\ninstance=db-1\njob=postgres",
+		Start:       time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC),
+		End:         time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+	}, events[0])
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	require.True(t, time.Date(2024, 7, 5, 22, 0, 0, 0, berlin).Equal(events[1].Start))
+	require.Equal(t, 150*time.Minute, events[1].End.Sub(events[1].Start))
+
+	require.Equal(t, 24*time.Hour, events[2].End.Sub(events[2].Start), "an all-day event lasts the day")
+}
+
+func TestParseInvalid(t *testing.T) {
+	testcases := []struct {
+		name    string
+		content string
+		err     string
+	}{{
+		name:    "NoUID",
+		content: "BEGIN:VEVENT\nSUMMARY:DB upgrade\nDTSTART:20240701T200000Z\nEND:VEVENT\n",
+		err:     `event "DB upgrade" has no UID`,
+	}, {
+		name:    "NoStart",
+		content: "BEGIN:VEVENT\nUID:a1b2c3\nEND:VEVENT\n",
+		err:     "event a1b2c3 has no DTSTART",
+	}, {
+		name:    "InvalidDuration",
+		content: "BEGIN:VEVENT\nUID:a1b2c3\nDTSTART:20240701T200000Z\nDURATION:PT2X\nEND:VEVENT\n",
+		err:     `event a1b2c3: invalid DURATION: invalid duration "PT2X"`,
+	}, {
+		name:    "InvalidLine",
+		content: "BEGIN:VEVENT\nUID\nEND:VEVENT\n",
+		err:     `invalid line "UID"`,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.content))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/basic.ics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		_, _ = w.Write([]byte(testCalendar))
+	}))
+	defer server.Close()
+
+	events, err := NewFeed(server.URL+"/basic.ics", time.Second).Events(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	_, err = NewFeed(server.URL+"/private.ics", time.Second).Events(context.Background())
+	require.EqualError(t, err, "unexpected status 404 Not Found")
+}
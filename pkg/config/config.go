@@ -0,0 +1,670 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultTenant is the name of the bot configured with command line flags,
+// it receives webhooks on /webhooks/telegram/<chat>.
+const DefaultTenant = "telegram"
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Config is the configuration file of the alertmanager-bot.
+type Config struct {
+	// Groups are the chat groups of the bot configured with flags.
+	Groups map[string][]int64 `yaml:"groups"`
+	// Authorization of the bot configured with flags.
+	Authorization *Authorization `yaml:"authorization"`
+	// Commands and Roles of the bot configured with flags.
+	Commands map[string][]string `yaml:"commands"`
+	Roles    map[string][]string `yaml:"roles"`
+	// Subscriptions of the bot configured with flags.
+	Subscriptions      []Subscription `yaml:"subscriptions"`
+	PruneSubscriptions bool           `yaml:"pruneSubscriptions"`
+	// Reminders of the bot configured with flags.
+	Reminders map[string][]time.Duration `yaml:"reminders"`
+	// Aliases of the bot configured with flags.
+	Aliases map[string]string `yaml:"aliases"`
+	// Templates of the bot configured with flags.
+	Templates []TemplateOverride `yaml:"templates"`
+	// Enrichment and Inventory of the bot configured with flags.
+	Enrichment []Enrichment `yaml:"enrichment"`
+	Inventory  *Inventory   `yaml:"inventory"`
+	// Redaction of the bot configured with flags.
+	Redaction []Redaction `yaml:"redaction"`
+	// Shadow and Canary of the bot configured with flags.
+	Shadow *Shadow `yaml:"shadow"`
+	Canary *Canary `yaml:"canary"`
+	// Alertmanagers of the bot configured with flags.
+	Alertmanagers []Alertmanager `yaml:"alertmanagers"`
+	// Forward and Outputs of the bot configured with flags.
+	Forward []Forward `yaml:"forward"`
+	Outputs []Output  `yaml:"outputs"`
+	Tenants []Tenant  `yaml:"tenants"`
+}
+
+// Tenant is an independent bot running in the same process as the others.
+// It receives webhooks on /webhooks/<name>/<chat>.
+type Tenant struct {
+	Name          string   `yaml:"name"`
+	Token         string   `yaml:"token"`
+	Admins        []int    `yaml:"admins"`
+	TemplatePaths []string `yaml:"templatePaths"`
+	// StorePrefix is prepended to all keys of the tenant in the store, defaults to tenants/<name>.
+	StorePrefix string `yaml:"storePrefix"`
+	Labels      Labels `yaml:"labels"`
+	// Groups map the names of chat groups to the IDs of their chats,
+	// webhooks sent to /webhooks/<name>/<group> are sent to all chats of the group.
+	Groups        map[string][]int64 `yaml:"groups"`
+	Authorization *Authorization     `yaml:"authorization"`
+	// Commands map commands to the principals allowed to send them, see telegram.WithCommandPermissions.
+	Commands map[string][]string `yaml:"commands"`
+	// Roles are named lists of principals commands can refer to.
+	Roles map[string][]string `yaml:"roles"`
+	// Subscriptions are subscribed when the bot starts.
+	Subscriptions []Subscription `yaml:"subscriptions"`
+	// PruneSubscriptions unsubscribes all chats that aren't in Subscriptions when the bot starts.
+	PruneSubscriptions bool `yaml:"pruneSubscriptions"`
+	// Reminders are the durations after an alert started to remind about it by severity, see telegram.WithReminders.
+	Reminders map[string][]time.Duration `yaml:"reminders"`
+	// Aliases map aliases to the commands they stand for, see telegram.WithCommandAliases.
+	Aliases map[string]string `yaml:"aliases"`
+	// Templates render matching alerts with another template than telegram.default, see telegram.WithTemplateOverrides.
+	Templates []TemplateOverride `yaml:"templates"`
+	// Enrichment adds the fields of external HTTP sources to the alerts' annotations, see telegram.WithEnrichers.
+	Enrichment []Enrichment `yaml:"enrichment"`
+	// Inventory resolves the instance labels of alerts to their hostname, datacenter and rack.
+	Inventory *Inventory `yaml:"inventory"`
+	// Redaction replaces secrets in the alerts' annotations, see telegram.WithRedaction.
+	Redaction []Redaction `yaml:"redaction"`
+	// Shadow sends all alerts rendered with another configuration to a test chat, see telegram.WithShadow.
+	Shadow *Shadow `yaml:"shadow"`
+	// Canary renders the alerts of a percentage of chats with another configuration, see telegram.WithCanary.
+	Canary *Canary `yaml:"canary"`
+	// Alertmanagers are more Alertmanager clusters chats can select with /cluster, see telegram.WithClusters.
+	Alertmanagers []Alertmanager `yaml:"alertmanagers"`
+	// Forward sends the alerts matching the matchers of a rule with the bot of another tenant, see alertmanager.Forward.
+	Forward []Forward `yaml:"forward"`
+	// Outputs are chat services the alerts are sent to in addition to Telegram, see telegram.WithOutput.
+	Outputs []Output `yaml:"outputs"`
+}
+
+// Output sends the alerts to Webex Teams, Mattermost or by email, rendered with the same templates as for Telegram.
+type Output struct {
+	Name string `yaml:"name"`
+	// Type is webex, mattermost or email.
+	Type string `yaml:"type"`
+	// URL is the incoming webhook of Mattermost, or the Webex API, which defaults to https://webexapis.com.
+	URL string `yaml:"url"`
+	// Token is the access token of the Webex bot and Room the ID of the room it sends to.
+	Token string `yaml:"token"`
+	Room  string `yaml:"room"`
+	// Channel and Username override the ones of the Mattermost webhook.
+	Channel  string `yaml:"channel"`
+	Username string `yaml:"username"`
+	// Smarthost is the SMTP server emails are sent with, e.g. smtp.example.com:587,
+	// authenticating with Username and Password unless Username is empty.
+	Smarthost string   `yaml:"smarthost"`
+	Password  string   `yaml:"password"`
+	From      string   `yaml:"from"`
+	To        []string `yaml:"to"`
+	// Timeout of sending the alerts, defaults to 10s.
+	Timeout time.Duration `yaml:"timeout"`
+	// Commands receives the commands sent in the room or channel of a Webex or Mattermost output.
+	Commands *OutputCommands `yaml:"commands"`
+}
+
+// OutputCommands lets users send the commands of the bot in the room or channel of an output, see telegram.WithOutputCommands.
+type OutputCommands struct {
+	// Secret signs the Webex webhook of created messages, Token is the token of the Mattermost slash command or outgoing webhook.
+	Secret string `yaml:"secret"`
+	Token  string `yaml:"token"`
+	// Chat is the Telegram chat the commands are handled as if they were sent in, e.g. /alerts lists its alerts.
+	Chat int64 `yaml:"chat"`
+	// Users may send commands, by email address in Webex and username in Mattermost, everyone in the room or channel if empty.
+	Users []string `yaml:"users"`
+}
+
+// Forward sends the alerts matching all matchers to the same chat or group with the bot of another tenant,
+// e.g. a separate bot for critical alerts whose notifications are louder.
+type Forward struct {
+	Matchers []string `yaml:"matchers"`
+	// Tenant is the name of the other tenant, telegram for the bot configured with flags.
+	Tenant string `yaml:"tenant"`
+}
+
+// Alertmanager is an Alertmanager cluster in addition to the one of --alertmanager.url.
+type Alertmanager struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Peers are the URLs of the other peers of the cluster.
+	Peers []string `yaml:"peers"`
+}
+
+// Shadow is a template and label configuration whose rendering of all alerts is sent to a test chat,
+// to compare it with the messages of the other chats before switching them over.
+type Shadow struct {
+	ChatID int64 `yaml:"chatID"`
+	// TemplatePaths default to the templates of the bot.
+	TemplatePaths []string           `yaml:"templatePaths"`
+	Templates     []TemplateOverride `yaml:"templates"`
+	Labels        Labels             `yaml:"labels"`
+}
+
+// Canary is a template and label configuration rolled out to a percentage of chats with /config promote.
+type Canary struct {
+	// TemplatePaths default to the templates of the bot.
+	TemplatePaths []string           `yaml:"templatePaths"`
+	Templates     []TemplateOverride `yaml:"templates"`
+	Labels        Labels             `yaml:"labels"`
+}
+
+// Inventory looks up the hosts of instance labels in a static file, see enrichment.Inventory,
+// or in Netbox, see enrichment.Netbox. The fields of the file take precedence.
+type Inventory struct {
+	File   string  `yaml:"file"`
+	Netbox *Netbox `yaml:"netbox"`
+}
+
+// Netbox is the API of a Netbox instance.
+type Netbox struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+	// Timeout of a lookup, defaults to 5s.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the fields of an instance are cached, defaults to 1h.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+}
+
+// Enrichment is an HTTP endpoint returning additional fields of alerts, see enrichment.Source.
+type Enrichment struct {
+	URL string `yaml:"url"`
+	// Fields are the fields of the responses added to the alerts, all if not set.
+	Fields []string `yaml:"fields"`
+	// Timeout of a lookup, defaults to 5s.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the fields of an alert are cached, defaults to 5m.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+}
+
+// Redaction replaces the matches of a regular expression in the values of annotations, see telegram.RedactionRule.
+type Redaction struct {
+	Regexp string `yaml:"regexp"`
+	// Replacement can refer to submatches like ${1}, defaults to [REDACTED].
+	Replacement string `yaml:"replacement"`
+}
+
+// TemplateOverride renders alerts with another template defined in the template files.
+// Alertname is a shorthand for the matcher alertname=<Alertname>.
+type TemplateOverride struct {
+	Alertname string   `yaml:"alertname"`
+	Matchers  []string `yaml:"matchers"`
+	Template  string   `yaml:"template"`
+	// DisablePreview turns off the previews of links in the alerts rendered with the template.
+	DisablePreview bool `yaml:"disablePreview"`
+}
+
+// Subscription is a chat subscribed by configuration instead of /start, see telegram.WithSubscriptions.
+type Subscription struct {
+	ChatID int64  `yaml:"chatID"`
+	Title  string `yaml:"title"`
+	// Matchers only send alerts with matching labels to the chat, e.g. team=sre.
+	Matchers []string `yaml:"matchers"`
+	// Muted are the usernames that aren't mentioned in the chat's alerts.
+	Muted []string `yaml:"muted"`
+}
+
+// Authorization lets users that aren't admins command the bot if they are members of a group
+// in the organization's directory, looked up with either LDAP or OAuth2.
+type Authorization struct {
+	// Identities map Telegram user IDs to the users in the directory.
+	Identities map[int]string `yaml:"identities"`
+	// CacheTTL is how long the membership of a user is cached, defaults to 5m.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	LDAP     *LDAP         `yaml:"ldap"`
+	OAuth2   *OAuth2       `yaml:"oauth2"`
+}
+
+// LDAP looks up users with the group in their memberOf attribute, see authz.LDAP.
+type LDAP struct {
+	// URL is either ldaps:// or ldap://, whose connections are upgraded with StartTLS.
+	URL string `yaml:"url"`
+	// CAFile verifies the certificate of the server instead of the system's CAs.
+	CAFile        string        `yaml:"caFile"`
+	BindDN        string        `yaml:"bindDN"`
+	BindPassword  string        `yaml:"bindPassword"`
+	BaseDN        string        `yaml:"baseDN"`
+	UserAttribute string        `yaml:"userAttribute"`
+	GroupDN       string        `yaml:"groupDN"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+// OAuth2 reads the groups claim of users from the identity provider, see authz.NewOAuth2.
+type OAuth2 struct {
+	TokenURL     string   `yaml:"tokenURL"`
+	ClientID     string   `yaml:"clientID"`
+	ClientSecret string   `yaml:"clientSecret"`
+	Scopes       []string `yaml:"scopes"`
+	// UserURL returns the claims of a user, {user} is replaced with the identity.
+	UserURL     string `yaml:"userURL"`
+	GroupsClaim string `yaml:"groupsClaim"`
+	Group       string `yaml:"group"`
+}
+
+// Labels hides labels from rendered messages, see telegram.WithLabelFilter.
+type Labels struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Load parses the configuration file at path.
+func Load(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Parse parses and validates the configuration, setting the defaults of unset fields.
+func Parse(content []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, err
+	}
+
+	if err := validateGroups(c.Groups); err != nil {
+		return nil, err
+	}
+	if err := validateAuthorization(c.Authorization); err != nil {
+		return nil, err
+	}
+	if err := validateSubscriptions(c.Subscriptions); err != nil {
+		return nil, err
+	}
+	if err := validateReminders(c.Reminders); err != nil {
+		return nil, err
+	}
+	if err := validateTemplates(c.Templates); err != nil {
+		return nil, err
+	}
+	if err := validateEnrichment(c.Enrichment); err != nil {
+		return nil, err
+	}
+	if err := validateInventory(c.Inventory); err != nil {
+		return nil, err
+	}
+	if err := validateRedaction(c.Redaction); err != nil {
+		return nil, err
+	}
+	if err := validateShadow(c.Shadow); err != nil {
+		return nil, err
+	}
+	if err := validateCanary(c.Canary); err != nil {
+		return nil, err
+	}
+	if err := validateAlertmanagers(c.Alertmanagers); err != nil {
+		return nil, err
+	}
+	if err := validateOutputs(c.Outputs); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for i := range c.Tenants {
+		t := &c.Tenants[i]
+		if !validName.MatchString(t.Name) {
+			return nil, fmt.Errorf("tenant name %q must only contain letters, digits, _ and -", t.Name)
+		}
+		if t.Name == DefaultTenant {
+			return nil, fmt.Errorf("tenant name %q is reserved for the bot configured with flags", t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenant %s is configured more than once", t.Name)
+		}
+		names[t.Name] = true
+
+		if t.Token == "" {
+			return nil, fmt.Errorf("tenant %s has no token", t.Name)
+		}
+		if len(t.Admins) == 0 {
+			return nil, fmt.Errorf("tenant %s has no admins", t.Name)
+		}
+		if t.StorePrefix == "" {
+			t.StorePrefix = "tenants/" + t.Name
+		}
+		if err := validateGroups(t.Groups); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateAuthorization(t.Authorization); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateSubscriptions(t.Subscriptions); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateReminders(t.Reminders); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateTemplates(t.Templates); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateEnrichment(t.Enrichment); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateInventory(t.Inventory); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateRedaction(t.Redaction); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateShadow(t.Shadow); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateCanary(t.Canary); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateAlertmanagers(t.Alertmanagers); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		if err := validateOutputs(t.Outputs); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+	}
+
+	// Forward rules can only be validated once the names of all tenants are known.
+	names[DefaultTenant] = true
+	if err := validateForward(c.Forward, DefaultTenant, names); err != nil {
+		return nil, err
+	}
+	for _, t := range c.Tenants {
+		if err := validateForward(t.Forward, t.Name, names); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func validateGroups(groups map[string][]int64) error {
+	for name, chats := range groups {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("group name %q must only contain letters, digits, _ and -", name)
+		}
+		if len(chats) == 0 {
+			return fmt.Errorf("group %s has no chats", name)
+		}
+	}
+	return nil
+}
+
+func validateAuthorization(a *Authorization) error {
+	if a == nil {
+		return nil
+	}
+	if len(a.Identities) == 0 {
+		return fmt.Errorf("authorization has no identities")
+	}
+	if (a.LDAP == nil) == (a.OAuth2 == nil) {
+		return fmt.Errorf("authorization needs either ldap or oauth2")
+	}
+	if a.CacheTTL == 0 {
+		a.CacheTTL = 5 * time.Minute
+	}
+	if a.LDAP != nil && (a.LDAP.URL == "" || a.LDAP.BaseDN == "" || a.LDAP.GroupDN == "") {
+		return fmt.Errorf("ldap authorization needs url, baseDN and groupDN")
+	}
+	if a.LDAP != nil {
+		if u, err := url.Parse(a.LDAP.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") {
+			return fmt.Errorf("ldap url %q needs to start with ldap:// or ldaps://", a.LDAP.URL)
+		}
+	}
+	if a.OAuth2 != nil && (a.OAuth2.TokenURL == "" || a.OAuth2.UserURL == "" || a.OAuth2.Group == "") {
+		return fmt.Errorf("oauth2 authorization needs tokenURL, userURL and group")
+	}
+	return nil
+}
+
+func validateSubscriptions(subscriptions []Subscription) error {
+	chats := map[int64]bool{}
+	for _, s := range subscriptions {
+		if s.ChatID == 0 {
+			return fmt.Errorf("subscription has no chatID")
+		}
+		if chats[s.ChatID] {
+			return fmt.Errorf("chat %d is subscribed more than once", s.ChatID)
+		}
+		chats[s.ChatID] = true
+		for _, m := range s.Matchers {
+			if _, err := alertmanager.ParseMatcher(m); err != nil {
+				return fmt.Errorf("subscription of chat %d: %w", s.ChatID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateReminders(reminders map[string][]time.Duration) error {
+	for severity, durations := range reminders {
+		if len(durations) == 0 {
+			return fmt.Errorf("reminders of %s have no durations", severity)
+		}
+		for _, d := range durations {
+			if d <= 0 {
+				return fmt.Errorf("reminders of %s have a non-positive duration %s", severity, d)
+			}
+		}
+	}
+	return nil
+}
+
+func validateTemplates(templates []TemplateOverride) error {
+	for i, t := range templates {
+		if t.Template == "" {
+			return fmt.Errorf("template override %d has no template", i)
+		}
+		if t.Alertname == "" && len(t.Matchers) == 0 {
+			return fmt.Errorf("template override %s needs an alertname or matchers", t.Template)
+		}
+		for _, m := range t.Matchers {
+			if _, err := alertmanager.ParseMatcher(m); err != nil {
+				return fmt.Errorf("template override %s: %w", t.Template, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateEnrichment(enrichment []Enrichment) error {
+	for i := range enrichment {
+		e := &enrichment[i]
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("enrichment %d needs an http or https url", i)
+		}
+		if e.Timeout < 0 || e.CacheTTL < 0 {
+			return fmt.Errorf("enrichment %s has a negative timeout or cacheTTL", e.URL)
+		}
+		if e.Timeout == 0 {
+			e.Timeout = 5 * time.Second
+		}
+		if e.CacheTTL == 0 {
+			e.CacheTTL = 5 * time.Minute
+		}
+	}
+	return nil
+}
+
+func validateInventory(i *Inventory) error {
+	if i == nil {
+		return nil
+	}
+	if i.File == "" && i.Netbox == nil {
+		return fmt.Errorf("inventory needs a file or netbox")
+	}
+	if i.Netbox == nil {
+		return nil
+	}
+	if i.Netbox.URL == "" {
+		return fmt.Errorf("netbox inventory needs a url")
+	}
+	if i.Netbox.Timeout == 0 {
+		i.Netbox.Timeout = 5 * time.Second
+	}
+	if i.Netbox.CacheTTL == 0 {
+		i.Netbox.CacheTTL = time.Hour
+	}
+	return nil
+}
+
+func validateRedaction(redaction []Redaction) error {
+	for i := range redaction {
+		r := &redaction[i]
+		if r.Regexp == "" {
+			return fmt.Errorf("redaction %d has no regexp", i)
+		}
+		if _, err := regexp.Compile(r.Regexp); err != nil {
+			return fmt.Errorf("redaction %d: %w", i, err)
+		}
+		if r.Replacement == "" {
+			r.Replacement = "[REDACTED]"
+		}
+	}
+	return nil
+}
+
+func validateAlertmanagers(alertmanagers []Alertmanager) error {
+	names := map[string]bool{}
+	for _, a := range alertmanagers {
+		if !validName.MatchString(a.Name) {
+			return fmt.Errorf("alertmanager name %q must only contain letters, digits, _ and -", a.Name)
+		}
+		if a.Name == "default" {
+			return fmt.Errorf("alertmanager name %q is reserved for --alertmanager.url", a.Name)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("alertmanager %s is configured more than once", a.Name)
+		}
+		names[a.Name] = true
+
+		for _, raw := range append([]string{a.URL}, a.Peers...) {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("alertmanager %s needs http or https urls", a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func validateOutputs(outputs []Output) error {
+	names := map[string]bool{}
+	for i := range outputs {
+		o := &outputs[i]
+		if !validName.MatchString(o.Name) {
+			return fmt.Errorf("output name %q must only contain letters, digits, _ and -", o.Name)
+		}
+		if names[o.Name] {
+			return fmt.Errorf("output %s is configured more than once", o.Name)
+		}
+		names[o.Name] = true
+
+		switch o.Type {
+		case "webex":
+			if o.Token == "" || o.Room == "" {
+				return fmt.Errorf("webex output %s needs token and room", o.Name)
+			}
+			if o.URL == "" {
+				o.URL = "https://webexapis.com"
+			}
+		case "mattermost":
+			if o.URL == "" {
+				return fmt.Errorf("mattermost output %s needs the url of an incoming webhook", o.Name)
+			}
+		case "email":
+			if _, _, err := net.SplitHostPort(o.Smarthost); err != nil {
+				return fmt.Errorf("email output %s needs a smarthost like smtp.example.com:587", o.Name)
+			}
+			if o.From == "" || len(o.To) == 0 {
+				return fmt.Errorf("email output %s needs from and to", o.Name)
+			}
+		default:
+			return fmt.Errorf("output %s has the unknown type %q, must be webex, mattermost or email", o.Name, o.Type)
+		}
+		if o.Type != "email" {
+			if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("output %s needs an http or https url", o.Name)
+			}
+		}
+		if c := o.Commands; c != nil {
+			switch {
+			case o.Type == "email":
+				return fmt.Errorf("email output %s can't receive commands", o.Name)
+			case o.Type == "webex" && c.Secret == "":
+				return fmt.Errorf("webex output %s needs the secret of the webhook to receive commands", o.Name)
+			case o.Type == "mattermost" && c.Token == "":
+				return fmt.Errorf("mattermost output %s needs the token of the slash command or outgoing webhook to receive commands", o.Name)
+			case c.Chat == 0:
+				return fmt.Errorf("output %s needs the telegram chat its commands are handled for", o.Name)
+			}
+		}
+		if o.Timeout == 0 {
+			o.Timeout = 10 * time.Second
+		}
+	}
+	return nil
+}
+
+func validateForward(forward []Forward, tenant string, tenants map[string]bool) error {
+	for i, f := range forward {
+		if len(f.Matchers) == 0 {
+			return fmt.Errorf("forward %d has no matchers", i)
+		}
+		for _, m := range f.Matchers {
+			if _, err := alertmanager.ParseMatcher(m); err != nil {
+				return fmt.Errorf("forward %d: %w", i, err)
+			}
+		}
+		if f.Tenant == tenant {
+			return fmt.Errorf("forward %d can't forward to its own tenant", i)
+		}
+		if !tenants[f.Tenant] {
+			return fmt.Errorf("forward %d has an unknown tenant %q", i, f.Tenant)
+		}
+	}
+	return nil
+}
+
+func validateShadow(s *Shadow) error {
+	if s == nil {
+		return nil
+	}
+	if s.ChatID == 0 {
+		return fmt.Errorf("shadow has no chatID")
+	}
+	if err := validateTemplates(s.Templates); err != nil {
+		return fmt.Errorf("shadow: %w", err)
+	}
+	return nil
+}
+
+func validateCanary(c *Canary) error {
+	if c == nil {
+		return nil
+	}
+	if len(c.TemplatePaths) == 0 && len(c.Templates) == 0 && len(c.Labels.Allow) == 0 && len(c.Labels.Deny) == 0 {
+		return fmt.Errorf("canary doesn't change templates or labels")
+	}
+	if err := validateTemplates(c.Templates); err != nil {
+		return fmt.Errorf("canary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,466 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(`
+groups:
+  sre: [123, -1234]
+tenants:
+- name: team-a
+  token: "123:abc"
+  admins: [1, 2]
+  labels:
+    deny: ["__replica__"]
+- name: team-b
+  token: "456:def"
+  admins: [3]
+  templatePaths: [/templates/team-b.tmpl]
+  storePrefix: team-b
+  groups:
+    db-team: [-5678]
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Config{Groups: map[string][]int64{"sre": {123, -1234}}, Tenants: []Tenant{{
+		Name:        "team-a",
+		Token:       "123:abc",
+		Admins:      []int{1, 2},
+		StorePrefix: "tenants/team-a",
+		Labels:      Labels{Deny: []string{"__replica__"}},
+	}, {
+		Name:          "team-b",
+		Token:         "456:def",
+		Admins:        []int{3},
+		TemplatePaths: []string{"/templates/team-b.tmpl"},
+		StorePrefix:   "team-b",
+		Groups:        map[string][]int64{"db-team": {-5678}},
+	}}}, c)
+}
+
+func TestParseAuthorization(t *testing.T) {
+	c, err := Parse([]byte(`
+authorization:
+  identities:
+    123: alice
+  ldap:
+    url: ldaps://ldap.example.com
+    baseDN: ou=people,dc=example,dc=com
+    groupDN: cn=sre,ou=groups,dc=example,dc=com
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Authorization{
+		Identities: map[int]string{123: "alice"},
+		CacheTTL:   5 * time.Minute,
+		LDAP: &LDAP{
+			URL:     "ldaps://ldap.example.com",
+			BaseDN:  "ou=people,dc=example,dc=com",
+			GroupDN: "cn=sre,ou=groups,dc=example,dc=com",
+		},
+	}, c.Authorization)
+}
+
+func TestParseReminders(t *testing.T) {
+	c, err := Parse([]byte(`
+reminders:
+  default: [30m, 2h, 6h]
+  critical: [15m, 1h]
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]time.Duration{
+		"default":  {30 * time.Minute, 2 * time.Hour, 6 * time.Hour},
+		"critical": {15 * time.Minute, time.Hour},
+	}, c.Reminders)
+}
+
+func TestParseCommands(t *testing.T) {
+	c, err := Parse([]byte(`
+commands:
+  /silences: [sre-admins]
+  /alerts: [all-subscribed]
+roles:
+  sre-admins: [123456, "@alice", authorized]
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"/silences": {"sre-admins"}, "/alerts": {"all-subscribed"}}, c.Commands)
+	require.Equal(t, map[string][]string{"sre-admins": {"123456", "@alice", "authorized"}}, c.Roles)
+}
+
+func TestParseAliases(t *testing.T) {
+	c, err := Parse([]byte(`
+aliases:
+  /s: /silences
+tenants:
+- name: a
+  token: abc
+  admins: [1]
+  aliases:
+    /alarme: /alerts
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"/s": "/silences"}, c.Aliases)
+	require.Equal(t, map[string]string{"/alarme": "/alerts"}, c.Tenants[0].Aliases)
+}
+
+func TestParseTemplates(t *testing.T) {
+	c, err := Parse([]byte(`
+templates:
+- alertname: KubePodCrashLooping
+  template: telegram.compact
+- matchers: ['slo=~".+"', severity=page]
+  template: telegram.slo
+`))
+	require.NoError(t, err)
+	require.Equal(t, []TemplateOverride{
+		{Alertname: "KubePodCrashLooping", Template: "telegram.compact"},
+		{Matchers: []string{`slo=~".+"`, "severity=page"}, Template: "telegram.slo"},
+	}, c.Templates)
+}
+
+func TestParseEnrichment(t *testing.T) {
+	c, err := Parse([]byte(`
+enrichment:
+- url: http://cmdb/api/enrich
+  fields: [owner, tier]
+- url: https://ownership/api/alerts
+  timeout: 1s
+  cacheTTL: 1h
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Enrichment{
+		{URL: "http://cmdb/api/enrich", Fields: []string{"owner", "tier"}, Timeout: 5 * time.Second, CacheTTL: 5 * time.Minute},
+		{URL: "https://ownership/api/alerts", Timeout: time.Second, CacheTTL: time.Hour},
+	}, c.Enrichment)
+}
+
+func TestParseInventory(t *testing.T) {
+	c, err := Parse([]byte(`
+inventory:
+  file: /etc/alertmanager-bot/inventory.yaml
+  netbox:
+    url: https://netbox.example.com
+    token: secret
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Inventory{
+		File:   "/etc/alertmanager-bot/inventory.yaml",
+		Netbox: &Netbox{URL: "https://netbox.example.com", Token: "secret", Timeout: 5 * time.Second, CacheTTL: time.Hour},
+	}, c.Inventory)
+}
+
+func TestParseRedaction(t *testing.T) {
+	c, err := Parse([]byte(`
+redaction:
+- regexp: (password|token)=\S+
+  replacement: ${1}=***
+- regexp: '[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}'
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Redaction{
+		{Regexp: `(password|token)=\S+`, Replacement: "${1}=***"},
+		{Regexp: `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`, Replacement: "[REDACTED]"},
+	}, c.Redaction)
+}
+
+func TestParseAlertmanagers(t *testing.T) {
+	c, err := Parse([]byte(`
+alertmanagers:
+- name: prod-eu
+  url: http://alertmanager-0.eu:9093
+  peers: [http://alertmanager-1.eu:9093]
+- name: prod-us
+  url: https://alertmanager.us.example.com
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Alertmanager{
+		{Name: "prod-eu", URL: "http://alertmanager-0.eu:9093", Peers: []string{"http://alertmanager-1.eu:9093"}},
+		{Name: "prod-us", URL: "https://alertmanager.us.example.com"},
+	}, c.Alertmanagers)
+}
+
+func TestParseForward(t *testing.T) {
+	c, err := Parse([]byte(`
+forward:
+- matchers: [severity="critical"]
+  tenant: critical
+tenants:
+- name: critical
+  token: "123:abc"
+  admins: [1]
+  forward:
+  - matchers: [severity!="critical"]
+    tenant: telegram
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Forward{{Matchers: []string{`severity="critical"`}, Tenant: "critical"}}, c.Forward)
+	require.Equal(t, []Forward{{Matchers: []string{`severity!="critical"`}, Tenant: "telegram"}}, c.Tenants[0].Forward)
+}
+
+func TestParseOutputs(t *testing.T) {
+	c, err := Parse([]byte(`
+outputs:
+- name: webex
+  type: webex
+  token: s3cr3t
+  room: Y2lzY29zcGFyazovL3VzL1JPT00v
+  commands:
+    secret: w3bh00k
+    chat: -1234
+    users: [alice@example.com]
+tenants:
+- name: db
+  token: "123:abc"
+  admins: [1]
+  outputs:
+  - name: mattermost
+    type: mattermost
+    url: https://mattermost.example.com/hooks/xyz
+    channel: db-alerts
+    timeout: 5s
+  - name: email
+    type: email
+    smarthost: smtp.example.com:587
+    username: alertmanager
+    password: s3cr3t
+    from: alertmanager@example.com
+    to: [db@example.com]
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Output{{
+		Name:    "webex",
+		Type:    "webex",
+		URL:     "https://webexapis.com",
+		Token:   "s3cr3t",
+		Room:    "Y2lzY29zcGFyazovL3VzL1JPT00v",
+		Timeout: 10 * time.Second,
+		Commands: &OutputCommands{
+			Secret: "w3bh00k",
+			Chat:   -1234,
+			Users:  []string{"alice@example.com"},
+		},
+	}}, c.Outputs)
+	require.Equal(t, []Output{{
+		Name:    "mattermost",
+		Type:    "mattermost",
+		URL:     "https://mattermost.example.com/hooks/xyz",
+		Channel: "db-alerts",
+		Timeout: 5 * time.Second,
+	}, {
+		Name:      "email",
+		Type:      "email",
+		Username:  "alertmanager",
+		Smarthost: "smtp.example.com:587",
+		Password:  "s3cr3t",
+		From:      "alertmanager@example.com",
+		To:        []string{"db@example.com"},
+		Timeout:   10 * time.Second,
+	}}, c.Tenants[0].Outputs)
+}
+
+func TestParseShadow(t *testing.T) {
+	c, err := Parse([]byte(`
+shadow:
+  chatID: -1234
+  templatePaths: [/templates/new.tmpl]
+  templates:
+  - alertname: KubePodCrashLooping
+    template: telegram.compact
+  labels:
+    deny: [prometheus, pod_template_hash]
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Shadow{
+		ChatID:        -1234,
+		TemplatePaths: []string{"/templates/new.tmpl"},
+		Templates:     []TemplateOverride{{Alertname: "KubePodCrashLooping", Template: "telegram.compact"}},
+		Labels:        Labels{Deny: []string{"prometheus", "pod_template_hash"}},
+	}, c.Shadow)
+}
+
+func TestParseCanary(t *testing.T) {
+	c, err := Parse([]byte(`
+canary:
+  templates:
+  - alertname: KubePodCrashLooping
+    template: telegram.compact
+  labels:
+    deny: [prometheus]
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Canary{
+		Templates: []TemplateOverride{{Alertname: "KubePodCrashLooping", Template: "telegram.compact"}},
+		Labels:    Labels{Deny: []string{"prometheus"}},
+	}, c.Canary)
+}
+
+func TestParseInvalid(t *testing.T) {
+	testcases := []struct {
+		name    string
+		content string
+		err     string
+	}{{
+		name:    "UnknownField",
+		content: "tenants:\n- name: a\n  tokn: abc\n",
+		err:     "field tokn not found",
+	}, {
+		name:    "InvalidName",
+		content: "tenants:\n- name: team/a\n  token: abc\n  admins: [1]\n",
+		err:     `tenant name "team/a" must only contain letters, digits, _ and -`,
+	}, {
+		name:    "ReservedName",
+		content: "tenants:\n- name: telegram\n  token: abc\n  admins: [1]\n",
+		err:     `tenant name "telegram" is reserved for the bot configured with flags`,
+	}, {
+		name:    "DuplicateName",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n- name: a\n  token: def\n  admins: [2]\n",
+		err:     "tenant a is configured more than once",
+	}, {
+		name:    "NoToken",
+		content: "tenants:\n- name: a\n  admins: [1]\n",
+		err:     "tenant a has no token",
+	}, {
+		name:    "InvalidGroupName",
+		content: "groups:\n  team a: [1]\n",
+		err:     `group name "team a" must only contain letters, digits, _ and -`,
+	}, {
+		name:    "EmptyTenantGroup",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  groups:\n    sre: []\n",
+		err:     "tenant a: group sre has no chats",
+	}, {
+		name:    "NoAdmins",
+		content: "tenants:\n- name: a\n  token: abc\n",
+		err:     "tenant a has no admins",
+	}, {
+		name:    "AuthorizationWithoutBackend",
+		content: "authorization:\n  identities:\n    123: alice\n",
+		err:     "authorization needs either ldap or oauth2",
+	}, {
+		name:    "AuthorizationWithoutIdentities",
+		content: "authorization:\n  ldap:\n    url: ldap://localhost\n",
+		err:     "authorization has no identities",
+	}, {
+		name:    "AuthorizationWithoutLDAPScheme",
+		content: "authorization:\n  identities:\n    123: alice\n  ldap:\n    url: ldap.example.com\n    baseDN: dc=example\n    groupDN: cn=sre\n",
+		err:     `ldap url "ldap.example.com" needs to start with ldap:// or ldaps://`,
+	}, {
+		name:    "RemindersWithoutDurations",
+		content: "reminders:\n  critical: []\n",
+		err:     "reminders of critical have no durations",
+	}, {
+		name:    "SubscriptionWithoutChat",
+		content: "subscriptions:\n- title: sre\n",
+		err:     "subscription has no chatID",
+	}, {
+		name:    "DuplicateSubscription",
+		content: "subscriptions:\n- chatID: -1234\n- chatID: -1234\n",
+		err:     "chat -1234 is subscribed more than once",
+	}, {
+		name:    "InvalidSubscriptionMatcher",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  subscriptions:\n  - chatID: -1234\n    matchers: [critical]\n",
+		err:     `tenant a: subscription of chat -1234: invalid matcher "critical": operator is missing, expected one of =, !=, =~, !~`,
+	}, {
+		name:    "TemplateWithoutMatchers",
+		content: "templates:\n- template: telegram.compact\n",
+		err:     "template override telegram.compact needs an alertname or matchers",
+	}, {
+		name:    "TemplateWithoutName",
+		content: "templates:\n- alertname: KubePodCrashLooping\n",
+		err:     "template override 0 has no template",
+	}, {
+		name:    "EnrichmentWithoutURL",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  enrichment:\n  - fields: [owner]\n",
+		err:     "tenant a: enrichment 0 needs an http or https url",
+	}, {
+		name:    "EmptyInventory",
+		content: "inventory: {}\n",
+		err:     "inventory needs a file or netbox",
+	}, {
+		name:    "NetboxWithoutURL",
+		content: "inventory:\n  netbox:\n    token: secret\n",
+		err:     "netbox inventory needs a url",
+	}, {
+		name:    "RedactionWithoutRegexp",
+		content: "redaction:\n- replacement: '***'\n",
+		err:     "redaction 0 has no regexp",
+	}, {
+		name:    "InvalidRedactionRegexp",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  redaction:\n  - regexp: '(token'\n",
+		err:     "tenant a: redaction 0: error parsing regexp: missing closing ): `(token`",
+	}, {
+		name:    "AlertmanagerReservedName",
+		content: "alertmanagers:\n- name: default\n  url: http://alertmanager:9093\n",
+		err:     "alertmanager name \"default\" is reserved for --alertmanager.url",
+	}, {
+		name:    "AlertmanagerInvalidPeer",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  alertmanagers:\n  - name: prod-eu\n    url: http://alertmanager-0:9093\n    peers: [alertmanager-1:9093]\n",
+		err:     "tenant a: alertmanager prod-eu needs http or https urls",
+	}, {
+		name:    "OutputUnknownType",
+		content: "outputs:\n- name: slack\n  type: slack\n  url: https://hooks.slack.com/services/xyz\n",
+		err:     "output slack has the unknown type \"slack\", must be webex, mattermost or email",
+	}, {
+		name:    "OutputWebexWithoutRoom",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  outputs:\n  - name: webex\n    type: webex\n    token: s3cr3t\n",
+		err:     "tenant a: webex output webex needs token and room",
+	}, {
+		name:    "OutputMattermostWithoutURL",
+		content: "outputs:\n- name: mattermost\n  type: mattermost\n",
+		err:     "mattermost output mattermost needs the url of an incoming webhook",
+	}, {
+		name:    "OutputEmailWithoutSmarthostPort",
+		content: "outputs:\n- name: ops\n  type: email\n  smarthost: smtp.example.com\n  from: alertmanager@example.com\n  to: [ops@example.com]\n",
+		err:     "email output ops needs a smarthost like smtp.example.com:587",
+	}, {
+		name:    "OutputEmailWithoutTo",
+		content: "outputs:\n- name: ops\n  type: email\n  smarthost: smtp.example.com:587\n  from: alertmanager@example.com\n",
+		err:     "email output ops needs from and to",
+	}, {
+		name:    "OutputMattermostCommandsWithoutToken",
+		content: "outputs:\n- name: mattermost\n  type: mattermost\n  url: https://mattermost.example.com/hooks/xyz\n  commands:\n    chat: -1234\n",
+		err:     "mattermost output mattermost needs the token of the slash command or outgoing webhook to receive commands",
+	}, {
+		name:    "OutputCommandsWithoutChat",
+		content: "outputs:\n- name: webex\n  type: webex\n  token: s3cr3t\n  room: abc\n  commands:\n    secret: w3bh00k\n",
+		err:     "output webex needs the telegram chat its commands are handled for",
+	}, {
+		name:    "ForwardWithoutMatchers",
+		content: "forward:\n- tenant: critical\ntenants:\n- name: critical\n  token: abc\n  admins: [1]\n",
+		err:     "forward 0 has no matchers",
+	}, {
+		name:    "ForwardToUnknownTenant",
+		content: "forward:\n- matchers: [severity=critical]\n  tenant: critical\n",
+		err:     "forward 0 has an unknown tenant \"critical\"",
+	}, {
+		name:    "ForwardToOwnTenant",
+		content: "tenants:\n- name: a\n  token: abc\n  admins: [1]\n  forward:\n  - matchers: [severity=critical]\n    tenant: a\n",
+		err:     "tenant a: forward 0 can't forward to its own tenant",
+	}, {
+		name:    "ShadowWithoutChat",
+		content: "shadow:\n  templatePaths: [/templates/new.tmpl]\n",
+		err:     "shadow has no chatID",
+	}, {
+		name:    "ShadowTemplateWithoutName",
+		content: "shadow:\n  chatID: -1234\n  templates:\n  - alertname: KubePodCrashLooping\n",
+		err:     "shadow: template override 0 has no template",
+	}, {
+		name:    "CanaryWithoutChanges",
+		content: "canary: {}\n",
+		err:     "canary doesn't change templates or labels",
+	}, {
+		name:    "CanaryTemplateWithoutName",
+		content: "canary:\n  templates:\n  - alertname: KubePodCrashLooping\n",
+		err:     "canary: template override 0 has no template",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.content))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
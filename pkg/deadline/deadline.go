@@ -0,0 +1,127 @@
+// Package deadline fails the operations of a libkv store that take longer than a timeout,
+// so that a hanging Consul or etcd doesn't block the bot forever.
+package deadline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/libkv/store"
+)
+
+// ErrTimeout is returned if an operation of the store didn't finish within the timeout.
+// The operation keeps running in the background and its result is dropped.
+var ErrTimeout = errors.New("store operation timed out")
+
+// Store limits the operations of the wrapped store to the timeout.
+// Watches and locks aren't limited, they're meant to block.
+type Store struct {
+	store.Store
+	timeout time.Duration
+}
+
+// NewStore limits the operations of the store to the timeout.
+func NewStore(kv store.Store, timeout time.Duration) (*Store, error) {
+	if timeout <= 0 {
+		return nil, errors.New("the timeout of the store must be positive")
+	}
+	return &Store{Store: kv, timeout: timeout}, nil
+}
+
+// do runs the operation and returns ErrTimeout if it doesn't finish within the timeout.
+func (s *Store) do(op, key string, f func() error) error {
+	// The channel is buffered, so that the operation can finish after its caller gave up.
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%s %s: %w after %s", op, key, ErrTimeout, s.timeout)
+	}
+}
+
+func (s *Store) Put(key string, value []byte, options *store.WriteOptions) error {
+	return s.do("put", key, func() error {
+		return s.Store.Put(key, value, options)
+	})
+}
+
+func (s *Store) Get(key string) (*store.KVPair, error) {
+	var pair *store.KVPair
+	err := s.do("get", key, func() (err error) {
+		pair, err = s.Store.Get(key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *Store) Delete(key string) error {
+	return s.do("delete", key, func() error {
+		return s.Store.Delete(key)
+	})
+}
+
+func (s *Store) Exists(key string) (bool, error) {
+	var exists bool
+	err := s.do("exists", key, func() (err error) {
+		exists, err = s.Store.Exists(key)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (s *Store) List(directory string) ([]*store.KVPair, error) {
+	var pairs []*store.KVPair
+	err := s.do("list", directory, func() (err error) {
+		pairs, err = s.Store.List(directory)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+func (s *Store) DeleteTree(directory string) error {
+	return s.do("delete tree", directory, func() error {
+		return s.Store.DeleteTree(directory)
+	})
+}
+
+func (s *Store) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	var (
+		ok   bool
+		pair *store.KVPair
+	)
+	err := s.do("atomic put", key, func() (err error) {
+		ok, pair, err = s.Store.AtomicPut(key, value, previous, options)
+		return err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return ok, pair, nil
+}
+
+func (s *Store) AtomicDelete(key string, previous *store.KVPair) (bool, error) {
+	var ok bool
+	err := s.do("atomic delete", key, func() (err error) {
+		ok, err = s.Store.AtomicDelete(key, previous)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
@@ -0,0 +1,48 @@
+package deadline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingStore blocks getting the key "hang" until release is closed.
+type hangingStore struct {
+	store.Store
+	release chan struct{}
+}
+
+func (s *hangingStore) Get(key string) (*store.KVPair, error) {
+	if key == "hang" {
+		<-s.release
+	}
+	if key == "missing" {
+		return nil, store.ErrKeyNotFound
+	}
+	return &store.KVPair{Key: key, Value: []byte(`{}`)}, nil
+}
+
+func TestStore(t *testing.T) {
+	kv := &hangingStore{release: make(chan struct{})}
+	defer close(kv.release)
+
+	s, err := NewStore(kv, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	pair, err := s.Get("telegram/chats/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{}`), pair.Value)
+
+	_, err = s.Get("missing")
+	require.True(t, errors.Is(err, store.ErrKeyNotFound))
+
+	_, err = s.Get("hang")
+	require.True(t, errors.Is(err, ErrTimeout))
+	require.EqualError(t, err, "get hang: store operation timed out after 10ms")
+
+	_, err = NewStore(kv, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,207 @@
+// Package encryption encrypts the values of a libkv store at rest with AES-GCM,
+// as chats and alerts may be sensitive in regulated environments.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/libkv/store"
+)
+
+// encryptedPrefix marks encrypted values, it can't start the JSON of unencrypted values.
+// The nonce and ciphertext following it are base64 encoded, as etcd carries the values as JSON strings,
+// which would replace the bytes that aren't valid UTF-8.
+var encryptedPrefix = []byte("aes-gcm:")
+
+// binaryPrefix marks values encrypted before they were base64 encoded, which are still read.
+var binaryPrefix = []byte("\x00aes-gcm:")
+
+// Store encrypts the values put into the wrapped store and decrypts them when they are read.
+// Keys aren't encrypted, so that they can still be listed by their prefix.
+// Values written before encryption was turned on are read as they are and encrypted when they are written the next time.
+type Store struct {
+	store.Store
+	aead cipher.AEAD
+}
+
+// NewStore encrypts the values of the store with the AES key, which has to be 16, 24 or 32 bytes long.
+func NewStore(kv store.Store, key []byte) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Store: kv, aead: aead}, nil
+}
+
+// LoadKey reads the base64 encoded key from the file.
+func LoadKey(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return nil, fmt.Errorf("the key in %s isn't base64 encoded: %w", path, err)
+	}
+	return key, nil
+}
+
+// encrypt the value with a random nonce, the key is authenticated with it so that values can't be swapped between keys.
+func (s *Store) encrypt(key string, value []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := s.aead.Seal(nonce, nonce, value, []byte(key))
+	out := make([]byte, len(encryptedPrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, encryptedPrefix)
+	base64.StdEncoding.Encode(out[len(encryptedPrefix):], sealed)
+	return out, nil
+}
+
+// decrypt the pair's value in place, unencrypted values are kept as they are.
+func (s *Store) decrypt(pair *store.KVPair) error {
+	if pair == nil {
+		return nil
+	}
+	var sealed []byte
+	switch {
+	case bytes.HasPrefix(pair.Value, encryptedPrefix):
+		encoded := pair.Value[len(encryptedPrefix):]
+		sealed = make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+		n, err := base64.StdEncoding.Decode(sealed, encoded)
+		if err != nil {
+			return fmt.Errorf("the encrypted value of %s isn't base64 encoded: %w", pair.Key, err)
+		}
+		sealed = sealed[:n]
+	case bytes.HasPrefix(pair.Value, binaryPrefix):
+		sealed = pair.Value[len(binaryPrefix):]
+	default:
+		return nil
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return fmt.Errorf("the encrypted value of %s is too short", pair.Key)
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	value, err := s.aead.Open(nil, nonce, ciphertext, []byte(normalize(pair.Key)))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s, was it encrypted with another key? %w", pair.Key, err)
+	}
+	pair.Value = value
+	return nil
+}
+
+// normalize the key like the store backends do, as they return keys with or without a leading slash.
+func normalize(key string) string {
+	for len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+	return key
+}
+
+// Put encrypts the value.
+func (s *Store) Put(key string, value []byte, options *store.WriteOptions) error {
+	encrypted, err := s.encrypt(normalize(key), value)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(key, encrypted, options)
+}
+
+// Get decrypts the value.
+func (s *Store) Get(key string) (*store.KVPair, error) {
+	pair, err := s.Store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return pair, s.decrypt(pair)
+}
+
+// List decrypts the values.
+func (s *Store) List(prefix string) ([]*store.KVPair, error) {
+	pairs, err := s.Store.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		if err := s.decrypt(pair); err != nil {
+			return nil, err
+		}
+	}
+	return pairs, nil
+}
+
+// AtomicPut encrypts the value.
+func (s *Store) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	encrypted, err := s.encrypt(normalize(key), value)
+	if err != nil {
+		return false, nil, err
+	}
+	ok, pair, err := s.Store.AtomicPut(key, encrypted, previous, options)
+	if err != nil {
+		return ok, nil, err
+	}
+	if pair != nil {
+		pair.Value = value
+	}
+	return ok, pair, nil
+}
+
+// Watch decrypts the watched values. The watch ends if a value can't be decrypted.
+func (s *Store) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
+	events, err := s.Store.Watch(key, stopCh)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *store.KVPair)
+	go func() {
+		defer close(out)
+		for pair := range events {
+			if err := s.decrypt(pair); err != nil {
+				return
+			}
+			select {
+			case out <- pair:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// WatchTree decrypts the watched values. The watch ends if a value can't be decrypted.
+func (s *Store) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	events, err := s.Store.WatchTree(directory, stopCh)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan []*store.KVPair)
+	go func() {
+		defer close(out)
+		for pairs := range events {
+			for _, pair := range pairs {
+				if err := s.decrypt(pair); err != nil {
+					return
+				}
+			}
+			select {
+			case out <- pairs:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/docker/libkv/store"
+	"github.com/metalmatze/alertmanager-bot/pkg/kvtest"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+func TestStore(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+
+	s, err := NewStore(kv, testKey)
+	require.NoError(t, err)
+
+	// Values written before encryption are read as they are.
+	pair, err := s.Get("telegram/chats/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":1}`), pair.Value)
+
+	require.NoError(t, s.Put("telegram/chats/2", []byte(`{"id":2,"title":"sre"}`), nil))
+	raw, err := kv.Get("telegram/chats/2")
+	require.NoError(t, err)
+	require.NotContains(t, string(raw.Value), "sre")
+	require.True(t, utf8.Valid(raw.Value), "encrypted values are valid UTF-8 for etcd")
+
+	pairs, err := s.List("telegram/chats")
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	require.Equal(t, []byte(`{"id":1}`), pairs[0].Value)
+	require.Equal(t, []byte(`{"id":2,"title":"sre"}`), pairs[1].Value)
+
+	// Values can't be moved to other keys.
+	require.NoError(t, kv.Put("telegram/chats/3", raw.Value, nil))
+	_, err = s.Get("telegram/chats/3")
+	require.Error(t, err)
+
+	other, err := NewStore(kv, bytes.Repeat([]byte{0x23}, 32))
+	require.NoError(t, err)
+	_, err = other.Get("telegram/chats/2")
+	require.EqualError(t, err, "failed to decrypt telegram/chats/2, was it encrypted with another key? cipher: message authentication failed")
+
+	// Values encrypted before they were base64 encoded are still read.
+	nonce := make([]byte, s.aead.NonceSize())
+	legacy := append(append([]byte("\x00aes-gcm:"), nonce...), s.aead.Seal(nil, nonce, []byte(`{"id":4}`), []byte("telegram/chats/4"))...)
+	require.NoError(t, kv.Put("telegram/chats/4", legacy, nil))
+	pair, err = s.Get("telegram/chats/4")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":4}`), pair.Value)
+
+	_, err = NewStore(kv, []byte("short"))
+	require.EqualError(t, err, "crypto/aes: invalid key size 5")
+}
+
+func TestLoadKey(t *testing.T) {
+	f, err := ioutil.TempFile("", "alertmanager-bot-key")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(testKey) + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	key, err := LoadKey(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, testKey, key)
+}
+
+// watchingStore sends the pairs below the watched directory once per WatchTree.
+type watchingStore struct {
+	store.Store
+}
+
+func (s *watchingStore) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	pairs, err := s.List(directory)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan []*store.KVPair, 1)
+	events <- pairs
+	close(events)
+	return events, nil
+}
+
+func TestStoreWatchTree(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+
+	s, err := NewStore(&watchingStore{Store: kv}, testKey)
+	require.NoError(t, err)
+	require.NoError(t, s.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	events, err := s.WatchTree("telegram/chats", stop)
+	require.NoError(t, err)
+	pairs := <-events
+	require.Len(t, pairs, 1)
+	require.Equal(t, []byte(`{"id":1}`), pairs[0].Value)
+
+	// The watch ends instead of passing on values it can't decrypt.
+	other, err := NewStore(&watchingStore{Store: kv}, bytes.Repeat([]byte{0x23}, 32))
+	require.NoError(t, err)
+	events, err = other.WatchTree("telegram/chats", stop)
+	require.NoError(t, err)
+	_, ok := <-events
+	require.False(t, ok)
+}
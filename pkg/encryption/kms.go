@@ -0,0 +1,83 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/sigv4"
+)
+
+// KMS decrypts data keys with AWS KMS, so that the key file only has to contain the encrypted data key.
+type KMS struct {
+	url    *url.URL
+	signer sigv4.Signer
+	http   *http.Client
+}
+
+// NewKMS returns a client of the KMS API at the URL, e.g. https://kms.eu-central-1.amazonaws.com/.
+func NewKMS(u *url.URL, region, accessKeyID, secretAccessKey, sessionToken string) *KMS {
+	return &KMS{
+		url: u,
+		signer: sigv4.Signer{
+			Region:          region,
+			Service:         "kms",
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+		},
+		http: http.DefaultClient,
+	}
+}
+
+// Decrypt the ciphertext of a data key, e.g. of aws kms generate-data-key, and return the plaintext key.
+func (k *KMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		CiphertextBlob []byte
+	}{CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	k.signer.Sign(req, body, time.Now())
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Type != "" {
+			return nil, fmt.Errorf("failed to decrypt key with KMS: %s: %s", e.Type, e.Message)
+		}
+		return nil, fmt.Errorf("failed to decrypt key with KMS: %s", resp.Status)
+	}
+
+	var result struct {
+		Plaintext []byte
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
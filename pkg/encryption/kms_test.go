@@ -0,0 +1,44 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMSDecrypt(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-central-1/kms/aws4_request")
+		require.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+
+		var req struct {
+			CiphertextBlob []byte
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if string(req.CiphertextBlob) != "encrypted" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException","message":"invalid ciphertext"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": testKey})
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	kms := NewKMS(u, "eu-central-1", "id", "secret", "token")
+
+	key, err := kms.Decrypt(context.Background(), []byte("encrypted"))
+	require.NoError(t, err)
+	require.Equal(t, testKey, key)
+
+	_, err = kms.Decrypt(context.Background(), []byte("other"))
+	require.EqualError(t, err, "failed to decrypt key with KMS: InvalidCiphertextException: invalid ciphertext")
+}
@@ -0,0 +1,145 @@
+// Package enrichment looks up additional fields of alerts by their labels from external HTTP sources,
+// e.g. the owner and service tier from a CMDB or the runbook from an ownership service.
+package enrichment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source posts the labels of alerts as {"labels":{...}} to an HTTP endpoint,
+// which has to respond with a JSON object of the fields to add to the alerts.
+// Responses are cached by the labels, so that repeated notifications don't need a lookup.
+type Source struct {
+	url    string
+	client *http.Client
+	cache  *cache
+	// fields are the fields of the response that are used, all if empty.
+	fields map[string]bool
+}
+
+// NewSource returns a Source looking up the fields at url, waiting at most timeout for a response.
+// Fields are cached for ttl, a ttl of 0 looks them up every time. Only the given fields are used, all if none are given.
+func NewSource(url string, timeout, ttl time.Duration, fields []string) *Source {
+	s := &Source{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		cache:  newCache(ttl),
+	}
+	if len(fields) > 0 {
+		s.fields = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			s.fields[f] = true
+		}
+	}
+	return s
+}
+
+// Enrich returns the fields of the alert with the labels.
+// Values that are numbers or booleans are formatted, nested objects and lists are ignored.
+func (s *Source) Enrich(ctx context.Context, labels map[string]string) (map[string]string, error) {
+	key := cacheKey(labels)
+	if fields, ok := s.cache.get(key); ok {
+		return fields, nil
+	}
+
+	body, err := json.Marshal(struct {
+		Labels map[string]string `json:"labels"`
+	}{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	fields := map[string]string{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var values map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&values); err != nil {
+			return nil, fmt.Errorf("failed to decode fields from %s: %w", s.url, err)
+		}
+		for name, value := range values {
+			if s.fields != nil && !s.fields[name] {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				fields[name] = v
+			case float64, bool:
+				fields[name] = fmt.Sprint(v)
+			}
+		}
+	case http.StatusNotFound:
+		// The source doesn't know the alert, which is cached like an empty response.
+	default:
+		return nil, fmt.Errorf("unexpected status %s looking up fields at %s", resp.Status, s.url)
+	}
+
+	s.cache.put(key, fields)
+	return fields, nil
+}
+
+// cache keeps the fields looked up for alerts for a while.
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	fields map[string]string
+	at     time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{ttl: ttl, entries: map[string]entry{}}
+}
+
+func (c *cache) get(key string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Since(e.at) >= c.ttl {
+		return nil, false
+	}
+	return e.fields, true
+}
+
+func (c *cache) put(key string, fields map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry{fields: fields, at: time.Now()}
+}
+
+// cacheKey returns the labels sorted by name as a string.
+func cacheKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&key, "%s=%q,", name, labels[name])
+	}
+	return key.String()
+}
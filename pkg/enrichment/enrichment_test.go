@@ -0,0 +1,81 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var body struct {
+			Labels map[string]string `json:"labels"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Labels["service"] != "db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"owner":"team-db","tier":1,"critical":true,"contacts":["alice"],"internal":"x"}`))
+	}))
+	defer server.Close()
+
+	s := NewSource(server.URL, time.Second, time.Hour, []string{"owner", "tier", "critical", "contacts"})
+
+	for i := 0; i < 2; i++ {
+		fields, err := s.Enrich(context.Background(), map[string]string{"alertname": "Fire", "service": "db"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"owner": "team-db", "tier": "1", "critical": "true"}, fields)
+	}
+	require.Equal(t, 1, lookups)
+
+	fields, err := s.Enrich(context.Background(), map[string]string{"alertname": "Fire", "service": "web"})
+	require.NoError(t, err)
+	require.Empty(t, fields)
+	require.Equal(t, 2, lookups)
+}
+
+func TestSourceAllFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"owner":"team-db","runbook":"https://runbooks/db"}`))
+	}))
+	defer server.Close()
+
+	s := NewSource(server.URL, time.Second, 0, nil)
+	fields, err := s.Enrich(context.Background(), map[string]string{"alertname": "Fire"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"owner": "team-db", "runbook": "https://runbooks/db"}, fields)
+}
+
+func TestSourceErrors(t *testing.T) {
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s := NewSource(server.URL, time.Second, time.Hour, nil)
+	for i := 0; i < 2; i++ {
+		_, err := s.Enrich(context.Background(), map[string]string{"alertname": "Fire"})
+		require.Error(t, err)
+	}
+	// Failed lookups aren't cached.
+	require.Equal(t, 2, lookups)
+
+	s = NewSource(server.URL+"/slow", 10*time.Millisecond, time.Hour, nil)
+	_, err := s.Enrich(context.Background(), map[string]string{"alertname": "Fire"})
+	require.Error(t, err)
+}
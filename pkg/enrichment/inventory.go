@@ -0,0 +1,99 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Inventory returns the fields of the host in an alert's instance label from a static inventory,
+// e.g. its hostname, datacenter and rack.
+type Inventory struct {
+	hosts    map[string]map[string]string
+	networks []network
+}
+
+type network struct {
+	*net.IPNet
+	fields map[string]string
+}
+
+// inventoryFile maps hosts and CIDR networks to their fields.
+type inventoryFile struct {
+	Hosts    map[string]map[string]string `yaml:"hosts"`
+	Networks map[string]map[string]string `yaml:"networks"`
+}
+
+// LoadInventory reads a YAML inventory file with the fields of hosts by their name or IP under hosts
+// and the fields of networks by their CIDR under networks, e.g. 10.0.1.0/24: {datacenter: fra1}.
+func LoadInventory(path string) (*Inventory, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInventory(content)
+}
+
+// ParseInventory parses the content of an inventory file, see LoadInventory.
+func ParseInventory(content []byte) (*Inventory, error) {
+	var f inventoryFile
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{hosts: f.Hosts}
+	for cidr, fields := range f.Networks {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", cidr, err)
+		}
+		inv.networks = append(inv.networks, network{IPNet: ipNet, fields: fields})
+	}
+	// The most specific network is looked at first.
+	sort.Slice(inv.networks, func(i, j int) bool {
+		oi, _ := inv.networks[i].Mask.Size()
+		oj, _ := inv.networks[j].Mask.Size()
+		return oi > oj
+	})
+	return inv, nil
+}
+
+// Enrich returns the fields of the alert's instance, the fields of its host take precedence over the ones of its networks.
+// Instances are looked up as they are and without their port.
+func (inv *Inventory) Enrich(_ context.Context, labels map[string]string) (map[string]string, error) {
+	instance, ok := labels["instance"]
+	if !ok {
+		return nil, nil
+	}
+	host := instanceHost(instance)
+
+	fields := map[string]string{}
+	if ip := net.ParseIP(host); ip != nil {
+		for i := len(inv.networks) - 1; i >= 0; i-- {
+			if inv.networks[i].Contains(ip) {
+				for name, value := range inv.networks[i].fields {
+					fields[name] = value
+				}
+			}
+		}
+	}
+	for _, key := range []string{host, instance} {
+		for name, value := range inv.hosts[key] {
+			fields[name] = value
+		}
+	}
+	return fields, nil
+}
+
+// instanceHost returns the host of an instance label, which usually is host:port.
+func instanceHost(instance string) string {
+	host, _, err := net.SplitHostPort(instance)
+	if err != nil {
+		return instance
+	}
+	return host
+}
@@ -0,0 +1,98 @@
+package enrichment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventory(t *testing.T) {
+	inv, err := ParseInventory([]byte(`
+hosts:
+  10.0.1.5: {hostname: db-1.fra1, rack: r12}
+  web-1:9100: {hostname: web-1.fra1}
+networks:
+  10.0.0.0/16: {datacenter: fra1, rack: unknown}
+  10.0.2.0/24: {datacenter: fra1-b}
+`))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		instance string
+		fields   map[string]string
+	}{{
+		instance: "10.0.1.5:9100",
+		fields:   map[string]string{"hostname": "db-1.fra1", "datacenter": "fra1", "rack": "r12"},
+	}, {
+		instance: "10.0.2.7:9100",
+		fields:   map[string]string{"datacenter": "fra1-b", "rack": "unknown"},
+	}, {
+		instance: "web-1:9100",
+		fields:   map[string]string{"hostname": "web-1.fra1"},
+	}, {
+		instance: "192.168.0.1",
+		fields:   map[string]string{},
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.instance, func(t *testing.T) {
+			fields, err := inv.Enrich(context.Background(), map[string]string{"instance": tc.instance})
+			require.NoError(t, err)
+			require.Equal(t, tc.fields, fields)
+		})
+	}
+
+	fields, err := inv.Enrich(context.Background(), map[string]string{"alertname": "Fire"})
+	require.NoError(t, err)
+	require.Empty(t, fields)
+
+	_, err = ParseInventory([]byte("networks:\n  10.0.0.0/33: {datacenter: fra1}\n"))
+	require.Error(t, err)
+}
+
+func TestNetbox(t *testing.T) {
+	lookups := 0
+	m := http.NewServeMux()
+	m.HandleFunc("/api/ipam/ip-addresses/", func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		require.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("address") != "10.0.1.5" {
+			_, _ = w.Write([]byte(`{"results":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"dns_name":"db-1.fra1.example.com","assigned_object":{"device":{"name":"db-1"}}}]}`))
+	})
+	m.HandleFunc("/api/dcim/devices/", func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		switch r.URL.Query().Get("name") {
+		case "db-1":
+			_, _ = w.Write([]byte(`{"results":[{"name":"db-1","site":{"name":"fra1"},"rack":{"name":"r12"}}]}`))
+		case "web-1":
+			_, _ = w.Write([]byte(`{"results":[{"name":"web-1","site":{"name":"ams1"},"rack":null}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"results":[]}`))
+		}
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	n := NewNetbox(server.URL+"/", "secret", time.Second, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		fields, err := n.Enrich(context.Background(), map[string]string{"instance": "10.0.1.5:9100"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"hostname": "db-1.fra1.example.com", "datacenter": "fra1", "rack": "r12"}, fields)
+	}
+	require.Equal(t, 2, lookups)
+
+	fields, err := n.Enrich(context.Background(), map[string]string{"instance": "web-1:9100"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"hostname": "web-1", "datacenter": "ams1"}, fields)
+
+	fields, err = n.Enrich(context.Background(), map[string]string{"instance": "10.9.9.9:9100"})
+	require.NoError(t, err)
+	require.Empty(t, fields)
+}
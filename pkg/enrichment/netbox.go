@@ -0,0 +1,129 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Netbox returns the hostname, datacenter and rack of the device in an alert's instance label from the Netbox API.
+// Instances with an IP are looked up by the IP address assigned to the device, all others by the device's name.
+type Netbox struct {
+	url    string
+	token  string
+	client *http.Client
+	cache  *cache
+}
+
+// NewNetbox returns a Netbox looking up devices at the API of baseURL with the token,
+// waiting at most timeout for a response. The fields of an instance are cached for ttl.
+func NewNetbox(baseURL, token string, timeout, ttl time.Duration) *Netbox {
+	return &Netbox{
+		url:    strings.TrimSuffix(baseURL, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+		cache:  newCache(ttl),
+	}
+}
+
+type netboxIPAddresses struct {
+	Results []struct {
+		DNSName        string `json:"dns_name"`
+		AssignedObject struct {
+			Device struct {
+				Name string `json:"name"`
+			} `json:"device"`
+		} `json:"assigned_object"`
+	} `json:"results"`
+}
+
+type netboxDevices struct {
+	Results []struct {
+		Name string `json:"name"`
+		Site struct {
+			Name string `json:"name"`
+		} `json:"site"`
+		Rack *struct {
+			Name string `json:"name"`
+		} `json:"rack"`
+	} `json:"results"`
+}
+
+// Enrich returns the hostname, datacenter and rack of the alert's instance, no fields if Netbox doesn't know it.
+func (n *Netbox) Enrich(ctx context.Context, labels map[string]string) (map[string]string, error) {
+	instance, ok := labels["instance"]
+	if !ok {
+		return nil, nil
+	}
+	host := instanceHost(instance)
+	if fields, ok := n.cache.get(host); ok {
+		return fields, nil
+	}
+
+	fields := map[string]string{}
+	device := host
+	if net.ParseIP(host) != nil {
+		var addresses netboxIPAddresses
+		if err := n.get(ctx, "/api/ipam/ip-addresses/", url.Values{"address": {host}}, &addresses); err != nil {
+			return nil, err
+		}
+		if len(addresses.Results) == 0 {
+			n.cache.put(host, fields)
+			return fields, nil
+		}
+		device = addresses.Results[0].AssignedObject.Device.Name
+		if name := addresses.Results[0].DNSName; name != "" {
+			fields["hostname"] = name
+		}
+	}
+
+	if device != "" {
+		var devices netboxDevices
+		if err := n.get(ctx, "/api/dcim/devices/", url.Values{"name": {device}}, &devices); err != nil {
+			return nil, err
+		}
+		if len(devices.Results) > 0 {
+			d := devices.Results[0]
+			if _, ok := fields["hostname"]; !ok {
+				fields["hostname"] = d.Name
+			}
+			if d.Site.Name != "" {
+				fields["datacenter"] = d.Site.Name
+			}
+			if d.Rack != nil && d.Rack.Name != "" {
+				fields["rack"] = d.Rack.Name
+			}
+		}
+	}
+
+	n.cache.put(host, fields)
+	return fields, nil
+}
+
+// get decodes the response of the API path with the query into v.
+func (n *Netbox) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.url+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Token "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s looking up %s in netbox", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
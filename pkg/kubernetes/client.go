@@ -0,0 +1,183 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Group and Version of the custom resources, see deployments/crds.yaml.
+const (
+	Group   = "alertmanager-bot.metalmatze.de"
+	Version = "v1alpha1"
+)
+
+// serviceAccountDir is where Kubernetes mounts the token and CA of a pod's service account.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client lists and watches the custom resources with the Kubernetes API.
+type Client struct {
+	URL *url.URL
+	// TokenFile is read for every request, as service account tokens are rotated.
+	TokenFile string
+	// Namespace to watch the resources in, all namespaces if empty.
+	Namespace string
+	HTTP      *http.Client
+}
+
+// NewInClusterClient returns a client using the service account of the pod the bot is running in.
+func NewInClusterClient(namespace string) (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are missing")
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("service account ca has no certificates")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return &Client{
+		URL:       &url.URL{Scheme: "https", Host: net.JoinHostPort(host, port)},
+		TokenFile: serviceAccountDir + "/token",
+		Namespace: namespace,
+		HTTP:      &http.Client{Transport: transport},
+	}, nil
+}
+
+// object is the part of a resource's metadata the controller needs.
+type object struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+}
+
+// key identifies a resource across namespaces.
+func (o object) key() string {
+	return o.Metadata.Namespace + "/" + o.Metadata.Name
+}
+
+// event is sent by the API for every change of a watched resource.
+type event struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// Types of events.
+const (
+	eventAdded    = "ADDED"
+	eventModified = "MODIFIED"
+	eventDeleted  = "DELETED"
+	eventBookmark = "BOOKMARK"
+	eventError    = "ERROR"
+)
+
+func (c *Client) path(resource string) string {
+	if c.Namespace == "" {
+		return fmt.Sprintf("/apis/%s/%s/%s", Group, Version, resource)
+	}
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", Group, Version, c.Namespace, resource)
+}
+
+func (c *Client) get(ctx context.Context, resource string, query url.Values) (*http.Response, error) {
+	u := *c.URL
+	u.Path = strings.TrimSuffix(u.Path, "/") + c.path(resource)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if c.TokenFile != "" {
+		token, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %s: %s", resource, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// list returns all resources and the version to watch them from.
+func (c *Client) list(ctx context.Context, resource string) ([]json.RawMessage, string, error) {
+	resp, err := c.get(ctx, resource, url.Values{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", resource, err)
+	}
+	return list.Items, list.Metadata.ResourceVersion, nil
+}
+
+// watch calls f with every event of the resources after the version until the API closes the watch or ctx is done.
+func (c *Client) watch(ctx context.Context, resource, version string, f func(event) error) error {
+	resp, err := c.get(ctx, resource, url.Values{
+		"watch":               {"true"},
+		"resourceVersion":     {version},
+		"allowWatchBookmarks": {"true"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var e event
+		if err := dec.Decode(&e); err != nil {
+			// The API closes watches after a timeout.
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("watch of %s ended: %w", resource, err)
+		}
+		if e.Type == eventError {
+			// Most likely the version is too old and the resources have to be listed again.
+			return fmt.Errorf("watch of %s failed: %s", resource, e.Object)
+		}
+		if err := f(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+)
+
+// defaultTenant is used by resources that don't name a tenant, like the admin HTTP endpoints do.
+const defaultTenant = "telegram"
+
+// Resources watched by the controller.
+const (
+	resourceSubscriptions = "telegramsubscriptions"
+	resourceFilters       = "alertfilters"
+)
+
+// TelegramSubscription subscribes a chat to a tenant's alerts.
+type TelegramSubscription struct {
+	object
+	Spec struct {
+		Tenant string `json:"tenant"`
+		ChatID int64  `json:"chatID"`
+		Title  string `json:"title"`
+		// Muted are the usernames not mentioned in the chat's alerts.
+		Muted []string `json:"muted"`
+	} `json:"spec"`
+}
+
+// AlertFilter only sends alerts matching all matchers to a subscribed chat.
+// The matchers of several filters for the same chat are combined.
+type AlertFilter struct {
+	object
+	Spec struct {
+		Tenant   string   `json:"tenant"`
+		ChatID   int64    `json:"chatID"`
+		Matchers []string `json:"matchers"`
+	} `json:"spec"`
+}
+
+// Subscriber is a tenant's bot the chats are subscribed to.
+type Subscriber interface {
+	Subscribe(telegram.Subscription) error
+	Unsubscribe(chatID int64) error
+}
+
+// Controller watches TelegramSubscription and AlertFilter resources and reconciles them into the tenants' bots.
+type Controller struct {
+	client  *Client
+	logger  log.Logger
+	tenants map[string]Subscriber
+	// RetryInterval is how long to wait before listing the resources again after an error.
+	RetryInterval time.Duration
+
+	mu            sync.Mutex
+	subscriptions map[string]TelegramSubscription
+	filters       map[string]AlertFilter
+	// applied are the subscriptions by tenant and chat the last reconciliation subscribed.
+	applied map[string]map[int64]telegram.Subscription
+}
+
+// NewController returns a Controller for the tenants by name.
+func NewController(client *Client, logger log.Logger, tenants map[string]Subscriber) *Controller {
+	return &Controller{
+		client:        client,
+		logger:        logger,
+		tenants:       tenants,
+		RetryInterval: 5 * time.Second,
+		subscriptions: map[string]TelegramSubscription{},
+		filters:       map[string]AlertFilter{},
+		applied:       map[string]map[int64]telegram.Subscription{},
+	}
+}
+
+// Run watches the resources until ctx is done.
+func (c *Controller) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, resource := range []string{resourceSubscriptions, resourceFilters} {
+		wg.Add(1)
+		go func(resource string) {
+			defer wg.Done()
+			c.watch(ctx, resource)
+		}(resource)
+	}
+	wg.Wait()
+	return nil
+}
+
+// watch lists the resource and then watches it, starting over with a fresh list if the watch ends.
+func (c *Controller) watch(ctx context.Context, resource string) {
+	for ctx.Err() == nil {
+		err := c.listAndWatch(ctx, resource)
+		if err == nil {
+			continue
+		}
+		level.Warn(c.logger).Log("msg", "failed to watch resources", "resource", resource, "err", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.RetryInterval):
+		}
+	}
+}
+
+func (c *Controller) listAndWatch(ctx context.Context, resource string) error {
+	items, version, err := c.client.list(ctx, resource)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	err = c.replace(resource, items)
+	if err == nil {
+		c.reconcile()
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return c.client.watch(ctx, resource, version, func(e event) error {
+		if e.Type == eventBookmark {
+			return nil
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err := c.update(resource, e.Type, e.Object); err != nil {
+			return err
+		}
+		c.reconcile()
+		return nil
+	})
+}
+
+// replace sets all listed resources, dropping the ones deleted in the meantime.
+func (c *Controller) replace(resource string, items []json.RawMessage) error {
+	switch resource {
+	case resourceSubscriptions:
+		c.subscriptions = map[string]TelegramSubscription{}
+	case resourceFilters:
+		c.filters = map[string]AlertFilter{}
+	}
+	for _, item := range items {
+		if err := c.update(resource, eventAdded, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update applies an event of the resource.
+func (c *Controller) update(resource, typ string, raw json.RawMessage) error {
+	switch resource {
+	case resourceSubscriptions:
+		var s TelegramSubscription
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("failed to decode TelegramSubscription: %w", err)
+		}
+		if typ == eventDeleted {
+			delete(c.subscriptions, s.key())
+		} else {
+			c.subscriptions[s.key()] = s
+		}
+	case resourceFilters:
+		var f AlertFilter
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return fmt.Errorf("failed to decode AlertFilter: %w", err)
+		}
+		if typ == eventDeleted {
+			delete(c.filters, f.key())
+		} else {
+			c.filters[f.key()] = f
+		}
+	}
+	return nil
+}
+
+func tenantName(name string) string {
+	if name == "" {
+		return defaultTenant
+	}
+	return name
+}
+
+// reconcile subscribes the chats of the resources that changed since the last reconciliation
+// and unsubscribes the chats whose resources were deleted. It has to be called with c.mu held.
+func (c *Controller) reconcile() {
+	desired := map[string]map[int64]telegram.Subscription{}
+
+	keys := make([]string, 0, len(c.subscriptions))
+	for key := range c.subscriptions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := c.subscriptions[key]
+		tenant := tenantName(s.Spec.Tenant)
+		if _, ok := c.tenants[tenant]; !ok {
+			level.Warn(c.logger).Log("msg", "subscription of unknown tenant", "subscription", key, "tenant", tenant)
+			continue
+		}
+		if s.Spec.ChatID == 0 {
+			level.Warn(c.logger).Log("msg", "subscription has no chat id", "subscription", key)
+			continue
+		}
+		if desired[tenant] == nil {
+			desired[tenant] = map[int64]telegram.Subscription{}
+		}
+		desired[tenant][s.Spec.ChatID] = telegram.Subscription{ChatID: s.Spec.ChatID, Title: s.Spec.Title, Muted: s.Spec.Muted}
+	}
+
+	keys = keys[:0]
+	for key := range c.filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := c.filters[key]
+		s, ok := desired[tenantName(f.Spec.Tenant)][f.Spec.ChatID]
+		if !ok {
+			// Filters of chats without subscription are applied once the chat is subscribed.
+			continue
+		}
+		if err := (&telegram.ChatFilter{ChatID: f.Spec.ChatID, Matchers: f.Spec.Matchers}).Validate(); err != nil {
+			level.Warn(c.logger).Log("msg", "invalid alert filter", "filter", key, "err", err)
+			continue
+		}
+		s.Matchers = append(s.Matchers, f.Spec.Matchers...)
+		desired[tenantName(f.Spec.Tenant)][f.Spec.ChatID] = s
+	}
+
+	for tenant, bot := range c.tenants {
+		logger := log.With(c.logger, "tenant", tenant)
+		applied := c.applied[tenant]
+		if applied == nil {
+			applied = map[int64]telegram.Subscription{}
+			c.applied[tenant] = applied
+		}
+
+		for chatID, s := range desired[tenant] {
+			if old, ok := applied[chatID]; ok && reflect.DeepEqual(old, s) {
+				continue
+			}
+			if err := bot.Subscribe(s); err != nil {
+				level.Warn(logger).Log("msg", "failed to subscribe chat", "chat_id", chatID, "err", err)
+				continue
+			}
+			applied[chatID] = s
+			level.Info(logger).Log("msg", "chat subscribed by resource", "chat_id", chatID, "matchers", len(s.Matchers))
+		}
+		for chatID := range applied {
+			if _, ok := desired[tenant][chatID]; ok {
+				continue
+			}
+			if err := bot.Unsubscribe(chatID); err != nil {
+				level.Warn(logger).Log("msg", "failed to unsubscribe chat", "chat_id", chatID, "err", err)
+				continue
+			}
+			delete(applied, chatID)
+			level.Info(logger).Log("msg", "chat unsubscribed by resource", "chat_id", chatID)
+		}
+	}
+}
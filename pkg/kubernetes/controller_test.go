@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+)
+
+type testSubscriber struct {
+	mu            sync.Mutex
+	subscriptions map[int64]telegram.Subscription
+}
+
+func (s *testSubscriber) Subscribe(sub telegram.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[sub.ChatID] = sub
+	return nil
+}
+
+func (s *testSubscriber) Unsubscribe(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, chatID)
+	return nil
+}
+
+func (s *testSubscriber) get(chatID int64) (telegram.Subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subscriptions[chatID]
+	return sub, ok
+}
+
+const testSubscription = `{"metadata":{"name":"sre","namespace":"monitoring"},"spec":{"chatID":-1234,"title":"sre"}}`
+
+func TestController(t *testing.T) {
+	events := make(chan string, 1)
+
+	m := http.NewServeMux()
+	m.HandleFunc("/apis/alertmanager-bot.metalmatze.de/v1alpha1/namespaces/monitoring/telegramsubscriptions", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprintf(w, `{"metadata":{"resourceVersion":"1"},"items":[%s]}`, testSubscription)
+			return
+		}
+		require.Equal(t, "1", r.URL.Query().Get("resourceVersion"))
+		for {
+			select {
+			case e := <-events:
+				fmt.Fprintln(w, e)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	m.HandleFunc("/apis/alertmanager-bot.metalmatze.de/v1alpha1/namespaces/monitoring/alertfilters", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"1"},"items":[
+				{"metadata":{"name":"critical","namespace":"monitoring"},"spec":{"chatID":-1234,"matchers":["severity=critical"]}},
+				{"metadata":{"name":"other","namespace":"monitoring"},"spec":{"chatID":-5678,"matchers":["team=db"]}}
+			]}`)
+			return
+		}
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	token, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(token.Name())
+	_, err = token.WriteString("secret\n")
+	require.NoError(t, err)
+	require.NoError(t, token.Close())
+
+	bot := &testSubscriber{subscriptions: map[int64]telegram.Subscription{}}
+	c := NewController(&Client{URL: u, TokenFile: token.Name(), Namespace: "monitoring", HTTP: server.Client()}, log.NewNopLogger(), map[string]Subscriber{"telegram": bot})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = c.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		sub, ok := bot.get(-1234)
+		return ok && len(sub.Matchers) == 1
+	}, time.Second, 10*time.Millisecond)
+	sub, _ := bot.get(-1234)
+	require.Equal(t, telegram.Subscription{ChatID: -1234, Title: "sre", Matchers: []string{"severity=critical"}}, sub)
+	_, ok := bot.get(-5678)
+	require.False(t, ok, "filters don't subscribe chats")
+
+	events <- `{"type":"DELETED","object":` + testSubscription + `}`
+	require.Eventually(t, func() bool {
+		_, ok := bot.get(-1234)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,28 @@
+// Package kvtest provides stores for the tests of the packages wrapping a libkv store.
+//
+//	kv, closeStore := kvtest.New(t)
+//	defer closeStore()
+package kvtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/stretchr/testify/require"
+)
+
+// New returns a bolt store in a temporary directory and a function removing it.
+func New(t *testing.T) (store.Store, func()) {
+	dir, err := ioutil.TempDir("", "alertmanager-bot")
+	require.NoError(t, err)
+	kv, err := boltdb.New([]string{filepath.Join(dir, "bot.db")}, &store.Config{Bucket: "alertmanager"})
+	require.NoError(t, err)
+	return kv, func() {
+		kv.Close()
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,383 @@
+// Package mirror keeps a copy of directories of a libkv store in memory, in sync with the store by watching them,
+// so that replicas taking over the chats of another one don't have to load them from the store first.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// writeTimeout is how long a write is kept over watch events that don't show it,
+// if their LastIndex can't tell whether they were sent before or after it.
+const writeTimeout = 10 * time.Second
+
+// Store reads the keys below the mirrored directories from memory once their watch delivered them,
+// all other keys and directories that aren't watched yet are read from the wrapped store.
+// Writes go to the wrapped store and are applied to the copy right away, the watches bring in the writes of others.
+type Store struct {
+	store.Store
+	directories []string
+	logger      log.Logger
+
+	mu sync.RWMutex
+	// synced are the pairs of the directories whose watch delivered them, by directory and key.
+	synced map[string]map[string]*store.KVPair
+	// written are the writes of this store no watch event showed yet, by key.
+	written map[string]*write
+}
+
+// write is a put or delete of a key by this store, which watch events sent before it don't undo.
+type write struct {
+	// pair is the written pair, nil for a delete.
+	pair *store.KVPair
+	// index is the LastIndex of the put pair, or of the deleted pair before the delete, 0 if it's unknown.
+	index uint64
+	at    time.Time
+}
+
+// shownBy returns whether the pair of the key in a watch event, nil if the event doesn't have the key,
+// shows the write or a later one. Events without the key don't have an index,
+// so they only show deletes and writes older than the writeTimeout.
+func (w *write) shownBy(p *store.KVPair, now time.Time) bool {
+	switch {
+	case now.Sub(w.at) > writeTimeout:
+		return true
+	case p == nil:
+		return w.pair == nil
+	case w.pair == nil:
+		// The key was put again after the delete.
+		return w.index > 0 && p.LastIndex > w.index
+	case w.index > 0:
+		return p.LastIndex >= w.index
+	default:
+		return string(p.Value) == string(w.pair.Value)
+	}
+}
+
+// NewStore mirrors the directories of the store once Run watches them.
+func NewStore(kv store.Store, directories []string, logger log.Logger) (*Store, error) {
+	if len(directories) == 0 {
+		return nil, errors.New("at least one directory to mirror is required")
+	}
+	dirs := make([]string, 0, len(directories))
+	for _, d := range directories {
+		d = normalize(d)
+		if d == "" {
+			return nil, errors.New("the directories to mirror must not be empty")
+		}
+		dirs = append(dirs, d)
+	}
+	return &Store{
+		Store:       kv,
+		directories: dirs,
+		logger:      logger,
+		synced:      map[string]map[string]*store.KVPair{},
+		written:     map[string]*write{},
+	}, nil
+}
+
+// normalize the key like the store backends do, as they return keys with or without a leading slash.
+func normalize(key string) string {
+	return strings.Trim(key, "/")
+}
+
+// Run watches the directories until the context is done, watching them again with backoff if a watch ends.
+// It fails right away if the wrapped store can't watch, e.g. a bolt store.
+func (s *Store) Run(ctx context.Context) error {
+	errs := make(chan error, len(s.directories))
+	var wg sync.WaitGroup
+	for _, d := range s.directories {
+		wg.Add(1)
+		go func(directory string) {
+			defer wg.Done()
+			errs <- s.watch(ctx, directory)
+		}(d)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch keeps the copy of the directory in sync until the context is done.
+// While the directory isn't watched, e.g. because it's empty and etcd can't watch it yet, it's read from the store.
+func (s *Store) watch(ctx context.Context, directory string) error {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		stop := make(chan struct{})
+		events, err := s.Store.WatchTree(directory, stop)
+		if errors.Is(err, store.ErrCallNotSupported) {
+			close(stop)
+			return err
+		}
+		if err == nil {
+			err = s.consume(ctx, directory, events)
+		}
+		close(stop)
+		wasSynced := s.unsync(directory)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		// etcd can't watch directories that don't exist yet, they're read from the store without a warning.
+		logger := level.Debug(s.logger)
+		if wasSynced {
+			logger = level.Warn(s.logger)
+		}
+		logger.Log("msg", "store watch ended, reading from the store until it's watched again", "directory", directory, "err", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// consume applies the events of the watch to the copy until the watch ends or the context is done.
+func (s *Store) consume(ctx context.Context, directory string, events <-chan []*store.KVPair) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case pairs, ok := <-events:
+			if !ok {
+				return errors.New("watch closed")
+			}
+			s.sync(directory, pairs)
+		}
+	}
+}
+
+// sync replaces the copy of the directory with the pairs of a watch event.
+// The event may have been sent before a write of this store, so writes it doesn't show yet are kept.
+func (s *Store) sync(directory string, pairs []*store.KVPair) {
+	copied := make(map[string]*store.KVPair, len(pairs))
+	for _, p := range pairs {
+		key := normalize(p.Key)
+		// Consul lists all keys starting with the directory, e.g. telegram/chatsfoo for telegram/chats.
+		if !strings.HasPrefix(key, directory+"/") {
+			continue
+		}
+		copied[key] = &store.KVPair{Key: key, Value: p.Value, LastIndex: p.LastIndex}
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for key, w := range s.written {
+		if !strings.HasPrefix(key, directory+"/") {
+			continue
+		}
+		if w.shownBy(copied[key], now) {
+			delete(s.written, key)
+			continue
+		}
+		if w.pair == nil {
+			delete(copied, key)
+		} else {
+			copied[key] = w.pair
+		}
+	}
+	_, wasSynced := s.synced[directory]
+	s.synced[directory] = copied
+	s.mu.Unlock()
+
+	if !wasSynced {
+		level.Debug(s.logger).Log("msg", "store directory mirrored", "directory", directory, "keys", len(copied))
+	}
+}
+
+// unsync drops the copy of the directory and returns whether there was one.
+func (s *Store) unsync(directory string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.synced[directory]
+	delete(s.synced, directory)
+	return ok
+}
+
+// mirrored returns the copy of the directory the key is below, false if it isn't mirrored or not watched yet.
+// The caller has to hold the lock.
+func (s *Store) mirrored(key string) (map[string]*store.KVPair, bool) {
+	d, ok := s.directory(key)
+	if !ok {
+		return nil, false
+	}
+	pairs, ok := s.synced[d]
+	return pairs, ok
+}
+
+// directory returns the mirrored directory the key is below, false if there's none.
+func (s *Store) directory(key string) (string, bool) {
+	for _, d := range s.directories {
+		if key == d || strings.HasPrefix(key, d+"/") {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func (s *Store) Get(key string) (*store.KVPair, error) {
+	key = normalize(key)
+	s.mu.RLock()
+	pairs, ok := s.mirrored(key)
+	var pair *store.KVPair
+	if ok {
+		pair = pairs[key]
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return s.Store.Get(key)
+	}
+	if pair == nil {
+		return nil, store.ErrKeyNotFound
+	}
+	p := *pair
+	return &p, nil
+}
+
+func (s *Store) Exists(key string) (bool, error) {
+	key = normalize(key)
+	s.mu.RLock()
+	pairs, ok := s.mirrored(key)
+	_, exists := pairs[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return s.Store.Exists(key)
+	}
+	return exists, nil
+}
+
+func (s *Store) List(directory string) ([]*store.KVPair, error) {
+	directory = normalize(directory)
+	s.mu.RLock()
+	mirrored, ok := s.mirrored(directory)
+	var pairs []*store.KVPair
+	for key, pair := range mirrored {
+		if strings.HasPrefix(key, directory+"/") {
+			p := *pair
+			pairs = append(pairs, &p)
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return s.Store.List(directory)
+	}
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+// put applies a successful write of the pair to the copy and keeps it until a watch event shows it.
+func (s *Store) put(pair *store.KVPair) {
+	pair.Key = normalize(pair.Key)
+	if _, ok := s.directory(pair.Key); !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written[pair.Key] = &write{pair: pair, index: pair.LastIndex, at: time.Now()}
+	if pairs, ok := s.mirrored(pair.Key); ok {
+		pairs[pair.Key] = pair
+	}
+}
+
+// remove applies a successful delete of the key, or of all keys below it, to the copy
+// and keeps the deletes of the keys until a watch event shows them.
+func (s *Store) remove(key string, tree bool) {
+	key = normalize(key)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.directory(key); ok && !tree {
+		s.written[key] = &write{at: now}
+	}
+	for _, d := range s.directories {
+		pairs, ok := s.synced[d]
+		if !ok {
+			continue
+		}
+		for k, p := range pairs {
+			if k == key || tree && strings.HasPrefix(k, key+"/") {
+				s.written[k] = &write{index: p.LastIndex, at: now}
+				delete(pairs, k)
+			}
+		}
+	}
+}
+
+func (s *Store) Put(key string, value []byte, options *store.WriteOptions) error {
+	if err := s.Store.Put(key, value, options); err != nil {
+		return err
+	}
+	// The index of the write tells watch events sent before it from later ones.
+	pair := &store.KVPair{Key: key, Value: value}
+	if _, ok := s.directory(normalize(key)); ok {
+		if written, err := s.Store.Get(key); err == nil && string(written.Value) == string(value) {
+			pair.LastIndex = written.LastIndex
+		}
+	}
+	s.put(pair)
+	return nil
+}
+
+func (s *Store) Delete(key string) error {
+	if err := s.Store.Delete(key); err != nil {
+		return err
+	}
+	s.remove(key, false)
+	return nil
+}
+
+func (s *Store) DeleteTree(directory string) error {
+	if err := s.Store.DeleteTree(directory); err != nil {
+		return err
+	}
+	s.remove(directory, true)
+	return nil
+}
+
+func (s *Store) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	ok, pair, err := s.Store.AtomicPut(key, value, previous, options)
+	if err != nil || !ok {
+		return ok, pair, err
+	}
+	mirrored := store.KVPair{Key: key, Value: value}
+	if pair != nil {
+		mirrored.LastIndex = pair.LastIndex
+	}
+	s.put(&mirrored)
+	return ok, pair, nil
+}
+
+func (s *Store) AtomicDelete(key string, previous *store.KVPair) (bool, error) {
+	ok, err := s.Store.AtomicDelete(key, previous)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.remove(key, false)
+	return ok, nil
+}
@@ -0,0 +1,171 @@
+package mirror
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/kvtest"
+	"github.com/stretchr/testify/require"
+)
+
+// watchedStore passes the events sent by the test to the watch of the directory and counts the reads.
+type watchedStore struct {
+	store.Store
+	events chan []*store.KVPair
+	reads  chan string
+}
+
+func (s *watchedStore) WatchTree(_ string, _ <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return s.events, nil
+}
+
+func (s *watchedStore) Get(key string) (*store.KVPair, error) {
+	s.reads <- key
+	return s.Store.Get(key)
+}
+
+func (s *watchedStore) List(directory string) ([]*store.KVPair, error) {
+	s.reads <- directory
+	return s.Store.List(directory)
+}
+
+func TestStore(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+	require.NoError(t, kv.Put("telegram/alerts/1/a", []byte(`{}`), nil))
+
+	watched := &watchedStore{Store: kv, events: make(chan []*store.KVPair), reads: make(chan string, 10)}
+	s, err := NewStore(watched, []string{"/telegram/chats"}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// Until the watch delivered the directory it's read from the store.
+	pair, err := s.Get("telegram/chats/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":1}`), pair.Value)
+	require.Equal(t, "telegram/chats/1", <-watched.reads)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	watched.events <- []*store.KVPair{
+		{Key: "/telegram/chats/1", Value: []byte(`{"id":1}`)},
+		{Key: "/telegram/chats/2", Value: []byte(`{"id":2}`)},
+		{Key: "/telegram/chatsfoo/3", Value: []byte(`{"id":3}`)},
+	}
+	changed := []*store.KVPair{
+		{Key: "telegram/chats/1", Value: []byte(`{"id":1}`)},
+		{Key: "telegram/chats/2", Value: []byte(`{"id":2,"title":"sre"}`)},
+	}
+	// An event is only received once the one before is applied.
+	watched.events <- changed
+	watched.events <- changed
+
+	pairs, err := s.List("telegram/chats")
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	require.Equal(t, "telegram/chats/2", pairs[1].Key)
+	require.Equal(t, []byte(`{"id":2,"title":"sre"}`), pairs[1].Value)
+	_, err = s.Get("telegram/chats/3")
+	require.Equal(t, store.ErrKeyNotFound, err)
+
+	// Writes are visible right away and reach the store.
+	require.NoError(t, s.Put("telegram/chats/4", []byte(`{"id":4}`), nil))
+	// The index of the write is read back.
+	require.Equal(t, "telegram/chats/4", <-watched.reads)
+	exists, err := s.Exists("telegram/chats/4")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.NoError(t, s.Delete("telegram/chats/1"))
+	_, err = s.Get("telegram/chats/1")
+	require.Equal(t, store.ErrKeyNotFound, err)
+	raw, err := kv.Get("telegram/chats/4")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":4}`), raw.Value)
+
+	// Other directories are read from the store.
+	pairs, err = s.List("telegram/alerts")
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	select {
+	case key := <-watched.reads:
+		require.Equal(t, "telegram/alerts", key)
+	case <-time.After(time.Second):
+		t.Fatal("the unmirrored directory wasn't read from the store")
+	}
+
+	// Once the watch ends the directory is read from the store again.
+	close(watched.events)
+	require.Eventually(t, func() bool {
+		_, _ = s.Get("telegram/chats/4")
+		select {
+		case <-watched.reads:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = NewStore(kv, nil, log.NewNopLogger())
+	require.Error(t, err)
+}
+
+func TestStoreStaleEvents(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+
+	events := make(chan []*store.KVPair)
+	s, err := NewStore(&watchedStore{Store: kv, events: events, reads: make(chan string, 10)}, []string{"telegram/chats"}, log.NewNopLogger())
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Run(ctx) }()
+
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+	chat1, err := kv.Get("telegram/chats/1")
+	require.NoError(t, err)
+	events <- []*store.KVPair{chat1}
+	events <- []*store.KVPair{chat1}
+
+	require.NoError(t, s.Put("telegram/chats/2", []byte(`{"id":2}`), nil))
+	require.NoError(t, s.Delete("telegram/chats/1"))
+	// The event was sent before the writes, so the copy keeps them.
+	events <- []*store.KVPair{chat1}
+	events <- []*store.KVPair{chat1}
+	_, err = s.Get("telegram/chats/1")
+	require.Equal(t, store.ErrKeyNotFound, err)
+	pair, err := s.Get("telegram/chats/2")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":2}`), pair.Value)
+
+	// Events sent after the writes show them and later writes of others.
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1,"title":"sre"}`), nil))
+	chat1, err = kv.Get("telegram/chats/1")
+	require.NoError(t, err)
+	chat2, err := kv.Get("telegram/chats/2")
+	require.NoError(t, err)
+	events <- []*store.KVPair{chat1, chat2}
+	events <- []*store.KVPair{chat1}
+	pair, err = s.Get("telegram/chats/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":1,"title":"sre"}`), pair.Value)
+	// Once an event showed the write, later events without the key delete it.
+	_, err = s.Get("telegram/chats/2")
+	require.Equal(t, store.ErrKeyNotFound, err)
+}
+
+func TestStoreNotWatchable(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+
+	s, err := NewStore(kv, []string{"telegram/chats"}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, store.ErrCallNotSupported, s.Run(context.Background()))
+}
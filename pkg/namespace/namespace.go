@@ -0,0 +1,146 @@
+// Package namespace puts all keys of a libkv store below a prefix,
+// so that several bots can share one Consul or etcd cluster without their keys colliding.
+package namespace
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/docker/libkv/store"
+)
+
+// Store prefixes the keys of all operations on the wrapped store and removes the prefix from the keys it returns.
+type Store struct {
+	store.Store
+	prefix string
+}
+
+// NewStore puts the keys of the store below the prefix.
+func NewStore(kv store.Store, prefix string) (*Store, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return nil, errors.New("the prefix of the store must not be empty")
+	}
+	return &Store{Store: kv, prefix: prefix}, nil
+}
+
+// key returns the key below the prefix.
+func (s *Store) key(key string) string {
+	return s.prefix + "/" + strings.TrimLeft(key, "/")
+}
+
+// strip removes the prefix from the pair's key in place.
+// The store backends return keys with or without a leading slash, it's removed as well.
+func (s *Store) strip(pair *store.KVPair) {
+	if pair == nil {
+		return
+	}
+	pair.Key = strings.TrimPrefix(strings.TrimLeft(pair.Key, "/"), s.prefix+"/")
+}
+
+func (s *Store) Put(key string, value []byte, options *store.WriteOptions) error {
+	return s.Store.Put(s.key(key), value, options)
+}
+
+func (s *Store) Get(key string) (*store.KVPair, error) {
+	pair, err := s.Store.Get(s.key(key))
+	if err != nil {
+		return nil, err
+	}
+	s.strip(pair)
+	return pair, nil
+}
+
+func (s *Store) Delete(key string) error {
+	return s.Store.Delete(s.key(key))
+}
+
+func (s *Store) Exists(key string) (bool, error) {
+	return s.Store.Exists(s.key(key))
+}
+
+func (s *Store) List(directory string) ([]*store.KVPair, error) {
+	pairs, err := s.Store.List(s.key(directory))
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		s.strip(pair)
+	}
+	return pairs, nil
+}
+
+func (s *Store) DeleteTree(directory string) error {
+	return s.Store.DeleteTree(s.key(directory))
+}
+
+func (s *Store) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	ok, pair, err := s.Store.AtomicPut(s.key(key), value, s.previous(previous), options)
+	if err != nil {
+		return ok, nil, err
+	}
+	s.strip(pair)
+	return ok, pair, nil
+}
+
+func (s *Store) AtomicDelete(key string, previous *store.KVPair) (bool, error) {
+	return s.Store.AtomicDelete(s.key(key), s.previous(previous))
+}
+
+// previous returns a copy of the pair with its key below the prefix, as the backends compare it.
+func (s *Store) previous(pair *store.KVPair) *store.KVPair {
+	if pair == nil {
+		return nil
+	}
+	p := *pair
+	p.Key = s.key(p.Key)
+	return &p
+}
+
+func (s *Store) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
+	return s.Store.NewLock(s.key(key), options)
+}
+
+// Watch removes the prefix from the keys of the watched pairs.
+func (s *Store) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
+	events, err := s.Store.Watch(s.key(key), stopCh)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *store.KVPair)
+	go func() {
+		defer close(out)
+		for pair := range events {
+			s.strip(pair)
+			select {
+			case out <- pair:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// WatchTree removes the prefix from the keys of the watched pairs.
+func (s *Store) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	events, err := s.Store.WatchTree(s.key(directory), stopCh)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan []*store.KVPair)
+	go func() {
+		defer close(out)
+		for pairs := range events {
+			for _, pair := range pairs {
+				s.strip(pair)
+			}
+			select {
+			case out <- pairs:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
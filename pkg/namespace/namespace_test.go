@@ -0,0 +1,86 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/metalmatze/alertmanager-bot/pkg/kvtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+	require.NoError(t, kv.Put("telegram/chats/1", []byte(`{"id":1}`), nil))
+
+	a, err := NewStore(kv, "/team-a/")
+	require.NoError(t, err)
+	b, err := NewStore(kv, "team-b")
+	require.NoError(t, err)
+
+	require.NoError(t, a.Put("telegram/chats/2", []byte(`{"id":2}`), nil))
+	require.NoError(t, b.Put("telegram/chats/3", []byte(`{"id":3}`), nil))
+
+	raw, err := kv.Get("team-a/telegram/chats/2")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"id":2}`), raw.Value)
+
+	pair, err := a.Get("telegram/chats/2")
+	require.NoError(t, err)
+	require.Equal(t, "telegram/chats/2", pair.Key)
+
+	// The keys without and with other prefixes aren't visible.
+	pairs, err := a.List("telegram/chats")
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	require.Equal(t, "telegram/chats/2", pairs[0].Key)
+
+	_, err = b.Get("telegram/chats/2")
+	require.Equal(t, store.ErrKeyNotFound, err)
+
+	require.NoError(t, a.DeleteTree("telegram"))
+	exists, err := b.Exists("telegram/chats/3")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = kv.Exists("telegram/chats/1")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	_, err = NewStore(kv, "/")
+	require.Error(t, err)
+}
+
+// watchingStore sends the pairs below the watched directory once per WatchTree.
+type watchingStore struct {
+	store.Store
+}
+
+func (s *watchingStore) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	pairs, err := s.List(directory)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan []*store.KVPair, 1)
+	events <- pairs
+	close(events)
+	return events, nil
+}
+
+func TestStoreWatchTree(t *testing.T) {
+	kv, closeStore := kvtest.New(t)
+	defer closeStore()
+	require.NoError(t, kv.Put("team-a/telegram/chats/1", []byte(`{"id":1}`), nil))
+
+	s, err := NewStore(&watchingStore{Store: kv}, "team-a")
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	events, err := s.WatchTree("telegram/chats", stop)
+	require.NoError(t, err)
+	pairs := <-events
+	require.Len(t, pairs, 1)
+	require.Equal(t, "telegram/chats/1", pairs[0].Key)
+	_, ok := <-events
+	require.False(t, ok, "the watch ends with the watch of the wrapped store")
+}
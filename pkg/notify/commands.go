@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Command is a command sent to the bot in the chat of an output.
+type Command struct {
+	// User is who sent the command, the email address in Webex and the username in Mattermost.
+	User string
+	// Text is the message with the command, e.g. /alerts silenced.
+	Text string
+}
+
+// Commands receives the commands sent to the bot in the chat of an output with HTTP requests and replies to them.
+type Commands interface {
+	// Receive returns the command of the request, false if it isn't a command for the bot, e.g. a message of the bot itself.
+	Receive(r *http.Request) (Command, bool, error)
+	// Reply sends the message in response to the command of the request.
+	Reply(ctx context.Context, w http.ResponseWriter, msg Message) error
+}
+
+// ErrUnauthenticated is returned by Receive if the request isn't signed by or has the token of the chat service.
+var ErrUnauthenticated = errors.New("request isn't authenticated")
+
+// WebexCommands receives the messages of a Webex Teams room with a webhook for messages created
+// and replies with messages to the room.
+type WebexCommands struct {
+	webex  *Webex
+	secret []byte
+}
+
+// NewWebexCommands receives the commands sent in the room of the Webex with a webhook signed with the secret.
+func NewWebexCommands(webex *Webex, secret string) *WebexCommands {
+	return &WebexCommands{webex: webex, secret: []byte(secret)}
+}
+
+// Receive verifies the signature of the webhook and gets the message from the Webex API, as the webhook only has its ID.
+func (c *WebexCommands) Receive(r *http.Request) (Command, bool, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Command{}, false, err
+	}
+	mac := hmac.New(sha1.New, c.secret)
+	mac.Write(body)
+	signature, err := hex.DecodeString(r.Header.Get("X-Spark-Signature"))
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return Command{}, false, ErrUnauthenticated
+	}
+
+	var event struct {
+		Resource string `json:"resource"`
+		Event    string `json:"event"`
+		Data     struct {
+			ID          string `json:"id"`
+			RoomID      string `json:"roomId"`
+			PersonEmail string `json:"personEmail"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return Command{}, false, err
+	}
+	// Messages of bots, including the bot's own replies, are ignored.
+	if event.Resource != "messages" || event.Event != "created" || event.Data.RoomID != c.webex.roomID ||
+		strings.HasSuffix(event.Data.PersonEmail, "@webex.bot") {
+		return Command{}, false, nil
+	}
+
+	var message struct {
+		Text string `json:"text"`
+	}
+	if err := c.webex.get(r.Context(), "/v1/messages/"+event.Data.ID, &message); err != nil {
+		return Command{}, false, err
+	}
+	// In group rooms the message starts with the mention of the bot, e.g. Alertmanager /alerts.
+	i := strings.Index(message.Text, "/")
+	if i < 0 {
+		return Command{}, false, nil
+	}
+	return Command{User: event.Data.PersonEmail, Text: strings.TrimSpace(message.Text[i:])}, true, nil
+}
+
+// Reply sends the message to the room, the webhook is only acknowledged.
+func (c *WebexCommands) Reply(ctx context.Context, w http.ResponseWriter, msg Message) error {
+	if err := c.webex.Notify(ctx, msg); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// get decodes the JSON the Webex API responds with to a GET request of the path.
+func (w *Webex) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.token)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// MattermostCommands receives the commands of a Mattermost slash command or outgoing webhook
+// and replies in the response to it.
+type MattermostCommands struct {
+	token []byte
+}
+
+// NewMattermostCommands receives the commands of the slash command or outgoing webhook with the token.
+func NewMattermostCommands(token string) *MattermostCommands {
+	return &MattermostCommands{token: []byte(token)}
+}
+
+// Receive verifies the token of the request. Slash commands like /alertmanager alerts are received as /alerts,
+// outgoing webhooks triggered by words like /alerts are received as they are.
+func (c *MattermostCommands) Receive(r *http.Request) (Command, bool, error) {
+	if err := r.ParseForm(); err != nil {
+		return Command{}, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("token")), c.token) != 1 {
+		return Command{}, false, ErrUnauthenticated
+	}
+
+	text := strings.TrimSpace(r.PostForm.Get("text"))
+	if r.PostForm.Get("command") != "" {
+		text = "/" + text
+	}
+	if !strings.HasPrefix(text, "/") || text == "/" {
+		return Command{}, false, nil
+	}
+	return Command{User: r.PostForm.Get("user_name"), Text: text}, true, nil
+}
+
+// Reply responds with the message, visible to the whole channel.
+func (c *MattermostCommands) Reply(_ context.Context, w http.ResponseWriter, msg Message) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(struct {
+		ResponseType string `json:"response_type"`
+		Text         string `json:"text"`
+	}{ResponseType: "in_channel", Text: msg.Markdown})
+}
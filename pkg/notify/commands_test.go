@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// webexWebhook returns the request of a webhook for the created message, signed with the secret.
+func webexWebhook(secret, room, email string) *http.Request {
+	body := `{"resource":"messages","event":"created","data":{"id":"m3ss4ge","roomId":"` + room + `","personEmail":"` + email + `"}}`
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	r := httptest.NewRequest(http.MethodPost, "/commands/telegram/webex", strings.NewReader(body))
+	r.Header.Set("X-Spark-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestWebexCommands(t *testing.T) {
+	var sent map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/v1/messages/m3ss4ge":
+			require.Equal(t, http.MethodGet, r.Method)
+			_, _ = w.Write([]byte(`{"id":"m3ss4ge","text":"Alertmanager /alerts silenced"}`))
+		case "/v1/messages":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+		default:
+			t.Fatalf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewWebexCommands(NewWebex(server.URL, "secret", "r00m", time.Second), "w3bh00k")
+
+	cmd, ok, err := c.Receive(webexWebhook("w3bh00k", "r00m", "alice@example.com"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Command{User: "alice@example.com", Text: "/alerts silenced"}, cmd)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, c.Reply(context.Background(), w, Message{Markdown: "No alerts right now! 🎉"}))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, map[string]string{"roomId": "r00m", "markdown": "No alerts right now! 🎉"}, sent)
+
+	// The bot's own replies and messages of other rooms are ignored.
+	_, ok, err = c.Receive(webexWebhook("w3bh00k", "r00m", "alertmanager@webex.bot"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = c.Receive(webexWebhook("w3bh00k", "other", "alice@example.com"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = c.Receive(webexWebhook("guessed", "r00m", "alice@example.com"))
+	require.Equal(t, ErrUnauthenticated, err)
+}
+
+// mattermostCommand returns the request of a slash command or outgoing webhook with the form.
+func mattermostCommand(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/commands/telegram/mattermost", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestMattermostCommands(t *testing.T) {
+	c := NewMattermostCommands("t0ken")
+
+	cmd, ok, err := c.Receive(mattermostCommand(url.Values{
+		"token":     {"t0ken"},
+		"command":   {"/alertmanager"},
+		"text":      {"alerts silenced"},
+		"user_name": {"alice"},
+	}))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Command{User: "alice", Text: "/alerts silenced"}, cmd)
+
+	cmd, ok, err = c.Receive(mattermostCommand(url.Values{
+		"token":        {"t0ken"},
+		"trigger_word": {"/status"},
+		"text":         {"/status"},
+		"user_name":    {"bob"},
+	}))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Command{User: "bob", Text: "/status"}, cmd)
+
+	_, ok, err = c.Receive(mattermostCommand(url.Values{"token": {"t0ken"}, "text": {"hello"}, "user_name": {"bob"}}))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = c.Receive(mattermostCommand(url.Values{"token": {"guessed"}, "text": {"/status"}}))
+	require.Equal(t, ErrUnauthenticated, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, c.Reply(context.Background(), w, Message{Markdown: "No alerts right now! 🎉"}))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"response_type":"in_channel","text":"No alerts right now! 🎉"}`, w.Body.String())
+}
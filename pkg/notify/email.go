@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email sends messages by email to a distribution list with an SMTP server.
+type Email struct {
+	smarthost string
+	from      string
+	to        []string
+	auth      smtp.Auth
+	timeout   time.Duration
+}
+
+// NewEmail returns an Email sending from the address from to the addresses to with the SMTP server at smarthost, e.g. smtp.example.com:587,
+// waiting at most timeout. The username and password authenticate with the server unless the username is empty.
+// STARTTLS is used if the server supports it.
+func NewEmail(smarthost, from string, to []string, username, password string, timeout time.Duration) *Email {
+	e := &Email{smarthost: smarthost, from: from, to: to, timeout: timeout}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(smarthost)
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Notify sends the message as an email with the HTML and a plain text alternative.
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+	body, err := e.message(msg)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(e.smarthost)
+	if err != nil {
+		return err
+	}
+	conn, err := (&net.Dialer{Timeout: e.timeout}).DialContext(ctx, "tcp", e.smarthost)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(e.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if err := c.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message returns the email with the headers and a multipart body of the Markdown as plain text and the HTML.
+func (e *Email) message(msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	plain, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plain.Write([]byte(msg.Markdown)); err != nil {
+		return nil, err
+	}
+
+	rich, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return nil, err
+	}
+	// The templates for Telegram separate lines with newlines instead of tags.
+	if _, err := fmt.Fprintf(rich, "<html><body>%s</body></html>", strings.ReplaceAll(msg.HTML, "\n", "<br>\n")); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From: %s\r\n", e.from)
+	fmt.Fprintf(&out, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&out, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&out, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&out, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer accepts a single email and sends its envelope and content to the returned channel.
+func smtpServer(t *testing.T) (string, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	received := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		var envelope []string
+		_ = tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				_ = tp.PrintfLine("250 OK")
+			case "DATA":
+				_ = tp.PrintfLine("354 Go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- append(envelope, string(data))
+				_ = tp.PrintfLine("250 OK")
+			case "QUIT":
+				_ = tp.PrintfLine("221 Bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestEmail(t *testing.T) {
+	addr, received := smtpServer(t)
+
+	e := NewEmail(addr, "alertmanager@example.com", []string{"ops@example.com", "db@example.com"}, "", "", time.Second)
+	err := e.Notify(context.Background(), Message{
+		Subject:  "[FIRING:1] fire",
+		HTML:     "🔥 <b>fire</b> 🔥\n<b>Labels:</b>",
+		Markdown: "🔥 **fire** 🔥\n**Labels:**",
+	})
+	require.NoError(t, err)
+
+	r := <-received
+	require.Equal(t, []string{
+		"MAIL FROM:<alertmanager@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<db@example.com>",
+	}, r[:3])
+
+	m, err := mail.ReadMessage(strings.NewReader(r[3]))
+	require.NoError(t, err)
+	require.Equal(t, "alertmanager@example.com", m.Header.Get("From"))
+	require.Equal(t, "ops@example.com, db@example.com", m.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "[FIRING:1] fire", subject)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	var bodies []string
+	for {
+		p, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(bufio.NewReader(p))
+		require.NoError(t, err)
+		bodies = append(bodies, p.Header.Get("Content-Type")+"\n"+string(b))
+	}
+	require.Equal(t, []string{
+		"text/plain; charset=UTF-8\n🔥 **fire** 🔥\n**Labels:**",
+		"text/html; charset=UTF-8\n<html><body>🔥 <b>fire</b> 🔥<br>\n<b>Labels:</b></body></html>",
+	}, bodies)
+}
@@ -0,0 +1,113 @@
+// Package notify sends rendered alerts to chat services other than Telegram and by email,
+// and receives the commands sent to the bot in these chat services.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Message are alerts rendered for a Notifier.
+type Message struct {
+	// Subject summarizes the alerts, e.g. [FIRING:2] NodeDown.
+	Subject string
+	// HTML are the alerts rendered with the templates for Telegram, Markdown the same converted to Markdown.
+	HTML     string
+	Markdown string
+}
+
+// Notifier sends a message.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Webex sends messages to a Webex Teams room as a bot.
+type Webex struct {
+	url    string
+	token  string
+	roomID string
+	client *http.Client
+}
+
+// NewWebex returns a Webex sending messages to the room with the ID roomID,
+// authenticating with the bot's token and waiting at most timeout for the Webex API at apiURL.
+func NewWebex(apiURL, token, roomID string, timeout time.Duration) *Webex {
+	return &Webex{
+		url:    strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+		roomID: roomID,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify sends the message to the room.
+func (w *Webex) Notify(ctx context.Context, msg Message) error {
+	message := struct {
+		RoomID   string `json:"roomId"`
+		Markdown string `json:"markdown"`
+	}{RoomID: w.roomID, Markdown: msg.Markdown}
+
+	return post(ctx, w.client, w.url+"/v1/messages", message, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	})
+}
+
+// Mattermost sends messages to a channel of Mattermost with an incoming webhook.
+type Mattermost struct {
+	url      string
+	channel  string
+	username string
+	client   *http.Client
+}
+
+// NewMattermost returns a Mattermost sending messages to the incoming webhook at url, waiting at most timeout.
+// The channel and username override the ones of the webhook unless they're empty.
+func NewMattermost(url, channel, username string, timeout time.Duration) *Mattermost {
+	return &Mattermost{
+		url:      url,
+		channel:  channel,
+		username: username,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify sends the message to the channel.
+func (m *Mattermost) Notify(ctx context.Context, msg Message) error {
+	message := struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+	}{Text: msg.Markdown, Channel: m.channel, Username: m.username}
+
+	return post(ctx, m.client, m.url, message, func(*http.Request) {})
+}
+
+// post sends the message as JSON to the url.
+func post(ctx context.Context, client *http.Client, url string, message interface{}, authenticate func(*http.Request)) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authenticate(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
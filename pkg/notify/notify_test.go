@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/v1/messages", r.URL.Path)
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var message map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		require.Equal(t, map[string]string{"roomId": "r00m", "markdown": "🔥 **fire** 🔥"}, message)
+	}))
+	defer server.Close()
+
+	w := NewWebex(server.URL+"/", "secret", "r00m", time.Second)
+	require.NoError(t, w.Notify(context.Background(), Message{Markdown: "🔥 **fire** 🔥"}))
+}
+
+func TestMattermost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/hooks/xyz", r.URL.Path)
+
+		var message map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		require.Equal(t, map[string]string{"text": "🔥 **fire** 🔥", "channel": "alerts"}, message)
+	}))
+	defer server.Close()
+
+	m := NewMattermost(server.URL+"/hooks/xyz", "alerts", "", time.Second)
+	require.NoError(t, m.Notify(context.Background(), Message{Markdown: "🔥 **fire** 🔥"}))
+}
+
+func TestNotifyError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	m := NewMattermost(server.URL+"/hooks/unknown", "", "", time.Second)
+	require.EqualError(t, m.Notify(context.Background(), Message{Markdown: "🔥 **fire** 🔥"}), "unexpected status 404 Not Found")
+}
@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []*testNode       `json:"children,omitempty"`
+	At       time.Time         `json:"at"`
+	Raw      json.RawMessage   `json:"raw"`
+	Data     []byte            `json:"data,omitempty"`
+	Ignored  string            `json:"-"`
+	Untagged bool
+	private  string
+}
+
+func TestSchema(t *testing.T) {
+	d := New(Info{Title: "test", Version: "1"})
+
+	require.Equal(t, &Schema{Type: "string"}, d.Schema(""))
+	require.Equal(t, &Schema{Type: "integer", Format: "int64"}, d.Schema(int64(0)))
+	require.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "number", Format: "double"}}, d.Schema([]float64{}))
+	require.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{"url": {Type: "string"}}, Required: []string{"url"}}, d.Schema(struct {
+		URL string `json:"url"`
+	}{}))
+	require.Empty(t, d.Components.Schemas, "anonymous structs are inlined")
+
+	ref := &Schema{Ref: "#/components/schemas/testNode"}
+	require.Equal(t, ref, d.Schema(&testNode{}))
+	require.Equal(t, ref, d.Schema(testNode{}))
+	require.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":       {Type: "integer", Format: "int64"},
+			"name":     {Type: "string"},
+			"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"children": {Type: "array", Items: ref},
+			"at":       {Type: "string", Format: "date-time"},
+			"raw":      {},
+			"data":     {Type: "string", Format: "byte"},
+			"Untagged": {Type: "boolean"},
+		},
+		Required: []string{"id", "name", "at", "raw", "Untagged"},
+	}, d.Components.Schemas["testNode"])
+	require.Len(t, d.Components.Schemas, 1)
+}
+
+func TestDocument(t *testing.T) {
+	d := New(Info{Title: "test", Version: "1"})
+	d.Add(http.MethodGet, "/things", &Operation{
+		Summary:     "List things",
+		OperationID: "listThings",
+		Responses:   map[string]Response{"200": {Description: "The things", Content: JSON(d.Schema([]testBase{}))}},
+	})
+	d.Add(http.MethodPost, "/things", &Operation{
+		Summary:     "Add a thing",
+		OperationID: "addThing",
+		RequestBody: &RequestBody{Required: true, Content: JSON(d.Schema(testBase{}))},
+		Responses:   map[string]Response{"201": {Description: "Added"}},
+		Security:    &[]SecurityRequirement{},
+	})
+
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"openapi": "3.0.3",
+		"info": {"title": "test", "version": "1"},
+		"paths": {
+			"/things": {
+				"get": {
+					"summary": "List things",
+					"operationId": "listThings",
+					"responses": {"200": {"description": "The things", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/testBase"}}}}}}
+				},
+				"post": {
+					"summary": "Add a thing",
+					"operationId": "addThing",
+					"requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/testBase"}}}},
+					"responses": {"201": {"description": "Added"}},
+					"security": []
+				}
+			}
+		},
+		"components": {
+			"schemas": {
+				"testBase": {"type": "object", "properties": {"id": {"type": "integer", "format": "int64"}}, "required": ["id"]}
+			}
+		}
+	}`, string(b))
+}
@@ -0,0 +1,48 @@
+package prometheus
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+type Client struct {
+	prometheus v1.API
+}
+
+func NewClient(url *url.URL) (*Client, error) {
+	client, err := api.NewClient(api.Config{Address: url.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{prometheus: v1.NewAPI(client)}, nil
+}
+
+// Query runs an instant query at the current time.
+func (c *Client) Query(ctx context.Context, query string) (model.Value, error) {
+	value, _, err := c.prometheus.Query(ctx, query, time.Now())
+	return value, err
+}
+
+// Targets returns the active scrape targets.
+func (c *Client) Targets(ctx context.Context) ([]v1.ActiveTarget, error) {
+	targets, err := c.prometheus.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return targets.Active, nil
+}
+
+// Rules returns the rule groups with their alerting and recording rules.
+func (c *Client) Rules(ctx context.Context) ([]v1.RuleGroup, error) {
+	rules, err := c.prometheus.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Groups, nil
+}
@@ -0,0 +1,37 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonQuery = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"node"},"value":[1614000000,"1"]}]}}`
+
+func TestClient(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(jsonQuery))
+	})
+
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	client, err := NewClient(u)
+	require.NoError(t, err)
+
+	value, err := client.Query(context.Background(), "up")
+	require.NoError(t, err)
+	require.Equal(t, model.Vector{{
+		Metric:    model.Metric{"__name__": "up", "job": "node"},
+		Value:     1,
+		Timestamp: 1614000000000,
+	}}, value)
+}
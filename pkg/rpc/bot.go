@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// The messages of bot.proto. They are written by hand with protobuf struct tags,
+// instead of being generated, and have to be kept in sync with bot.proto.
+
+type Alert struct {
+	Labels       map[string]string `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations  map[string]string `protobuf:"bytes,2,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Resolved     bool              `protobuf:"varint,3,opt,name=resolved,proto3" json:"resolved,omitempty"`
+	GeneratorURL string            `protobuf:"bytes,4,opt,name=generator_url,json=generatorUrl,proto3" json:"generator_url,omitempty"`
+}
+
+func (m *Alert) Reset()         { *m = Alert{} }
+func (m *Alert) String() string { return proto.CompactTextString(m) }
+func (*Alert) ProtoMessage()    {}
+
+type NotifyRequest struct {
+	Tenant string   `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	ChatID int64    `protobuf:"varint,2,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
+	Group  string   `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"`
+	Alerts []*Alert `protobuf:"bytes,4,rep,name=alerts,proto3" json:"alerts,omitempty"`
+}
+
+func (m *NotifyRequest) Reset()         { *m = NotifyRequest{} }
+func (m *NotifyRequest) String() string { return proto.CompactTextString(m) }
+func (*NotifyRequest) ProtoMessage()    {}
+
+type NotifyResponse struct{}
+
+func (m *NotifyResponse) Reset()         { *m = NotifyResponse{} }
+func (m *NotifyResponse) String() string { return proto.CompactTextString(m) }
+func (*NotifyResponse) ProtoMessage()    {}
+
+type ListChatsRequest struct {
+	Tenant string `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+}
+
+func (m *ListChatsRequest) Reset()         { *m = ListChatsRequest{} }
+func (m *ListChatsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListChatsRequest) ProtoMessage()    {}
+
+type Chat struct {
+	ID       int64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type     string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Title    string `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Username string `protobuf:"bytes,4,opt,name=username,proto3" json:"username,omitempty"`
+}
+
+func (m *Chat) Reset()         { *m = Chat{} }
+func (m *Chat) String() string { return proto.CompactTextString(m) }
+func (*Chat) ProtoMessage()    {}
+
+type ListChatsResponse struct {
+	Chats []*Chat `protobuf:"bytes,1,rep,name=chats,proto3" json:"chats,omitempty"`
+}
+
+func (m *ListChatsResponse) Reset()         { *m = ListChatsResponse{} }
+func (m *ListChatsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListChatsResponse) ProtoMessage()    {}
+
+type CreateSilenceRequest struct {
+	Matchers        map[string]string `protobuf:"bytes,1,rep,name=matchers,proto3" json:"matchers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	DurationSeconds int64             `protobuf:"varint,2,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	CreatedBy       string            `protobuf:"bytes,3,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Comment         string            `protobuf:"bytes,4,opt,name=comment,proto3" json:"comment,omitempty"`
+}
+
+func (m *CreateSilenceRequest) Reset()         { *m = CreateSilenceRequest{} }
+func (m *CreateSilenceRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateSilenceRequest) ProtoMessage()    {}
+
+type CreateSilenceResponse struct {
+	ID string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (m *CreateSilenceResponse) Reset()         { *m = CreateSilenceResponse{} }
+func (m *CreateSilenceResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateSilenceResponse) ProtoMessage()    {}
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// defaultTenant is used if requests don't name a tenant, like the admin HTTP endpoints do.
+const defaultTenant = "telegram"
+
+// Tenant is a bot the server notifies and lists the chats of.
+type Tenant struct {
+	Chats interface {
+		List() ([]*telebot.Chat, error)
+	}
+	// Webhooks are processed by the tenant's bot like the ones received from Alertmanager.
+	Webhooks chan<- alertmanager.TelegramWebhook
+}
+
+// Silencer creates silences in Alertmanager.
+type Silencer interface {
+	CreateSilence(ctx context.Context, matchers []*alertmanager.Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error)
+}
+
+// Server implements the Bot service for the tenants.
+type Server struct {
+	tenants  map[string]Tenant
+	silencer Silencer
+}
+
+// NewServer returns a Server for the tenants by name creating silences with the silencer.
+func NewServer(tenants map[string]Tenant, silencer Silencer) *Server {
+	return &Server{tenants: tenants, silencer: silencer}
+}
+
+func (s *Server) tenant(name string) (Tenant, error) {
+	if name == "" {
+		name = defaultTenant
+	}
+	t, ok := s.tenants[name]
+	if !ok {
+		return Tenant{}, status.Errorf(codes.NotFound, "tenant %s not found", name)
+	}
+	return t, nil
+}
+
+// Notify sends the alerts to the tenant's bot as a webhook message.
+func (s *Server) Notify(ctx context.Context, req *NotifyRequest) (*NotifyResponse, error) {
+	t, err := s.tenant(req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	if (req.ChatID == 0) == (req.Group == "") {
+		return nil, status.Error(codes.InvalidArgument, "either chat_id or group has to be set")
+	}
+	if len(req.Alerts) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "alerts are missing")
+	}
+
+	message := webhookMessage(req.Alerts, time.Now())
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	select {
+	case t.Webhooks <- alertmanager.TelegramWebhook{ChatID: req.ChatID, Group: req.Group, Message: message, Payload: payload, ReceivedAt: time.Now()}:
+		return &NotifyResponse{}, nil
+	case <-ctx.Done():
+		return nil, status.Error(codes.Canceled, ctx.Err().Error())
+	}
+}
+
+// webhookMessage converts the alerts to a message as if Alertmanager sent them.
+func webhookMessage(alerts []*Alert, now time.Time) webhook.Message {
+	data := &template.Data{Receiver: "grpc", Status: string(model.AlertResolved), CommonLabels: template.KV{}}
+	for i, a := range alerts {
+		labels := model.LabelSet{}
+		for name, value := range a.Labels {
+			labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+
+		alert := template.Alert{
+			Status:       string(model.AlertFiring),
+			Labels:       template.KV(a.Labels),
+			Annotations:  template.KV(a.Annotations),
+			StartsAt:     now,
+			GeneratorURL: a.GeneratorURL,
+			Fingerprint:  labels.Fingerprint().String(),
+		}
+		if a.Resolved {
+			alert.Status = string(model.AlertResolved)
+			alert.EndsAt = now
+		} else {
+			data.Status = string(model.AlertFiring)
+		}
+		data.Alerts = append(data.Alerts, alert)
+
+		// Common labels are the ones all alerts have with the same value.
+		if i == 0 {
+			for name, value := range a.Labels {
+				data.CommonLabels[name] = value
+			}
+		}
+		for name, value := range data.CommonLabels {
+			if a.Labels[name] != value {
+				delete(data.CommonLabels, name)
+			}
+		}
+	}
+
+	common := make([]string, 0, len(data.CommonLabels))
+	for _, name := range data.CommonLabels.SortedPairs().Names() {
+		common = append(common, fmt.Sprintf("%s=%q", name, data.CommonLabels[name]))
+	}
+
+	return webhook.Message{
+		Data:     data,
+		Version:  "4",
+		GroupKey: "grpc:{" + strings.Join(common, ",") + "}",
+	}
+}
+
+// ListChats returns the chats subscribed to the tenant's bot.
+func (s *Server) ListChats(_ context.Context, req *ListChatsRequest) (*ListChatsResponse, error) {
+	t, err := s.tenant(req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	chats, err := t.Chats.List()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &ListChatsResponse{}
+	for _, c := range chats {
+		resp.Chats = append(resp.Chats, &Chat{ID: c.ID, Type: string(c.Type), Title: c.Title, Username: c.Username})
+	}
+	return resp, nil
+}
+
+// CreateSilence silences the alerts matching the matchers for the duration.
+func (s *Server) CreateSilence(ctx context.Context, req *CreateSilenceRequest) (*CreateSilenceResponse, error) {
+	if len(req.Matchers) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "matchers are missing")
+	}
+	if req.DurationSeconds <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "duration_seconds has to be positive")
+	}
+	if req.CreatedBy == "" {
+		return nil, status.Error(codes.InvalidArgument, "created_by is missing")
+	}
+
+	now := time.Now()
+	id, err := s.silencer.CreateSilence(ctx, alertmanager.EqualMatchers(req.Matchers), now, now.Add(time.Duration(req.DurationSeconds)*time.Second), req.CreatedBy, req.Comment)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &CreateSilenceResponse{ID: id}, nil
+}
+
+// TokenAuth only lets calls with the token as bearer token in their authorization metadata through.
+func TokenAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var auth string
+		if values := md.Get("authorization"); len(values) > 0 {
+			auth = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(ctx, req)
+	}
+}
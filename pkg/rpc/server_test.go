@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+type testChats []*telebot.Chat
+
+func (c testChats) List() ([]*telebot.Chat, error) {
+	return c, nil
+}
+
+type testSilencer struct {
+	matchers []*alertmanager.Matcher
+	duration time.Duration
+}
+
+func (s *testSilencer) CreateSilence(_ context.Context, matchers []*alertmanager.Matcher, startsAt, endsAt time.Time, _, _ string) (string, error) {
+	s.matchers = matchers
+	s.duration = endsAt.Sub(startsAt)
+	return "7e9a", nil
+}
+
+func testClient(t *testing.T, srv *Server) (BotClient, func()) {
+	l := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.UnaryInterceptor(TokenAuth("secret")))
+	RegisterBotServer(s, srv)
+	go func() { _ = s.Serve(l) }()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+
+	return NewBotClient(conn), func() {
+		_ = conn.Close()
+		s.Stop()
+	}
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+}
+
+func TestServer(t *testing.T) {
+	webhooks := make(chan alertmanager.TelegramWebhook, 1)
+	silencer := &testSilencer{}
+	client, stop := testClient(t, NewServer(map[string]Tenant{
+		"telegram": {
+			Chats:    testChats{{ID: -1234, Type: telebot.ChatGroup, Title: "sre"}},
+			Webhooks: webhooks,
+		},
+	}, silencer))
+	defer stop()
+
+	_, err := client.ListChats(context.Background(), &ListChatsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	chats, err := client.ListChats(authorized(), &ListChatsRequest{})
+	require.NoError(t, err)
+	require.Len(t, chats.Chats, 1)
+	require.Equal(t, &Chat{ID: -1234, Type: "group", Title: "sre"}, chats.Chats[0])
+
+	_, err = client.ListChats(authorized(), &ListChatsRequest{Tenant: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Notify(authorized(), &NotifyRequest{Alerts: []*Alert{{Labels: map[string]string{"alertname": "fire"}}}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Notify(authorized(), &NotifyRequest{
+		Group: "sre",
+		Alerts: []*Alert{
+			{Labels: map[string]string{"alertname": "fire", "instance": "a"}, Annotations: map[string]string{"message": "Something is on fire"}},
+			{Labels: map[string]string{"alertname": "fire", "instance": "b"}, Resolved: true},
+		},
+	})
+	require.NoError(t, err)
+
+	w := <-webhooks
+	require.Equal(t, "sre", w.Group)
+	require.Equal(t, "firing", w.Message.Status)
+	require.Len(t, w.Message.Alerts, 2)
+	require.Equal(t, "resolved", w.Message.Alerts[1].Status)
+	require.Equal(t, "Something is on fire", w.Message.Alerts[0].Annotations["message"])
+	require.Equal(t, `grpc:{alertname="fire"}`, w.Message.GroupKey)
+	require.NotEmpty(t, w.Payload)
+	require.False(t, w.ReceivedAt.IsZero())
+
+	silence, err := client.CreateSilence(authorized(), &CreateSilenceRequest{
+		Matchers:        map[string]string{"alertname": "fire"},
+		DurationSeconds: 3600,
+		CreatedBy:       "deploy",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "7e9a", silence.ID)
+	require.Equal(t, alertmanager.EqualMatchers(map[string]string{"alertname": "fire"}), silencer.matchers)
+	require.Equal(t, time.Hour, silencer.duration)
+}
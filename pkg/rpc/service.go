@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// BotServer is the server API for the Bot service of bot.proto.
+type BotServer interface {
+	Notify(context.Context, *NotifyRequest) (*NotifyResponse, error)
+	ListChats(context.Context, *ListChatsRequest) (*ListChatsResponse, error)
+	CreateSilence(context.Context, *CreateSilenceRequest) (*CreateSilenceResponse, error)
+}
+
+// RegisterBotServer registers the Bot service with a gRPC server.
+func RegisterBotServer(s *grpc.Server, srv BotServer) {
+	s.RegisterService(&botServiceDesc, srv)
+}
+
+const serviceName = "alertmanagerbot.v1.Bot"
+
+var botServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*BotServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Notify",
+		Handler: unaryHandler("Notify", func() interface{} { return new(NotifyRequest) }, func(ctx context.Context, srv BotServer, req interface{}) (interface{}, error) {
+			return srv.Notify(ctx, req.(*NotifyRequest))
+		}),
+	}, {
+		MethodName: "ListChats",
+		Handler: unaryHandler("ListChats", func() interface{} { return new(ListChatsRequest) }, func(ctx context.Context, srv BotServer, req interface{}) (interface{}, error) {
+			return srv.ListChats(ctx, req.(*ListChatsRequest))
+		}),
+	}, {
+		MethodName: "CreateSilence",
+		Handler: unaryHandler("CreateSilence", func() interface{} { return new(CreateSilenceRequest) }, func(ctx context.Context, srv BotServer, req interface{}) (interface{}, error) {
+			return srv.CreateSilence(ctx, req.(*CreateSilenceRequest))
+		}),
+	}},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bot.proto",
+}
+
+// unaryHandler decodes a method's request and calls it through the server's interceptor.
+func unaryHandler(method string, newRequest func() interface{}, call func(context.Context, BotServer, interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(ctx, srv.(BotServer), in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(ctx, srv.(BotServer), req)
+		})
+	}
+}
+
+// BotClient is the client API for the Bot service of bot.proto.
+type BotClient interface {
+	Notify(ctx context.Context, in *NotifyRequest, opts ...grpc.CallOption) (*NotifyResponse, error)
+	ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error)
+	CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*CreateSilenceResponse, error)
+}
+
+type botClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewBotClient returns a client calling the Bot service on the connection.
+func NewBotClient(cc grpc.ClientConnInterface) BotClient {
+	return &botClient{cc: cc}
+}
+
+func (c *botClient) Notify(ctx context.Context, in *NotifyRequest, opts ...grpc.CallOption) (*NotifyResponse, error) {
+	out := new(NotifyResponse)
+	err := c.cc.Invoke(ctx, "/"+serviceName+"/Notify", in, out, opts...)
+	return out, err
+}
+
+func (c *botClient) ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error) {
+	out := new(ListChatsResponse)
+	err := c.cc.Invoke(ctx, "/"+serviceName+"/ListChats", in, out, opts...)
+	return out, err
+}
+
+func (c *botClient) CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*CreateSilenceResponse, error) {
+	out := new(CreateSilenceResponse)
+	err := c.cc.Invoke(ctx, "/"+serviceName+"/CreateSilence", in, out, opts...)
+	return out, err
+}
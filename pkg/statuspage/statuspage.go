@@ -0,0 +1,119 @@
+// Package statuspage creates and resolves incidents of a Statuspage.io page.
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// StatusInvestigating is the status incidents are created with.
+	StatusInvestigating = "investigating"
+	// StatusResolved is the status of resolved incidents.
+	StatusResolved = "resolved"
+
+	componentMajorOutage = "major_outage"
+	componentOperational = "operational"
+)
+
+// Client creates and updates the incidents of a page with the Statuspage REST API.
+type Client struct {
+	url    string
+	pageID string
+	apiKey string
+	client *http.Client
+}
+
+// NewClient returns a Client for the page of the Statuspage API at apiURL, usually https://api.statuspage.io,
+// authenticating with the API key and waiting at most timeout for a response.
+func NewClient(apiURL, pageID, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		url:    strings.TrimSuffix(apiURL, "/"),
+		pageID: pageID,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type incident struct {
+	Name         string            `json:"name,omitempty"`
+	Status       string            `json:"status,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	ComponentIDs []string          `json:"component_ids,omitempty"`
+	Components   map[string]string `json:"components,omitempty"`
+}
+
+// CreateIncident creates an investigating incident with the components in a major outage and returns its ID.
+func (c *Client) CreateIncident(ctx context.Context, name, body string, components []string) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/incidents", incident{
+		Name:         name,
+		Status:       StatusInvestigating,
+		Body:         body,
+		ComponentIDs: components,
+		Components:   componentStatus(components, componentMajorOutage),
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// UpdateIncident posts an update with the body to the incident, keeping its status.
+func (c *Client) UpdateIncident(ctx context.Context, id, body string) error {
+	return c.do(ctx, http.MethodPatch, "/incidents/"+id, incident{Body: body}, nil)
+}
+
+// ResolveIncident resolves the incident with the body and marks the components as operational again.
+func (c *Client) ResolveIncident(ctx context.Context, id, body string, components []string) error {
+	return c.do(ctx, http.MethodPatch, "/incidents/"+id, incident{
+		Status:     StatusResolved,
+		Body:       body,
+		Components: componentStatus(components, componentOperational),
+	}, nil)
+}
+
+func componentStatus(components []string, status string) map[string]string {
+	statuses := make(map[string]string, len(components))
+	for _, id := range components {
+		statuses[id] = status
+	}
+	return statuses
+}
+
+// do sends the incident to the path of the page and decodes the response into response, unless it's nil.
+func (c *Client) do(ctx context.Context, method, path string, i incident, response interface{}) error {
+	body, err := json.Marshal(struct {
+		Incident incident `json:"incident"`
+	}{Incident: i})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+"/v1/pages/"+c.pageID+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "OAuth "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -0,0 +1,73 @@
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	Incident incident `json:"incident"`
+}
+
+func TestClient(t *testing.T) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "OAuth secret", r.Header.Get("Authorization"))
+
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pages/p4g3/incidents":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"inc1","name":"API is down","status":"investigating"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/v1/pages/p4g3/incidents/inc1":
+			_, _ = w.Write([]byte(`{"id":"inc1"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/", "p4g3", "secret", time.Second)
+	ctx := context.Background()
+
+	id, err := c.CreateIncident(ctx, "API is down", "We're investigating.", []string{"c1"})
+	require.NoError(t, err)
+	require.Equal(t, "inc1", id)
+	require.NoError(t, c.UpdateIncident(ctx, id, "Also affects the database."))
+	require.NoError(t, c.ResolveIncident(ctx, id, "Resolved.", []string{"c1"}))
+
+	require.Equal(t, []request{{Incident: incident{
+		Name:         "API is down",
+		Status:       StatusInvestigating,
+		Body:         "We're investigating.",
+		ComponentIDs: []string{"c1"},
+		Components:   map[string]string{"c1": "major_outage"},
+	}}, {Incident: incident{
+		Body: "Also affects the database.",
+	}}, {Incident: incident{
+		Status:     StatusResolved,
+		Body:       "Resolved.",
+		Components: map[string]string{"c1": "operational"},
+	}}}, requests)
+}
+
+func TestClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "p4g3", "wrong", time.Second)
+	_, err := c.CreateIncident(context.Background(), "API is down", "We're investigating.", []string{"c1"})
+	require.EqualError(t, err, "unexpected status 401 Unauthorized")
+}
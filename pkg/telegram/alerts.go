@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+// AlertNotFoundErr returned by the store if an alert isn't found.
+var AlertNotFoundErr = errors.New("alert not found in store")
+
+// ChatAlert is the state of a firing alert that was sent to a chat.
+type ChatAlert struct {
+	ChatID      int64             `json:"chatID"`
+	Fingerprint string            `json:"fingerprint"`
+	Receiver    string            `json:"receiver"`
+	Labels      map[string]string `json:"labels"`
+	StartsAt    time.Time         `json:"startsAt"`
+	AckedAt     time.Time         `json:"ackedAt,omitempty"`
+	AckedBy     string            `json:"ackedBy,omitempty"`
+	EscalatedAt time.Time         `json:"escalatedAt,omitempty"`
+	// Reminders is the number of reminders that were due, see WithReminders.
+	Reminders int `json:"reminders,omitempty"`
+	// MessageID is the ID of the latest message the alert was sent in, replies to it refer to the alert.
+	MessageID int `json:"messageID,omitempty"`
+}
+
+// Name returns the alertname label of the alert.
+func (a *ChatAlert) Name() string {
+	return a.Labels[model.AlertNameLabel]
+}
+
+// Acked returns whether someone acknowledged the alert.
+func (a *ChatAlert) Acked() bool {
+	return !a.AckedAt.IsZero()
+}
+
+// BotAlertStore keeps track of the firing alerts sent to chats.
+type BotAlertStore interface {
+	List() ([]*ChatAlert, error)
+	Get(chatID int64, fingerprint string) (*ChatAlert, error)
+	Put(*ChatAlert) error
+	Remove(chatID int64, fingerprint string) error
+}
+
+// AlertStore writes the alert states to a libkv store backend.
+type AlertStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewAlertStore stores alert states in the provided kv backend.
+func NewAlertStore(kv store.Store, storeKeyPrefix string) (*AlertStore, error) {
+	return &AlertStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *AlertStore) key(chatID int64, fingerprint string) string {
+	return fmt.Sprintf("%s/%d-%s", s.storeKeyPrefix, chatID, fingerprint)
+}
+
+// List all alerts saved in the kv backend.
+func (s *AlertStore) List() ([]*ChatAlert, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var alerts []*ChatAlert
+	for _, kv := range kvPairs {
+		var a *ChatAlert
+		if err := json.Unmarshal(kv.Value, &a); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+
+	return alerts, nil
+}
+
+// Get a specific alert of a chat by its fingerprint.
+func (s *AlertStore) Get(chatID int64, fingerprint string) (*ChatAlert, error) {
+	kv, err := s.kv.Get(s.key(chatID, fingerprint))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, AlertNotFoundErr
+		}
+		return nil, err
+	}
+	var a *ChatAlert
+	err = json.Unmarshal(kv.Value, &a)
+	return a, err
+}
+
+// Put an alert into the kv backend, replacing any previous state.
+func (s *AlertStore) Put(a *ChatAlert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(a.ChatID, a.Fingerprint), b, nil)
+}
+
+// Remove an alert from the kv backend.
+func (s *AlertStore) Remove(chatID int64, fingerprint string) error {
+	err := s.kv.Delete(s.key(chatID, fingerprint))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// alertFingerprint returns the fingerprint Alertmanager sent or calculates it from the labels.
+func alertFingerprint(a template.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	labels := make(model.LabelSet, len(a.Labels))
+	for name, value := range a.Labels {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return labels.Fingerprint().String()
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// validAlias is what Telegram accepts as command name.
+var validAlias = regexp.MustCompile(`^/[a-z0-9_]{1,32}$`)
+
+// WithCommandAliases lets the aliases be sent instead of the commands they stand for, e.g. /s for /silences.
+// The commands and their aliases are registered with Telegram, so that clients suggest them.
+func WithCommandAliases(aliases map[string]string) BotOption {
+	return func(b *Bot) error {
+		commands := map[string]bool{}
+		for _, c := range helpCommands() {
+			commands["/"+c.Text] = true
+		}
+		for alias, command := range aliases {
+			if !validAlias.MatchString(alias) {
+				return fmt.Errorf("alias %q has to start with / followed by up to 32 lowercase letters, digits and _", alias)
+			}
+			if commands[alias] {
+				return fmt.Errorf("alias %s is a command itself", alias)
+			}
+			if !commands[command] {
+				return fmt.Errorf("alias %s is for the unknown command %s", alias, command)
+			}
+		}
+		b.aliases = aliases
+		return nil
+	}
+}
+
+// helpCommands returns the commands with their description from the help.
+func helpCommands() []telebot.Command {
+	commands := []telebot.Command{{Text: strings.TrimPrefix(CommandHelp, "/"), Description: "Show the available commands."}}
+	for _, line := range strings.Split(ResponseHelp, "\n") {
+		parts := strings.SplitN(line, " - ", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") {
+			continue
+		}
+		commands = append(commands, telebot.Command{Text: strings.TrimPrefix(parts[0], "/"), Description: parts[1]})
+	}
+	return commands
+}
+
+// registerCommands registers the commands and their aliases with Telegram.
+func (b *Bot) registerCommands() {
+	commands := helpCommands()
+	descriptions := make(map[string]string, len(commands))
+	for _, c := range commands {
+		descriptions["/"+c.Text] = c.Description
+	}
+
+	aliases := make([]string, 0, len(b.aliases))
+	for alias := range b.aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		command := b.aliases[alias]
+		commands = append(commands, telebot.Command{
+			Text:        strings.TrimPrefix(alias, "/"),
+			Description: fmt.Sprintf("%s Alias of %s.", descriptions[command], command),
+		})
+	}
+
+	if err := b.telegram.SetCommands(commands); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to register commands", "err", err)
+	}
+}
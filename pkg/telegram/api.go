@@ -0,0 +1,328 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Broadcast is a message sent to chats via the API.
+type Broadcast struct {
+	Text string `json:"text"`
+	// Chats the message is sent to, all subscribed chats if neither Chats nor Group are set.
+	Chats  []int64 `json:"chats,omitempty"`
+	Group  string  `json:"group,omitempty"`
+	Silent bool    `json:"silent,omitempty"`
+}
+
+// BroadcastResult tells to how many chats a broadcast was sent and which ones failed.
+type BroadcastResult struct {
+	Sent int `json:"sent"`
+	// RateLimited is the number of failed chats that Telegram rate limited.
+	RateLimited int     `json:"rateLimited"`
+	Failed      []int64 `json:"failed"`
+}
+
+// HandleAPI returns a Handler serving the versioned API of the tenants' bots to manage them without Telegram:
+// GET and POST /api/v1/chats, DELETE /api/v1/chats/<id>,
+// GET /api/v1/filters, PUT and DELETE /api/v1/filters/<chat id> and POST /api/v1/broadcast.
+// Like the other admin endpoints the tenant is selected with ?tenant=, telegram by default.
+func HandleAPI(logger log.Logger, bots map[string]*Bot) http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/api/v1/chats", apiHandler(bots, func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			b.apiListChats(w)
+		case http.MethodPost:
+			b.apiAddChat(logger, tenant, w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	m.HandleFunc("/api/v1/chats/", apiHandler(bots, func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/data") {
+			b.apiPurgeChat(logger, tenant, w, r)
+			return
+		}
+		b.apiRemoveChat(logger, tenant, w, r)
+	}))
+	m.HandleFunc("/api/v1/filters", apiHandler(bots, func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		b.apiListFilters(w)
+	}))
+	m.HandleFunc("/api/v1/filters/", apiHandler(bots, func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			b.apiPutFilter(logger, tenant, w, r)
+		case http.MethodDelete:
+			b.apiRemoveFilter(logger, tenant, w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	m.HandleFunc("/api/v1/broadcast", apiHandler(bots, func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		b.apiBroadcast(logger, tenant, w, r)
+	}))
+	return m
+}
+
+// apiHandler looks up the tenant's bot before calling the handler.
+func apiHandler(bots map[string]*Bot, handle func(b *Bot, tenant string, w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		bot, tenant, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+		handle(bot, tenant, w, r)
+	}
+}
+
+func apiError(w http.ResponseWriter, status int, err string) {
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `{"error":%q}`, err)
+}
+
+// apiChatID parses the chat ID following the prefix of the request's path.
+func apiChatID(w http.ResponseWriter, r *http.Request, prefix string) (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, prefix), 10, 64)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid chat id")
+		return 0, false
+	}
+	return id, true
+}
+
+func (b *Bot) apiListChats(w http.ResponseWriter) {
+	chats, err := b.chats.List()
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if chats == nil {
+		chats = []*telebot.Chat{}
+	}
+	_ = json.NewEncoder(w).Encode(chats)
+}
+
+func (b *Bot) apiAddChat(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	var chat telebot.Chat
+	if err := json.NewDecoder(r.Body).Decode(&chat); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid chat: "+err.Error())
+		return
+	}
+	if chat.ID == 0 {
+		apiError(w, http.StatusBadRequest, "chat id is missing")
+		return
+	}
+	// Group chats have negative IDs.
+	if chat.Type == "" {
+		chat.Type = telebot.ChatPrivate
+		if chat.ID < 0 {
+			chat.Type = telebot.ChatGroup
+		}
+	}
+
+	if err := b.chats.Add(&chat); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	level.Info(logger).Log("msg", "chat subscribed via api", "tenant", tenant, "chat_id", chat.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(chat)
+}
+
+func (b *Bot) apiRemoveChat(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	id, ok := apiChatID(w, r, "/api/v1/chats/")
+	if !ok {
+		return
+	}
+	chat, err := b.chats.Get(telebot.ChatID(id))
+	if err != nil {
+		if errors.Is(err, ChatNotFoundErr) {
+			apiError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := b.archiveChat(chat); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := b.chats.Remove(chat); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if b.filters != nil {
+		if err := b.filters.Remove(id); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove chat filter", "chat_id", id, "err", err)
+		}
+	}
+	level.Info(logger).Log("msg", "chat unsubscribed via api", "tenant", tenant, "chat_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiPurgeChat removes everything stored about a chat and, for private chats, the name of its user.
+func (b *Bot) apiPurgeChat(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	r.URL.Path = strings.TrimSuffix(r.URL.Path, "/data")
+	id, ok := apiChatID(w, r, "/api/v1/chats/")
+	if !ok {
+		return
+	}
+	var username string
+	chat, err := b.chats.Get(telebot.ChatID(id))
+	if err != nil && !errors.Is(err, ChatNotFoundErr) {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err == nil && chat.Type == telebot.ChatPrivate {
+		username = chat.Username
+	}
+
+	result, err := b.Purge(id, username)
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	logPurge(log.With(logger, "tenant", tenant), result, "api")
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// apiFilters returns false and responds with an error if filters aren't enabled.
+func (b *Bot) apiFilters(w http.ResponseWriter) bool {
+	if b.filters == nil {
+		apiError(w, http.StatusConflict, "filters aren't enabled")
+		return false
+	}
+	return true
+}
+
+func (b *Bot) apiListFilters(w http.ResponseWriter) {
+	if !b.apiFilters(w) {
+		return
+	}
+	filters, err := b.filters.List()
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if filters == nil {
+		filters = []*ChatFilter{}
+	}
+	_ = json.NewEncoder(w).Encode(filters)
+}
+
+func (b *Bot) apiPutFilter(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	if !b.apiFilters(w) {
+		return
+	}
+	id, ok := apiChatID(w, r, "/api/v1/filters/")
+	if !ok {
+		return
+	}
+
+	var f ChatFilter
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid filter: "+err.Error())
+		return
+	}
+	f.ChatID = id
+	if err := f.Validate(); err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := b.filters.Put(&f); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	level.Info(logger).Log("msg", "chat filter set via api", "tenant", tenant, "chat_id", id, "matchers", strings.Join(f.Matchers, ","))
+
+	_ = json.NewEncoder(w).Encode(f)
+}
+
+func (b *Bot) apiRemoveFilter(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	if !b.apiFilters(w) {
+		return
+	}
+	id, ok := apiChatID(w, r, "/api/v1/filters/")
+	if !ok {
+		return
+	}
+	if err := b.filters.Remove(id); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	level.Info(logger).Log("msg", "chat filter removed via api", "tenant", tenant, "chat_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (b *Bot) apiBroadcast(logger log.Logger, tenant string, w http.ResponseWriter, r *http.Request) {
+	var bc Broadcast
+	if err := json.NewDecoder(r.Body).Decode(&bc); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid broadcast: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(bc.Text) == "" {
+		apiError(w, http.StatusBadRequest, "text is missing")
+		return
+	}
+
+	chatIDs := bc.Chats
+	switch {
+	case bc.Group != "":
+		ids, err := b.groupChats(bc.Group)
+		if err != nil {
+			if errors.Is(err, ChatGroupNotFoundErr) {
+				apiError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			apiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		chatIDs = append(chatIDs, ids...)
+	case len(chatIDs) == 0:
+		chats, err := b.chats.List()
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, c := range chats {
+			chatIDs = append(chatIDs, c.ID)
+		}
+	}
+
+	f, failed := b.broadcast(log.With(logger, "tenant", tenant), chatIDs, bc.Text, &telebot.SendOptions{DisableNotification: bc.Silent})
+	results := f.wait()
+	result := BroadcastResult{
+		Sent:        results[SendResultSent],
+		RateLimited: results[SendResultRateLimited],
+		Failed:      failed,
+	}
+	b.logFanOut(f, "broadcast sent via api", "tenant", tenant)
+
+	_ = json.NewEncoder(w).Encode(result)
+}
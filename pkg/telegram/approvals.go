@@ -0,0 +1,172 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseApprovalRequested = "I asked the admins to approve this chat, I'll let you know once they did."
+	responseApprovalPending   = "This chat is still waiting for the admins to approve it."
+	responseApprovalDenied    = "Sorry, the admins didn't approve this chat to receive alerts."
+)
+
+var (
+	// approveButton subscribes the chat of a pending request.
+	approveButton = telebot.InlineButton{Unique: "approve", Text: "Approve"}
+	// denyButton rejects the pending request of a chat.
+	denyButton = telebot.InlineButton{Unique: "deny", Text: "Deny"}
+)
+
+// subscriptionApprovals are the chats whose subscription waits for an admin's approval.
+type subscriptionApprovals struct {
+	mu      sync.Mutex
+	pending map[int64]*telebot.Chat
+}
+
+// WithSubscriptionApproval asks the admins to approve or deny a subscription
+// if someone who isn't an admin sends the start command, instead of dropping it.
+// Pending requests are only kept in memory and need to be sent again after a restart.
+func WithSubscriptionApproval() BotOption {
+	return func(b *Bot) error {
+		b.approvals = &subscriptionApprovals{pending: map[int64]*telebot.Chat{}}
+		return nil
+	}
+}
+
+// add keeps the chat as pending and returns false if it already was.
+func (a *subscriptionApprovals) add(chat *telebot.Chat) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.pending[chat.ID]; ok {
+		return false
+	}
+	a.pending[chat.ID] = chat
+	return true
+}
+
+// take removes the pending chat and returns it.
+func (a *subscriptionApprovals) take(chatID int64) (*telebot.Chat, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	chat, ok := a.pending[chatID]
+	delete(a.pending, chatID)
+	return chat, ok
+}
+
+// senderName returns a user's name, username and ID to tell admins who sent something.
+func senderName(u *telebot.User) string {
+	if u.Username == "" {
+		return fmt.Sprintf("%s (%d)", u.FirstName, u.ID)
+	}
+	return fmt.Sprintf("%s (@%s, %d)", u.FirstName, u.Username, u.ID)
+}
+
+// requestApproval sends the admins a request to approve the subscription of the message's chat.
+func (b *Bot) requestApproval(message *telebot.Message) error {
+	if _, err := b.chats.Get(telebot.ChatID(message.Chat.ID)); err == nil {
+		_, err = b.telegram.Send(message.Chat, "This chat is already subscribed.")
+		return err
+	}
+	if !b.approvals.add(message.Chat) {
+		_, err := b.telegram.Send(message.Chat, responseApprovalPending)
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "subscription approval requested",
+		"username", message.Sender.Username,
+		"user_id", message.Sender.ID,
+		"chat_id", message.Chat.ID,
+	)
+
+	if _, err := b.telegram.Send(message.Chat, responseApprovalRequested); err != nil {
+		return err
+	}
+
+	request := fmt.Sprintf("%s wants to receive alerts.", senderName(message.Sender))
+	if isGroupChat(message.Chat) {
+		request = fmt.Sprintf("%s wants to subscribe the group %s (%d) to alerts.", senderName(message.Sender), message.Chat.Title, message.Chat.ID)
+	}
+
+	approve, deny := approveButton, denyButton
+	approve.Data = strconv.FormatInt(message.Chat.ID, 10)
+	deny.Data = approve.Data
+	markup := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{approve, deny}}}
+
+	for _, id := range b.admins {
+		if _, err := b.telegram.Send(&telebot.User{ID: id}, request, &telebot.SendOptions{ReplyMarkup: markup}); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send approval request", "admin_id", id, "err", err)
+		}
+	}
+	return nil
+}
+
+func (b *Bot) handleApprove(c *telebot.Callback) {
+	b.handleApproval(c, true)
+}
+
+func (b *Bot) handleDeny(c *telebot.Callback) {
+	b.handleApproval(c, false)
+}
+
+// handleApproval subscribes or rejects the chat of a pending request and notifies the requester.
+func (b *Bot) handleApproval(c *telebot.Callback, approved bool) {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+
+	if !b.isAuthorized(c.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping callback from forbidden sender",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Data, 10, 64)
+	if err != nil {
+		return
+	}
+	chat, ok := b.approvals.take(chatID)
+	if !ok {
+		_, _ = b.telegram.Edit(c.Message, "This request was already handled.")
+		return
+	}
+
+	if !approved {
+		level.Info(b.logger).Log("msg", "subscription denied", "chat_id", chat.ID, "admin_id", c.Sender.ID)
+		if _, err := b.telegram.Edit(c.Message, fmt.Sprintf("%s denied chat %d.", c.Sender.FirstName, chat.ID)); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to edit approval request", "err", err)
+		}
+		if _, err := b.telegram.Send(chat, responseApprovalDenied); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to notify about denied subscription", "err", err)
+		}
+		return
+	}
+
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to add chat to chat store", "err", err)
+		b.approvals.add(chat)
+		_, _ = b.telegram.Send(c.Message.Chat, "I can't add this chat to the subscribers list.")
+		return
+	}
+
+	level.Info(b.logger).Log("msg", "subscription approved", "chat_id", chat.ID, "admin_id", c.Sender.ID)
+	if _, err := b.telegram.Edit(c.Message, fmt.Sprintf("%s approved chat %d.", c.Sender.FirstName, chat.ID)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit approval request", "err", err)
+	}
+
+	response := responseStartGroup
+	if chat.Type == telebot.ChatPrivate {
+		response = fmt.Sprintf(responseStartPrivate, chat.FirstName)
+	}
+	if _, err := b.telegram.Send(chat, response); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to notify about approved subscription", "err", err)
+	}
+}
@@ -0,0 +1,223 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	subcommandChatsArchived = "archived"
+	subcommandChatRestore   = "restore"
+
+	responseChatRestoreUsage    = "Usage: " + CommandChats + " " + subcommandChatRestore + " <id>"
+	responseChatRestoreForAdmin = "Only admins can restore chats."
+)
+
+// ArchivedChatNotFoundErr returned by the store if a chat isn't archived.
+var ArchivedChatNotFoundErr = errors.New("archived chat not found in store")
+
+// ArchivedChat is a chat that unsubscribed or was removed, kept to restore it with its filter.
+// The other settings of chats, e.g. their link previews, are kept when they unsubscribe anyway.
+type ArchivedChat struct {
+	Chat       *telebot.Chat `json:"chat"`
+	Filter     *ChatFilter   `json:"filter,omitempty"`
+	ArchivedAt time.Time     `json:"archivedAt"`
+}
+
+// BotArchiveStore keeps the archived chats.
+type BotArchiveStore interface {
+	List() ([]*ArchivedChat, error)
+	Get(chatID int64) (*ArchivedChat, error)
+	Put(*ArchivedChat) error
+	Remove(chatID int64) error
+}
+
+// ArchiveStore writes the archived chats to a libkv store backend.
+type ArchiveStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewArchiveStore stores archived chats in the provided kv backend.
+func NewArchiveStore(kv store.Store, storeKeyPrefix string) (*ArchiveStore, error) {
+	return &ArchiveStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all archived chats saved in the kv backend.
+func (s *ArchiveStore) List() ([]*ArchivedChat, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var chats []*ArchivedChat
+	for _, kv := range kvPairs {
+		var c *ArchivedChat
+		if err := json.Unmarshal(kv.Value, &c); err != nil {
+			return nil, err
+		}
+		chats = append(chats, c)
+	}
+	return chats, nil
+}
+
+// Get an archived chat by its ID.
+func (s *ArchiveStore) Get(chatID int64) (*ArchivedChat, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, ArchivedChatNotFoundErr
+		}
+		return nil, err
+	}
+	var c *ArchivedChat
+	err = json.Unmarshal(kv.Value, &c)
+	return c, err
+}
+
+// Put an archived chat into the kv backend, replacing an earlier archive of it.
+func (s *ArchiveStore) Put(c *ArchivedChat) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, c.Chat.ID), b, nil)
+}
+
+// Remove an archived chat from the kv backend.
+func (s *ArchiveStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithChatArchive archives chats that unsubscribe with /stop or are removed via the API instead of forgetting them,
+// so that admins can list them with /chats archived and restore them with their filter with /chats restore <id>.
+// Chats sending /start again are restored too.
+func WithChatArchive(archive BotArchiveStore) BotOption {
+	return func(b *Bot) error {
+		b.archive = archive
+		return nil
+	}
+}
+
+// archiveChat archives the chat with its current filter before it's removed.
+func (b *Bot) archiveChat(chat *telebot.Chat) error {
+	if b.archive == nil {
+		return nil
+	}
+	archived := &ArchivedChat{Chat: chat, ArchivedAt: time.Now()}
+	if b.filters != nil {
+		f, err := b.filters.Get(chat.ID)
+		if err != nil && !errors.Is(err, FilterNotFoundErr) {
+			return err
+		}
+		archived.Filter = f
+	}
+	return b.archive.Put(archived)
+}
+
+// restoreChat subscribes the archived chat again, restores its filter and removes it from the archive.
+// It returns the archived chat, or nil if the chat isn't archived.
+func (b *Bot) restoreChat(chatID int64) (*ArchivedChat, error) {
+	if b.archive == nil {
+		return nil, nil
+	}
+	archived, err := b.archive.Get(chatID)
+	if err != nil {
+		if errors.Is(err, ArchivedChatNotFoundErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := b.chats.Add(archived.Chat); err != nil {
+		return nil, err
+	}
+	if archived.Filter != nil && b.filters != nil {
+		if err := b.filters.Put(archived.Filter); err != nil {
+			return nil, err
+		}
+	}
+	return archived, b.archive.Remove(chatID)
+}
+
+func (b *Bot) handleChatsArchived(message *telebot.Message) error {
+	if b.archive == nil {
+		_, err := b.telegram.Send(message.Chat, "Archiving chats isn't enabled.")
+		return err
+	}
+
+	archived, err := b.archive.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list archived chats", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the archived chats.")
+		return err
+	}
+	if len(archived) == 0 {
+		_, err = b.telegram.Send(message.Chat, "No chats are archived.")
+		return err
+	}
+
+	sort.Slice(archived, func(i, j int) bool { return archived[i].ArchivedAt.After(archived[j].ArchivedAt) })
+	var sb strings.Builder
+	sb.WriteString("Archived chats, restore them with " + CommandChats + " " + subcommandChatRestore + " <id>:\n")
+	for _, a := range archived {
+		fmt.Fprintf(&sb, "%s (%d)", chatName(a.Chat), a.Chat.ID)
+		if a.Filter != nil && len(a.Filter.Matchers) > 0 {
+			fmt.Fprintf(&sb, " filtered by %s", strings.Join(a.Filter.Matchers, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	_, err = b.telegram.Send(message.Chat, sb.String())
+	return err
+}
+
+func (b *Bot) handleChatRestore(message *telebot.Message, fields []string) error {
+	if b.archive == nil {
+		_, err := b.telegram.Send(message.Chat, "Archiving chats isn't enabled.")
+		return err
+	}
+	if !b.isAdminID(message.Sender.ID) {
+		_, err := b.telegram.Send(message.Chat, responseChatRestoreForAdmin)
+		return err
+	}
+	if len(fields) != 1 {
+		_, err := b.telegram.Send(message.Chat, responseChatRestoreUsage)
+		return err
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseChatRestoreUsage)
+		return err
+	}
+
+	archived, err := b.restoreChat(id)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to restore chat", "chat_id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't restore this chat.")
+		return err
+	}
+	if archived == nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The chat %d isn't archived.", id))
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "chat restored", "chat_id", id, "username", message.Sender.Username)
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Restored the chat %s (%d), it receives alerts again.", chatName(archived.Chat), id))
+	return err
+}
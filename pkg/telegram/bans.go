@@ -0,0 +1,250 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseBanUsage = "Usage:\n" +
+	CommandBan + " - List all banned users.\n" +
+	CommandBan + " <user_id|@username> - Ignore everything the user sends.\n" +
+	CommandUnban + " <user_id|@username> - Stop ignoring the user."
+
+// BanNotFoundErr returned by the store if a user isn't banned.
+var BanNotFoundErr = errors.New("ban not found in store")
+
+// Ban is a user whose messages are ignored by the bot.
+// Users are banned either by their ID or by their username.
+type Ban struct {
+	UserID   int       `json:"userID,omitempty"`
+	Username string    `json:"username,omitempty"`
+	BannedBy int       `json:"bannedBy"`
+	BannedAt time.Time `json:"bannedAt"`
+}
+
+// Key identifies the banned user, e.g. 222 or @nobody.
+func (b *Ban) Key() string {
+	if b.UserID != 0 {
+		return strconv.Itoa(b.UserID)
+	}
+	return "@" + strings.ToLower(b.Username)
+}
+
+// parseBan returns the ban of a user ID or @username argument.
+func parseBan(arg string) (*Ban, bool) {
+	if strings.HasPrefix(arg, "@") && len(arg) > 1 {
+		return &Ban{Username: strings.TrimPrefix(arg, "@")}, true
+	}
+	id, err := strconv.Atoi(arg)
+	if err != nil || id == 0 {
+		return nil, false
+	}
+	return &Ban{UserID: id}, true
+}
+
+// BotBanStore keeps the banned users.
+type BotBanStore interface {
+	List() ([]*Ban, error)
+	Get(key string) (*Ban, error)
+	Put(*Ban) error
+	Remove(key string) error
+}
+
+// BanStore writes the banned users to a libkv store backend.
+type BanStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewBanStore stores banned users in the provided kv backend.
+func NewBanStore(kv store.Store, storeKeyPrefix string) (*BanStore, error) {
+	return &BanStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all banned users saved in the kv backend.
+func (s *BanStore) List() ([]*Ban, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var bans []*Ban
+	for _, kv := range kvPairs {
+		var b *Ban
+		if err := json.Unmarshal(kv.Value, &b); err != nil {
+			return nil, err
+		}
+		bans = append(bans, b)
+	}
+
+	return bans, nil
+}
+
+// Get the ban of a user by its key.
+func (s *BanStore) Get(key string) (*Ban, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%s", s.storeKeyPrefix, key))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, BanNotFoundErr
+		}
+		return nil, err
+	}
+	var b *Ban
+	err = json.Unmarshal(kv.Value, &b)
+	return b, err
+}
+
+// Put a ban into the kv backend.
+func (s *BanStore) Put(b *Ban) error {
+	value, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, b.Key()), value, nil)
+}
+
+// Remove a ban from the kv backend.
+func (s *BanStore) Remove(key string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, key))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithBans lets admins ban users with the ban command, everything banned users send is ignored.
+func WithBans(bans BotBanStore) BotOption {
+	return func(b *Bot) error {
+		b.bans = bans
+		return nil
+	}
+}
+
+// isBanned returns whether the user is banned by its ID or username.
+// If the bans can't be read the user is treated as banned.
+func (b *Bot) isBanned(u *telebot.User) bool {
+	if b.bans == nil || u == nil {
+		return false
+	}
+
+	keys := []string{(&Ban{UserID: u.ID}).Key()}
+	if u.Username != "" {
+		keys = append(keys, (&Ban{Username: u.Username}).Key())
+	}
+	for _, key := range keys {
+		_, err := b.bans.Get(key)
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, BanNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get ban", "err", err)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) handleBan(message *telebot.Message) error {
+	if b.bans == nil {
+		_, err := b.telegram.Send(message.Chat, "Banning users isn't enabled.")
+		return err
+	}
+
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 {
+		return b.handleBanList(message)
+	}
+
+	ban, ok := parseBan(args[0])
+	if len(args) > 1 || !ok {
+		_, err := b.telegram.Send(message.Chat, responseBanUsage)
+		return err
+	}
+	if ban.UserID != 0 && b.isAdminID(ban.UserID) {
+		_, err := b.telegram.Send(message.Chat, "Admins can't be banned.")
+		return err
+	}
+
+	ban.BannedBy = message.Sender.ID
+	ban.BannedAt = time.Now()
+	if err := b.bans.Put(ban); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put ban", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't ban the user.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "user banned", "user", ban.Key(), "banned_by", message.Sender.ID)
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf("Banned %s.", ban.Key()))
+	return err
+}
+
+func (b *Bot) handleUnban(message *telebot.Message) error {
+	if b.bans == nil {
+		_, err := b.telegram.Send(message.Chat, "Banning users isn't enabled.")
+		return err
+	}
+
+	args := strings.Fields(message.Payload)
+	if len(args) != 1 {
+		_, err := b.telegram.Send(message.Chat, responseBanUsage)
+		return err
+	}
+	ban, ok := parseBan(args[0])
+	if !ok {
+		_, err := b.telegram.Send(message.Chat, responseBanUsage)
+		return err
+	}
+
+	if _, err := b.bans.Get(ban.Key()); err != nil {
+		if !errors.Is(err, BanNotFoundErr) {
+			return err
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%s isn't banned.", ban.Key()))
+		return err
+	}
+	if err := b.bans.Remove(ban.Key()); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove ban", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't unban the user.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "user unbanned", "user", ban.Key(), "unbanned_by", message.Sender.ID)
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf("Unbanned %s.", ban.Key()))
+	return err
+}
+
+func (b *Bot) handleBanList(message *telebot.Message) error {
+	bans, err := b.bans.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list bans", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the banned users.")
+		return err
+	}
+
+	if len(bans) == 0 {
+		_, err = b.telegram.Send(message.Chat, "No one is banned.\n"+responseBanUsage)
+		return err
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].BannedAt.Before(bans[j].BannedAt) })
+
+	var out strings.Builder
+	out.WriteString("Banned users:")
+	for _, ban := range bans {
+		fmt.Fprintf(&out, "\n%s, %s ago by %d", ban.Key(), formatDuration(time.Since(ban.BannedAt)), ban.BannedBy)
+	}
+
+	_, err = b.telegram.Send(message.Chat, out.String())
+	return err
+}
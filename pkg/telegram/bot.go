@@ -3,6 +3,7 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
@@ -28,6 +29,37 @@ const (
 	CommandHelp  = "/help"
 	CommandChats = "/chats"
 	CommandID    = "/id"
+	CommandAck   = "/ack"
+	CommandStats = "/stats"
+
+	CommandIncident    = "/incident"
+	CommandQuery       = "/query"
+	CommandTargets     = "/targets"
+	CommandRules       = "/rules"
+	CommandGroup       = "/group"
+	CommandReplay      = "/replay"
+	CommandLastWebhook = "/lastwebhook"
+	CommandDelivery    = "/delivery"
+	CommandMute        = "/mute"
+	CommandUnmute      = "/unmute"
+	CommandInvite      = "/invite"
+	CommandBan         = "/ban"
+	CommandUnban       = "/unban"
+	CommandReminders   = "/reminders"
+	CommandRoutes      = "/routes"
+	CommandSilence     = "/silence"
+	CommandFind        = "/find"
+	CommandPreviews    = "/previews"
+	CommandVoice       = "/voice"
+	CommandQR          = "/qr"
+	CommandBroadcast   = "/broadcast"
+	CommandConfig      = "/config"
+	CommandForgetMe    = "/forgetme"
+	CommandCluster     = "/cluster"
+	CommandMaintenance = "/maintenance"
+	CommandTicket      = "/ticket"
+	CommandStatuspage  = "/statuspage"
+	CommandMyStats     = "/mystats"
 
 	CommandStatus   = "/status"
 	CommandAlerts   = "/alerts"
@@ -39,6 +71,7 @@ const (
 	responseStartPrivate = "Hey, %s! I will now keep you up to date!\n" + CommandHelp
 	responseStartGroup   = "Hey! I will now keep you all up to date!\n" + CommandHelp
 	responseStop         = "Alright, %s! I won't talk to you again.\n" + CommandHelp
+	responseChatsUsage   = "Usage: " + CommandChats + ", " + CommandChats + " " + subcommandChatMode + " <id> " + chatModeReadOnly + "|" + chatModeNormal + ", " + CommandChats + " " + subcommandChatsArchived + " or " + CommandChats + " " + subcommandChatRestore + " <id>"
 	ResponseHelp         = `
 I'm a Prometheus AlertManager Bot for Telegram. I will notify you about alerts.
 You can also ask me about my ` + CommandStatus + `, ` + CommandAlerts + ` & ` + CommandSilences + `
@@ -49,11 +82,49 @@ Available commands:
 ` + CommandStatus + ` - Print the current status.
 ` + CommandAlerts + ` - List all alerts.
 ` + CommandSilences + ` - List all silences.
-` + CommandChats + ` - List all users and group chats that subscribed.
+` + CommandFind + ` - Search the current and past alerts, e.g. ` + CommandFind + ` db or ` + CommandFind + ` /node-[0-9]+/.
+` + CommandAck + ` - Acknowledge a firing alert by its name or by replying to it.
+` + CommandSilence + ` - Silence the alerts you reply to, e.g. ` + CommandSilence + ` 2h.
+` + CommandTicket + ` - Open a ticket for the alerts you reply to.
+` + CommandStats + ` - Show statistics about the alerts, e.g. ` + CommandStats + ` 7d.
+` + CommandMyStats + ` - Show how many alerts this chat was sent in the last day, week and month.
+` + CommandIncident + ` - Group related alerts into an incident.
+` + CommandQuery + ` - Run an instant query against Prometheus.
+` + CommandTargets + ` - List Prometheus' targets, ` + CommandTargets + ` down only lists the down ones.
+` + CommandRules + ` - Show a Prometheus rule's expression and annotations.
+` + CommandGroup + ` - Manage the chat groups alerts can be sent to.
+` + CommandReplay + ` - Send the last webhooks again, e.g. ` + CommandReplay + ` 3.
+` + CommandLastWebhook + ` - Show the last received webhooks, e.g. ` + CommandLastWebhook + ` 3.
+` + CommandDelivery + ` - Show whether the alerts of a group key reached their chats.
+` + CommandMute + ` - Stop being mentioned in this group's alerts, e.g. ` + CommandMute + ` me.
+` + CommandUnmute + ` - Be mentioned in this group's alerts again, e.g. ` + CommandUnmute + ` me.
+` + CommandInvite + ` - Create a one-time token others can subscribe with.
+` + CommandBan + ` - Ignore everything a user sends, e.g. ` + CommandBan + ` @username.
+` + CommandUnban + ` - Stop ignoring a banned user.
+` + CommandReminders + ` - Turn reminders about unacknowledged alerts on or off, e.g. ` + CommandReminders + ` off.
+` + CommandPreviews + ` - Turn previews of links in alerts on or off, e.g. ` + CommandPreviews + ` off.
+` + CommandVoice + ` - Turn voice messages about critical alerts on or off, e.g. ` + CommandVoice + ` on.
+` + CommandCluster + ` - Choose the Alertmanager clusters this chat's commands target, e.g. ` + CommandCluster + ` use prod-eu.
+` + CommandRoutes + ` - Show Alertmanager's routing tree, ` + CommandRoutes + ` test severity=critical shows where alerts with these labels are sent.
+` + CommandMaintenance + ` - Schedule maintenance windows silencing alerts, e.g. ` + CommandMaintenance + ` add "DB upgrade" 2024-07-01T22:00 4h instance=db-1.
+` + CommandStatuspage + ` - List the Statuspage incidents of alerts, ` + CommandStatuspage + ` confirm api creates the proposed incident of a component.
+` + CommandQR + ` - Send the QR code of a link to scan it with another device, e.g. ` + CommandQR + ` https://example.com/d/abc.
+` + CommandBroadcast + ` - Send a message to all subscribed chats, e.g. about maintenance.
+` + CommandConfig + ` - Roll the canary configuration out with ` + CommandConfig + ` promote, compare configuration versions with ` + CommandConfig + ` diff v3 v4 or roll back to one with ` + CommandConfig + ` rollback v3.
+` + CommandForgetMe + ` - Remove everything I stored about you.
+` + CommandChats + ` - List all users and group chats that subscribed, ` + CommandChats + ` mode -1234 readonly makes a chat only receive alerts, ` + CommandChats + ` archived lists unsubscribed chats to ` + CommandChats + ` restore them.
 ` + CommandID + ` - Send the senders Telegram ID (works for all Telegram users).
 `
 )
 
+// publicCommands can be sent by everyone, not only by admins.
+var publicCommands = map[string]bool{
+	CommandID:       true,
+	CommandMute:     true,
+	CommandUnmute:   true,
+	CommandForgetMe: true,
+}
+
 // BotChatStore is all the Bot needs to store and read.
 type BotChatStore interface {
 	List() ([]*telebot.Chat, error)
@@ -69,14 +140,27 @@ type Telebot interface {
 	Start()
 	Stop()
 	Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error)
+	Edit(msg telebot.Editable, what interface{}, options ...interface{}) (*telebot.Message, error)
+	Pin(msg telebot.Editable, options ...interface{}) error
+	Respond(c *telebot.Callback, resp ...*telebot.CallbackResponse) error
+	Answer(query *telebot.Query, resp *telebot.QueryResponse) error
 	Notify(to telebot.Recipient, action telebot.ChatAction) error
 	Handle(endpoint interface{}, handler interface{})
+	SetCommands(cmds []telebot.Command) error
+	ChatByID(id string) (*telebot.Chat, error)
 }
 
 type Alertmanager interface {
 	ListAlerts(context.Context, string, bool) ([]*types.Alert, error)
+	ListAlertStatuses(context.Context, string, bool) ([]alertmanager.Alert, error)
 	ListSilences(context.Context) ([]*types.Silence, error)
 	Status(context.Context) (*models.AlertmanagerStatus, error)
+	CreateSilence(ctx context.Context, matchers []*alertmanager.Matcher, startsAt, endsAt time.Time, createdBy, comment string) (string, error)
+}
+
+// Authorizer decides whether users that aren't admins may command the bot, e.g. by their group membership.
+type Authorizer interface {
+	Authorized(ctx context.Context, userID int) (bool, error)
 }
 
 // Bot runs the alertmanager telegram.
@@ -84,35 +168,163 @@ type Bot struct {
 	addr         string
 	admins       []int // must be kept sorted
 	alertmanager Alertmanager
+	prometheus   Prometheus
 	templates    *template.Template
-	chats        BotChatStore
-	logger       log.Logger
-	revision     string
-	startTime    time.Time
+	overrides    []templateOverride
+	previews     BotPreviewStore
+	voice        *voiceMessages
+	locations    *locationLabels
+	chatModes    BotChatModeStore
+	archive      BotArchiveStore
+	migrations   BotMigrationStore
+	chatRefresh  time.Duration
+	alertOrder   *alertOrder
+	clusters     *clusters
+	maintenance  *maintenance
+	calendar     *maintenanceCalendar
+	tickets      IssueTracker
+	statuspage   *statuspageSync
+	outputs      []namedOutput
+	// commandOutputs are the outputs commands are received from by name, see WithOutputCommands.
+	commandOutputs map[string]*commandOutput
+	chats          BotChatStore
+	alerts         BotAlertStore
+	history        BotHistoryStore
+	incidents      BotIncidentStore
+	logger         log.Logger
+	revision       string
+	startTime      time.Time
 
 	telegram Telebot
 
 	commandEvents func(command string)
+	ackEvents     func(alertname string, d time.Duration)
+	resolveEvents func(alertname string, d time.Duration)
+	// resolved are the alerts whose resolution was passed to resolveEvents already.
+	resolved      *resolvedAlerts
+	sendEvents    func(result string)
+	latencyEvents func(severity string, d time.Duration)
+
+	processedEvents func(Processed)
+	// active is shared with the copies of the bot running tracked commands, see runTracked.
+	active *activity
+	// barriers are passed by the webhook loop once the webhooks received before are handled.
+	barriers chan chan struct{}
+
+	escalationAfter time.Duration
+	escalationChat  int64
+
+	historyRetention time.Duration
+
+	flapping    *flapDetector
+	labelFilter *labelFilter
+	details     *alertDetails
+	groups      map[string][]int64
+	groupStore  BotChatGroupStore
+	webhooks    *webhookBuffer
+	replays     chan alertmanager.TelegramWebhook
+	deliveries  BotDeliveryStore
+	outage      *outageBuffer
+	sendWorkers int
+	sendQueues  []chan sendJob
+	mutes       BotMuteStore
+	approvals   *subscriptionApprovals
+	invites     BotInviteStore
+	bans        BotBanStore
+	authorizer  Authorizer
+	permissions *commandPermissions
+	deepLinks   *deepLinks
+	filters     BotFilterStore
+	subscribed  *declaredSubscriptions
+	watchdog    *watchdog
+	digest      *digest
+	deadMans    *deadMansSwitch
+	selfMonitor *selfMonitor
+	reminders   *alertReminders
+	correlator  *correlator
+	suppression bool
+	reconcile   bool
+	idempotent  *idempotency
+	peerDedup   *peerDedup
+	enrichers   []Enricher
+	redactions  []redactionRule
+	shadow      *shadow
+	canary      *canary
+	configs     *configVersions
+	reload      func() (string, error)
+	recorder    *botRecorder
+	aliases     map[string]string
+	edits       *commandEdits
+	reactionAck bool
+	offsets     BotOffsetStore
+	// poller polls the updates of bots created with NewBot and keeps their IDs once they're handled.
+	poller   *updatePoller
+	handled  *handledCommands
+	shards   *shards
+	username string
+	me       *telebot.User
+
+	deliveryRetention time.Duration
+	notifyMaxAge      time.Duration
+	inviteExpiry      time.Duration
+	pollTimeout       time.Duration
+	pollInterval      time.Duration
+	telegramTimeout   time.Duration
+	requestTimeout    time.Duration
+
+	// commandQueues are handled by the command workers, commandsDone is closed once they stop.
+	commandWorkers int
+	commandQueues  []chan func()
+	commandsDone   <-chan struct{}
 }
 
+// The default timeouts of the requests to Telegram and to Alertmanager, Prometheus and the other APIs.
+const (
+	defaultTelegramTimeout = 30 * time.Second
+	defaultRequestTimeout  = 30 * time.Second
+)
+
 // BotOption passed to NewBot to change the default instance.
 type BotOption func(b *Bot) error
 
 // NewBot creates a Bot with the UserStore and telegram telegram.
 func NewBot(chats BotChatStore, token string, admin int, opts ...BotOption) (*Bot, error) {
-	poller := &telebot.LongPoller{
-		Timeout: 10 * time.Second,
-	}
+	return NewBotWithURL(chats, telebot.DefaultApiURL, token, admin, opts...)
+}
+
+// NewBotWithURL creates a Bot talking to the Telegram Bot API at the URL, e.g. a test server.
+func NewBotWithURL(chats BotChatStore, url, token string, admin int, opts ...BotOption) (*Bot, error) {
+	poller := &updatePoller{logger: log.NewNopLogger()}
+	client := &http.Client{Timeout: defaultTelegramTimeout}
 
 	bot, err := telebot.NewBot(telebot.Settings{
+		URL:    url,
 		Token:  token,
 		Poller: poller,
+		Client: client,
+		// The updates are passed to the handlers in order, the command workers handle them concurrently per chat.
+		Synchronous: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return NewBotWithTelegram(chats, bot, admin, opts...)
+	b, err := NewBotWithTelegram(chats, &reactingTelebot{Bot: bot, poller: poller}, admin, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b.username = bot.Me.Username
+	b.me = bot.Me
+	poller.timeout = b.pollTimeout
+	poller.interval = b.pollInterval
+	poller.offsets = b.offsets
+	poller.logger = b.logger
+	poller.owns = b.ownsUpdates
+	b.poller = poller
+	// Long polls wait for updates up to the poll timeout before Telegram responds.
+	client.Timeout = b.telegramTimeout + b.pollTimeout
+
+	return b, nil
 }
 
 func NewBotWithTelegram(chats BotChatStore, bot Telebot, admin int, opts ...BotOption) (*Bot, error) {
@@ -123,6 +335,23 @@ func NewBotWithTelegram(chats BotChatStore, bot Telebot, admin int, opts ...BotO
 		addr:          "127.0.0.1:8080",
 		admins:        []int{admin},
 		commandEvents: func(command string) {},
+		ackEvents:     func(alertname string, d time.Duration) {},
+		resolveEvents: func(alertname string, d time.Duration) {},
+		resolved:      &resolvedAlerts{alerts: map[string]time.Time{}},
+		sendEvents:    func(result string) {},
+		latencyEvents: func(severity string, d time.Duration) {},
+		pollTimeout:   10 * time.Second,
+
+		processedEvents: func(Processed) {},
+		telegramTimeout: defaultTelegramTimeout,
+		requestTimeout:  defaultRequestTimeout,
+		active:          &activity{},
+		barriers:        make(chan chan struct{}),
+	}
+
+	// Transports knowing the bot's own user let it notice being added to or removed from groups.
+	if me, ok := bot.(interface{ Me() *telebot.User }); ok {
+		b.me = me.Me()
 	}
 
 	for _, opt := range opts {
@@ -150,6 +379,41 @@ func WithCommandEvent(callback func(command string)) BotOption {
 	}
 }
 
+// WithAckEvent sets a func to call with the time it took to acknowledge an alert.
+func WithAckEvent(callback func(alertname string, d time.Duration)) BotOption {
+	return func(b *Bot) error {
+		b.ackEvents = callback
+		return nil
+	}
+}
+
+// WithResolveEvent sets a func to call with the time it took an alert to resolve.
+func WithResolveEvent(callback func(alertname string, d time.Duration)) BotOption {
+	return func(b *Bot) error {
+		b.resolveEvents = callback
+		return nil
+	}
+}
+
+// WithSendEvent sets a func to call with the result of every message sent to a chat for webhooks and broadcasts,
+// one of SendResultSent, SendResultRateLimited and SendResultFailed.
+func WithSendEvent(callback func(result string)) BotOption {
+	return func(b *Bot) error {
+		b.sendEvents = callback
+		return nil
+	}
+}
+
+// WithLatencyEvent sets a func to call with the time from receiving a webhook until its alerts were sent to a chat,
+// once for every severity of the alerts. Replayed webhooks and alerts that were delayed on purpose,
+// e.g. to correlate them, aren't observed.
+func WithLatencyEvent(callback func(severity string, d time.Duration)) BotOption {
+	return func(b *Bot) error {
+		b.latencyEvents = callback
+		return nil
+	}
+}
+
 // WithAddr sets the internal listening addr of the bot's web server receiving webhooks.
 func WithAddr(addr string) BotOption {
 	return func(b *Bot) error {
@@ -175,6 +439,7 @@ func WithTemplates(alertmanager *url.URL, templatePaths ...string) BotOption {
 		funcs["duration"] = func(start time.Time, end time.Time) string {
 			return durafmt.Parse(end.Sub(start)).String()
 		}
+		funcs["qr"] = qrTemplateFunc
 
 		template.DefaultFuncs = funcs
 
@@ -216,6 +481,72 @@ func WithExtraAdmins(ids ...int) BotOption {
 	}
 }
 
+// WithAlertStore keeps track of the alerts sent to chats, which is needed to acknowledge them.
+func WithAlertStore(alerts BotAlertStore) BotOption {
+	return func(b *Bot) error {
+		b.alerts = alerts
+		return nil
+	}
+}
+
+// WithHistory records the alerts sent to chats and removes resolved ones after the retention.
+// A retention of 0 keeps the history forever.
+func WithHistory(history BotHistoryStore, retention time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.history = history
+		b.historyRetention = retention
+		return nil
+	}
+}
+
+// WithIncidents allows chats to group related alerts into incidents.
+func WithIncidents(incidents BotIncidentStore) BotOption {
+	return func(b *Bot) error {
+		b.incidents = incidents
+		return nil
+	}
+}
+
+// WithEscalation re-notifies about alerts firing longer than after without being acked or silenced.
+// If chatID isn't 0 the escalations are sent to that chat instead of the alert's original chat.
+func WithEscalation(after time.Duration, chatID int64) BotOption {
+	return func(b *Bot) error {
+		if after <= 0 {
+			return errors.New("escalation duration must be positive")
+		}
+		b.escalationAfter = after
+		b.escalationChat = chatID
+		return nil
+	}
+}
+
+// WithTimeouts limits how long the requests to Telegram may take, and the ones to Alertmanager, Prometheus, the authorizer
+// and the other APIs, so that a hanging request doesn't keep the bot from handling the next commands and webhooks.
+// The Telegram timeout only applies to bots created with NewBot.
+func WithTimeouts(telegram, requests time.Duration) BotOption {
+	return func(b *Bot) error {
+		if telegram <= 0 || requests <= 0 {
+			return errors.New("timeouts must be positive")
+		}
+		b.telegramTimeout = telegram
+		b.requestTimeout = requests
+		return nil
+	}
+}
+
+// requestContext limits a request to Alertmanager, Prometheus or another API to the request timeout, see WithTimeouts.
+func (b *Bot) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, b.requestTimeout)
+}
+
+// WithAuthorizer lets users that aren't admins command the bot if the authorizer allows them to.
+func WithAuthorizer(a Authorizer) BotOption {
+	return func(b *Bot) error {
+		b.authorizer = a
+		return nil
+	}
+}
+
 // SendAdminMessage to the admin's ID with a message.
 func (b *Bot) SendAdminMessage(adminID int, message string) {
 	_, _ = b.telegram.Send(&telebot.User{ID: adminID}, message)
@@ -227,24 +558,299 @@ func (b *Bot) isAdminID(id int) bool {
 	return i < len(b.admins) && b.admins[i] == id
 }
 
+// isAuthorized returns whether the user is an admin or allowed by the authorizer.
+func (b *Bot) isAuthorized(u *telebot.User) bool {
+	if b.isAdminID(u.ID) {
+		return true
+	}
+	if b.authorizer == nil {
+		return false
+	}
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	allowed, err := b.authorizer.Authorized(ctx, u.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to authorize sender", "sender_id", u.ID, "err", err)
+		return false
+	}
+	return allowed
+}
+
+// commandHandlers returns the handlers of the commands by command.
+func commandHandlers() map[string]func(*Bot, *telebot.Message) error {
+	return map[string]func(*Bot, *telebot.Message) error{
+		CommandStart:       (*Bot).handleStart,
+		CommandStop:        (*Bot).handleStop,
+		CommandHelp:        (*Bot).handleHelp,
+		CommandChats:       (*Bot).handleChats,
+		CommandID:          (*Bot).handleID,
+		CommandStatus:      (*Bot).handleStatus,
+		CommandAlerts:      (*Bot).handleAlerts,
+		CommandSilences:    (*Bot).handleSilences,
+		CommandAck:         (*Bot).handleAck,
+		CommandStats:       (*Bot).handleStats,
+		CommandMyStats:     (*Bot).handleMyStats,
+		CommandIncident:    (*Bot).handleIncident,
+		CommandQuery:       (*Bot).handleQuery,
+		CommandTargets:     (*Bot).handleTargets,
+		CommandRules:       (*Bot).handleRules,
+		CommandGroup:       (*Bot).handleGroup,
+		CommandReplay:      (*Bot).handleReplay,
+		CommandLastWebhook: (*Bot).handleLastWebhook,
+		CommandDelivery:    (*Bot).handleDelivery,
+		CommandMute:        (*Bot).handleMute,
+		CommandUnmute:      (*Bot).handleUnmute,
+		CommandInvite:      (*Bot).handleInvite,
+		CommandBan:         (*Bot).handleBan,
+		CommandUnban:       (*Bot).handleUnban,
+		CommandReminders:   (*Bot).handleReminders,
+		CommandRoutes:      (*Bot).handleRoutes,
+		CommandSilence:     (*Bot).handleSilence,
+		CommandFind:        (*Bot).handleFind,
+		CommandPreviews:    (*Bot).handlePreviews,
+		CommandVoice:       (*Bot).handleVoice,
+		CommandQR:          (*Bot).handleQR,
+		CommandBroadcast:   (*Bot).handleBroadcast,
+		CommandConfig:      (*Bot).handleConfig,
+		CommandForgetMe:    (*Bot).handleForgetMe,
+		CommandCluster:     (*Bot).handleCluster,
+		CommandMaintenance: (*Bot).handleMaintenance,
+		CommandTicket:      (*Bot).handleTicket,
+		CommandStatuspage:  (*Bot).handleStatuspage,
+	}
+}
+
 // Run the telegram and listen to messages send to the telegram.
 func (b *Bot) Run(ctx context.Context, webhooks <-chan alertmanager.TelegramWebhook) error {
-	b.telegram.Handle(CommandStart, b.middleware(b.handleStart))
-	b.telegram.Handle(CommandStop, b.middleware(b.handleStop))
-	b.telegram.Handle(CommandHelp, b.middleware(b.handleHelp))
-	b.telegram.Handle(CommandChats, b.middleware(b.handleChats))
-	b.telegram.Handle(CommandID, b.middleware(b.handleID))
-	b.telegram.Handle(CommandStatus, b.middleware(b.handleStatus))
-	b.telegram.Handle(CommandAlerts, b.middleware(b.handleAlerts))
-	b.telegram.Handle(CommandSilences, b.middleware(b.handleSilences))
+	if b.subscribed != nil {
+		if err := b.reconcileSubscriptions(); err != nil {
+			return fmt.Errorf("failed to reconcile subscriptions: %w", err)
+		}
+	}
+	if b.recorder != nil {
+		if err := b.recordChats(); err != nil {
+			return fmt.Errorf("failed to record subscribed chats: %w", err)
+		}
+	}
+	if b.reconcile && b.alerts != nil && b.alertmanager != nil {
+		if err := b.reconcileAlerts(ctx); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to reconcile alerts with alertmanager", "err", err)
+		}
+	}
+	if b.configs != nil {
+		if err := b.loadConfigVersions(); err != nil {
+			return fmt.Errorf("failed to load configuration versions: %w", err)
+		}
+	}
+	if b.canary != nil {
+		if err := b.loadCanary(); err != nil {
+			return fmt.Errorf("failed to load canary rollout: %w", err)
+		}
+	}
+	if b.shards != nil {
+		// The members are known before the first webhook, so that it's only sent by one of them.
+		if _, err := b.heartbeat(time.Now()); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to update shard members", "err", err)
+		}
+	}
+	if b.idempotent != nil {
+		if err := b.sendPending(b.ownsChat, "sending alerts received before the restart"); err != nil {
+			return fmt.Errorf("failed to send alerts received before the restart: %w", err)
+		}
+	}
+
+	commands := commandHandlers()
+	for command, handler := range commands {
+		b.handle(command, b.middleware(b.command(handler)))
+	}
+	for alias, command := range b.aliases {
+		b.handle(alias, b.middleware(b.command(commands[command])))
+	}
+	if b.edits != nil {
+		b.handle(telebot.OnEdited, b.handleEdited(commands))
+	}
+	if len(b.aliases) > 0 {
+		b.registerCommands()
+	}
+	b.handle(telebot.OnQuery, b.handleInlineQuery)
+	b.handle(telebot.OnAddedToGroup, b.handleUserJoined)
+	b.handle(telebot.OnUserJoined, b.handleUserJoined)
+	b.handle(telebot.OnUserLeft, b.handleUserLeft)
+	if b.migrations != nil {
+		b.handle(telebot.OnMigration, b.handleMigration)
+	}
+	if b.details != nil {
+		b.handle(&detailsButton, b.handleDetails)
+	}
+	if b.correlator != nil {
+		b.handle(&correlatedButton, b.handleCorrelated)
+	}
+	if b.approvals != nil {
+		b.handle(&approveButton, b.handleApprove)
+		b.handle(&denyButton, b.handleDeny)
+	}
+	b.handle(&forgetMeButton, b.handleForgetMeConfirm)
+	if b.tickets != nil && b.alerts != nil {
+		b.handle(&ticketButton, b.handleTicketButton)
+	}
+	if b.reactionAck && b.alerts != nil {
+		b.handle(OnReaction, b.handleReaction)
+	}
+	if b.deepLinks != nil && b.alerts != nil {
+		b.handle(&ackLinkButton, b.handleAckLink)
+		b.handle(&silenceLinkButton, b.handleSilenceLink)
+	}
 
 	var gr run.Group
+	if b.commandWorkers > 1 {
+		b.commandQueues = make([]chan func(), b.commandWorkers)
+		b.commandsDone = ctx.Done()
+		for i := range b.commandQueues {
+			updates := make(chan func(), commandQueueSize)
+			b.commandQueues[i] = updates
+
+			ctx, cancel := context.WithCancel(ctx)
+			gr.Add(func() error {
+				return b.runCommandWorker(ctx, updates)
+			}, func(err error) {
+				cancel()
+			})
+		}
+	}
 	{
 		gr.Add(func() error {
 			return b.sendWebhook(ctx, webhooks)
 		}, func(err error) {
 		})
 	}
+	if b.alerts != nil && b.escalationAfter > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runEscalation(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.alerts != nil && b.reminders != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runReminders(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.maintenance != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runMaintenance(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.idempotent != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.pruneIdempotency(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.handled != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.pruneHandledCommands(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.history != nil && b.historyRetention > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.pruneHistory(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.sendWorkers > 1 {
+		b.sendQueues = make([]chan sendJob, b.sendWorkers)
+		for i := range b.sendQueues {
+			jobs := make(chan sendJob, sendQueueSize)
+			b.sendQueues[i] = jobs
+
+			ctx, cancel := context.WithCancel(ctx)
+			gr.Add(func() error {
+				return b.runSendWorker(ctx, jobs)
+			}, func(err error) {
+				cancel()
+			})
+		}
+	}
+	if b.selfMonitor != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runSelfMonitoring(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.deadMans != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runDeadMansSwitch(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.watchdog != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runWatchdog(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.digest != nil {
+		// The first digest covers the webhooks handled from now on.
+		since := time.Now()
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runDigest(ctx, since)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.outage != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runOutage(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.shards != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runShards(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.chatRefresh > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.runChatRefresh(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
+	if b.deliveries != nil && b.deliveryRetention > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(func() error {
+			return b.pruneDeliveries(ctx)
+		}, func(err error) {
+			cancel()
+		})
+	}
 	{
 		gr.Add(func() error {
 			b.telegram.Start()
@@ -264,44 +870,140 @@ func (b *Bot) sendWebhook(ctx context.Context, webhooks <-chan alertmanager.Tele
 		case <-ctx.Done():
 			return nil
 		case w := <-webhooks:
-			chat, err := b.chats.Get(telebot.ChatID(w.ChatID))
-			if err != nil {
-				if errors.Is(err, ChatNotFoundErr) {
-					level.Warn(b.logger).Log("msg", "chat is not subscribed for alerts", "chat_id", w.ChatID, "err", err)
-					continue
-				}
+			if err := b.receiveWebhook(ctx, w); err != nil {
 				return err
 			}
-
-			data := &template.Data{
-				Receiver:          w.Message.Receiver,
-				Status:            w.Message.Status,
-				Alerts:            w.Message.Alerts,
-				GroupLabels:       w.Message.GroupLabels,
-				CommonLabels:      w.Message.CommonLabels,
-				CommonAnnotations: w.Message.CommonAnnotations,
-				ExternalURL:       w.Message.ExternalURL,
+		case w := <-b.replays:
+			if err := b.replayWebhook(ctx, w); err != nil {
+				return err
 			}
-
-			out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
-			if err != nil {
-				level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
-				continue
+		case barrier := <-b.barriers:
+			// The webhooks received before the barrier are handled before it's passed.
+			for len(webhooks) > 0 {
+				if err := b.receiveWebhook(ctx, <-webhooks); err != nil {
+					return err
+				}
+			}
+			for len(b.replays) > 0 {
+				if err := b.replayWebhook(ctx, <-b.replays); err != nil {
+					return err
+				}
 			}
+			close(barrier)
+		}
+	}
+}
+
+// replayWebhook processes a webhook replayed on purpose, even if its alerts were sent already.
+func (b *Bot) replayWebhook(ctx context.Context, w alertmanager.TelegramWebhook) error {
+	if err := b.processWebhook(ctx, w, true); err != nil {
+		return err
+	}
+	b.processedEvents(Processed{Kind: ProcessedWebhook, ChatID: w.ChatID})
+	return nil
+}
 
-			sendOpts := telebot.SendOptions{ParseMode: telebot.ModeHTML}
+// receiveWebhook keeps and processes a webhook received from Alertmanager.
+func (b *Bot) receiveWebhook(ctx context.Context, w alertmanager.TelegramWebhook) error {
+	if b.peerDedup != nil && b.dropPeerDuplicate(w) {
+		b.processedEvents(Processed{Kind: ProcessedWebhook, ChatID: w.ChatID})
+		return nil
+	}
+	if len(b.redactions) > 0 {
+		w = b.redactWebhook(w)
+	}
+	if b.recorder != nil {
+		b.recordWebhook(w)
+	}
+	if err := b.webhooks.add(w, time.Now()); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to keep received webhook", "err", err)
+	}
+	if err := b.processWebhook(ctx, w, false); err != nil {
+		return err
+	}
+	if b.deadMans != nil {
+		b.pingDeadMansSwitch()
+	}
+	b.processedEvents(Processed{Kind: ProcessedWebhook, ChatID: w.ChatID})
+	return nil
+}
 
-			if value, ok := data.GroupLabels["silent"]; ok && value == "true" {
-				sendOpts.DisableNotification = true
+// processWebhook sends the alerts of a webhook to its chat or all chats of its group.
+// Unless the webhook is replayed, alerts that were sent to a chat already are skipped, see WithIdempotency.
+func (b *Bot) processWebhook(ctx context.Context, w alertmanager.TelegramWebhook, replayed bool) error {
+	w.ChatID = b.migratedChatID(w.ChatID)
+	if b.watchdog != nil {
+		w.Message = b.receiveHeartbeat(w.Message)
+		if len(w.Message.Alerts) == 0 {
+			return nil
+		}
+	}
+	if b.suppression {
+		w.Message = b.annotateSuppression(ctx, w.Message)
+	}
+	if len(b.enrichers) > 0 {
+		w.Message = b.enrichAlerts(ctx, w.Message)
+	}
+	if b.shadow != nil {
+		b.sendShadow(w)
+	}
+	if b.statuspage != nil && !replayed {
+		b.syncStatuspage(ctx, w.Message)
+	}
+	if len(b.outputs) > 0 && !replayed {
+		b.sendOutputs(ctx, w)
+	}
+
+	chatIDs := []int64{w.ChatID}
+	var f *fanOut
+	if w.Group != "" {
+		ids, err := b.groupChats(w.Group)
+		if err != nil {
+			if errors.Is(err, ChatGroupNotFoundErr) {
+				level.Warn(b.logger).Log("msg", "chat group not found", "group", w.Group)
+			} else {
+				level.Warn(b.logger).Log("msg", "failed to get chat group", "group", w.Group, "err", err)
 			}
+			return nil
+		}
+		chatIDs = ids
+		f = newFanOut(len(chatIDs))
+	}
 
-			_, err = b.telegram.Send(chat, b.truncateMessage(out), &sendOpts)
-			if err != nil {
-				level.Warn(b.logger).Log("msg", "failed to send message with alerts", "err", err)
+	for _, chatID := range chatIDs {
+		if !b.ownsChat(chatID) {
+			f.done("")
+			continue
+		}
+		m := w.Message
+		if !replayed {
+			var ok bool
+			if m, ok = b.claimAlerts(chatID, m); !ok {
+				f.done("")
 				continue
 			}
 		}
+		if b.correlator != nil && b.correlate(chatID, m) {
+			f.done("")
+			continue
+		}
+		if err := b.enqueue(ctx, chatID, m, f, w.ReceivedAt); err != nil {
+			return err
+		}
+	}
+
+	if f != nil {
+		logSummary := func() {
+			b.logFanOut(f, "webhook sent to chat group", "group", w.Group)
+		}
+		// The workers send to the chats in the background, so wait for them without blocking the next webhook.
+		if b.sendQueues != nil {
+			go logSummary()
+		} else {
+			logSummary()
+		}
 	}
+	return nil
 }
 
 func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Message) {
@@ -309,7 +1011,51 @@ func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Messag
 		if m.IsService() {
 			return
 		}
-		if !b.isAdminID(m.Sender.ID) && m.Text != CommandID {
+		if b.isBanned(m.Sender) {
+			level.Info(b.logger).Log(
+				"msg", "dropping message from banned sender",
+				"sender_id", m.Sender.ID,
+				"sender_username", m.Sender.Username,
+			)
+			return
+		}
+		command := strings.Split(m.Text, " ")[0]
+		if alias, ok := b.aliases[command]; ok {
+			command = alias
+		}
+		if !b.claimCommand(m, command) {
+			return
+		}
+		if b.isDeepLink(m, command) {
+			if !b.isAuthorized(m.Sender) {
+				level.Info(b.logger).Log(
+					"msg", "dropping message from forbidden sender",
+					"sender_id", m.Sender.ID,
+					"sender_username", m.Sender.Username,
+				)
+				return
+			}
+			b.commandEvents(command)
+			if err := b.handleDeepLink(m); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to handle deep link", "err", err)
+			}
+			return
+		}
+		if !b.isAllowed(m, command) {
+			if command == CommandStart && b.invites != nil && strings.TrimSpace(m.Payload) != "" {
+				b.commandEvents(command)
+				if err := b.redeemInvite(m); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to redeem invite", "err", err)
+				}
+				return
+			}
+			if command == CommandStart && b.approvals != nil {
+				b.commandEvents(command)
+				if err := b.requestApproval(m); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to request approval", "err", err)
+				}
+				return
+			}
 			level.Info(b.logger).Log(
 				"msg", "dropping message from forbidden sender",
 				"sender_id", m.Sender.ID,
@@ -318,9 +1064,16 @@ func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Messag
 			return
 		}
 
-		command := strings.Split(m.Text, " ")[0]
 		b.commandEvents(command)
 
+		if b.readOnly(m.Chat.ID) {
+			level.Debug(b.logger).Log("msg", "refusing command in read-only chat", "chat_id", m.Chat.ID, "text", m.Text)
+			if _, err := b.telegram.Send(m.Chat, responseChatReadOnly); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to refuse command", "err", err)
+			}
+			return
+		}
+
 		level.Debug(b.logger).Log("msg", "message received", "text", m.Text)
 		if err := next(m); err != nil {
 			level.Warn(b.logger).Log("msg", "failed to handle command", "err", err)
@@ -329,6 +1082,14 @@ func (b *Bot) middleware(next func(*telebot.Message) error) func(*telebot.Messag
 }
 
 func (b *Bot) handleStart(message *telebot.Message) error {
+	archived, err := b.restoreChat(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to restore archived chat", "chat_id", message.Chat.ID, "err", err)
+	}
+	if archived != nil {
+		level.Info(b.logger).Log("msg", "archived chat restored", "chat_id", message.Chat.ID)
+	}
+
 	if err := b.chats.Add(message.Chat); err != nil {
 		level.Warn(b.logger).Log("msg", "failed to add chat to chat store", "err", err)
 		_, err = b.telegram.Send(message.Chat, "I can't add this chat to the subscribers list.")
@@ -352,6 +1113,12 @@ func (b *Bot) handleStart(message *telebot.Message) error {
 }
 
 func (b *Bot) handleStop(message *telebot.Message) error {
+	if err := b.archiveChat(message.Chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to archive chat", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't archive this chat before removing it from the subscribers list.")
+		return err
+	}
+
 	if err := b.chats.Remove(message.Chat); err != nil {
 		level.Warn(b.logger).Log("msg", "failed to remove chat from chat store", "err", err)
 		_, err = b.telegram.Send(message.Chat, "I can't remove this chat from the subscribers list.")
@@ -373,6 +1140,19 @@ func (b *Bot) handleHelp(message *telebot.Message) error {
 }
 
 func (b *Bot) handleChats(message *telebot.Message) error {
+	if fields := strings.Fields(message.Payload); len(fields) > 0 {
+		switch fields[0] {
+		case subcommandChatMode:
+			return b.handleChatMode(message, fields[1:])
+		case subcommandChatsArchived:
+			return b.handleChatsArchived(message)
+		case subcommandChatRestore:
+			return b.handleChatRestore(message, fields[1:])
+		}
+		_, err := b.telegram.Send(message.Chat, responseChatsUsage)
+		return err
+	}
+
 	chats, err := b.chats.List()
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to list chats from chat store", "err", err)
@@ -387,10 +1167,10 @@ func (b *Bot) handleChats(message *telebot.Message) error {
 
 	list := ""
 	for _, chat := range chats {
-		if chat.Type == telebot.ChatGroup {
-			list = list + fmt.Sprintf("@%s\n", chat.Title)
+		if b.readOnly(chat.ID) {
+			list = list + fmt.Sprintf("%s (read-only)\n", chatName(chat))
 		} else {
-			list = list + fmt.Sprintf("@%s\n", chat.Username)
+			list = list + fmt.Sprintf("%s\n", chatName(chat))
 		}
 	}
 
@@ -398,6 +1178,14 @@ func (b *Bot) handleChats(message *telebot.Message) error {
 	return err
 }
 
+// chatName returns the @name of a chat like /chats lists it.
+func chatName(chat *telebot.Chat) string {
+	if chat.Type == telebot.ChatGroup {
+		return "@" + chat.Title
+	}
+	return "@" + chat.Username
+}
+
 func (b *Bot) handleID(message *telebot.Message) error {
 	if message.Private() {
 		_, err := b.telegram.Send(message.Chat, fmt.Sprintf("Your ID is %d", message.Sender.ID))
@@ -408,11 +1196,26 @@ func (b *Bot) handleID(message *telebot.Message) error {
 	return err
 }
 
+// unreachableResponse is the response to a failed request to Alertmanager while its breaker is open,
+// or response if the request failed otherwise.
+func unreachableResponse(err error, response string) string {
+	var unavailable *alertmanager.UnavailableError
+	if !errors.As(err, &unavailable) {
+		return response
+	}
+	if unavailable.LastSuccess.IsZero() {
+		return "Alertmanager is currently unreachable."
+	}
+	return fmt.Sprintf("Alertmanager is currently unreachable (last success %s ago).", formatDuration(time.Since(unavailable.LastSuccess)))
+}
+
 func (b *Bot) handleStatus(message *telebot.Message) error {
-	status, err := b.alertmanager.Status(context.TODO())
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	status, err := b.alertmanagerFor(message.Chat.ID).Status(ctx)
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to get status", "err", err)
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to get status... %v", err))
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to get status... %v", err)))
 		return err
 	}
 
@@ -423,9 +1226,9 @@ func (b *Bot) handleStatus(message *telebot.Message) error {
 		message.Chat,
 		fmt.Sprintf(
 			"*AlertManager*\nVersion: %s\nUptime: %s\n*AlertManager Bot*\nVersion: %s\nUptime: %s",
-			*status.VersionInfo.Version,
+			escapeText(telebot.ModeMarkdown, *status.VersionInfo.Version),
 			uptime,
-			b.revision,
+			escapeText(telebot.ModeMarkdown, b.revision),
 			uptimeBot,
 		),
 		&telebot.SendOptions{ParseMode: telebot.ModeMarkdown},
@@ -434,10 +1237,13 @@ func (b *Bot) handleStatus(message *telebot.Message) error {
 }
 
 func (b *Bot) handleAlerts(message *telebot.Message) error {
-	status, err := b.alertmanager.Status(context.TODO())
+	am := b.alertmanagerFor(message.Chat.ID)
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	status, err := am.Status(ctx)
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to get status with config", "err", err)
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to list alerts... %v", err)))
 		return err
 	}
 
@@ -452,12 +1258,13 @@ func (b *Bot) handleAlerts(message *telebot.Message) error {
 		silenced = true
 	}
 
-	alerts, err := b.alertmanager.ListAlerts(context.TODO(), receiver, silenced)
+	amAlerts, err := am.ListAlertStatuses(ctx, receiver, silenced)
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list alerts... %v", err))
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to list alerts... %v", err)))
 		return err
 	}
+	alerts := suppressedAlerts(amAlerts)
 
 	if len(alerts) == 0 {
 		_, err = b.telegram.Send(message.Chat, "No alerts right now! 🎉")
@@ -505,9 +1312,11 @@ func receiverFromConfig(c string, id int64) (string, error) {
 }
 
 func (b *Bot) handleSilences(message *telebot.Message) error {
-	silences, err := b.alertmanager.ListSilences(context.TODO())
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	silences, err := b.alertmanagerFor(message.Chat.ID).ListSilences(ctx)
 	if err != nil {
-		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list silences... %v", err))
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to list silences... %v", err)))
 		return err
 	}
 
@@ -526,30 +1335,13 @@ func (b *Bot) handleSilences(message *telebot.Message) error {
 }
 
 func (b *Bot) tmplAlerts(alerts ...*types.Alert) (string, error) {
-	data := b.templates.Data("default", nil, alerts...)
+	data := escapeData(telebot.ModeHTML, b.redactData(b.templates.Data("default", nil, alerts...)))
 
-	out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
+	out, err := b.templates.ExecuteTextString(`{{ template "`+defaultTemplate+`" . }}`, data)
 	if err != nil {
 		return "", err
 	}
 
+	out, _ = extractQRCodes(out)
 	return out, nil
 }
-
-// Truncate very big message.
-func (b *Bot) truncateMessage(str string) string {
-	truncateMsg := str
-	if len(str) > 4095 { // telegram API can only support 4096 bytes per message
-		level.Warn(b.logger).Log("msg", "Message is bigger than 4095, truncate...")
-		// find the end of last alert, we do not want break the html tags
-		i := strings.LastIndex(str[0:4080], "\n\n") // 4080 + "\n<b>[SNIP]</b>" == 4095
-		if i > 1 {
-			truncateMsg = str[0:i] + "\n<b>[SNIP]</b>"
-		} else {
-			truncateMsg = "Message is too long... can't send.."
-			level.Warn(b.logger).Log("msg", "truncateMessage: Unable to find the end of last alert.")
-		}
-		return truncateMsg
-	}
-	return truncateMsg
-}
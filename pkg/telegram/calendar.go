@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/calendar"
+)
+
+// calendarSource is the Source of the maintenance windows synced from a calendar.
+const calendarSource = "calendar"
+
+// MaintenanceCalendar returns the events maintenance windows are synced from, e.g. a calendar.Feed.
+type MaintenanceCalendar interface {
+	Events(ctx context.Context) ([]calendar.Event, error)
+}
+
+type maintenanceCalendar struct {
+	calendar MaintenanceCalendar
+	interval time.Duration
+	// synced is only used by the maintenance loop.
+	synced time.Time
+}
+
+// WithMaintenanceCalendar syncs the maintenance windows of WithMaintenance from the events of the calendar every interval.
+// Events are maintenance windows if lines of their description are equality matchers like instance=db-1, other events are skipped.
+// The windows of the calendar are announced to all subscribed chats before they start and end.
+func WithMaintenanceCalendar(c MaintenanceCalendar, interval time.Duration) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return errors.New("maintenance calendar interval must be positive")
+		}
+		b.calendar = &maintenanceCalendar{calendar: c, interval: interval}
+		return nil
+	}
+}
+
+// syncCalendar puts the windows of the calendar's events that haven't ended into the store
+// and removes the windows whose events were removed.
+func (b *Bot) syncCalendar(ctx context.Context, now time.Time) error {
+	events, err := b.calendar.calendar.Events(ctx)
+	if err != nil {
+		return err
+	}
+	windows, err := b.maintenance.store.List()
+	if err != nil {
+		return err
+	}
+
+	synced := map[string]*Maintenance{}
+	for _, m := range windows {
+		if m.Source == calendarSource {
+			synced[m.ID] = m
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, e := range events {
+		if !e.End.After(now) {
+			continue
+		}
+		matchers := eventMatchers(e.Description)
+		if len(matchers) == 0 {
+			continue
+		}
+
+		m := &Maintenance{
+			ID:        calendarSource + "-" + maintenanceID(e.UID),
+			Name:      e.Summary,
+			Matchers:  matchers,
+			StartsAt:  e.Start,
+			EndsAt:    e.End,
+			CreatedBy: calendarSource,
+			Source:    calendarSource,
+		}
+		if m.Name == "" {
+			m.Name = e.UID
+		}
+		seen[m.ID] = true
+
+		if old, ok := synced[m.ID]; ok {
+			if old.Name == m.Name && old.StartsAt.Equal(m.StartsAt) && old.EndsAt.Equal(m.EndsAt) && reflect.DeepEqual(old.Matchers, m.Matchers) {
+				continue
+			}
+			// A changed window is announced again, but the silence created once it started is kept.
+			m.SilenceID = old.SilenceID
+		}
+		if err := b.maintenance.store.Put(m); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "maintenance window synced from calendar", "id", m.ID)
+	}
+
+	for id := range synced {
+		if seen[id] {
+			continue
+		}
+		if err := b.maintenance.store.Remove(id); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "maintenance window removed from calendar", "id", id)
+	}
+	return nil
+}
+
+// eventMatchers returns the matchers on the lines of an event's description.
+func eventMatchers(description string) []string {
+	var matchers []string
+	for _, line := range strings.Split(description, "\n") {
+		m, err := alertmanager.ParseMatcher(line)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, m.String())
+	}
+	return matchers
+}
@@ -0,0 +1,258 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseConfigUsage = "Usage: " + CommandConfig + " [promote [percent]|rollback [version]|history|diff <version> <version>]"
+
+// canarySteps are the percentages of chats /config promote rolls the canary out to one after another.
+var canarySteps = []int{10, 25, 50, 100}
+
+// canaryHistoryLimit is the number of changes of the rollout that are kept.
+const canaryHistoryLimit = 10
+
+// RolloutChange is a change of the percentage of chats the canary configuration is rolled out to.
+type RolloutChange struct {
+	Version   int       `json:"version"`
+	Percent   int       `json:"percent"`
+	ChangedBy string    `json:"changedBy"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// Rollout is the current rollout of the canary configuration with its latest changes, newest last.
+type Rollout struct {
+	RolloutChange
+	History []RolloutChange `json:"history,omitempty"`
+}
+
+// BotCanaryStore keeps the rollout of the canary configuration.
+type BotCanaryStore interface {
+	Get() (*Rollout, error)
+	Put(*Rollout) error
+}
+
+// CanaryStore writes the rollout to a single key of a libkv store backend.
+type CanaryStore struct {
+	kv  store.Store
+	key string
+}
+
+// NewCanaryStore stores the rollout in the provided kv backend.
+func NewCanaryStore(kv store.Store, key string) (*CanaryStore, error) {
+	return &CanaryStore{kv: kv, key: key}, nil
+}
+
+// Get the rollout from the kv backend, a rollout to no chats if it was never changed.
+func (s *CanaryStore) Get() (*Rollout, error) {
+	kv, err := s.kv.Get(s.key)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &Rollout{}, nil
+		}
+		return nil, err
+	}
+	var r *Rollout
+	err = json.Unmarshal(kv.Value, &r)
+	return r, err
+}
+
+// Put the rollout into the kv backend.
+func (s *CanaryStore) Put(r *Rollout) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key, b, nil)
+}
+
+type canary struct {
+	store BotCanaryStore
+	// bot only holds the canary's templates, template overrides and label filter.
+	bot *Bot
+
+	mu      sync.Mutex
+	percent int
+}
+
+// WithCanary renders the alerts of a percentage of chats with the bot changed by the options,
+// so that changes of the templates and hidden labels can be rolled out step by step with /config promote
+// and taken back with /config rollback. Like with WithShadow only WithTemplates, WithTemplateOverrides
+// and WithLabelFilter change how the canary renders alerts, without WithTemplates it uses the bot's templates.
+func WithCanary(s BotCanaryStore, opts ...BotOption) BotOption {
+	return func(b *Bot) error {
+		r := &Bot{}
+		for _, opt := range opts {
+			if err := opt(r); err != nil {
+				return fmt.Errorf("canary: %w", err)
+			}
+		}
+		b.canary = &canary{store: s, bot: r}
+		return nil
+	}
+}
+
+// canaryBucket returns the chat's place among all chats from 0 to 99.
+// A chat in a rollout stays in it while the rollout grows.
+func canaryBucket(chatID int64) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.FormatInt(chatID, 10)))
+	return int(h.Sum32() % 100)
+}
+
+// renderer returns the bot rendering the alerts of the chat, the canary if the chat is part of its rollout
+// and otherwise the configuration rolled back to, if any.
+func (b *Bot) renderer(chatID int64) *Bot {
+	r := b
+	if active := b.activeConfig(); active != nil {
+		r = active
+	}
+	if b.canary == nil {
+		return r
+	}
+	b.canary.mu.Lock()
+	percent := b.canary.percent
+	b.canary.mu.Unlock()
+	if canaryBucket(chatID) < percent {
+		return b.canary.bot
+	}
+	return r
+}
+
+// loadCanary reads the rollout the canary had before the bot was started.
+func (b *Bot) loadCanary() error {
+	b.canary.bot.inherit(b)
+
+	r, err := b.canary.store.Get()
+	if err != nil {
+		return err
+	}
+	b.canary.mu.Lock()
+	b.canary.percent = r.Percent
+	b.canary.mu.Unlock()
+	return nil
+}
+
+// inherit uses the logger of the bot p and its templates unless b has its own.
+func (b *Bot) inherit(p *Bot) {
+	b.logger = p.logger
+	if b.templates == nil {
+		b.templates = p.templates
+	}
+	if b.alertOrder == nil {
+		b.alertOrder = p.alertOrder
+	}
+}
+
+func (b *Bot) handleConfig(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) > 0 && (args[0] == "history" || args[0] == "diff" || args[0] == "rollback" && len(args) == 2) {
+		return b.handleConfigVersions(message, args)
+	}
+
+	if b.canary == nil {
+		_, err := b.telegram.Send(message.Chat, "No canary configuration is configured.")
+		return err
+	}
+
+	r, err := b.canary.store.Get()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get canary rollout", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the rollout of the canary configuration.")
+		return err
+	}
+
+	if len(args) == 0 {
+		return b.sendRollout(message.Chat, r)
+	}
+
+	percent := 0
+	switch {
+	case args[0] == "rollback" && len(args) == 1:
+		if r.Percent == 0 {
+			_, err = b.telegram.Send(message.Chat, "The canary configuration isn't rolled out to any chats.")
+			return err
+		}
+	case args[0] == "promote" && len(args) == 1:
+		for _, step := range canarySteps {
+			if step > r.Percent {
+				percent = step
+				break
+			}
+		}
+		if percent == 0 {
+			_, err = b.telegram.Send(message.Chat, "The canary configuration is rolled out to all chats already.")
+			return err
+		}
+	case args[0] == "promote" && len(args) == 2:
+		percent, err = strconv.Atoi(strings.TrimSuffix(args[1], "%"))
+		if err != nil || percent < 1 || percent > 100 {
+			_, err = b.telegram.Send(message.Chat, "The percentage has to be between 1 and 100.")
+			return err
+		}
+	default:
+		_, err = b.telegram.Send(message.Chat, responseConfigUsage)
+		return err
+	}
+
+	if r.Version > 0 {
+		r.History = append(r.History, r.RolloutChange)
+		if len(r.History) > canaryHistoryLimit {
+			r.History = r.History[len(r.History)-canaryHistoryLimit:]
+		}
+	}
+	r.RolloutChange = RolloutChange{
+		Version:   r.Version + 1,
+		Percent:   percent,
+		ChangedBy: message.Sender.Username,
+		ChangedAt: time.Now(),
+	}
+	if err := b.canary.store.Put(r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put canary rollout", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the rollout of the canary configuration.")
+		return err
+	}
+
+	b.canary.mu.Lock()
+	b.canary.percent = percent
+	b.canary.mu.Unlock()
+
+	level.Info(b.logger).Log("msg", "canary rollout changed", "version", r.Version, "percent", percent, "username", message.Sender.Username)
+
+	return b.sendRollout(message.Chat, r)
+}
+
+// sendRollout sends the rollout of the canary with the number of subscribed chats it's rolled out to.
+func (b *Bot) sendRollout(chat *telebot.Chat, r *Rollout) error {
+	chats, err := b.chats.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list chats from chat store", "err", err)
+		_, err = b.telegram.Send(chat, "I can't list the subscribed chats.")
+		return err
+	}
+	canaryChats := 0
+	for _, c := range chats {
+		if canaryBucket(c.ID) < r.Percent {
+			canaryChats++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "The canary configuration is rolled out to %d%% of the chats, %d of %d subscribed chats.", r.Percent, canaryChats, len(chats))
+	if r.Version > 0 {
+		fmt.Fprintf(&out, "\nVersion %d by @%s %s ago.", r.Version, r.ChangedBy, formatDuration(time.Since(r.ChangedAt)))
+	}
+	_, err = b.telegram.Send(chat, out.String())
+	return err
+}
@@ -0,0 +1,168 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	subcommandChatMode = "mode"
+	chatModeReadOnly   = "readonly"
+	chatModeNormal     = "normal"
+
+	responseChatModeUsage    = "Usage: " + CommandChats + " " + subcommandChatMode + " <id> " + chatModeReadOnly + "|" + chatModeNormal
+	responseChatModeForAdmin = "Only admins can change the mode of chats."
+	responseChatReadOnly     = "This chat is read-only, I only send alerts here."
+)
+
+// ChatMode is the mode of a chat.
+type ChatMode struct {
+	ChatID int64 `json:"chatID"`
+	// ReadOnly chats only receive alerts, e.g. wallboards and announcement channels, commands sent there are refused.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// BotChatModeStore keeps the chats' modes.
+type BotChatModeStore interface {
+	Get(chatID int64) (*ChatMode, error)
+	Put(*ChatMode) error
+	Remove(chatID int64) error
+}
+
+// ChatModeStore writes the chats' modes to a libkv store backend.
+type ChatModeStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewChatModeStore stores the chats' modes in the provided kv backend.
+func NewChatModeStore(kv store.Store, storeKeyPrefix string) (*ChatModeStore, error) {
+	return &ChatModeStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the mode of a chat, chats are normal unless an admin changed their mode.
+func (s *ChatModeStore) Get(chatID int64) (*ChatMode, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatMode{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var m *ChatMode
+	err = json.Unmarshal(kv.Value, &m)
+	return m, err
+}
+
+// Put the mode of a chat into the kv backend.
+func (s *ChatModeStore) Put(m *ChatMode) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, m.ChatID), b, nil)
+}
+
+// Remove the mode of a chat from the kv backend.
+func (s *ChatModeStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithChatModes lets admins make chats read-only with /chats mode <id> readonly,
+// e.g. for wallboards and announcement channels that should only show alerts.
+func WithChatModes(modes BotChatModeStore) BotOption {
+	return func(b *Bot) error {
+		b.chatModes = modes
+		return nil
+	}
+}
+
+// readOnly returns whether an admin made the chat read-only.
+func (b *Bot) readOnly(chatID int64) bool {
+	if b.chatModes == nil {
+		return false
+	}
+	m, err := b.chatModes.Get(chatID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chat mode", "chat_id", chatID, "err", err)
+		return false
+	}
+	return m.ReadOnly
+}
+
+// handleChatMode shows or changes the mode of a chat, the fields are the ones of /chats after mode.
+func (b *Bot) handleChatMode(message *telebot.Message, fields []string) error {
+	if b.chatModes == nil {
+		_, err := b.telegram.Send(message.Chat, "Chat modes aren't enabled.")
+		return err
+	}
+
+	if len(fields) == 0 || len(fields) > 2 {
+		_, err := b.telegram.Send(message.Chat, responseChatModeUsage)
+		return err
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseChatModeUsage)
+		return err
+	}
+	if _, err := b.chats.Get(telebot.ChatID(id)); err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The chat %d isn't subscribed.", id))
+		return err
+	}
+
+	m, err := b.chatModes.Get(id)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chat mode", "chat_id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the mode of this chat.")
+		return err
+	}
+
+	if len(fields) == 1 {
+		mode := chatModeNormal
+		if m.ReadOnly {
+			mode = chatModeReadOnly
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The chat %d is %s.", id, mode))
+		return err
+	}
+
+	if !b.isAdminID(message.Sender.ID) {
+		_, err = b.telegram.Send(message.Chat, responseChatModeForAdmin)
+		return err
+	}
+	switch fields[1] {
+	case chatModeReadOnly:
+		m.ReadOnly = true
+	case chatModeNormal:
+		m.ReadOnly = false
+	default:
+		_, err = b.telegram.Send(message.Chat, responseChatModeUsage)
+		return err
+	}
+
+	if err := b.chatModes.Put(m); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put chat mode", "chat_id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the mode of this chat.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "chat mode changed", "chat_id", id, "readonly", m.ReadOnly, "username", message.Sender.Username)
+
+	if m.ReadOnly {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The chat %d is now read-only, I only send alerts there.", id))
+	} else {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The chat %d is now normal, commands work there again.", id))
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// DefaultCluster is the name of the Alertmanager set with WithAlertmanager, which chats target by default.
+const DefaultCluster = "default"
+
+const responseClusterUsage = "Usage: " + CommandCluster + " use <cluster>..., e.g. " + CommandCluster + " use prod-eu prod-us"
+
+// ChatClusters are the Alertmanager clusters a chat's commands target.
+type ChatClusters struct {
+	ChatID int64 `json:"chatID"`
+	// Names of the clusters, the default cluster if empty.
+	Names []string `json:"names,omitempty"`
+}
+
+// BotClusterStore keeps the Alertmanager clusters the chats selected.
+type BotClusterStore interface {
+	Get(chatID int64) (*ChatClusters, error)
+	Put(*ChatClusters) error
+	Remove(chatID int64) error
+}
+
+// ClusterStore writes the chats' selected Alertmanager clusters to a libkv store backend.
+type ClusterStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewClusterStore stores the chats' selected Alertmanager clusters in the provided kv backend.
+func NewClusterStore(kv store.Store, storeKeyPrefix string) (*ClusterStore, error) {
+	return &ClusterStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the selected clusters of a chat, none if the chat never selected any.
+func (s *ClusterStore) Get(chatID int64) (*ChatClusters, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatClusters{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var c *ChatClusters
+	err = json.Unmarshal(kv.Value, &c)
+	return c, err
+}
+
+// Put the selected clusters of a chat into the kv backend.
+func (s *ClusterStore) Put(c *ChatClusters) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, c.ChatID), b, nil)
+}
+
+// Remove the selected clusters of a chat from the kv backend.
+func (s *ClusterStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Cluster is a named Alertmanager cluster chats can select with /cluster.
+type Cluster struct {
+	Name         string
+	Alertmanager Alertmanager
+}
+
+type clusters struct {
+	store BotClusterStore
+	// names in the order they're listed, starting with DefaultCluster.
+	names  []string
+	byName map[string]Alertmanager
+}
+
+// WithClusters lets chats select which Alertmanager clusters /alerts, /silences, /find, /status, /routes,
+// inline queries and silences target with /cluster use, in addition to the default one set with WithAlertmanager.
+// Alerts and silences of several selected clusters are merged, silences are created in the first one.
+// Webhooks, reconciliation and suppression keep using the default cluster.
+func WithClusters(clusterList []Cluster, s BotClusterStore) BotOption {
+	return func(b *Bot) error {
+		c := &clusters{store: s, names: []string{DefaultCluster}, byName: map[string]Alertmanager{}}
+		for _, cluster := range clusterList {
+			if cluster.Name == DefaultCluster {
+				return fmt.Errorf("the cluster name %s is reserved", DefaultCluster)
+			}
+			if _, ok := c.byName[cluster.Name]; ok {
+				return fmt.Errorf("cluster %s is configured more than once", cluster.Name)
+			}
+			c.names = append(c.names, cluster.Name)
+			c.byName[cluster.Name] = cluster.Alertmanager
+		}
+		b.clusters = c
+		return nil
+	}
+}
+
+// clusterAlertmanager returns the Alertmanager of the cluster, the default one for DefaultCluster.
+func (b *Bot) clusterAlertmanager(name string) (Alertmanager, bool) {
+	if name == DefaultCluster {
+		return b.alertmanager, true
+	}
+	am, ok := b.clusters.byName[name]
+	return am, ok
+}
+
+// selectedClusters returns the names of the clusters selected by the chat.
+func (b *Bot) selectedClusters(chatID int64) ([]string, error) {
+	c, err := b.clusters.store.Get(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Names) == 0 {
+		return []string{DefaultCluster}, nil
+	}
+	return c.Names, nil
+}
+
+// alertmanagerFor returns the Alertmanager of the clusters the chat selected,
+// the default one if it selected none or they can't be read.
+func (b *Bot) alertmanagerFor(chatID int64) Alertmanager {
+	if b.clusters == nil {
+		return b.alertmanager
+	}
+	names, err := b.selectedClusters(chatID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get selected clusters", "chat_id", chatID, "err", err)
+		return b.alertmanager
+	}
+
+	var selected []Alertmanager
+	for _, name := range names {
+		// Clusters removed from the configuration since they were selected are skipped.
+		if am, ok := b.clusterAlertmanager(name); ok {
+			selected = append(selected, am)
+		}
+	}
+	switch len(selected) {
+	case 0:
+		return b.alertmanager
+	case 1:
+		return selected[0]
+	}
+	peers := make([]alertmanager.Peer, 0, len(selected))
+	for _, am := range selected {
+		peers = append(peers, am)
+	}
+	return alertmanager.NewClusterOf(peers...)
+}
+
+func (b *Bot) handleCluster(message *telebot.Message) error {
+	if b.clusters == nil {
+		_, err := b.telegram.Send(message.Chat, "Selecting Alertmanager clusters isn't enabled.")
+		return err
+	}
+
+	args := strings.Fields(strings.ReplaceAll(message.Payload, ",", " "))
+	if len(args) == 0 {
+		selected, err := b.selectedClusters(message.Chat.ID)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get selected clusters", "chat_id", message.Chat.ID, "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't get the clusters of this chat.")
+			return err
+		}
+		isSelected := map[string]bool{}
+		for _, name := range selected {
+			isSelected[name] = true
+		}
+		out := "Alertmanager clusters:\n"
+		for _, name := range b.clusters.names {
+			if isSelected[name] {
+				out += "✅ " + name + "\n"
+			} else {
+				out += "▫️ " + name + "\n"
+			}
+		}
+		_, err = b.telegram.Send(message.Chat, strings.TrimSuffix(out, "\n"))
+		return err
+	}
+	if args[0] != "use" || len(args) == 1 {
+		_, err := b.telegram.Send(message.Chat, responseClusterUsage)
+		return err
+	}
+
+	names := args[1:]
+	seen := map[string]bool{}
+	c := &ChatClusters{ChatID: message.Chat.ID}
+	for _, name := range names {
+		if _, ok := b.clusterAlertmanager(name); !ok {
+			_, err := b.telegram.Send(message.Chat, fmt.Sprintf("There is no cluster %s, %s lists them.", name, CommandCluster))
+			return err
+		}
+		if !seen[name] {
+			seen[name] = true
+			c.Names = append(c.Names, name)
+		}
+	}
+	if len(c.Names) == 1 && c.Names[0] == DefaultCluster {
+		c.Names = nil
+	}
+
+	if err := b.clusters.store.Put(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put selected clusters", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the clusters of this chat.")
+		return err
+	}
+
+	selected := strings.Join(c.Names, ", ")
+	if selected == "" {
+		selected = DefaultCluster
+	}
+	level.Info(b.logger).Log("msg", "clusters selected", "chat_id", message.Chat.ID, "clusters", selected)
+
+	_, err := b.telegram.Send(message.Chat, fmt.Sprintf("This chat now uses the Alertmanager cluster(s) %s.", selected))
+	return err
+}
@@ -0,0 +1,481 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// configHistoryLimit is the number of versions /config history shows.
+const configHistoryLimit = 10
+
+// ConfigVersion is a configuration the bot applied.
+type ConfigVersion struct {
+	Version   int       `json:"version"`
+	AppliedAt time.Time `json:"appliedAt"`
+	// AppliedBy is the username of the admin who rolled back to the configuration, empty if it was applied on startup.
+	AppliedBy string `json:"appliedBy,omitempty"`
+	// RollbackOf is the version that was rolled back to.
+	RollbackOf int `json:"rollbackOf,omitempty"`
+	// Reloaded is whether the configuration was applied by reloading it while the bot was running.
+	Reloaded bool   `json:"reloaded,omitempty"`
+	Config   string `json:"config"`
+}
+
+// ReloadResult tells which configuration version is applied after a reload.
+type ReloadResult struct {
+	Version int `json:"version"`
+	// Changed is whether the reloaded configuration was applied as a new version.
+	Changed bool `json:"changed"`
+}
+
+// BotConfigStore keeps all versions of the applied configuration.
+type BotConfigStore interface {
+	List() ([]*ConfigVersion, error)
+	Get(version int) (*ConfigVersion, error)
+	Put(*ConfigVersion) error
+}
+
+// ConfigVersionNotFoundErr returned by the store if a version doesn't exist.
+var ConfigVersionNotFoundErr = errors.New("config version not found in store")
+
+// ConfigStore writes the configuration versions to a libkv store backend.
+type ConfigStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewConfigStore stores configuration versions in the provided kv backend.
+func NewConfigStore(kv store.Store, storeKeyPrefix string) (*ConfigStore, error) {
+	return &ConfigStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all versions saved in the kv backend, oldest first.
+func (s *ConfigStore) List() ([]*ConfigVersion, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []*ConfigVersion
+	for _, kv := range kvPairs {
+		var v *ConfigVersion
+		if err := json.Unmarshal(kv.Value, &v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	return versions, nil
+}
+
+// Get a version from the kv backend.
+func (s *ConfigStore) Get(version int) (*ConfigVersion, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, version))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, ConfigVersionNotFoundErr
+		}
+		return nil, err
+	}
+	var v *ConfigVersion
+	err = json.Unmarshal(kv.Value, &v)
+	return v, err
+}
+
+// Put a version into the kv backend.
+func (s *ConfigStore) Put(v *ConfigVersion) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, v.Version), b, nil)
+}
+
+type configVersions struct {
+	store  BotConfigStore
+	config string
+	apply  func(config string) ([]BotOption, error)
+
+	mu sync.Mutex
+	// active renders the alerts after a rollback or reload, nil while the configuration of the startup is used.
+	active *Bot
+	// changes serializes rollbacks and reloads, so that they don't record the same version.
+	changes sync.Mutex
+}
+
+// WithConfigVersions records the configuration the bot is started with as a new version whenever it changed
+// and allows admins to compare versions with /config diff and to roll back to one with /config rollback.
+// apply returns the options rendering alerts like a stored configuration, a rollback lasts until the bot
+// is started with a changed configuration.
+func WithConfigVersions(s BotConfigStore, config string, apply func(config string) ([]BotOption, error)) BotOption {
+	return func(b *Bot) error {
+		b.configs = &configVersions{store: s, config: config, apply: apply}
+		return nil
+	}
+}
+
+// WithConfigReload allows the configuration to be reloaded with ReloadConfig while the bot is running,
+// e.g. after changing the template files. read returns the current configuration like the one of WithConfigVersions.
+func WithConfigReload(read func() (string, error)) BotOption {
+	return func(b *Bot) error {
+		b.reload = read
+		return nil
+	}
+}
+
+// activeConfig returns the bot rendering the alerts of a rolled back configuration, nil if there is none.
+func (b *Bot) activeConfig() *Bot {
+	if b.configs == nil {
+		return nil
+	}
+	b.configs.mu.Lock()
+	defer b.configs.mu.Unlock()
+	return b.configs.active
+}
+
+// loadConfigVersions records the configuration the bot is started with,
+// unless it didn't change since the last start, then a rollback since is applied again.
+func (b *Bot) loadConfigVersions() error {
+	versions, err := b.configs.store.List()
+	if err != nil {
+		return err
+	}
+
+	var startup, latest *ConfigVersion
+	for _, v := range versions {
+		if v.AppliedBy == "" && !v.Reloaded {
+			startup = v
+		}
+		latest = v
+	}
+
+	if startup == nil || startup.Config != b.configs.config {
+		v := &ConfigVersion{Version: 1, AppliedAt: time.Now(), Config: b.configs.config}
+		if latest != nil {
+			v.Version = latest.Version + 1
+		}
+		if err := b.configs.store.Put(v); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "configuration version applied", "version", v.Version)
+		return nil
+	}
+
+	if latest.Config == b.configs.config {
+		return nil
+	}
+	active, err := b.configBot(latest.Config)
+	if err != nil {
+		return fmt.Errorf("version %d: %w", latest.Version, err)
+	}
+	b.configs.mu.Lock()
+	b.configs.active = active
+	b.configs.mu.Unlock()
+	keyvals := []interface{}{"msg", "configuration version applied", "version", latest.Version}
+	if latest.RollbackOf != 0 {
+		keyvals = append(keyvals, "rollback_of", latest.RollbackOf)
+	}
+	level.Info(b.logger).Log(keyvals...)
+	return nil
+}
+
+// configBot returns the bot rendering alerts with the configuration.
+func (b *Bot) configBot(config string) (*Bot, error) {
+	opts, err := b.configs.apply(config)
+	if err != nil {
+		return nil, err
+	}
+	r := &Bot{}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	r.inherit(b)
+	return r, nil
+}
+
+// parseConfigVersion parses versions like v3 or 3.
+func parseConfigVersion(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+func (b *Bot) handleConfigVersions(message *telebot.Message, args []string) error {
+	if b.configs == nil {
+		_, err := b.telegram.Send(message.Chat, "Configuration versions aren't recorded.")
+		return err
+	}
+
+	switch args[0] {
+	case "history":
+		return b.sendConfigHistory(message.Chat)
+	case "diff":
+		if len(args) != 3 {
+			break
+		}
+		from, err := parseConfigVersion(args[1])
+		if err != nil {
+			break
+		}
+		to, err := parseConfigVersion(args[2])
+		if err != nil {
+			break
+		}
+		return b.sendConfigDiff(message.Chat, from, to)
+	case "rollback":
+		version, err := parseConfigVersion(args[1])
+		if err != nil {
+			break
+		}
+		return b.rollbackConfig(message, version)
+	}
+	_, err := b.telegram.Send(message.Chat, responseConfigUsage)
+	return err
+}
+
+func (b *Bot) sendConfigHistory(chat *telebot.Chat) error {
+	versions, err := b.configs.store.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list config versions", "err", err)
+		_, err = b.telegram.Send(chat, "I can't list the configuration versions.")
+		return err
+	}
+	if len(versions) == 0 {
+		_, err = b.telegram.Send(chat, "No configuration versions are recorded yet.")
+		return err
+	}
+
+	var out strings.Builder
+	out.WriteString("Configuration versions:")
+	for i := len(versions) - 1; i >= 0 && i >= len(versions)-configHistoryLimit; i-- {
+		v := versions[i]
+		fmt.Fprintf(&out, "\nv%d ", v.Version)
+		switch {
+		case v.Reloaded:
+			out.WriteString("reloaded")
+		case v.AppliedBy == "":
+			out.WriteString("applied on startup")
+		default:
+			fmt.Fprintf(&out, "rollback to v%d by @%s", v.RollbackOf, v.AppliedBy)
+		}
+		fmt.Fprintf(&out, " %s ago", formatDuration(time.Since(v.AppliedAt)))
+		if i == len(versions)-1 {
+			out.WriteString(" (active)")
+		}
+	}
+	_, err = b.telegram.Send(chat, out.String())
+	return err
+}
+
+func (b *Bot) sendConfigDiff(chat *telebot.Chat, from, to int) error {
+	var configs []string
+	for _, version := range []int{from, to} {
+		v, err := b.configs.store.Get(version)
+		if err != nil {
+			if errors.Is(err, ConfigVersionNotFoundErr) {
+				_, err = b.telegram.Send(chat, fmt.Sprintf("Version v%d doesn't exist.", version))
+				return err
+			}
+			level.Warn(b.logger).Log("msg", "failed to get config version", "version", version, "err", err)
+			_, err = b.telegram.Send(chat, "I can't get the configuration versions.")
+			return err
+		}
+		configs = append(configs, v.Config)
+	}
+
+	lines := diffLines(configs[0], configs[1])
+	if len(lines) == 0 {
+		_, err := b.telegram.Send(chat, fmt.Sprintf("v%d and v%d are the same.", from, to))
+		return err
+	}
+	out := fmt.Sprintf("<b>v%d → v%d:</b>\n<pre>%s</pre>", from, to, html.EscapeString(strings.Join(lines, "\n")))
+	_, err := b.telegram.Send(chat, b.truncateMessage(out), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+func (b *Bot) rollbackConfig(message *telebot.Message, version int) error {
+	b.configs.changes.Lock()
+	defer b.configs.changes.Unlock()
+
+	target, err := b.configs.store.Get(version)
+	if err != nil {
+		if errors.Is(err, ConfigVersionNotFoundErr) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Version v%d doesn't exist.", version))
+			return err
+		}
+		level.Warn(b.logger).Log("msg", "failed to get config version", "version", version, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the configuration versions.")
+		return err
+	}
+
+	var active *Bot
+	if target.Config != b.configs.config {
+		active, err = b.configBot(target.Config)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to apply config version", "version", version, "err", err)
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("I can't apply v%d: %s", version, err))
+			return err
+		}
+	}
+
+	versions, err := b.configs.store.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list config versions", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the configuration versions.")
+		return err
+	}
+	v := &ConfigVersion{
+		Version:    versions[len(versions)-1].Version + 1,
+		AppliedAt:  time.Now(),
+		AppliedBy:  message.Sender.Username,
+		RollbackOf: version,
+		Config:     target.Config,
+	}
+	if err := b.configs.store.Put(v); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put config version", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't record the rollback.")
+		return err
+	}
+
+	b.configs.mu.Lock()
+	b.configs.active = active
+	b.configs.mu.Unlock()
+
+	level.Info(b.logger).Log("msg", "configuration rolled back", "version", v.Version, "rollback_of", version, "username", message.Sender.Username)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Rolled back to v%d, the configuration is now v%d.", version, v.Version))
+	return err
+}
+
+// ReloadConfig reads the configuration again and applies it as a new version if it differs from the active one.
+func (b *Bot) ReloadConfig() (ReloadResult, error) {
+	if b.configs == nil || b.reload == nil {
+		return ReloadResult{}, errors.New("reloading the configuration isn't enabled")
+	}
+	b.configs.changes.Lock()
+	defer b.configs.changes.Unlock()
+
+	config, err := b.reload()
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	versions, err := b.configs.store.List()
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to list config versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return ReloadResult{}, errors.New("the bot didn't record its configuration yet")
+	}
+	latest := versions[len(versions)-1]
+	if latest.Config == config {
+		return ReloadResult{Version: latest.Version}, nil
+	}
+
+	var active *Bot
+	if config != b.configs.config {
+		active, err = b.configBot(config)
+		if err != nil {
+			return ReloadResult{}, fmt.Errorf("failed to apply configuration: %w", err)
+		}
+	}
+	v := &ConfigVersion{Version: latest.Version + 1, AppliedAt: time.Now(), Reloaded: true, Config: config}
+	if err := b.configs.store.Put(v); err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to put config version: %w", err)
+	}
+
+	b.configs.mu.Lock()
+	b.configs.active = active
+	b.configs.mu.Unlock()
+
+	level.Info(b.logger).Log("msg", "configuration reloaded", "version", v.Version)
+	return ReloadResult{Version: v.Version, Changed: true}, nil
+}
+
+// HandleReload returns a HandlerFunc that reloads the configuration of a tenant's bot,
+// e.g. POST /-/reload?tenant=telegram.
+func HandleReload(logger log.Logger, bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		bot, tenant, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if bot.configs == nil || bot.reload == nil {
+			apiError(w, http.StatusConflict, "reloading the configuration isn't enabled")
+			return
+		}
+
+		result, err := bot.ReloadConfig()
+		if err != nil {
+			level.Warn(logger).Log("msg", "failed to reload configuration", "tenant", tenant, "err", err)
+			apiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// diffLines returns the lines removed from a prefixed with - and the ones added in b prefixed with +.
+func diffLines(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i++
+			j++
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+x[i])
+			i++
+		default:
+			lines = append(lines, "+ "+y[j])
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,205 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// correlationCapacity is the number of correlated messages whose alert list is kept for the expand button.
+const correlationCapacity = 1000
+
+// correlatedButton is the inline button that expands a correlated message with the list of its alerts.
+var correlatedButton = telebot.InlineButton{Unique: "correlated", Text: "Show alerts"}
+
+// correlator holds back firing messages sharing the correlation labels to send them as one message.
+type correlator struct {
+	window time.Duration
+	labels []string
+
+	mu sync.Mutex
+	// pending messages by chat and correlation label values.
+	pending map[string]*correlation
+	// expanded texts of sent correlated messages by ID, for the expand button.
+	next     int
+	order    []int
+	expanded map[int]string
+}
+
+// correlation are the messages of a chat received within the window that share the correlation labels.
+type correlation struct {
+	chatID   int64
+	values   []string
+	messages []webhook.Message
+}
+
+// WithCorrelation holds back firing alerts that share all the labels for the window.
+// If several messages with the same values of these labels arrive within the window,
+// e.g. the alerts of a failing node, they are sent as a single message that lists the alerts on demand.
+func WithCorrelation(window time.Duration, labels []string) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 {
+			return fmt.Errorf("correlation window has to be positive")
+		}
+		if len(labels) == 0 {
+			return fmt.Errorf("correlation needs at least one label")
+		}
+		b.correlator = &correlator{
+			window:   window,
+			labels:   labels,
+			pending:  map[string]*correlation{},
+			expanded: map[int]string{},
+		}
+		return nil
+	}
+}
+
+// correlate holds back the message if it's firing and its alerts share all correlation labels.
+// It returns whether the message was held back and will be sent once the window is over.
+func (b *Bot) correlate(chatID int64, m webhook.Message) bool {
+	if m.Status != string(model.AlertFiring) {
+		return false
+	}
+	values := make([]string, 0, len(b.correlator.labels))
+	for _, name := range b.correlator.labels {
+		value, ok := m.CommonLabels[name]
+		if !ok {
+			return false
+		}
+		values = append(values, value)
+	}
+	key := fmt.Sprintf("%d/%s", chatID, strings.Join(values, "/"))
+
+	b.correlator.mu.Lock()
+	defer b.correlator.mu.Unlock()
+
+	if c, ok := b.correlator.pending[key]; ok {
+		c.messages = append(c.messages, m)
+		return true
+	}
+	b.correlator.pending[key] = &correlation{chatID: chatID, values: values, messages: []webhook.Message{m}}
+	// Held back messages are in progress until they're sent, see WaitIdle.
+	b.active.begin()
+	time.AfterFunc(b.correlator.window, func() {
+		defer b.active.end()
+
+		b.correlator.mu.Lock()
+		c := b.correlator.pending[key]
+		delete(b.correlator.pending, key)
+		b.correlator.mu.Unlock()
+
+		b.sendCorrelation(c)
+	})
+	return true
+}
+
+// sendCorrelation sends a single message as it is, several messages are merged into one.
+func (b *Bot) sendCorrelation(c *correlation) {
+	if len(c.messages) == 1 {
+		if _, err := b.sendMessage(c.chatID, c.messages[0]); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send held back message", "chat_id", c.chatID, "err", err)
+		}
+		return
+	}
+
+	chat, err := b.chats.Get(telebot.ChatID(c.chatID))
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "chat is not subscribed for alerts", "chat_id", c.chatID, "err", err)
+		return
+	}
+
+	var names []string
+	for _, m := range c.messages {
+		for _, a := range b.filterAlerts(chat.ID, m.Alerts) {
+			names = append(names, a.Labels[string(model.AlertNameLabel)])
+		}
+	}
+	sort.Strings(names)
+
+	var list strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&list, "\n🔥 %s", html.EscapeString(name))
+	}
+	text := fmt.Sprintf("🔗 <b>%d related alerts on %s</b>", len(names), html.EscapeString(strings.Join(c.values, ", ")))
+	id := b.correlator.keep(text + "\n" + list.String())
+
+	button := correlatedButton
+	button.Data = strconv.Itoa(id)
+	sent, err := b.telegram.Send(chat, text, &telebot.SendOptions{
+		ParseMode:   telebot.ModeHTML,
+		ReplyMarkup: &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{button}}},
+	})
+
+	status := DeliverySent
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send correlated alerts", "chat_id", chat.ID, "err", err)
+		status = DeliveryFailed
+	} else {
+		level.Info(b.logger).Log("msg", "correlated alerts", "chat_id", chat.ID, "messages", len(c.messages), "alerts", len(names))
+	}
+
+	for _, m := range c.messages {
+		d := &Delivery{GroupKey: m.GroupKey, ChatID: chat.ID, Status: status, Attempts: 1, Alerts: len(m.Alerts), At: time.Now()}
+		if err != nil {
+			d.Error = err.Error()
+		} else {
+			d.MessageID = sent.ID
+			b.trackAlerts(chat.ID, sent.ID, m)
+		}
+		b.completeAlerts(chat.ID, m)
+		b.recordDelivery(d)
+		b.countSendFailure(d, m)
+	}
+}
+
+// keep the expanded text of a correlated message and forget the oldest ones when at capacity.
+func (c *correlator) keep(expanded string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next++
+	c.expanded[c.next] = expanded
+	c.order = append(c.order, c.next)
+	for len(c.order) > correlationCapacity {
+		delete(c.expanded, c.order[0])
+		c.order = c.order[1:]
+	}
+	return c.next
+}
+
+func (b *Bot) handleCorrelated(c *telebot.Callback) {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+
+	if !b.isAuthorized(c.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping callback from forbidden sender",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Data)
+	b.correlator.mu.Lock()
+	expanded, ok := b.correlator.expanded[id]
+	b.correlator.mu.Unlock()
+	if !ok {
+		_, _ = b.telegram.Send(c.Message.Chat, "The alerts of this message are no longer available.")
+		return
+	}
+
+	if _, err := b.telegram.Edit(c.Message, expanded, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to expand correlated alerts", "err", err)
+	}
+}
@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// deadMansSwitch pings an external URL, like a healthchecks.io check, whenever a webhook was processed.
+type deadMansSwitch struct {
+	url    *url.URL
+	client *http.Client
+	// pings has room for one ping, so a burst of webhooks results in at most one ping after the current one.
+	pings chan struct{}
+}
+
+// WithDeadMansSwitch pings the URL with a GET request every time a webhook from Alertmanager was processed.
+// The external service then notices when the bot stops receiving alerts.
+func WithDeadMansSwitch(u *url.URL, timeout time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.deadMans = &deadMansSwitch{
+			url:    u,
+			client: &http.Client{Timeout: timeout},
+			pings:  make(chan struct{}, 1),
+		}
+		return nil
+	}
+}
+
+// pingDeadMansSwitch schedules a ping without waiting for it.
+func (b *Bot) pingDeadMansSwitch() {
+	select {
+	case b.deadMans.pings <- struct{}{}:
+	default:
+	}
+}
+
+// runDeadMansSwitch sends the scheduled pings.
+func (b *Bot) runDeadMansSwitch(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.deadMans.pings:
+			if err := b.deadMans.ping(ctx); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to ping dead man's switch", "err", err)
+				continue
+			}
+			level.Debug(b.logger).Log("msg", "dead man's switch pinged")
+		}
+	}
+}
+
+func (d *deadMansSwitch) ping(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, d.url.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
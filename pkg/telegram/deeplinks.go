@@ -0,0 +1,278 @@
+package telegram
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Actions deep links can pre-fill.
+const (
+	DeepLinkAck     = "ack"
+	DeepLinkSilence = "silence"
+)
+
+const responseDeepLinkInvalid = "This link isn't valid."
+
+// deepLinkPayload matches the start payloads of deep links, which Telegram limits to 64 characters of [A-Za-z0-9_-].
+var deepLinkPayload = regexp.MustCompile(`^(ack|silence)-([0-9a-f]{1,16})-([0-9a-f]{32})$`)
+
+var (
+	// ackLinkButton acknowledges the alert of a deep link.
+	ackLinkButton = telebot.InlineButton{Unique: "ack", Text: "Acknowledge"}
+	// silenceLinkButton silences the alert of a deep link.
+	silenceLinkButton = telebot.InlineButton{Unique: "silence", Text: "Silence"}
+)
+
+// deepLinks signs and verifies the start payloads of deep links.
+type deepLinks struct {
+	secret          []byte
+	silenceDuration time.Duration
+}
+
+// WithDeepLinks lets other tools link to t.me/<bot>?start=<payload> to acknowledge or silence an alert.
+// The payloads are signed with the secret, authorized users opening a link are asked to confirm the action.
+// Silences created via links last for silenceDuration.
+func WithDeepLinks(secret []byte, silenceDuration time.Duration) BotOption {
+	return func(b *Bot) error {
+		if len(secret) == 0 {
+			return errors.New("deep links need a secret")
+		}
+		if silenceDuration <= 0 {
+			return errors.New("silence duration must be positive")
+		}
+		b.deepLinks = &deepLinks{secret: secret, silenceDuration: silenceDuration}
+		return nil
+	}
+}
+
+// SignDeepLink returns the signed start payload of a deep link for the action on the alert with the fingerprint.
+func SignDeepLink(secret []byte, action, fingerprint string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(action + ":" + fingerprint))
+	return fmt.Sprintf("%s-%s-%s", action, fingerprint, hex.EncodeToString(mac.Sum(nil)[:16]))
+}
+
+// verify returns the action and fingerprint of a payload if its signature is valid.
+func (d *deepLinks) verify(payload string) (string, string, bool) {
+	m := deepLinkPayload.FindStringSubmatch(payload)
+	if m == nil {
+		return "", "", false
+	}
+	expected := SignDeepLink(d.secret, m[1], m[2])
+	if !hmac.Equal([]byte(expected), []byte(payload)) {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// DeepLink returns the t.me link for the action on the alert with the fingerprint.
+func (b *Bot) DeepLink(action, fingerprint string) (string, error) {
+	if b.deepLinks == nil {
+		return "", errors.New("deep links aren't enabled")
+	}
+	if action != DeepLinkAck && action != DeepLinkSilence {
+		return "", fmt.Errorf("unknown action %q", action)
+	}
+	payload := SignDeepLink(b.deepLinks.secret, action, fingerprint)
+	if !deepLinkPayload.MatchString(payload) {
+		return "", fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s", b.username, payload), nil
+}
+
+// isDeepLink returns whether the message is the start command sent by opening a deep link.
+func (b *Bot) isDeepLink(m *telebot.Message, command string) bool {
+	return b.deepLinks != nil && command == CommandStart && deepLinkPayload.MatchString(strings.TrimSpace(m.Payload))
+}
+
+// handleDeepLink asks an authorized sender to confirm the action of a deep link.
+func (b *Bot) handleDeepLink(message *telebot.Message) error {
+	action, fingerprint, ok := b.deepLinks.verify(strings.TrimSpace(message.Payload))
+	if !ok {
+		level.Info(b.logger).Log(
+			"msg", "invalid deep link",
+			"username", message.Sender.Username,
+			"user_id", message.Sender.ID,
+		)
+		_, err := b.telegram.Send(message.Chat, responseDeepLinkInvalid)
+		return err
+	}
+
+	alert, err := b.firingAlert(fingerprint)
+	if err != nil {
+		return err
+	}
+	if alert == nil {
+		_, err := b.telegram.Send(message.Chat, fmt.Sprintf("The alert %s isn't firing anymore.", fingerprint))
+		return err
+	}
+
+	button := ackLinkButton
+	question := fmt.Sprintf("Acknowledge %s (%s)?", alert.Name(), fingerprint)
+	if action == DeepLinkSilence {
+		button = silenceLinkButton
+		question = fmt.Sprintf("Silence %s (%s) for %s?", alert.Name(), fingerprint, formatDuration(b.deepLinks.silenceDuration))
+	}
+	button.Data = fingerprint
+
+	_, err = b.telegram.Send(message.Chat, question, &telebot.SendOptions{
+		ReplyMarkup: &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{button}}},
+	})
+	return err
+}
+
+// firingAlert returns the first alert sent to any chat with the fingerprint or nil if there is none.
+func (b *Bot) firingAlert(fingerprint string) (*ChatAlert, error) {
+	if b.alerts == nil {
+		return nil, nil
+	}
+	alerts, err := b.alerts.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts from alert store: %w", err)
+	}
+	for _, a := range alerts {
+		if a.Fingerprint == fingerprint {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// deepLinkCallback responds to the callback and returns whether its sender is authorized.
+func (b *Bot) deepLinkCallback(c *telebot.Callback) bool {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+	if !b.isAuthorized(c.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping callback from forbidden sender",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return false
+	}
+	return true
+}
+
+// handleAckLink acknowledges the alert with the callback's fingerprint in all chats.
+func (b *Bot) handleAckLink(c *telebot.Callback) {
+	if !b.deepLinkCallback(c) {
+		return
+	}
+
+	alerts, err := b.alerts.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts from alert store", "err", err)
+		_, _ = b.telegram.Edit(c.Message, "I can't list the firing alerts.")
+		return
+	}
+
+	name, acked := c.Data, 0
+	for _, a := range alerts {
+		if a.Fingerprint != c.Data || a.Acked() {
+			continue
+		}
+		name = a.Name()
+		a.AckedAt = time.Now()
+		a.AckedBy = c.Sender.Username
+		if err := b.alerts.Put(a); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put alert into alert store", "err", err)
+			continue
+		}
+		b.recordAck(a)
+		acked++
+	}
+
+	if acked == 0 {
+		_, _ = b.telegram.Edit(c.Message, fmt.Sprintf("No unacknowledged alert matches %s.", c.Data))
+		return
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "alerts acknowledged",
+		"alertname", name,
+		"count", acked,
+		"username", c.Sender.Username,
+	)
+	if _, err := b.telegram.Edit(c.Message, fmt.Sprintf("Acknowledged %d alert(s) of %s.", acked, name)); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit deep link confirmation", "err", err)
+	}
+}
+
+// handleSilenceLink silences the alert with the callback's fingerprint by all its labels.
+func (b *Bot) handleSilenceLink(c *telebot.Callback) {
+	if !b.deepLinkCallback(c) {
+		return
+	}
+
+	alert, err := b.firingAlert(c.Data)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get alert", "err", err)
+		_, _ = b.telegram.Edit(c.Message, "I can't list the firing alerts.")
+		return
+	}
+	if alert == nil {
+		_, _ = b.telegram.Edit(c.Message, fmt.Sprintf("The alert %s isn't firing anymore.", c.Data))
+		return
+	}
+
+	now := time.Now()
+	comment := fmt.Sprintf("Silenced by %s via Telegram", senderName(c.Sender))
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	id, err := b.alertmanagerFor(c.Message.Chat.ID).CreateSilence(ctx, alertmanager.EqualMatchers(alert.Labels), now, now.Add(b.deepLinks.silenceDuration), c.Sender.Username, comment)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create silence", "err", err)
+		_, _ = b.telegram.Edit(c.Message, unreachableResponse(err, "I can't create the silence."))
+		return
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "silence created",
+		"id", id,
+		"alertname", alert.Name(),
+		"username", c.Sender.Username,
+	)
+	out := fmt.Sprintf("Silenced %s for %s.", alert.Name(), formatDuration(b.deepLinks.silenceDuration))
+	if _, err := b.telegram.Edit(c.Message, out); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit deep link confirmation", "err", err)
+	}
+}
+
+// HandleDeepLink returns a HandlerFunc that responds with the deep link of a tenant's bot for an action on an alert,
+// e.g. GET /-/deeplink?tenant=telegram&action=silence&fingerprint=a1b2c3d4e5f60718.
+func HandleDeepLink(bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		bot, _, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+
+		link, err := bot.DeepLink(r.URL.Query().Get("action"), r.URL.Query().Get("fingerprint"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"url":%q}`, link)
+	}
+}
@@ -0,0 +1,446 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	// deliveryListLimit is the number of deliveries listed by the delivery command.
+	deliveryListLimit = 10
+	// deliveryMaxRetryAfter is the longest Telegram may ask to wait before a message is retried.
+	deliveryMaxRetryAfter = time.Minute
+)
+
+// DeliveryStatus is the outcome of sending a webhook to a chat.
+type DeliveryStatus string
+
+const (
+	// DeliverySent means that the alerts were sent on the first attempt.
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryRetried means that the alerts were sent after Telegram asked to retry.
+	DeliveryRetried DeliveryStatus = "retried"
+	// DeliveryFailed means that the alerts couldn't be sent.
+	DeliveryFailed DeliveryStatus = "failed"
+	// DeliveryBuffered means that Telegram was unreachable and the alerts are part of the outage summary.
+	DeliveryBuffered DeliveryStatus = "buffered"
+	// DeliveryFiltered means that the bot deliberately didn't send the alerts, e.g. as they are flapping.
+	DeliveryFiltered DeliveryStatus = "filtered"
+)
+
+var deliveryEmoji = map[DeliveryStatus]string{
+	DeliverySent:     "✅",
+	DeliveryRetried:  "🔁",
+	DeliveryFailed:   "❌",
+	DeliveryBuffered: "⏳",
+	DeliveryFiltered: "🔕",
+}
+
+// Delivery is the outcome of sending a webhook to a chat.
+type Delivery struct {
+	GroupKey string         `json:"groupKey"`
+	ChatID   int64          `json:"chatID"`
+	Status   DeliveryStatus `json:"status"`
+	Attempts int            `json:"attempts"`
+	Alerts   int            `json:"alerts"`
+	Error    string         `json:"error,omitempty"`
+	At       time.Time      `json:"at"`
+	// MessageID is the ID of the message the alerts were sent in.
+	MessageID int `json:"messageID,omitempty"`
+	// SentAt is when Telegram accepted the message, At is when sending started.
+	SentAt time.Time `json:"sentAt,omitempty"`
+
+	// rateLimited is whether Telegram still asked to slow down when sending failed.
+	rateLimited bool
+}
+
+// BotDeliveryStore keeps the outcomes of sending webhooks to chats.
+type BotDeliveryStore interface {
+	List() ([]*Delivery, error)
+	Put(*Delivery) error
+	Remove(*Delivery) error
+	Prune(before time.Time) error
+}
+
+// DeliveryStore writes the deliveries to a libkv store backend.
+type DeliveryStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewDeliveryStore stores deliveries in the provided kv backend.
+func NewDeliveryStore(kv store.Store, storeKeyPrefix string) (*DeliveryStore, error) {
+	return &DeliveryStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *DeliveryStore) key(d *Delivery) string {
+	return fmt.Sprintf("%s/%d-%d", s.storeKeyPrefix, d.ChatID, d.At.UnixNano())
+}
+
+// List all deliveries saved in the kv backend.
+func (s *DeliveryStore) List() ([]*Delivery, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var deliveries []*Delivery
+	for _, kv := range kvPairs {
+		var d *Delivery
+		if err := json.Unmarshal(kv.Value, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+// Put a delivery into the kv backend.
+func (s *DeliveryStore) Put(d *Delivery) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(d), b, nil)
+}
+
+// Remove a delivery from the kv backend.
+func (s *DeliveryStore) Remove(d *Delivery) error {
+	err := s.kv.Delete(s.key(d))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Prune removes all deliveries that happened before the given time.
+func (s *DeliveryStore) Prune(before time.Time) error {
+	deliveries, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		if d.At.Before(before) {
+			if err := s.kv.Delete(s.key(d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WithDeliveries keeps track of whether webhooks reached their chats and removes deliveries after the retention.
+// A retention of 0 keeps the deliveries forever.
+func WithDeliveries(deliveries BotDeliveryStore, retention time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.deliveries = deliveries
+		b.deliveryRetention = retention
+		return nil
+	}
+}
+
+func (b *Bot) pruneDeliveries(ctx context.Context) error {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.deliveries.Prune(time.Now().Add(-b.deliveryRetention)); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to prune deliveries", "err", err)
+			}
+		}
+	}
+}
+
+// sendMessage renders the alerts of a webhook message, sends them to a chat and records the delivery.
+func (b *Bot) sendMessage(chatID int64, m webhook.Message) (*Delivery, error) {
+	// Chat groups might still have the ID of a group upgraded to a supergroup.
+	chatID = b.migratedChatID(chatID)
+	d := &Delivery{GroupKey: m.GroupKey, ChatID: chatID, Alerts: len(m.Alerts), At: time.Now()}
+	err := b.deliver(d, m)
+	if err == nil {
+		b.completeAlerts(chatID, m)
+	}
+	b.recordDelivery(d)
+	b.countSendFailure(d, m)
+	if result := sendResult(d); result != "" {
+		b.sendEvents(result)
+	}
+	return d, err
+}
+
+// observeLatency calls the latency event if the delivery's alerts were sent, see WithLatencyEvent.
+func (b *Bot) observeLatency(d *Delivery, m webhook.Message, receivedAt time.Time) {
+	if receivedAt.IsZero() || sendResult(d) != SendResultSent {
+		return
+	}
+	latency := time.Since(receivedAt)
+	seen := map[string]bool{}
+	for _, a := range m.Alerts {
+		severity := a.Labels["severity"]
+		if !seen[severity] {
+			seen[severity] = true
+			b.latencyEvents(severity, latency)
+		}
+	}
+}
+
+// recordDelivery puts the delivery into the store if it has an outcome.
+func (b *Bot) recordDelivery(d *Delivery) {
+	if b.deliveries == nil || d.Status == "" {
+		return
+	}
+	if err := b.deliveries.Put(d); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put delivery", "err", err)
+	}
+}
+
+// deliver sends the alerts to the delivery's chat and sets the delivery's outcome.
+func (b *Bot) deliver(d *Delivery, m webhook.Message) error {
+	chat, err := b.chats.Get(telebot.ChatID(d.ChatID))
+	if err != nil {
+		if errors.Is(err, ChatNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "chat is not subscribed for alerts", "chat_id", d.ChatID, "err", err)
+			d.Status, d.Error = DeliveryFailed, "chat is not subscribed"
+			return nil
+		}
+		return err
+	}
+
+	alerts := b.filterFlapping(chat, b.filterAlerts(chat.ID, b.filterMaintenance(m.Alerts)))
+	if len(alerts) == 0 {
+		d.Status = DeliveryFiltered
+		b.trackAlerts(chat.ID, 0, m)
+		return nil
+	}
+
+	if b.outage.active() {
+		b.bufferOutage(d, chat.ID, m, alerts, nil)
+		b.trackAlerts(chat.ID, 0, m)
+		return nil
+	}
+
+	err = b.sendAlerts(d, chat, m, alerts)
+	if err != nil && b.outage != nil && unreachable(err) {
+		b.bufferOutage(d, chat.ID, m, alerts, err)
+		b.trackAlerts(chat.ID, 0, m)
+		return nil
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send message with alerts", "err", err)
+		d.Status, d.Error = DeliveryFailed, err.Error()
+		return nil
+	}
+
+	b.trackAlerts(chat.ID, d.MessageID, m)
+	b.sendVoice(chat, d.MessageID, alerts)
+	b.sendLocations(chat, d.MessageID, alerts)
+	return nil
+}
+
+// sendAlerts renders the alerts of a webhook message and sends them to a chat.
+// Only the error of sending is returned, as the message wouldn't render on a retry either.
+func (b *Bot) sendAlerts(d *Delivery, chat *telebot.Chat, m webhook.Message, alerts template.Alerts) error {
+	// Chats in the canary's rollout get the alerts rendered with the canary's configuration.
+	r := b.renderer(chat.ID)
+
+	// Overrides match the labels before mentions and hidden labels are removed.
+	matched := alerts
+	alerts, mentions := b.extractMentions(chat, alerts)
+	alerts, markup := b.truncateAnnotations(r.labelFilter.filterAlerts(alerts))
+	markup = b.addTicketButtons(markup, matched)
+
+	data := &template.Data{
+		Receiver:          m.Receiver,
+		Status:            m.Status,
+		Alerts:            alerts,
+		GroupLabels:       r.labelFilter.filter(m.GroupLabels),
+		CommonLabels:      r.labelFilter.filter(m.CommonLabels),
+		CommonAnnotations: m.CommonAnnotations,
+		ExternalURL:       m.ExternalURL,
+	}
+
+	out, disablePreview, err := r.renderAlerts(matched, data)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
+		d.Status, d.Error = DeliveryFailed, err.Error()
+		return nil
+	}
+	out, qrCodes := extractQRCodes(out)
+	if mentions != "" {
+		out = strings.TrimRight(out, "\n") + "\n\n" + mentions
+	}
+
+	sendOpts := telebot.SendOptions{
+		ParseMode:             telebot.ModeHTML,
+		ReplyMarkup:           markup,
+		DisableWebPagePreview: disablePreview || b.previewsOff(chat.ID),
+	}
+
+	if value, ok := m.GroupLabels["silent"]; ok && value == "true" {
+		sendOpts.DisableNotification = true
+	}
+
+	d.Status = DeliverySent
+	for {
+		d.Attempts++
+		var sent *telebot.Message
+		sent, err = b.telegram.Send(chat, b.truncateMessage(out), &sendOpts)
+		if err == nil {
+			d.MessageID = sent.ID
+			d.SentAt = time.Now()
+		}
+
+		// Telegram asks to slow down if too many messages are sent, try once more after waiting.
+		var flood telebot.FloodError
+		d.rateLimited = errors.As(err, &flood)
+		if d.rateLimited && d.Attempts == 1 {
+			if wait := time.Duration(flood.RetryAfter) * time.Second; wait <= deliveryMaxRetryAfter {
+				d.Status = DeliveryRetried
+				time.Sleep(wait)
+				continue
+			}
+		}
+		if err == nil {
+			b.sendQRCodes(chat, d.MessageID, qrCodes)
+		}
+		return err
+	}
+}
+
+// bufferOutage keeps the alerts for the outage summary instead of sending them.
+func (b *Bot) bufferOutage(d *Delivery, chatID int64, m webhook.Message, alerts template.Alerts, cause error) {
+	d.Status = DeliveryBuffered
+	if cause != nil {
+		d.Error = cause.Error()
+	}
+
+	started, err := b.outage.buffer(chatID, m, alerts, cause, d.At)
+	if started {
+		level.Warn(b.logger).Log("msg", "telegram is unreachable, buffering alerts", "err", cause)
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put outage", "err", err)
+	}
+}
+
+// matchDeliveries returns the latest deliveries whose group key contains the query, newest first.
+func matchDeliveries(deliveries []*Delivery, query string, limit int) []*Delivery {
+	var matched []*Delivery
+	for _, d := range deliveries {
+		if strings.Contains(d.GroupKey, query) {
+			matched = append(matched, d)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].At.After(matched[j].At) })
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched
+}
+
+func (b *Bot) handleDelivery(message *telebot.Message) error {
+	if b.deliveries == nil {
+		_, err := b.telegram.Send(message.Chat, "Tracking deliveries isn't enabled.")
+		return err
+	}
+
+	query := strings.TrimSpace(message.Payload)
+	if query == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandDelivery+" <groupKey>, e.g. "+CommandDelivery+` alertname="Fire"`)
+		return err
+	}
+
+	deliveries, err := b.deliveries.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list deliveries", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the deliveries.")
+		return err
+	}
+
+	matched := matchDeliveries(deliveries, query, deliveryListLimit)
+	if len(matched) == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No deliveries of %s found.", query))
+		return err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "<b>Deliveries of %s:</b>", html.EscapeString(query))
+	for _, d := range matched {
+		writeDelivery(&out, d)
+	}
+
+	_, err = b.telegram.Send(message.Chat, out.String(), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// writeDelivery writes a line with the outcome of the delivery and one with its group key.
+func writeDelivery(out *strings.Builder, d *Delivery) {
+	fmt.Fprintf(out, "\n%s %s to chat %d %s ago, %d alert(s)",
+		deliveryEmoji[d.Status],
+		d.Status,
+		d.ChatID,
+		formatDuration(time.Since(d.At)),
+		d.Alerts,
+	)
+	if d.Error != "" {
+		fmt.Fprintf(out, ": %s", html.EscapeString(d.Error))
+	}
+	fmt.Fprintf(out, "\n    <code>%s</code>", html.EscapeString(d.GroupKey))
+}
+
+// HandleDeliveries returns a HandlerFunc that responds with the deliveries of a tenant's bot
+// whose group key contains the groupKey parameter, e.g. GET /-/deliveries?tenant=telegram&groupKey=Fire.
+func HandleDeliveries(bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		bot, _, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+		if bot.deliveries == nil {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":"tracking deliveries isn't enabled"}`))
+			return
+		}
+
+		deliveries, err := bot.deliveries.List()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+			return
+		}
+
+		matched := matchDeliveries(deliveries, r.URL.Query().Get("groupKey"), 0)
+		if matched == nil {
+			matched = []*Delivery{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(matched)
+	}
+}
@@ -0,0 +1,161 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	// detailsCapacity is the number of alerts whose full annotations are kept for the details button.
+	detailsCapacity = 1000
+	// telegramMessageLimit is the maximum length of a Telegram message.
+	telegramMessageLimit = 4096
+)
+
+// detailsButton is the inline button that sends the full annotations of an alert.
+var detailsButton = telebot.InlineButton{Unique: "details", Text: "Show details"}
+
+// alertDetails keeps the full annotations of alerts that were truncated in messages.
+type alertDetails struct {
+	limit int
+
+	mu      sync.Mutex
+	order   []string
+	details map[string]string
+}
+
+// WithAnnotationLimit truncates annotations longer than limit characters in messages
+// and adds a button to the message that sends the full annotations on demand.
+func WithAnnotationLimit(limit int) BotOption {
+	return func(b *Bot) error {
+		if limit <= 0 {
+			return fmt.Errorf("annotation limit must be positive")
+		}
+		b.details = &alertDetails{limit: limit, details: map[string]string{}}
+		return nil
+	}
+}
+
+// put keeps the details of an alert and forgets the oldest ones when at capacity.
+func (d *alertDetails) put(fingerprint, details string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.details[fingerprint]; !ok {
+		d.order = append(d.order, fingerprint)
+	}
+	d.details[fingerprint] = details
+
+	for len(d.order) > detailsCapacity {
+		delete(d.details, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+func (d *alertDetails) get(fingerprint string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	details, ok := d.details[fingerprint]
+	return details, ok
+}
+
+// truncateAnnotations returns a copy of the alerts with long annotations truncated
+// and a keyboard with a details button for each alert that was truncated.
+func (b *Bot) truncateAnnotations(alerts template.Alerts) (template.Alerts, *telebot.ReplyMarkup) {
+	if b.details == nil {
+		return alerts, nil
+	}
+
+	var buttons [][]telebot.InlineButton
+	truncated := make(template.Alerts, 0, len(alerts))
+	for _, a := range alerts {
+		annotations := make(template.KV, len(a.Annotations))
+		long := false
+		for name, value := range a.Annotations {
+			if utf8.RuneCountInString(value) > b.details.limit {
+				value = string([]rune(value)[:b.details.limit]) + "…"
+				long = true
+			}
+			annotations[name] = value
+		}
+
+		if long {
+			fingerprint := alertFingerprint(a)
+			b.details.put(fingerprint, formatDetails(a))
+
+			button := detailsButton
+			button.Data = fingerprint
+			if len(alerts) > 1 {
+				button.Text = fmt.Sprintf("%s: %s", detailsButton.Text, a.Labels[string(model.AlertNameLabel)])
+			}
+			buttons = append(buttons, []telebot.InlineButton{button})
+		}
+
+		a.Annotations = annotations
+		truncated = append(truncated, a)
+	}
+
+	if len(buttons) == 0 {
+		return truncated, nil
+	}
+	return truncated, &telebot.ReplyMarkup{InlineKeyboard: buttons}
+}
+
+// formatDetails renders the full annotations of an alert.
+func formatDetails(a template.Alert) string {
+	names := make([]string, 0, len(a.Annotations))
+	for name := range a.Annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	out.WriteString(a.Labels[string(model.AlertNameLabel)])
+	for _, name := range names {
+		fmt.Fprintf(&out, "\n\n%s:\n%s", name, a.Annotations[name])
+	}
+	return out.String()
+}
+
+func (b *Bot) handleDetails(c *telebot.Callback) {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+
+	if !b.isAuthorized(c.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping callback from forbidden sender",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return
+	}
+
+	details, ok := b.details.get(c.Data)
+	if !ok {
+		_, _ = b.telegram.Send(c.Message.Chat, "The details of this alert are no longer available.")
+		return
+	}
+
+	var err error
+	if utf8.RuneCountInString(details) > telegramMessageLimit {
+		_, err = b.telegram.Send(c.Message.Chat, &telebot.Document{
+			File:     telebot.FromReader(strings.NewReader(details)),
+			FileName: c.Data + ".txt",
+			MIME:     "text/plain",
+		})
+	} else {
+		_, err = b.telegram.Send(c.Message.Chat, details)
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send alert details", "err", err)
+	}
+}
@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// digest periodically sends the statistics of the alerts and the report of the alerts that couldn't be delivered.
+type digest struct {
+	interval time.Duration
+	// outputs are the names of the outputs the digest is sent to in addition to the admins.
+	outputs []string
+}
+
+// WithDigest sends the admins the statistics of the alerts of the last interval every interval, e.g. daily or weekly,
+// and the dead-letter report of the alerts that couldn't be delivered to their chats, if there were any.
+// Both are also sent to the outputs with the names, e.g. an email output mailing them to a distribution list.
+func WithDigest(interval time.Duration, outputs ...string) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return fmt.Errorf("digest interval has to be positive")
+		}
+		b.digest = &digest{interval: interval, outputs: outputs}
+		return nil
+	}
+}
+
+// digestOutputs returns the outputs the digest is sent to.
+func (b *Bot) digestOutputs() ([]namedOutput, error) {
+	var outputs []namedOutput
+	for _, name := range b.digest.outputs {
+		found := false
+		for _, o := range b.outputs {
+			if o.name == name {
+				outputs = append(outputs, o)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("digest output %s isn't configured", name)
+		}
+	}
+	return outputs, nil
+}
+
+// runDigest sends the digest every interval, each covering the time since the one before or since the bot started.
+func (b *Bot) runDigest(ctx context.Context, since time.Time) error {
+	outputs, err := b.digestOutputs()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(b.digest.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			// With sharding only the replica polling the updates sends the digest.
+			if b.ownsUpdates() {
+				b.sendDigest(ctx, since, now, outputs)
+			}
+			since = now
+		}
+	}
+}
+
+// sendDigest sends the digest of the alerts between since and now.
+func (b *Bot) sendDigest(ctx context.Context, since, now time.Time, outputs []namedOutput) {
+	period := formatDuration(now.Sub(since))
+
+	if b.history != nil {
+		entries, err := b.history.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list alert history for the digest", "err", err)
+		} else {
+			out, ok := renderStats(entries, now, now.Sub(since))
+			if !ok {
+				out = fmt.Sprintf("No alerts in the last %s! 🎉", period)
+			}
+			b.sendReport(ctx, outputs, "Alert digest for the last "+period, out)
+		}
+	}
+
+	if b.deliveries != nil {
+		deliveries, err := b.deliveries.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list deliveries for the dead-letter report", "err", err)
+			return
+		}
+		failed := failedDeliveries(deliveries, since, now)
+		if len(failed) == 0 {
+			return
+		}
+
+		var out strings.Builder
+		fmt.Fprintf(&out, "<b>Alerts that couldn't be delivered in the last %s:</b>", period)
+		for _, d := range failed {
+			writeDelivery(&out, d)
+		}
+		b.sendReport(ctx, outputs, fmt.Sprintf("[UNDELIVERED:%d] Dead-letter report for the last %s", len(failed), period), out.String())
+	}
+}
+
+// failedDeliveries returns the deliveries that failed between since and now, oldest first.
+func failedDeliveries(deliveries []*Delivery, since, now time.Time) []*Delivery {
+	var failed []*Delivery
+	for _, d := range deliveries {
+		if d.Status == DeliveryFailed && !d.At.Before(since) && d.At.Before(now) {
+			failed = append(failed, d)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].At.Before(failed[j].At) })
+	return failed
+}
+
+// sendReport sends the HTML report to the admins and the outputs, for which it's converted to Markdown as well.
+func (b *Bot) sendReport(ctx context.Context, outputs []namedOutput, subject, out string) {
+	out = strings.TrimSpace(out)
+	b.notifyAdmins(b.truncateMessage(out), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+
+	msg := notify.Message{Subject: subject, HTML: out, Markdown: htmlToMarkdown(out)}
+	for _, o := range outputs {
+		if err := o.output.Notify(ctx, msg); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send digest to output", "output", o.name, "subject", subject, "err", err)
+		}
+	}
+}
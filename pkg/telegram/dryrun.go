@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// DryRunTelegram doesn't connect to Telegram and only logs the messages the bot would send,
+// e.g. to load test the bot without hitting Telegram's limits. It doesn't receive any updates.
+type DryRunTelegram struct {
+	logger log.Logger
+	// messages is the ID of the last message.
+	messages int64
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewDryRunTelegram returns a Telegram logging the messages to the logger on debug level.
+func NewDryRunTelegram(logger log.Logger) *DryRunTelegram {
+	return &DryRunTelegram{logger: logger, stop: make(chan struct{})}
+}
+
+func (t *DryRunTelegram) Start() {
+	<-t.stop
+}
+
+func (t *DryRunTelegram) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
+
+func (t *DryRunTelegram) Send(to telebot.Recipient, what interface{}, _ ...interface{}) (*telebot.Message, error) {
+	id := atomic.AddInt64(&t.messages, 1)
+	level.Debug(t.logger).Log("msg", "not sending message in dry run", "chat_id", to.Recipient(), "message_id", id)
+
+	chatID, _ := strconv.ParseInt(to.Recipient(), 10, 64)
+	m := &telebot.Message{ID: int(id), Chat: &telebot.Chat{ID: chatID}}
+	if text, ok := what.(string); ok {
+		m.Text = text
+	}
+	return m, nil
+}
+
+func (t *DryRunTelegram) Edit(msg telebot.Editable, what interface{}, _ ...interface{}) (*telebot.Message, error) {
+	messageID, chatID := msg.MessageSig()
+	level.Debug(t.logger).Log("msg", "not editing message in dry run", "chat_id", chatID, "message_id", messageID)
+
+	id, _ := strconv.Atoi(messageID)
+	m := &telebot.Message{ID: id, Chat: &telebot.Chat{ID: chatID}}
+	if text, ok := what.(string); ok {
+		m.Text = text
+	}
+	return m, nil
+}
+
+func (t *DryRunTelegram) Pin(_ telebot.Editable, _ ...interface{}) error {
+	return nil
+}
+
+func (t *DryRunTelegram) Respond(_ *telebot.Callback, _ ...*telebot.CallbackResponse) error {
+	return nil
+}
+
+func (t *DryRunTelegram) Answer(_ *telebot.Query, _ *telebot.QueryResponse) error {
+	return nil
+}
+
+func (t *DryRunTelegram) Notify(_ telebot.Recipient, _ telebot.ChatAction) error {
+	return nil
+}
+
+func (t *DryRunTelegram) Handle(_ interface{}, _ interface{}) {}
+
+func (t *DryRunTelegram) SetCommands(_ []telebot.Command) error {
+	return nil
+}
+
+func (t *DryRunTelegram) ChatByID(_ string) (*telebot.Chat, error) {
+	return nil, errors.New("chats can't be looked up in dry run")
+}
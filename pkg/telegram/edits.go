@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// editsCapacity is the number of replies to commands kept to be edited when their command is.
+const editsCapacity = 1000
+
+// commandEdits keeps the first reply to recent commands by the chat and ID of the command's message.
+type commandEdits struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	order   []string
+	replies map[string]*telebot.Message
+}
+
+// WithEditedCommands re-runs commands that are edited within the window after they were sent
+// and edits the previous reply of the bot with the new one, instead of ignoring the edit.
+func WithEditedCommands(window time.Duration) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 {
+			return fmt.Errorf("edit window has to be positive")
+		}
+		b.edits = &commandEdits{window: window, replies: map[string]*telebot.Message{}}
+		return nil
+	}
+}
+
+func editKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d/%d", chatID, messageID)
+}
+
+// put keeps the reply to a command and forgets the oldest ones when at capacity.
+func (e *commandEdits) put(key string, reply *telebot.Message) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.replies[key]; !ok {
+		e.order = append(e.order, key)
+	}
+	e.replies[key] = reply
+
+	for len(e.order) > editsCapacity {
+		delete(e.replies, e.order[0])
+		e.order = e.order[1:]
+	}
+}
+
+func (e *commandEdits) get(key string) (*telebot.Message, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	reply, ok := e.replies[key]
+	return reply, ok
+}
+
+// replyTracker is the transport of a single command remembering the command's first reply.
+// If the command is re-run after an edit, the first reply edits the previous one instead of being sent.
+type replyTracker struct {
+	Telebot
+	previous *telebot.Message
+
+	mu    sync.Mutex
+	reply *telebot.Message
+}
+
+func (t *replyTracker) Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.reply != nil {
+		return t.Telebot.Send(to, what, options...)
+	}
+	// Documents can't replace a text message, they are sent instead.
+	if text, ok := what.(string); ok && t.previous != nil && to.Recipient() == t.previous.Chat.Recipient() {
+		m, err := t.Telebot.Edit(t.previous, text, options...)
+		if err == nil {
+			t.reply = t.previous
+		}
+		return m, err
+	}
+	m, err := t.Telebot.Send(to, what, options...)
+	if err == nil {
+		t.reply = m
+	}
+	return m, err
+}
+
+// command returns the handler of a command bound to the bot, remembering the reply if edited commands are handled.
+func (b *Bot) command(handler func(*Bot, *telebot.Message) error) func(*telebot.Message) error {
+	return func(m *telebot.Message) error {
+		if b.edits == nil {
+			return handler(b, m)
+		}
+		return b.runTracked(handler, m, nil)
+	}
+}
+
+// runTracked runs the handler with a copy of the bot whose transport tracks the reply to the command.
+func (b *Bot) runTracked(handler func(*Bot, *telebot.Message) error, m *telebot.Message, previous *telebot.Message) error {
+	t := &replyTracker{Telebot: b.telegram, previous: previous}
+	tracked := *b
+	tracked.telegram = t
+
+	err := handler(&tracked, m)
+	if t.reply != nil {
+		b.edits.put(editKey(m.Chat.ID, m.ID), t.reply)
+	}
+	return err
+}
+
+// handleEdited re-runs edited commands, editing the previous reply if there is one.
+// Commands that didn't exist before the edit, e.g. because of a typo, are replied to as usual.
+func (b *Bot) handleEdited(commands map[string]func(*Bot, *telebot.Message) error) func(*telebot.Message) {
+	return func(m *telebot.Message) {
+		if m.Sender == nil || m.Chat == nil {
+			return
+		}
+		if time.Duration(m.LastEdit-m.Unixtime)*time.Second > b.edits.window {
+			level.Debug(b.logger).Log("msg", "ignoring edit of old message", "chat_id", m.Chat.ID)
+			return
+		}
+
+		fields := strings.SplitN(m.Text, " ", 2)
+		command := strings.Split(fields[0], "@")[0]
+		if alias, ok := b.aliases[command]; ok {
+			command = alias
+		}
+		handler, ok := commands[command]
+		if !ok {
+			return
+		}
+		// telebot only sets the payload of commands in new messages.
+		m.Payload = ""
+		if len(fields) == 2 {
+			m.Payload = strings.TrimSpace(fields[1])
+		}
+
+		previous, _ := b.edits.get(editKey(m.Chat.ID, m.ID))
+		b.middleware(func(m *telebot.Message) error {
+			return b.runTracked(handler, m, previous)
+		})(m)
+	}
+}
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Enricher looks up additional fields of an alert by its labels, e.g. its owner from a CMDB.
+type Enricher interface {
+	Enrich(ctx context.Context, labels map[string]string) (map[string]string, error)
+}
+
+// WithEnrichers adds the fields the enrichers return for the alerts of webhooks to their annotations,
+// so that templates can show them like any other annotation, e.g. {{ .Annotations.owner }}.
+// Annotations of the alerts and of earlier enrichers are never replaced.
+func WithEnrichers(enrichers ...Enricher) BotOption {
+	return func(b *Bot) error {
+		b.enrichers = append(b.enrichers, enrichers...)
+		return nil
+	}
+}
+
+// enrichAlerts returns the message with the fields of the enrichers added to the annotations of its alerts.
+// Alerts are sent without the fields of enrichers that fail, e.g. as they're unreachable.
+func (b *Bot) enrichAlerts(ctx context.Context, m webhook.Message) webhook.Message {
+	alerts := make(template.Alerts, 0, len(m.Alerts))
+	for _, a := range m.Alerts {
+		kv := make(template.KV, len(a.Annotations))
+		for name, value := range a.Annotations {
+			kv[name] = value
+		}
+		for _, e := range b.enrichers {
+			fields, err := e.Enrich(ctx, a.Labels)
+			if err != nil {
+				level.Warn(b.logger).Log("msg", "failed to enrich alert", "alertname", a.Labels["alertname"], "err", err)
+				continue
+			}
+			for name, value := range fields {
+				if _, ok := kv[name]; !ok {
+					kv[name] = value
+				}
+			}
+		}
+		a.Annotations = kv
+		alerts = append(alerts, a)
+	}
+
+	return withAlerts(m, alerts)
+}
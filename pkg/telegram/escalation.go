@@ -0,0 +1,234 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseEscalation = "⏰ %s has been firing for %s with no ack.\n" + CommandAck + " %s"
+
+// trackAlerts updates the state of the alerts that were sent to a chat in the message with the ID, 0 if they weren't sent.
+func (b *Bot) trackAlerts(chatID int64, messageID int, m webhook.Message) {
+	b.recordHistory(chatID, m)
+	b.updateIncident(chatID, m)
+
+	if b.alerts == nil {
+		return
+	}
+
+	for _, a := range m.Alerts {
+		fingerprint := alertFingerprint(a)
+
+		if a.Status == "resolved" {
+			if err := b.alerts.Remove(chatID, fingerprint); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to remove alert from alert store", "err", err)
+			}
+			continue
+		}
+
+		state, err := b.alerts.Get(chatID, fingerprint)
+		if err != nil {
+			state = &ChatAlert{ChatID: chatID, Fingerprint: fingerprint}
+		}
+		state.Receiver = m.Receiver
+		state.Labels = a.Labels
+		state.StartsAt = a.StartsAt
+		if messageID != 0 {
+			state.MessageID = messageID
+		}
+
+		if err := b.alerts.Put(state); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put alert into alert store", "err", err)
+		}
+	}
+}
+
+// runEscalation periodically checks for alerts that are firing for too long without an ack.
+func (b *Bot) runEscalation(ctx context.Context) error {
+	interval := time.Minute
+	if b.escalationAfter < interval {
+		interval = b.escalationAfter
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.escalate(ctx); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to escalate alerts", "err", err)
+			}
+		}
+	}
+}
+
+func (b *Bot) escalate(ctx context.Context) error {
+	alerts, err := b.alerts.List()
+	if err != nil {
+		return err
+	}
+
+	// Alerts that are still firing and not silenced, by receiver.
+	active := map[string]map[string]bool{}
+
+	for _, a := range alerts {
+		if a.Acked() || !a.EscalatedAt.IsZero() || time.Since(a.StartsAt) < b.escalationAfter || !b.ownsChat(a.ChatID) {
+			continue
+		}
+
+		ok, err := b.alertActive(ctx, active, a)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		chatID := a.ChatID
+		if b.escalationChat != 0 {
+			chatID = b.escalationChat
+		}
+
+		message := fmt.Sprintf(responseEscalation, a.Name(), durafmt.Parse(time.Since(a.StartsAt)), a.Name())
+		if _, err := b.telegram.Send(&telebot.Chat{ID: chatID}, message); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send escalation", "chat_id", chatID, "err", err)
+			continue
+		}
+
+		level.Info(b.logger).Log("msg", "escalated alert", "alertname", a.Name(), "chat_id", chatID)
+
+		a.EscalatedAt = time.Now()
+		if err := b.alerts.Put(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertActive returns whether the alert is still firing and not silenced in Alertmanager.
+// The active alerts are listed once per receiver and kept in active. Without Alertmanager all alerts are active.
+func (b *Bot) alertActive(ctx context.Context, active map[string]map[string]bool, a *ChatAlert) (bool, error) {
+	if b.alertmanager == nil {
+		return true, nil
+	}
+	if _, ok := active[a.Receiver]; !ok {
+		ctx, cancel := b.requestContext(ctx)
+		amAlerts, err := b.alertmanager.ListAlerts(ctx, a.Receiver, false)
+		cancel()
+		if err != nil {
+			return false, err
+		}
+		active[a.Receiver] = map[string]bool{}
+		for _, amAlert := range amAlerts {
+			active[a.Receiver][amAlert.Fingerprint().String()] = true
+		}
+	}
+	return active[a.Receiver][a.Fingerprint], nil
+}
+
+func (b *Bot) handleAck(message *telebot.Message) error {
+	if b.alerts == nil {
+		_, err := b.telegram.Send(message.Chat, "Acknowledging alerts isn't enabled.")
+		return err
+	}
+
+	name := strings.TrimSpace(message.Payload)
+	if name == "" && message.ReplyTo != nil {
+		return b.ackReplied(message)
+	}
+	if name == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandAck+" <alertname|fingerprint>")
+		return err
+	}
+
+	alerts, err := b.alerts.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts from alert store", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the firing alerts.")
+		return err
+	}
+
+	acked := 0
+	for _, a := range alerts {
+		// The escalation chat may acknowledge the alerts of every chat.
+		if a.ChatID != message.Chat.ID && message.Chat.ID != b.escalationChat {
+			continue
+		}
+		if a.Acked() || (a.Name() != name && a.Fingerprint != name) {
+			continue
+		}
+
+		a.AckedAt = time.Now()
+		a.AckedBy = message.Sender.Username
+		if err := b.alerts.Put(a); err != nil {
+			return err
+		}
+		b.recordAck(a)
+		acked++
+	}
+
+	if acked == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No unacknowledged alert matches %s.", name))
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "alerts acknowledged",
+		"alertname", name,
+		"count", acked,
+		"username", message.Sender.Username,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Acknowledged %d alert(s) of %s.", acked, name))
+	return err
+}
+
+// ackReplied acknowledges the alerts of the message the command replies to.
+func (b *Bot) ackReplied(message *telebot.Message) error {
+	alerts, err := b.repliedAlerts(message)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get replied alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the firing alerts.")
+		return err
+	}
+
+	var names []string
+	for _, a := range alerts {
+		if a.Acked() {
+			continue
+		}
+		a.AckedAt = time.Now()
+		a.AckedBy = message.Sender.Username
+		if err := b.alerts.Put(a); err != nil {
+			return err
+		}
+		b.recordAck(a)
+		names = append(names, a.Name())
+	}
+
+	if len(names) == 0 {
+		_, err = b.telegram.Send(message.Chat, "The message you replied to has no unacknowledged alerts.")
+		return err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "alerts acknowledged",
+		"alertname", strings.Join(names, ","),
+		"count", len(names),
+		"username", message.Sender.Username,
+	)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Acknowledged %d alert(s) of %s.", len(names), strings.Join(names, ", ")))
+	return err
+}
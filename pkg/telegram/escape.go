@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"html"
+	"strings"
+
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	markdownEscaper   = strings.NewReplacer(`_`, `\_`, `*`, `\*`, "`", "\\`", `[`, `\[`)
+	markdownV2Escaper = strings.NewReplacer(
+		`\`, `\\`, `_`, `\_`, `*`, `\*`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `~`, `\~`, "`", "\\`",
+		`>`, `\>`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `|`, `\|`, `{`, `\{`, `}`, `\}`, `.`, `\.`, `!`, `\!`,
+	)
+)
+
+// escapeText escapes text for the parse mode, so that values containing _, * or < are shown as they are
+// instead of breaking the formatting of the message or injecting markup into it.
+func escapeText(mode telebot.ParseMode, text string) string {
+	switch mode {
+	case telebot.ModeHTML:
+		return html.EscapeString(text)
+	case telebot.ModeMarkdown:
+		return markdownEscaper.Replace(text)
+	case telebot.ModeMarkdownV2:
+		return markdownV2Escaper.Replace(text)
+	}
+	return text
+}
+
+// escapeKV returns a copy of the labels or annotations with their values escaped for the parse mode.
+func escapeKV(mode telebot.ParseMode, kv template.KV) template.KV {
+	if kv == nil {
+		return nil
+	}
+	escaped := make(template.KV, len(kv))
+	for name, value := range kv {
+		escaped[name] = escapeText(mode, value)
+	}
+	return escaped
+}
+
+// escapeData returns a copy of the template data with all values coming from alerts escaped for the parse mode.
+// Templates are executed as text on the escaped data, so that not even safeHtml in a template lets an alert
+// inject markup into the messages of other chats.
+func escapeData(mode telebot.ParseMode, data *template.Data) *template.Data {
+	escaped := &template.Data{
+		Receiver:          escapeText(mode, data.Receiver),
+		Status:            data.Status,
+		Alerts:            make(template.Alerts, 0, len(data.Alerts)),
+		GroupLabels:       escapeKV(mode, data.GroupLabels),
+		CommonLabels:      escapeKV(mode, data.CommonLabels),
+		CommonAnnotations: escapeKV(mode, data.CommonAnnotations),
+		ExternalURL:       escapeText(mode, data.ExternalURL),
+	}
+	for _, a := range data.Alerts {
+		a.Labels = escapeKV(mode, a.Labels)
+		a.Annotations = escapeKV(mode, a.Annotations)
+		a.GeneratorURL = escapeText(mode, a.GeneratorURL)
+		escaped.Alerts = append(escaped.Alerts, a)
+	}
+	return escaped
+}
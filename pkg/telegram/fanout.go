@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// The results of sending a message to a chat, see WithSendEvent.
+const (
+	SendResultSent        = "sent"
+	SendResultRateLimited = "rate_limited"
+	SendResultFailed      = "failed"
+)
+
+// sendResult returns the result of a delivery, or an empty string if nothing was sent, e.g. as the alerts were filtered.
+func sendResult(d *Delivery) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.Status == DeliverySent || d.Status == DeliveryRetried:
+		return SendResultSent
+	case d.Status == DeliveryFailed && d.rateLimited:
+		return SendResultRateLimited
+	case d.Status == DeliveryFailed:
+		return SendResultFailed
+	}
+	return ""
+}
+
+// errorResult returns the result of sending a message with the error.
+func errorResult(err error) string {
+	var flood telebot.FloodError
+	switch {
+	case err == nil:
+		return SendResultSent
+	case errors.As(err, &flood):
+		return SendResultRateLimited
+	}
+	return SendResultFailed
+}
+
+// fanOut sums up the results of sending a message to several chats.
+// A nil fanOut ignores the results, so that single chats don't need one.
+type fanOut struct {
+	chats int
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	results map[string]int
+}
+
+func newFanOut(chats int) *fanOut {
+	f := &fanOut{chats: chats, results: map[string]int{}}
+	f.wg.Add(chats)
+	return f
+}
+
+// done records the result of one of the chats.
+func (f *fanOut) done(result string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.results[result]++
+	f.mu.Unlock()
+	f.wg.Done()
+}
+
+// wait blocks until the results of all chats are recorded.
+func (f *fanOut) wait() map[string]int {
+	f.wg.Wait()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.results
+}
+
+// logFanOut waits for all chats of the fan-out and logs how many of them the message reached.
+func (b *Bot) logFanOut(f *fanOut, msg string, keyvals ...interface{}) {
+	results := f.wait()
+	keyvals = append([]interface{}{"msg", msg}, keyvals...)
+	keyvals = append(keyvals,
+		"chats", f.chats,
+		"sent", results[SendResultSent],
+		"rate_limited", results[SendResultRateLimited],
+		"failed", results[SendResultFailed],
+	)
+	level.Info(b.logger).Log(keyvals...)
+}
+
+// broadcast sends the text to the subscribed chats of chatIDs, to as many at once as there are send workers.
+// It returns the fan-out with the chats' results and the IDs of the chats it failed to send to.
+func (b *Bot) broadcast(logger log.Logger, chatIDs []int64, text string, options *telebot.SendOptions) (*fanOut, []int64) {
+	workers := b.sendWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	f := newFanOut(len(chatIDs))
+	var (
+		mu     sync.Mutex
+		failed = []int64{}
+	)
+	ids := make(chan int64)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range ids {
+				// Only subscribed chats receive broadcasts.
+				chat, err := b.chats.Get(telebot.ChatID(id))
+				if err == nil {
+					_, err = b.telegram.Send(chat, text, options)
+				}
+				if err != nil {
+					level.Warn(logger).Log("msg", "failed to broadcast to chat", "chat_id", id, "err", err)
+					mu.Lock()
+					failed = append(failed, id)
+					mu.Unlock()
+				}
+				result := errorResult(err)
+				b.sendEvents(result)
+				f.done(result)
+			}
+		}()
+	}
+	for _, id := range chatIDs {
+		ids <- id
+	}
+	close(ids)
+	f.wait()
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+	return f, failed
+}
+
+// handleBroadcast sends the text to all subscribed chats, e.g. to announce maintenance.
+func (b *Bot) handleBroadcast(message *telebot.Message) error {
+	text := strings.TrimSpace(message.Payload)
+	if text == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandBroadcast+" <message>")
+		return err
+	}
+
+	chats, err := b.chats.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list chats from chat store", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the subscribed chats.")
+		return err
+	}
+	if len(chats) == 0 {
+		_, err = b.telegram.Send(message.Chat, "Currently no one is subscribed.")
+		return err
+	}
+
+	chatIDs := make([]int64, 0, len(chats))
+	for _, c := range chats {
+		chatIDs = append(chatIDs, c.ID)
+	}
+
+	f, _ := b.broadcast(b.logger, chatIDs, text, &telebot.SendOptions{})
+	b.logFanOut(f, "broadcast sent", "username", message.Sender.Username)
+
+	results := f.wait()
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Broadcast to %d chat(s): %d sent, %d rate limited, %d failed.",
+		len(chatIDs), results[SendResultSent], results[SendResultRateLimited], results[SendResultFailed],
+	))
+	return err
+}
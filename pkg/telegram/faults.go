@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Faults are injected into the requests to Telegram to load test the retries, queues and outage handling, e.g. in staging.
+type Faults struct {
+	// ErrorRate is the share of requests failing as if Telegram was unreachable, from 0 to 1.
+	ErrorRate float64
+	// RateLimitRate is the share of requests Telegram answers with 429 Too Many Requests, from 0 to 1.
+	RateLimitRate float64
+	// RetryAfter is how long the 429s ask to wait before retrying, rounded to seconds.
+	RetryAfter time.Duration
+	// Latency is the most requests are delayed by, each by a random duration up to it.
+	Latency time.Duration
+}
+
+// WithFaults injects the faults into the bot's requests to Telegram.
+func WithFaults(f Faults) BotOption {
+	return func(b *Bot) error {
+		if f.ErrorRate < 0 || f.ErrorRate > 1 {
+			return fmt.Errorf("the error rate %v isn't between 0 and 1", f.ErrorRate)
+		}
+		if f.RateLimitRate < 0 || f.RateLimitRate > 1 {
+			return fmt.Errorf("the rate limit rate %v isn't between 0 and 1", f.RateLimitRate)
+		}
+		if f.RetryAfter < 0 || f.Latency < 0 {
+			return errors.New("the retry after and latency of faults must not be negative")
+		}
+		b.telegram = &faultyTelegram{Telebot: b.telegram, bot: b, faults: f}
+		return nil
+	}
+}
+
+// faultyTelegram delays and fails the requests to Telegram by its faults.
+type faultyTelegram struct {
+	Telebot
+	bot    *Bot
+	faults Faults
+}
+
+// inject delays the request and returns the error it fails with, if any.
+func (t *faultyTelegram) inject(request string) error {
+	if t.faults.Latency > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(t.faults.Latency) + 1)))
+	}
+
+	var err error
+	switch r := rand.Float64(); {
+	case r < t.faults.ErrorRate:
+		err = &url.Error{Op: "Post", URL: "https://api.telegram.org/" + request, Err: errors.New("injected fault: connection refused")}
+	case r < t.faults.ErrorRate+t.faults.RateLimitRate:
+		retryAfter := int(t.faults.RetryAfter.Round(time.Second) / time.Second)
+		err = telebot.FloodError{
+			APIError:   telebot.NewAPIError(http.StatusTooManyRequests, fmt.Sprintf("injected fault: Too Many Requests: retry after %d", retryAfter)),
+			RetryAfter: retryAfter,
+		}
+	default:
+		return nil
+	}
+	level.Debug(t.bot.logger).Log("msg", "injected fault", "request", request, "err", err)
+	return err
+}
+
+func (t *faultyTelegram) Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	if err := t.inject("sendMessage"); err != nil {
+		return nil, err
+	}
+	return t.Telebot.Send(to, what, options...)
+}
+
+func (t *faultyTelegram) Edit(msg telebot.Editable, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	if err := t.inject("editMessageText"); err != nil {
+		return nil, err
+	}
+	return t.Telebot.Edit(msg, what, options...)
+}
+
+func (t *faultyTelegram) Pin(msg telebot.Editable, options ...interface{}) error {
+	if err := t.inject("pinChatMessage"); err != nil {
+		return err
+	}
+	return t.Telebot.Pin(msg, options...)
+}
+
+func (t *faultyTelegram) Respond(c *telebot.Callback, resp ...*telebot.CallbackResponse) error {
+	if err := t.inject("answerCallbackQuery"); err != nil {
+		return err
+	}
+	return t.Telebot.Respond(c, resp...)
+}
+
+func (t *faultyTelegram) Answer(query *telebot.Query, resp *telebot.QueryResponse) error {
+	if err := t.inject("answerInlineQuery"); err != nil {
+		return err
+	}
+	return t.Telebot.Answer(query, resp)
+}
+
+func (t *faultyTelegram) Notify(to telebot.Recipient, action telebot.ChatAction) error {
+	if err := t.inject("sendChatAction"); err != nil {
+		return err
+	}
+	return t.Telebot.Notify(to, action)
+}
+
+func (t *faultyTelegram) SetCommands(cmds []telebot.Command) error {
+	if err := t.inject("setMyCommands"); err != nil {
+		return err
+	}
+	return t.Telebot.SetCommands(cmds)
+}
+
+func (t *faultyTelegram) ChatByID(id string) (*telebot.Chat, error) {
+	if err := t.inject("getChat"); err != nil {
+		return nil, err
+	}
+	return t.Telebot.ChatByID(id)
+}
@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// FilterNotFoundErr returned by the store if a chat has no filter.
+var FilterNotFoundErr = errors.New("filter not found in store")
+
+// ChatFilter limits the alerts sent to a chat to the ones whose labels match all matchers.
+type ChatFilter struct {
+	ChatID int64 `json:"chatID"`
+	// Matchers use the syntax of amtool, e.g. team=db or severity=~"critical|page".
+	Matchers []string `json:"matchers"`
+}
+
+// Validate returns an error if one of the matchers can't be parsed.
+func (f *ChatFilter) Validate() error {
+	for _, m := range f.Matchers {
+		if _, err := alertmanager.ParseMatcher(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches returns whether the labels match all matchers of the filter.
+func (f *ChatFilter) matches(labels template.KV) bool {
+	for _, m := range f.Matchers {
+		matcher, err := alertmanager.ParseMatcher(m)
+		if err != nil || !matcher.Matches(labels[matcher.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// BotFilterStore keeps the filters of chats.
+type BotFilterStore interface {
+	List() ([]*ChatFilter, error)
+	Get(chatID int64) (*ChatFilter, error)
+	Put(*ChatFilter) error
+	Remove(chatID int64) error
+}
+
+// FilterStore writes the chat filters to a libkv store backend.
+type FilterStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewFilterStore stores chat filters in the provided kv backend.
+func NewFilterStore(kv store.Store, storeKeyPrefix string) (*FilterStore, error) {
+	return &FilterStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all filters saved in the kv backend.
+func (s *FilterStore) List() ([]*ChatFilter, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var filters []*ChatFilter
+	for _, kv := range kvPairs {
+		var f *ChatFilter
+		if err := json.Unmarshal(kv.Value, &f); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// Get the filter of a chat.
+func (s *FilterStore) Get(chatID int64) (*ChatFilter, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, FilterNotFoundErr
+		}
+		return nil, err
+	}
+	var f *ChatFilter
+	err = json.Unmarshal(kv.Value, &f)
+	return f, err
+}
+
+// Put a chat's filter into the kv backend, replacing any previous one.
+func (s *FilterStore) Put(f *ChatFilter) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, f.ChatID), b, nil)
+}
+
+// Remove a chat's filter from the kv backend.
+func (s *FilterStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithFilters only sends the alerts matching a chat's filter to the chat.
+// Chats without a filter receive all alerts.
+func WithFilters(filters BotFilterStore) BotOption {
+	return func(b *Bot) error {
+		b.filters = filters
+		return nil
+	}
+}
+
+// filterAlerts returns the alerts that match the chat's filter.
+func (b *Bot) filterAlerts(chatID int64, alerts template.Alerts) template.Alerts {
+	if b.filters == nil {
+		return alerts
+	}
+	f, err := b.filters.Get(chatID)
+	if err != nil {
+		if !errors.Is(err, FilterNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get chat filter", "chat_id", chatID, "err", err)
+		}
+		return alerts
+	}
+
+	matched := make(template.Alerts, 0, len(alerts))
+	for _, a := range alerts {
+		if f.matches(a.Labels) {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,159 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// findLimit is the number of alerts /find lists at most.
+const findLimit = 20
+
+// parseFindQuery returns a case-insensitive matcher for the query,
+// queries in slashes like /node-[0-9]+/ are regular expressions, all others substrings.
+func parseFindQuery(query string) (func(string) bool, error) {
+	if len(query) > 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		re, err := regexp.Compile("(?i)" + query[1:len(query)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	query = strings.ToLower(query)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), query)
+	}, nil
+}
+
+// findText returns the text /find searches, a line of name=value for every label and annotation.
+func findText(labels, annotations model.LabelSet) string {
+	var text strings.Builder
+	for _, ls := range []model.LabelSet{labels, annotations} {
+		for name, value := range ls {
+			fmt.Fprintf(&text, "%s=%s\n", name, value)
+		}
+	}
+	return text.String()
+}
+
+func (b *Bot) handleFind(message *telebot.Message) error {
+	query := strings.TrimSpace(message.Payload)
+	if query == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandFind+" <text|/regexp/>, e.g. "+CommandFind+" db or "+CommandFind+" /node-[0-9]+/")
+		return err
+	}
+	match, err := parseFindQuery(query)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The regular expression is invalid: %v", err))
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	alerts, err := b.alertmanagerFor(message.Chat.ID).ListAlertStatuses(ctx, "", true)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to list alerts... %v", err)))
+		return err
+	}
+
+	now := time.Now()
+	listed := map[string]bool{}
+	var found []string
+	for _, a := range alerts {
+		if !match(findText(a.Labels, a.Annotations)) {
+			continue
+		}
+		listed[a.Fingerprint().String()] = true
+		found = append(found, formatFoundAlert(a, now))
+	}
+
+	if b.history != nil {
+		entries, err := b.history.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list alert history", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't list the alert history.")
+			return err
+		}
+
+		history := mergeHistory(entries)
+		sort.Slice(history, func(i, j int) bool {
+			return history[i].StartsAt.After(history[j].StartsAt)
+		})
+		for _, e := range history {
+			// Alerts that are still firing were found in Alertmanager already.
+			if !e.Resolved() && listed[e.Fingerprint] {
+				continue
+			}
+			labels := make(model.LabelSet, len(e.Labels))
+			for name, value := range e.Labels {
+				labels[model.LabelName(name)] = model.LabelValue(value)
+			}
+			if !match(findText(labels, nil)) {
+				continue
+			}
+			found = append(found, formatFoundEntry(e, labels, now))
+		}
+	}
+
+	if len(found) == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No alerts match %s.", query))
+		return err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "<b>Alerts matching %s:</b>", html.EscapeString(query))
+	for i, line := range found {
+		if i == findLimit {
+			fmt.Fprintf(&out, "\n… and %d more.", len(found)-findLimit)
+			break
+		}
+		out.WriteString("\n" + line)
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(out.String()), &telebot.SendOptions{
+		ParseMode:             telebot.ModeHTML,
+		DisableWebPagePreview: true,
+	})
+	return err
+}
+
+// formatFoundAlert formats an alert of Alertmanager with its status and a link to its source.
+func formatFoundAlert(a alertmanager.Alert, now time.Time) string {
+	status := fmt.Sprintf("🔥 <b>%s</b> (%s) firing for %s",
+		html.EscapeString(string(a.Labels[model.AlertNameLabel])),
+		html.EscapeString(formatLabels(a.Labels)),
+		formatDuration(now.Sub(a.StartsAt)),
+	)
+	switch {
+	case len(a.SilencedBy) > 0:
+		status = strings.Replace(status, "🔥", "🔕", 1) + ", silenced"
+	case len(a.InhibitedBy) > 0:
+		status = strings.Replace(status, "🔥", "🔇", 1) + ", inhibited"
+	}
+	if a.GeneratorURL != "" {
+		status += fmt.Sprintf(`, <a href="%s">source</a>`, html.EscapeString(a.GeneratorURL))
+	}
+	return status
+}
+
+// formatFoundEntry formats an alert of the history with its status.
+func formatFoundEntry(e *HistoryEntry, labels model.LabelSet, now time.Time) string {
+	name := html.EscapeString(e.Name())
+	ls := html.EscapeString(formatLabels(labels))
+	if !e.Resolved() {
+		return fmt.Sprintf("🔥 <b>%s</b> (%s) firing for %s", name, ls, formatDuration(now.Sub(e.StartsAt)))
+	}
+	return fmt.Sprintf("✅ <b>%s</b> (%s) resolved %s ago after %s",
+		name, ls, formatDuration(now.Sub(e.EndsAt)), formatDuration(e.EndsAt.Sub(e.StartsAt)),
+	)
+}
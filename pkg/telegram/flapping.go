@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseFlapping = "🔁 <b>%s</b> is flapping (%d transitions in %s), currently %s."
+
+// flapState tracks the status transitions of one alert in one chat.
+type flapState struct {
+	status      string
+	transitions []time.Time
+	message     *telebot.Message
+	text        string
+}
+
+// flapDetector detects alerts that fire and resolve repeatedly within a window.
+type flapDetector struct {
+	window     time.Duration
+	threshold  int
+	thresholds map[string]int
+
+	mu     sync.Mutex
+	states map[string]*flapState
+}
+
+// WithFlapping summarizes alerts with at least threshold status transitions within window
+// into a single message that is updated in place. Thresholds can be overridden per alertname.
+func WithFlapping(window time.Duration, threshold int, thresholds map[string]int) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 || threshold <= 1 {
+			return fmt.Errorf("flapping needs a positive window and a threshold of at least 2 transitions")
+		}
+		b.flapping = &flapDetector{
+			window:     window,
+			threshold:  threshold,
+			thresholds: thresholds,
+			states:     map[string]*flapState{},
+		}
+		return nil
+	}
+}
+
+// observe records the alert's status and returns its state if the alert is flapping.
+// The states of alerts without a transition within the window are forgotten, as those might never come back.
+func (d *flapDetector) observe(chatID int64, a template.Alert, now time.Time) *flapState {
+	for k, s := range d.states {
+		if n := len(s.transitions); n == 0 || now.Sub(s.transitions[n-1]) > d.window {
+			delete(d.states, k)
+		}
+	}
+
+	key := fmt.Sprintf("%d-%s", chatID, alertFingerprint(a))
+
+	state, ok := d.states[key]
+	if !ok {
+		state = &flapState{}
+		d.states[key] = state
+	}
+
+	if state.status != a.Status {
+		state.status = a.Status
+		state.transitions = append(state.transitions, now)
+	}
+
+	for len(state.transitions) > 0 && now.Sub(state.transitions[0]) > d.window {
+		state.transitions = state.transitions[1:]
+	}
+
+	if len(state.transitions) == 0 {
+		delete(d.states, key)
+		return nil
+	}
+
+	threshold := d.threshold
+	if t, ok := d.thresholds[a.Labels[string(model.AlertNameLabel)]]; ok {
+		threshold = t
+	}
+	if len(state.transitions) < threshold {
+		state.message = nil
+		state.text = ""
+		return nil
+	}
+
+	return state
+}
+
+// filterFlapping sends or updates the summaries of flapping alerts
+// and returns the alerts that should be sent as usual.
+func (b *Bot) filterFlapping(chat *telebot.Chat, alerts template.Alerts) template.Alerts {
+	if b.flapping == nil {
+		return alerts
+	}
+
+	b.flapping.mu.Lock()
+	defer b.flapping.mu.Unlock()
+
+	var remaining template.Alerts
+	for _, a := range alerts {
+		state := b.flapping.observe(chat.ID, a, time.Now())
+		if state == nil {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		text := fmt.Sprintf(responseFlapping,
+			html.EscapeString(a.Labels[string(model.AlertNameLabel)]),
+			len(state.transitions),
+			durafmt.Parse(b.flapping.window),
+			state.status,
+		)
+		if text == state.text {
+			continue
+		}
+
+		var err error
+		if state.message != nil {
+			_, err = b.telegram.Edit(state.message, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		} else {
+			state.message, err = b.telegram.Send(chat, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send flapping summary", "chat_id", chat.ID, "err", err)
+			continue
+		}
+		state.text = text
+	}
+
+	return remaining
+}
@@ -0,0 +1,27 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlapDetectorPrunesStates(t *testing.T) {
+	d := &flapDetector{window: 30 * time.Minute, threshold: 3, states: map[string]*flapState{}}
+	now := time.Now()
+
+	gone := template.Alert{Status: "firing", Labels: template.KV{"alertname": "gone"}}
+	require.Nil(t, d.observe(1, gone, now))
+	require.Nil(t, d.observe(2, gone, now.Add(10*time.Minute)))
+	require.Len(t, d.states, 2)
+
+	// The alert never comes back, its states are forgotten once another alert is seen after the window.
+	other := template.Alert{Status: "firing", Labels: template.KV{"alertname": "other"}}
+	require.Nil(t, d.observe(1, other, now.Add(35*time.Minute)))
+	require.Len(t, d.states, 2)
+	require.Nil(t, d.observe(1, other, now.Add(45*time.Minute)))
+	require.Len(t, d.states, 1)
+	require.Contains(t, d.states, "1-"+alertFingerprint(other))
+}
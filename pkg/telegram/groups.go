@@ -0,0 +1,300 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseGroupUsage = "Usage:\n" +
+	CommandGroup + " - List all chat groups.\n" +
+	CommandGroup + " add <group> [chat] - Add a chat to a group, defaults to this chat.\n" +
+	CommandGroup + " remove <group> [chat] - Remove a chat from a group, defaults to this chat."
+
+// ChatGroupNotFoundErr returned by the store if a chat group isn't found.
+var ChatGroupNotFoundErr = errors.New("chat group not found in store")
+
+var groupName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ChatGroup is a named group of chats that alerts can be sent to.
+type ChatGroup struct {
+	Name    string  `json:"name"`
+	ChatIDs []int64 `json:"chatIDs"`
+}
+
+// has returns whether the chat is part of the group.
+func (g *ChatGroup) has(chatID int64) bool {
+	for _, id := range g.ChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// BotChatGroupStore keeps the chat groups managed with the group command.
+type BotChatGroupStore interface {
+	List() ([]*ChatGroup, error)
+	Get(name string) (*ChatGroup, error)
+	Put(*ChatGroup) error
+	Remove(name string) error
+}
+
+// ChatGroupStore writes the chat groups to a libkv store backend.
+type ChatGroupStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewChatGroupStore stores chat groups in the provided kv backend.
+func NewChatGroupStore(kv store.Store, storeKeyPrefix string) (*ChatGroupStore, error) {
+	return &ChatGroupStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all chat groups saved in the kv backend.
+func (s *ChatGroupStore) List() ([]*ChatGroup, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var groups []*ChatGroup
+	for _, kv := range kvPairs {
+		var g *ChatGroup
+		if err := json.Unmarshal(kv.Value, &g); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// Get a chat group by its name.
+func (s *ChatGroupStore) Get(name string) (*ChatGroup, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%s", s.storeKeyPrefix, name))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, ChatGroupNotFoundErr
+		}
+		return nil, err
+	}
+	var g *ChatGroup
+	err = json.Unmarshal(kv.Value, &g)
+	return g, err
+}
+
+// Put a chat group into the kv backend.
+func (s *ChatGroupStore) Put(g *ChatGroup) error {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, g.Name), b, nil)
+}
+
+// Remove a chat group from the kv backend.
+func (s *ChatGroupStore) Remove(name string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, name))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithChatGroups sets the named groups of chats that webhooks sent to /webhooks/<tenant>/<group> are sent to.
+func WithChatGroups(groups map[string][]int64) BotOption {
+	return func(b *Bot) error {
+		b.groups = groups
+		return nil
+	}
+}
+
+// WithChatGroupStore lets admins manage chat groups with the group command in addition to the configured ones.
+func WithChatGroupStore(groups BotChatGroupStore) BotOption {
+	return func(b *Bot) error {
+		b.groupStore = groups
+		return nil
+	}
+}
+
+// groupChats returns the IDs of the chats in a configured or stored group.
+func (b *Bot) groupChats(name string) ([]int64, error) {
+	chatIDs, configured := b.groups[name]
+
+	if b.groupStore != nil {
+		g, err := b.groupStore.Get(name)
+		if err != nil && !errors.Is(err, ChatGroupNotFoundErr) {
+			return nil, err
+		}
+		if err == nil {
+			group := &ChatGroup{ChatIDs: append([]int64(nil), chatIDs...)}
+			for _, id := range g.ChatIDs {
+				if !group.has(id) {
+					group.ChatIDs = append(group.ChatIDs, id)
+				}
+			}
+			return group.ChatIDs, nil
+		}
+	}
+
+	if !configured {
+		return nil, ChatGroupNotFoundErr
+	}
+	return chatIDs, nil
+}
+
+// listGroups returns the configured and stored groups merged by name and sorted.
+func (b *Bot) listGroups() ([]*ChatGroup, error) {
+	names := map[string]bool{}
+	for name := range b.groups {
+		names[name] = true
+	}
+	if b.groupStore != nil {
+		stored, err := b.groupStore.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range stored {
+			names[g.Name] = true
+		}
+	}
+
+	groups := make([]*ChatGroup, 0, len(names))
+	for name := range names {
+		chatIDs, err := b.groupChats(name)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, &ChatGroup{Name: name, ChatIDs: chatIDs})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups, nil
+}
+
+func (b *Bot) handleGroup(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) == 0 || args[0] == "list" {
+		return b.handleGroupList(message)
+	}
+
+	if b.groupStore == nil {
+		_, err := b.telegram.Send(message.Chat, "Managing chat groups isn't enabled.")
+		return err
+	}
+
+	if (args[0] != "add" && args[0] != "remove") || len(args) < 2 || len(args) > 3 || !groupName.MatchString(args[1]) {
+		_, err := b.telegram.Send(message.Chat, responseGroupUsage)
+		return err
+	}
+
+	chatID := message.Chat.ID
+	if len(args) == 3 {
+		id, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			_, err = b.telegram.Send(message.Chat, responseGroupUsage)
+			return err
+		}
+		chatID = id
+	}
+
+	group, err := b.groupStore.Get(args[1])
+	if err != nil {
+		if !errors.Is(err, ChatGroupNotFoundErr) {
+			return err
+		}
+		group = &ChatGroup{Name: args[1]}
+	}
+
+	if args[0] == "add" {
+		if _, err := b.chats.Get(telebot.ChatID(chatID)); err != nil {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Chat %d isn't subscribed, send %s in that chat first.", chatID, CommandStart))
+			return err
+		}
+		if group.has(chatID) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Chat %d already is in group %s.", chatID, group.Name))
+			return err
+		}
+		group.ChatIDs = append(group.ChatIDs, chatID)
+		if err := b.groupStore.Put(group); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put chat group", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't save the chat group.")
+			return err
+		}
+
+		level.Info(b.logger).Log("msg", "chat added to group", "group", group.Name, "chat_id", chatID, "username", message.Sender.Username)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Added chat %d to group %s.", chatID, group.Name))
+		return err
+	}
+
+	if !group.has(chatID) {
+		configured := &ChatGroup{ChatIDs: b.groups[group.Name]}
+		if configured.has(chatID) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Chat %d is in group %s by the config file, remove it there.", chatID, group.Name))
+			return err
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Chat %d isn't in group %s.", chatID, group.Name))
+		return err
+	}
+	chatIDs := group.ChatIDs[:0]
+	for _, id := range group.ChatIDs {
+		if id != chatID {
+			chatIDs = append(chatIDs, id)
+		}
+	}
+	group.ChatIDs = chatIDs
+
+	if len(group.ChatIDs) == 0 {
+		err = b.groupStore.Remove(group.Name)
+	} else {
+		err = b.groupStore.Put(group)
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put chat group", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't save the chat group.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "chat removed from group", "group", group.Name, "chat_id", chatID, "username", message.Sender.Username)
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Removed chat %d from group %s.", chatID, group.Name))
+	return err
+}
+
+func (b *Bot) handleGroupList(message *telebot.Message) error {
+	groups, err := b.listGroups()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list chat groups", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the chat groups.")
+		return err
+	}
+
+	if len(groups) == 0 {
+		_, err = b.telegram.Send(message.Chat, "There are no chat groups yet.\n"+responseGroupUsage)
+		return err
+	}
+
+	var out strings.Builder
+	out.WriteString("Chat groups:")
+	for _, g := range groups {
+		ids := make([]string, 0, len(g.ChatIDs))
+		for _, id := range g.ChatIDs {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		fmt.Fprintf(&out, "\n%s: %s", g.Name, strings.Join(ids, ", "))
+	}
+
+	_, err = b.telegram.Send(message.Chat, out.String())
+	return err
+}
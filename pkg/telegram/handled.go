@@ -0,0 +1,175 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// HandledCommandNotFoundErr returned by the store if the bot didn't handle the command message.
+var HandledCommandNotFoundErr = errors.New("handled command not found in store")
+
+// HandledCommand records a command message the bot handled, so that it isn't handled again
+// if Telegram sends it twice, e.g. because the bot crashed before it stored the offset of the update.
+type HandledCommand struct {
+	ChatID    int64     `json:"chatID"`
+	MessageID int       `json:"messageID"`
+	Command   string    `json:"command"`
+	At        time.Time `json:"at"`
+}
+
+// BotHandledCommandStore keeps the records of handled command messages.
+type BotHandledCommandStore interface {
+	List() ([]*HandledCommand, error)
+	Get(chatID int64, messageID int) (*HandledCommand, error)
+	Put(*HandledCommand) error
+	Remove(*HandledCommand) error
+}
+
+// HandledCommandStore writes the handled command messages to a libkv store backend.
+type HandledCommandStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewHandledCommandStore stores handled command messages in the provided kv backend.
+func NewHandledCommandStore(kv store.Store, storeKeyPrefix string) (*HandledCommandStore, error) {
+	return &HandledCommandStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *HandledCommandStore) key(chatID int64, messageID int) string {
+	return fmt.Sprintf("%s/%d-%d", s.storeKeyPrefix, chatID, messageID)
+}
+
+// List all handled command messages saved in the kv backend.
+func (s *HandledCommandStore) List() ([]*HandledCommand, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var handled []*HandledCommand
+	for _, kv := range kvPairs {
+		var h *HandledCommand
+		if err := json.Unmarshal(kv.Value, &h); err != nil {
+			return nil, err
+		}
+		handled = append(handled, h)
+	}
+
+	return handled, nil
+}
+
+// Get the record of a command message of a chat.
+func (s *HandledCommandStore) Get(chatID int64, messageID int) (*HandledCommand, error) {
+	kv, err := s.kv.Get(s.key(chatID, messageID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, HandledCommandNotFoundErr
+		}
+		return nil, err
+	}
+	var h *HandledCommand
+	err = json.Unmarshal(kv.Value, &h)
+	return h, err
+}
+
+// Put a handled command message into the kv backend.
+func (s *HandledCommandStore) Put(h *HandledCommand) error {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(h.ChatID, h.MessageID), b, nil)
+}
+
+// Remove a handled command message from the kv backend.
+func (s *HandledCommandStore) Remove(h *HandledCommand) error {
+	err := s.kv.Delete(s.key(h.ChatID, h.MessageID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+type handledCommands struct {
+	store  BotHandledCommandStore
+	window time.Duration
+}
+
+// WithHandledCommands handles every command message of a chat only once within the window,
+// so that commands with side effects like /start or /silence don't run twice if Telegram sends them again after a restart.
+// Commands are recorded before they're handled, a command interrupted by a crash isn't run again.
+func WithHandledCommands(s BotHandledCommandStore, window time.Duration) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 {
+			return errors.New("the window of handled commands must be positive")
+		}
+		b.handled = &handledCommands{store: s, window: window}
+		return nil
+	}
+}
+
+// claimCommand records the command message as handled and returns false if it was handled within the window already.
+func (b *Bot) claimCommand(m *telebot.Message, command string) bool {
+	if b.handled == nil {
+		return true
+	}
+
+	h, err := b.handled.store.Get(m.Chat.ID, m.ID)
+	if err == nil && time.Since(h.At) < b.handled.window {
+		level.Info(b.logger).Log(
+			"msg", "dropping command handled before",
+			"command", h.Command,
+			"chat_id", m.Chat.ID,
+			"message_id", m.ID,
+		)
+		return false
+	}
+	if err != nil && !errors.Is(err, HandledCommandNotFoundErr) {
+		// Handling a command twice is better than dropping it.
+		level.Warn(b.logger).Log("msg", "failed to get handled command", "chat_id", m.Chat.ID, "err", err)
+	}
+
+	h = &HandledCommand{ChatID: m.Chat.ID, MessageID: m.ID, Command: command, At: time.Now()}
+	if err := b.handled.store.Put(h); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put handled command", "chat_id", m.Chat.ID, "err", err)
+	}
+	return true
+}
+
+// pruneHandledCommands removes the records of handled command messages once they're older than the window.
+func (b *Bot) pruneHandledCommands(ctx context.Context) error {
+	ticker := time.NewTicker(b.handled.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			handled, err := b.handled.store.List()
+			if err != nil {
+				level.Warn(b.logger).Log("msg", "failed to list handled commands", "err", err)
+				continue
+			}
+			for _, h := range handled {
+				if time.Since(h.At) < b.handled.window {
+					continue
+				}
+				if err := b.handled.store.Remove(h); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to remove handled command", "err", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/common/model"
+)
+
+// HistoryEntry is a single occurrence of an alert sent to a chat.
+type HistoryEntry struct {
+	ChatID      int64             `json:"chatID"`
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt,omitempty"`
+	AckedAt     time.Time         `json:"ackedAt,omitempty"`
+	// TicketURL links to the ticket opened for the alert, see WithTickets.
+	TicketURL string `json:"ticketURL,omitempty"`
+}
+
+// Name returns the alertname label of the alert.
+func (e *HistoryEntry) Name() string {
+	return e.Labels[model.AlertNameLabel]
+}
+
+// Resolved returns whether the alert resolved.
+func (e *HistoryEntry) Resolved() bool {
+	return !e.EndsAt.IsZero()
+}
+
+// Acked returns whether someone acknowledged the alert.
+func (e *HistoryEntry) Acked() bool {
+	return !e.AckedAt.IsZero()
+}
+
+// BotHistoryStore keeps the history of alerts sent to chats.
+type BotHistoryStore interface {
+	List() ([]*HistoryEntry, error)
+	Get(chatID int64, fingerprint string, startsAt time.Time) (*HistoryEntry, error)
+	Put(*HistoryEntry) error
+	Remove(chatID int64, fingerprint string, startsAt time.Time) error
+	Prune(before time.Time) error
+}
+
+// HistoryStore writes the alert history to a libkv store backend.
+type HistoryStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewHistoryStore stores the alert history in the provided kv backend.
+func NewHistoryStore(kv store.Store, storeKeyPrefix string) (*HistoryStore, error) {
+	return &HistoryStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *HistoryStore) key(chatID int64, fingerprint string, startsAt time.Time) string {
+	return fmt.Sprintf("%s/%d-%s-%d", s.storeKeyPrefix, chatID, fingerprint, startsAt.Unix())
+}
+
+// List all history entries saved in the kv backend.
+func (s *HistoryStore) List() ([]*HistoryEntry, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []*HistoryEntry
+	for _, kv := range kvPairs {
+		var e *HistoryEntry
+		if err := json.Unmarshal(kv.Value, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Get the history entry of a chat's alert that started at startsAt.
+func (s *HistoryStore) Get(chatID int64, fingerprint string, startsAt time.Time) (*HistoryEntry, error) {
+	kv, err := s.kv.Get(s.key(chatID, fingerprint, startsAt))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, AlertNotFoundErr
+		}
+		return nil, err
+	}
+	var e *HistoryEntry
+	err = json.Unmarshal(kv.Value, &e)
+	return e, err
+}
+
+// Put a history entry into the kv backend, replacing any previous one.
+func (s *HistoryStore) Put(e *HistoryEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(e.ChatID, e.Fingerprint, e.StartsAt), b, nil)
+}
+
+// Remove the history entry of a chat's alert that started at startsAt.
+func (s *HistoryStore) Remove(chatID int64, fingerprint string, startsAt time.Time) error {
+	err := s.kv.Delete(s.key(chatID, fingerprint, startsAt))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Prune removes all resolved entries that ended before the given time.
+func (s *HistoryStore) Prune(before time.Time) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Resolved() && e.EndsAt.Before(before) {
+			if err := s.kv.Delete(s.key(e.ChatID, e.Fingerprint, e.StartsAt)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneHistory periodically removes the entries older than the retention.
+func (b *Bot) pruneHistory(ctx context.Context) error {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.history.Prune(time.Now().Add(-b.historyRetention)); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to prune alert history", "err", err)
+			}
+		}
+	}
+}
+
+// resolvedDedupTTL is how long a resolved alert is remembered, so that its resolution is only observed once
+// though it's sent to several chats.
+const resolvedDedupTTL = 24 * time.Hour
+
+// resolvedAlerts remembers the alerts whose resolution was observed, by alertKey.
+type resolvedAlerts struct {
+	mu     sync.Mutex
+	alerts map[string]time.Time
+}
+
+// first returns whether the resolution of the alert wasn't observed before and forgets the ones observed
+// longer than the resolvedDedupTTL ago.
+func (r *resolvedAlerts) first(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, at := range r.alerts {
+		if now.Sub(at) > resolvedDedupTTL {
+			delete(r.alerts, k)
+		}
+	}
+	if _, ok := r.alerts[key]; ok {
+		return false
+	}
+	r.alerts[key] = now
+	return true
+}
+
+// recordHistory adds the alerts sent to a chat to the history.
+// The resolution of an alert is observed only once, though it's recorded for every chat it's sent to.
+func (b *Bot) recordHistory(chatID int64, m webhook.Message) {
+	if b.history == nil {
+		return
+	}
+
+	for _, a := range m.Alerts {
+		fingerprint := alertFingerprint(a)
+
+		entry, err := b.history.Get(chatID, fingerprint, a.StartsAt)
+		if err != nil {
+			entry = &HistoryEntry{ChatID: chatID, Fingerprint: fingerprint, StartsAt: a.StartsAt}
+		}
+		entry.Labels = a.Labels
+		if a.Status == "resolved" && !entry.Resolved() {
+			entry.EndsAt = a.EndsAt
+			if b.resolved.first(alertKey(fingerprint, entry.StartsAt), time.Now()) {
+				b.resolveEvents(entry.Name(), entry.EndsAt.Sub(entry.StartsAt))
+			}
+		}
+
+		if err := b.history.Put(entry); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put alert into history store", "err", err)
+		}
+	}
+}
+
+// recordAck adds the time an alert was acknowledged to its history entry.
+func (b *Bot) recordAck(a *ChatAlert) {
+	b.ackEvents(a.Name(), a.AckedAt.Sub(a.StartsAt))
+
+	if b.history == nil {
+		return
+	}
+
+	entry, err := b.history.Get(a.ChatID, a.Fingerprint, a.StartsAt)
+	if err != nil {
+		if !errors.Is(err, AlertNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get alert from history store", "err", err)
+		}
+		return
+	}
+	entry.AckedAt = a.AckedAt
+
+	if err := b.history.Put(entry); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put alert into history store", "err", err)
+	}
+}
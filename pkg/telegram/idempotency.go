@@ -0,0 +1,272 @@
+package telegram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// IdempotencyNotFoundErr returned by the store if no alert of the group was sent to the chat with the status.
+var IdempotencyNotFoundErr = errors.New("idempotency record not found in store")
+
+// Idempotency records sending an alert of a group with a status to a chat.
+// It's put before the alert is sent and done once it's sent, so that alerts received twice are only sent once
+// and alerts received before a restart are sent after it.
+type Idempotency struct {
+	ChatID      int64     `json:"chatID"`
+	GroupKey    string    `json:"groupKey"`
+	Fingerprint string    `json:"fingerprint"`
+	Status      string    `json:"status"`
+	Done        bool      `json:"done,omitempty"`
+	At          time.Time `json:"at"`
+	// Message is the webhook message of the alert, kept until the alert is sent.
+	Message *webhook.Message `json:"message,omitempty"`
+}
+
+// BotIdempotencyStore keeps the records of alerts being sent to chats.
+type BotIdempotencyStore interface {
+	List() ([]*Idempotency, error)
+	Get(chatID int64, groupKey, fingerprint, status string) (*Idempotency, error)
+	Put(*Idempotency) error
+	Remove(*Idempotency) error
+}
+
+// IdempotencyStore writes the idempotency records to a libkv store backend.
+type IdempotencyStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewIdempotencyStore stores idempotency records in the provided kv backend.
+func NewIdempotencyStore(kv store.Store, storeKeyPrefix string) (*IdempotencyStore, error) {
+	return &IdempotencyStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// key hashes the group key, as it contains slashes and quotes that some backends don't allow in keys.
+func (s *IdempotencyStore) key(chatID int64, groupKey, fingerprint, status string) string {
+	return fmt.Sprintf("%s/%d-%x-%s-%s", s.storeKeyPrefix, chatID, sha256.Sum256([]byte(groupKey)), fingerprint, status)
+}
+
+// List all idempotency records saved in the kv backend.
+func (s *IdempotencyStore) List() ([]*Idempotency, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []*Idempotency
+	for _, kv := range kvPairs {
+		var r *Idempotency
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// Get the record of sending an alert of a group with the status to a chat.
+func (s *IdempotencyStore) Get(chatID int64, groupKey, fingerprint, status string) (*Idempotency, error) {
+	kv, err := s.kv.Get(s.key(chatID, groupKey, fingerprint, status))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, IdempotencyNotFoundErr
+		}
+		return nil, err
+	}
+	var r *Idempotency
+	err = json.Unmarshal(kv.Value, &r)
+	return r, err
+}
+
+// Put a record into the kv backend, replacing any previous one.
+func (s *IdempotencyStore) Put(r *Idempotency) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(r.ChatID, r.GroupKey, r.Fingerprint, r.Status), b, nil)
+}
+
+// Remove a record from the kv backend.
+func (s *IdempotencyStore) Remove(r *Idempotency) error {
+	err := s.kv.Delete(s.key(r.ChatID, r.GroupKey, r.Fingerprint, r.Status))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+type idempotency struct {
+	store  BotIdempotencyStore
+	window time.Duration
+}
+
+// WithIdempotency sends every alert of a group with a status only once to a chat within the window,
+// e.g. if Alertmanager retries a webhook or all peers of an Alertmanager cluster send it.
+// The alerts are recorded before they're sent, so that the ones received before a restart are sent after it.
+func WithIdempotency(s BotIdempotencyStore, window time.Duration) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 {
+			return errors.New("the idempotency window must be positive")
+		}
+		b.idempotent = &idempotency{store: s, window: window}
+		return nil
+	}
+}
+
+// claimAlerts records the alerts of the message as pending for the chat and returns the message without
+// the alerts that were already sent to the chat within the window. It returns false if all of them were.
+func (b *Bot) claimAlerts(chatID int64, m webhook.Message) (webhook.Message, bool) {
+	if b.idempotent == nil {
+		return m, true
+	}
+
+	now := time.Now()
+	var alerts template.Alerts
+	for _, a := range m.Alerts {
+		fingerprint := alertFingerprint(a)
+		r, err := b.idempotent.store.Get(chatID, m.GroupKey, fingerprint, a.Status)
+		if err == nil && r.Done && now.Sub(r.At) < b.idempotent.window {
+			continue
+		}
+		if err != nil && !errors.Is(err, IdempotencyNotFoundErr) {
+			// Sending an alert twice is better than losing it.
+			level.Warn(b.logger).Log("msg", "failed to get idempotency record", "chat_id", chatID, "err", err)
+		}
+
+		r = &Idempotency{ChatID: chatID, GroupKey: m.GroupKey, Fingerprint: fingerprint, Status: a.Status, At: now, Message: &m}
+		if err := b.idempotent.store.Put(r); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put idempotency record", "chat_id", chatID, "err", err)
+		}
+		alerts = append(alerts, a)
+	}
+
+	if skipped := len(m.Alerts) - len(alerts); skipped > 0 {
+		level.Debug(b.logger).Log("msg", "skipped duplicate alerts", "chat_id", chatID, "group_key", m.GroupKey, "alerts", skipped)
+	}
+	if len(alerts) == 0 {
+		return m, false
+	}
+	return withAlerts(m, alerts), true
+}
+
+// completeAlerts marks the pending alerts of the message as sent to the chat.
+func (b *Bot) completeAlerts(chatID int64, m webhook.Message) {
+	if b.idempotent == nil {
+		return
+	}
+	for _, a := range m.Alerts {
+		r, err := b.idempotent.store.Get(chatID, m.GroupKey, alertFingerprint(a), a.Status)
+		if err != nil {
+			if !errors.Is(err, IdempotencyNotFoundErr) {
+				level.Warn(b.logger).Log("msg", "failed to get idempotency record", "chat_id", chatID, "err", err)
+			}
+			continue
+		}
+		if r.Done {
+			continue
+		}
+		r.Done, r.At, r.Message = true, time.Now(), nil
+		if err := b.idempotent.store.Put(r); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put idempotency record", "chat_id", chatID, "err", err)
+		}
+	}
+}
+
+// sendPending sends the alerts of the chats that were received but not sent, e.g. before the bot restarted,
+// in the order they were received. The pending records are the persistent queue of the alerts to send.
+func (b *Bot) sendPending(chats func(chatID int64) bool, msg string) error {
+	records, err := b.idempotent.store.List()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].At.Before(records[j].At) })
+
+	type pending struct {
+		chatID int64
+		m      webhook.Message
+		alerts template.Alerts
+	}
+	var order []string
+	groups := map[string]*pending{}
+	for _, r := range records {
+		if r.Done || r.Message == nil || !chats(r.ChatID) {
+			continue
+		}
+		key := fmt.Sprintf("%d-%s", r.ChatID, r.GroupKey)
+		p, ok := groups[key]
+		if !ok {
+			p = &pending{chatID: r.ChatID, m: *r.Message}
+			groups[key] = p
+			order = append(order, key)
+		}
+		for _, a := range r.Message.Alerts {
+			if alertFingerprint(a) == r.Fingerprint && a.Status == r.Status {
+				p.alerts = append(p.alerts, a)
+				break
+			}
+		}
+	}
+
+	for _, key := range order {
+		p := groups[key]
+		if len(p.alerts) == 0 {
+			continue
+		}
+		level.Info(b.logger).Log("msg", msg, "chat_id", p.chatID, "alerts", len(p.alerts))
+		if _, err := b.sendMessage(p.chatID, withAlerts(p.m, p.alerts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneIdempotency removes the records of sent alerts once they're older than the window.
+func (b *Bot) pruneIdempotency(ctx context.Context) error {
+	ticker := time.NewTicker(b.idempotent.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			records, err := b.idempotent.store.List()
+			if err != nil {
+				level.Warn(b.logger).Log("msg", "failed to list idempotency records", "err", err)
+				continue
+			}
+			for _, r := range records {
+				if !r.Done || time.Since(r.At) < b.idempotent.window {
+					continue
+				}
+				if err := b.idempotent.store.Remove(r); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to remove idempotency record", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// withAlerts returns a copy of the message with only the alerts.
+// The data is copied as well, as it's shared with the buffer of received webhooks.
+func withAlerts(m webhook.Message, alerts template.Alerts) webhook.Message {
+	data := *m.Data
+	data.Alerts = alerts
+	m.Data = &data
+	return m
+}
@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// The kinds of what the bot processed.
+const (
+	ProcessedMessage  = "message"
+	ProcessedCallback = "callback"
+	ProcessedQuery    = "query"
+	ProcessedReaction = "reaction"
+	ProcessedWebhook  = "webhook"
+)
+
+// Processed is a Telegram update or webhook the bot handled completely.
+type Processed struct {
+	// Kind is one of ProcessedMessage, ProcessedCallback, ProcessedQuery, ProcessedReaction and ProcessedWebhook.
+	Kind   string
+	ChatID int64
+	// Text is the message's text, the callback's data, the inline query or the reaction's emoji.
+	Text string
+}
+
+// WithProcessedEvent sets a func to call whenever an update or webhook is handled completely,
+// so that tests and projects embedding the bot can wait for it instead of sleeping.
+// The messages of webhooks may still wait for send workers or correlation, see WaitIdle.
+func WithProcessedEvent(callback func(Processed)) BotOption {
+	return func(b *Bot) error {
+		b.processedEvents = callback
+		return nil
+	}
+}
+
+// activity counts the work in progress of a bot.
+type activity struct {
+	mu   sync.Mutex
+	busy int
+	// idle is closed once no work is in progress anymore.
+	idle chan struct{}
+}
+
+func (a *activity) begin() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.busy++
+}
+
+func (a *activity) end() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.busy--
+	if a.busy == 0 && a.idle != nil {
+		close(a.idle)
+		a.idle = nil
+	}
+}
+
+// wait returns once no work is in progress.
+func (a *activity) wait(ctx context.Context) error {
+	a.mu.Lock()
+	if a.busy == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.idle == nil {
+		a.idle = make(chan struct{})
+	}
+	idle := a.idle
+	a.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+// WaitIdle waits until the running bot handled the webhooks received and the updates processed before it's called,
+// including their messages queued for send workers and held back for correlation.
+// Timers like reminders and the watchdog aren't waited for. Updates handled concurrently, i.e. by a
+// telebot.Bot that isn't synchronous, are only waited for once their handler started.
+func (b *Bot) WaitIdle(ctx context.Context) error {
+	barrier := make(chan struct{})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case b.barriers <- barrier:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-barrier:
+	}
+	return b.active.wait(ctx)
+}
+
+// handle registers the handler for the endpoint, keeping track of the updates in progress.
+// The updates are handled on the worker of their chat, see WithCommandWorkers.
+func (b *Bot) handle(endpoint interface{}, handler interface{}) {
+	switch h := handler.(type) {
+	case func(*telebot.Message):
+		handler = func(m *telebot.Message) {
+			p := Processed{Kind: ProcessedMessage, Text: m.Text}
+			if m.Chat != nil {
+				p.ChatID = m.Chat.ID
+			}
+			b.dispatch(p.ChatID, func() {
+				h(m)
+				b.processedEvents(p)
+			})
+		}
+	case func(*telebot.Callback):
+		handler = func(c *telebot.Callback) {
+			p := Processed{Kind: ProcessedCallback, Text: c.Data}
+			if c.Message != nil && c.Message.Chat != nil {
+				p.ChatID = c.Message.Chat.ID
+			}
+			b.dispatch(p.ChatID, func() {
+				h(c)
+				b.processedEvents(p)
+			})
+		}
+	case func(*telebot.Query):
+		handler = func(q *telebot.Query) {
+			p := Processed{Kind: ProcessedQuery, ChatID: int64(q.From.ID), Text: q.Text}
+			b.dispatch(p.ChatID, func() {
+				h(q)
+				b.processedEvents(p)
+			})
+		}
+	case func(*Reaction):
+		handler = func(r *Reaction) {
+			p := Processed{Kind: ProcessedReaction, Text: strings.Join(r.Emoji, "")}
+			if r.Chat != nil {
+				p.ChatID = r.Chat.ID
+			}
+			b.dispatch(p.ChatID, func() {
+				h(r)
+				b.processedEvents(p)
+			})
+		}
+	case func(from, to int64):
+		handler = func(from, to int64) {
+			// The migration is handled after the updates of the group that are still queued.
+			b.dispatch(from, func() {
+				h(from, to)
+				b.processedEvents(Processed{Kind: ProcessedMessage, ChatID: to})
+			})
+		}
+	}
+	b.telegram.Handle(endpoint, handler)
+}
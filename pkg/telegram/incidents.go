@@ -0,0 +1,349 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseIncidentUsage = "Usage:\n" +
+	CommandIncident + " create <title> - Open an incident in this chat.\n" +
+	CommandIncident + " add <alertname|fingerprint> - Add firing alerts to the incident.\n" +
+	CommandIncident + " close - Close the incident."
+
+// IncidentNotFoundErr returned by the store if a chat has no open incident.
+var IncidentNotFoundErr = errors.New("incident not found in store")
+
+// IncidentAlert is an alert that is part of an incident.
+type IncidentAlert struct {
+	Fingerprint string    `json:"fingerprint"`
+	Name        string    `json:"name"`
+	ResolvedAt  time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Incident groups related alerts of a chat under one title.
+type Incident struct {
+	ChatID    int64           `json:"chatID"`
+	Title     string          `json:"title"`
+	CreatedBy string          `json:"createdBy"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ClosedAt  time.Time       `json:"closedAt,omitempty"`
+	MessageID int             `json:"messageID"`
+	Alerts    []IncidentAlert `json:"alerts"`
+}
+
+// resolved returns the number of resolved alerts of the incident.
+func (i *Incident) resolved() int {
+	resolved := 0
+	for _, a := range i.Alerts {
+		if !a.ResolvedAt.IsZero() {
+			resolved++
+		}
+	}
+	return resolved
+}
+
+// summary renders the incident's pinned summary message.
+func (i *Incident) summary() string {
+	var out strings.Builder
+	if i.ClosedAt.IsZero() {
+		fmt.Fprintf(&out, "🚨 <b>Incident: %s</b>\n", html.EscapeString(i.Title))
+	} else {
+		fmt.Fprintf(&out, "✅ <b>Incident closed: %s</b>\n", html.EscapeString(i.Title))
+		fmt.Fprintf(&out, "<b>Duration:</b> %s\n", formatDuration(i.ClosedAt.Sub(i.CreatedAt)))
+	}
+	if i.CreatedBy != "" {
+		fmt.Fprintf(&out, "<b>Opened by:</b> %s\n", html.EscapeString(i.CreatedBy))
+	}
+	fmt.Fprintf(&out, "<b>Alerts:</b> %d (%d resolved)", len(i.Alerts), i.resolved())
+	for _, a := range i.Alerts {
+		emoji := "🔥"
+		if !a.ResolvedAt.IsZero() {
+			emoji = "✅"
+		}
+		fmt.Fprintf(&out, "\n%s %s", emoji, html.EscapeString(a.Name))
+	}
+	return out.String()
+}
+
+// formatDuration rounds a duration to minutes for humans.
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	return durafmt.Parse(d.Round(time.Minute)).String()
+}
+
+// BotIncidentStore keeps the open incident of each chat.
+type BotIncidentStore interface {
+	List() ([]*Incident, error)
+	Get(chatID int64) (*Incident, error)
+	Put(*Incident) error
+	Remove(chatID int64) error
+}
+
+// IncidentStore writes the incidents to a libkv store backend.
+type IncidentStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewIncidentStore stores incidents in the provided kv backend.
+func NewIncidentStore(kv store.Store, storeKeyPrefix string) (*IncidentStore, error) {
+	return &IncidentStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List the open incidents of all chats saved in the kv backend.
+func (s *IncidentStore) List() ([]*Incident, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var incidents []*Incident
+	for _, kv := range kvPairs {
+		var i *Incident
+		if err := json.Unmarshal(kv.Value, &i); err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, nil
+}
+
+// Get the open incident of a chat.
+func (s *IncidentStore) Get(chatID int64) (*Incident, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, IncidentNotFoundErr
+		}
+		return nil, err
+	}
+	var i *Incident
+	err = json.Unmarshal(kv.Value, &i)
+	return i, err
+}
+
+// Put an incident into the kv backend.
+func (s *IncidentStore) Put(i *Incident) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, i.ChatID), b, nil)
+}
+
+// Remove the incident of a chat from the kv backend.
+func (s *IncidentStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// editIncident updates the pinned summary message of an incident.
+func (b *Bot) editIncident(i *Incident) error {
+	msg := &telebot.StoredMessage{MessageID: strconv.Itoa(i.MessageID), ChatID: i.ChatID}
+	_, err := b.telegram.Edit(msg, i.summary(), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// updateIncident marks resolved alerts of a chat's open incident.
+func (b *Bot) updateIncident(chatID int64, m webhook.Message) {
+	if b.incidents == nil {
+		return
+	}
+
+	incident, err := b.incidents.Get(chatID)
+	if err != nil {
+		if !errors.Is(err, IncidentNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get incident", "chat_id", chatID, "err", err)
+		}
+		return
+	}
+
+	changed := false
+	for _, a := range m.Alerts {
+		if a.Status != "resolved" {
+			continue
+		}
+		fingerprint := alertFingerprint(a)
+		for i := range incident.Alerts {
+			if incident.Alerts[i].Fingerprint == fingerprint && incident.Alerts[i].ResolvedAt.IsZero() {
+				incident.Alerts[i].ResolvedAt = a.EndsAt
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := b.incidents.Put(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put incident", "chat_id", chatID, "err", err)
+		return
+	}
+	if err := b.editIncident(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update incident summary", "chat_id", chatID, "err", err)
+	}
+}
+
+func (b *Bot) handleIncident(message *telebot.Message) error {
+	if b.incidents == nil {
+		_, err := b.telegram.Send(message.Chat, "Incidents aren't enabled.")
+		return err
+	}
+
+	args := strings.SplitN(strings.TrimSpace(message.Payload), " ", 2)
+	arg := ""
+	if len(args) == 2 {
+		arg = strings.TrimSpace(args[1])
+	}
+
+	switch args[0] {
+	case "create":
+		return b.handleIncidentCreate(message, arg)
+	case "add":
+		return b.handleIncidentAdd(message, arg)
+	case "close":
+		return b.handleIncidentClose(message)
+	default:
+		_, err := b.telegram.Send(message.Chat, responseIncidentUsage)
+		return err
+	}
+}
+
+func (b *Bot) handleIncidentCreate(message *telebot.Message, title string) error {
+	if title == "" {
+		_, err := b.telegram.Send(message.Chat, responseIncidentUsage)
+		return err
+	}
+
+	if _, err := b.incidents.Get(message.Chat.ID); err == nil {
+		_, err = b.telegram.Send(message.Chat, "There already is an open incident in this chat, close it first.")
+		return err
+	}
+
+	incident := &Incident{
+		ChatID:    message.Chat.ID,
+		Title:     title,
+		CreatedBy: message.Sender.Username,
+		CreatedAt: time.Now(),
+	}
+
+	summary, err := b.telegram.Send(message.Chat, incident.summary(), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	if err != nil {
+		return err
+	}
+	incident.MessageID = summary.ID
+
+	if err := b.telegram.Pin(summary, telebot.Silent); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to pin incident summary", "chat_id", message.Chat.ID, "err", err)
+	}
+
+	if err := b.incidents.Put(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put incident", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't save the incident.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "incident created", "title", title, "chat_id", message.Chat.ID)
+	return nil
+}
+
+func (b *Bot) handleIncidentAdd(message *telebot.Message, name string) error {
+	if name == "" {
+		_, err := b.telegram.Send(message.Chat, responseIncidentUsage)
+		return err
+	}
+
+	incident, err := b.incidents.Get(message.Chat.ID)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, "There is no open incident in this chat.")
+		return err
+	}
+
+	if b.alerts == nil {
+		_, err := b.telegram.Send(message.Chat, "I don't keep track of firing alerts.")
+		return err
+	}
+
+	alerts, err := b.alerts.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts from alert store", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the firing alerts.")
+		return err
+	}
+
+	added := 0
+alerts:
+	for _, a := range alerts {
+		if a.ChatID != message.Chat.ID || (a.Name() != name && a.Fingerprint != name) {
+			continue
+		}
+		for _, ia := range incident.Alerts {
+			if ia.Fingerprint == a.Fingerprint {
+				continue alerts
+			}
+		}
+		incident.Alerts = append(incident.Alerts, IncidentAlert{Fingerprint: a.Fingerprint, Name: a.Name()})
+		added++
+	}
+
+	if added == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No firing alert matches %s.", name))
+		return err
+	}
+
+	if err := b.incidents.Put(incident); err != nil {
+		return err
+	}
+	if err := b.editIncident(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update incident summary", "chat_id", message.Chat.ID, "err", err)
+	}
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Added %d alert(s) to the incident.", added))
+	return err
+}
+
+func (b *Bot) handleIncidentClose(message *telebot.Message) error {
+	incident, err := b.incidents.Get(message.Chat.ID)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, "There is no open incident in this chat.")
+		return err
+	}
+
+	incident.ClosedAt = time.Now()
+	if err := b.incidents.Remove(message.Chat.ID); err != nil {
+		return err
+	}
+	if err := b.editIncident(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update incident summary", "chat_id", message.Chat.ID, "err", err)
+	}
+
+	level.Info(b.logger).Log("msg", "incident closed", "title", incident.Title, "chat_id", message.Chat.ID)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(
+		"Incident %s closed after %s with %d alert(s), %d resolved.",
+		incident.Title,
+		formatDuration(incident.ClosedAt.Sub(incident.CreatedAt)),
+		len(incident.Alerts),
+		incident.resolved(),
+	))
+	return err
+}
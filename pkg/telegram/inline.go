@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// inlineResultsLimit is the number of results Telegram accepts for an inline query.
+const inlineResultsLimit = 50
+
+// handleInlineQuery answers inline queries like @alertmanager_bot firing db with the firing alerts matching every word,
+// so that authorized users can send an alert's summary to any chat, even if it isn't subscribed.
+func (b *Bot) handleInlineQuery(q *telebot.Query) {
+	resp := &telebot.QueryResponse{IsPersonal: true}
+	defer func() {
+		if err := b.telegram.Answer(q, resp); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to answer inline query", "err", err)
+		}
+	}()
+
+	if !b.isAuthorized(&q.From) {
+		level.Info(b.logger).Log(
+			"msg", "dropping inline query from forbidden sender",
+			"sender_id", q.From.ID,
+			"sender_username", q.From.Username,
+		)
+		return
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	alerts, err := b.alertmanagerFor(int64(q.From.ID)).ListAlerts(ctx, "", false)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		return
+	}
+
+	words := strings.Fields(strings.ToLower(q.Text))
+	for _, a := range alerts {
+		if len(resp.Results) == inlineResultsLimit {
+			break
+		}
+		if a.Resolved() || !matchesWords(a, words) {
+			continue
+		}
+
+		out, err := b.tmplAlerts(a)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
+			return
+		}
+
+		result := &telebot.ArticleResult{
+			Title:       "🔥 " + string(a.Labels[model.AlertNameLabel]),
+			Description: formatLabels(a.Labels),
+		}
+		result.SetContent(&telebot.InputTextMessageContent{Text: b.truncateMessage(out), ParseMode: telebot.ModeHTML})
+		result.SetResultID(a.Fingerprint().String())
+		resp.Results = append(resp.Results, result)
+	}
+}
+
+// matchesWords returns whether every word is part of the alert's labels or annotations, ignoring case.
+// The words firing and alert match every alert, e.g. to list all with @alertmanager_bot firing.
+func matchesWords(a *types.Alert, words []string) bool {
+	var text strings.Builder
+	for name, value := range a.Labels {
+		fmt.Fprintf(&text, "%s=%s\n", name, value)
+	}
+	for name, value := range a.Annotations {
+		fmt.Fprintf(&text, "%s=%s\n", name, value)
+	}
+	haystack := strings.ToLower(text.String())
+
+	for _, w := range words {
+		if w == "firing" || w == "alert" || w == "alerts" {
+			continue
+		}
+		if !strings.Contains(haystack, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels returns the labels except the alertname sorted by name, e.g. instance=node-1, job=node.
+func formatLabels(labels model.LabelSet) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if name == model.AlertNameLabel {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// InviteNotFoundErr returned by the store if an invitation isn't found.
+var InviteNotFoundErr = errors.New("invite not found in store")
+
+// Invite is a one-time token that subscribes the chat it's sent in with the start command.
+type Invite struct {
+	Token     string    `json:"token"`
+	CreatedBy int       `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BotInviteStore keeps the invitations that weren't used yet.
+type BotInviteStore interface {
+	Get(token string) (*Invite, error)
+	Put(*Invite) error
+	Remove(token string) error
+}
+
+// InviteStore writes the invitations to a libkv store backend.
+type InviteStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewInviteStore stores invitations in the provided kv backend.
+func NewInviteStore(kv store.Store, storeKeyPrefix string) (*InviteStore, error) {
+	return &InviteStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get an invitation by its token.
+func (s *InviteStore) Get(token string) (*Invite, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%s", s.storeKeyPrefix, token))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, InviteNotFoundErr
+		}
+		return nil, err
+	}
+	var i *Invite
+	err = json.Unmarshal(kv.Value, &i)
+	return i, err
+}
+
+// Put an invitation into the kv backend.
+func (s *InviteStore) Put(i *Invite) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, i.Token), b, nil)
+}
+
+// Remove an invitation from the kv backend.
+func (s *InviteStore) Remove(token string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, token))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithInvites lets admins create one-time tokens with the invite command.
+// Anyone sending the start command with a token subscribes the chat without being an admin.
+// Tokens expire after expiry, an expiry of 0 keeps them until they're used.
+func WithInvites(invites BotInviteStore, expiry time.Duration) BotOption {
+	return func(b *Bot) error {
+		b.invites = invites
+		b.inviteExpiry = expiry
+		return nil
+	}
+}
+
+func (b *Bot) handleInvite(message *telebot.Message) error {
+	if b.invites == nil {
+		_, err := b.telegram.Send(message.Chat, "Invitations aren't enabled.")
+		return err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	invite := &Invite{Token: hex.EncodeToString(token), CreatedBy: message.Sender.ID, CreatedAt: time.Now()}
+	if err := b.invites.Put(invite); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put invite", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't create an invitation.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "invite created", "username", message.Sender.Username, "user_id", message.Sender.ID)
+
+	out := fmt.Sprintf("Send `%s %s` in the chat that should receive alerts. The invitation can only be used once", CommandStart, invite.Token)
+	if b.inviteExpiry > 0 {
+		out += fmt.Sprintf(" and expires in %s", formatDuration(b.inviteExpiry))
+	}
+	_, err := b.telegram.Send(message.Chat, out+".", &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
+	return err
+}
+
+// redeemInvite subscribes the message's chat if it was sent with a valid invitation token.
+func (b *Bot) redeemInvite(message *telebot.Message) error {
+	token := strings.TrimSpace(message.Payload)
+
+	invite, err := b.invites.Get(token)
+	if err != nil && !errors.Is(err, InviteNotFoundErr) {
+		level.Warn(b.logger).Log("msg", "failed to get invite", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't check the invitation.")
+		return err
+	}
+	if err != nil || (b.inviteExpiry > 0 && time.Since(invite.CreatedAt) > b.inviteExpiry) {
+		level.Info(b.logger).Log(
+			"msg", "invalid invite",
+			"username", message.Sender.Username,
+			"user_id", message.Sender.ID,
+			"chat_id", message.Chat.ID,
+		)
+		_, err = b.telegram.Send(message.Chat, "This invitation isn't valid, ask an admin for a new one.")
+		return err
+	}
+
+	if err := b.invites.Remove(token); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove invite", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't check the invitation.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "invite used", "invited_by", invite.CreatedBy, "chat_id", message.Chat.ID)
+	return b.handleStart(message)
+}
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+// labelFilter hides labels from rendered messages.
+type labelFilter struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// WithLabelFilter hides labels whose names match one of the deny regular expressions from rendered messages.
+// If allow isn't empty only labels matching one of its regular expressions are shown.
+// The alertname label is always kept.
+func WithLabelFilter(allow, deny []string) BotOption {
+	return func(b *Bot) error {
+		f := &labelFilter{}
+		for _, expr := range allow {
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return fmt.Errorf("failed to parse allowed label %q: %w", expr, err)
+			}
+			f.allow = append(f.allow, re)
+		}
+		for _, expr := range deny {
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return fmt.Errorf("failed to parse denied label %q: %w", expr, err)
+			}
+			f.deny = append(f.deny, re)
+		}
+		b.labelFilter = f
+		return nil
+	}
+}
+
+// shown returns whether the label should be shown in rendered messages.
+func (f *labelFilter) shown(name string) bool {
+	if name == string(model.AlertNameLabel) {
+		return true
+	}
+	for _, re := range f.deny {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, re := range f.allow {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns a copy of the labels without the hidden ones.
+func (f *labelFilter) filter(kv template.KV) template.KV {
+	if f == nil || kv == nil {
+		return kv
+	}
+	filtered := make(template.KV, len(kv))
+	for name, value := range kv {
+		if f.shown(name) {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
+// filterAlerts returns a copy of the alerts without the hidden labels.
+func (f *labelFilter) filterAlerts(alerts template.Alerts) template.Alerts {
+	if f == nil {
+		return alerts
+	}
+	filtered := make(template.Alerts, 0, len(alerts))
+	for _, a := range alerts {
+		a.Labels = f.filter(a.Labels)
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
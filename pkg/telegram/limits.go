@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// telegramCaptionLimit is the maximum length of the caption of a document.
+	telegramCaptionLimit = 1024
+	// telegramEntityLimit is the maximum number of formatting entities of a message.
+	telegramEntityLimit = 100
+	// truncatedSuffix ends messages that were truncated.
+	truncatedSuffix = "\n<b>[SNIP]</b>"
+)
+
+// htmlTag matches the opening and closing tags of HTML messages.
+var htmlTag = regexp.MustCompile(`<(/?)([a-zA-Z-]+)[^>]*>`)
+
+// truncateMessage makes an HTML message fit Telegram's limits, so that it isn't rejected with a cryptic error.
+// A message with too many formatting entities loses its formatting. A message that is still too long
+// is cut after the last alert that fits, or cut without formatting if not even the first alert fits.
+func (b *Bot) truncateMessage(str string) string {
+	if n := messageEntities(str); n > telegramEntityLimit {
+		level.Warn(b.logger).Log("msg", "message has too many formatting entities, dropping its formatting", "entities", n)
+		str = htmlTag.ReplaceAllString(str, "")
+	}
+
+	length := visibleLength(str)
+	if length <= telegramMessageLimit {
+		return str
+	}
+	level.Warn(b.logger).Log("msg", "message is too long, truncating it", "length", length)
+
+	// Alerts are separated by empty lines, cutting there doesn't break any formatting.
+	end, length, suffix := -1, 0, visibleLength(truncatedSuffix)
+	for start := 0; ; {
+		i := strings.Index(str[start:], "\n\n")
+		if i < 0 {
+			break
+		}
+		length += visibleLength(str[start : start+i])
+		if length+suffix > telegramMessageLimit {
+			break
+		}
+		if balancedTags(str[:start+i]) {
+			end = start + i
+		}
+		length += 2
+		start += i + 2
+	}
+	if end > 0 {
+		return str[:end] + truncatedSuffix
+	}
+
+	level.Warn(b.logger).Log("msg", "message is too long without a line break to cut it at, dropping its formatting")
+	return html.EscapeString(cutText(html.UnescapeString(htmlTag.ReplaceAllString(str, "")), telegramMessageLimit))
+}
+
+// truncateCaption cuts a plain text caption of a document to Telegram's limit.
+func truncateCaption(caption string) string {
+	return cutText(caption, telegramCaptionLimit)
+}
+
+// cutText cuts plain text longer than the limit of UTF-16 code units and ends it with an ellipsis.
+func cutText(text string, limit int) string {
+	units := utf16.Encode([]rune(text))
+	if len(units) <= limit {
+		return text
+	}
+	keep := limit - 1
+	if utf16.IsSurrogate(rune(units[keep-1])) && units[keep-1] < 0xdc00 {
+		// Don't split the surrogate pair of an emoji.
+		keep--
+	}
+	return string(utf16.Decode(units[:keep])) + "…"
+}
+
+// messageEntities returns the number of formatting entities of an HTML message.
+func messageEntities(text string) int {
+	n := 0
+	for _, m := range htmlTag.FindAllStringSubmatch(text, -1) {
+		if m[1] == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// visibleLength returns the length of an HTML message after Telegram parsed its formatting,
+// counted in UTF-16 code units like Telegram does.
+func visibleLength(text string) int {
+	return len(utf16.Encode([]rune(html.UnescapeString(htmlTag.ReplaceAllString(text, "")))))
+}
+
+// balancedTags returns whether all tags opened in the HTML are closed again.
+func balancedTags(text string) bool {
+	var open []string
+	for _, m := range htmlTag.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(m[2])
+		if m[1] == "" {
+			open = append(open, name)
+			continue
+		}
+		if len(open) == 0 || open[len(open)-1] != name {
+			return false
+		}
+		open = open[:len(open)-1]
+	}
+	return len(open) == 0
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// maxLocations is the most locations sent for the alerts of a message, to not bury the chat in maps.
+const maxLocations = 5
+
+type locationLabels struct {
+	latitude  string
+	longitude string
+}
+
+// WithLocations sends the sites of firing alerts with the latitude and longitude labels as Telegram locations
+// in reply to the message of the alerts, e.g. for edge or IoT monitoring.
+func WithLocations(latitude, longitude string) BotOption {
+	return func(b *Bot) error {
+		b.locations = &locationLabels{latitude: latitude, longitude: longitude}
+		return nil
+	}
+}
+
+// sendLocations sends the distinct locations of the firing alerts in reply to the message of the alerts.
+// The alerts were sent already, so failures are only logged.
+func (b *Bot) sendLocations(chat *telebot.Chat, messageID int, alerts template.Alerts) {
+	if b.locations == nil || messageID == 0 {
+		return
+	}
+
+	seen := map[telebot.Location]bool{}
+	for _, a := range alerts {
+		if a.Status != string(model.AlertFiring) {
+			continue
+		}
+		loc, ok, err := b.locations.parse(a.Labels)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "alert has an invalid location", "alertname", a.Labels[string(model.AlertNameLabel)], "err", err)
+			continue
+		}
+		if !ok || seen[loc] {
+			continue
+		}
+		if len(seen) == maxLocations {
+			level.Debug(b.logger).Log("msg", "not sending more locations", "chat_id", chat.ID, "max", maxLocations)
+			return
+		}
+		seen[loc] = true
+
+		_, err = b.telegram.Send(chat, &loc, &telebot.SendOptions{ReplyTo: &telebot.Message{ID: messageID, Chat: chat}})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send location", "chat_id", chat.ID, "err", err)
+		}
+	}
+}
+
+// parse returns the location of the labels and whether they have one.
+func (l *locationLabels) parse(labels template.KV) (telebot.Location, bool, error) {
+	lat, hasLat := labels[l.latitude]
+	lng, hasLng := labels[l.longitude]
+	if !hasLat || !hasLng {
+		return telebot.Location{}, false, nil
+	}
+
+	latitude, err := strconv.ParseFloat(lat, 32)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return telebot.Location{}, false, fmt.Errorf("latitude %q isn't between -90 and 90", lat)
+	}
+	longitude, err := strconv.ParseFloat(lng, 32)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return telebot.Location{}, false, fmt.Errorf("longitude %q isn't between -180 and 180", lng)
+	}
+	return telebot.Location{Lat: float32(latitude), Lng: float32(longitude)}, true, nil
+}
@@ -0,0 +1,527 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// MaintenanceLayout is the layout of the start times of maintenance windows, in the bot's local time zone.
+const MaintenanceLayout = "2006-01-02T15:04"
+
+const responseMaintenanceUsage = "Usage:\n" +
+	CommandMaintenance + " - List the maintenance windows.\n" +
+	CommandMaintenance + " add \"<name>\" <start> <duration> <matcher>... - Add a window, e.g. " + CommandMaintenance + " add \"DB upgrade\" 2024-07-01T22:00 4h instance=db-1\n" +
+	CommandMaintenance + " remove <id> - Remove a window."
+
+// MaintenanceNotFoundErr returned by the store if a maintenance window isn't found.
+var MaintenanceNotFoundErr = errors.New("maintenance window not found in store")
+
+var maintenanceIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Maintenance is a scheduled window in which the alerts matching its matchers are silenced.
+type Maintenance struct {
+	// ID is derived from the name, e.g. db-upgrade for "DB upgrade".
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Matchers use the syntax of amtool, e.g. instance=db-1 or team!="db".
+	Matchers  []string  `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	// ChatID is the chat the window was added in, which is reminded of it.
+	ChatID int64 `json:"chatID"`
+	// SilenceID is the silence created in Alertmanager once the window started.
+	SilenceID     string `json:"silenceID,omitempty"`
+	StartReminded bool   `json:"startReminded,omitempty"`
+	EndReminded   bool   `json:"endReminded,omitempty"`
+	// Source is calendar for the windows synced with WithMaintenanceCalendar, empty for the ones added with the command.
+	Source string `json:"source,omitempty"`
+}
+
+// active returns whether the window is running at the time.
+func (m *Maintenance) active(now time.Time) bool {
+	return !now.Before(m.StartsAt) && now.Before(m.EndsAt)
+}
+
+// matches returns whether the labels match all matchers of the window.
+func (m *Maintenance) matches(labels template.KV) bool {
+	for _, raw := range m.Matchers {
+		matcher, err := alertmanager.ParseMatcher(raw)
+		if err != nil || !matcher.Matches(labels[matcher.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalJSON also reads the windows stored before matchers other than equality were supported,
+// whose matchers are a map of label names to values.
+func (m *Maintenance) UnmarshalJSON(data []byte) error {
+	type plain Maintenance
+	var v struct {
+		*plain
+		Matchers json.RawMessage `json:"matchers"`
+	}
+	v.plain = (*plain)(m)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Matchers) == 0 || string(v.Matchers) == "null" {
+		m.Matchers = nil
+		return nil
+	}
+	if err := json.Unmarshal(v.Matchers, &m.Matchers); err == nil {
+		return nil
+	}
+
+	var equal map[string]string
+	if err := json.Unmarshal(v.Matchers, &equal); err != nil {
+		return err
+	}
+	m.Matchers = nil
+	for _, matcher := range alertmanager.EqualMatchers(equal) {
+		m.Matchers = append(m.Matchers, matcher.String())
+	}
+	return nil
+}
+
+// BotMaintenanceStore keeps the scheduled maintenance windows.
+type BotMaintenanceStore interface {
+	List() ([]*Maintenance, error)
+	Get(id string) (*Maintenance, error)
+	Put(*Maintenance) error
+	Remove(id string) error
+}
+
+// MaintenanceStore writes the maintenance windows to a libkv store backend.
+type MaintenanceStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewMaintenanceStore stores the maintenance windows in the provided kv backend.
+func NewMaintenanceStore(kv store.Store, storeKeyPrefix string) (*MaintenanceStore, error) {
+	return &MaintenanceStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all maintenance windows saved in the kv backend.
+func (s *MaintenanceStore) List() ([]*Maintenance, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var windows []*Maintenance
+	for _, kv := range kvPairs {
+		var m *Maintenance
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, err
+		}
+		windows = append(windows, m)
+	}
+	return windows, nil
+}
+
+// Get a maintenance window by its ID.
+func (s *MaintenanceStore) Get(id string) (*Maintenance, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%s", s.storeKeyPrefix, id))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, MaintenanceNotFoundErr
+		}
+		return nil, err
+	}
+	var m *Maintenance
+	err = json.Unmarshal(kv.Value, &m)
+	return m, err
+}
+
+// Put a maintenance window into the kv backend.
+func (s *MaintenanceStore) Put(m *Maintenance) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, m.ID), b, nil)
+}
+
+// Remove a maintenance window from the kv backend.
+func (s *MaintenanceStore) Remove(id string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, id))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+type maintenance struct {
+	store        BotMaintenanceStore
+	remindBefore time.Duration
+}
+
+// WithMaintenance lets admins schedule maintenance windows with the maintenance command.
+// The alerts matching an active window aren't sent to any chat, and once a window starts
+// a silence lasting until its end is created in Alertmanager.
+// The chat a window was added in is reminded remindBefore it starts and ends, not at all if it's 0.
+func WithMaintenance(s BotMaintenanceStore, remindBefore time.Duration) BotOption {
+	return func(b *Bot) error {
+		if remindBefore < 0 {
+			return errors.New("maintenance reminders must not be negative")
+		}
+		b.maintenance = &maintenance{store: s, remindBefore: remindBefore}
+		return nil
+	}
+}
+
+// filterMaintenance returns the alerts that don't match an active maintenance window.
+func (b *Bot) filterMaintenance(alerts template.Alerts) template.Alerts {
+	if b.maintenance == nil {
+		return alerts
+	}
+	windows, err := b.maintenance.store.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list maintenance windows", "err", err)
+		return alerts
+	}
+
+	now := time.Now()
+	var active []*Maintenance
+	for _, m := range windows {
+		if m.active(now) {
+			active = append(active, m)
+		}
+	}
+	if len(active) == 0 {
+		return alerts
+	}
+
+	remaining := make(template.Alerts, 0, len(alerts))
+alerts:
+	for _, a := range alerts {
+		for _, m := range active {
+			if m.matches(a.Labels) {
+				continue alerts
+			}
+		}
+		remaining = append(remaining, a)
+	}
+	return remaining
+}
+
+// runMaintenance starts and ends the maintenance windows and reminds their chats of them,
+// once when the bot starts and every minute after. The windows of a calendar are synced before if they're due.
+func (b *Bot) runMaintenance(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if b.calendar != nil && now.Sub(b.calendar.synced) >= b.calendar.interval {
+			if err := b.syncCalendar(ctx, now); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to sync maintenance calendar", "err", err)
+			}
+			// A failed sync is retried after the interval, the windows synced before are kept until then.
+			b.calendar.synced = now
+		}
+		if err := b.maintain(ctx, now); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to run maintenance windows", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bot) maintain(ctx context.Context, now time.Time) error {
+	windows, err := b.maintenance.store.List()
+	if err != nil {
+		return err
+	}
+
+	remindBefore := b.maintenance.remindBefore
+	for _, m := range windows {
+		if !now.Before(m.EndsAt) {
+			if err := b.maintenance.store.Remove(m.ID); err != nil {
+				return err
+			}
+			level.Info(b.logger).Log("msg", "maintenance window ended", "id", m.ID)
+			continue
+		}
+
+		changed := false
+		if remindBefore > 0 && !m.StartReminded && now.Before(m.StartsAt) && !now.Before(m.StartsAt.Add(-remindBefore)) {
+			b.remindMaintenance(m, "🔧 The maintenance window %s starts at %s.", m.StartsAt)
+			m.StartReminded, changed = true, true
+		}
+		if m.SilenceID == "" && m.active(now) {
+			comment := fmt.Sprintf("Maintenance window %s scheduled via Telegram", m.Name)
+			id, err := b.createMaintenanceSilence(ctx, m, now, comment)
+			if err != nil {
+				// The silence is created on the next run, the alerts are still suppressed locally until then.
+				level.Warn(b.logger).Log("msg", "failed to create maintenance silence", "id", m.ID, "err", err)
+			} else {
+				level.Info(b.logger).Log("msg", "maintenance window started", "id", m.ID, "silence_id", id)
+				m.SilenceID, changed = id, true
+			}
+		}
+		if remindBefore > 0 && !m.EndReminded && m.active(now) && !now.Before(m.EndsAt.Add(-remindBefore)) {
+			b.remindMaintenance(m, "🔧 The maintenance window %s ends at %s.", m.EndsAt)
+			m.EndReminded, changed = true, true
+		}
+
+		if changed {
+			if err := b.maintenance.store.Put(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createMaintenanceSilence silences the alerts matching the matchers of the window until it ends.
+func (b *Bot) createMaintenanceSilence(ctx context.Context, m *Maintenance, now time.Time, comment string) (string, error) {
+	matchers := make([]*alertmanager.Matcher, 0, len(m.Matchers))
+	for _, raw := range m.Matchers {
+		matcher, err := alertmanager.ParseMatcher(raw)
+		if err != nil {
+			return "", err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	return b.alertmanagerFor(m.ChatID).CreateSilence(ctx, matchers, now, m.EndsAt, m.CreatedBy, comment)
+}
+
+// remindMaintenance sends the reminder about the window to the chat it was added in,
+// the windows of a calendar are announced to all subscribed chats.
+func (b *Bot) remindMaintenance(m *Maintenance, format string, at time.Time) {
+	chats := []*telebot.Chat{{ID: m.ChatID}}
+	if m.ChatID == 0 {
+		var err error
+		if chats, err = b.chats.List(); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list chats", "err", err)
+			return
+		}
+	}
+
+	text := fmt.Sprintf(format, m.Name, at.Format(MaintenanceLayout))
+	for _, chat := range chats {
+		if _, err := b.telegram.Send(chat, text); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send maintenance reminder", "id", m.ID, "chat_id", chat.ID, "err", err)
+		}
+	}
+}
+
+func (b *Bot) handleMaintenance(message *telebot.Message) error {
+	if b.maintenance == nil {
+		_, err := b.telegram.Send(message.Chat, "Maintenance windows aren't enabled.")
+		return err
+	}
+
+	args, err := splitQuoted(message.Payload)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, responseMaintenanceUsage)
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return b.listMaintenance(message)
+	case args[0] == "add" && len(args) >= 5:
+		return b.addMaintenance(message, args[1], args[2], args[3], args[4:])
+	case args[0] == "remove" && len(args) == 2:
+		return b.removeMaintenance(message, args[1])
+	}
+	_, err = b.telegram.Send(message.Chat, responseMaintenanceUsage)
+	return err
+}
+
+func (b *Bot) listMaintenance(message *telebot.Message) error {
+	windows, err := b.maintenance.store.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list maintenance windows", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the maintenance windows.")
+		return err
+	}
+	if len(windows) == 0 {
+		_, err = b.telegram.Send(message.Chat, "No maintenance windows are scheduled.")
+		return err
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].StartsAt.Before(windows[j].StartsAt) })
+
+	out := "Maintenance windows:\n"
+	for _, m := range windows {
+		if m.Source == calendarSource {
+			out += "📅 "
+		}
+		out += fmt.Sprintf("%s (%s) %s - %s %s\n",
+			m.Name,
+			m.ID,
+			m.StartsAt.Format(MaintenanceLayout),
+			m.EndsAt.Format(MaintenanceLayout),
+			formatMaintenanceMatchers(m.Matchers),
+		)
+	}
+	_, err = b.telegram.Send(message.Chat, strings.TrimSuffix(out, "\n"))
+	return err
+}
+
+func (b *Bot) addMaintenance(message *telebot.Message, name, start, duration string, rawMatchers []string) error {
+	startsAt, err := time.ParseInLocation(MaintenanceLayout, start, time.Local)
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%q isn't a valid start, e.g. 2024-07-01T22:00.", start))
+		return err
+	}
+	d, err := model.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%q isn't a valid duration, e.g. 30m, 4h or 1d.", duration))
+		return err
+	}
+	endsAt := startsAt.Add(time.Duration(d))
+	if !endsAt.After(time.Now()) {
+		_, err = b.telegram.Send(message.Chat, "The maintenance window would be over already.")
+		return err
+	}
+
+	matchers := make([]string, 0, len(rawMatchers))
+	for _, raw := range rawMatchers {
+		m, err := alertmanager.ParseMatcher(raw)
+		if err != nil {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%q isn't a valid matcher, e.g. instance=db-1 or team!=db.", raw))
+			return err
+		}
+		matchers = append(matchers, m.String())
+	}
+
+	id := maintenanceID(name)
+	if id == "" {
+		_, err = b.telegram.Send(message.Chat, "The name of a maintenance window needs letters or digits.")
+		return err
+	}
+	if _, err := b.maintenance.store.Get(id); err == nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("There is a maintenance window %s already.", id))
+		return err
+	} else if !errors.Is(err, MaintenanceNotFoundErr) {
+		level.Warn(b.logger).Log("msg", "failed to get maintenance window", "id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't add the maintenance window.")
+		return err
+	}
+
+	m := &Maintenance{
+		ID:        id,
+		Name:      name,
+		Matchers:  matchers,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		CreatedBy: message.Sender.Username,
+		ChatID:    message.Chat.ID,
+	}
+	if err := b.maintenance.store.Put(m); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put maintenance window", "id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't add the maintenance window.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "maintenance window added", "id", id, "username", message.Sender.Username)
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf(
+		"Added the maintenance window %s from %s to %s, the alerts matching %s will be silenced.",
+		name,
+		startsAt.Format(MaintenanceLayout),
+		endsAt.Format(MaintenanceLayout),
+		formatMaintenanceMatchers(matchers),
+	))
+	return err
+}
+
+func (b *Bot) removeMaintenance(message *telebot.Message, id string) error {
+	m, err := b.maintenance.store.Get(id)
+	if err != nil {
+		if errors.Is(err, MaintenanceNotFoundErr) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("There is no maintenance window %s.", id))
+			return err
+		}
+		level.Warn(b.logger).Log("msg", "failed to get maintenance window", "id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't remove the maintenance window.")
+		return err
+	}
+	if m.Source == calendarSource {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The maintenance window %s comes from the calendar, remove its event there.", m.Name))
+		return err
+	}
+	if err := b.maintenance.store.Remove(id); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove maintenance window", "id", id, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't remove the maintenance window.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "maintenance window removed", "id", id, "username", message.Sender.Username)
+	text := fmt.Sprintf("Removed the maintenance window %s.", m.Name)
+	if m.SilenceID != "" {
+		text += fmt.Sprintf(" Its silence %s in Alertmanager keeps running until it expires.", m.SilenceID)
+	}
+	_, err = b.telegram.Send(message.Chat, text)
+	return err
+}
+
+// maintenanceID returns the lower-case letters and digits of the name separated by dashes, e.g. db-upgrade for "DB upgrade".
+func maintenanceID(name string) string {
+	return strings.Trim(maintenanceIDChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// formatMaintenanceMatchers returns the matchers in braces, e.g. {instance="db-1", team!="db"}.
+func formatMaintenanceMatchers(matchers []string) string {
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// splitQuoted splits s at spaces except within double quotes, which are removed,
+// e.g. `add "DB upgrade" 4h` is split into add, DB upgrade and 4h.
+func splitQuoted(s string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		inField bool
+		quoted  bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case r == ' ' && !quoted:
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
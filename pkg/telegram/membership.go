@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"errors"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseGroupRestored = "Hey! I'm back and will keep you all up to date again, with the settings from before.\n" + CommandHelp
+
+// isMe returns whether u is the bot itself.
+// It's only known if the transport knows the bot's user, see NewBot and NewBotWithTelegram.
+func (b *Bot) isMe(u *telebot.User) bool {
+	return u != nil && b.me != nil && u.ID == b.me.ID
+}
+
+// handleUserJoined restores the archived subscription of a group when the bot is added to it again.
+// Groups that were never subscribed still have to send /start.
+func (b *Bot) handleUserJoined(m *telebot.Message) {
+	added := m.GroupCreated || m.SuperGroupCreated || b.isMe(m.UserJoined)
+	for i := range m.UsersJoined {
+		added = added || b.isMe(&m.UsersJoined[i])
+	}
+	if !added || m.Private() {
+		return
+	}
+	if m.Sender == nil || b.isBanned(m.Sender) || !b.isAllowed(m, CommandStart) {
+		level.Debug(b.logger).Log("msg", "not restoring group added by forbidden sender", "chat_id", m.Chat.ID)
+		return
+	}
+
+	archived, err := b.restoreChat(m.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to restore archived group", "chat_id", m.Chat.ID, "err", err)
+		return
+	}
+	if archived == nil {
+		return
+	}
+	// The archived chat might have an outdated title.
+	if err := b.chats.Add(m.Chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to add chat to chat store", "err", err)
+		return
+	}
+
+	level.Info(b.logger).Log("msg", "group subscription restored", "chat_id", m.Chat.ID, "username", m.Sender.Username)
+	if _, err := b.telegram.Send(m.Chat, responseGroupRestored); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send message", "err", err)
+	}
+}
+
+// handleUserLeft archives the subscription of a group when the bot is removed from it,
+// as it can't send alerts there anymore.
+func (b *Bot) handleUserLeft(m *telebot.Message) {
+	if !b.isMe(m.UserLeft) {
+		return
+	}
+
+	chat, err := b.chats.Get(telebot.ChatID(m.Chat.ID))
+	if err != nil {
+		if !errors.Is(err, ChatNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "chat_id", m.Chat.ID, "err", err)
+		}
+		return
+	}
+	if err := b.archiveChat(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to archive chat", "chat_id", chat.ID, "err", err)
+		return
+	}
+	if err := b.chats.Remove(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove chat from chat store", "chat_id", chat.ID, "err", err)
+		return
+	}
+	level.Info(b.logger).Log("msg", "group subscription archived, bot was removed", "chat_id", chat.ID)
+}
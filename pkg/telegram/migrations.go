@@ -0,0 +1,374 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ChatMigrationNotFoundErr returned by the store if a chat didn't migrate.
+var ChatMigrationNotFoundErr = errors.New("chat migration not found in store")
+
+// ChatMigration is a group that was upgraded to a supergroup with a new ID.
+type ChatMigration struct {
+	From int64     `json:"from"`
+	To   int64     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// BotMigrationStore keeps the chat migrations, to send the webhooks of the old chat IDs to the new ones.
+type BotMigrationStore interface {
+	List() ([]*ChatMigration, error)
+	Get(from int64) (*ChatMigration, error)
+	Put(*ChatMigration) error
+	Remove(from int64) error
+}
+
+// MigrationStore writes the chat migrations to a libkv store backend.
+type MigrationStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewMigrationStore stores chat migrations in the provided kv backend.
+func NewMigrationStore(kv store.Store, storeKeyPrefix string) (*MigrationStore, error) {
+	return &MigrationStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all chat migrations saved in the kv backend.
+func (s *MigrationStore) List() ([]*ChatMigration, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var migrations []*ChatMigration
+	for _, kv := range kvPairs {
+		var m *ChatMigration
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, nil
+}
+
+// Get the migration of a chat by its old ID.
+func (s *MigrationStore) Get(from int64) (*ChatMigration, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, from))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, ChatMigrationNotFoundErr
+		}
+		return nil, err
+	}
+	var m *ChatMigration
+	err = json.Unmarshal(kv.Value, &m)
+	return m, err
+}
+
+// Put a chat migration into the kv backend.
+func (s *MigrationStore) Put(m *ChatMigration) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, m.From), b, nil)
+}
+
+// Remove the migration of a chat by its old ID from the kv backend.
+func (s *MigrationStore) Remove(from int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, from))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithChatMigrations moves everything stored about a group to its new ID when it's upgraded to a supergroup,
+// and sends the webhooks of the old ID, that Alertmanager keeps using, to the supergroup.
+func WithChatMigrations(migrations BotMigrationStore) BotOption {
+	return func(b *Bot) error {
+		b.migrations = migrations
+		return nil
+	}
+}
+
+// migratedChatID returns the ID of the supergroup a group was upgraded to, or the chatID itself.
+func (b *Bot) migratedChatID(chatID int64) int64 {
+	if b.migrations == nil {
+		return chatID
+	}
+	m, err := b.migrations.Get(chatID)
+	if err != nil {
+		if !errors.Is(err, ChatMigrationNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get chat migration", "chat_id", chatID, "err", err)
+		}
+		return chatID
+	}
+	return m.To
+}
+
+// handleMigration is called by Telegram with the old and new ID of a group upgraded to a supergroup.
+func (b *Bot) handleMigration(from, to int64) {
+	if err := b.migrateChat(from, to); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to migrate chat", "from", from, "to", to, "err", err)
+		return
+	}
+	level.Info(b.logger).Log("msg", "chat migrated to supergroup", "from", from, "to", to)
+}
+
+// migrateChat moves the subscription, settings, alerts, history, incident, deliveries and chat groups of a chat to its new ID.
+func (b *Bot) migrateChat(from, to int64) error {
+	if err := b.migrations.Put(&ChatMigration{From: from, To: to, At: time.Now()}); err != nil {
+		return fmt.Errorf("failed to put chat migration: %w", err)
+	}
+
+	chat, err := b.chats.Get(telebot.ChatID(from))
+	if err != nil && !errors.Is(err, ChatNotFoundErr) {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err == nil {
+		migrated := *chat
+		migrated.ID, migrated.Type = to, telebot.ChatSuperGroup
+		if err := b.chats.Add(&migrated); err != nil {
+			return fmt.Errorf("failed to add chat: %w", err)
+		}
+		if err := b.chats.Remove(chat); err != nil {
+			return fmt.Errorf("failed to remove chat: %w", err)
+		}
+	}
+
+	if b.archive != nil {
+		a, err := b.archive.Get(from)
+		if err != nil && !errors.Is(err, ArchivedChatNotFoundErr) {
+			return fmt.Errorf("failed to get archived chat: %w", err)
+		}
+		if err == nil {
+			migrated := *a.Chat
+			migrated.ID, migrated.Type = to, telebot.ChatSuperGroup
+			a.Chat = &migrated
+			if a.Filter != nil {
+				a.Filter.ChatID = to
+			}
+			if err := b.archive.Put(a); err != nil {
+				return fmt.Errorf("failed to put archived chat: %w", err)
+			}
+			if err := b.archive.Remove(from); err != nil {
+				return fmt.Errorf("failed to remove archived chat: %w", err)
+			}
+		}
+	}
+	if b.filters != nil {
+		f, err := b.filters.Get(from)
+		if err != nil && !errors.Is(err, FilterNotFoundErr) {
+			return fmt.Errorf("failed to get chat filter: %w", err)
+		}
+		if err == nil {
+			f.ChatID = to
+			if err := b.filters.Put(f); err != nil {
+				return fmt.Errorf("failed to put chat filter: %w", err)
+			}
+			if err := b.filters.Remove(from); err != nil {
+				return fmt.Errorf("failed to remove chat filter: %w", err)
+			}
+		}
+	}
+	if b.mutes != nil {
+		m, err := b.mutes.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get muted users: %w", err)
+		}
+		m.ChatID = to
+		if err := b.mutes.Put(m); err != nil {
+			return fmt.Errorf("failed to put muted users: %w", err)
+		}
+		if err := b.mutes.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove muted users: %w", err)
+		}
+	}
+	if b.reminders != nil && b.reminders.store != nil {
+		r, err := b.reminders.store.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get reminder settings: %w", err)
+		}
+		r.ChatID = to
+		if err := b.reminders.store.Put(r); err != nil {
+			return fmt.Errorf("failed to put reminder settings: %w", err)
+		}
+		if err := b.reminders.store.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove reminder settings: %w", err)
+		}
+	}
+	if b.previews != nil {
+		p, err := b.previews.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get link preview settings: %w", err)
+		}
+		p.ChatID = to
+		if err := b.previews.Put(p); err != nil {
+			return fmt.Errorf("failed to put link preview settings: %w", err)
+		}
+		if err := b.previews.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove link preview settings: %w", err)
+		}
+	}
+	if b.chatModes != nil {
+		m, err := b.chatModes.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get chat mode: %w", err)
+		}
+		m.ChatID = to
+		if err := b.chatModes.Put(m); err != nil {
+			return fmt.Errorf("failed to put chat mode: %w", err)
+		}
+		if err := b.chatModes.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove chat mode: %w", err)
+		}
+	}
+	if b.voice != nil {
+		v, err := b.voice.store.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get voice message settings: %w", err)
+		}
+		v.ChatID = to
+		if err := b.voice.store.Put(v); err != nil {
+			return fmt.Errorf("failed to put voice message settings: %w", err)
+		}
+		if err := b.voice.store.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove voice message settings: %w", err)
+		}
+	}
+	if b.clusters != nil {
+		c, err := b.clusters.store.Get(from)
+		if err != nil {
+			return fmt.Errorf("failed to get selected clusters: %w", err)
+		}
+		c.ChatID = to
+		if err := b.clusters.store.Put(c); err != nil {
+			return fmt.Errorf("failed to put selected clusters: %w", err)
+		}
+		if err := b.clusters.store.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove selected clusters: %w", err)
+		}
+	}
+
+	if b.alerts != nil {
+		alerts, err := b.alerts.List()
+		if err != nil {
+			return fmt.Errorf("failed to list alerts: %w", err)
+		}
+		for _, a := range alerts {
+			if a.ChatID != from {
+				continue
+			}
+			a.ChatID = to
+			if err := b.alerts.Put(a); err != nil {
+				return fmt.Errorf("failed to put alert: %w", err)
+			}
+			if err := b.alerts.Remove(from, a.Fingerprint); err != nil {
+				return fmt.Errorf("failed to remove alert: %w", err)
+			}
+		}
+	}
+	if b.history != nil {
+		entries, err := b.history.List()
+		if err != nil {
+			return fmt.Errorf("failed to list history: %w", err)
+		}
+		for _, e := range entries {
+			if e.ChatID != from {
+				continue
+			}
+			e.ChatID = to
+			if err := b.history.Put(e); err != nil {
+				return fmt.Errorf("failed to put history entry: %w", err)
+			}
+			if err := b.history.Remove(from, e.Fingerprint, e.StartsAt); err != nil {
+				return fmt.Errorf("failed to remove history entry: %w", err)
+			}
+		}
+	}
+	if b.incidents != nil {
+		i, err := b.incidents.Get(from)
+		if err != nil && !errors.Is(err, IncidentNotFoundErr) {
+			return fmt.Errorf("failed to get incident: %w", err)
+		}
+		if err == nil {
+			i.ChatID = to
+			if err := b.incidents.Put(i); err != nil {
+				return fmt.Errorf("failed to put incident: %w", err)
+			}
+			if err := b.incidents.Remove(from); err != nil {
+				return fmt.Errorf("failed to remove incident: %w", err)
+			}
+		}
+	}
+	if b.deliveries != nil {
+		deliveries, err := b.deliveries.List()
+		if err != nil {
+			return fmt.Errorf("failed to list deliveries: %w", err)
+		}
+		for _, d := range deliveries {
+			if d.ChatID != from {
+				continue
+			}
+			migrated := *d
+			migrated.ChatID = to
+			if err := b.deliveries.Put(&migrated); err != nil {
+				return fmt.Errorf("failed to put delivery: %w", err)
+			}
+			if err := b.deliveries.Remove(d); err != nil {
+				return fmt.Errorf("failed to remove delivery: %w", err)
+			}
+		}
+	}
+	if b.idempotent != nil {
+		records, err := b.idempotent.store.List()
+		if err != nil {
+			return fmt.Errorf("failed to list idempotency records: %w", err)
+		}
+		for _, r := range records {
+			if r.ChatID != from {
+				continue
+			}
+			migrated := *r
+			migrated.ChatID = to
+			if err := b.idempotent.store.Put(&migrated); err != nil {
+				return fmt.Errorf("failed to put idempotency record: %w", err)
+			}
+			if err := b.idempotent.store.Remove(r); err != nil {
+				return fmt.Errorf("failed to remove idempotency record: %w", err)
+			}
+		}
+	}
+
+	if b.groupStore != nil {
+		groups, err := b.groupStore.List()
+		if err != nil {
+			return fmt.Errorf("failed to list chat groups: %w", err)
+		}
+		for _, g := range groups {
+			if !g.has(from) {
+				continue
+			}
+			for i, id := range g.ChatIDs {
+				if id == from {
+					g.ChatIDs[i] = to
+				}
+			}
+			if err := b.groupStore.Put(g); err != nil {
+				return fmt.Errorf("failed to put chat group: %w", err)
+			}
+		}
+	}
+	return nil
+}
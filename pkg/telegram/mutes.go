@@ -0,0 +1,245 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// mentionsAnnotation lists the Telegram usernames to mention in group chats, e.g. "@alice @bob".
+const mentionsAnnotation = "mentions"
+
+const responseMuteUsage = "Usage:\n" +
+	CommandMute + " me - Stop being mentioned in the alerts of this chat.\n" +
+	CommandUnmute + " me - Be mentioned in the alerts of this chat again."
+
+// ChatMutes are the users of a group chat that don't want to be mentioned in its alerts.
+type ChatMutes struct {
+	ChatID    int64    `json:"chatID"`
+	Usernames []string `json:"usernames"`
+}
+
+// has returns whether the user opted out of being mentioned.
+func (m *ChatMutes) has(username string) bool {
+	for _, u := range m.Usernames {
+		if strings.EqualFold(u, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// BotMuteStore keeps the users of group chats that opted out of being mentioned.
+type BotMuteStore interface {
+	List() ([]*ChatMutes, error)
+	Get(chatID int64) (*ChatMutes, error)
+	Put(*ChatMutes) error
+	Remove(chatID int64) error
+}
+
+// MuteStore writes the opted out users to a libkv store backend.
+type MuteStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewMuteStore stores the opted out users in the provided kv backend.
+func NewMuteStore(kv store.Store, storeKeyPrefix string) (*MuteStore, error) {
+	return &MuteStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List the opted out users of all chats saved in the kv backend.
+func (s *MuteStore) List() ([]*ChatMutes, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var mutes []*ChatMutes
+	for _, kv := range kvPairs {
+		var m *ChatMutes
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, m)
+	}
+	return mutes, nil
+}
+
+// Get the opted out users of a chat, which are empty if no one opted out yet.
+func (s *MuteStore) Get(chatID int64) (*ChatMutes, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatMutes{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var m *ChatMutes
+	err = json.Unmarshal(kv.Value, &m)
+	return m, err
+}
+
+// Put the opted out users of a chat into the kv backend.
+func (s *MuteStore) Put(m *ChatMutes) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, m.ChatID), b, nil)
+}
+
+// Remove the opted out users of a chat from the kv backend.
+func (s *MuteStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithMentions mentions the users listed in the mentions annotation of alerts sent to group chats.
+// Members of a group can opt out of being mentioned in it with the mute command.
+func WithMentions(mutes BotMuteStore) BotOption {
+	return func(b *Bot) error {
+		b.mutes = mutes
+		return nil
+	}
+}
+
+// isGroupChat returns whether the chat has several members.
+func isGroupChat(chat *telebot.Chat) bool {
+	return chat.Type == telebot.ChatGroup || chat.Type == telebot.ChatSuperGroup
+}
+
+// extractMentions returns a copy of the alerts without the mentions annotation
+// and the mentions of all users that didn't opt out of being mentioned in the chat.
+func (b *Bot) extractMentions(chat *telebot.Chat, alerts template.Alerts) (template.Alerts, string) {
+	if b.mutes == nil {
+		return alerts, ""
+	}
+
+	var usernames []string
+	seen := map[string]bool{}
+	extracted := make(template.Alerts, 0, len(alerts))
+	for _, a := range alerts {
+		annotations := make(template.KV, len(a.Annotations))
+		for name, value := range a.Annotations {
+			if name != mentionsAnnotation {
+				annotations[name] = value
+				continue
+			}
+			for _, u := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+				u = strings.TrimPrefix(u, "@")
+				if u != "" && !seen[strings.ToLower(u)] {
+					seen[strings.ToLower(u)] = true
+					usernames = append(usernames, u)
+				}
+			}
+		}
+		a.Annotations = annotations
+		extracted = append(extracted, a)
+	}
+
+	if len(usernames) == 0 || !isGroupChat(chat) {
+		return extracted, ""
+	}
+
+	mutes, err := b.mutes.Get(chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get muted users", "chat_id", chat.ID, "err", err)
+		mutes = &ChatMutes{}
+	}
+
+	var mentions []string
+	for _, u := range usernames {
+		if !mutes.has(u) {
+			mentions = append(mentions, "@"+u)
+		}
+	}
+	return extracted, strings.Join(mentions, " ")
+}
+
+func (b *Bot) handleMute(message *telebot.Message) error {
+	return b.setMuted(message, true)
+}
+
+func (b *Bot) handleUnmute(message *telebot.Message) error {
+	return b.setMuted(message, false)
+}
+
+// setMuted opts the sender out of or back into being mentioned in the group chat's alerts.
+func (b *Bot) setMuted(message *telebot.Message, muted bool) error {
+	if b.mutes == nil {
+		_, err := b.telegram.Send(message.Chat, "Mentions aren't enabled.")
+		return err
+	}
+	if strings.TrimSpace(message.Payload) != "me" {
+		_, err := b.telegram.Send(message.Chat, responseMuteUsage)
+		return err
+	}
+	if !isGroupChat(message.Chat) {
+		_, err := b.telegram.Send(message.Chat, "You're only mentioned in group chats.")
+		return err
+	}
+	if message.Sender.Username == "" {
+		_, err := b.telegram.Send(message.Chat, "You can't be mentioned without a Telegram username.")
+		return err
+	}
+	if _, err := b.chats.Get(telebot.ChatID(message.Chat.ID)); err != nil {
+		_, err = b.telegram.Send(message.Chat, "This chat isn't subscribed.")
+		return err
+	}
+
+	mutes, err := b.mutes.Get(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get muted users", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change your mentions.")
+		return err
+	}
+
+	username := message.Sender.Username
+	if mutes.has(username) == muted {
+		if muted {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%s, you already aren't mentioned in this chat.", message.Sender.FirstName))
+		} else {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%s, you already are mentioned in this chat.", message.Sender.FirstName))
+		}
+		return err
+	}
+
+	if muted {
+		mutes.Usernames = append(mutes.Usernames, username)
+	} else {
+		usernames := mutes.Usernames[:0]
+		for _, u := range mutes.Usernames {
+			if !strings.EqualFold(u, username) {
+				usernames = append(usernames, u)
+			}
+		}
+		mutes.Usernames = usernames
+	}
+
+	if err := b.mutes.Put(mutes); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put muted users", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change your mentions.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "user changed mentions", "chat_id", message.Chat.ID, "username", username, "muted", muted)
+	if muted {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Alright, %s! I won't mention you in this chat anymore.", message.Sender.FirstName))
+	} else {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Alright, %s! I will mention you in this chat again.", message.Sender.FirstName))
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/openapi"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// apiErrorBody is the body of the HTTP API's error responses.
+type apiErrorBody struct {
+	Error string `json:"error"`
+}
+
+// OpenAPI returns the OpenAPI document of the webhook and admin endpoints of the tenants' bots,
+// so that clients for automation around the bot can be generated.
+func OpenAPI(version string, bots map[string]*Bot) *openapi.Document {
+	var tenants []string
+	for tenant := range bots {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	d := openapi.New(openapi.Info{
+		Title:       "alertmanager-bot",
+		Description: "Alertmanager webhooks and the admin endpoints of alertmanager-bot. The admin endpoints need the --admin.token as bearer token.",
+		Version:     version,
+	})
+	d.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"adminToken": {Type: "http", Scheme: "bearer", Description: "The --admin.token"},
+	}
+	d.Security = []openapi.SecurityRequirement{{"adminToken": {}}}
+
+	errorResponse := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(d.Schema(apiErrorBody{}))}
+	}
+	jsonResponse := func(description string, v interface{}) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(d.Schema(v))}
+	}
+	jsonBody := func(v interface{}) *openapi.RequestBody {
+		return &openapi.RequestBody{Required: true, Content: openapi.JSON(d.Schema(v))}
+	}
+	tenant := openapi.Parameter{
+		Name:        "tenant",
+		In:          "query",
+		Description: "The tenant of the bot, telegram by default",
+		Schema:      &openapi.Schema{Type: "string", Enum: tenants},
+	}
+	count := openapi.Parameter{
+		Name:        "count",
+		In:          "query",
+		Description: "The number of the most recent webhooks, 1 by default",
+		Schema:      &openapi.Schema{Type: "integer", Format: "int32"},
+	}
+	chatID := openapi.Parameter{Name: "id", In: "path", Description: "The chat's ID", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
+	tenantNotFound := errorResponse("The tenant doesn't exist")
+
+	d.Add(http.MethodPost, "/webhooks/{tenant}/{target}", &openapi.Operation{
+		Summary:     "Receive an Alertmanager webhook",
+		Description: "Sends the alerts to a chat by its ID or to the chats of a chat group by its name.",
+		OperationID: "receiveWebhook",
+		Tags:        []string{"webhooks"},
+		Parameters: []openapi.Parameter{
+			{Name: "tenant", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Enum: tenants}},
+			{Name: "target", In: "path", Description: "A chat ID or the name of a chat group", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		RequestBody: jsonBody(webhook.Message{}),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The alerts are sent"},
+			"400": errorResponse("The target or the webhook is invalid"),
+		},
+		Security: &[]openapi.SecurityRequirement{},
+	})
+
+	d.Add(http.MethodPost, "/-/replay", &openapi.Operation{
+		Summary:     "Replay the most recent webhooks",
+		OperationID: "replayWebhooks",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{tenant, count},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The number of replayed webhooks", struct {
+				Replayed int `json:"replayed"`
+			}{}),
+			"400": errorResponse("The count is invalid"),
+			"404": tenantNotFound,
+			"409": errorResponse("Recording webhooks isn't enabled"),
+		},
+	})
+	d.Add(http.MethodGet, "/-/webhooks", &openapi.Operation{
+		Summary:     "List the most recent webhooks",
+		OperationID: "listWebhooks",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{tenant, count},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The webhooks", []ReceivedWebhook{}),
+			"400": errorResponse("The count is invalid"),
+			"404": tenantNotFound,
+			"409": errorResponse("Recording webhooks isn't enabled"),
+		},
+	})
+	d.Add(http.MethodGet, "/-/deliveries", &openapi.Operation{
+		Summary:     "List the deliveries of alert groups",
+		OperationID: "listDeliveries",
+		Tags:        []string{"deliveries"},
+		Parameters: []openapi.Parameter{
+			tenant,
+			{Name: "groupKey", In: "query", Description: "Only the deliveries of the alert group", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The deliveries", []Delivery{}),
+			"404": tenantNotFound,
+			"409": errorResponse("Tracking deliveries isn't enabled"),
+		},
+	})
+	d.Add(http.MethodGet, "/-/deeplink", &openapi.Operation{
+		Summary:     "Create a deep link to acknowledge or silence an alert",
+		OperationID: "createDeepLink",
+		Tags:        []string{"deeplinks"},
+		Parameters: []openapi.Parameter{
+			tenant,
+			{Name: "action", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{DeepLinkAck, DeepLinkSilence}}},
+			{Name: "fingerprint", In: "query", Description: "The alert's fingerprint", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The deep link", struct {
+				URL string `json:"url"`
+			}{}),
+			"400": errorResponse("Deep links aren't enabled or the action or fingerprint is invalid"),
+			"404": tenantNotFound,
+		},
+	})
+	d.Add(http.MethodPost, "/-/reload", &openapi.Operation{
+		Summary:     "Reload the configuration",
+		Description: "Reads the template files again and applies them as a new configuration version if they changed.",
+		OperationID: "reloadConfig",
+		Tags:        []string{"config"},
+		Parameters:  []openapi.Parameter{tenant},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The applied configuration version", ReloadResult{}),
+			"404": tenantNotFound,
+			"409": errorResponse("Reloading the configuration isn't enabled"),
+			"500": errorResponse("The configuration can't be read or applied"),
+		},
+	})
+
+	d.Add(http.MethodGet, "/api/v1/chats", &openapi.Operation{
+		Summary:     "List the subscribed chats",
+		OperationID: "listChats",
+		Tags:        []string{"chats"},
+		Parameters:  []openapi.Parameter{tenant},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The chats", []telebot.Chat{}),
+			"404": tenantNotFound,
+		},
+	})
+	d.Add(http.MethodPost, "/api/v1/chats", &openapi.Operation{
+		Summary:     "Subscribe a chat",
+		Description: "Chats without a type are private chats, or groups if their ID is negative.",
+		OperationID: "addChat",
+		Tags:        []string{"chats"},
+		Parameters:  []openapi.Parameter{tenant},
+		RequestBody: jsonBody(telebot.Chat{}),
+		Responses: map[string]openapi.Response{
+			"201": jsonResponse("The subscribed chat", telebot.Chat{}),
+			"400": errorResponse("The chat is invalid"),
+			"404": tenantNotFound,
+		},
+	})
+	d.Add(http.MethodDelete, "/api/v1/chats/{id}", &openapi.Operation{
+		Summary:     "Unsubscribe a chat and remove its filter",
+		OperationID: "removeChat",
+		Tags:        []string{"chats"},
+		Parameters:  []openapi.Parameter{tenant, chatID},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The chat is unsubscribed"},
+			"400": errorResponse("The chat ID is invalid"),
+			"404": errorResponse("The tenant or chat doesn't exist"),
+		},
+	})
+	d.Add(http.MethodDelete, "/api/v1/chats/{id}/data", &openapi.Operation{
+		Summary:     "Remove everything stored about a chat",
+		Description: "Removes the chat's subscription, settings, alerts, history, incident, deliveries and received webhooks and removes it from the chat groups. The name of a private chat's user is removed from the alerts they acknowledged.",
+		OperationID: "purgeChat",
+		Tags:        []string{"chats"},
+		Parameters:  []openapi.Parameter{tenant, chatID},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("How much of the chat's data was removed", PurgeResult{}),
+			"400": errorResponse("The chat ID is invalid"),
+			"404": tenantNotFound,
+		},
+	})
+
+	filtersDisabled := errorResponse("Filters aren't enabled")
+	d.Add(http.MethodGet, "/api/v1/filters", &openapi.Operation{
+		Summary:     "List the chats' filters",
+		OperationID: "listFilters",
+		Tags:        []string{"filters"},
+		Parameters:  []openapi.Parameter{tenant},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The filters", []ChatFilter{}),
+			"404": tenantNotFound,
+			"409": filtersDisabled,
+		},
+	})
+	d.Add(http.MethodPut, "/api/v1/filters/{id}", &openapi.Operation{
+		Summary:     "Only send alerts matching all matchers to a chat",
+		Description: "The chat ID of the path overrides the one of the filter.",
+		OperationID: "putFilter",
+		Tags:        []string{"filters"},
+		Parameters:  []openapi.Parameter{tenant, chatID},
+		RequestBody: jsonBody(ChatFilter{}),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The filter", ChatFilter{}),
+			"400": errorResponse("The chat ID or a matcher is invalid"),
+			"404": tenantNotFound,
+			"409": filtersDisabled,
+		},
+	})
+	d.Add(http.MethodDelete, "/api/v1/filters/{id}", &openapi.Operation{
+		Summary:     "Send all alerts to a chat again",
+		OperationID: "removeFilter",
+		Tags:        []string{"filters"},
+		Parameters:  []openapi.Parameter{tenant, chatID},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The filter is removed"},
+			"400": errorResponse("The chat ID is invalid"),
+			"404": tenantNotFound,
+			"409": filtersDisabled,
+		},
+	})
+
+	d.Add(http.MethodPost, "/api/v1/broadcast", &openapi.Operation{
+		Summary:     "Send a message to chats",
+		Description: "Sends the message to the listed chats, the chats of a group or all subscribed chats.",
+		OperationID: "broadcast",
+		Tags:        []string{"chats"},
+		Parameters:  []openapi.Parameter{tenant},
+		RequestBody: jsonBody(Broadcast{}),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("To how many chats the message was sent and which ones failed", BroadcastResult{}),
+			"400": errorResponse("The broadcast is invalid"),
+			"404": errorResponse("The tenant or chat group doesn't exist"),
+		},
+	})
+
+	return d
+}
+
+// HandleOpenAPI returns a HandlerFunc serving the OpenAPI document of the HTTP API, e.g. GET /api/openapi.json.
+func HandleOpenAPI(version string, bots map[string]*Bot) http.HandlerFunc {
+	doc, err := json.MarshalIndent(OpenAPI(version, bots), "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		_, _ = w.Write(doc)
+	}
+}
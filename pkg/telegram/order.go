@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Keys alerts can be sorted by, see WithAlertOrder.
+const (
+	SortBySeverity  = "severity"
+	SortByStartsAt  = "startsAt"
+	SortByAlertname = "alertname"
+)
+
+// alertOrder sorts and groups the alerts of a message before they're rendered.
+type alertOrder struct {
+	sortBy []string
+	// severities ranks the severities, the first one is sorted first, unknown ones last.
+	severities map[string]int
+	groupBy    string
+}
+
+// WithAlertOrder sorts the alerts of a message by the keys, SortBySeverity with the most severe first
+// as ranked by severities, SortByStartsAt with the oldest first and SortByAlertname alphabetically,
+// instead of keeping the order Alertmanager sent them in.
+// If groupBy isn't empty, the alerts with the same value of that label are rendered together below a heading.
+func WithAlertOrder(sortBy []string, severities []string, groupBy string) BotOption {
+	return func(b *Bot) error {
+		for _, key := range sortBy {
+			if key != SortBySeverity && key != SortByStartsAt && key != SortByAlertname {
+				return fmt.Errorf("unknown sort key %q, must be %s, %s or %s", key, SortBySeverity, SortByStartsAt, SortByAlertname)
+			}
+		}
+		o := &alertOrder{sortBy: sortBy, severities: map[string]int{}, groupBy: groupBy}
+		for i, s := range severities {
+			o.severities[s] = i
+		}
+		b.alertOrder = o
+		return nil
+	}
+}
+
+// severityRank returns the rank of the alert's severity, lower is more severe.
+func (o *alertOrder) severityRank(a template.Alert) int {
+	if rank, ok := o.severities[a.Labels["severity"]]; ok {
+		return rank
+	}
+	return len(o.severities)
+}
+
+// less compares the alerts by the sort keys one after the other.
+func (o *alertOrder) less(a, b template.Alert) bool {
+	for _, key := range o.sortBy {
+		switch key {
+		case SortBySeverity:
+			if ra, rb := o.severityRank(a), o.severityRank(b); ra != rb {
+				return ra < rb
+			}
+		case SortByStartsAt:
+			if !a.StartsAt.Equal(b.StartsAt) {
+				return a.StartsAt.Before(b.StartsAt)
+			}
+		case SortByAlertname:
+			if na, nb := a.Labels["alertname"], b.Labels["alertname"]; na != nb {
+				return na < nb
+			}
+		}
+	}
+	return false
+}
+
+// groups returns the sorted alerts, split into groups by the groupBy label in the order its values first occur.
+// Without groupBy all alerts are a single group.
+func (o *alertOrder) groups(alerts template.Alerts) []template.Alerts {
+	sorted := append(template.Alerts(nil), alerts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return o.less(sorted[i], sorted[j]) })
+	if o.groupBy == "" {
+		return []template.Alerts{sorted}
+	}
+
+	var groups []template.Alerts
+	index := map[string]int{}
+	for _, a := range sorted {
+		value := a.Labels[o.groupBy]
+		i, ok := index[value]
+		if !ok {
+			i = len(groups)
+			index[value] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], a)
+	}
+	return groups
+}
+
+// renderOrdered renders the groups of alerts one after another, each below a heading with the value of the groupBy label.
+func (b *Bot) renderOrdered(alerts template.Alerts, data *template.Data) (string, bool, error) {
+	groups := b.alertOrder.groups(data.Alerts)
+	if b.alertOrder.groupBy == "" {
+		ordered := *data
+		ordered.Alerts = groups[0]
+		return b.renderTemplate(alerts, &ordered)
+	}
+
+	var out strings.Builder
+	disablePreview := false
+	for _, g := range groups {
+		grouped := *data
+		grouped.Alerts = g
+		rendered, disable, err := b.renderTemplate(alerts, &grouped)
+		if err != nil {
+			return "", false, err
+		}
+		disablePreview = disablePreview || disable
+
+		// The labels of the data are escaped already.
+		value := g[0].Labels[b.alertOrder.groupBy]
+		if value == "" {
+			value = "-"
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n\n")
+		}
+		fmt.Fprintf(&out, "<b>%s: %s</b>\n%s", html.EscapeString(b.alertOrder.groupBy), value, strings.TrimSpace(rendered))
+	}
+	return out.String(), disablePreview, nil
+}
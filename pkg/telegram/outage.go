@@ -0,0 +1,382 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// responseOutage is the catch-up summary sent to chats once Telegram is reachable again.
+const responseOutage = "⚠️ Telegram was unreachable for %s, %d alert(s) occurred, %d still firing."
+
+// Outage is a period of time in which Telegram couldn't be reached.
+type Outage struct {
+	Since     time.Time             `json:"since"`
+	LastError string                `json:"lastError"`
+	Chats     map[int64]*OutageChat `json:"chats"`
+}
+
+// OutageChat are the alerts that couldn't be sent to a chat during an outage.
+type OutageChat struct {
+	Alerts int `json:"alerts"`
+	// Firing alerts by fingerprint.
+	Firing map[string]OutageAlert `json:"firing"`
+	// Messages received within the max age, they are sent after the summary.
+	Messages   []OutageMessage `json:"messages,omitempty"`
+	Summarized bool            `json:"summarized,omitempty"`
+}
+
+// OutageAlert is an alert that was still firing when it couldn't be sent.
+type OutageAlert struct {
+	Name     string `json:"name"`
+	Receiver string `json:"receiver"`
+}
+
+// OutageMessage is a webhook message that couldn't be sent.
+type OutageMessage struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Message    webhook.Message `json:"message"`
+}
+
+// BotOutageStore persists an ongoing outage across restarts.
+type BotOutageStore interface {
+	Get() (*Outage, error)
+	Put(*Outage) error
+	Remove() error
+}
+
+// OutageStore writes the ongoing outage to a single key of a libkv store backend.
+type OutageStore struct {
+	kv  store.Store
+	key string
+}
+
+// NewOutageStore stores the ongoing outage in the provided kv backend.
+func NewOutageStore(kv store.Store, key string) (*OutageStore, error) {
+	return &OutageStore{kv: kv, key: key}, nil
+}
+
+// Get the ongoing outage from the kv backend, nil if Telegram is reachable.
+func (s *OutageStore) Get() (*Outage, error) {
+	kv, err := s.kv.Get(s.key)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var o *Outage
+	err = json.Unmarshal(kv.Value, &o)
+	return o, err
+}
+
+// Put the ongoing outage into the kv backend.
+func (s *OutageStore) Put(o *Outage) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key, b, nil)
+}
+
+// Remove the outage from the kv backend once it is over.
+func (s *OutageStore) Remove() error {
+	err := s.kv.Delete(s.key)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// outageBuffer collects the alerts that couldn't be sent while Telegram is unreachable.
+type outageBuffer struct {
+	interval time.Duration
+	store    BotOutageStore
+	maxAge   time.Duration
+
+	mu     sync.Mutex
+	outage *Outage
+}
+
+// WithOutageBuffer stops sending alerts once Telegram is unreachable and instead sends a summary
+// to each chat once it is reachable again, which is checked every interval.
+// If store isn't nil an ongoing outage is persisted across restarts.
+func WithOutageBuffer(interval time.Duration, store BotOutageStore) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return fmt.Errorf("outage interval must be positive")
+		}
+		b.outage = &outageBuffer{interval: interval, store: store, maxAge: b.notifyMaxAge}
+		return nil
+	}
+}
+
+// WithNotifyMaxAge sends the alerts buffered during an outage that are younger than maxAge
+// after the outage's summary, if they didn't resolve in Alertmanager in the meantime.
+// Older alerts are only part of the summary.
+func WithNotifyMaxAge(maxAge time.Duration) BotOption {
+	return func(b *Bot) error {
+		if maxAge < 0 {
+			return fmt.Errorf("notify max age must not be negative")
+		}
+		b.notifyMaxAge = maxAge
+		if b.outage != nil {
+			b.outage.maxAge = maxAge
+		}
+		return nil
+	}
+}
+
+// unreachable returns whether the error is caused by not reaching Telegram at all,
+// as opposed to Telegram responding with an error.
+func unreachable(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// active returns whether there is an ongoing outage.
+func (ob *outageBuffer) active() bool {
+	if ob == nil {
+		return false
+	}
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	return ob.outage != nil
+}
+
+// buffer adds alerts that couldn't be sent to a chat to the outage, starting it if necessary.
+func (ob *outageBuffer) buffer(chatID int64, m webhook.Message, alerts template.Alerts, cause error, now time.Time) (started bool, err error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if ob.outage == nil {
+		ob.outage = &Outage{Since: now, Chats: map[int64]*OutageChat{}}
+		started = true
+	}
+	if cause != nil {
+		ob.outage.LastError = cause.Error()
+	}
+
+	chat, ok := ob.outage.Chats[chatID]
+	if !ok {
+		chat = &OutageChat{Firing: map[string]OutageAlert{}}
+		ob.outage.Chats[chatID] = chat
+	}
+	for _, a := range alerts {
+		chat.Alerts++
+		fingerprint := alertFingerprint(a)
+		if a.Status == "resolved" {
+			delete(chat.Firing, fingerprint)
+			continue
+		}
+		chat.Firing[fingerprint] = OutageAlert{Name: a.Labels["alertname"], Receiver: m.Receiver}
+	}
+	if ob.maxAge > 0 {
+		data := *m.Data
+		data.Alerts = alerts
+		m.Data = &data
+		chat.Messages = append(chat.Messages, OutageMessage{ReceivedAt: now, Message: m})
+	}
+
+	if ob.store == nil {
+		return started, nil
+	}
+	return started, ob.store.Put(ob.outage)
+}
+
+// formatOutage renders the catch-up summary of the alerts a chat missed.
+func formatOutage(o *Outage, chat *OutageChat, now time.Time) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, responseOutage, formatDuration(now.Sub(o.Since)), chat.Alerts, len(chat.Firing))
+
+	names := make([]string, 0, len(chat.Firing))
+	for _, a := range chat.Firing {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&out, "\n🔥 %s", name)
+	}
+	if len(names) > 0 {
+		out.WriteString("\nSee " + CommandAlerts + " for details.")
+	}
+
+	return out.String()
+}
+
+// runOutage loads a persisted outage and periodically tries to send the catch-up summaries.
+func (b *Bot) runOutage(ctx context.Context) error {
+	if b.outage.store != nil {
+		o, err := b.outage.store.Get()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get outage", "err", err)
+		}
+		b.outage.mu.Lock()
+		if o != nil && b.outage.outage == nil {
+			b.outage.outage = o
+		}
+		b.outage.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(b.outage.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.catchUp(ctx); err != nil {
+				if unreachable(err) {
+					level.Debug(b.logger).Log("msg", "telegram is still unreachable", "err", err)
+					continue
+				}
+				level.Warn(b.logger).Log("msg", "failed to send outage summary", "err", err)
+			}
+		}
+	}
+}
+
+// catchUp sends the summary of the outage to every chat that missed alerts, followed by the alerts
+// younger than the max age, and ends the outage once all of them got it.
+// Alerts that resolved in Alertmanager in the meantime are dropped.
+func (b *Bot) catchUp(ctx context.Context) error {
+	b.outage.mu.Lock()
+	defer b.outage.mu.Unlock()
+
+	o := b.outage.outage
+	if o == nil {
+		return nil
+	}
+
+	active := b.activeAlerts(ctx, o)
+
+	chatIDs := make([]int64, 0, len(o.Chats))
+	for chatID := range o.Chats {
+		chatIDs = append(chatIDs, chatID)
+	}
+	sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+
+	now := time.Now()
+	for _, chatID := range chatIDs {
+		if err := b.catchUpChat(ctx, chatID, o.Chats[chatID], o, active, now); err != nil {
+			if b.outage.store != nil {
+				_ = b.outage.store.Put(o)
+			}
+			return err
+		}
+		delete(o.Chats, chatID)
+	}
+
+	level.Info(b.logger).Log("msg", "telegram is reachable again", "duration", now.Sub(o.Since).Round(time.Second))
+
+	b.outage.outage = nil
+	if b.outage.store == nil {
+		return nil
+	}
+	return b.outage.store.Remove()
+}
+
+func (b *Bot) catchUpChat(ctx context.Context, chatID int64, chat *OutageChat, o *Outage, active map[string]map[string]bool, now time.Time) error {
+	for fingerprint, a := range chat.Firing {
+		if resolved(active, a.Receiver, fingerprint) {
+			delete(chat.Firing, fingerprint)
+		}
+	}
+
+	if !chat.Summarized {
+		if _, err := b.telegram.Send(&telebot.Chat{ID: chatID}, formatOutage(o, chat, now)); err != nil {
+			return err
+		}
+		chat.Summarized = true
+	}
+
+	for len(chat.Messages) > 0 {
+		m := chat.Messages[0]
+		if now.Sub(m.ReceivedAt) > b.outage.maxAge {
+			chat.Messages = chat.Messages[1:]
+			continue
+		}
+
+		var alerts template.Alerts
+		for _, a := range m.Message.Alerts {
+			if a.Status != "resolved" && resolved(active, m.Message.Receiver, alertFingerprint(a)) {
+				continue
+			}
+			alerts = append(alerts, a)
+		}
+		if len(alerts) > 0 {
+			d := &Delivery{GroupKey: m.Message.GroupKey, ChatID: chatID, Alerts: len(alerts), At: now}
+			err := b.sendAlerts(d, &telebot.Chat{ID: chatID}, m.Message, alerts)
+			if err != nil && unreachable(err) {
+				return err
+			}
+			if err != nil {
+				level.Warn(b.logger).Log("msg", "failed to send message with alerts", "err", err)
+				d.Status, d.Error = DeliveryFailed, err.Error()
+			}
+			b.recordDelivery(d)
+		}
+		chat.Messages = chat.Messages[1:]
+	}
+
+	return nil
+}
+
+// activeAlerts returns the fingerprints of the alerts firing in Alertmanager by receiver.
+// Receivers whose alerts can't be listed are left out, so that their alerts aren't dropped.
+func (b *Bot) activeAlerts(ctx context.Context, o *Outage) map[string]map[string]bool {
+	active := map[string]map[string]bool{}
+	if b.alertmanager == nil {
+		return active
+	}
+
+	for _, chat := range o.Chats {
+		receivers := map[string]bool{}
+		for _, a := range chat.Firing {
+			receivers[a.Receiver] = true
+		}
+		for _, m := range chat.Messages {
+			receivers[m.Message.Receiver] = true
+		}
+
+		for receiver := range receivers {
+			if _, ok := active[receiver]; ok || receiver == "" {
+				continue
+			}
+			ctx, cancel := b.requestContext(ctx)
+			amAlerts, err := b.alertmanager.ListAlerts(ctx, receiver, false)
+			cancel()
+			if err != nil {
+				level.Warn(b.logger).Log("msg", "failed to list alerts to verify buffered alerts", "receiver", receiver, "err", err)
+				continue
+			}
+			active[receiver] = map[string]bool{}
+			for _, amAlert := range amAlerts {
+				active[receiver][amAlert.Fingerprint().String()] = true
+			}
+		}
+	}
+
+	return active
+}
+
+// resolved returns whether Alertmanager doesn't know the alert as firing anymore.
+func resolved(active map[string]map[string]bool, receiver, fingerprint string) bool {
+	firing, ok := active[receiver]
+	return ok && !firing[fingerprint]
+}
@@ -0,0 +1,242 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseOutputForbidden    = "You aren't allowed to command the bot."
+	responseOutputTelegramOnly = "%s is only available in Telegram, send %s for the commands available here."
+)
+
+// outputCommands are the commands available in the chats of outputs, all that don't manage Telegram chats.
+var outputCommands = map[string]bool{
+	CommandHelp:     true,
+	CommandStatus:   true,
+	CommandAlerts:   true,
+	CommandSilences: true,
+	CommandAck:      true,
+	CommandStats:    true,
+	CommandMyStats:  true,
+	CommandFind:     true,
+	CommandQuery:    true,
+	CommandTargets:  true,
+	CommandRules:    true,
+	CommandRoutes:   true,
+	CommandDelivery: true,
+}
+
+// commandOutput receives the commands sent in the chat of an output.
+type commandOutput struct {
+	commands notify.Commands
+	// chatID is the Telegram chat the commands are handled as if sent in, e.g. /alerts lists its alerts.
+	chatID int64
+	// users may send commands, everyone in the chat if empty.
+	users map[string]bool
+}
+
+// WithOutputCommands handles the commands sent in the chat of the output with the same handlers as in Telegram,
+// as if they were sent in the Telegram chat with the chatID, e.g. /alerts lists the alerts of its receiver.
+// Only the users may send commands, everyone in the chat of the output if there are none.
+// The commands are received by HandleOutputCommands.
+func WithOutputCommands(name string, c notify.Commands, chatID int64, users []string) BotOption {
+	return func(b *Bot) error {
+		if b.commandOutputs == nil {
+			b.commandOutputs = map[string]*commandOutput{}
+		}
+		o := &commandOutput{commands: c, chatID: chatID, users: map[string]bool{}}
+		for _, u := range users {
+			o.users[u] = true
+		}
+		b.commandOutputs[name] = o
+		return nil
+	}
+}
+
+// HandleOutputCommands returns a HandlerFunc that receives the commands sent in the chats of the tenants' outputs,
+// e.g. POST /commands/telegram/webex for the output webex of the bot configured with flags.
+func HandleOutputCommands(logger log.Logger, bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/commands/"), "/")
+		if len(path) != 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		bot, ok := bots[path[0]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		o, ok := bot.commandOutputs[path[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		cmd, ok, err := o.commands.Receive(r)
+		if errors.Is(err, notify.ErrUnauthenticated) {
+			level.Warn(logger).Log("msg", "dropping unauthenticated output command", "tenant", path[0], "output", path[1])
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			level.Warn(logger).Log("msg", "failed to receive output command", "tenant", path[0], "output", path[1], "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		msg := bot.handleOutputCommand(path[1], o, cmd)
+		if err := o.commands.Reply(r.Context(), w, msg); err != nil {
+			level.Warn(logger).Log("msg", "failed to reply to output command", "tenant", path[0], "output", path[1], "err", err)
+		}
+	}
+}
+
+// handleOutputCommand runs the handler of the command with a copy of the bot whose transport collects the replies,
+// which are returned as the message to reply with.
+func (b *Bot) handleOutputCommand(output string, o *commandOutput, cmd notify.Command) notify.Message {
+	fields := strings.SplitN(cmd.Text, " ", 2)
+	command := strings.Split(fields[0], "@")[0]
+	if alias, ok := b.aliases[command]; ok {
+		command = alias
+	}
+	reply := func(out string) notify.Message {
+		return notify.Message{Subject: command, HTML: out, Markdown: htmlToMarkdown(out)}
+	}
+
+	if len(o.users) > 0 && !o.users[cmd.User] {
+		level.Info(b.logger).Log("msg", "dropping output command from forbidden user", "output", output, "user", cmd.User)
+		return reply(responseOutputForbidden)
+	}
+	handler, ok := commandHandlers()[command]
+	if !ok || !outputCommands[command] {
+		return reply(html.EscapeString(fmt.Sprintf(responseOutputTelegramOnly, fields[0], CommandHelp)))
+	}
+
+	b.commandEvents(command)
+	level.Debug(b.logger).Log("msg", "output command received", "output", output, "user", cmd.User, "text", cmd.Text)
+	if command == CommandHelp {
+		return reply(html.EscapeString(outputHelp()))
+	}
+
+	m := &telebot.Message{
+		Text:   cmd.Text,
+		Sender: &telebot.User{Username: cmd.User, FirstName: cmd.User},
+		Chat:   &telebot.Chat{ID: o.chatID},
+	}
+	if len(fields) == 2 {
+		m.Payload = strings.TrimSpace(fields[1])
+	}
+
+	collector := &replyCollector{Telebot: b.telegram}
+	collected := *b
+	collected.telegram = collector
+	if err := handler(&collected, m); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to handle output command", "output", output, "err", err)
+	}
+	return reply(collector.String())
+}
+
+// outputHelp lists the commands available in the chats of outputs.
+func outputHelp() string {
+	var out strings.Builder
+	out.WriteString("The commands available here are:\n")
+	for _, c := range helpCommands() {
+		if outputCommands["/"+c.Text] {
+			fmt.Fprintf(&out, "/%s - %s\n", c.Text, c.Description)
+		}
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// replyCollector collects the texts sent by a handler as HTML instead of sending them to Telegram.
+type replyCollector struct {
+	Telebot
+
+	mu      sync.Mutex
+	replies []string
+}
+
+func (c *replyCollector) Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	text, ok := what.(string)
+	if !ok {
+		// Documents, photos and the like can't be replied with.
+		return &telebot.Message{}, nil
+	}
+	if !sentAsHTML(options) {
+		text = html.EscapeString(text)
+	}
+	c.replies = append(c.replies, strings.TrimSpace(text))
+	return &telebot.Message{ID: len(c.replies), Text: text}, nil
+}
+
+// Edit replaces a collected reply, e.g. a progress message replaced by the result.
+func (c *replyCollector) Edit(msg telebot.Editable, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sent, ok := msg.(*telebot.Message)
+	text, isText := what.(string)
+	if !ok || !isText || sent.ID < 1 || sent.ID > len(c.replies) {
+		return &telebot.Message{}, nil
+	}
+	if !sentAsHTML(options) {
+		text = html.EscapeString(text)
+	}
+	c.replies[sent.ID-1] = strings.TrimSpace(text)
+	return &telebot.Message{ID: sent.ID, Text: text}, nil
+}
+
+func (c *replyCollector) Notify(telebot.Recipient, telebot.ChatAction) error {
+	return nil
+}
+
+func (c *replyCollector) Pin(telebot.Editable, ...interface{}) error {
+	return nil
+}
+
+// String returns the collected replies separated by empty lines.
+func (c *replyCollector) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.Join(c.replies, "\n\n")
+}
+
+// sentAsHTML returns whether the send options parse the text as HTML.
+func sentAsHTML(options []interface{}) bool {
+	for _, o := range options {
+		switch opt := o.(type) {
+		case *telebot.SendOptions:
+			if opt != nil && opt.ParseMode == telebot.ModeHTML {
+				return true
+			}
+		case telebot.ParseMode:
+			if opt == telebot.ModeHTML {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+// Output is where the alerts are sent to in addition to Telegram, e.g. a notify.Webex, notify.Mattermost or notify.Email.
+type Output interface {
+	Notify(ctx context.Context, msg notify.Message) error
+}
+
+type namedOutput struct {
+	name   string
+	output Output
+}
+
+// WithOutput sends the alerts of all webhooks to the output too, rendered with the templates of the bot as HTML and converted to Markdown.
+// It can be passed several times to send to several outputs.
+func WithOutput(name string, o Output) BotOption {
+	return func(b *Bot) error {
+		b.outputs = append(b.outputs, namedOutput{name: name, output: o})
+		return nil
+	}
+}
+
+// sendOutputs renders the alerts of the webhook and sends them to all outputs.
+func (b *Bot) sendOutputs(ctx context.Context, w alertmanager.TelegramWebhook) {
+	m := w.Message
+	alerts := b.filterMaintenance(m.Alerts)
+	if len(alerts) == 0 {
+		return
+	}
+
+	r := b.renderer(w.ChatID)
+	data := &template.Data{
+		Receiver:          m.Receiver,
+		Status:            m.Status,
+		Alerts:            r.labelFilter.filterAlerts(alerts),
+		GroupLabels:       r.labelFilter.filter(m.GroupLabels),
+		CommonLabels:      r.labelFilter.filter(m.CommonLabels),
+		CommonAnnotations: m.CommonAnnotations,
+		ExternalURL:       m.ExternalURL,
+	}
+	out, _, err := r.renderAlerts(alerts, data)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to template alerts for outputs", "err", err)
+		return
+	}
+	out, _ = extractQRCodes(out)
+	out = strings.TrimSpace(out)
+	msg := notify.Message{
+		Subject:  outputSubject(m.Status, alerts),
+		HTML:     out,
+		Markdown: htmlToMarkdown(out),
+	}
+
+	for _, o := range b.outputs {
+		if err := o.output.Notify(ctx, msg); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send alerts to output", "output", o.name, "err", err)
+		}
+	}
+}
+
+// outputSubject summarizes the alerts like Alertmanager's emails, e.g. [FIRING:2] NodeDown, DiskFull.
+func outputSubject(status string, alerts template.Alerts) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, a := range alerts {
+		name := a.Labels[string(model.AlertNameLabel)]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return fmt.Sprintf("[%s:%d] %s", strings.ToUpper(status), len(alerts), strings.Join(names, ", "))
+}
+
+var (
+	htmlLink = regexp.MustCompile(`(?s)<a href="([^"]*)">(.*?)</a>`)
+	htmlTags = strings.NewReplacer(
+		"<b>", "**", "</b>", "**",
+		"<strong>", "**", "</strong>", "**",
+		"<i>", "_", "</i>", "_",
+		"<em>", "_", "</em>", "_",
+		"<s>", "~~", "</s>", "~~",
+		"<u>", "", "</u>", "",
+		"<code>", "`", "</code>", "`",
+		"<pre>", "```\n", "</pre>", "\n```",
+	)
+)
+
+// htmlToMarkdown converts the subset of HTML Telegram supports, that the templates render, to Markdown.
+func htmlToMarkdown(s string) string {
+	s = htmlLink.ReplaceAllString(s, "[$2]($1)")
+	return html.UnescapeString(htmlTags.Replace(s))
+}
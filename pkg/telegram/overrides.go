@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// defaultTemplate renders the alerts of webhooks unless a template override matches them.
+const defaultTemplate = "telegram.default"
+
+// TemplateOverride renders alerts whose labels match all matchers with another template than telegram.default,
+// e.g. a compact one for alertname=KubePodCrashLooping.
+type TemplateOverride struct {
+	// Matchers use the syntax of amtool, e.g. alertname=KubePodCrashLooping or slo=~".+".
+	Matchers []string
+	// Template is the name of a template defined in the template files.
+	Template string
+	// DisablePreview turns off the previews of links in the alerts rendered with the template.
+	DisablePreview bool
+}
+
+type templateOverride struct {
+	matchers       []*alertmanager.Matcher
+	template       string
+	disablePreview bool
+}
+
+// WithTemplateOverrides renders the alerts of a webhook with the template of the first override
+// matching all of them. Alerts no override matches, or whose override fails, are rendered with telegram.default.
+func WithTemplateOverrides(overrides []TemplateOverride) BotOption {
+	return func(b *Bot) error {
+		for i, o := range overrides {
+			if o.Template == "" {
+				return fmt.Errorf("template override %d has no template", i)
+			}
+			if len(o.Matchers) == 0 {
+				return fmt.Errorf("template override %s has no matchers", o.Template)
+			}
+			parsed := templateOverride{template: o.Template, disablePreview: o.DisablePreview}
+			for _, m := range o.Matchers {
+				matcher, err := alertmanager.ParseMatcher(m)
+				if err != nil {
+					return fmt.Errorf("template override %s: %w", o.Template, err)
+				}
+				parsed.matchers = append(parsed.matchers, matcher)
+			}
+			b.overrides = append(b.overrides, parsed)
+		}
+		return nil
+	}
+}
+
+// matches returns whether every alert's labels match all matchers of the override.
+func (o templateOverride) matches(alerts template.Alerts) bool {
+	for _, a := range alerts {
+		for _, m := range o.matchers {
+			if !m.Matches(a.Labels[m.Name]) {
+				return false
+			}
+		}
+	}
+	return len(alerts) > 0
+}
+
+// renderAlerts renders the webhook data as HTML with the template of the first override matching the alerts,
+// falling back to telegram.default. It also returns whether the override turns off link previews.
+func (b *Bot) renderAlerts(alerts template.Alerts, data *template.Data) (string, bool, error) {
+	data = escapeData(telebot.ModeHTML, data)
+	if b.alertOrder != nil {
+		return b.renderOrdered(alerts, data)
+	}
+	return b.renderTemplate(alerts, data)
+}
+
+// renderTemplate renders the escaped data with the first override matching the alerts or the default template.
+func (b *Bot) renderTemplate(alerts template.Alerts, data *template.Data) (string, bool, error) {
+	for _, o := range b.overrides {
+		if !o.matches(alerts) {
+			continue
+		}
+		out, err := b.templates.ExecuteTextString(`{{ template "`+o.template+`" . }}`, data)
+		if err == nil {
+			return out, o.disablePreview, nil
+		}
+		level.Warn(b.logger).Log("msg", "failed to template alerts with override, using default", "template", o.template, "err", err)
+		break
+	}
+	out, err := b.templates.ExecuteTextString(`{{ template "`+defaultTemplate+`" . }}`, data)
+	return out, false, err
+}
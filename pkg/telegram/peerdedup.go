@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+)
+
+// peerNotification is a notification received first from a peer of the Alertmanager cluster.
+type peerNotification struct {
+	peer string
+	at   time.Time
+}
+
+// peerDedup drops notifications that other peers of the Alertmanager cluster sent already.
+type peerDedup struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]peerNotification
+}
+
+// WithPeerDedup drops webhooks of a group with the same alerts and statuses as one received within the window.
+// Every peer of an Alertmanager cluster sends the notifications of a group unless the notification log gossiped
+// by the others shows it was sent already, which fails if the peers are partitioned or gossip is slow.
+// Unlike WithIdempotency, the webhooks are dropped in memory as soon as they're received, before they're kept
+// for replaying or processed, so that duplicates arriving at the same time aren't sent twice either.
+func WithPeerDedup(window time.Duration) BotOption {
+	return func(b *Bot) error {
+		if window <= 0 {
+			return errors.New("the peer deduplication window must be positive")
+		}
+		b.peerDedup = &peerDedup{window: window, seen: map[string]peerNotification{}}
+		return nil
+	}
+}
+
+// notificationKey identifies a notification by its destination, group key and the statuses of its alerts,
+// as the peers' notifications differ in their external URL and the times of the alerts.
+func notificationKey(w alertmanager.TelegramWebhook) string {
+	alerts := make([]string, 0, len(w.Message.Alerts))
+	for _, a := range w.Message.Alerts {
+		alerts = append(alerts, alertFingerprint(a)+"="+a.Status)
+	}
+	sort.Strings(alerts)
+
+	return fmt.Sprintf("%d/%s/%s/%s/%x", w.ChatID, w.Group, w.Message.Receiver, w.Message.Status,
+		sha256.Sum256([]byte(w.Message.GroupKey+"\n"+strings.Join(alerts, ","))))
+}
+
+// duplicate returns the notification received first if the webhook is a duplicate of one received within the window.
+func (d *peerDedup) duplicate(w alertmanager.TelegramWebhook, now time.Time) (peerNotification, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, n := range d.seen {
+		if now.Sub(n.at) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+
+	key := notificationKey(w)
+	if first, ok := d.seen[key]; ok {
+		return first, true
+	}
+	d.seen[key] = peerNotification{peer: w.Message.ExternalURL, at: now}
+	return peerNotification{}, false
+}
+
+// dropPeerDuplicate returns whether the webhook was received from another peer already and should be dropped.
+func (b *Bot) dropPeerDuplicate(w alertmanager.TelegramWebhook) bool {
+	first, ok := b.peerDedup.duplicate(w, time.Now())
+	if !ok {
+		return false
+	}
+	level.Debug(b.logger).Log(
+		"msg", "dropping duplicate webhook of alertmanager peer",
+		"group_key", w.Message.GroupKey,
+		"status", w.Message.Status,
+		"peer", w.Message.ExternalURL,
+		"first_peer", first.peer,
+	)
+	return true
+}
@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Principals that can be allowed to send a command besides roles, user IDs and @usernames.
+const (
+	// PrincipalAdmins are the configured admins.
+	PrincipalAdmins = "admins"
+	// PrincipalAuthorized are the users allowed by the authorizer, see WithAuthorizer.
+	PrincipalAuthorized = "authorized"
+	// PrincipalSubscribed are all members of subscribed chats sending the command in that chat.
+	PrincipalSubscribed = "all-subscribed"
+	// PrincipalEveryone is every Telegram user.
+	PrincipalEveryone = "everyone"
+)
+
+// commandPermissions maps commands to the principals allowed to send them.
+type commandPermissions struct {
+	commands map[string][]string
+	roles    map[string][]string
+}
+
+// WithCommandPermissions sets who is allowed to send a command, instead of only admins and authorized users.
+// Principals are admins, authorized, all-subscribed, everyone, user IDs, @usernames or the name of a role,
+// which itself is a list of principals. Admins are always allowed to send all commands.
+// Commands without permissions keep the default.
+func WithCommandPermissions(commands map[string][]string, roles map[string][]string) BotOption {
+	return func(b *Bot) error {
+		for role, principals := range roles {
+			for _, p := range principals {
+				if _, ok := roles[p]; ok {
+					return fmt.Errorf("role %s can't contain the role %s", role, p)
+				}
+				if err := validatePrincipal(p, nil); err != nil {
+					return fmt.Errorf("role %s: %w", role, err)
+				}
+			}
+		}
+		for command, principals := range commands {
+			if !strings.HasPrefix(command, "/") {
+				return fmt.Errorf("command %q has to start with /", command)
+			}
+			for _, p := range principals {
+				if err := validatePrincipal(p, roles); err != nil {
+					return fmt.Errorf("command %s: %w", command, err)
+				}
+			}
+		}
+		b.permissions = &commandPermissions{commands: commands, roles: roles}
+		return nil
+	}
+}
+
+func validatePrincipal(p string, roles map[string][]string) error {
+	switch p {
+	case PrincipalAdmins, PrincipalAuthorized, PrincipalSubscribed, PrincipalEveryone:
+		return nil
+	}
+	if _, ok := roles[p]; ok {
+		return nil
+	}
+	if strings.HasPrefix(p, "@") && len(p) > 1 {
+		return nil
+	}
+	if _, err := strconv.Atoi(p); err == nil {
+		return nil
+	}
+	return fmt.Errorf("unknown principal %q", p)
+}
+
+// isAllowed returns whether the sender of the message may send the command.
+func (b *Bot) isAllowed(m *telebot.Message, command string) bool {
+	if b.permissions != nil {
+		if principals, ok := b.permissions.commands[command]; ok {
+			return b.isAdminID(m.Sender.ID) || b.matchesAny(m, principals)
+		}
+	}
+	return publicCommands[command] || b.isAuthorized(m.Sender)
+}
+
+// matchesAny returns whether the message's sender is one of the principals.
+func (b *Bot) matchesAny(m *telebot.Message, principals []string) bool {
+	for _, p := range principals {
+		if role, ok := b.permissions.roles[p]; ok {
+			if b.matchesAny(m, role) {
+				return true
+			}
+			continue
+		}
+		if b.matches(m, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) matches(m *telebot.Message, principal string) bool {
+	switch principal {
+	case PrincipalEveryone:
+		return true
+	case PrincipalAdmins:
+		return b.isAdminID(m.Sender.ID)
+	case PrincipalAuthorized:
+		return b.isAuthorized(m.Sender)
+	case PrincipalSubscribed:
+		if m.Chat == nil {
+			return false
+		}
+		_, err := b.chats.Get(telebot.ChatID(m.Chat.ID))
+		return err == nil
+	}
+	if strings.HasPrefix(principal, "@") {
+		return m.Sender.Username != "" && strings.EqualFold(strings.TrimPrefix(principal, "@"), m.Sender.Username)
+	}
+	return principal == strconv.Itoa(m.Sender.ID)
+}
@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responsePreviewsUsage = "Usage: " + CommandPreviews + " on|off"
+
+// ChatPreviews are the link preview settings of a chat.
+type ChatPreviews struct {
+	ChatID int64 `json:"chatID"`
+	Off    bool  `json:"off,omitempty"`
+}
+
+// BotPreviewStore keeps the chats' link preview settings.
+type BotPreviewStore interface {
+	Get(chatID int64) (*ChatPreviews, error)
+	Put(*ChatPreviews) error
+	Remove(chatID int64) error
+}
+
+// PreviewStore writes the chats' link preview settings to a libkv store backend.
+type PreviewStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewPreviewStore stores the chats' link preview settings in the provided kv backend.
+func NewPreviewStore(kv store.Store, storeKeyPrefix string) (*PreviewStore, error) {
+	return &PreviewStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the link preview settings of a chat, previews are on if the chat never changed them.
+func (s *PreviewStore) Get(chatID int64) (*ChatPreviews, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatPreviews{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var p *ChatPreviews
+	err = json.Unmarshal(kv.Value, &p)
+	return p, err
+}
+
+// Put the link preview settings of a chat into the kv backend.
+func (s *PreviewStore) Put(p *ChatPreviews) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, p.ChatID), b, nil)
+}
+
+// Remove the link preview settings of a chat from the kv backend.
+func (s *PreviewStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// WithLinkPreviews lets chats turn off the previews Telegram shows for links in alerts,
+// e.g. of generator URLs and runbooks, which can bury the alert's text.
+func WithLinkPreviews(previews BotPreviewStore) BotOption {
+	return func(b *Bot) error {
+		b.previews = previews
+		return nil
+	}
+}
+
+// previewsOff returns whether the chat turned off link previews.
+func (b *Bot) previewsOff(chatID int64) bool {
+	if b.previews == nil {
+		return false
+	}
+	p, err := b.previews.Get(chatID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get link previews", "chat_id", chatID, "err", err)
+		return false
+	}
+	return p.Off
+}
+
+func (b *Bot) handlePreviews(message *telebot.Message) error {
+	if b.previews == nil {
+		_, err := b.telegram.Send(message.Chat, "Changing link previews isn't enabled.")
+		return err
+	}
+	if _, err := b.chats.Get(telebot.ChatID(message.Chat.ID)); err != nil {
+		_, err = b.telegram.Send(message.Chat, "This chat isn't subscribed.")
+		return err
+	}
+
+	p, err := b.previews.Get(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get link previews", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the link previews of this chat.")
+		return err
+	}
+
+	switch strings.TrimSpace(message.Payload) {
+	case "":
+		state := "on"
+		if p.Off {
+			state = "off"
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Link previews are %s for this chat.", state))
+		return err
+	case "on":
+		p.Off = false
+	case "off":
+		p.Off = true
+	default:
+		_, err = b.telegram.Send(message.Chat, responsePreviewsUsage)
+		return err
+	}
+
+	if err := b.previews.Put(p); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put link previews", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the link previews of this chat.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "link previews changed", "chat_id", message.Chat.ID, "off", p.Off)
+
+	if p.Off {
+		_, err = b.telegram.Send(message.Chat, "Link previews are now off for this chat.")
+	} else {
+		_, err = b.telegram.Send(message.Chat, "Link previews are now on for this chat.")
+	}
+	return err
+}
@@ -0,0 +1,354 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseForgetMe = "This removes your subscription, settings and the history of alerts sent to you, " +
+	"and your name from the alerts you acknowledged, the incidents you opened and the groups you muted. It can't be undone."
+
+// forgetMeButton confirms removing the data of the user who sent the forgetme command.
+var forgetMeButton = telebot.InlineButton{Unique: "forgetme", Text: "Forget me"}
+
+// PurgeResult is how much of the data stored about a chat and its user was removed.
+type PurgeResult struct {
+	ChatID int64 `json:"chatID"`
+	// Subscribed is whether the chat was subscribed.
+	Subscribed bool `json:"subscribed"`
+	Alerts     int  `json:"alerts"`
+	History    int  `json:"history"`
+	Incidents  int  `json:"incidents"`
+	Deliveries int  `json:"deliveries"`
+	Groups     int  `json:"groups"`
+	Webhooks   int  `json:"webhooks"`
+	// Acks is the number of alerts of other chats the user's name was removed from.
+	Acks int `json:"acks"`
+	// Mutes is the number of group chats the user's name was removed from the muted users of.
+	Mutes int `json:"mutes"`
+	// Migrations is the number of chat migrations from or to the chat that were removed.
+	Migrations int `json:"migrations"`
+}
+
+// Purge removes everything stored about the chat: its subscription, settings, alerts, history,
+// incident, deliveries, migrations and buffered webhooks, and removes it from the stored chat groups.
+// If username isn't empty the user's name is removed from the alerts they acknowledged and the incidents
+// they opened in other chats, and from the users muted in groups.
+// Bans are kept, so that a banned user can't get rid of the ban.
+func (b *Bot) Purge(chatID int64, username string) (PurgeResult, error) {
+	result := PurgeResult{ChatID: chatID}
+
+	chat, err := b.chats.Get(telebot.ChatID(chatID))
+	if err != nil && !errors.Is(err, ChatNotFoundErr) {
+		return result, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err == nil {
+		if err := b.chats.Remove(chat); err != nil {
+			return result, fmt.Errorf("failed to remove chat: %w", err)
+		}
+		result.Subscribed = true
+	}
+
+	if b.filters != nil {
+		if err := b.filters.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove chat filter: %w", err)
+		}
+	}
+	if b.mutes != nil {
+		if err := b.mutes.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove muted users: %w", err)
+		}
+		if username != "" {
+			n, err := b.purgeMutes(username)
+			if err != nil {
+				return result, err
+			}
+			result.Mutes = n
+		}
+	}
+	if b.reminders != nil && b.reminders.store != nil {
+		if err := b.reminders.store.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove reminder settings: %w", err)
+		}
+	}
+	if b.previews != nil {
+		if err := b.previews.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove link preview settings: %w", err)
+		}
+	}
+	if b.chatModes != nil {
+		if err := b.chatModes.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove chat mode: %w", err)
+		}
+	}
+	if b.archive != nil {
+		if err := b.archive.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove archived chat: %w", err)
+		}
+	}
+	if b.voice != nil {
+		if err := b.voice.store.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove voice message settings: %w", err)
+		}
+	}
+	if b.clusters != nil {
+		if err := b.clusters.store.Remove(chatID); err != nil {
+			return result, fmt.Errorf("failed to remove selected clusters: %w", err)
+		}
+	}
+
+	if b.alerts != nil {
+		alerts, err := b.alerts.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list alerts: %w", err)
+		}
+		for _, a := range alerts {
+			if a.ChatID == chatID {
+				if err := b.alerts.Remove(a.ChatID, a.Fingerprint); err != nil {
+					return result, fmt.Errorf("failed to remove alert: %w", err)
+				}
+				result.Alerts++
+				continue
+			}
+			if username != "" && strings.EqualFold(a.AckedBy, username) {
+				a.AckedBy = ""
+				if err := b.alerts.Put(a); err != nil {
+					return result, fmt.Errorf("failed to put alert: %w", err)
+				}
+				result.Acks++
+			}
+		}
+	}
+
+	if b.history != nil {
+		entries, err := b.history.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list history: %w", err)
+		}
+		for _, e := range entries {
+			if e.ChatID != chatID {
+				continue
+			}
+			if err := b.history.Remove(e.ChatID, e.Fingerprint, e.StartsAt); err != nil {
+				return result, fmt.Errorf("failed to remove history entry: %w", err)
+			}
+			result.History++
+		}
+	}
+
+	if b.incidents != nil {
+		incidents, err := b.incidents.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list incidents: %w", err)
+		}
+		for _, i := range incidents {
+			if i.ChatID == chatID {
+				if err := b.incidents.Remove(chatID); err != nil {
+					return result, fmt.Errorf("failed to remove incident: %w", err)
+				}
+				result.Incidents++
+				continue
+			}
+			if username != "" && strings.EqualFold(i.CreatedBy, username) {
+				i.CreatedBy = ""
+				if err := b.incidents.Put(i); err != nil {
+					return result, fmt.Errorf("failed to put incident: %w", err)
+				}
+				if err := b.editIncident(i); err != nil {
+					level.Warn(b.logger).Log("msg", "failed to edit incident summary", "chat_id", i.ChatID, "err", err)
+				}
+			}
+		}
+	}
+
+	if b.deliveries != nil {
+		deliveries, err := b.deliveries.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list deliveries: %w", err)
+		}
+		for _, d := range deliveries {
+			if d.ChatID != chatID {
+				continue
+			}
+			if err := b.deliveries.Remove(d); err != nil {
+				return result, fmt.Errorf("failed to remove delivery: %w", err)
+			}
+			result.Deliveries++
+		}
+	}
+	if b.idempotent != nil {
+		records, err := b.idempotent.store.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list idempotency records: %w", err)
+		}
+		for _, r := range records {
+			if r.ChatID != chatID {
+				continue
+			}
+			if err := b.idempotent.store.Remove(r); err != nil {
+				return result, fmt.Errorf("failed to remove idempotency record: %w", err)
+			}
+		}
+	}
+	if b.handled != nil {
+		handled, err := b.handled.store.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list handled commands: %w", err)
+		}
+		for _, h := range handled {
+			if h.ChatID != chatID {
+				continue
+			}
+			if err := b.handled.store.Remove(h); err != nil {
+				return result, fmt.Errorf("failed to remove handled command: %w", err)
+			}
+		}
+	}
+
+	if b.migrations != nil {
+		migrations, err := b.migrations.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list chat migrations: %w", err)
+		}
+		for _, m := range migrations {
+			if m.From != chatID && m.To != chatID {
+				continue
+			}
+			if err := b.migrations.Remove(m.From); err != nil {
+				return result, fmt.Errorf("failed to remove chat migration: %w", err)
+			}
+			result.Migrations++
+		}
+	}
+
+	if b.groupStore != nil {
+		groups, err := b.groupStore.List()
+		if err != nil {
+			return result, fmt.Errorf("failed to list chat groups: %w", err)
+		}
+		for _, g := range groups {
+			if !g.has(chatID) {
+				continue
+			}
+			chatIDs := g.ChatIDs[:0]
+			for _, id := range g.ChatIDs {
+				if id != chatID {
+					chatIDs = append(chatIDs, id)
+				}
+			}
+			g.ChatIDs = chatIDs
+			if len(g.ChatIDs) == 0 {
+				err = b.groupStore.Remove(g.Name)
+			} else {
+				err = b.groupStore.Put(g)
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed to put chat group: %w", err)
+			}
+			result.Groups++
+		}
+	}
+
+	if b.webhooks != nil {
+		n, err := b.webhooks.forget(chatID)
+		if err != nil {
+			return result, fmt.Errorf("failed to remove received webhooks: %w", err)
+		}
+		result.Webhooks = n
+	}
+
+	return result, nil
+}
+
+// purgeMutes removes the user's name from the muted users of all group chats and returns in how many it was.
+func (b *Bot) purgeMutes(username string) (int, error) {
+	mutes, err := b.mutes.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list muted users: %w", err)
+	}
+	n := 0
+	for _, m := range mutes {
+		if !m.has(username) {
+			continue
+		}
+		usernames := m.Usernames[:0]
+		for _, u := range m.Usernames {
+			if !strings.EqualFold(u, username) {
+				usernames = append(usernames, u)
+			}
+		}
+		m.Usernames = usernames
+		if len(m.Usernames) == 0 {
+			err = b.mutes.Remove(m.ChatID)
+		} else {
+			err = b.mutes.Put(m)
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to put muted users: %w", err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// logPurge writes the audit log of a purge and who requested it.
+func logPurge(logger log.Logger, result PurgeResult, requestedBy string) {
+	level.Info(logger).Log(
+		"msg", "chat data purged",
+		"chat_id", result.ChatID,
+		"requested_by", requestedBy,
+		"subscribed", result.Subscribed,
+		"alerts", result.Alerts,
+		"history", result.History,
+		"incidents", result.Incidents,
+		"deliveries", result.Deliveries,
+		"groups", result.Groups,
+		"webhooks", result.Webhooks,
+		"acks", result.Acks,
+		"mutes", result.Mutes,
+		"migrations", result.Migrations,
+	)
+}
+
+// handleForgetMe asks the sender to confirm removing their data.
+func (b *Bot) handleForgetMe(message *telebot.Message) error {
+	button := forgetMeButton
+	button.Data = strconv.Itoa(message.Sender.ID)
+	_, err := b.telegram.Send(message.Chat, responseForgetMe, &telebot.SendOptions{
+		ReplyMarkup: &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{button}}},
+	})
+	return err
+}
+
+// handleForgetMeConfirm removes the data of the user's private chat and their name, if they sent the command.
+func (b *Bot) handleForgetMeConfirm(c *telebot.Callback) {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+	if c.Data != strconv.Itoa(c.Sender.ID) {
+		level.Info(b.logger).Log(
+			"msg", "dropping forgetme confirmation of another user",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return
+	}
+
+	result, err := b.Purge(int64(c.Sender.ID), c.Sender.Username)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to purge user data", "user_id", c.Sender.ID, "err", err)
+		_, _ = b.telegram.Edit(c.Message, "I can't remove your data, please try again later.")
+		return
+	}
+	logPurge(b.logger, result, fmt.Sprintf("user %d", c.Sender.ID))
+
+	if _, err := b.telegram.Edit(c.Message, "I removed your data."); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit forgetme confirmation", "err", err)
+	}
+}
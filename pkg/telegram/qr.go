@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/skip2/go-qrcode"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseQRUsage   = "Usage: " + CommandQR + " <url>"
+	responseQRTooLong = "This is too long for a QR code."
+
+	// qrScale is the number of pixels per module of the QR code images.
+	qrScale = 8
+	// qrMaxLength is the most bytes a QR code with error correction level M holds.
+	qrMaxLength = 2331
+	// maxQRCodes is the most QR codes sent for the alerts of a message.
+	maxQRCodes = 3
+)
+
+// errQRTooLong is returned for text that doesn't fit into the largest QR code.
+var errQRTooLong = errors.New("text is too long for a QR code")
+
+// qrMarker is what the qr template function wraps links in. Values of alerts are escaped before templating,
+// so that only templates can ask for QR codes.
+var qrMarker = regexp.MustCompile(`<qr>(.*?)</qr>`)
+
+// qrTemplateFunc marks the link to be sent as QR code in reply to the alerts, e.g. {{ qr .GeneratorURL }}.
+// The link itself stays in the message.
+func qrTemplateFunc(link string) string {
+	return "<qr>" + link + "</qr>"
+}
+
+// extractQRCodes removes the marks of the qr template function from the rendered alerts and returns the marked links.
+func extractQRCodes(out string) (string, []string) {
+	var links []string
+	seen := map[string]bool{}
+	for _, m := range qrMarker.FindAllStringSubmatch(out, -1) {
+		link := html.UnescapeString(m[1])
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return qrMarker.ReplaceAllString(out, "$1"), links
+}
+
+// qrPhoto returns the image of the QR code of the text with error correction level M.
+func qrPhoto(text string) (*telebot.Photo, error) {
+	if len(text) > qrMaxLength {
+		return nil, errQRTooLong
+	}
+	code, err := qrcode.New(text, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	// A negative size is the number of pixels per module.
+	img, err := code.PNG(-qrScale)
+	if err != nil {
+		return nil, err
+	}
+	return &telebot.Photo{File: telebot.FromReader(bytes.NewReader(img))}, nil
+}
+
+// sendQRCodes sends the QR codes of the links in reply to the message of the alerts.
+// The alerts were sent already, so failures are only logged.
+func (b *Bot) sendQRCodes(chat *telebot.Chat, messageID int, links []string) {
+	if len(links) > maxQRCodes {
+		level.Debug(b.logger).Log("msg", "not sending more QR codes", "chat_id", chat.ID, "max", maxQRCodes)
+		links = links[:maxQRCodes]
+	}
+	for _, link := range links {
+		photo, err := qrPhoto(link)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to encode QR code", "err", err)
+			continue
+		}
+		_, err = b.telegram.Send(chat, photo, &telebot.SendOptions{ReplyTo: &telebot.Message{ID: messageID, Chat: chat}})
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send QR code", "chat_id", chat.ID, "err", err)
+		}
+	}
+}
+
+func (b *Bot) handleQR(message *telebot.Message) error {
+	text := strings.TrimSpace(message.Payload)
+	if text == "" {
+		_, err := b.telegram.Send(message.Chat, responseQRUsage)
+		return err
+	}
+
+	photo, err := qrPhoto(text)
+	if errors.Is(err, errQRTooLong) {
+		_, err = b.telegram.Send(message.Chat, responseQRTooLong)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, err = b.telegram.Send(message.Chat, photo)
+	return err
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// queryTop is the number of series shown for a query.
+const queryTop = 10
+
+type Prometheus interface {
+	Query(ctx context.Context, query string) (model.Value, error)
+	Targets(ctx context.Context) ([]v1.ActiveTarget, error)
+	Rules(ctx context.Context) ([]v1.RuleGroup, error)
+}
+
+// WithPrometheus allows admins to query Prometheus from the chat.
+func WithPrometheus(prometheus Prometheus) BotOption {
+	return func(b *Bot) error {
+		b.prometheus = prometheus
+		return nil
+	}
+}
+
+func (b *Bot) handleQuery(message *telebot.Message) error {
+	if b.prometheus == nil {
+		_, err := b.telegram.Send(message.Chat, "Querying Prometheus isn't configured.")
+		return err
+	}
+
+	query := strings.TrimSpace(message.Payload)
+	if query == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandQuery+" <promql>")
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	value, err := b.prometheus.Query(ctx, query)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to query prometheus", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to query prometheus... %v", err))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(formatQueryResult(value)), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	return err
+}
+
+// formatQueryResult renders the query result as a code block with the highest values first.
+func formatQueryResult(value model.Value) string {
+	var lines []string
+	total := 0
+
+	switch v := value.(type) {
+	case model.Vector:
+		sort.Slice(v, func(i, j int) bool { return v[i].Value > v[j].Value })
+		total = len(v)
+		for i, s := range v {
+			if i == queryTop {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("%s => %s", s.Metric, s.Value))
+		}
+	case model.Matrix:
+		total = len(v)
+		for i, s := range v {
+			if i == queryTop {
+				break
+			}
+			if len(s.Values) == 0 {
+				continue
+			}
+			last := s.Values[len(s.Values)-1]
+			lines = append(lines, fmt.Sprintf("%s => %s (%d samples)", s.Metric, last.Value, len(s.Values)))
+		}
+	case *model.Scalar:
+		total = 1
+		lines = append(lines, v.Value.String())
+	case *model.String:
+		total = 1
+		lines = append(lines, v.Value)
+	}
+
+	if total == 0 {
+		return "The query returned no results."
+	}
+
+	out := "<pre>" + html.EscapeString(strings.Join(lines, "\n")) + "</pre>"
+	if total > len(lines) {
+		out += fmt.Sprintf("\nShowing %d of %d results.", len(lines), total)
+	}
+	return out
+}
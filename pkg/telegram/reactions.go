@@ -0,0 +1,121 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// OnReaction is the endpoint of the handler of reactions to messages, a func(*Reaction).
+// telebot doesn't know reactions, the transports of NewBot and telegramtest handle them.
+const OnReaction = "\areaction"
+
+// ackReaction acknowledges the alerts of a message if an authorized user reacts with it, see WithReactionAck.
+const ackReaction = "👍"
+
+// reactionUpdates are the updates Telegram sends if reactions are handled.
+// Telegram only sends reactions if they're listed, all other updates are the ones it sends by default.
+const reactionUpdates = `["message","edited_message","channel_post","edited_channel_post","inline_query",` +
+	`"chosen_inline_result","callback_query","shipping_query","pre_checkout_query","poll","poll_answer","message_reaction"]`
+
+// Reaction is a user changing their reactions to a message.
+type Reaction struct {
+	Chat      *telebot.Chat
+	MessageID int
+	// Sender is nil if the reaction is anonymous, e.g. of a group's anonymous admin.
+	Sender *telebot.User
+	// Emoji are the emoji the sender reacted to the message with now.
+	Emoji []string
+}
+
+// WithReactionAck acknowledges the alerts of a message if an authorized user reacts to it with 👍.
+// The bot has to be an admin of groups to get the reactions of their members.
+func WithReactionAck() BotOption {
+	return func(b *Bot) error {
+		b.reactionAck = true
+		return nil
+	}
+}
+
+// handleReaction acknowledges the alerts of the message if the sender reacted with ackReaction.
+func (b *Bot) handleReaction(r *Reaction) {
+	if r.Sender == nil || r.Chat == nil {
+		return
+	}
+	reacted := false
+	for _, e := range r.Emoji {
+		reacted = reacted || e == ackReaction
+	}
+	if !reacted {
+		return
+	}
+	if !b.isAuthorized(r.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping reaction from forbidden sender",
+			"sender_id", r.Sender.ID,
+			"sender_username", r.Sender.Username,
+		)
+		return
+	}
+
+	alerts, err := b.alerts.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts from alert store", "err", err)
+		return
+	}
+
+	var names []string
+	for _, a := range alerts {
+		if a.ChatID != r.Chat.ID || a.MessageID == 0 || a.MessageID != r.MessageID || a.Acked() {
+			continue
+		}
+		a.AckedAt = time.Now()
+		a.AckedBy = r.Sender.Username
+		if err := b.alerts.Put(a); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put alert into alert store", "err", err)
+			continue
+		}
+		b.recordAck(a)
+		names = append(names, a.Name())
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "alerts acknowledged",
+		"alertname", strings.Join(names, ","),
+		"count", len(names),
+		"username", r.Sender.Username,
+	)
+}
+
+// reactionUpdate is an update as Telegram sends it, including the reactions telebot doesn't know.
+type reactionUpdate struct {
+	telebot.Update
+	MessageReaction *struct {
+		Chat        *telebot.Chat `json:"chat"`
+		MessageID   int           `json:"message_id"`
+		User        *telebot.User `json:"user"`
+		NewReaction []struct {
+			Type  string `json:"type"`
+			Emoji string `json:"emoji"`
+		} `json:"new_reaction"`
+	} `json:"message_reaction"`
+}
+
+// reactingTelebot passes the handler of OnReaction to its poller.
+type reactingTelebot struct {
+	*telebot.Bot
+	poller *updatePoller
+}
+
+func (t *reactingTelebot) Handle(endpoint interface{}, handler interface{}) {
+	if h, ok := handler.(func(*Reaction)); ok && endpoint == OnReaction {
+		t.poller.handler = h
+		return
+	}
+	t.Bot.Handle(endpoint, handler)
+}
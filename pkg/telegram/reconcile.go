@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// WithReconciliation compares the alerts of the alert store with the alerts firing in Alertmanager on startup.
+// Alerts that resolved while the bot wasn't running are resolved, so that they aren't acknowledged,
+// reminded about or escalated anymore and their history and incidents are up to date.
+func WithReconciliation() BotOption {
+	return func(b *Bot) error {
+		b.reconcile = true
+		return nil
+	}
+}
+
+// reconcileAlerts resolves the alerts of the alert store that aren't firing in Alertmanager anymore.
+// Their resolved webhooks were missed, so they're resolved at the time of the reconciliation.
+func (b *Bot) reconcileAlerts(ctx context.Context) error {
+	alerts, err := b.alerts.List()
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	// Silenced alerts are still firing, so they're listed too.
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	amAlerts, err := b.alertmanager.ListAlerts(ctx, "", true)
+	if err != nil {
+		return err
+	}
+	firing := make(map[string]bool, len(amAlerts))
+	for _, a := range amAlerts {
+		firing[a.Fingerprint().String()] = true
+	}
+
+	now := time.Now()
+	resolved := map[int64]webhook.Message{}
+	count := 0
+	for _, a := range alerts {
+		// The bot's own alerts aren't sent to Alertmanager, self-monitoring resolves them.
+		if firing[a.Fingerprint] || a.Receiver == selfReceiver {
+			continue
+		}
+		m, ok := resolved[a.ChatID]
+		if !ok {
+			m = webhook.Message{Data: &template.Data{Receiver: a.Receiver, Status: "resolved"}}
+		}
+		m.Alerts = append(m.Alerts, template.Alert{
+			Status:      "resolved",
+			Labels:      a.Labels,
+			StartsAt:    a.StartsAt,
+			EndsAt:      now,
+			Fingerprint: a.Fingerprint,
+		})
+		resolved[a.ChatID] = m
+		count++
+	}
+
+	for chatID, m := range resolved {
+		b.trackAlerts(chatID, 0, m)
+	}
+
+	level.Info(b.logger).Log("msg", "alerts reconciled with alertmanager", "alerts", len(alerts), "resolved", count)
+	return nil
+}
@@ -0,0 +1,379 @@
+package telegram
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// RecordedEvent is an input or output of a tenant's bot in a recording, exactly one of the fields besides At and Tenant is set.
+type RecordedEvent struct {
+	At     time.Time `json:"at"`
+	Tenant string    `json:"tenant"`
+	// Chats are the chats subscribed when the bot started recording.
+	Chats   []*telebot.Chat  `json:"chats,omitempty"`
+	Webhook *ReceivedWebhook `json:"webhook,omitempty"`
+	Update  *telebot.Update  `json:"update,omitempty"`
+	Sent    *SentMessage     `json:"sent,omitempty"`
+}
+
+// SentMessage is a message the bot sent or edited.
+type SentMessage struct {
+	// Recipient is the chat the message was sent to, prefixed with edit: if a message of the chat was edited.
+	Recipient string `json:"recipient"`
+	Text      string `json:"text"`
+}
+
+// Recorder writes the events of bots to a recording, one JSON object per line.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a Recorder appending the events to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) record(e RecordedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(e)
+}
+
+// ReadRecording reads the events of a recording written by a Recorder.
+func ReadRecording(r io.Reader) ([]RecordedEvent, error) {
+	var events []RecordedEvent
+	s := bufio.NewScanner(r)
+	// Webhooks with many alerts make for long lines.
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var e RecordedEvent
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, s.Err()
+}
+
+type botRecorder struct {
+	recorder *Recorder
+	tenant   string
+}
+
+func (r *botRecorder) record(b *Bot, e RecordedEvent) {
+	e.At = time.Now()
+	e.Tenant = r.tenant
+	if err := r.recorder.record(e); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to record event", "err", err)
+	}
+}
+
+// WithRecorder records the subscribed chats on startup, all received webhooks and Telegram updates
+// and the messages sent for them, so that they can be replayed against another configuration or build
+// with ReplayRecording. The tenant tells the events of several bots recorded to the same Recorder apart.
+func WithRecorder(r *Recorder, tenant string) BotOption {
+	return func(b *Bot) error {
+		b.recorder = &botRecorder{recorder: r, tenant: tenant}
+		b.telegram = &recordingTelegram{Telebot: b.telegram, bot: b}
+		return nil
+	}
+}
+
+// recordChats records the subscribed chats, the state the recorded events start from.
+func (b *Bot) recordChats() error {
+	chats, err := b.chats.List()
+	if err != nil {
+		return err
+	}
+	if chats == nil {
+		chats = []*telebot.Chat{}
+	}
+	b.recorder.record(b, RecordedEvent{Chats: chats})
+	return nil
+}
+
+// recordWebhook records a webhook received from Alertmanager.
+func (b *Bot) recordWebhook(w alertmanager.TelegramWebhook) {
+	payload := w.Payload
+	if payload == nil {
+		var err error
+		if payload, err = json.Marshal(w.Message); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to record webhook", "err", err)
+			return
+		}
+	}
+	b.recorder.record(b, RecordedEvent{Webhook: &ReceivedWebhook{ReceivedAt: time.Now(), ChatID: w.ChatID, Group: w.Group, Payload: payload}})
+}
+
+// sentMessage returns what is sent like the test and recorded messages show it.
+func sentMessage(to string, what interface{}) SentMessage {
+	switch m := what.(type) {
+	case string:
+		return SentMessage{Recipient: to, Text: m}
+	case *telebot.Document:
+		return SentMessage{Recipient: to, Text: "document:" + m.FileName}
+	case *telebot.Voice:
+		return SentMessage{Recipient: to, Text: "voice"}
+	case *telebot.Location:
+		return SentMessage{Recipient: to, Text: fmt.Sprintf("location:%g,%g", m.Lat, m.Lng)}
+	case *telebot.Photo:
+		return SentMessage{Recipient: to, Text: "photo"}
+	default:
+		return SentMessage{Recipient: to, Text: fmt.Sprintf("%T", what)}
+	}
+}
+
+// recordingTelegram records the updates handled by the bot and the messages it sends.
+type recordingTelegram struct {
+	Telebot
+	bot *Bot
+}
+
+func (t *recordingTelegram) Send(to telebot.Recipient, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	m, err := t.Telebot.Send(to, what, options...)
+	if err == nil {
+		sent := sentMessage(to.Recipient(), what)
+		t.bot.recorder.record(t.bot, RecordedEvent{Sent: &sent})
+	}
+	return m, err
+}
+
+func (t *recordingTelegram) Edit(msg telebot.Editable, what interface{}, options ...interface{}) (*telebot.Message, error) {
+	m, err := t.Telebot.Edit(msg, what, options...)
+	if err == nil {
+		// The IDs of the edited messages differ between Telegram and replays, only their chats are recorded.
+		_, chatID := msg.MessageSig()
+		sent := sentMessage("edit:"+strconv.FormatInt(chatID, 10), what)
+		t.bot.recorder.record(t.bot, RecordedEvent{Sent: &sent})
+	}
+	return m, err
+}
+
+func (t *recordingTelegram) Handle(endpoint interface{}, handler interface{}) {
+	record := func(u telebot.Update) { t.bot.recorder.record(t.bot, RecordedEvent{Update: &u}) }
+
+	switch h := handler.(type) {
+	case func(*telebot.Message):
+		handler = func(m *telebot.Message) {
+			if endpoint == telebot.OnEdited {
+				record(telebot.Update{EditedMessage: m})
+			} else {
+				record(telebot.Update{Message: m})
+			}
+			h(m)
+		}
+	case func(*telebot.Callback):
+		handler = func(c *telebot.Callback) {
+			// Telegram sends the data of a button prefixed with its unique name, telebot strips it before the handler.
+			recorded := *c
+			if e, ok := endpoint.(telebot.CallbackEndpoint); ok {
+				recorded.Data = "\f" + e.CallbackUnique()
+				if c.Data != "" {
+					recorded.Data += "|" + c.Data
+				}
+			}
+			record(telebot.Update{Callback: &recorded})
+			h(c)
+		}
+	case func(*telebot.Query):
+		handler = func(q *telebot.Query) {
+			record(telebot.Update{Query: q})
+			h(q)
+		}
+	}
+	t.Telebot.Handle(endpoint, handler)
+}
+
+// ReplayTelegram handles the updates of a replay without connecting to Telegram and keeps the sent messages.
+type ReplayTelegram struct {
+	bot     *telebot.Bot
+	started chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+
+	mu   sync.Mutex
+	sent []SentMessage
+}
+
+// NewReplayTelegram returns a Telegram for bots replaying a recording.
+func NewReplayTelegram() (*ReplayTelegram, error) {
+	bot, err := telebot.NewBot(telebot.Settings{
+		Offline: true,
+		Poller:  &telebot.LongPoller{},
+		// Handle the updates one after the other, like they were recorded.
+		Synchronous: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTelegram{bot: bot, started: make(chan struct{}), stop: make(chan struct{})}, nil
+}
+
+// Sent returns the messages sent so far.
+func (t *ReplayTelegram) Sent() []SentMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SentMessage(nil), t.sent...)
+}
+
+func (t *ReplayTelegram) add(m SentMessage) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = append(t.sent, m)
+	return len(t.sent)
+}
+
+func (t *ReplayTelegram) Start() {
+	close(t.started)
+	<-t.stop
+}
+
+func (t *ReplayTelegram) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
+
+func (t *ReplayTelegram) Send(to telebot.Recipient, what interface{}, _ ...interface{}) (*telebot.Message, error) {
+	m := sentMessage(to.Recipient(), what)
+	id := t.add(m)
+	chatID, _ := strconv.ParseInt(to.Recipient(), 10, 64)
+	return &telebot.Message{ID: id, Chat: &telebot.Chat{ID: chatID}, Text: m.Text}, nil
+}
+
+func (t *ReplayTelegram) Edit(msg telebot.Editable, what interface{}, _ ...interface{}) (*telebot.Message, error) {
+	messageID, chatID := msg.MessageSig()
+	m := sentMessage("edit:"+strconv.FormatInt(chatID, 10), what)
+	t.add(m)
+	id, _ := strconv.Atoi(messageID)
+	return &telebot.Message{ID: id, Chat: &telebot.Chat{ID: chatID}, Text: m.Text}, nil
+}
+
+func (t *ReplayTelegram) Pin(_ telebot.Editable, _ ...interface{}) error {
+	return nil
+}
+
+func (t *ReplayTelegram) Respond(_ *telebot.Callback, _ ...*telebot.CallbackResponse) error {
+	return nil
+}
+
+func (t *ReplayTelegram) Answer(_ *telebot.Query, _ *telebot.QueryResponse) error {
+	return nil
+}
+
+func (t *ReplayTelegram) Notify(_ telebot.Recipient, _ telebot.ChatAction) error {
+	return nil
+}
+
+func (t *ReplayTelegram) Handle(endpoint interface{}, handler interface{}) {
+	t.bot.Handle(endpoint, handler)
+}
+
+func (t *ReplayTelegram) SetCommands(_ []telebot.Command) error {
+	return nil
+}
+
+func (t *ReplayTelegram) ChatByID(_ string) (*telebot.Chat, error) {
+	return nil, errors.New("chats can't be looked up in replays")
+}
+
+// ReplayRecording runs the bot with the recorded chats, webhooks and updates of the tenant one after the other
+// and returns the messages it sent. The bot has to be created with the ReplayTelegram and must not be running.
+// The alerts of the webhooks are moved in time, so that they're as old as they were when they were recorded.
+func (b *Bot) ReplayRecording(ctx context.Context, t *ReplayTelegram, tenant string, events []RecordedEvent) ([]SentMessage, error) {
+	if b.sendWorkers > 1 {
+		return nil, errors.New("replaying requires a single send worker to send the messages in order")
+	}
+	for _, e := range events {
+		if e.Tenant != tenant || e.Chats == nil {
+			continue
+		}
+		for _, c := range e.Chats {
+			if err := b.chats.Add(c); err != nil {
+				return nil, fmt.Errorf("failed to add recorded chat %d: %w", c.ID, err)
+			}
+		}
+		break
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.Run(ctx, nil)
+	}()
+	select {
+	case <-t.started:
+	case err := <-errs:
+		return nil, err
+	}
+
+	for _, e := range events {
+		if e.Tenant != tenant {
+			continue
+		}
+		switch {
+		case e.Webhook != nil:
+			var message webhook.Message
+			if err := json.Unmarshal(e.Webhook.Payload, &message); err != nil {
+				return nil, fmt.Errorf("failed to decode webhook recorded at %s: %w", e.At, err)
+			}
+			shift := time.Since(e.At)
+			for i := range message.Alerts {
+				if !message.Alerts[i].StartsAt.IsZero() {
+					message.Alerts[i].StartsAt = message.Alerts[i].StartsAt.Add(shift)
+				}
+				if !message.Alerts[i].EndsAt.IsZero() {
+					message.Alerts[i].EndsAt = message.Alerts[i].EndsAt.Add(shift)
+				}
+			}
+			w := alertmanager.TelegramWebhook{ChatID: e.Webhook.ChatID, Group: e.Webhook.Group, Message: message, Payload: e.Webhook.Payload}
+			if err := b.receiveWebhook(ctx, w); err != nil {
+				return nil, err
+			}
+		case e.Update != nil:
+			t.bot.ProcessUpdate(*e.Update)
+		}
+	}
+
+	// Messages held back for correlation and webhooks replayed with /replay are sent in the background.
+	if err := b.WaitIdle(ctx); err != nil {
+		return nil, err
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return t.Sent(), nil
+}
+
+// DiffSent returns the lines of the recorded messages missing from the replayed ones prefixed with -
+// and the lines of the replayed messages that weren't recorded prefixed with +.
+func DiffSent(recorded, replayed []SentMessage) []string {
+	format := func(messages []SentMessage) string {
+		var out strings.Builder
+		for _, m := range messages {
+			fmt.Fprintf(&out, "to %s:\n", m.Recipient)
+			for _, line := range strings.Split(strings.TrimSpace(m.Text), "\n") {
+				fmt.Fprintf(&out, "  %s\n", line)
+			}
+		}
+		return out.String()
+	}
+	return diffLines(format(recorded), format(replayed))
+}
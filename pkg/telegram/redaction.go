@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// DefaultRedactionReplacement replaces the matches of redaction rules without a replacement.
+const DefaultRedactionReplacement = "[REDACTED]"
+
+// RedactionRule replaces the matches of a regular expression in the values of annotations,
+// e.g. password=\S+ or [a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+ for email addresses.
+type RedactionRule struct {
+	// Regexp uses the RE2 syntax of Go's regexp package.
+	Regexp string
+	// Replacement can refer to submatches like ${1}, it defaults to DefaultRedactionReplacement.
+	Replacement string
+}
+
+type redactionRule struct {
+	regexp      *regexp.Regexp
+	replacement string
+}
+
+// WithRedaction replaces the matches of the rules in the annotations of received webhooks before they're kept,
+// recorded, persisted or rendered, and in the annotations of the alerts listed from Alertmanager,
+// so that secrets leaked into annotations don't end up in Telegram or the store. Labels aren't redacted.
+func WithRedaction(rules []RedactionRule) BotOption {
+	return func(b *Bot) error {
+		for i, r := range rules {
+			if r.Regexp == "" {
+				return fmt.Errorf("redaction rule %d has no regexp", i)
+			}
+			re, err := regexp.Compile(r.Regexp)
+			if err != nil {
+				return fmt.Errorf("redaction rule %d: %w", i, err)
+			}
+			replacement := r.Replacement
+			if replacement == "" {
+				replacement = DefaultRedactionReplacement
+			}
+			b.redactions = append(b.redactions, redactionRule{regexp: re, replacement: replacement})
+		}
+		return nil
+	}
+}
+
+// redact applies all rules to the value in order.
+func (b *Bot) redact(value string) string {
+	for _, r := range b.redactions {
+		value = r.regexp.ReplaceAllString(value, r.replacement)
+	}
+	return value
+}
+
+// redactKV returns a copy of the annotations with the rules applied to their values.
+func (b *Bot) redactKV(kv template.KV) template.KV {
+	if kv == nil {
+		return nil
+	}
+	redacted := make(template.KV, len(kv))
+	for name, value := range kv {
+		redacted[name] = b.redact(value)
+	}
+	return redacted
+}
+
+// redactData returns a copy of the data with the annotations of all alerts redacted.
+func (b *Bot) redactData(data *template.Data) *template.Data {
+	if len(b.redactions) == 0 || data == nil {
+		return data
+	}
+	redacted := *data
+	redacted.CommonAnnotations = b.redactKV(data.CommonAnnotations)
+	redacted.Alerts = make(template.Alerts, 0, len(data.Alerts))
+	for _, a := range data.Alerts {
+		a.Annotations = b.redactKV(a.Annotations)
+		redacted.Alerts = append(redacted.Alerts, a)
+	}
+	return &redacted
+}
+
+// redactWebhook returns the webhook with its annotations redacted.
+// The raw payload is dropped, so that the redacted message is kept instead of it.
+func (b *Bot) redactWebhook(w alertmanager.TelegramWebhook) alertmanager.TelegramWebhook {
+	w.Message.Data = b.redactData(w.Message.Data)
+	w.Payload = nil
+	return w
+}
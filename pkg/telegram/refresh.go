@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// WithChatRefresh looks up the subscribed chats in Telegram every interval and updates their stored
+// usernames, names and titles, so that /chats and the muted users stay accurate when users rename themselves.
+func WithChatRefresh(interval time.Duration) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return errors.New("chat refresh interval must be positive")
+		}
+		b.chatRefresh = interval
+		return nil
+	}
+}
+
+func (b *Bot) runChatRefresh(ctx context.Context) error {
+	ticker := time.NewTicker(b.chatRefresh)
+	defer ticker.Stop()
+
+	for {
+		if err := b.refreshChats(); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to refresh chats", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshChats updates the stored chats that changed in Telegram.
+func (b *Bot) refreshChats() error {
+	chats, err := b.chats.List()
+	if err != nil {
+		return err
+	}
+
+	for _, chat := range chats {
+		fresh, err := b.telegram.ChatByID(strconv.FormatInt(chat.ID, 10))
+		if err != nil {
+			// The bot might have been blocked or removed without noticing, the chat is kept as it is.
+			level.Debug(b.logger).Log("msg", "failed to look up chat", "chat_id", chat.ID, "err", err)
+			continue
+		}
+		if fresh.Username == chat.Username && fresh.Title == chat.Title &&
+			fresh.FirstName == chat.FirstName && fresh.LastName == chat.LastName {
+			continue
+		}
+
+		refreshed := *chat
+		refreshed.Username, refreshed.Title = fresh.Username, fresh.Title
+		refreshed.FirstName, refreshed.LastName = fresh.FirstName, fresh.LastName
+		if err := b.chats.Add(&refreshed); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "chat refreshed", "chat_id", chat.ID, "username", refreshed.Username, "title", refreshed.Title)
+
+		// The ID of a private chat is the user's ID, so a new username is a rename of the user.
+		if chat.Type == telebot.ChatPrivate && chat.Username != "" && refreshed.Username != "" && chat.Username != refreshed.Username {
+			if err := b.renameMuted(chats, chat.Username, refreshed.Username); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renameMuted replaces the old username of a user who muted themselves in any of the chats.
+func (b *Bot) renameMuted(chats []*telebot.Chat, from, to string) error {
+	if b.mutes == nil {
+		return nil
+	}
+	for _, chat := range chats {
+		mutes, err := b.mutes.Get(chat.ID)
+		if err != nil {
+			return err
+		}
+		if !mutes.has(from) {
+			continue
+		}
+		for i, u := range mutes.Usernames {
+			if strings.EqualFold(u, from) {
+				mutes.Usernames[i] = to
+			}
+		}
+		if err := b.mutes.Put(mutes); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "renamed muted user", "chat_id", chat.ID, "from", from, "to", to)
+	}
+	return nil
+}
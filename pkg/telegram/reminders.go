@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// DefaultReminderPolicy is the policy of alerts whose severity has no policy of its own.
+const DefaultReminderPolicy = "default"
+
+const (
+	responseReminder       = "⏳ %s is still firing after %s.\n" + CommandAck + " %s"
+	responseRemindersUsage = "Usage: " + CommandReminders + " on|off"
+)
+
+// ChatReminders are the reminder settings of a chat.
+type ChatReminders struct {
+	ChatID int64 `json:"chatID"`
+	Off    bool  `json:"off,omitempty"`
+}
+
+// BotReminderStore keeps the chats' reminder settings.
+type BotReminderStore interface {
+	Get(chatID int64) (*ChatReminders, error)
+	Put(*ChatReminders) error
+	Remove(chatID int64) error
+}
+
+// ReminderStore writes the chats' reminder settings to a libkv store backend.
+type ReminderStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewReminderStore stores the chats' reminder settings in the provided kv backend.
+func NewReminderStore(kv store.Store, storeKeyPrefix string) (*ReminderStore, error) {
+	return &ReminderStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the reminder settings of a chat, reminders are on if the chat never changed them.
+func (s *ReminderStore) Get(chatID int64) (*ChatReminders, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatReminders{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var r *ChatReminders
+	err = json.Unmarshal(kv.Value, &r)
+	return r, err
+}
+
+// Put the reminder settings of a chat into the kv backend.
+func (s *ReminderStore) Put(r *ChatReminders) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, r.ChatID), b, nil)
+}
+
+// Remove the reminder settings of a chat from the kv backend.
+func (s *ReminderStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// alertReminders are the policies of when to remind chats about unacknowledged alerts.
+type alertReminders struct {
+	// policies are the durations after an alert started to remind at, by severity.
+	policies map[string][]time.Duration
+	store    BotReminderStore
+}
+
+// policy returns the reminder durations of the severity.
+func (r *alertReminders) policy(severity string) []time.Duration {
+	if p, ok := r.policies[severity]; ok {
+		return p
+	}
+	return r.policies[DefaultReminderPolicy]
+}
+
+// WithReminders reminds chats about alerts that are still firing without being acked, once for each of the durations after the alert started.
+// The policies are the durations by the alert's severity label, alerts of other severities use the DefaultReminderPolicy.
+// Chats turn reminders off with the reminders command.
+func WithReminders(policies map[string][]time.Duration, reminders BotReminderStore) BotOption {
+	return func(b *Bot) error {
+		sorted := make(map[string][]time.Duration, len(policies))
+		for severity, durations := range policies {
+			p := append([]time.Duration(nil), durations...)
+			for _, d := range p {
+				if d <= 0 {
+					return fmt.Errorf("reminder policy %s has a non-positive duration %s", severity, d)
+				}
+			}
+			sort.Slice(p, func(i, j int) bool { return p[i] < p[j] })
+			sorted[severity] = p
+		}
+		b.reminders = &alertReminders{policies: sorted, store: reminders}
+		return nil
+	}
+}
+
+// runReminders periodically reminds chats about their unacknowledged alerts.
+func (b *Bot) runReminders(ctx context.Context) error {
+	interval := time.Minute
+	for _, p := range b.reminders.policies {
+		if len(p) > 0 && p[0] < interval {
+			interval = p[0]
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.remind(ctx); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to send reminders", "err", err)
+			}
+		}
+	}
+}
+
+func (b *Bot) remind(ctx context.Context) error {
+	alerts, err := b.alerts.List()
+	if err != nil {
+		return err
+	}
+
+	// Alerts that are still firing and not silenced, by receiver.
+	active := map[string]map[string]bool{}
+	// Chats that turned reminders off.
+	off := map[int64]bool{}
+
+	for _, a := range alerts {
+		if a.Acked() || !b.ownsChat(a.ChatID) {
+			continue
+		}
+
+		// Only the latest due reminder is sent, e.g. after the bot wasn't running.
+		due := 0
+		since := time.Since(a.StartsAt)
+		for _, d := range b.reminders.policy(a.Labels["severity"]) {
+			if since >= d {
+				due++
+			}
+		}
+		if due <= a.Reminders {
+			continue
+		}
+
+		ok, err := b.alertActive(ctx, active, a)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if _, ok := off[a.ChatID]; !ok {
+			r, err := b.reminders.store.Get(a.ChatID)
+			if err != nil {
+				return err
+			}
+			off[a.ChatID] = r.Off
+		}
+		if off[a.ChatID] {
+			continue
+		}
+
+		message := fmt.Sprintf(responseReminder, a.Name(), durafmt.Parse(since.Round(time.Minute)), a.Name())
+		if _, err := b.telegram.Send(&telebot.Chat{ID: a.ChatID}, message); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send reminder", "chat_id", a.ChatID, "err", err)
+			continue
+		}
+
+		level.Info(b.logger).Log("msg", "reminded of alert", "alertname", a.Name(), "chat_id", a.ChatID, "reminder", due)
+
+		a.Reminders = due
+		if err := b.alerts.Put(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Bot) handleReminders(message *telebot.Message) error {
+	if b.reminders == nil || b.alerts == nil {
+		_, err := b.telegram.Send(message.Chat, "Reminders aren't enabled.")
+		return err
+	}
+	if _, err := b.chats.Get(telebot.ChatID(message.Chat.ID)); err != nil {
+		_, err = b.telegram.Send(message.Chat, "This chat isn't subscribed.")
+		return err
+	}
+
+	r, err := b.reminders.store.Get(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get reminders", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the reminders of this chat.")
+		return err
+	}
+
+	switch strings.TrimSpace(message.Payload) {
+	case "":
+		state := "on"
+		if r.Off {
+			state = "off"
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Reminders are %s for this chat.", state))
+		return err
+	case "on":
+		r.Off = false
+	case "off":
+		r.Off = true
+	default:
+		_, err = b.telegram.Send(message.Chat, responseRemindersUsage)
+		return err
+	}
+
+	if err := b.reminders.store.Put(r); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put reminders", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the reminders of this chat.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "reminders changed", "chat_id", message.Chat.ID, "off", r.Off)
+
+	if r.Off {
+		_, err = b.telegram.Send(message.Chat, "Reminders are now off for this chat.")
+	} else {
+		_, err = b.telegram.Send(message.Chat, "Reminders are now on for this chat.")
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// defaultSilenceDuration is how long alerts are silenced by /silence without a duration.
+const defaultSilenceDuration = time.Hour
+
+// repliedAlerts returns the firing alerts of the chat that were sent in the message the command replies to.
+func (b *Bot) repliedAlerts(message *telebot.Message) ([]*ChatAlert, error) {
+	if message.ReplyTo == nil {
+		return nil, nil
+	}
+	alerts, err := b.alerts.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts from alert store: %w", err)
+	}
+
+	var replied []*ChatAlert
+	for _, a := range alerts {
+		if a.ChatID == message.Chat.ID && a.MessageID != 0 && a.MessageID == message.ReplyTo.ID {
+			replied = append(replied, a)
+		}
+	}
+	return replied, nil
+}
+
+// handleSilence silences the alerts of the message the command replies to by all their labels.
+func (b *Bot) handleSilence(message *telebot.Message) error {
+	if b.alerts == nil {
+		_, err := b.telegram.Send(message.Chat, "Silencing alerts by replying to them isn't enabled.")
+		return err
+	}
+	if message.ReplyTo == nil {
+		_, err := b.telegram.Send(message.Chat, "Usage: reply to an alert with "+CommandSilence+" [duration], e.g. "+CommandSilence+" 2h.")
+		return err
+	}
+
+	duration := defaultSilenceDuration
+	if payload := strings.TrimSpace(message.Payload); payload != "" {
+		d, err := model.ParseDuration(payload)
+		if err != nil || d <= 0 {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%q isn't a valid duration, e.g. 30m, 2h or 1d.", payload))
+			return err
+		}
+		duration = time.Duration(d)
+	}
+
+	alerts, err := b.repliedAlerts(message)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get replied alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the firing alerts.")
+		return err
+	}
+	if len(alerts) == 0 {
+		_, err = b.telegram.Send(message.Chat, "The message you replied to has no firing alerts.")
+		return err
+	}
+
+	now := time.Now()
+	comment := fmt.Sprintf("Silenced by %s via Telegram", senderName(message.Sender))
+	names := make([]string, 0, len(alerts))
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	for _, a := range alerts {
+		id, err := b.alertmanagerFor(message.Chat.ID).CreateSilence(ctx, alertmanager.EqualMatchers(a.Labels), now, now.Add(duration), message.Sender.Username, comment)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to create silence", "err", err)
+			_, err = b.telegram.Send(message.Chat, unreachableResponse(err, "I can't create the silence."))
+			return err
+		}
+
+		level.Info(b.logger).Log(
+			"msg", "silence created",
+			"id", id,
+			"alertname", a.Name(),
+			"username", message.Sender.Username,
+		)
+		names = append(names, a.Name())
+	}
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Silenced %s for %s.", strings.Join(names, ", "), formatDuration(duration)))
+	return err
+}
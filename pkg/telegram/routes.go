@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseRoutesUsage = "Usage: " + CommandRoutes + " [test <name=value>...]"
+
+func (b *Bot) handleRoutes(message *telebot.Message) error {
+	args := strings.Fields(message.Payload)
+	if len(args) > 0 && (args[0] != "test" || len(args) == 1) {
+		_, err := b.telegram.Send(message.Chat, responseRoutesUsage)
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	status, err := b.alertmanagerFor(message.Chat.ID).Status(ctx)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get status with config", "err", err)
+		_, err = b.telegram.Send(message.Chat, unreachableResponse(err, fmt.Sprintf("failed to get routes... %v", err)))
+		return err
+	}
+	conf, err := config.Load(*status.Config.Original)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to load alertmanager config", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to get routes... %v", err))
+		return err
+	}
+	route := dispatch.NewRoute(conf.Route, nil)
+
+	if len(args) == 0 {
+		var out strings.Builder
+		writeRoute(&out, route, nil, "", "")
+		_, err = b.telegram.Send(message.Chat, b.truncateMessage("<b>Routing tree:</b>\n<pre>"+out.String()+"</pre>"), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		return err
+	}
+
+	labels, err := parseLabelSet(args[1:])
+	if err != nil {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("%v\n%s", err, responseRoutesUsage))
+		return err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "<b>Alerts with %s are sent to:</b>", html.EscapeString(labels.String()))
+	for _, r := range route.Match(labels) {
+		fmt.Fprintf(&out, "\n➡️ <b>%s</b>", html.EscapeString(r.RouteOpts.Receiver))
+		if len(r.Matchers) > 0 {
+			fmt.Fprintf(&out, " matched by %s", html.EscapeString(formatMatchers(r.Matchers)))
+		}
+		if groupBy := formatGroupBy(r.RouteOpts); groupBy != "" {
+			fmt.Fprintf(&out, ", grouped by %s", html.EscapeString(groupBy))
+		}
+	}
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(out.String()), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// writeRoute writes the route and its children as a tree.
+// The grouping is only written if it differs from the parent's.
+func writeRoute(out *strings.Builder, r, parent *dispatch.Route, prefix, branch string) {
+	out.WriteString(html.EscapeString(branch + r.RouteOpts.Receiver))
+	if len(r.Matchers) > 0 {
+		out.WriteString(" " + html.EscapeString(formatMatchers(r.Matchers)))
+	}
+	groupBy := formatGroupBy(r.RouteOpts)
+	if groupBy != "" && (parent == nil || groupBy != formatGroupBy(parent.RouteOpts)) {
+		out.WriteString(" group_by: " + html.EscapeString(groupBy))
+	}
+	if r.Continue {
+		out.WriteString(" continue")
+	}
+	out.WriteString("\n")
+
+	for i, child := range r.Routes {
+		if i == len(r.Routes)-1 {
+			writeRoute(out, child, r, prefix+"    ", prefix+"└── ")
+			continue
+		}
+		writeRoute(out, child, r, prefix+"│   ", prefix+"├── ")
+	}
+}
+
+func formatMatchers(matchers types.Matchers) string {
+	ms := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		op, value := "=", m.Value
+		if m.IsRegex {
+			// Alertmanager anchors the regular expressions of the config.
+			op, value = "=~", strings.TrimSuffix(strings.TrimPrefix(value, "^(?:"), ")$")
+		}
+		ms = append(ms, m.Name+op+strconv.Quote(value))
+	}
+	return "{" + strings.Join(ms, ", ") + "}"
+}
+
+func formatGroupBy(opts dispatch.RouteOpts) string {
+	if opts.GroupByAll {
+		return "..."
+	}
+	names := make([]string, 0, len(opts.GroupBy))
+	for name := range opts.GroupBy {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseLabelSet parses labels like severity=critical or {team="db"}.
+func parseLabelSet(args []string) (model.LabelSet, error) {
+	labels := model.LabelSet{}
+	for _, arg := range strings.Fields(strings.NewReplacer("{", " ", "}", " ", ",", " ").Replace(strings.Join(args, " "))) {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", arg)
+		}
+		name, value := model.LabelName(parts[0]), parts[1]
+		if !name.IsValid() {
+			return nil, fmt.Errorf("invalid label name %q", parts[0])
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		labels[name] = model.LabelValue(value)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("labels are missing")
+	}
+	return labels, nil
+}
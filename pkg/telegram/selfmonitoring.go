@@ -0,0 +1,168 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// selfReceiver is the receiver of the bot's own alerts, they carry it as source label too.
+const selfReceiver = "alertmanager-bot"
+
+// Names of the alerts the bot raises about itself.
+const (
+	SelfAlertSendFailures     = "BotSendFailures"
+	SelfAlertStoreUnavailable = "BotStoreUnavailable"
+	SelfAlertQueueSaturated   = "BotSendQueueSaturated"
+)
+
+// selfQueueSaturation is the share of a send queue's capacity that's considered saturated.
+const selfQueueSaturation = 0.9
+
+// selfMonitor checks the bot's health and raises alerts about it.
+type selfMonitor struct {
+	interval         time.Duration
+	failureThreshold int
+	chatIDs          []int64
+
+	// failures of sending to chats since the last check.
+	failures int64
+	// firing alerts by name, only used by the monitor's goroutine.
+	firing map[string]template.Alert
+}
+
+// WithSelfMonitoring checks the bot's health every interval and sends alerts about it to the chats, the admins' private chats by default.
+// Alerts are raised if more than failureThreshold messages couldn't be sent since the last check,
+// if the chat store is unavailable or if a send queue is nearly full.
+func WithSelfMonitoring(interval time.Duration, failureThreshold int, chatIDs []int64) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return fmt.Errorf("self-monitoring interval has to be positive")
+		}
+		b.selfMonitor = &selfMonitor{
+			interval:         interval,
+			failureThreshold: failureThreshold,
+			chatIDs:          chatIDs,
+			firing:           map[string]template.Alert{},
+		}
+		return nil
+	}
+}
+
+// countSendFailure counts a delivery that failed, except for the bot's own alerts.
+func (b *Bot) countSendFailure(d *Delivery, m webhook.Message) {
+	if b.selfMonitor == nil || d.Status != DeliveryFailed || m.Receiver == selfReceiver {
+		return
+	}
+	atomic.AddInt64(&b.selfMonitor.failures, 1)
+}
+
+func (b *Bot) runSelfMonitoring(ctx context.Context) error {
+	ticker := time.NewTicker(b.selfMonitor.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := b.checkSelf(ctx, now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkSelf evaluates the self-monitoring alerts and sends the ones that started firing or resolved.
+func (b *Bot) checkSelf(ctx context.Context, now time.Time) error {
+	problems := map[string]string{}
+
+	if failures := atomic.SwapInt64(&b.selfMonitor.failures, 0); failures > int64(b.selfMonitor.failureThreshold) {
+		problems[SelfAlertSendFailures] = fmt.Sprintf("%d message(s) couldn't be sent in the last %s.", failures, durafmt.Parse(b.selfMonitor.interval))
+	}
+	if _, err := b.chats.List(); err != nil {
+		problems[SelfAlertStoreUnavailable] = fmt.Sprintf("The store is unavailable: %v", err)
+	}
+	for _, q := range b.sendQueues {
+		if float64(len(q)) >= float64(cap(q))*selfQueueSaturation {
+			problems[SelfAlertQueueSaturated] = fmt.Sprintf("%d of %d messages of a send queue are waiting to be sent.", len(q), cap(q))
+			break
+		}
+	}
+
+	var changed template.Alerts
+	for name, message := range problems {
+		if _, ok := b.selfMonitor.firing[name]; ok {
+			continue
+		}
+		a := selfAlert(name, message, now)
+		b.selfMonitor.firing[name] = a
+		changed = append(changed, a)
+		level.Warn(b.logger).Log("msg", "self-monitoring alert firing", "alertname", name)
+	}
+	for name, a := range b.selfMonitor.firing {
+		if _, ok := problems[name]; ok {
+			continue
+		}
+		delete(b.selfMonitor.firing, name)
+		a.Status, a.EndsAt = "resolved", now
+		changed = append(changed, a)
+		level.Info(b.logger).Log("msg", "self-monitoring alert resolved", "alertname", name)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Labels["alertname"] < changed[j].Labels["alertname"] })
+
+	chatIDs := b.selfMonitor.chatIDs
+	if len(chatIDs) == 0 {
+		for _, id := range b.admins {
+			chatIDs = append(chatIDs, int64(id))
+		}
+	}
+	for _, a := range changed {
+		m := selfMessage(a)
+		for _, chatID := range chatIDs {
+			if err := b.enqueue(ctx, chatID, m, nil, time.Time{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func selfAlert(name, message string, now time.Time) template.Alert {
+	severity := "warning"
+	if name == SelfAlertStoreUnavailable {
+		severity = "critical"
+	}
+	return template.Alert{
+		Status:      "firing",
+		Labels:      template.KV{"alertname": name, "severity": severity, "source": selfReceiver},
+		Annotations: template.KV{"message": "🤖 " + message},
+		StartsAt:    now,
+		Fingerprint: selfReceiver + "-" + name,
+	}
+}
+
+// selfMessage wraps the alert like a webhook from Alertmanager, so that it's sent like any other alert.
+func selfMessage(a template.Alert) webhook.Message {
+	return webhook.Message{
+		Data: &template.Data{
+			Receiver:     selfReceiver,
+			Status:       a.Status,
+			Alerts:       template.Alerts{a},
+			GroupLabels:  template.KV{"alertname": a.Labels["alertname"]},
+			CommonLabels: a.Labels,
+		},
+		Version:  "4",
+		GroupKey: fmt.Sprintf(`%s:{alertname=%q}`, selfReceiver, a.Labels["alertname"]),
+	}
+}
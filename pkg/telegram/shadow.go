@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// shadow renders the alerts of all webhooks with another configuration and sends them to a test chat.
+type shadow struct {
+	chatID int64
+	// bot only holds the shadow's templates, template overrides and label filter.
+	bot *Bot
+}
+
+// WithShadow sends the alerts of every webhook to the chat once more, rendered with the bot changed by the options,
+// so that a new configuration can be compared with the messages of the other chats before switching them over.
+// Only WithTemplates, WithTemplateOverrides and WithLabelFilter change how the shadow renders alerts,
+// without WithTemplates the shadow uses the bot's templates.
+func WithShadow(chatID int64, opts ...BotOption) BotOption {
+	return func(b *Bot) error {
+		s := &Bot{}
+		for _, opt := range opts {
+			if err := opt(s); err != nil {
+				return fmt.Errorf("shadow: %w", err)
+			}
+		}
+		b.shadow = &shadow{chatID: chatID, bot: s}
+		return nil
+	}
+}
+
+// sendShadow renders the webhook's alerts with the shadow's configuration and sends them to the shadow chat.
+// Failures are only logged, they never affect the other chats.
+func (b *Bot) sendShadow(w alertmanager.TelegramWebhook) {
+	s := b.shadow.bot
+	s.inherit(b)
+
+	m := w.Message
+	data := &template.Data{
+		Receiver:          m.Receiver,
+		Status:            m.Status,
+		Alerts:            s.labelFilter.filterAlerts(m.Alerts),
+		GroupLabels:       s.labelFilter.filter(m.GroupLabels),
+		CommonLabels:      s.labelFilter.filter(m.CommonLabels),
+		CommonAnnotations: m.CommonAnnotations,
+		ExternalURL:       m.ExternalURL,
+	}
+	out, disablePreview, err := s.renderAlerts(m.Alerts, data)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to template alerts for shadow chat", "err", err)
+		out = fmt.Sprintf("<i>Failed to render: %s</i>", html.EscapeString(err.Error()))
+	}
+	out, _ = extractQRCodes(out)
+
+	target := fmt.Sprintf("chat %d", w.ChatID)
+	if w.Group != "" {
+		target = "group " + w.Group
+	}
+	out = fmt.Sprintf("🧪 <b>Shadow of %s</b>\n%s", html.EscapeString(target), strings.TrimLeft(out, "\n"))
+
+	_, err = b.telegram.Send(&telebot.Chat{ID: b.shadow.chatID}, b.truncateMessage(out), &telebot.SendOptions{
+		ParseMode:             telebot.ModeHTML,
+		DisableWebPagePreview: disablePreview,
+		DisableNotification:   true,
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send alerts to shadow chat", "chat_id", b.shadow.chatID, "err", err)
+	}
+}
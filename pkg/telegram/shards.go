@@ -0,0 +1,215 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+)
+
+// shardExpiry is how many heartbeat intervals a replica is kept as a shard member without a heartbeat.
+const shardExpiry = 3
+
+// shardUpdates is the key of the shard polling Telegram for updates, only one replica may poll.
+const shardUpdates = "updates"
+
+// ShardMember is a replica of the bot sharing the chats with the others.
+type ShardMember struct {
+	ID string `json:"id"`
+	// SeenAt is the time of the replica's last heartbeat.
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// BotShardMemberStore keeps the replicas sharing the chats.
+type BotShardMemberStore interface {
+	List() ([]*ShardMember, error)
+	Put(*ShardMember) error
+	Remove(id string) error
+}
+
+// ShardMemberStore writes the replicas to a libkv store backend.
+type ShardMemberStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewShardMemberStore stores the replicas in the provided kv backend.
+func NewShardMemberStore(kv store.Store, storeKeyPrefix string) (*ShardMemberStore, error) {
+	return &ShardMemberStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+func (s *ShardMemberStore) key(id string) string {
+	return fmt.Sprintf("%s/%s", s.storeKeyPrefix, id)
+}
+
+// List all replicas saved in the kv backend.
+func (s *ShardMemberStore) List() ([]*ShardMember, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var members []*ShardMember
+	for _, kv := range kvPairs {
+		var m *ShardMember
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, nil
+}
+
+// Put a replica into the kv backend.
+func (s *ShardMemberStore) Put(m *ShardMember) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key(m.ID), b, nil)
+}
+
+// Remove a replica from the kv backend.
+func (s *ShardMemberStore) Remove(id string) error {
+	err := s.kv.Delete(s.key(id))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+type shards struct {
+	store    BotShardMemberStore
+	id       string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	members []string
+}
+
+// WithSharding shares the chats with the other replicas of the bot with the same store.
+// Every interval the replica sends a heartbeat to the store and updates the members from the heartbeats of the others.
+// The chats are assigned to the members by rendezvous hashing of their ID, so that a member joining or leaving only moves
+// the chats it gets or had. Every replica has to receive all webhooks, each sends the alerts to its own chats.
+// Only the member the updates are assigned to polls Telegram for commands.
+func WithSharding(members BotShardMemberStore, id string, interval time.Duration) BotOption {
+	return func(b *Bot) error {
+		if id == "" {
+			return errors.New("the shard member ID must not be empty")
+		}
+		if interval <= 0 {
+			return errors.New("the shard heartbeat interval must be positive")
+		}
+		b.shards = &shards{store: members, id: id, interval: interval, members: []string{id}}
+		return nil
+	}
+}
+
+// owns returns whether the key is assigned to this replica.
+func (s *shards) owns(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return shardOwner(s.members, key) == s.id
+}
+
+// shardOwner returns the member the key is assigned to, the one with the highest hash of the key and its ID.
+func shardOwner(members []string, key string) string {
+	var owner string
+	var highest uint64
+	for _, id := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(id + "/" + key))
+		if sum := h.Sum64(); owner == "" || sum > highest {
+			owner, highest = id, sum
+		}
+	}
+	return owner
+}
+
+// ownsChat returns whether this replica sends the alerts of the chat, always true without sharding.
+func (b *Bot) ownsChat(chatID int64) bool {
+	return b.shards == nil || b.shards.owns(strconv.FormatInt(chatID, 10))
+}
+
+// ownsUpdates returns whether this replica polls Telegram for updates, always true without sharding.
+func (b *Bot) ownsUpdates() bool {
+	return b.shards == nil || b.shards.owns(shardUpdates)
+}
+
+func (b *Bot) runShards(ctx context.Context) error {
+	ticker := time.NewTicker(b.shards.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The others take over the chats right away instead of waiting for the heartbeat to expire.
+			if err := b.shards.store.Remove(b.shards.id); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to remove shard member", "err", err)
+			}
+			return nil
+		case <-ticker.C:
+		}
+		previous, err := b.heartbeat(time.Now())
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to update shard members", "err", err)
+			continue
+		}
+		if previous == nil || b.idempotent == nil {
+			continue
+		}
+		// The alerts of the chats taken over from another member, which it received but didn't send, are sent now.
+		takenOver := func(chatID int64) bool {
+			key := strconv.FormatInt(chatID, 10)
+			return b.shards.owns(key) && shardOwner(previous, key) != b.shards.id
+		}
+		if err := b.sendPending(takenOver, "sending alerts taken over from another replica"); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send alerts taken over from another replica", "err", err)
+		}
+	}
+}
+
+// heartbeat puts this replica into the store and updates the members to the ones with a recent heartbeat.
+// It returns the previous members if they changed.
+func (b *Bot) heartbeat(now time.Time) ([]string, error) {
+	s := b.shards
+	if err := s.store.Put(&ShardMember{ID: s.id, SeenAt: now}); err != nil {
+		return nil, err
+	}
+	stored, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{s.id}
+	for _, m := range stored {
+		if m.ID != s.id && now.Sub(m.SeenAt) < shardExpiry*s.interval {
+			members = append(members, m.ID)
+		}
+	}
+	sort.Strings(members)
+
+	s.mu.Lock()
+	previous := s.members
+	s.members = members
+	s.mu.Unlock()
+
+	if strings.Join(members, ",") == strings.Join(previous, ",") {
+		return nil, nil
+	}
+	level.Info(b.logger).Log("msg", "shard members changed", "members", strings.Join(members, ","), "count", len(members))
+	return previous, nil
+}
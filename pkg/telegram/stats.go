@@ -0,0 +1,330 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/hako/durafmt"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	statsDefaultRange = 7 * 24 * time.Hour
+	statsTop          = 5
+)
+
+// myStatsWindows are the ranges the mystats command counts the alerts of a chat in.
+var myStatsWindows = [...]time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// windowCounts counts the alerts with the same severity or name in each of the myStatsWindows.
+type windowCounts struct {
+	name   string
+	counts [len(myStatsWindows)]int
+}
+
+// observe counts an alert that started age ago in the windows containing it.
+func (c *windowCounts) observe(age time.Duration) {
+	for i, w := range myStatsWindows {
+		if age <= w {
+			c.counts[i]++
+		}
+	}
+}
+
+func (c *windowCounts) String() string {
+	return fmt.Sprintf("%d / %d / %d", c.counts[0], c.counts[1], c.counts[2])
+}
+
+// rankWindowCounts returns the counts sorted by the largest window and then name, limited to the top n.
+func rankWindowCounts(counts map[string]*windowCounts, n int) []*windowCounts {
+	ranked := make([]*windowCounts, 0, len(counts))
+	for _, c := range counts {
+		ranked = append(ranked, c)
+	}
+	last := len(myStatsWindows) - 1
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].counts[last] != ranked[j].counts[last] {
+			return ranked[i].counts[last] > ranked[j].counts[last]
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// alertStats are the statistics of all alerts with the same name or namespace.
+type alertStats struct {
+	name     string
+	count    int
+	firing   time.Duration
+	acked    int
+	ack      time.Duration
+	resolved int
+	resolve  time.Duration
+}
+
+// observe adds a history entry to the stats.
+func (s *alertStats) observe(e *HistoryEntry) {
+	s.count++
+	if e.Acked() {
+		s.acked++
+		s.ack += e.AckedAt.Sub(e.StartsAt)
+	}
+	if e.Resolved() {
+		s.resolved++
+		s.resolve += e.EndsAt.Sub(e.StartsAt)
+	}
+}
+
+// mtta returns the mean time to acknowledge or an empty string without acks.
+func (s *alertStats) mtta() string {
+	if s.acked == 0 {
+		return ""
+	}
+	return durafmt.Parse((s.ack / time.Duration(s.acked)).Round(time.Minute)).String()
+}
+
+// mttr returns the mean time to resolve or an empty string without resolved alerts.
+func (s *alertStats) mttr() string {
+	if s.resolved == 0 {
+		return ""
+	}
+	return durafmt.Parse((s.resolve / time.Duration(s.resolved)).Round(time.Minute)).String()
+}
+
+// rankStats returns the stats sorted by count and then name, limited to the top n.
+func rankStats(stats map[string]*alertStats, n int) []*alertStats {
+	ranked := make([]*alertStats, 0, len(stats))
+	for _, s := range stats {
+		ranked = append(ranked, s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].count != ranked[j].count {
+			return ranked[i].count > ranked[j].count
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// alertKey identifies an alert across the chats it was sent to.
+func alertKey(fingerprint string, startsAt time.Time) string {
+	return fmt.Sprintf("%s-%d", fingerprint, startsAt.Unix())
+}
+
+// mergeHistory merges the entries of the same alert sent to multiple chats,
+// so that it is only counted once and the first ack in any chat counts.
+func mergeHistory(entries []*HistoryEntry) []*HistoryEntry {
+	merged := map[string]*HistoryEntry{}
+	var result []*HistoryEntry
+
+	for _, e := range entries {
+		key := alertKey(e.Fingerprint, e.StartsAt)
+		m, ok := merged[key]
+		if !ok {
+			m = &HistoryEntry{Fingerprint: e.Fingerprint, Labels: e.Labels, StartsAt: e.StartsAt}
+			merged[key] = m
+			result = append(result, m)
+		}
+		if e.Resolved() {
+			m.EndsAt = e.EndsAt
+		}
+		if e.Acked() && (!m.Acked() || e.AckedAt.Before(m.AckedAt)) {
+			m.AckedAt = e.AckedAt
+		}
+	}
+
+	return result
+}
+
+// renderStats renders the statistics of the alerts of the history firing in the range before now,
+// false if there weren't any.
+func renderStats(entries []*HistoryEntry, now time.Time, statsRange time.Duration) (string, bool) {
+	since := now.Add(-statsRange)
+
+	total := &alertStats{}
+	alertnames := map[string]*alertStats{}
+	namespaces := map[string]*alertStats{}
+	for _, e := range mergeHistory(entries) {
+		end := e.EndsAt
+		if !e.Resolved() {
+			end = now
+		}
+		if end.Before(since) {
+			continue
+		}
+
+		start := e.StartsAt
+		if start.Before(since) {
+			start = since
+		}
+
+		total.observe(e)
+		if alertnames[e.Name()] == nil {
+			alertnames[e.Name()] = &alertStats{name: e.Name()}
+		}
+		alertnames[e.Name()].observe(e)
+		alertnames[e.Name()].firing += end.Sub(start)
+
+		if ns, ok := e.Labels["namespace"]; ok {
+			if namespaces[ns] == nil {
+				namespaces[ns] = &alertStats{name: ns}
+			}
+			namespaces[ns].count++
+		}
+	}
+
+	if total.count == 0 {
+		return "", false
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "<b>Alert statistics for the last %s</b>\n", formatDuration(statsRange))
+	fmt.Fprintf(&out, "Alerts: %d (%d acknowledged, %d resolved)\n", total.count, total.acked, total.resolved)
+
+	out.WriteString("\n<b>Top alerts:</b>\n")
+	for _, s := range rankStats(alertnames, statsTop) {
+		fmt.Fprintf(&out, "%d× %s, firing %s\n", s.count, html.EscapeString(s.name), formatDuration(s.firing))
+	}
+
+	if len(namespaces) > 0 {
+		out.WriteString("\n<b>Noisiest namespaces:</b>\n")
+		for _, s := range rankStats(namespaces, statsTop) {
+			fmt.Fprintf(&out, "%d× %s\n", s.count, html.EscapeString(s.name))
+		}
+	}
+
+	if total.acked > 0 || total.resolved > 0 {
+		out.WriteString("\n")
+	}
+	if mtta := total.mtta(); mtta != "" {
+		fmt.Fprintf(&out, "<b>Mean time to acknowledge:</b> %s\n", mtta)
+	}
+	if mttr := total.mttr(); mttr != "" {
+		fmt.Fprintf(&out, "<b>Mean time to resolve:</b> %s\n", mttr)
+	}
+
+	if total.acked > 0 || total.resolved > 0 {
+		out.WriteString("\n<b>MTTA / MTTR by alert:</b>\n")
+		for _, s := range rankStats(alertnames, len(alertnames)) {
+			if s.acked == 0 && s.resolved == 0 {
+				continue
+			}
+			mtta, mttr := s.mtta(), s.mttr()
+			if mtta == "" {
+				mtta = "-"
+			}
+			if mttr == "" {
+				mttr = "-"
+			}
+			fmt.Fprintf(&out, "%s: %s / %s\n", html.EscapeString(s.name), mtta, mttr)
+		}
+	}
+
+	return out.String(), true
+}
+
+func (b *Bot) handleStats(message *telebot.Message) error {
+	if b.history == nil {
+		_, err := b.telegram.Send(message.Chat, "The alert history isn't enabled.")
+		return err
+	}
+
+	statsRange := statsDefaultRange
+	if payload := strings.TrimSpace(message.Payload); payload != "" {
+		d, err := model.ParseDuration(payload)
+		if err != nil {
+			_, err = b.telegram.Send(message.Chat, "Usage: "+CommandStats+" [range], e.g. "+CommandStats+" 7d")
+			return err
+		}
+		statsRange = time.Duration(d)
+	}
+
+	entries, err := b.history.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alert history", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the alert history.")
+		return err
+	}
+
+	out, ok := renderStats(entries, time.Now(), statsRange)
+	if !ok {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No alerts in the last %s! 🎉", durafmt.Parse(statsRange)))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, out, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+// handleMyStats shows a chat how many alerts it was sent in the last day, week and month, by severity and alertname.
+func (b *Bot) handleMyStats(message *telebot.Message) error {
+	if b.history == nil {
+		_, err := b.telegram.Send(message.Chat, "The alert history isn't enabled.")
+		return err
+	}
+
+	entries, err := b.history.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alert history", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the alert history.")
+		return err
+	}
+
+	now := time.Now()
+	total := &windowCounts{}
+	severities := map[string]*windowCounts{}
+	alertnames := map[string]*windowCounts{}
+	for _, e := range entries {
+		age := now.Sub(e.StartsAt)
+		if e.ChatID != message.Chat.ID || age > myStatsWindows[len(myStatsWindows)-1] {
+			continue
+		}
+
+		severity := e.Labels["severity"]
+		if severity == "" {
+			severity = "none"
+		}
+		if severities[severity] == nil {
+			severities[severity] = &windowCounts{name: severity}
+		}
+		if alertnames[e.Name()] == nil {
+			alertnames[e.Name()] = &windowCounts{name: e.Name()}
+		}
+		total.observe(age)
+		severities[severity].observe(age)
+		alertnames[e.Name()].observe(age)
+	}
+
+	if total.counts[len(myStatsWindows)-1] == 0 {
+		_, err = b.telegram.Send(message.Chat, "This chat wasn't sent any alerts in the last month! 🎉")
+		return err
+	}
+
+	var out strings.Builder
+	out.WriteString("<b>Alerts sent to this chat in the last day / week / month</b>\n")
+	fmt.Fprintf(&out, "All: %s\n", total)
+
+	out.WriteString("\n<b>By severity:</b>\n")
+	for _, c := range rankWindowCounts(severities, len(severities)) {
+		fmt.Fprintf(&out, "%s: %s\n", html.EscapeString(c.name), c)
+	}
+
+	out.WriteString("\n<b>Top alerts:</b>\n")
+	for _, c := range rankWindowCounts(alertnames, statsTop) {
+		fmt.Fprintf(&out, "%s: %s\n", html.EscapeString(c.name), c)
+	}
+
+	_, err = b.telegram.Send(message.Chat, out.String(), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
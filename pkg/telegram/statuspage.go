@@ -0,0 +1,349 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// StatuspageComponentLabel is the label of alerts with the ID of the Statuspage component they affect.
+const StatuspageComponentLabel = "statuspage_component"
+
+const responseStatuspageUsage = "Usage:\n" +
+	CommandStatuspage + " - List the Statuspage incidents.\n" +
+	CommandStatuspage + " confirm <component> - Create the proposed incident of a component on the Statuspage."
+
+// StatuspageIncidentNotFoundErr returned by the store if a component has no incident.
+var StatuspageIncidentNotFoundErr = errors.New("statuspage incident not found in store")
+
+// StatuspageIncident is the incident of a Statuspage component affected by firing alerts.
+type StatuspageIncident struct {
+	Component string `json:"component"`
+	// IncidentID is empty until an admin confirms the incident, only then it's created on the Statuspage.
+	IncidentID string `json:"incidentID,omitempty"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	// Alerts are the alertnames of the firing alerts affecting the component by their fingerprint.
+	Alerts      map[string]string `json:"alerts"`
+	ProposedAt  time.Time         `json:"proposedAt"`
+	ConfirmedBy string            `json:"confirmedBy,omitempty"`
+}
+
+// Confirmed returns whether an admin confirmed the incident.
+func (i *StatuspageIncident) Confirmed() bool {
+	return i.IncidentID != ""
+}
+
+// alertNames returns the sorted alertnames of the firing alerts.
+func (i *StatuspageIncident) alertNames() string {
+	seen := map[string]bool{}
+	names := make([]string, 0, len(i.Alerts))
+	for _, name := range i.Alerts {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// BotStatuspageStore keeps the Statuspage incidents by component.
+type BotStatuspageStore interface {
+	List() ([]*StatuspageIncident, error)
+	Get(component string) (*StatuspageIncident, error)
+	Put(*StatuspageIncident) error
+	Remove(component string) error
+}
+
+// StatuspageStore writes the Statuspage incidents to a libkv store backend.
+type StatuspageStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewStatuspageStore stores the Statuspage incidents in the provided kv backend.
+func NewStatuspageStore(kv store.Store, storeKeyPrefix string) (*StatuspageStore, error) {
+	return &StatuspageStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// List all Statuspage incidents saved in the kv backend.
+func (s *StatuspageStore) List() ([]*StatuspageIncident, error) {
+	kvPairs, err := s.kv.List(s.storeKeyPrefix)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var incidents []*StatuspageIncident
+	for _, kv := range kvPairs {
+		var i *StatuspageIncident
+		if err := json.Unmarshal(kv.Value, &i); err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, nil
+}
+
+// Get the Statuspage incident of a component.
+func (s *StatuspageStore) Get(component string) (*StatuspageIncident, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%s", s.storeKeyPrefix, component))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, StatuspageIncidentNotFoundErr
+		}
+		return nil, err
+	}
+	var i *StatuspageIncident
+	err = json.Unmarshal(kv.Value, &i)
+	return i, err
+}
+
+// Put a Statuspage incident into the kv backend.
+func (s *StatuspageStore) Put(i *StatuspageIncident) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%s", s.storeKeyPrefix, i.Component), b, nil)
+}
+
+// Remove the Statuspage incident of a component from the kv backend.
+func (s *StatuspageStore) Remove(component string) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%s", s.storeKeyPrefix, component))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Statuspage creates, updates and resolves the incidents of a page, e.g. a statuspage.Client.
+type Statuspage interface {
+	CreateIncident(ctx context.Context, name, body string, components []string) (string, error)
+	UpdateIncident(ctx context.Context, id, body string) error
+	ResolveIncident(ctx context.Context, id, body string, components []string) error
+}
+
+type statuspageSync struct {
+	page  Statuspage
+	store BotStatuspageStore
+	// mu serializes the changes of the webhooks and the command to the incidents.
+	mu sync.Mutex
+}
+
+// WithStatuspage proposes an incident on the Statuspage to the admins for the component of firing alerts with the StatuspageComponentLabel.
+// An incident is only created once an admin confirms it with /statuspage confirm, then it's updated with the alerts affecting the component
+// and resolved when all of them resolved.
+func WithStatuspage(page Statuspage, s BotStatuspageStore) BotOption {
+	return func(b *Bot) error {
+		b.statuspage = &statuspageSync{page: page, store: s}
+		return nil
+	}
+}
+
+// syncStatuspage updates the incidents of the components the webhook's alerts affect.
+func (b *Bot) syncStatuspage(ctx context.Context, m webhook.Message) {
+	b.statuspage.mu.Lock()
+	defer b.statuspage.mu.Unlock()
+
+	for _, a := range m.Alerts {
+		component := a.Labels[StatuspageComponentLabel]
+		if component == "" {
+			continue
+		}
+		ctx, cancel := b.requestContext(ctx)
+		err := b.syncStatuspageAlert(ctx, component, a)
+		cancel()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to sync statuspage incident", "component", component, "err", err)
+		}
+	}
+}
+
+func (b *Bot) syncStatuspageAlert(ctx context.Context, component string, a template.Alert) error {
+	firing := a.Status == string(model.AlertFiring)
+	fingerprint := alertFingerprint(a)
+
+	incident, err := b.statuspage.store.Get(component)
+	if errors.Is(err, StatuspageIncidentNotFoundErr) {
+		if !firing {
+			return nil
+		}
+		incident = &StatuspageIncident{
+			Component:  component,
+			Name:       statuspageSummary(a),
+			Body:       "We're investigating the issue.",
+			Alerts:     map[string]string{},
+			ProposedAt: time.Now(),
+		}
+		if description := a.Annotations["description"]; description != "" {
+			incident.Body = description
+		}
+	} else if err != nil {
+		return err
+	}
+	_, known := incident.Alerts[fingerprint]
+
+	if firing {
+		if known {
+			return nil
+		}
+		incident.Alerts[fingerprint] = a.Labels[string(model.AlertNameLabel)]
+		if incident.Confirmed() && len(incident.Alerts) > 1 {
+			if err := b.statuspage.page.UpdateIncident(ctx, incident.IncidentID, "Also affected: "+statuspageSummary(a)); err != nil {
+				return err
+			}
+			level.Info(b.logger).Log("msg", "statuspage incident updated", "component", component, "id", incident.IncidentID)
+		}
+		if err := b.statuspage.store.Put(incident); err != nil {
+			return err
+		}
+		if len(incident.Alerts) == 1 {
+			b.proposeStatuspageIncident(incident)
+		}
+		return nil
+	}
+
+	if !known {
+		return nil
+	}
+	delete(incident.Alerts, fingerprint)
+	if len(incident.Alerts) > 0 {
+		return b.statuspage.store.Put(incident)
+	}
+	if incident.Confirmed() {
+		if err := b.statuspage.page.ResolveIncident(ctx, incident.IncidentID, "This incident has been resolved.", []string{component}); err != nil {
+			return err
+		}
+		level.Info(b.logger).Log("msg", "statuspage incident resolved", "component", component, "id", incident.IncidentID)
+	}
+	return b.statuspage.store.Remove(component)
+}
+
+// statuspageSummary returns the summary annotation of an alert, or its alertname if it has none.
+func statuspageSummary(a template.Alert) string {
+	if summary := a.Annotations["summary"]; summary != "" {
+		return summary
+	}
+	return a.Labels[string(model.AlertNameLabel)]
+}
+
+// proposeStatuspageIncident asks the admins to confirm the incident.
+func (b *Bot) proposeStatuspageIncident(i *StatuspageIncident) {
+	level.Info(b.logger).Log("msg", "statuspage incident proposed", "component", i.Component)
+
+	text := fmt.Sprintf("🚦 %s affects the Statuspage component %s.\nCreate the public incident %q with %s confirm %s",
+		i.alertNames(), i.Component, i.Name, CommandStatuspage, i.Component,
+	)
+	for _, id := range b.admins {
+		if _, err := b.telegram.Send(&telebot.User{ID: id}, text); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send statuspage proposal", "admin_id", id, "err", err)
+		}
+	}
+}
+
+func (b *Bot) handleStatuspage(message *telebot.Message) error {
+	if b.statuspage == nil {
+		_, err := b.telegram.Send(message.Chat, "Statuspage incidents aren't enabled.")
+		return err
+	}
+
+	args := strings.Fields(message.Payload)
+	switch {
+	case len(args) == 0:
+		return b.listStatuspage(message)
+	case args[0] == "confirm" && len(args) == 2:
+		return b.confirmStatuspage(message, args[1])
+	}
+	_, err := b.telegram.Send(message.Chat, responseStatuspageUsage)
+	return err
+}
+
+func (b *Bot) listStatuspage(message *telebot.Message) error {
+	incidents, err := b.statuspage.store.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list statuspage incidents", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the Statuspage incidents.")
+		return err
+	}
+	if len(incidents) == 0 {
+		_, err = b.telegram.Send(message.Chat, "No alerts affect Statuspage components.")
+		return err
+	}
+
+	out := "Statuspage incidents:\n"
+	for _, i := range incidents {
+		if i.Confirmed() {
+			out += fmt.Sprintf("%s: incident %s, %s (%s)\n", i.Component, i.IncidentID, i.Name, i.alertNames())
+		} else {
+			out += fmt.Sprintf("%s: waiting for %s confirm %s, %s (%s)\n", i.Component, CommandStatuspage, i.Component, i.Name, i.alertNames())
+		}
+	}
+	_, err = b.telegram.Send(message.Chat, strings.TrimSuffix(out, "\n"))
+	return err
+}
+
+// confirmStatuspage creates the proposed incident of the component on the Statuspage.
+func (b *Bot) confirmStatuspage(message *telebot.Message, component string) error {
+	if !b.isAdminID(message.Sender.ID) {
+		_, err := b.telegram.Send(message.Chat, "Only admins can confirm Statuspage incidents.")
+		return err
+	}
+
+	b.statuspage.mu.Lock()
+	defer b.statuspage.mu.Unlock()
+
+	incident, err := b.statuspage.store.Get(component)
+	if err != nil {
+		if errors.Is(err, StatuspageIncidentNotFoundErr) {
+			_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No firing alerts affect the Statuspage component %s.", component))
+			return err
+		}
+		level.Warn(b.logger).Log("msg", "failed to get statuspage incident", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the Statuspage incident.")
+		return err
+	}
+	if incident.Confirmed() {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("The incident %s of %s was confirmed by %s already.", incident.IncidentID, component, incident.ConfirmedBy))
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	id, err := b.statuspage.page.CreateIncident(ctx, incident.Name, incident.Body, []string{component})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create statuspage incident", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't create the Statuspage incident.")
+		return err
+	}
+	incident.IncidentID = id
+	incident.ConfirmedBy = message.Sender.Username
+
+	level.Info(b.logger).Log(
+		"msg", "statuspage incident created",
+		"component", component,
+		"id", id,
+		"username", message.Sender.Username,
+	)
+	if err := b.statuspage.store.Put(incident); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put statuspage incident", "err", err)
+	}
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Created the Statuspage incident %s %q for %s.", id, incident.Name, component))
+	return err
+}
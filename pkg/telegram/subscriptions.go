@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// Subscription is a chat subscribed by configuration instead of the start command.
+type Subscription struct {
+	ChatID int64
+	Title  string
+	// Matchers are the chat's filter, the chat receives all alerts without matchers.
+	Matchers []string
+	// Muted are the usernames not mentioned in the chat's alerts, they are left alone if nil.
+	Muted []string
+}
+
+// declaredSubscriptions are reconciled into the stores when the bot starts.
+type declaredSubscriptions struct {
+	subscriptions []Subscription
+	prune         bool
+}
+
+// WithSubscriptions subscribes the chats with their filters and muted users when the bot starts.
+// With prune all other chats are unsubscribed, otherwise chats subscribed with the start command are kept.
+func WithSubscriptions(subscriptions []Subscription, prune bool) BotOption {
+	return func(b *Bot) error {
+		seen := map[int64]bool{}
+		for _, s := range subscriptions {
+			if s.ChatID == 0 {
+				return errors.New("subscription has no chat id")
+			}
+			if seen[s.ChatID] {
+				return fmt.Errorf("chat %d is subscribed more than once", s.ChatID)
+			}
+			seen[s.ChatID] = true
+			f := &ChatFilter{ChatID: s.ChatID, Matchers: s.Matchers}
+			if err := f.Validate(); err != nil {
+				return fmt.Errorf("subscription of chat %d: %w", s.ChatID, err)
+			}
+		}
+		b.subscribed = &declaredSubscriptions{subscriptions: subscriptions, prune: prune}
+		return nil
+	}
+}
+
+// reconcileSubscriptions subscribes the declared chats and, if pruning, unsubscribes all others.
+func (b *Bot) reconcileSubscriptions() error {
+	declared := map[int64]bool{}
+	for _, s := range b.subscribed.subscriptions {
+		declared[s.ChatID] = true
+		if err := b.Subscribe(s); err != nil {
+			return err
+		}
+	}
+
+	removed := 0
+	if b.subscribed.prune {
+		chats, err := b.chats.List()
+		if err != nil {
+			return fmt.Errorf("failed to list chats: %w", err)
+		}
+		for _, chat := range chats {
+			if declared[chat.ID] {
+				continue
+			}
+			if err := b.Unsubscribe(chat.ID); err != nil {
+				return err
+			}
+			removed++
+		}
+	}
+
+	level.Info(b.logger).Log("msg", "subscriptions reconciled", "subscribed", len(declared), "unsubscribed", removed)
+	return nil
+}
+
+// Subscribe subscribes the chat of the subscription, replacing its filter and, unless nil, its muted users.
+func (b *Bot) Subscribe(s Subscription) error {
+	chat, err := b.chats.Get(telebot.ChatID(s.ChatID))
+	if err != nil {
+		if !errors.Is(err, ChatNotFoundErr) {
+			return fmt.Errorf("failed to get chat %d: %w", s.ChatID, err)
+		}
+		// Group chats have negative IDs.
+		chat = &telebot.Chat{ID: s.ChatID, Type: telebot.ChatPrivate}
+		if s.ChatID < 0 {
+			chat.Type = telebot.ChatGroup
+		}
+	}
+	if s.Title != "" {
+		chat.Title = s.Title
+	}
+	if err := b.chats.Add(chat); err != nil {
+		return fmt.Errorf("failed to subscribe chat %d: %w", s.ChatID, err)
+	}
+
+	if len(s.Matchers) > 0 && b.filters == nil {
+		return fmt.Errorf("chat %d has matchers but filters aren't enabled", s.ChatID)
+	}
+	if b.filters != nil {
+		if len(s.Matchers) > 0 {
+			err = b.filters.Put(&ChatFilter{ChatID: s.ChatID, Matchers: s.Matchers})
+		} else {
+			err = b.filters.Remove(s.ChatID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set filter of chat %d: %w", s.ChatID, err)
+		}
+	}
+
+	if s.Muted != nil && b.mutes != nil {
+		if err := b.mutes.Put(&ChatMutes{ChatID: s.ChatID, Usernames: s.Muted}); err != nil {
+			return fmt.Errorf("failed to set muted users of chat %d: %w", s.ChatID, err)
+		}
+	}
+	return nil
+}
+
+// Unsubscribe unsubscribes the chat and removes its filter, chats that aren't subscribed are ignored.
+func (b *Bot) Unsubscribe(chatID int64) error {
+	if err := b.chats.Remove(&telebot.Chat{ID: chatID}); err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return fmt.Errorf("failed to unsubscribe chat %d: %w", chatID, err)
+	}
+	if b.filters != nil {
+		if err := b.filters.Remove(chatID); err != nil {
+			return fmt.Errorf("failed to remove filter of chat %d: %w", chatID, err)
+		}
+	}
+	return nil
+}
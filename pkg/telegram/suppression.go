@@ -0,0 +1,124 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// annotationSilencedBy shows the IDs of the silences matching an alert.
+	annotationSilencedBy = "silenced_by"
+	// annotationInhibitedBy shows the alerts inhibiting an alert.
+	annotationInhibitedBy = "inhibited_by"
+)
+
+// WithSuppressionStatus asks Alertmanager for the silences and inhibitions of forwarded alerts
+// and shows them as annotations, alerts listed with /alerts always show them.
+func WithSuppressionStatus() BotOption {
+	return func(b *Bot) error {
+		b.suppression = true
+		return nil
+	}
+}
+
+// suppressionAnnotations returns the annotations showing why alerts are suppressed by fingerprint.
+// Inhibiting alerts are shown by their alertname if they're among the alerts, otherwise by their fingerprint.
+func suppressionAnnotations(alerts []alertmanager.Alert) map[string]map[string]string {
+	names := make(map[string]string, len(alerts))
+	for _, a := range alerts {
+		names[a.Fingerprint().String()] = string(a.Labels[model.AlertNameLabel])
+	}
+
+	annotations := map[string]map[string]string{}
+	for _, a := range alerts {
+		if len(a.SilencedBy) == 0 && len(a.InhibitedBy) == 0 {
+			continue
+		}
+		suppressed := map[string]string{}
+		if len(a.SilencedBy) > 0 {
+			suppressed[annotationSilencedBy] = strings.Join(a.SilencedBy, ", ")
+		}
+		if len(a.InhibitedBy) > 0 {
+			inhibitors := make([]string, 0, len(a.InhibitedBy))
+			for _, fingerprint := range a.InhibitedBy {
+				if name, ok := names[fingerprint]; ok && name != "" {
+					inhibitors = append(inhibitors, name)
+					continue
+				}
+				inhibitors = append(inhibitors, fingerprint)
+			}
+			suppressed[annotationInhibitedBy] = strings.Join(inhibitors, ", ")
+		}
+		annotations[a.Fingerprint().String()] = suppressed
+	}
+	return annotations
+}
+
+// suppressedAlerts returns the alerts with annotations showing why they're suppressed.
+func suppressedAlerts(alerts []alertmanager.Alert) []*types.Alert {
+	annotations := suppressionAnnotations(alerts)
+
+	out := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		suppressed, ok := annotations[a.Fingerprint().String()]
+		if !ok {
+			out = append(out, a.Alert)
+			continue
+		}
+
+		alert := *a.Alert
+		alert.Annotations = a.Annotations.Clone()
+		for name, value := range suppressed {
+			alert.Annotations[model.LabelName(name)] = model.LabelValue(value)
+		}
+		out = append(out, &alert)
+	}
+	return out
+}
+
+// annotateSuppression returns the message with annotations showing why its firing alerts are suppressed.
+// The message is returned as is if Alertmanager can't be asked.
+func (b *Bot) annotateSuppression(ctx context.Context, m webhook.Message) webhook.Message {
+	if m.Status != string(model.AlertFiring) || b.alertmanager == nil {
+		return m
+	}
+
+	ctx, cancel := b.requestContext(ctx)
+	defer cancel()
+	amAlerts, err := b.alertmanager.ListAlertStatuses(ctx, m.Receiver, true)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts to show their suppression", "receiver", m.Receiver, "err", err)
+		return m
+	}
+	annotations := suppressionAnnotations(amAlerts)
+	if len(annotations) == 0 {
+		return m
+	}
+
+	alerts := make(template.Alerts, 0, len(m.Alerts))
+	for _, a := range m.Alerts {
+		suppressed, ok := annotations[a.Fingerprint]
+		if !ok || a.Status != string(model.AlertFiring) {
+			alerts = append(alerts, a)
+			continue
+		}
+		kv := make(template.KV, len(a.Annotations)+len(suppressed))
+		for name, value := range a.Annotations {
+			kv[name] = value
+		}
+		for name, value := range suppressed {
+			kv[name] = value
+		}
+		a.Annotations = kv
+		alerts = append(alerts, a)
+	}
+
+	return withAlerts(m, alerts)
+}
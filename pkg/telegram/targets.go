@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func (b *Bot) handleTargets(message *telebot.Message) error {
+	if b.prometheus == nil {
+		_, err := b.telegram.Send(message.Chat, "Querying Prometheus isn't configured.")
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	targets, err := b.prometheus.Targets(ctx)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list targets", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list targets... %v", err))
+		return err
+	}
+
+	var out strings.Builder
+	if strings.TrimSpace(message.Payload) == "down" {
+		var down []v1.ActiveTarget
+		for _, t := range targets {
+			if t.Health != v1.HealthGood {
+				down = append(down, t)
+			}
+		}
+		if len(down) == 0 {
+			_, err = b.telegram.Send(message.Chat, "All targets are up! 🎉")
+			return err
+		}
+
+		fmt.Fprintf(&out, "<b>Down targets (%d of %d):</b>\n", len(down), len(targets))
+		for _, t := range down {
+			fmt.Fprintf(&out, "❌ %s %s", html.EscapeString(string(t.Labels["job"])), html.EscapeString(t.ScrapeURL))
+			if t.LastError != "" {
+				fmt.Fprintf(&out, "\n    %s", html.EscapeString(t.LastError))
+			}
+			out.WriteString("\n")
+		}
+	} else {
+		up := map[string]int{}
+		total := map[string]int{}
+		for _, t := range targets {
+			job := string(t.Labels["job"])
+			total[job]++
+			if t.Health == v1.HealthGood {
+				up[job]++
+			}
+		}
+		jobs := make([]string, 0, len(total))
+		for job := range total {
+			jobs = append(jobs, job)
+		}
+		sort.Strings(jobs)
+
+		fmt.Fprintf(&out, "<b>Targets by job (up/total):</b>\n")
+		for _, job := range jobs {
+			emoji := "✅"
+			if up[job] < total[job] {
+				emoji = "❌"
+			}
+			fmt.Fprintf(&out, "%s %s: %d/%d\n", emoji, html.EscapeString(job), up[job], total[job])
+		}
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(out.String()), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+func (b *Bot) handleRules(message *telebot.Message) error {
+	if b.prometheus == nil {
+		_, err := b.telegram.Send(message.Chat, "Querying Prometheus isn't configured.")
+		return err
+	}
+
+	name := strings.TrimSpace(message.Payload)
+	if name == "" {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandRules+" <name>")
+		return err
+	}
+
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	groups, err := b.prometheus.Rules(ctx)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list rules", "err", err)
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("failed to list rules... %v", err))
+		return err
+	}
+
+	var rules []string
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			switch rule := r.(type) {
+			case v1.AlertingRule:
+				if rule.Name == name {
+					rules = append(rules, formatAlertingRule(g, rule))
+				}
+			case v1.RecordingRule:
+				if rule.Name == name {
+					rules = append(rules, formatRecordingRule(g, rule))
+				}
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("No rule named %s found.", name))
+		return err
+	}
+
+	_, err = b.telegram.Send(message.Chat, b.truncateMessage(strings.Join(rules, "\n\n")), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	return err
+}
+
+func formatAlertingRule(g v1.RuleGroup, r v1.AlertingRule) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "🔔 <b>%s</b> (%s)\n", html.EscapeString(r.Name), html.EscapeString(g.Name))
+	fmt.Fprintf(&out, "<pre>%s</pre>\n", html.EscapeString(r.Query))
+	if r.Duration > 0 {
+		fmt.Fprintf(&out, "<b>For:</b> %s\n", time.Duration(r.Duration*float64(time.Second)))
+	}
+	fmt.Fprintf(&out, "<b>Health:</b> %s, <b>Active alerts:</b> %d", r.Health, len(r.Alerts))
+	if r.LastError != "" {
+		fmt.Fprintf(&out, "\n<b>Error:</b> %s", html.EscapeString(r.LastError))
+	}
+	if len(r.Labels) > 0 {
+		out.WriteString("\n<b>Labels:</b>")
+		for _, name := range sortedLabelNames(r.Labels) {
+			fmt.Fprintf(&out, "\n    %s: %s", html.EscapeString(string(name)), html.EscapeString(string(r.Labels[name])))
+		}
+	}
+	if len(r.Annotations) > 0 {
+		out.WriteString("\n<b>Annotations:</b>")
+		for _, name := range sortedLabelNames(r.Annotations) {
+			fmt.Fprintf(&out, "\n    %s: %s", html.EscapeString(string(name)), html.EscapeString(string(r.Annotations[name])))
+		}
+	}
+	return out.String()
+}
+
+func formatRecordingRule(g v1.RuleGroup, r v1.RecordingRule) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "⏺ <b>%s</b> (%s)\n", html.EscapeString(r.Name), html.EscapeString(g.Name))
+	fmt.Fprintf(&out, "<pre>%s</pre>\n", html.EscapeString(r.Query))
+	fmt.Fprintf(&out, "<b>Health:</b> %s", r.Health)
+	if r.LastError != "" {
+		fmt.Fprintf(&out, "\n<b>Error:</b> %s", html.EscapeString(r.LastError))
+	}
+	return out.String()
+}
+
+func sortedLabelNames(ls model.LabelSet) []model.LabelName {
+	names := make([]model.LabelName, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,318 @@
+// Package telegramtest provides test doubles of Telegram and the chat store,
+// so that bots can be tested end to end without connecting to Telegram.
+//
+//	tg, err := telegramtest.New()
+//	bot, err := telegram.NewBotWithTelegram(&telegramtest.ChatStore{}, tg, adminID, opts...)
+//	go bot.Run(ctx, webhooks)
+//	<-tg.Started()
+//	tg.Process(telebot.Update{Message: &telebot.Message{Sender: admin, Chat: chat, Text: "/status"}})
+//	replies, err := tg.WaitReplies(ctx, 1)
+package telegramtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ChatStore keeps the chats in memory. The zero value is an empty store.
+type ChatStore struct {
+	mu    sync.Mutex
+	chats map[int64]*telebot.Chat
+}
+
+// List returns the chats ordered by their ID.
+func (s *ChatStore) List() ([]*telebot.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chats := make([]*telebot.Chat, 0, len(s.chats))
+	for _, chat := range s.chats {
+		chats = append(chats, chat)
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].ID < chats[j].ID })
+	return chats, nil
+}
+
+// Get returns the chat or telegram.ChatNotFoundErr.
+func (s *ChatStore) Get(id telebot.ChatID) (*telebot.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[int64(id)]
+	if !ok {
+		return nil, telegram.ChatNotFoundErr
+	}
+	return chat, nil
+}
+
+// Add adds or replaces the chat.
+func (s *ChatStore) Add(c *telebot.Chat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chats == nil {
+		s.chats = make(map[int64]*telebot.Chat)
+	}
+	s.chats[c.ID] = c
+	return nil
+}
+
+// Remove removes the chat, removing a chat that isn't stored is no error.
+func (s *ChatStore) Remove(c *telebot.Chat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.chats, c.ID)
+	return nil
+}
+
+// Reply is a message the bot sent, edited or answered with.
+// The recipient of edits is "edit:" and the message's ID, of inline query answers "query:" and the query's ID
+// and of the bot's commands "commands".
+type Reply struct {
+	Recipient string
+	Message   string
+}
+
+// Telegram handles the updates of a bot without connecting to Telegram and keeps its replies.
+type Telegram struct {
+	bot         *telebot.Bot
+	unreachable int32
+
+	started     chan struct{}
+	startedOnce sync.Once
+	stop        chan struct{}
+	stopOnce    sync.Once
+
+	mu      sync.Mutex
+	replies []Reply
+	// changed is closed and replaced whenever a reply is added.
+	changed chan struct{}
+	// chats are returned by ChatByID, see SetChat.
+	chats map[int64]*telebot.Chat
+	// react handles reactions, see React.
+	react func(*telegram.Reaction)
+}
+
+// Me is the fake bot's own user, e.g. to process updates adding it to a group or removing it.
+var Me = &telebot.User{ID: 1000, FirstName: "Alertmanager", Username: "alertmanager_bot", IsBot: true}
+
+// New returns a Telegram for a bot created with telegram.NewBotWithTelegram.
+func New() (*Telegram, error) {
+	bot, err := telebot.NewBot(telebot.Settings{
+		Offline: true,
+		Poller:  &telebot.LongPoller{},
+		// Handle the updates right away in the order they're processed.
+		Synchronous: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bot.Me = Me
+	return &Telegram{
+		bot:     bot,
+		started: make(chan struct{}),
+		stop:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Me returns the fake bot's own user.
+func (t *Telegram) Me() *telebot.User {
+	return Me
+}
+
+// Started is closed once the bot runs and has registered its handlers.
+func (t *Telegram) Started() <-chan struct{} {
+	return t.started
+}
+
+// Process handles the update with the bot's handlers and returns once they're done, or queued for its command workers.
+// The bot has to be started, see Started.
+func (t *Telegram) Process(u telebot.Update) {
+	t.bot.ProcessUpdate(u)
+}
+
+// React handles the reaction with the bot's handler and returns once it's done.
+// Reactions are dropped if the bot doesn't handle them.
+func (t *Telegram) React(r *telegram.Reaction) {
+	if t.react != nil {
+		t.react(r)
+	}
+}
+
+// SetChat sets the chat as Telegram knows it now, e.g. after a user renamed themselves.
+func (t *Telegram) SetChat(chat *telebot.Chat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.chats == nil {
+		t.chats = make(map[int64]*telebot.Chat)
+	}
+	t.chats[chat.ID] = chat
+}
+
+// SetUnreachable fails the next n sends, as if Telegram was down.
+func (t *Telegram) SetUnreachable(n int32) {
+	atomic.StoreInt32(&t.unreachable, n)
+}
+
+// Replies returns the replies so far.
+func (t *Telegram) Replies() []Reply {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Reply(nil), t.replies...)
+}
+
+// WaitReplies waits until the bot replied at least n times and returns the replies.
+// It returns the replies so far and an error if the context is done before.
+func (t *Telegram) WaitReplies(ctx context.Context, n int) ([]Reply, error) {
+	for {
+		t.mu.Lock()
+		replies, changed := append([]Reply(nil), t.replies...), t.changed
+		t.mu.Unlock()
+
+		if len(replies) >= n {
+			return replies, nil
+		}
+		select {
+		case <-ctx.Done():
+			return replies, fmt.Errorf("got %d of %d replies: %w", len(replies), n, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func (t *Telegram) add(r Reply) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.replies = append(t.replies, r)
+	close(t.changed)
+	t.changed = make(chan struct{})
+	return len(t.replies)
+}
+
+// Start marks the bot as started and blocks until it's stopped.
+func (t *Telegram) Start() {
+	t.startedOnce.Do(func() { close(t.started) })
+	<-t.stop
+}
+
+func (t *Telegram) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+func (t *Telegram) Send(to telebot.Recipient, message interface{}, _ ...interface{}) (*telebot.Message, error) {
+	for n := atomic.LoadInt32(&t.unreachable); n > 0; n = atomic.LoadInt32(&t.unreachable) {
+		if atomic.CompareAndSwapInt32(&t.unreachable, n, n-1) {
+			return nil, &url.Error{Op: "Post", URL: "https://api.telegram.org/sendMessage", Err: errors.New("connection refused")}
+		}
+	}
+	var text string
+	switch m := message.(type) {
+	case string:
+		text = m
+	case *telebot.Document:
+		text = "document:" + m.FileName
+	case *telebot.Voice:
+		audio, err := ioutil.ReadAll(m.FileReader)
+		if err != nil {
+			return nil, err
+		}
+		text = "voice:" + string(audio)
+	case *telebot.Location:
+		text = fmt.Sprintf("location:%g,%g", m.Lat, m.Lng)
+	case *telebot.Photo:
+		text = "photo"
+	default:
+		return nil, errors.New("message is neither a string, a document, a photo, a voice message nor a location")
+	}
+	id := t.add(Reply{Recipient: to.Recipient(), Message: text})
+	chatID, _ := strconv.ParseInt(to.Recipient(), 10, 64)
+	return &telebot.Message{ID: id, Chat: &telebot.Chat{ID: chatID}, Text: text}, nil
+}
+
+func (t *Telegram) Edit(msg telebot.Editable, message interface{}, _ ...interface{}) (*telebot.Message, error) {
+	text, ok := message.(string)
+	if !ok {
+		return nil, errors.New("message is not a string")
+	}
+	messageID, _ := msg.MessageSig()
+	t.add(Reply{Recipient: "edit:" + messageID, Message: text})
+	return &telebot.Message{Text: text}, nil
+}
+
+func (t *Telegram) Pin(_ telebot.Editable, _ ...interface{}) error {
+	return nil
+}
+
+func (t *Telegram) Respond(_ *telebot.Callback, _ ...*telebot.CallbackResponse) error {
+	return nil
+}
+
+func (t *Telegram) Answer(q *telebot.Query, resp *telebot.QueryResponse) error {
+	texts := make([]string, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		a, ok := r.(*telebot.ArticleResult)
+		if !ok {
+			continue
+		}
+		text := a.Text
+		if a.Content != nil {
+			if c, ok := (*a.Content).(*telebot.InputTextMessageContent); ok {
+				text = c.Text
+			}
+		}
+		texts = append(texts, a.Title+": "+strings.TrimSpace(text))
+	}
+	t.add(Reply{Recipient: "query:" + q.ID, Message: strings.Join(texts, "\n\n")})
+	return nil
+}
+
+func (t *Telegram) Notify(_ telebot.Recipient, _ telebot.ChatAction) error {
+	return nil
+}
+
+func (t *Telegram) Handle(endpoint interface{}, handler interface{}) {
+	if h, ok := handler.(func(*telegram.Reaction)); ok && endpoint == telegram.OnReaction {
+		t.react = h
+		return
+	}
+	t.bot.Handle(endpoint, handler)
+}
+
+func (t *Telegram) SetCommands(cmds []telebot.Command) error {
+	names := make([]string, 0, len(cmds))
+	for _, c := range cmds {
+		names = append(names, c.Text)
+	}
+	t.add(Reply{Recipient: "commands", Message: strings.Join(names, " ")})
+	return nil
+}
+
+// ChatByID returns the chat set with SetChat.
+func (t *Telegram) ChatByID(id string) (*telebot.Chat, error) {
+	chatID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	chat, ok := t.chats[chatID]
+	if !ok {
+		return nil, errors.New("chat not found")
+	}
+	c := *chat
+	return &c, nil
+}
@@ -0,0 +1,95 @@
+package telegramtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func TestChatStore(t *testing.T) {
+	s := &ChatStore{}
+
+	chats, err := s.List()
+	require.NoError(t, err)
+	require.Empty(t, chats)
+
+	require.NoError(t, s.Add(&telebot.Chat{ID: 123}))
+	require.NoError(t, s.Add(&telebot.Chat{ID: -1234, Title: "sre"}))
+	chats, err = s.List()
+	require.NoError(t, err)
+	require.Equal(t, []*telebot.Chat{{ID: -1234, Title: "sre"}, {ID: 123}}, chats)
+
+	chat, err := s.Get(-1234)
+	require.NoError(t, err)
+	require.Equal(t, "sre", chat.Title)
+
+	require.NoError(t, s.Remove(&telebot.Chat{ID: -1234}))
+	_, err = s.Get(-1234)
+	require.Equal(t, telegram.ChatNotFoundErr, err)
+	require.NoError(t, s.Remove(&telebot.Chat{ID: -1234}))
+}
+
+func TestTelegram(t *testing.T) {
+	tg, err := New()
+	require.NoError(t, err)
+
+	tg.Handle("/ping", func(m *telebot.Message) {
+		_, _ = tg.Send(m.Chat, "pong")
+	})
+	go tg.Start()
+	defer tg.Stop()
+	<-tg.Started()
+
+	chat := &telebot.Chat{ID: 123, Type: telebot.ChatPrivate}
+	tg.Process(telebot.Update{Message: &telebot.Message{Sender: &telebot.User{ID: 123}, Chat: chat, Text: "/ping"}})
+	require.Equal(t, []Reply{{Recipient: "123", Message: "pong"}}, tg.Replies(), "updates are handled right away")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = tg.Send(chat, "later")
+	}()
+	replies, err := tg.WaitReplies(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, Reply{Recipient: "123", Message: "later"}, replies[1])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	replies, err = tg.WaitReplies(ctx, 3)
+	require.Error(t, err)
+	require.Len(t, replies, 2)
+
+	tg.SetUnreachable(1)
+	_, err = tg.Send(chat, "lost")
+	require.Error(t, err)
+	msg, err := tg.Send(chat, "sent")
+	require.NoError(t, err)
+	require.Equal(t, 3, msg.ID)
+}
+
+func TestTelegramServiceMessages(t *testing.T) {
+	tg, err := New()
+	require.NoError(t, err)
+
+	tg.Handle(telebot.OnAddedToGroup, func(m *telebot.Message) {
+		_, _ = tg.Send(m.Chat, "added")
+	})
+	tg.Handle(telebot.OnUserLeft, func(m *telebot.Message) {
+		_, _ = tg.Send(m.Chat, "left "+m.UserLeft.Username)
+	})
+	go tg.Start()
+	defer tg.Stop()
+	<-tg.Started()
+
+	chat := &telebot.Chat{ID: -1234, Type: telebot.ChatGroup}
+	sender := &telebot.User{ID: 123}
+	tg.Process(telebot.Update{Message: &telebot.Message{Sender: sender, Chat: chat, UserJoined: tg.Me()}})
+	tg.Process(telebot.Update{Message: &telebot.Message{Sender: sender, Chat: chat, UserLeft: tg.Me()}})
+	require.Equal(t, []Reply{
+		{Recipient: "-1234", Message: "added"},
+		{Recipient: "-1234", Message: "left alertmanager_bot"},
+	}, tg.Replies())
+}
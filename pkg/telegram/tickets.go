@@ -0,0 +1,216 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ticketTitleLimit is the maximum length of a ticket's title.
+const ticketTitleLimit = 200
+
+// ticketButton is the inline button that opens a ticket for a firing alert.
+var ticketButton = telebot.InlineButton{Unique: "ticket", Text: "Create ticket"}
+
+// IssueTracker opens issues and returns their URL, e.g. a tickets.Jira or tickets.GitHub.
+type IssueTracker interface {
+	CreateIssue(ctx context.Context, title, body string) (string, error)
+}
+
+// WithTickets opens tickets about alerts in the issue tracker with /ticket
+// and adds a button to messages with firing alerts that does the same.
+func WithTickets(t IssueTracker) BotOption {
+	return func(b *Bot) error {
+		b.tickets = t
+		return nil
+	}
+}
+
+// addTicketButtons adds a ticket button for each firing alert to the markup.
+func (b *Bot) addTicketButtons(markup *telebot.ReplyMarkup, alerts template.Alerts) *telebot.ReplyMarkup {
+	if b.tickets == nil || b.alerts == nil {
+		return markup
+	}
+
+	firing := firingAlerts(alerts)
+	var buttons [][]telebot.InlineButton
+	for _, a := range firing {
+		button := ticketButton
+		button.Data = alertFingerprint(a)
+		if len(firing) > 1 {
+			button.Text = fmt.Sprintf("%s: %s", ticketButton.Text, a.Labels[string(model.AlertNameLabel)])
+		}
+		buttons = append(buttons, []telebot.InlineButton{button})
+	}
+
+	if len(buttons) == 0 {
+		return markup
+	}
+	if markup == nil {
+		return &telebot.ReplyMarkup{InlineKeyboard: buttons}
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, buttons...)
+	return markup
+}
+
+// firingAlerts returns the alerts that are firing.
+func firingAlerts(alerts template.Alerts) template.Alerts {
+	var firing template.Alerts
+	for _, a := range alerts {
+		if a.Status == string(model.AlertFiring) {
+			firing = append(firing, a)
+		}
+	}
+	return firing
+}
+
+// handleTicket opens a ticket for each firing alert of the message the command replies to.
+func (b *Bot) handleTicket(message *telebot.Message) error {
+	if b.tickets == nil || b.alerts == nil {
+		_, err := b.telegram.Send(message.Chat, "Opening tickets for alerts isn't enabled.")
+		return err
+	}
+	if message.ReplyTo == nil {
+		_, err := b.telegram.Send(message.Chat, "Usage: reply to an alert with "+CommandTicket+".")
+		return err
+	}
+
+	alerts, err := b.repliedAlerts(message)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get replied alerts", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't list the firing alerts.")
+		return err
+	}
+	if len(alerts) == 0 {
+		_, err = b.telegram.Send(message.Chat, "The message you replied to has no firing alerts.")
+		return err
+	}
+
+	replies := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		reply, err := b.openTicket(a, message.ReplyTo.Text, message.Sender)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to create ticket", "err", err)
+			_, err = b.telegram.Send(message.Chat, "I can't create the ticket.")
+			return err
+		}
+		replies = append(replies, reply)
+	}
+
+	_, err = b.telegram.Send(message.Chat, strings.Join(replies, "\n"), telebot.NoPreview)
+	return err
+}
+
+// handleTicketButton opens a ticket for the firing alert with the callback's fingerprint.
+func (b *Bot) handleTicketButton(c *telebot.Callback) {
+	if err := b.telegram.Respond(c); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to respond to callback", "err", err)
+	}
+
+	if !b.isAuthorized(c.Sender) {
+		level.Info(b.logger).Log(
+			"msg", "dropping callback from forbidden sender",
+			"sender_id", c.Sender.ID,
+			"sender_username", c.Sender.Username,
+		)
+		return
+	}
+
+	a, err := b.alerts.Get(c.Message.Chat.ID, c.Data)
+	if err != nil {
+		if !errors.Is(err, AlertNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get alert from alert store", "err", err)
+		}
+		_, _ = b.telegram.Send(c.Message.Chat, "The alert isn't firing anymore.")
+		return
+	}
+
+	reply, err := b.openTicket(a, c.Message.Text, c.Sender)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to create ticket", "err", err)
+		reply = "I can't create the ticket."
+	}
+	if _, err := b.telegram.Send(c.Message.Chat, reply, telebot.NoPreview); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send ticket", "err", err)
+	}
+}
+
+// openTicket opens a ticket for the alert, unless its history record already has one,
+// and returns the reply linking to the ticket.
+// The ticket's description is the text of the message the alert was sent in.
+func (b *Bot) openTicket(a *ChatAlert, text string, sender *telebot.User) (string, error) {
+	var entry *HistoryEntry
+	if b.history != nil {
+		var err error
+		entry, err = b.history.Get(a.ChatID, a.Fingerprint, a.StartsAt)
+		if err != nil && !errors.Is(err, AlertNotFoundErr) {
+			level.Warn(b.logger).Log("msg", "failed to get alert from history store", "err", err)
+		}
+		if entry != nil && entry.TicketURL != "" {
+			return fmt.Sprintf("%s already has the ticket %s.", a.Name(), entry.TicketURL), nil
+		}
+	}
+
+	body := fmt.Sprintf("%s\n\nLabels:\n%s\n\nStarted at %s, opened by %s via Telegram.",
+		strings.TrimSpace(text),
+		strings.Join(ticketLabels(a.Labels), "\n"),
+		a.StartsAt.UTC().Format("2006-01-02 15:04:05 MST"),
+		senderName(sender),
+	)
+	ctx, cancel := b.requestContext(context.Background())
+	defer cancel()
+	url, err := b.tickets.CreateIssue(ctx, ticketTitle(a), body)
+	if err != nil {
+		return "", err
+	}
+
+	level.Info(b.logger).Log(
+		"msg", "ticket created",
+		"alertname", a.Name(),
+		"url", url,
+		"username", sender.Username,
+	)
+
+	if entry != nil {
+		entry.TicketURL = url
+		if err := b.history.Put(entry); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to put alert into history store", "err", err)
+		}
+	}
+	return fmt.Sprintf("Created the ticket %s for %s.", url, a.Name()), nil
+}
+
+// ticketLabels returns the labels of an alert as sorted name=value pairs.
+func ticketLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// ticketTitle returns the alertname and the other labels of an alert, truncated to ticketTitleLimit.
+func ticketTitle(a *ChatAlert) string {
+	labels := make(model.LabelSet, len(a.Labels))
+	for name, value := range a.Labels {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+
+	title := a.Name()
+	if other := formatLabels(labels); other != "" {
+		title += " (" + other + ")"
+	}
+	if utf8.RuneCountInString(title) > ticketTitleLimit {
+		title = string([]rune(title)[:ticketTitleLimit-1]) + "…"
+	}
+	return title
+}
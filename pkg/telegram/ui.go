@@ -0,0 +1,293 @@
+package telegram
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/webauth"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// uiDeliveries is the number of the latest deliveries the web UI shows.
+const uiDeliveries = 50
+
+var uiTemplate = template.Must(template.New("ui").Funcs(template.FuncMap{
+	"emoji": func(s DeliveryStatus) string { return deliveryEmoji[s] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>alertmanager-bot</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ddd; padding: .4em .8em; text-align: left; vertical-align: top; }
+tr.failed { background: #fdecea; }
+textarea { font-family: monospace; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>alertmanager-bot</h1>
+<p>
+{{- range .Tenants }}<a href="?tenant={{ . }}">{{ if eq . $.Tenant }}<b>{{ . }}</b>{{ else }}{{ . }}{{ end }}</a> {{ end -}}
+{{ if .User }} · logged in as {{ .User }}{{ end }}
+</p>
+{{ if .Error }}<p class="error">{{ .Error }}</p>{{ end }}
+
+<h2>Subscribed chats</h2>
+{{ if .Chats }}
+<table>
+<tr><th>Chat</th><th>ID</th>{{ if .Filters }}<th>Filter</th>{{ end }}{{ if .Previews }}<th>Link previews off</th>{{ end }}{{ if .Reminders }}<th>Reminders off</th>{{ end }}<th></th></tr>
+{{ range .Chats }}
+<tr>
+<td>{{ .Name }}</td>
+<td>{{ .ID }}</td>
+{{ if $.Filters }}<td><textarea form="chat{{ .ID }}" name="matchers" rows="2" cols="40" placeholder="severity=critical">{{ .Matchers }}</textarea></td>{{ end }}
+{{ if $.Previews }}<td><input form="chat{{ .ID }}" type="checkbox" name="previews_off"{{ if .PreviewsOff }} checked{{ end }}></td>{{ end }}
+{{ if $.Reminders }}<td><input form="chat{{ .ID }}" type="checkbox" name="reminders_off"{{ if .RemindersOff }} checked{{ end }}></td>{{ end }}
+<td><form id="chat{{ .ID }}" method="post" action="chats/{{ .ID }}?tenant={{ $.Tenant }}"><button type="submit">Save</button></form></td>
+</tr>
+{{ end }}
+</table>
+{{ else }}
+<p>Currently no one is subscribed.</p>
+{{ end }}
+
+<h2>Recent deliveries</h2>
+{{ if .Deliveries }}
+<p>{{ .Failed }} of the latest {{ len .Deliveries }} deliveries failed.</p>
+<table>
+<tr><th></th><th>At</th><th>Chat</th><th>Group key</th><th>Alerts</th><th>Attempts</th><th>Error</th></tr>
+{{ range .Deliveries }}
+<tr{{ if eq .Status "failed" }} class="failed"{{ end }}>
+<td title="{{ .Status }}">{{ emoji .Status }}</td>
+<td>{{ .At.Format "2006-01-02 15:04:05" }}</td>
+<td>{{ .ChatID }}</td>
+<td><code>{{ .GroupKey }}</code></td>
+<td>{{ .Alerts }}</td>
+<td>{{ .Attempts }}</td>
+<td>{{ .Error }}</td>
+</tr>
+{{ end }}
+</table>
+{{ else }}
+<p>No deliveries recorded.</p>
+{{ end }}
+</body>
+</html>
+`))
+
+type uiChat struct {
+	ID           int64
+	Name         string
+	Matchers     string
+	PreviewsOff  bool
+	RemindersOff bool
+}
+
+type uiPage struct {
+	Tenant  string
+	Tenants []string
+	User    string
+	Error   string
+
+	Chats     []uiChat
+	Filters   bool
+	Previews  bool
+	Reminders bool
+
+	Deliveries []*Delivery
+	Failed     int
+}
+
+// HandleUI returns a Handler serving a web UI of the tenants' bots under /ui/ for operators who prefer a browser:
+// it shows the subscribed chats with their filter and settings, which can be changed, and the latest deliveries.
+// Like the other admin endpoints the tenant is selected with ?tenant=, telegram by default.
+// The UI has no authentication of its own, wrap it with webauth.BasicAuth or webauth.OIDC.
+func HandleUI(logger log.Logger, bots map[string]*Bot) http.Handler {
+	var tenants []string
+	for tenant := range bots {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	m := http.NewServeMux()
+	m.HandleFunc("/ui/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ui/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		bot, tenant, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+		bot.uiRender(w, r, uiPage{Tenant: tenant, Tenants: tenants, Error: r.URL.Query().Get("error")})
+	})
+	m.HandleFunc("/ui/chats/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests aren't allowed", http.StatusForbidden)
+			return
+		}
+		bot, tenant, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+		bot.uiUpdateChat(log.With(logger, "tenant", tenant), w, r, tenant)
+	})
+	return m
+}
+
+// sameOrigin returns whether a browser sent the request from a page of the bot,
+// forms of other sites can't change the chats' settings in the name of a logged in user.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (b *Bot) uiRender(w http.ResponseWriter, r *http.Request, page uiPage) {
+	page.User = webauth.User(r.Context())
+	page.Filters = b.filters != nil
+	page.Previews = b.previews != nil
+	page.Reminders = b.reminders != nil && b.reminders.store != nil
+
+	chats, err := b.chats.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].ID < chats[j].ID })
+	for _, c := range chats {
+		chat := uiChat{ID: c.ID, Name: c.Title}
+		if chat.Name == "" {
+			chat.Name = "@" + c.Username
+		}
+		if page.Filters {
+			f, err := b.filters.Get(c.ID)
+			if err != nil && !errors.Is(err, FilterNotFoundErr) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if f != nil {
+				chat.Matchers = strings.Join(f.Matchers, "\n")
+			}
+		}
+		chat.PreviewsOff = b.previewsOff(c.ID)
+		if page.Reminders {
+			rs, err := b.reminders.store.Get(c.ID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			chat.RemindersOff = rs.Off
+		}
+		page.Chats = append(page.Chats, chat)
+	}
+
+	if b.deliveries != nil {
+		deliveries, err := b.deliveries.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].At.After(deliveries[j].At) })
+		if len(deliveries) > uiDeliveries {
+			deliveries = deliveries[:uiDeliveries]
+		}
+		for _, d := range deliveries {
+			if d.Status == DeliveryFailed {
+				page.Failed++
+			}
+		}
+		page.Deliveries = deliveries
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplate.Execute(w, page); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to render web ui", "err", err)
+	}
+}
+
+// uiUpdateChat saves the filter and settings of a chat submitted with its form and redirects back to the overview.
+func (b *Bot) uiUpdateChat(logger log.Logger, w http.ResponseWriter, r *http.Request, tenant string) {
+	id, ok := apiChatID(w, r, "/ui/chats/")
+	if !ok {
+		return
+	}
+	if _, err := b.chats.Get(telebot.ChatID(id)); err != nil {
+		if errors.Is(err, ChatNotFoundErr) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	back := url.Values{"tenant": {tenant}}
+	if err := b.uiSaveChat(id, r.PostForm); err != nil {
+		back.Set("error", err.Error())
+	} else {
+		level.Info(logger).Log("msg", "chat settings changed via ui", "chat_id", id, "user", webauth.User(r.Context()))
+	}
+	http.Redirect(w, r, "/ui/?"+back.Encode(), http.StatusSeeOther)
+}
+
+func (b *Bot) uiSaveChat(id int64, form url.Values) error {
+	if b.filters != nil {
+		var matchers []string
+		for _, line := range strings.Split(form.Get("matchers"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				matchers = append(matchers, line)
+			}
+		}
+		if len(matchers) == 0 {
+			if err := b.filters.Remove(id); err != nil {
+				return err
+			}
+		} else {
+			f := &ChatFilter{ChatID: id, Matchers: matchers}
+			if err := f.Validate(); err != nil {
+				return err
+			}
+			if err := b.filters.Put(f); err != nil {
+				return err
+			}
+		}
+	}
+	if b.previews != nil {
+		if err := b.previews.Put(&ChatPreviews{ChatID: id, Off: form.Get("previews_off") != ""}); err != nil {
+			return err
+		}
+	}
+	if b.reminders != nil && b.reminders.store != nil {
+		if err := b.reminders.store.Put(&ChatReminders{ChatID: id, Off: form.Get("reminders_off") != ""}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// BotOffsetStore keeps the ID of the last Telegram update the bot handled.
+type BotOffsetStore interface {
+	Get() (int, error)
+	Put(updateID int) error
+}
+
+// OffsetStore writes the ID of the last update to a libkv store backend.
+type OffsetStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewOffsetStore stores the ID of the last update in the provided kv backend.
+func NewOffsetStore(kv store.Store, storeKeyPrefix string) (*OffsetStore, error) {
+	return &OffsetStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the ID of the last update, 0 if none was handled yet.
+func (s *OffsetStore) Get() (int, error) {
+	kv, err := s.kv.Get(s.storeKeyPrefix + "/offset")
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var updateID int
+	err = json.Unmarshal(kv.Value, &updateID)
+	return updateID, err
+}
+
+// Put the ID of the last update into the kv backend.
+func (s *OffsetStore) Put(updateID int) error {
+	b, err := json.Marshal(updateID)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.storeKeyPrefix+"/offset", b, nil)
+}
+
+// WithUpdateOffsets keeps the ID of the last update handled in the store, once its handlers and the ones of all updates
+// before it completed, so that the bot continues after it after a restart. Updates in progress or still queued
+// for a command worker when the bot stops are handled again, none are lost.
+func WithUpdateOffsets(offsets BotOffsetStore) BotOption {
+	return func(b *Bot) error {
+		b.offsets = offsets
+		return nil
+	}
+}
+
+// WithPolling sets how long the bot waits for updates in one request to Telegram and how long it pauses between requests.
+// A timeout of 0 polls without waiting.
+func WithPolling(timeout, interval time.Duration) BotOption {
+	return func(b *Bot) error {
+		if timeout < 0 {
+			return errors.New("polling timeout must not be negative")
+		}
+		if interval < 0 {
+			return errors.New("polling interval must not be negative")
+		}
+		b.pollTimeout = timeout
+		b.pollInterval = interval
+		return nil
+	}
+}
+
+// updatePoller long polls the updates like telebot.LongPoller, passes the reactions telebot doesn't know to its handler
+// and keeps the ID of the last update once it's handled.
+type updatePoller struct {
+	timeout  time.Duration
+	interval time.Duration
+	offsets  BotOffsetStore
+	logger   log.Logger
+	// handler is set before the bot starts polling, reactions are only requested if it's set.
+	handler func(*Reaction)
+	// owns returns whether this replica polls for updates, see WithSharding.
+	owns func() bool
+	// lastUpdateID is the last update received, the next request gets the ones after it.
+	lastUpdateID int
+
+	mu sync.Mutex
+	// current is the update being processed, whose handlers are dispatched to the command workers.
+	current int
+	// pending counts the handlers in progress of the updates, in the order the updates were received.
+	pending      map[int]int
+	pendingOrder []int
+}
+
+func (p *updatePoller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	p.loadOffset()
+
+	polling := true
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if p.owns != nil && !p.owns() {
+			// Another replica polls, this one only checks if it has to take over.
+			polling = false
+			if !p.wait(stop, time.Second) {
+				return
+			}
+			continue
+		}
+		if !polling {
+			// Continue after the last update the other replica handled.
+			p.loadOffset()
+			polling = true
+		}
+
+		updates, err := p.getUpdates(b)
+		if err != nil {
+			level.Warn(p.logger).Log("msg", "failed to get updates from telegram", "err", err)
+			if !p.wait(stop, time.Second) {
+				return
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			p.lastUpdateID = u.ID
+			p.process(b, u)
+		}
+
+		if p.interval > 0 && !p.wait(stop, p.interval) {
+			return
+		}
+	}
+}
+
+// process passes the update to its handler. The bot is synchronous, so that the handlers are dispatched
+// while the update is the current one and it's only done once they completed, see begin.
+func (p *updatePoller) process(b *telebot.Bot, u reactionUpdate) {
+	p.mu.Lock()
+	if p.pending == nil {
+		p.pending = map[int]int{}
+	}
+	p.current = u.ID
+	p.pending[u.ID]++
+	p.pendingOrder = append(p.pendingOrder, u.ID)
+	p.mu.Unlock()
+
+	if r := u.MessageReaction; r != nil && p.handler != nil {
+		reaction := &Reaction{Chat: r.Chat, MessageID: r.MessageID, Sender: r.User}
+		for _, t := range r.NewReaction {
+			if t.Type == "emoji" {
+				reaction.Emoji = append(reaction.Emoji, t.Emoji)
+			}
+		}
+		p.handler(reaction)
+	} else if r == nil {
+		b.ProcessUpdate(u.Update)
+	}
+
+	p.mu.Lock()
+	p.current = 0
+	p.mu.Unlock()
+	p.done(u.ID)
+}
+
+// begin returns the func to call once the handler dispatched for the current update completed,
+// handlers dispatched outside of the poller, e.g. in tests, aren't tracked.
+func (p *updatePoller) begin() func() {
+	if p == nil {
+		return func() {}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	updateID := p.current
+	if updateID == 0 {
+		return func() {}
+	}
+	p.pending[updateID]++
+	return func() { p.done(updateID) }
+}
+
+// done puts the ID of the last update whose handlers, and the ones of all updates before it, completed.
+func (p *updatePoller) done(updateID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending[updateID]--
+	if p.pending[updateID] > 0 {
+		return
+	}
+	delete(p.pending, updateID)
+
+	handled := 0
+	for len(p.pendingOrder) > 0 {
+		if _, ok := p.pending[p.pendingOrder[0]]; ok {
+			break
+		}
+		handled = p.pendingOrder[0]
+		p.pendingOrder = p.pendingOrder[1:]
+	}
+	if handled == 0 || p.offsets == nil {
+		return
+	}
+	if err := p.offsets.Put(handled); err != nil {
+		level.Warn(p.logger).Log("msg", "failed to put the ID of the last update", "update_id", handled, "err", err)
+	}
+}
+
+// loadOffset continues with the update after the last one in the store.
+func (p *updatePoller) loadOffset() {
+	if p.offsets == nil {
+		return
+	}
+	updateID, err := p.offsets.Get()
+	if err != nil {
+		level.Warn(p.logger).Log("msg", "failed to get the ID of the last update", "err", err)
+	}
+	p.lastUpdateID = updateID
+}
+
+// wait returns false if the poller was stopped before d passed.
+func (p *updatePoller) wait(stop chan struct{}, d time.Duration) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (p *updatePoller) getUpdates(b *telebot.Bot) ([]reactionUpdate, error) {
+	params := map[string]string{
+		"offset":  strconv.Itoa(p.lastUpdateID + 1),
+		"timeout": strconv.Itoa(int(p.timeout / time.Second)),
+	}
+	if p.handler != nil {
+		params["allowed_updates"] = reactionUpdates
+	}
+
+	data, err := b.Raw("getUpdates", params)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result []reactionUpdate `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode updates: %w", err)
+	}
+	return resp.Result, nil
+}
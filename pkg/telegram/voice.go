@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const responseVoiceUsage = "Usage: " + CommandVoice + " on|off"
+
+// voiceTimeout is how long synthesizing a voice message may take before the alerts are only sent as text.
+const voiceTimeout = 30 * time.Second
+
+// Speaker synthesizes the speech of voice messages, e.g. a tts.HTTP or tts.Command.
+// The audio has to be an OGG file encoded with Opus for Telegram to play it as voice message.
+type Speaker interface {
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// ChatVoice are the voice message settings of a chat.
+type ChatVoice struct {
+	ChatID int64 `json:"chatID"`
+	On     bool  `json:"on,omitempty"`
+}
+
+// BotVoiceStore keeps the chats' voice message settings.
+type BotVoiceStore interface {
+	Get(chatID int64) (*ChatVoice, error)
+	Put(*ChatVoice) error
+	Remove(chatID int64) error
+}
+
+// VoiceStore writes the chats' voice message settings to a libkv store backend.
+type VoiceStore struct {
+	kv             store.Store
+	storeKeyPrefix string
+}
+
+// NewVoiceStore stores the chats' voice message settings in the provided kv backend.
+func NewVoiceStore(kv store.Store, storeKeyPrefix string) (*VoiceStore, error) {
+	return &VoiceStore{kv: kv, storeKeyPrefix: storeKeyPrefix}, nil
+}
+
+// Get the voice message settings of a chat, voice messages are off if the chat never turned them on.
+func (s *VoiceStore) Get(chatID int64) (*ChatVoice, error) {
+	kv, err := s.kv.Get(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &ChatVoice{ChatID: chatID}, nil
+		}
+		return nil, err
+	}
+	var v *ChatVoice
+	err = json.Unmarshal(kv.Value, &v)
+	return v, err
+}
+
+// Put the voice message settings of a chat into the kv backend.
+func (s *VoiceStore) Put(v *ChatVoice) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(fmt.Sprintf("%s/%d", s.storeKeyPrefix, v.ChatID), b, nil)
+}
+
+// Remove the voice message settings of a chat from the kv backend.
+func (s *VoiceStore) Remove(chatID int64) error {
+	err := s.kv.Delete(fmt.Sprintf("%s/%d", s.storeKeyPrefix, chatID))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+type voiceMessages struct {
+	speaker    Speaker
+	store      BotVoiceStore
+	severities map[string]bool
+}
+
+// WithVoice lets chats turn on voice messages, which are sent in reply to firing alerts with one of the severities,
+// as they are harder to miss on a phone at night than a text message.
+func WithVoice(speaker Speaker, store BotVoiceStore, severities []string) BotOption {
+	return func(b *Bot) error {
+		if len(severities) == 0 {
+			return errors.New("voice messages need at least one severity")
+		}
+		v := &voiceMessages{speaker: speaker, store: store, severities: map[string]bool{}}
+		for _, s := range severities {
+			v.severities[s] = true
+		}
+		b.voice = v
+		return nil
+	}
+}
+
+// voiceOn returns whether the chat turned on voice messages.
+func (b *Bot) voiceOn(chatID int64) bool {
+	if b.voice == nil {
+		return false
+	}
+	v, err := b.voice.store.Get(chatID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get voice messages", "chat_id", chatID, "err", err)
+		return false
+	}
+	return v.On
+}
+
+// sendVoice sends a voice message about the firing alerts with one of the severities in reply to the message of the alerts,
+// if the chat turned voice messages on. The alerts were sent as text already, so failures are only logged.
+func (b *Bot) sendVoice(chat *telebot.Chat, messageID int, alerts template.Alerts) {
+	if b.voice == nil || messageID == 0 {
+		return
+	}
+	var spoken template.Alerts
+	for _, a := range alerts {
+		if a.Status == string(model.AlertFiring) && b.voice.severities[a.Labels["severity"]] {
+			spoken = append(spoken, a)
+		}
+	}
+	if len(spoken) == 0 || !b.voiceOn(chat.ID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), voiceTimeout)
+	defer cancel()
+	audio, err := b.voice.speaker.Synthesize(ctx, voiceText(spoken))
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to synthesize voice message", "chat_id", chat.ID, "err", err)
+		return
+	}
+
+	_, err = b.telegram.Send(chat, &telebot.Voice{File: telebot.FromReader(bytes.NewReader(audio))}, &telebot.SendOptions{
+		ReplyTo: &telebot.Message{ID: messageID, Chat: chat},
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send voice message", "chat_id", chat.ID, "err", err)
+	}
+}
+
+// voiceText returns what the voice message says about the alerts, kept short to be understood at once.
+func voiceText(alerts template.Alerts) string {
+	if len(alerts) == 1 {
+		a := alerts[0]
+		text := fmt.Sprintf("Alert %s is firing with severity %s.", a.Labels[string(model.AlertNameLabel)], a.Labels["severity"])
+		if summary := strings.TrimSpace(a.Annotations["summary"]); summary != "" {
+			text += " " + strings.TrimSuffix(summary, ".") + "."
+		}
+		return text
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, a := range alerts {
+		name := a.Labels[string(model.AlertNameLabel)]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return fmt.Sprintf("%d alerts are firing: %s.", len(alerts), strings.Join(names, ", "))
+}
+
+func (b *Bot) handleVoice(message *telebot.Message) error {
+	if b.voice == nil {
+		_, err := b.telegram.Send(message.Chat, "Voice messages aren't enabled.")
+		return err
+	}
+	if _, err := b.chats.Get(telebot.ChatID(message.Chat.ID)); err != nil {
+		_, err = b.telegram.Send(message.Chat, "This chat isn't subscribed.")
+		return err
+	}
+
+	v, err := b.voice.store.Get(message.Chat.ID)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get voice messages", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the voice messages of this chat.")
+		return err
+	}
+
+	switch strings.TrimSpace(message.Payload) {
+	case "":
+		state := "off"
+		if v.On {
+			state = "on"
+		}
+		_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Voice messages are %s for this chat.", state))
+		return err
+	case "on":
+		v.On = true
+	case "off":
+		v.On = false
+	default:
+		_, err = b.telegram.Send(message.Chat, responseVoiceUsage)
+		return err
+	}
+
+	if err := b.voice.store.Put(v); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to put voice messages", "chat_id", message.Chat.ID, "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't change the voice messages of this chat.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "voice messages changed", "chat_id", message.Chat.ID, "on", v.On)
+
+	if v.On {
+		_, err = b.telegram.Send(message.Chat, "Voice messages are now on for this chat.")
+	} else {
+		_, err = b.telegram.Send(message.Chat, "Voice messages are now off for this chat.")
+	}
+	return err
+}
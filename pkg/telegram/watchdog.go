@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	responseWatchdogMissing  = "⚠️ The %s alert hasn't been received for %s, the alerting pipeline might be broken."
+	responseWatchdogReceived = "✅ The %s alert is received again."
+)
+
+// watchdog tracks the always firing heartbeat alert, like the Watchdog alert of kube-prometheus.
+type watchdog struct {
+	alertname string
+	interval  time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+	// missing is set once the admins were told about the missing alert.
+	missing bool
+}
+
+// WithWatchdog notifies the admins if the alert with the alertname isn't received for longer than interval.
+// The alert itself is never sent to chats.
+func WithWatchdog(alertname string, interval time.Duration) BotOption {
+	return func(b *Bot) error {
+		if interval <= 0 {
+			return fmt.Errorf("watchdog interval has to be positive")
+		}
+		b.watchdog = &watchdog{alertname: alertname, interval: interval}
+		return nil
+	}
+}
+
+// receiveHeartbeat records firing heartbeat alerts of the message and returns the message without them.
+func (b *Bot) receiveHeartbeat(m webhook.Message) webhook.Message {
+	var alerts template.Alerts
+	heartbeat := false
+	for _, a := range m.Alerts {
+		if a.Labels["alertname"] != b.watchdog.alertname {
+			alerts = append(alerts, a)
+			continue
+		}
+		if a.Status == "firing" {
+			heartbeat = true
+		}
+	}
+	if len(alerts) == len(m.Alerts) {
+		return m
+	}
+
+	if heartbeat {
+		b.watchdog.mu.Lock()
+		b.watchdog.last = time.Now()
+		recovered := b.watchdog.missing
+		b.watchdog.missing = false
+		b.watchdog.mu.Unlock()
+
+		if recovered {
+			level.Info(b.logger).Log("msg", "watchdog alert received again", "alertname", b.watchdog.alertname)
+			b.notifyAdmins(fmt.Sprintf(responseWatchdogReceived, b.watchdog.alertname))
+		}
+	}
+
+	return withAlerts(m, alerts)
+}
+
+// runWatchdog periodically checks if the heartbeat alert is still received.
+func (b *Bot) runWatchdog(ctx context.Context) error {
+	b.watchdog.mu.Lock()
+	// Give Alertmanager one interval after starting to send the alert.
+	b.watchdog.last = time.Now()
+	b.watchdog.mu.Unlock()
+
+	interval := time.Minute
+	if b.watchdog.interval < interval {
+		interval = b.watchdog.interval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			b.checkWatchdog(now)
+		}
+	}
+}
+
+func (b *Bot) checkWatchdog(now time.Time) {
+	b.watchdog.mu.Lock()
+	missing := !b.watchdog.missing && now.Sub(b.watchdog.last) >= b.watchdog.interval
+	if missing {
+		b.watchdog.missing = true
+	}
+	b.watchdog.mu.Unlock()
+
+	if !missing {
+		return
+	}
+	level.Warn(b.logger).Log("msg", "watchdog alert missing", "alertname", b.watchdog.alertname)
+	b.notifyAdmins(fmt.Sprintf(responseWatchdogMissing, b.watchdog.alertname, formatDuration(b.watchdog.interval)))
+}
+
+// notifyAdmins sends the message to the private chats of all admins.
+func (b *Bot) notifyAdmins(message string, options ...interface{}) {
+	for _, id := range b.admins {
+		if _, err := b.telegram.Send(&telebot.User{ID: id}, message, options...); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to notify admin", "admin_id", id, "err", err)
+		}
+	}
+}
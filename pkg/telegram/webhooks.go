@@ -0,0 +1,385 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ReplayNotEnabledErr is returned by Replay if the bot doesn't keep the received webhooks.
+var ReplayNotEnabledErr = errors.New("replaying webhooks isn't enabled")
+
+// ReceivedWebhook is a webhook as the bot received it.
+type ReceivedWebhook struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	ChatID     int64           `json:"chatID,omitempty"`
+	Group      string          `json:"group,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// BotWebhookStore persists the most recently received webhooks.
+type BotWebhookStore interface {
+	Get() ([]ReceivedWebhook, error)
+	Put([]ReceivedWebhook) error
+}
+
+// WebhookStore writes the received webhooks to a single key of a libkv store backend.
+type WebhookStore struct {
+	kv  store.Store
+	key string
+}
+
+// NewWebhookStore stores the received webhooks in the provided kv backend.
+func NewWebhookStore(kv store.Store, key string) (*WebhookStore, error) {
+	return &WebhookStore{kv: kv, key: key}, nil
+}
+
+// Get the received webhooks from the kv backend, oldest first.
+func (s *WebhookStore) Get() ([]ReceivedWebhook, error) {
+	kv, err := s.kv.Get(s.key)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var webhooks []ReceivedWebhook
+	err = json.Unmarshal(kv.Value, &webhooks)
+	return webhooks, err
+}
+
+// Put the received webhooks into the kv backend, replacing the previous ones.
+func (s *WebhookStore) Put(webhooks []ReceivedWebhook) error {
+	b, err := json.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put(s.key, b, nil)
+}
+
+// webhookBuffer keeps the most recently received webhooks.
+type webhookBuffer struct {
+	size  int
+	store BotWebhookStore
+
+	mu       sync.Mutex
+	loaded   bool
+	webhooks []ReceivedWebhook
+}
+
+// WithWebhookBuffer keeps the last size received webhooks so that they can be inspected and replayed.
+// If store isn't nil the webhooks are persisted across restarts.
+func WithWebhookBuffer(size int, store BotWebhookStore) BotOption {
+	return func(b *Bot) error {
+		if size <= 0 {
+			return fmt.Errorf("webhook buffer size must be positive")
+		}
+		b.webhooks = &webhookBuffer{size: size, store: store}
+		b.replays = make(chan alertmanager.TelegramWebhook, size)
+		return nil
+	}
+}
+
+// load reads the persisted webhooks once, the lock must be held.
+func (wb *webhookBuffer) load() error {
+	if wb.loaded || wb.store == nil {
+		return nil
+	}
+	webhooks, err := wb.store.Get()
+	if err != nil {
+		return err
+	}
+	wb.webhooks = webhooks
+	wb.loaded = true
+	return nil
+}
+
+func (wb *webhookBuffer) add(w alertmanager.TelegramWebhook, receivedAt time.Time) error {
+	if wb == nil {
+		return nil
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if err := wb.load(); err != nil {
+		return err
+	}
+
+	payload := w.Payload
+	if payload == nil {
+		var err error
+		if payload, err = json.Marshal(w.Message); err != nil {
+			return err
+		}
+	}
+
+	wb.webhooks = append(wb.webhooks, ReceivedWebhook{
+		ReceivedAt: receivedAt,
+		ChatID:     w.ChatID,
+		Group:      w.Group,
+		Payload:    payload,
+	})
+	if len(wb.webhooks) > wb.size {
+		wb.webhooks = wb.webhooks[len(wb.webhooks)-wb.size:]
+	}
+
+	if wb.store == nil {
+		return nil
+	}
+	return wb.store.Put(wb.webhooks)
+}
+
+// last returns the last n webhooks, oldest first.
+func (wb *webhookBuffer) last(n int) ([]ReceivedWebhook, error) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if err := wb.load(); err != nil {
+		return nil, err
+	}
+
+	if n > len(wb.webhooks) {
+		n = len(wb.webhooks)
+	}
+	return append([]ReceivedWebhook(nil), wb.webhooks[len(wb.webhooks)-n:]...), nil
+}
+
+// forget removes the webhooks sent to the chat and returns how many were removed.
+func (wb *webhookBuffer) forget(chatID int64) (int, error) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if err := wb.load(); err != nil {
+		return 0, err
+	}
+
+	kept := make([]ReceivedWebhook, 0, len(wb.webhooks))
+	for _, w := range wb.webhooks {
+		if w.ChatID != chatID {
+			kept = append(kept, w)
+		}
+	}
+	removed := len(wb.webhooks) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	wb.webhooks = kept
+
+	if wb.store == nil {
+		return removed, nil
+	}
+	return removed, wb.store.Put(wb.webhooks)
+}
+
+// LastWebhooks returns the last n received webhooks, oldest first.
+func (b *Bot) LastWebhooks(n int) ([]ReceivedWebhook, error) {
+	if b.webhooks == nil {
+		return nil, ReplayNotEnabledErr
+	}
+	return b.webhooks.last(n)
+}
+
+// Replay sends the last n received webhooks through the pipeline again
+// and returns the number of webhooks that are replayed.
+func (b *Bot) Replay(n int) (int, error) {
+	webhooks, err := b.LastWebhooks(n)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, w := range webhooks {
+		var message webhook.Message
+		if err := json.Unmarshal(w.Payload, &message); err != nil {
+			return 0, fmt.Errorf("failed to decode webhook received at %s: %w", w.ReceivedAt, err)
+		}
+		b.replays <- alertmanager.TelegramWebhook{ChatID: w.ChatID, Group: w.Group, Message: message, Payload: w.Payload}
+	}
+	return len(webhooks), nil
+}
+
+// parseCount parses the optional count of a command's payload.
+func parseCount(payload string) (int, bool) {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return 1, true
+	}
+	n, err := strconv.Atoi(payload)
+	return n, err == nil && n > 0
+}
+
+func (b *Bot) handleReplay(message *telebot.Message) error {
+	n, ok := parseCount(message.Payload)
+	if !ok {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandReplay+" [count], e.g. "+CommandReplay+" 3")
+		return err
+	}
+
+	replayed, err := b.Replay(n)
+	if err != nil {
+		if errors.Is(err, ReplayNotEnabledErr) {
+			_, err = b.telegram.Send(message.Chat, "Replaying webhooks isn't enabled.")
+			return err
+		}
+		level.Warn(b.logger).Log("msg", "failed to replay webhooks", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't replay the webhooks.")
+		return err
+	}
+
+	level.Info(b.logger).Log("msg", "replaying webhooks", "count", replayed, "username", message.Sender.Username)
+
+	_, err = b.telegram.Send(message.Chat, fmt.Sprintf("Replaying %d webhook(s).", replayed))
+	return err
+}
+
+func (b *Bot) handleLastWebhook(message *telebot.Message) error {
+	n, ok := parseCount(message.Payload)
+	if !ok {
+		_, err := b.telegram.Send(message.Chat, "Usage: "+CommandLastWebhook+" [count], e.g. "+CommandLastWebhook+" 3")
+		return err
+	}
+
+	webhooks, err := b.LastWebhooks(n)
+	if err != nil {
+		if errors.Is(err, ReplayNotEnabledErr) {
+			_, err = b.telegram.Send(message.Chat, "Keeping the received webhooks isn't enabled.")
+			return err
+		}
+		level.Warn(b.logger).Log("msg", "failed to get the last webhooks", "err", err)
+		_, err = b.telegram.Send(message.Chat, "I can't get the last webhooks.")
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		_, err = b.telegram.Send(message.Chat, "I haven't received any webhooks yet.")
+		return err
+	}
+
+	for _, w := range webhooks {
+		target := fmt.Sprintf("chat %d", w.ChatID)
+		if w.Group != "" {
+			target = "group " + w.Group
+		}
+		header := fmt.Sprintf("Received %s ago for %s:", formatDuration(time.Since(w.ReceivedAt)), target)
+
+		var payload bytes.Buffer
+		if err := json.Indent(&payload, w.Payload, "", "  "); err != nil {
+			payload.Reset()
+			payload.Write(w.Payload)
+		}
+
+		text := header + "\n<pre>" + html.EscapeString(payload.String()) + "</pre>"
+		if len(text) > telegramMessageLimit {
+			_, err = b.telegram.Send(message.Chat, &telebot.Document{
+				File:     telebot.FromReader(&payload),
+				FileName: fmt.Sprintf("webhook-%d.json", w.ReceivedAt.Unix()),
+				MIME:     "application/json",
+				Caption:  truncateCaption(header),
+			})
+		} else {
+			_, err = b.telegram.Send(message.Chat, text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantBot returns the bot of the tenant given in the request, defaults to the bot configured with flags.
+func tenantBot(w http.ResponseWriter, r *http.Request, bots map[string]*Bot) (*Bot, string, bool) {
+	tenant := r.URL.Query().Get("tenant")
+	if tenant == "" {
+		tenant = "telegram"
+	}
+	bot, ok := bots[tenant]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"tenant not found"}`))
+	}
+	return bot, tenant, ok
+}
+
+// HandleReplay returns a HandlerFunc that replays the last webhooks of a tenant's bot,
+// e.g. POST /-/replay?tenant=telegram&count=3.
+func HandleReplay(logger log.Logger, bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		bot, tenant, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+
+		n, ok := parseCount(r.URL.Query().Get("count"))
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"count must be a positive integer"}`))
+			return
+		}
+
+		replayed, err := bot.Replay(n)
+		if err != nil {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+			return
+		}
+
+		level.Info(logger).Log("msg", "replaying webhooks", "tenant", tenant, "count", replayed)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"replayed":%d}`, replayed)
+	}
+}
+
+// HandleLastWebhooks returns a HandlerFunc that responds with the last received webhooks of a tenant's bot,
+// e.g. GET /-/webhooks?tenant=telegram&count=3.
+func HandleLastWebhooks(bots map[string]*Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		bot, _, ok := tenantBot(w, r, bots)
+		if !ok {
+			return
+		}
+
+		n, ok := parseCount(r.URL.Query().Get("count"))
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"count must be a positive integer"}`))
+			return
+		}
+
+		webhooks, err := bot.LastWebhooks(n)
+		if err != nil {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+			return
+		}
+		if webhooks == nil {
+			webhooks = []ReceivedWebhook{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(webhooks)
+	}
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/alertmanager/notify/webhook"
+)
+
+// sendQueueSize is the number of messages that can wait for each send worker.
+const sendQueueSize = 100
+
+// commandQueueSize is the number of updates that can wait for each command worker.
+const commandQueueSize = 100
+
+// sendJob is a webhook message to be sent to a single chat.
+type sendJob struct {
+	chatID  int64
+	message webhook.Message
+	// fanOut is told the result of the job if the message is sent to several chats.
+	fanOut *fanOut
+	// receivedAt is when the webhook of the message was received, see WithLatencyEvent.
+	receivedAt time.Time
+}
+
+// WithSendWorkers sends messages to different chats concurrently with n workers.
+// The messages of a chat are always sent by the same worker, so that they arrive in the order
+// the webhooks were received, e.g. an alert's resolved message never arrives before its firing one.
+func WithSendWorkers(n int) BotOption {
+	return func(b *Bot) error {
+		if n <= 0 {
+			return fmt.Errorf("number of send workers must be positive")
+		}
+		b.sendWorkers = n
+		return nil
+	}
+}
+
+// sendQueue returns the queue of the worker sending to the chat.
+func (b *Bot) sendQueue(chatID int64) chan sendJob {
+	return b.sendQueues[uint64(chatID)%uint64(len(b.sendQueues))]
+}
+
+// enqueue hands the message to the chat's worker, or sends it right away without workers.
+func (b *Bot) enqueue(ctx context.Context, chatID int64, m webhook.Message, f *fanOut, receivedAt time.Time) error {
+	if b.sendQueues == nil {
+		d, err := b.sendMessage(chatID, m)
+		b.observeLatency(d, m, receivedAt)
+		f.done(sendResult(d))
+		return err
+	}
+	// Queued jobs are in progress until they're sent, see WaitIdle.
+	b.active.begin()
+	select {
+	case <-ctx.Done():
+		b.active.end()
+		f.done("")
+	case b.sendQueue(chatID) <- sendJob{chatID: chatID, message: m, fanOut: f, receivedAt: receivedAt}:
+	}
+	return nil
+}
+
+// runSendWorker sends the messages of its queue one after another.
+func (b *Bot) runSendWorker(ctx context.Context, jobs <-chan sendJob) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case j := <-jobs:
+			d, err := b.sendMessage(j.chatID, j.message)
+			b.observeLatency(d, j.message, j.receivedAt)
+			j.fanOut.done(sendResult(d))
+			b.active.end()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WithCommandWorkers handles the commands and other updates of different chats concurrently with n workers,
+// so that a slow command, e.g. /alerts waiting for Alertmanager, doesn't hold up the commands of other chats.
+// The updates of a chat are always handled by the same worker, in the order they were received.
+func WithCommandWorkers(n int) BotOption {
+	return func(b *Bot) error {
+		if n <= 0 {
+			return fmt.Errorf("number of command workers must be positive")
+		}
+		b.commandWorkers = n
+		return nil
+	}
+}
+
+// dispatch hands the handling of an update to the chat's command worker, or handles it right away without workers.
+func (b *Bot) dispatch(chatID int64, handle func()) {
+	// Queued updates are in progress until they're handled, see WaitIdle.
+	b.active.begin()
+	// The ID of the update is only kept once it's handled, an update dropped when the bot stops is handled again.
+	done := b.poller.begin()
+	if b.commandQueues == nil {
+		defer b.active.end()
+		defer done()
+		handle()
+		return
+	}
+	select {
+	case <-b.commandsDone:
+		b.active.end()
+	case b.commandQueues[uint64(chatID)%uint64(len(b.commandQueues))] <- func() {
+		handle()
+		done()
+	}:
+	}
+}
+
+// runCommandWorker handles the updates of its queue one after another.
+func (b *Bot) runCommandWorker(ctx context.Context, updates <-chan func()) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case handle := <-updates:
+			handle()
+			b.active.end()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Package tickets opens issues about alerts in issue trackers like Jira or GitHub.
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Jira opens issues in a project of Jira with its REST API.
+type Jira struct {
+	url       string
+	project   string
+	issueType string
+	username  string
+	token     string
+	client    *http.Client
+}
+
+// NewJira returns a Jira opening issues of the issue type in the project of the Jira at baseURL,
+// authenticating with the username and API token and waiting at most timeout for a response.
+func NewJira(baseURL, project, issueType, username, token string, timeout time.Duration) *Jira {
+	return &Jira{
+		url:       strings.TrimSuffix(baseURL, "/"),
+		project:   project,
+		issueType: issueType,
+		username:  username,
+		token:     token,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+type jiraIssue struct {
+	Fields struct {
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		IssueType   struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+	} `json:"fields"`
+}
+
+// CreateIssue opens an issue and returns its URL.
+func (j *Jira) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	var issue jiraIssue
+	issue.Fields.Project.Key = j.project
+	issue.Fields.Summary = title
+	issue.Fields.Description = body
+	issue.Fields.IssueType.Name = j.issueType
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := post(ctx, j.client, j.url+"/rest/api/2/issue", issue, &created, func(req *http.Request) {
+		req.SetBasicAuth(j.username, j.token)
+	})
+	if err != nil {
+		return "", err
+	}
+	return j.url + "/browse/" + created.Key, nil
+}
+
+// GitHub opens issues in a repository of GitHub with its REST API.
+type GitHub struct {
+	url    string
+	repo   string
+	token  string
+	client *http.Client
+}
+
+// NewGitHub returns a GitHub opening issues in the repository, e.g. example/infrastructure, of the GitHub API at apiURL,
+// authenticating with the token and waiting at most timeout for a response.
+func NewGitHub(apiURL, repo, token string, timeout time.Duration) *GitHub {
+	return &GitHub{
+		url:    strings.TrimSuffix(apiURL, "/"),
+		repo:   repo,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// CreateIssue opens an issue and returns its URL.
+func (g *GitHub) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	issue := struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}{Title: title, Body: body}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := post(ctx, g.client, g.url+"/repos/"+g.repo+"/issues", issue, &created, func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+g.token)
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.HTMLURL, nil
+}
+
+// post sends the request as JSON to the url and decodes the JSON response into response.
+func post(ctx context.Context, client *http.Client, url string, request, response interface{}, authenticate func(*http.Request)) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authenticate(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
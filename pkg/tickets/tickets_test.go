@@ -0,0 +1,72 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJira(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/rest/api/2/issue", r.URL.Path)
+		username, token, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "bot@example.com", username)
+		require.Equal(t, "secret", token)
+
+		var issue jiraIssue
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&issue))
+		require.Equal(t, "OPS", issue.Fields.Project.Key)
+		require.Equal(t, "Task", issue.Fields.IssueType.Name)
+		require.Equal(t, "DiskFull", issue.Fields.Summary)
+		require.Equal(t, "The disk is full.", issue.Fields.Description)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"10000","key":"OPS-42"}`))
+	}))
+	defer server.Close()
+
+	j := NewJira(server.URL+"/", "OPS", "Task", "bot@example.com", "secret", time.Second)
+	url, err := j.CreateIssue(context.Background(), "DiskFull", "The disk is full.")
+	require.NoError(t, err)
+	require.Equal(t, server.URL+"/browse/OPS-42", url)
+}
+
+func TestGitHub(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/repos/example/infrastructure/issues", r.URL.Path)
+		require.Equal(t, "token secret", r.Header.Get("Authorization"))
+
+		var issue struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&issue))
+		require.Equal(t, "DiskFull", issue.Title)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number":7,"html_url":"https://github.com/example/infrastructure/issues/7"}`))
+	}))
+	defer server.Close()
+
+	g := NewGitHub(server.URL, "example/infrastructure", "secret", time.Second)
+	url, err := g.CreateIssue(context.Background(), "DiskFull", "The disk is full.")
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/example/infrastructure/issues/7", url)
+
+}
+
+func TestCreateIssueError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	g := NewGitHub(server.URL, "example/infrastructure", "secret", time.Second)
+	_, err := g.CreateIssue(context.Background(), "DiskFull", "The disk is full.")
+	require.EqualError(t, err, "unexpected status 404 Not Found")
+}
@@ -0,0 +1,82 @@
+// Package tts synthesizes speech for Telegram voice messages,
+// which have to be OGG files encoded with Opus.
+package tts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// HTTP synthesizes speech with a server returning the audio of the text POSTed to it,
+// e.g. a small wrapper around Piper or a cloud TTS API.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP returns an HTTP synthesizing speech with the server at url, waiting at most timeout for the audio.
+func NewHTTP(url string, timeout time.Duration) *HTTP {
+	return &HTTP{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Synthesize POSTs the text as text/plain and returns the audio of the response.
+func (h *HTTP) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Accept", "audio/ogg")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Command synthesizes speech with a command reading the text from stdin and writing the audio to stdout,
+// e.g. a script piping espeak-ng into opusenc.
+type Command struct {
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+// NewCommand returns a Command running the command line, the first element is the executable, killing it after timeout.
+func NewCommand(command []string, timeout time.Duration) *Command {
+	return &Command{name: command[0], args: command[1:], timeout: timeout}
+}
+
+// Synthesize runs the command with the text as stdin and returns its stdout.
+func (c *Command) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s returned no audio", c.name)
+	}
+	return stdout.Bytes(), nil
+}
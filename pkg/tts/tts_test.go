@@ -0,0 +1,47 @@
+package tts
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "text/plain; charset=utf-8", r.Header.Get("Content-Type"))
+		text, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, _ = w.Write(append([]byte("OggS:"), text...))
+	}))
+	defer server.Close()
+
+	audio, err := NewHTTP(server.URL, time.Second).Synthesize(context.Background(), "Alert fire is firing.")
+	require.NoError(t, err)
+	require.Equal(t, "OggS:Alert fire is firing.", string(audio))
+}
+
+func TestHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewHTTP(server.URL, time.Second).Synthesize(context.Background(), "Alert fire is firing.")
+	require.EqualError(t, err, "unexpected status 404 Not Found")
+}
+
+func TestCommand(t *testing.T) {
+	audio, err := NewCommand([]string{"sh", "-c", "tr a-z A-Z"}, time.Second).Synthesize(context.Background(), "Alert fire is firing.")
+	require.NoError(t, err)
+	require.Equal(t, "ALERT FIRE IS FIRING.", string(audio))
+
+	_, err = NewCommand([]string{"sh", "-c", "echo no voice >&2; exit 1"}, time.Second).Synthesize(context.Background(), "Alert fire is firing.")
+	require.EqualError(t, err, "exit status 1: no voice")
+
+	_, err = NewCommand([]string{"true"}, time.Second).Synthesize(context.Background(), "Alert fire is firing.")
+	require.EqualError(t, err, "true returned no audio")
+}
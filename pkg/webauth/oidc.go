@@ -0,0 +1,252 @@
+package webauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	sessionCookie = "alertmanager-bot-session"
+	stateCookie   = "alertmanager-bot-state"
+	// sessionDuration is how long users stay logged in.
+	sessionDuration = 12 * time.Hour
+)
+
+// OIDC lets users log in with an OpenID Connect issuer and keeps them logged in with a signed session cookie.
+type OIDC struct {
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	// emails and groups are the users and the groups of users allowed to log in, all users of the issuer if both are empty.
+	emails map[string]bool
+	groups map[string]bool
+	// key signs the session cookies, sessions end when the bot restarts.
+	key      []byte
+	callback string
+}
+
+// NewOIDC discovers the issuer's endpoints. The redirectURL is the URL of the callback handled by Handler
+// that has to be registered for the client with the issuer.
+// Users are allowed if their email is one of the emails or the groups claim of their ID token has one of the groups.
+func NewOIDC(ctx context.Context, issuer, clientID, clientSecret, redirectURL string, emails, groups []string) (*OIDC, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover oidc issuer: %w", err)
+	}
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect url: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	o := &OIDC{
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		emails:   map[string]bool{},
+		groups:   map[string]bool{},
+		key:      key,
+		callback: u.Path,
+	}
+	for _, e := range emails {
+		o.emails[strings.ToLower(e)] = true
+	}
+	// Issuers like Keycloak and Dex only add the groups claim if the scope is requested,
+	// others like Google reject unknown scopes, so it's only requested if needed.
+	if len(groups) > 0 {
+		o.config.Scopes = append(o.config.Scopes, "groups")
+	}
+	for _, g := range groups {
+		o.groups[g] = true
+	}
+	return o, nil
+}
+
+// Handler lets requests of logged in users through to next, redirects all others to the issuer to log in
+// and handles the issuer's callback.
+func (o *OIDC) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == o.callback {
+			o.handleCallback(w, r)
+			return
+		}
+		if c, err := r.Cookie(sessionCookie); err == nil {
+			if user, ok := o.verifySession(c.Value, time.Now()); ok {
+				next.ServeHTTP(w, withUser(r, user))
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		state := make([]byte, 16)
+		if _, err := rand.Read(state); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s := hex.EncodeToString(state)
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookie,
+			Value:    s + "|" + r.URL.RequestURI(),
+			Path:     o.callback,
+			MaxAge:   int((10 * time.Minute).Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, o.config.AuthCodeURL(s), http.StatusFound)
+	})
+}
+
+func (o *OIDC) handleCallback(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		http.Error(w, "login expired, try again", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(c.Value, "|", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[0]), []byte(r.URL.Query().Get("state"))) {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	if e := r.URL.Query().Get("error"); e != "" {
+		http.Error(w, "login failed: "+e, http.StatusForbidden)
+		return
+	}
+
+	token, err := o.config.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		http.Error(w, "failed to exchange code: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "no id_token in response", http.StatusForbidden)
+		return
+	}
+	idToken, err := o.verifier.Verify(r.Context(), raw)
+	if err != nil {
+		http.Error(w, "invalid id_token: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	email, err := o.allowed(idToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: o.callback, MaxAge: -1})
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    o.signSession(email, time.Now().Add(sessionDuration)),
+		Path:     "/",
+		MaxAge:   int(sessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Only redirect within the bot, never to other hosts.
+	target := parts[1]
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		target = "/"
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// idTokenClaims are the claims of ID tokens used to authorize users.
+type idTokenClaims struct {
+	Email    string   `json:"email"`
+	Verified *bool    `json:"email_verified"`
+	Groups   []string `json:"groups"`
+}
+
+// allowed returns the verified email of the ID token's user if they are allowed.
+func (o *OIDC) allowed(idToken *oidc.IDToken) (string, error) {
+	var claims idTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return "", fmt.Errorf("invalid claims: %w", err)
+	}
+	return o.authorize(claims)
+}
+
+func (o *OIDC) authorize(claims idTokenClaims) (string, error) {
+	email := strings.ToLower(claims.Email)
+	if email == "" || claims.Verified != nil && !*claims.Verified {
+		return "", errors.New("no verified email")
+	}
+	if len(o.emails) == 0 && len(o.groups) == 0 || o.emails[email] {
+		return email, nil
+	}
+	for _, g := range claims.Groups {
+		if o.groups[g] {
+			return email, nil
+		}
+	}
+	return "", fmt.Errorf("%s isn't allowed to use the bot", email)
+}
+
+// Verify returns the user of an ID token issued to the client, e.g. sent as bearer token to the admin endpoints,
+// if they are allowed to log in.
+func (o *OIDC) Verify(ctx context.Context, raw string) (string, error) {
+	idToken, err := o.verifier.Verify(ctx, raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid id_token: %w", err)
+	}
+	return o.allowed(idToken)
+}
+
+// signSession returns the value of a session cookie of the user that expires at the given time.
+func (o *OIDC) signSession(user string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(user)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + o.sign(payload)
+}
+
+// verifySession returns the user of a session cookie if it's signed and not expired.
+func (o *OIDC) verifySession(value string, now time.Time) (string, bool) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 || !hmac.Equal([]byte(value[i+1:]), []byte(o.sign(value[:i]))) {
+		return "", false
+	}
+	parts := strings.SplitN(value[:i], ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", false
+	}
+	user, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(user), true
+}
+
+func (o *OIDC) sign(payload string) string {
+	mac := hmac.New(sha256.New, o.key)
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,90 @@
+// Package webauth authenticates the users of the web UI, the admin endpoints and the metrics
+// with basic auth, bearer tokens or by logging in with OpenID Connect.
+package webauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+type userKey struct{}
+
+// User returns the name of the user authenticated for the request, empty if there is none.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+func withUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+}
+
+// BasicAuth only lets requests with the username and password through to the handler.
+func BasicAuth(username, password string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="alertmanager-bot"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, withUser(r, u))
+	})
+}
+
+// Credentials are the ways clients of the admin endpoints can authenticate, each one that is set is accepted.
+type Credentials struct {
+	// Token is a static bearer token, e.g. for the command line client.
+	Token string
+	// Username and Password are checked with basic auth if Password is set.
+	Username string
+	Password string
+	// OIDC accepts ID tokens of allowed users issued to its client as bearer tokens.
+	OIDC *OIDC
+}
+
+// Enabled returns whether any credentials are set, the admin endpoints are disabled otherwise.
+func (c Credentials) Enabled() bool {
+	return c.Token != "" || c.Password != "" || c.OIDC != nil
+}
+
+// Handler only lets requests with one of the credentials through to the handler.
+func (c Credentials) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := c.authenticate(r); ok {
+			next.ServeHTTP(w, withUser(r, user))
+			return
+		}
+		if c.Password != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="alertmanager-bot"`)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
+
+func (c Credentials) authenticate(r *http.Request) (string, bool) {
+	if u, p, ok := r.BasicAuth(); ok {
+		return u, c.Password != "" &&
+			subtle.ConstantTimeCompare([]byte(u), []byte(c.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(p), []byte(c.Password)) == 1
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if c.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.Token)) == 1 {
+		return "token", true
+	}
+	if c.OIDC != nil {
+		if user, err := c.OIDC.Verify(r.Context(), token); err == nil {
+			return user, true
+		}
+	}
+	return "", false
+}
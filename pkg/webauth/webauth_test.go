@@ -0,0 +1,185 @@
+package webauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func userHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(User(r.Context())))
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := BasicAuth("admin", "secret", userHandler())
+
+	testcases := []struct {
+		name     string
+		username string
+		password string
+		status   int
+	}{
+		{name: "Valid", username: "admin", password: "secret", status: http.StatusOK},
+		{name: "WrongPassword", username: "admin", password: "wrong", status: http.StatusUnauthorized},
+		{name: "WrongUsername", username: "root", password: "secret", status: http.StatusUnauthorized},
+		{name: "Missing", status: http.StatusUnauthorized},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ui/", nil)
+			if tc.username != "" {
+				r.SetBasicAuth(tc.username, tc.password)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+			if tc.status == http.StatusOK {
+				require.Equal(t, "admin", w.Body.String())
+			} else {
+				require.Equal(t, `Basic realm="alertmanager-bot"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	h := Credentials{Token: "token", Username: "admin", Password: "secret"}.Handler(userHandler())
+
+	testcases := []struct {
+		name   string
+		auth   func(r *http.Request)
+		status int
+		user   string
+	}{
+		{name: "Token", auth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, status: http.StatusOK, user: "token"},
+		{name: "WrongToken", auth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, status: http.StatusUnauthorized},
+		{name: "TokenWithoutBearer", auth: func(r *http.Request) { r.Header.Set("Authorization", "token") }, status: http.StatusUnauthorized},
+		{name: "BasicAuth", auth: func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, status: http.StatusOK, user: "admin"},
+		{name: "WrongPassword", auth: func(r *http.Request) { r.SetBasicAuth("admin", "token") }, status: http.StatusUnauthorized},
+		{name: "Missing", auth: func(r *http.Request) {}, status: http.StatusUnauthorized},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/chats", nil)
+			tc.auth(r)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+			if tc.status == http.StatusOK {
+				require.Equal(t, tc.user, w.Body.String())
+			} else {
+				require.Equal(t, `Basic realm="alertmanager-bot"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	require.False(t, Credentials{Username: "admin"}.Enabled())
+
+	// Without a token an empty bearer token must not match.
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/chats", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	Credentials{Password: "secret"}.Handler(userHandler()).ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func testOIDC() *OIDC {
+	return &OIDC{
+		config: oauth2.Config{
+			ClientID:    "bot",
+			RedirectURL: "https://bot.example.com/ui/callback",
+			Endpoint:    oauth2.Endpoint{AuthURL: "https://issuer.example.com/auth"},
+		},
+		emails:   map[string]bool{},
+		groups:   map[string]bool{},
+		key:      []byte("0123456789abcdef0123456789abcdef"),
+		callback: "/ui/callback",
+	}
+}
+
+func TestOIDCSession(t *testing.T) {
+	o := testOIDC()
+	now := time.Now()
+
+	value := o.signSession("alice@example.com", now.Add(time.Hour))
+	user, ok := o.verifySession(value, now)
+	require.True(t, ok)
+	require.Equal(t, "alice@example.com", user)
+
+	_, ok = o.verifySession(value, now.Add(2*time.Hour))
+	require.False(t, ok, "expired session")
+
+	other := testOIDC()
+	other.key = []byte("another key")
+	_, ok = other.verifySession(value, now)
+	require.False(t, ok, "session signed with another key")
+
+	// The session of alice@example.com with the user changed to alice.
+	_, ok = o.verifySession("YWxpY2U"+value[strings.Index(value, "."):], now)
+	require.False(t, ok, "changed user")
+}
+
+func TestOIDCHandler(t *testing.T) {
+	o := testOIDC()
+	h := o.Handler(userHandler())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui/?tenant=telegram", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "issuer.example.com", location.Host)
+	require.Equal(t, "bot", location.Query().Get("client_id"))
+	state := location.Query().Get("state")
+	require.NotEmpty(t, state)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, state+"|/ui/?tenant=telegram", cookies[0].Value)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ui/chats/123", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/ui/callback?state=forged&code=abc", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/ui/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: o.signSession("alice@example.com", time.Now().Add(time.Hour))})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "alice@example.com", w.Body.String())
+}
+
+func TestOIDCAuthorize(t *testing.T) {
+	verified, unverified := true, false
+
+	o := testOIDC()
+	email, err := o.authorize(idTokenClaims{Email: "Alice@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", email, "all users are allowed without emails and groups")
+
+	_, err = o.authorize(idTokenClaims{Email: "alice@example.com", Verified: &unverified})
+	require.EqualError(t, err, "no verified email")
+
+	o.emails["alice@example.com"] = true
+	o.groups["sre"] = true
+
+	_, err = o.authorize(idTokenClaims{Email: "alice@example.com", Verified: &verified})
+	require.NoError(t, err)
+	_, err = o.authorize(idTokenClaims{Email: "bob@example.com", Groups: []string{"dev", "sre"}})
+	require.NoError(t, err)
+	_, err = o.authorize(idTokenClaims{Email: "bob@example.com", Groups: []string{"dev"}})
+	require.EqualError(t, err, "bob@example.com isn't allowed to use the bot")
+}
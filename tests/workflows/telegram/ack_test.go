@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var ackWorkflows = []workflow{{
+	name: "AckUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /ack <alertname|fingerprint>",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+	},
+}, {
+	name: "AckNoAlert",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " fire",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No unacknowledged alert matches fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack fire\"",
+	},
+}, {
+	name: "AckFiring",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " fire",
+		},
+	}},
+	alerts: []*telegram.ChatAlert{{
+		ChatID:      int64(admin.ID),
+		Fingerprint: "a1b2c3",
+		Labels:      map[string]string{"alertname": "fire"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}, {
+		ChatID:      -1234,
+		Fingerprint: "a1b2c3",
+		Labels:      map[string]string{"alertname": "fire"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack fire\"",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}}
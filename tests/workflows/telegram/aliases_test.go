@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// helpCommands returns the names of the commands listed in the help, in the order they are registered with Telegram.
+func helpCommands() string {
+	names := []string{strings.TrimPrefix(telegram.CommandHelp, "/")}
+	for _, line := range strings.Split(telegram.ResponseHelp, "\n") {
+		if parts := strings.SplitN(line, " - ", 2); len(parts) == 2 && strings.HasPrefix(parts[0], "/") {
+			names = append(names, strings.TrimPrefix(parts[0], "/"))
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+var aliasesWorkflows = []workflow{{
+	name: "AliasResolved",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   "/hilfe",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   "/i",
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithCommandAliases(map[string]string{"/hilfe": telegram.CommandHelp, "/i": telegram.CommandID})},
+	replies: []reply{{
+		recipient: "commands",
+		message:   helpCommands() + " hilfe i",
+	}, {
+		recipient: "123",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}, {
+		recipient: "222",
+		message:   "Your ID is 222",
+	}},
+	counter: map[string]uint{telegram.CommandHelp: 1, telegram.CommandID: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/hilfe",
+		"level=debug msg=\"message received\" text=/i",
+	},
+}}
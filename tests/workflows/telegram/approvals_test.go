@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func callbackApproval(unique, chatID string) telebot.Update {
+	return telebot.Update{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  admin,
+		Message: &telebot.Message{ID: 2, Chat: chatFromUser(admin)},
+		Data:    "\f" + unique + "|" + chatID,
+	}}
+}
+
+var approvalWorkflows = []workflow{{
+	name: "StartApproved",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithSubscriptionApproval()},
+	replies: []reply{{
+		recipient: "222",
+		message:   "I asked the admins to approve this chat, I'll let you know once they did.",
+	}, {
+		recipient: "123",
+		message:   "John (@nobody, 222) wants to receive alerts.",
+	}, {
+		recipient: "edit:2",
+		message:   "Elliot approved chat 222.",
+	}, {
+		recipient: "222",
+		message:   "Hey, John! I will now keep you up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"subscription approval requested\" username=nobody user_id=222 chat_id=222",
+		"level=info msg=\"subscription approved\" chat_id=222 admin_id=123",
+	},
+	updates: []telebot.Update{callbackApproval("approve", "222")},
+}, {
+	name: "StartDenied",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat: &telebot.Chat{
+				ID:    -1234,
+				Title: "sre",
+				Type:  telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithSubscriptionApproval()},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "I asked the admins to approve this chat, I'll let you know once they did.",
+	}, {
+		recipient: "123",
+		message:   "John (@nobody, 222) wants to subscribe the group sre (-1234) to alerts.",
+	}, {
+		recipient: "edit:2",
+		message:   "Elliot denied chat -1234.",
+	}, {
+		recipient: "-1234",
+		message:   "Sorry, the admins didn't approve this chat to receive alerts.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"subscription approval requested\" username=nobody user_id=222 chat_id=-1234",
+		"level=info msg=\"subscription denied\" chat_id=-1234 admin_id=123",
+	},
+	updates: []telebot.Update{callbackApproval("deny", "-1234")},
+}}
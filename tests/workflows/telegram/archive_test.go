@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestChatArchive() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewArchiveStore(newTestKV(), "telegram/archive")
+		if err != nil {
+			return err
+		}
+		return telegram.WithChatArchive(s)(b)
+	}
+}
+
+var archiveWorkflows = []workflow{{
+	name: "ChatArchiveRestore",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStop,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " archived",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " restore -5678",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " restore -5678",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats,
+		},
+	}},
+	options: []telegram.BotOption{withTestChatArchive()},
+	replies: []reply{{
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-5678",
+		message:   "Alright, Elliot! I won't talk to you again.\n/help",
+	}, {
+		recipient: "123",
+		message:   "Archived chats, restore them with /chats restore <id>:\n@Wallboard (-5678)",
+	}, {
+		recipient: "123",
+		message:   "Restored the chat @Wallboard (-5678), it receives alerts again.",
+	}, {
+		recipient: "123",
+		message:   "The chat -5678 isn't archived.",
+	}, {
+		recipient: "123",
+		message:   "Currently these chat have subscribed:\n@Wallboard",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandStop: 1, telegram.CommandChats: 4},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=debug msg=\"message received\" text=/stop",
+		"level=info msg=\"user unsubscribed\" username=elliot user_id=123",
+		"level=debug msg=\"message received\" text=\"/chats archived\"",
+		"level=debug msg=\"message received\" text=\"/chats restore -5678\"",
+		"level=info msg=\"chat restored\" chat_id=-5678 username=elliot",
+		"level=debug msg=\"message received\" text=\"/chats restore -5678\"",
+		"level=debug msg=\"message received\" text=/chats",
+	},
+}, {
+	name: "ChatArchiveResubscribe",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStop,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " archived",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " unarchive",
+		},
+	}},
+	options: []telegram.BotOption{withTestChatArchive()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Alright, Elliot! I won't talk to you again.\n/help",
+	}, {
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "No chats are archived.",
+	}, {
+		recipient: "123",
+		message:   "Usage: /chats, /chats mode <id> readonly|normal, /chats archived or /chats restore <id>",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandStop: 1, telegram.CommandChats: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/stop",
+		"level=info msg=\"user unsubscribed\" username=elliot user_id=123",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"archived chat restored\" chat_id=123",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/chats archived\"",
+		"level=debug msg=\"message received\" text=\"/chats unarchive\"",
+	},
+}}
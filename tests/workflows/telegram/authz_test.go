@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testAuthorizer allows the users in its set, as if they were members of the group.
+type testAuthorizer map[int]bool
+
+func (a testAuthorizer) Authorized(_ context.Context, userID int) (bool, error) {
+	return a[userID], nil
+}
+
+var authzWorkflows = []workflow{{
+	name: "HelpAsGroupMember",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithAuthorizer(testAuthorizer{nobody.ID: true})},
+	replies: []reply{{
+		recipient: "222",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}},
+	counter: map[string]uint{telegram.CommandHelp: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/help",
+	},
+}, {
+	name: "HelpAsNonMember",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithAuthorizer(testAuthorizer{})},
+	replies: []reply{},
+	logs: []string{
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestBans lets admins ban users with an empty ban store.
+func withTestBans() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewBanStore(newTestKV(), "telegram/bans")
+		if err != nil {
+			return err
+		}
+		return telegram.WithBans(s)(b)
+	}
+}
+
+var banWorkflows = []workflow{{
+	name: "BanAndUnban",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandBan + " @nobody",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandID,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandUnban + " @nobody",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandID,
+		},
+	}},
+	options: []telegram.BotOption{withTestBans()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Banned @nobody.",
+	}, {
+		recipient: "123",
+		message:   "Unbanned @nobody.",
+	}, {
+		recipient: "222",
+		message:   "Your ID is 222",
+	}},
+	counter: map[string]uint{telegram.CommandBan: 1, telegram.CommandUnban: 1, telegram.CommandID: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ban @nobody\"",
+		"level=info msg=\"user banned\" user=@nobody banned_by=123",
+		"level=info msg=\"dropping message from banned sender\" sender_id=222 sender_username=nobody",
+		"level=debug msg=\"message received\" text=\"/unban @nobody\"",
+		"level=info msg=\"user unbanned\" user=@nobody unbanned_by=123",
+		"level=debug msg=\"message received\" text=/id",
+	},
+}, {
+	name: "BanAdmin",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandBan + " 123",
+		},
+	}},
+	options: []telegram.BotOption{withTestBans()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Admins can't be banned.",
+	}},
+	counter: map[string]uint{telegram.CommandBan: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ban 123\"",
+	},
+}}
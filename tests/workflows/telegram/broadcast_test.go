@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var broadcastWorkflows = []workflow{{
+	name: "Broadcast",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandBroadcast + " Maintenance at 10:00",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Maintenance at 10:00",
+	}, {
+		recipient: "123",
+		message:   "Broadcast to 1 chat(s): 1 sent, 0 rate limited, 0 failed.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandBroadcast: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/broadcast Maintenance at 10:00\"",
+		"level=info msg=\"broadcast sent\" username=elliot chats=1 sent=1 rate_limited=0 failed=0",
+	},
+}, {
+	name: "BroadcastNoChats",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandBroadcast + " Maintenance at 10:00",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Currently no one is subscribed.",
+	}},
+	counter: map[string]uint{telegram.CommandBroadcast: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/broadcast Maintenance at 10:00\"",
+	},
+}, {
+	name: "BroadcastUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandBroadcast,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /broadcast <message>",
+	}},
+	counter: map[string]uint{telegram.CommandBroadcast: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/broadcast",
+	},
+}}
@@ -0,0 +1,149 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestCanary(percent int, opts ...telegram.BotOption) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewCanaryStore(newTestKV(), "telegram/canary")
+		if err != nil {
+			return err
+		}
+		if percent > 0 {
+			if err := s.Put(&telegram.Rollout{RolloutChange: telegram.RolloutChange{Version: 1, Percent: percent}}); err != nil {
+				return err
+			}
+		}
+		return telegram.WithCanary(s, opts...)(b)
+	}
+}
+
+var (
+	canaryHideNode = telegram.WithLabelFilter(nil, []string{"node"})
+
+	configCommand = func(text string) telebot.Update {
+		return telebot.Update{Message: &telebot.Message{
+			Sender: admin,
+			Chat:   &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+			Text:   text,
+		}}
+	}
+)
+
+var canaryWorkflows = []workflow{{
+	name:     "CanaryRolledOut",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{withTestCanary(100, canaryHideNode)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "CanaryNotRolledOut",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{withTestCanary(0, canaryHideNode)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name: "CanaryPromote",
+	messages: []telebot.Update{
+		filterStart,
+		configCommand(telegram.CommandConfig + " promote"),
+		configCommand(telegram.CommandConfig + " promote 100%"),
+	},
+	options:  []telegram.BotOption{withTestCanary(0, canaryHideNode)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "The canary configuration is rolled out to 10% of the chats, 0 of 1 subscribed chats.\nVersion 1 by @elliot less than a minute ago.",
+	}, {
+		recipient: "-1234",
+		message:   "The canary configuration is rolled out to 100% of the chats, 1 of 1 subscribed chats.\nVersion 2 by @elliot less than a minute ago.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandConfig: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=\"/config promote\"",
+		"level=info msg=\"canary rollout changed\" version=1 percent=10 username=elliot",
+		"level=debug msg=\"message received\" text=\"/config promote 100%\"",
+		"level=info msg=\"canary rollout changed\" version=2 percent=100 username=elliot",
+	},
+}, {
+	name: "CanaryRollback",
+	messages: []telebot.Update{
+		filterStart,
+		configCommand(telegram.CommandConfig + " rollback"),
+	},
+	options:  []telegram.BotOption{withTestCanary(100, canaryHideNode)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "The canary configuration is rolled out to 0% of the chats, 0 of 1 subscribed chats.\nVersion 2 by @elliot less than a minute ago.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=\"/config rollback\"",
+		"level=info msg=\"canary rollout changed\" version=2 percent=0 username=elliot",
+	},
+}, {
+	name:     "CanaryUsage",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig + " promote 150")},
+	options:  []telegram.BotOption{withTestCanary(0, canaryHideNode)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "The percentage has to be between 1 and 100.",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/config promote 150\"",
+	},
+}, {
+	name:     "CanaryNotConfigured",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "No canary configuration is configured.",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/config",
+	},
+}}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var wallboard = &telebot.Chat{ID: -5678, Type: telebot.ChatGroup, Title: "Wallboard"}
+
+func withTestChatModes() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewChatModeStore(newTestKV(), "telegram/chatmodes")
+		if err != nil {
+			return err
+		}
+		return telegram.WithChatModes(s)(b)
+	}
+}
+
+var chatModesWorkflows = []workflow{{
+	name: "ChatModeReadOnly",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " mode -5678 readonly",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStatus,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " mode -5678 normal",
+		},
+	}},
+	options: []telegram.BotOption{withTestChatModes()},
+	replies: []reply{{
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "The chat -5678 is now read-only, I only send alerts there.",
+	}, {
+		recipient: "-5678",
+		message:   "This chat is read-only, I only send alerts here.",
+	}, {
+		recipient: "123",
+		message:   "Currently these chat have subscribed:\n@Wallboard (read-only)",
+	}, {
+		recipient: "123",
+		message:   "The chat -5678 is now normal, commands work there again.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandChats: 3, telegram.CommandStatus: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=debug msg=\"message received\" text=\"/chats mode -5678 readonly\"",
+		"level=info msg=\"chat mode changed\" chat_id=-5678 readonly=true username=elliot",
+		"level=debug msg=\"refusing command in read-only chat\" chat_id=-5678 text=/status",
+		"level=debug msg=\"message received\" text=/chats",
+		"level=debug msg=\"message received\" text=\"/chats mode -5678 normal\"",
+		"level=info msg=\"chat mode changed\" chat_id=-5678 readonly=false username=elliot",
+	},
+}, {
+	name: "ChatModeUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " mode -5678 maybe",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " mode 42 readonly",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " mode -5678",
+		},
+	}},
+	options: []telegram.BotOption{withTestChatModes()},
+	replies: []reply{{
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Usage: /chats mode <id> readonly|normal",
+	}, {
+		recipient: "123",
+		message:   "The chat 42 isn't subscribed.",
+	}, {
+		recipient: "123",
+		message:   "The chat -5678 is normal.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandChats: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=debug msg=\"message received\" text=\"/chats mode -5678 maybe\"",
+		"level=debug msg=\"message received\" text=\"/chats mode 42 readonly\"",
+		"level=debug msg=\"message received\" text=\"/chats mode -5678\"",
+	},
+}}
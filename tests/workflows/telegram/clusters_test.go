@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/types"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// unreachableAlertmanager fails every request, to tell which cluster a command targets.
+type unreachableAlertmanager struct {
+	name string
+}
+
+func (a unreachableAlertmanager) err() error {
+	return errors.New(a.name + " is unreachable")
+}
+
+func (a unreachableAlertmanager) ListAlerts(context.Context, string, bool) ([]*types.Alert, error) {
+	return nil, a.err()
+}
+
+func (a unreachableAlertmanager) ListAlertStatuses(context.Context, string, bool) ([]alertmanager.Alert, error) {
+	return nil, a.err()
+}
+
+func (a unreachableAlertmanager) ListSilences(context.Context) ([]*types.Silence, error) {
+	return nil, a.err()
+}
+
+func (a unreachableAlertmanager) Status(context.Context) (*models.AlertmanagerStatus, error) {
+	return nil, a.err()
+}
+
+func (a unreachableAlertmanager) CreateSilence(context.Context, []*alertmanager.Matcher, time.Time, time.Time, string, string) (string, error) {
+	return "", a.err()
+}
+
+func clusterMessage(text string) telebot.Update {
+	return telebot.Update{Message: &telebot.Message{
+		Sender: admin,
+		Chat:   chatFromUser(admin),
+		Text:   text,
+	}}
+}
+
+func withTestClusters() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewClusterStore(newTestKV(), "telegram/clusters")
+		if err != nil {
+			return err
+		}
+		return telegram.WithClusters([]telegram.Cluster{
+			{Name: "prod-eu", Alertmanager: unreachableAlertmanager{name: "prod-eu"}},
+			{Name: "prod-us", Alertmanager: unreachableAlertmanager{name: "prod-us"}},
+		}, s)(b)
+	}
+}
+
+var clustersWorkflows = []workflow{{
+	name: "ClusterUse",
+	messages: []telebot.Update{
+		clusterMessage(telegram.CommandCluster),
+		clusterMessage(telegram.CommandCluster + " use prod-eu"),
+		clusterMessage(telegram.CommandCluster),
+		clusterMessage(telegram.CommandSilences),
+		clusterMessage(telegram.CommandCluster + " use prod-eu,prod-us"),
+		clusterMessage(telegram.CommandSilences),
+		clusterMessage(telegram.CommandCluster + " use default"),
+		clusterMessage(telegram.CommandSilences),
+	},
+	options: []telegram.BotOption{withTestClusters()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Alertmanager clusters:\n✅ default\n▫️ prod-eu\n▫️ prod-us",
+	}, {
+		recipient: "123",
+		message:   "This chat now uses the Alertmanager cluster(s) prod-eu.",
+	}, {
+		recipient: "123",
+		message:   "Alertmanager clusters:\n▫️ default\n✅ prod-eu\n▫️ prod-us",
+	}, {
+		recipient: "123",
+		message:   "failed to list silences... prod-eu is unreachable",
+	}, {
+		recipient: "123",
+		message:   "This chat now uses the Alertmanager cluster(s) prod-eu, prod-us.",
+	}, {
+		recipient: "123",
+		message:   "failed to list silences... no peer reachable: prod-us is unreachable",
+	}, {
+		recipient: "123",
+		message:   "This chat now uses the Alertmanager cluster(s) default.",
+	}, {
+		recipient: "123",
+		message:   "No silences right now.",
+	}},
+	counter: map[string]uint{telegram.CommandCluster: 5, telegram.CommandSilences: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/cluster",
+		"level=debug msg=\"message received\" text=\"/cluster use prod-eu\"",
+		"level=info msg=\"clusters selected\" chat_id=123 clusters=prod-eu",
+		"level=debug msg=\"message received\" text=/cluster",
+		"level=debug msg=\"message received\" text=/silences",
+		"level=debug msg=\"message received\" text=\"/cluster use prod-eu,prod-us\"",
+		"level=info msg=\"clusters selected\" chat_id=123 clusters=\"prod-eu, prod-us\"",
+		"level=debug msg=\"message received\" text=/silences",
+		"level=debug msg=\"message received\" text=\"/cluster use default\"",
+		"level=info msg=\"clusters selected\" chat_id=123 clusters=default",
+		"level=debug msg=\"message received\" text=/silences",
+	},
+}, {
+	name: "ClusterUnknown",
+	messages: []telebot.Update{
+		clusterMessage(telegram.CommandCluster + " use prod-ap"),
+		clusterMessage(telegram.CommandCluster + " prod-eu"),
+	},
+	options: []telegram.BotOption{withTestClusters()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "There is no cluster prod-ap, /cluster lists them.",
+	}, {
+		recipient: "123",
+		message:   "Usage: /cluster use <cluster>..., e.g. /cluster use prod-eu prod-us",
+	}},
+	counter: map[string]uint{telegram.CommandCluster: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/cluster use prod-ap\"",
+		"level=debug msg=\"message received\" text=\"/cluster prod-eu\"",
+	},
+}}
@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestConfigVersions starts the bot with a configuration of the labels to hide, separated by spaces.
+func withTestConfigVersions(config string, versions ...*telegram.ConfigVersion) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewConfigStore(newTestKV(), "telegram/configs")
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			if err := s.Put(v); err != nil {
+				return err
+			}
+		}
+		return telegram.WithConfigVersions(s, config, func(config string) ([]telegram.BotOption, error) {
+			return []telegram.BotOption{telegram.WithLabelFilter(nil, strings.Fields(config))}, nil
+		})(b)
+	}
+}
+
+var configVersionsWorkflows = []workflow{{
+	name:     "ConfigHistory",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig + " history")},
+	options: []telegram.BotOption{withTestConfigVersions("pod",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-2 * time.Hour), Config: "node"},
+	)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Configuration versions:\nv2 applied on startup less than a minute ago (active)\nv1 applied on startup 2 hours ago",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=info msg=\"configuration version applied\" version=2",
+		"level=debug msg=\"message received\" text=\"/config history\"",
+	},
+}, {
+	name:     "ConfigDiff",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig + " diff v1 v2")},
+	options: []telegram.BotOption{withTestConfigVersions("node\npod",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-2 * time.Hour), Config: "node\nnamespace"},
+		&telegram.ConfigVersion{Version: 2, AppliedAt: time.Now().Add(-time.Hour), Config: "node\npod"},
+	)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "<b>v1 → v2:</b>\n<pre>- namespace\n+ pod</pre>",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/config diff v1 v2\"",
+	},
+}, {
+	name:     "ConfigDiffNotFound",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig + " diff v1 v7")},
+	options: []telegram.BotOption{withTestConfigVersions("node",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-time.Hour), Config: "node"},
+	)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Version v7 doesn't exist.",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/config diff v1 v7\"",
+	},
+}, {
+	name: "ConfigRollback",
+	messages: []telebot.Update{
+		filterStart,
+		configCommand(telegram.CommandConfig + " rollback v1"),
+	},
+	options: []telegram.BotOption{withTestConfigVersions("",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-2 * time.Hour), Config: "node"},
+		&telegram.ConfigVersion{Version: 2, AppliedAt: time.Now().Add(-time.Hour), Config: ""},
+	)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "Rolled back to v1, the configuration is now v3.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandConfig: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=\"/config rollback v1\"",
+		"level=info msg=\"configuration rolled back\" version=3 rollback_of=1 username=elliot",
+	},
+}, {
+	name:     "ConfigRollbackAfterRestart",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestConfigVersions("",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-2 * time.Hour), Config: "node"},
+		&telegram.ConfigVersion{Version: 2, AppliedAt: time.Now().Add(-time.Hour), Config: ""},
+		&telegram.ConfigVersion{Version: 3, AppliedAt: time.Now().Add(-time.Minute), AppliedBy: "elliot", RollbackOf: 1, Config: "node"},
+	)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"configuration version applied\" version=3 rollback_of=1",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "ConfigReloadAfterRestart",
+	messages: []telebot.Update{configCommand(telegram.CommandConfig + " history")},
+	options: []telegram.BotOption{withTestConfigVersions("pod",
+		&telegram.ConfigVersion{Version: 1, AppliedAt: time.Now().Add(-2 * time.Hour), Config: "pod"},
+		&telegram.ConfigVersion{Version: 2, AppliedAt: time.Now().Add(-time.Hour), Reloaded: true, Config: "node"},
+	)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Configuration versions:\nv2 reloaded 1 hour ago (active)\nv1 applied on startup 2 hours ago",
+	}},
+	counter: map[string]uint{telegram.CommandConfig: 1},
+	logs: []string{
+		"level=info msg=\"configuration version applied\" version=2",
+		"level=debug msg=\"message received\" text=\"/config history\"",
+	},
+}}
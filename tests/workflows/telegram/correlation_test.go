@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookNode(alertname string) alertmanager.TelegramWebhook {
+	labels := template.KV{"alertname": alertname, "node": "node-3"}
+	return alertmanager.TelegramWebhook{ChatID: -1234, Message: webhook.Message{
+		Data: &template.Data{
+			Receiver:     "telegram",
+			Status:       "firing",
+			Alerts:       template.Alerts{{Status: "firing", Labels: labels, StartsAt: time.Now().Add(-time.Hour)}},
+			GroupLabels:  template.KV{"alertname": alertname},
+			CommonLabels: labels,
+		},
+		Version:  "4",
+		GroupKey: `{}:{alertname="` + alertname + `"}`,
+	}}
+}
+
+var correlationWorkflows = []workflow{{
+	name:     "CorrelationMerged",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithCorrelation(time.Millisecond, []string{"node"})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔗 <b>2 related alerts on node-3</b>",
+	}, {
+		recipient: "edit:1",
+		message:   "🔗 <b>2 related alerts on node-3</b>\n\n🔥 DiskFull\n🔥 NodeDown",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=info msg=\"correlated alerts\" chat_id=-1234 messages=2 alerts=2",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{webhookNode("NodeDown"), webhookNode("DiskFull")}
+	},
+	updates: []telebot.Update{{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  admin,
+		Message: &telebot.Message{ID: 1, Chat: &telebot.Chat{ID: -1234, Type: telebot.ChatGroup}},
+		Data:    "\fcorrelated|1",
+	}}},
+}, {
+	name:     "CorrelationSingle",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithCorrelation(time.Millisecond, []string{"node"})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{webhookNode("NodeDown")}
+	},
+}}
@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+)
+
+// withTestDeadMansSwitch pings a server responding with the status.
+func withTestDeadMansSwitch(status int) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		u, err := url.Parse(s.URL + "/ping/abc")
+		if err != nil {
+			return err
+		}
+		return telegram.WithDeadMansSwitch(u, time.Second)(b)
+	}
+}
+
+func webhookDeadMansSwitch() []alertmanager.TelegramWebhook {
+	webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+	return []alertmanager.TelegramWebhook{{ChatID: 132461234, Message: webhookFiring}}
+}
+
+var deadMansSwitchWorkflows = []workflow{{
+	name:    "DeadMansSwitchPinged",
+	runFor:  100 * time.Millisecond,
+	options: []telegram.BotOption{withTestDeadMansSwitch(http.StatusOK)},
+	logs: []string{
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=132461234 err=\"chat not found in store\"",
+		"level=debug msg=\"dead man's switch pinged\"",
+	},
+	webhooks: webhookDeadMansSwitch,
+}, {
+	name:    "DeadMansSwitchFailed",
+	runFor:  100 * time.Millisecond,
+	options: []telegram.BotOption{withTestDeadMansSwitch(http.StatusServiceUnavailable)},
+	logs: []string{
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=132461234 err=\"chat not found in store\"",
+		"level=warn msg=\"failed to ping dead man's switch\" err=\"unexpected status 503 Service Unavailable\"",
+	},
+	webhooks: webhookDeadMansSwitch,
+}}
@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var deepLinkSecret = []byte("secret")
+
+func deepLinkStart(sender *telebot.User, action, fingerprint string) telebot.Update {
+	return telebot.Update{Message: &telebot.Message{
+		Sender: sender,
+		Chat:   chatFromUser(sender),
+		Text:   telegram.CommandStart + " " + telegram.SignDeepLink(deepLinkSecret, action, fingerprint),
+	}}
+}
+
+func callbackDeepLink(unique, fingerprint string) telebot.Update {
+	return telebot.Update{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  admin,
+		Message: &telebot.Message{ID: 1, Chat: chatFromUser(admin)},
+		Data:    "\f" + unique + "|" + fingerprint,
+	}}
+}
+
+var deepLinkAlerts = []*telegram.ChatAlert{{
+	ChatID:      -1234,
+	Fingerprint: "a1b2c3",
+	Labels:      map[string]string{"alertname": "fire", "instance": "node1"},
+	StartsAt:    time.Now().Add(-time.Hour),
+}}
+
+var deepLinkWorkflows = []workflow{{
+	name:     "DeepLinkAck",
+	messages: []telebot.Update{deepLinkStart(admin, telegram.DeepLinkAck, "a1b2c3")},
+	alerts:   deepLinkAlerts,
+	options:  []telegram.BotOption{telegram.WithDeepLinks(deepLinkSecret, time.Hour)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledge fire (a1b2c3)?",
+	}, {
+		recipient: "edit:1",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+	updates: []telebot.Update{callbackDeepLink("ack", "a1b2c3")},
+}, {
+	name:     "DeepLinkSilence",
+	messages: []telebot.Update{deepLinkStart(admin, telegram.DeepLinkSilence, "a1b2c3")},
+	alerts:   deepLinkAlerts,
+	options:  []telegram.BotOption{telegram.WithDeepLinks(deepLinkSecret, time.Hour)},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var s struct {
+			Matchers []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"matchers"`
+			CreatedBy string `json:"createdBy"`
+		}
+		require.NoError(t, json.Unmarshal(body, &s))
+		require.Len(t, s.Matchers, 2)
+		require.Equal(t, "elliot", s.CreatedBy)
+
+		return `{"silenceID":"7e9a"}`
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Silence fire (a1b2c3) for 1 hour?",
+	}, {
+		recipient: "edit:1",
+		message:   "Silenced fire for 1 hour.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"silence created\" id=7e9a alertname=fire username=elliot",
+	},
+	updates: []telebot.Update{callbackDeepLink("silence", "a1b2c3")},
+}, {
+	name: "DeepLinkInvalidSignature",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart + " ack-a1b2c3-00000000000000000000000000000000",
+		},
+	}},
+	alerts:  deepLinkAlerts,
+	options: []telegram.BotOption{telegram.WithDeepLinks(deepLinkSecret, time.Hour)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This link isn't valid.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"invalid deep link\" username=elliot user_id=123",
+	},
+}, {
+	name:     "DeepLinkForbidden",
+	messages: []telebot.Update{deepLinkStart(nobody, telegram.DeepLinkAck, "a1b2c3")},
+	alerts:   deepLinkAlerts,
+	options:  []telegram.BotOption{telegram.WithDeepLinks(deepLinkSecret, time.Hour)},
+	replies:  []reply{},
+	counter:  map[string]uint{},
+	logs: []string{
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var deliveryWorkflows = []workflow{{
+	name: "DeliverySent",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "<b>Deliveries of Fire:</b>\n✅ sent to chat 123 less than a minute ago, 1 alert(s)\n    <code>{}:{alertname=&#34;Fire&#34;}</code>",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandDelivery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/delivery Fire\"",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{ChatID: int64(admin.ID), Message: webhookFiring}}
+	},
+	updates: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandDelivery + " Fire",
+		},
+	}},
+}, {
+	name: "DeliveryFailed",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandDelivery + " Fire",
+		},
+	}},
+	deliveries: []*telegram.Delivery{{
+		GroupKey: `{}:{alertname="Fire"}`,
+		ChatID:   -1234,
+		Status:   telegram.DeliveryFailed,
+		Attempts: 2,
+		Alerts:   1,
+		Error:    "telegram: retry after 30 (429)",
+		At:       time.Now().Add(-2 * time.Hour),
+	}, {
+		GroupKey: `{}:{alertname="Fire"}`,
+		ChatID:   int64(admin.ID),
+		Status:   telegram.DeliveryRetried,
+		Attempts: 2,
+		Alerts:   1,
+		At:       time.Now().Add(-time.Hour),
+	}, {
+		GroupKey: `{}:{alertname="Water"}`,
+		ChatID:   int64(admin.ID),
+		Status:   telegram.DeliveryFiltered,
+		Alerts:   1,
+		At:       time.Now().Add(-time.Hour),
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>Deliveries of Fire:</b>\n🔁 retried to chat 123 1 hour ago, 1 alert(s)\n    <code>{}:{alertname=&#34;Fire&#34;}</code>\n❌ failed to chat -1234 2 hours ago, 1 alert(s): telegram: retry after 30 (429)\n    <code>{}:{alertname=&#34;Fire&#34;}</code>",
+	}},
+	counter: map[string]uint{telegram.CommandDelivery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/delivery Fire\"",
+	},
+}, {
+	name: "DeliveryNotFound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandDelivery + " Water",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No deliveries of Water found.",
+	}},
+	counter: map[string]uint{telegram.CommandDelivery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/delivery Water\"",
+	},
+}, {
+	name: "DeliveryUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandDelivery,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /delivery <groupKey>, e.g. /delivery alertname=\"Fire\"",
+	}},
+	counter: map[string]uint{telegram.CommandDelivery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/delivery",
+	},
+}}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookLongAnnotation(description string) webhook.Message {
+	return webhook.Message{Data: &template.Data{
+		Receiver: "telegram",
+		Status:   "firing",
+		Alerts: template.Alerts{{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "SlowQuery"},
+			Annotations: template.KV{"description": description},
+			StartsAt:    time.Now().Add(-time.Hour),
+			Fingerprint: "4a5b6c7d8e9f0a1b",
+		}},
+	}}
+}
+
+func callbackDetails(fingerprint string) telebot.Update {
+	return telebot.Update{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  admin,
+		Message: &telebot.Message{Chat: chatFromUser(admin)},
+		Data:    "\fdetails|" + fingerprint,
+	}}
+}
+
+var detailsWorkflows = []workflow{{
+	name: "WebhookAnnotationDetails",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithAnnotationLimit(10),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>SlowQuery</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n    description: SELECT * F…\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "SlowQuery\n\ndescription:\nSELECT * FROM alerts WHERE status = 'firing'",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{
+			ChatID:  int64(admin.ID),
+			Message: webhookLongAnnotation("SELECT * FROM alerts WHERE status = 'firing'"),
+		}}
+	},
+	updates: []telebot.Update{callbackDetails("4a5b6c7d8e9f0a1b")},
+}, {
+	name: "WebhookAnnotationDetailsDocument",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithAnnotationLimit(10),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>SlowQuery</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n    description: panic: run…\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "document:4a5b6c7d8e9f0a1b.txt",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{
+			ChatID:  int64(admin.ID),
+			Message: webhookLongAnnotation("panic: runtime error\n" + strings.Repeat("goroutine 1 [running]:\n", 200)),
+		}}
+	},
+	updates: []telebot.Update{callbackDetails("4a5b6c7d8e9f0a1b")},
+}, {
+	name: "AnnotationDetailsExpired",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithAnnotationLimit(10),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "The details of this alert are no longer available.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	updates: []telebot.Update{callbackDetails("4a5b6c7d8e9f0a1b")},
+}}
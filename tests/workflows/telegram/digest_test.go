@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testReports fails unless it's sent one of the expected messages, by subject.
+type testReports map[string]notify.Message
+
+func (o testReports) Notify(_ context.Context, msg notify.Message) error {
+	if expected, ok := o[msg.Subject]; !ok || msg != expected {
+		return fmt.Errorf("unexpected message %+v", msg)
+	}
+	return nil
+}
+
+func webhooksDigest() []alertmanager.TelegramWebhook {
+	m := webhook.Message{
+		Data: &template.Data{
+			Receiver: "telegram",
+			Status:   "firing",
+			Alerts: template.Alerts{{
+				Status:      "firing",
+				Labels:      template.KV{"alertname": "DiskFull", "severity": "warning"},
+				Fingerprint: "1a2b3c",
+				StartsAt:    time.Now().Add(-time.Hour),
+			}},
+		},
+		Version:  "4",
+		GroupKey: `{}:{alertname="DiskFull"}`,
+	}
+	return []alertmanager.TelegramWebhook{
+		{ChatID: int64(admin.ID), Message: m},
+		{ChatID: -1234, Message: m},
+	}
+}
+
+const (
+	digestStats = "<b>Alert statistics for the last less than a minute</b>\n" +
+		"Alerts: 1 (0 acknowledged, 0 resolved)\n\n" +
+		"<b>Top alerts:</b>\n1× DiskFull, firing less than a minute"
+	digestDeadLetters = "<b>Alerts that couldn't be delivered in the last less than a minute:</b>\n" +
+		"❌ failed to chat -1234 less than a minute ago, 1 alert(s): chat is not subscribed\n" +
+		"    <code>{}:{alertname=&#34;DiskFull&#34;}</code>"
+)
+
+var digestWorkflows = []workflow{{
+	name:   "Digest",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithOutput("email", testReports{
+			"[FIRING:1] DiskFull": {
+				Subject:  "[FIRING:1] DiskFull",
+				HTML:     "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+				Markdown: "🔥 **DiskFull** 🔥\n**Labels:**\n    severity: warning\n**Annotations:**\n**Duration:** 1 hour",
+			},
+			"Alert digest for the last less than a minute": {
+				Subject:  "Alert digest for the last less than a minute",
+				HTML:     digestStats,
+				Markdown: "**Alert statistics for the last less than a minute**\nAlerts: 1 (0 acknowledged, 0 resolved)\n\n**Top alerts:**\n1× DiskFull, firing less than a minute",
+			},
+			"[UNDELIVERED:1] Dead-letter report for the last less than a minute": {
+				Subject:  "[UNDELIVERED:1] Dead-letter report for the last less than a minute",
+				HTML:     digestDeadLetters,
+				Markdown: "**Alerts that couldn't be delivered in the last less than a minute:**\n❌ failed to chat -1234 less than a minute ago, 1 alert(s): chat is not subscribed\n    `{}:{alertname=\"DiskFull\"}`",
+			},
+		}),
+		telegram.WithOutput("mattermost", brokenOutput{}),
+		telegram.WithDigest(60*time.Millisecond, "email"),
+	},
+	webhooks: webhooksDigest,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   digestStats,
+	}, {
+		recipient: "123",
+		message:   digestDeadLetters,
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"failed to send alerts to output\" output=mattermost err=\"connection refused\"",
+		"level=warn msg=\"failed to send alerts to output\" output=mattermost err=\"connection refused\"",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=-1234 err=\"chat not found in store\"",
+	},
+}}
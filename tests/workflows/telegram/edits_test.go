@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var editsWorkflows = []workflow{{
+	name: "EditedCommand",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:   admin,
+			Chat:     chatFromUser(admin),
+			Text:     telegram.CommandID,
+			Unixtime: time.Now().Unix(),
+		},
+	}},
+	// The first message gets the ID 0.
+	updates: []telebot.Update{{
+		EditedMessage: &telebot.Message{
+			ID:       0,
+			Sender:   admin,
+			Chat:     chatFromUser(admin),
+			Text:     telegram.CommandHelp,
+			Unixtime: time.Now().Unix(),
+			LastEdit: time.Now().Unix(),
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithEditedCommands(time.Minute)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Your ID is 123",
+	}, {
+		recipient: "edit:1",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}},
+	counter: map[string]uint{telegram.CommandID: 1, telegram.CommandHelp: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/id",
+		"level=debug msg=\"message received\" text=/help",
+	},
+}, {
+	name: "EditedCommandTooLate",
+	updates: []telebot.Update{{
+		EditedMessage: &telebot.Message{
+			ID:       7,
+			Sender:   admin,
+			Chat:     chatFromUser(admin),
+			Text:     telegram.CommandHelp,
+			Unixtime: time.Now().Add(-time.Hour).Unix(),
+			LastEdit: time.Now().Unix(),
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithEditedCommands(time.Minute)},
+	logs: []string{
+		"level=debug msg=\"ignoring edit of old message\" chat_id=123",
+	},
+}, {
+	name: "EditedTypo",
+	updates: []telebot.Update{{
+		EditedMessage: &telebot.Message{
+			ID:       7,
+			Sender:   admin,
+			Chat:     chatFromUser(admin),
+			Text:     telegram.CommandID,
+			Unixtime: time.Now().Unix(),
+			LastEdit: time.Now().Unix(),
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithEditedCommands(time.Minute)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Your ID is 123",
+	}},
+	counter: map[string]uint{telegram.CommandID: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/id",
+	},
+}}
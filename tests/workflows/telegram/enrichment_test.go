@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/enrichment"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testEnricher returns the fields of the alerts' service label.
+type testEnricher map[string]map[string]string
+
+func (e testEnricher) Enrich(_ context.Context, labels map[string]string) (map[string]string, error) {
+	fields, ok := e[labels["service"]]
+	if !ok {
+		return nil, errors.New("unknown service")
+	}
+	return fields, nil
+}
+
+var cmdbEnricher = testEnricher{"db": {"owner": "team-db", "runbook": "https://runbooks/db"}}
+
+func withTestInventory(content string) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		inv, err := enrichment.ParseInventory([]byte(content))
+		if err != nil {
+			return err
+		}
+		return telegram.WithEnrichers(inv)(b)
+	}
+}
+
+var enrichmentWorkflows = []workflow{{
+	name:     "Enrichment",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithEnrichers(cmdbEnricher)},
+	webhooks: webhookAlert(
+		template.KV{"alertname": "DiskFull", "service": "db"},
+		template.KV{"runbook": "https://wiki/disk-full"},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    service: db\n<b>Annotations:</b>\n    owner: team-db\n    runbook: https://wiki/disk-full\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "EnrichmentFailed",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithEnrichers(cmdbEnricher)},
+	webhooks: webhookAlert(template.KV{"alertname": "DiskFull", "service": "web"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    service: web\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=warn msg=\"failed to enrich alert\" alertname=DiskFull err=\"unknown service\"",
+	},
+}, {
+	name:     "EnrichmentInventory",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestInventory(`
+hosts:
+  10.0.1.5: {hostname: db-1.fra1, rack: r12}
+networks:
+  10.0.1.0/24: {datacenter: fra1}
+`)},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "instance": "10.0.1.5:9100"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    instance: 10.0.1.5:9100\n<b>Annotations:</b>\n    datacenter: fra1\n    hostname: db-1.fra1\n    rack: r12\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
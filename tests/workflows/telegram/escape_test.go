@@ -0,0 +1,54 @@
+package telegram
+
+import (
+	"net/url"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var escapeWorkflows = []workflow{{
+	name:     "EscapeValues",
+	messages: []telebot.Update{filterStart},
+	webhooks: webhookAlert(
+		template.KV{"alertname": "Disk_Full*", "mountpoint": "/var/<lib>"},
+		template.KV{"summary": `<a href="https://evil.example">click</a> & a_b*c`},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message: "🔥 <b>Disk_Full*</b> 🔥\n<b>Labels:</b>\n    mountpoint: /var/&lt;lib&gt;\n" +
+			"<b>Annotations:</b>\n    summary: &lt;a href=&#34;https://evil.example&#34;&gt;click&lt;/a&gt; &amp; a_b*c\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "EscapeSafeHTML",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{
+		telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl", "testdata/unsafe.tmpl"),
+		telegram.WithTemplateOverrides([]telegram.TemplateOverride{{Matchers: []string{"alertname=Phishing"}, Template: "telegram.unsafe"}}),
+	},
+	webhooks: webhookAlert(
+		template.KV{"alertname": "Phishing"},
+		template.KV{"summary": `<a href="https://evil.example">Click to ack</a>`},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "&lt;a href=&#34;https://evil.example&#34;&gt;Click to ack&lt;/a&gt;",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaults(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		faults  telegram.Faults
+		result  string
+		replies int
+	}{{
+		name:    "None",
+		result:  telegram.SendResultSent,
+		replies: 1,
+	}, {
+		name:   "Errors",
+		faults: telegram.Faults{ErrorRate: 1},
+		result: telegram.SendResultFailed,
+	}, {
+		name:   "RateLimits",
+		faults: telegram.Faults{RateLimitRate: 1},
+		result: telegram.SendResultRateLimited,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			chats := &telegramtest.ChatStore{}
+			require.NoError(t, chats.Add(chatFromUser(admin)))
+			tg, err := telegramtest.New()
+			require.NoError(t, err)
+
+			var (
+				mu      sync.Mutex
+				results []string
+			)
+			bot, err := telegram.NewBotWithTelegram(chats, tg, admin.ID,
+				telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
+				telegram.WithSendEvent(func(result string) {
+					mu.Lock()
+					defer mu.Unlock()
+					results = append(results, result)
+				}),
+				telegram.WithFaults(tc.faults),
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			webhooks := make(chan alertmanager.TelegramWebhook, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- bot.Run(ctx, webhooks)
+			}()
+			<-tg.Started()
+
+			webhooks <- alertmanager.TelegramWebhook{ChatID: int64(admin.ID), Message: webhookFiring}
+			require.NoError(t, waitIdle(ctx, bot))
+			cancel()
+			require.NoError(t, <-done)
+
+			require.Len(t, tg.Replies(), tc.replies)
+			require.Equal(t, []string{tc.result}, results)
+		})
+	}
+
+	_, err := telegram.NewBotWithTelegram(&telegramtest.ChatStore{}, nil, admin.ID, telegram.WithFaults(telegram.Faults{ErrorRate: 2}))
+	require.EqualError(t, err, "the error rate 2 isn't between 0 and 1")
+}
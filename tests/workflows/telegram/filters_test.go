@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestFilters(filters ...*telegram.ChatFilter) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewFilterStore(newTestKV(), "telegram/filters")
+		if err != nil {
+			return err
+		}
+		for _, f := range filters {
+			if err := s.Put(f); err != nil {
+				return err
+			}
+		}
+		return telegram.WithFilters(s)(b)
+	}
+}
+
+var filterStart = telebot.Update{
+	Message: &telebot.Message{
+		Sender: admin,
+		Chat: &telebot.Chat{
+			ID:   -1234,
+			Type: telebot.ChatGroup,
+		},
+		Text: telegram.CommandStart,
+	},
+}
+
+func webhookFilters() []alertmanager.TelegramWebhook {
+	webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+	return []alertmanager.TelegramWebhook{{ChatID: -1234, Message: webhookFiring}}
+}
+
+var filterWorkflows = []workflow{{
+	name:     "FilterMatches",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestFilters(&telegram.ChatFilter{
+		ChatID:   -1234,
+		Matchers: []string{`severity="critical"`, "alertname=fire"},
+	})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: webhookFilters,
+}, {
+	name:     "FilterDoesNotMatch",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestFilters(&telegram.ChatFilter{
+		ChatID:   -1234,
+		Matchers: []string{"severity=warning"},
+	})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: webhookFilters,
+}, {
+	name:     "FilterOtherChat",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestFilters(&telegram.ChatFilter{
+		ChatID:   -5678,
+		Matchers: []string{"severity=warning"},
+	})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: webhookFilters,
+}, {
+	name:     "FilterRegexpMatches",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestFilters(&telegram.ChatFilter{
+		ChatID:   -1234,
+		Matchers: []string{`severity=~"crit.*|page"`, "team!=db", "alertname!~warn.*"},
+	})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: webhookFilters,
+}}
@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func findAlerts(t *testing.T, r *http.Request) string {
+	require.Equal(t, "", r.URL.Query().Get("receiver"))
+	require.Equal(t, "true", r.URL.Query().Get("silenced"))
+	return fmt.Sprintf(
+		`[{"labels":{"alertname":"PostgresDown","team":"db"},"annotations":{},"startsAt":"%[1]s","generatorURL":"http://prometheus/graph?g0.expr=pg_up","status":{"state":"active","silencedBy":[],"inhibitedBy":[]}},`+
+			`{"labels":{"alertname":"NodeDown","instance":"db-1"},"annotations":{},"startsAt":"%[1]s","status":{"state":"suppressed","silencedBy":["34f5f82b"],"inhibitedBy":[]}},`+
+			`{"labels":{"alertname":"DiskFull","instance":"web-1"},"annotations":{"summary":"Disk is full"},"startsAt":"%[1]s","status":{"state":"active","silencedBy":[],"inhibitedBy":[]}}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339),
+	)
+}
+
+var findHistory = []*telegram.HistoryEntry{{
+	ChatID:      123,
+	Fingerprint: "a",
+	Labels:      map[string]string{"alertname": "PostgresDown", "team": "db"},
+	StartsAt:    now.Add(-3 * time.Hour),
+	EndsAt:      now.Add(-2 * time.Hour),
+}, {
+	// Still firing, so it's only listed once from Alertmanager.
+	ChatID:      123,
+	Fingerprint: model.LabelSet{"alertname": "PostgresDown", "team": "db"}.Fingerprint().String(),
+	Labels:      map[string]string{"alertname": "PostgresDown", "team": "db"},
+	StartsAt:    now.Add(-time.Hour),
+}, {
+	ChatID:      123,
+	Fingerprint: "b",
+	Labels:      map[string]string{"alertname": "KafkaLag", "team": "streaming"},
+	StartsAt:    now.Add(-3 * time.Hour),
+	EndsAt:      now.Add(-2 * time.Hour),
+}}
+
+var findWorkflows = []workflow{{
+	name: "FindUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /find <text|/regexp/>, e.g. /find db or /find /node-[0-9]+/",
+	}},
+	counter: map[string]uint{telegram.CommandFind: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/find",
+	},
+}, {
+	name: "Find",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind + " DB",
+		},
+	}},
+	alertmanagerAlerts: findAlerts,
+	history:            findHistory,
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alerts matching DB:</b>\n" +
+			"🔥 <b>PostgresDown</b> (team=db) firing for 1 hour, <a href=\"http://prometheus/graph?g0.expr=pg_up\">source</a>\n" +
+			"🔕 <b>NodeDown</b> (instance=db-1) firing for 1 hour, silenced\n" +
+			"✅ <b>PostgresDown</b> (team=db) resolved 2 hours ago after 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandFind: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/find DB\"",
+	},
+}, {
+	name: "FindRegexp",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind + " /disk|kafka/",
+		},
+	}},
+	alertmanagerAlerts: findAlerts,
+	history:            findHistory,
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alerts matching /disk|kafka/:</b>\n" +
+			"🔥 <b>DiskFull</b> (instance=web-1) firing for 1 hour\n" +
+			"✅ <b>KafkaLag</b> (team=streaming) resolved 2 hours ago after 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandFind: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/find /disk|kafka/\"",
+	},
+}, {
+	name: "FindNothing",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind + " redis",
+		},
+	}},
+	alertmanagerAlerts: findAlerts,
+	history:            findHistory,
+	replies: []reply{{
+		recipient: "123",
+		message:   "No alerts match redis.",
+	}},
+	counter: map[string]uint{telegram.CommandFind: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/find redis\"",
+	},
+}, {
+	name: "FindInvalidRegexp",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind + " /(db/",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "The regular expression is invalid: error parsing regexp: missing closing ): `(?i)(db`",
+	}},
+	counter: map[string]uint{telegram.CommandFind: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/find /(db/\"",
+	},
+}}
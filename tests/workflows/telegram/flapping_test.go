@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookFlap(status string) webhook.Message {
+	a := template.Alert{
+		Status:   status,
+		Labels:   template.KV{"alertname": "flap"},
+		StartsAt: time.Now().Add(-time.Hour),
+	}
+	if status == "resolved" {
+		a.EndsAt = time.Now().Add(-2 * time.Minute)
+	}
+	return webhook.Message{Data: &template.Data{
+		Receiver: "telegram",
+		Status:   status,
+		Alerts:   template.Alerts{a},
+	}}
+}
+
+var flappingWorkflows = []workflow{{
+	name: "WebhookFlapping",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithFlapping(30*time.Minute, 3, nil),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>flap</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "✅ <b>flap</b> ✅\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 58 minutes\n<b>Ended:</b> 2 minutes",
+	}, {
+		recipient: "123",
+		message:   "🔁 <b>flap</b> is flapping (3 transitions in 30 minutes), currently firing.",
+	}, {
+		recipient: "edit:4",
+		message:   "🔁 <b>flap</b> is flapping (4 transitions in 30 minutes), currently resolved.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("resolved")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("resolved")},
+		}
+	},
+}}
@@ -0,0 +1,212 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var groupsWorkflows = []workflow{{
+	name: "WebhookChatGroup",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -5678,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithChatGroups(map[string][]int64{"sre": {-1234, -5678}}),
+	},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "-5678",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=info msg=\"webhook sent to chat group\" group=sre chats=2 sent=2 rate_limited=0 failed=0",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{Group: "sre", Message: webhookFiring}}
+	},
+}, {
+	name: "WebhookChatGroupNotFound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"chat group not found\" group=sre",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{Group: "sre", Message: webhookFiring}}
+	},
+}}
+
+var groupCommandWorkflows = []workflow{{
+	name: "GroupListEmpty",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "There are no chat groups yet.\nUsage:\n" +
+			"/group - List all chat groups.\n" +
+			"/group add <group> [chat] - Add a chat to a group, defaults to this chat.\n" +
+			"/group remove <group> [chat] - Remove a chat from a group, defaults to this chat.",
+	}},
+	counter: map[string]uint{telegram.CommandGroup: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/group",
+	},
+}, {
+	name: "GroupAddAndList",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup + " add sre -1234",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup + " add sre 4321",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithChatGroups(map[string][]int64{"db-team": {-5678}, "sre": {-5678}}),
+	},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Added chat -1234 to group sre.",
+	}, {
+		recipient: "123",
+		message:   "Chat 4321 isn't subscribed, send /start in that chat first.",
+	}, {
+		recipient: "123",
+		message:   "Chat groups:\ndb-team: -5678\nsre: -5678, -1234",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandGroup: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=\"/group add sre -1234\"",
+		"level=info msg=\"chat added to group\" group=sre chat_id=-1234 username=elliot",
+		"level=debug msg=\"message received\" text=\"/group add sre 4321\"",
+		"level=debug msg=\"message received\" text=/group",
+	},
+}, {
+	name: "GroupRemove",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup + " add sre",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup + " remove sre",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandGroup + " remove sre -5678",
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithChatGroups(map[string][]int64{"sre": {-5678}}),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Added chat 123 to group sre.",
+	}, {
+		recipient: "123",
+		message:   "Removed chat 123 from group sre.",
+	}, {
+		recipient: "123",
+		message:   "Chat -5678 is in group sre by the config file, remove it there.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandGroup: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/group add sre\"",
+		"level=info msg=\"chat added to group\" group=sre chat_id=123 username=elliot",
+		"level=debug msg=\"message received\" text=\"/group remove sre\"",
+		"level=info msg=\"chat removed from group\" group=sre chat_id=123 username=elliot",
+		"level=debug msg=\"message received\" text=\"/group remove sre -5678\"",
+	},
+}}
@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestHandledCommands handles the commands only once within an hour, the first message was handled age ago.
+func withTestHandledCommands(age time.Duration) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewHandledCommandStore(newTestKV(), "telegram/commands")
+		if err != nil {
+			return err
+		}
+		err = s.Put(&telegram.HandledCommand{
+			ChatID:    int64(admin.ID),
+			MessageID: 0,
+			Command:   telegram.CommandStart,
+			At:        time.Now().Add(-age),
+		})
+		if err != nil {
+			return err
+		}
+		return telegram.WithHandledCommands(s, time.Hour)(b)
+	}
+}
+
+var handledStarts = []telebot.Update{{
+	Message: &telebot.Message{
+		Sender: admin,
+		Chat:   chatFromUser(admin),
+		Text:   telegram.CommandStart,
+	},
+}, {
+	Message: &telebot.Message{
+		Sender: admin,
+		Chat:   chatFromUser(admin),
+		Text:   telegram.CommandStart,
+	},
+}}
+
+var handledWorkflows = []workflow{{
+	name:     "CommandHandledBefore",
+	messages: handledStarts,
+	options:  []telegram.BotOption{withTestHandledCommands(time.Minute)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"dropping command handled before\" command=/start chat_id=123 message_id=0",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}, {
+	name:     "CommandHandledOutsideWindow",
+	messages: handledStarts,
+	options:  []telegram.BotOption{withTestHandledCommands(2 * time.Hour)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}}
@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestIdempotency puts the records into the idempotency store before the bot runs.
+func withTestIdempotency(records ...*telegram.Idempotency) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewIdempotencyStore(newTestKV(), "telegram/idempotency")
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := s.Put(r); err != nil {
+				return err
+			}
+		}
+		return telegram.WithIdempotency(s, time.Hour)(b)
+	}
+}
+
+var pendingMessage = webhook.Message{
+	Data: &template.Data{
+		Receiver: "telegram",
+		Status:   "firing",
+		Alerts: template.Alerts{{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "fire"},
+			StartsAt:    time.Now().Add(-time.Hour),
+			Fingerprint: "a1b2c3",
+		}},
+		GroupLabels:  template.KV{"alertname": "fire"},
+		CommonLabels: template.KV{"alertname": "fire"},
+	},
+	Version:  "4",
+	GroupKey: `{}:{alertname="fire"}`,
+}
+
+var idempotencyWorkflows = []workflow{{
+	name: "IdempotencyDuplicate",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{withTestIdempotency()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"skipped duplicate alerts\" chat_id=123 group_key=\"{}:{alertname=\\\"Fire\\\"}\" alerts=1",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{
+			{ChatID: int64(admin.ID), Message: webhookFiring},
+			{ChatID: int64(admin.ID), Message: webhookFiring},
+		}
+	},
+}, {
+	name: "IdempotencyPending",
+	options: []telegram.BotOption{withTestIdempotency(&telegram.Idempotency{
+		ChatID:      132461234,
+		GroupKey:    pendingMessage.GroupKey,
+		Fingerprint: "a1b2c3",
+		Status:      "firing",
+		At:          time.Now().Add(-time.Minute),
+		Message:     &pendingMessage,
+	})},
+	replies: []reply{},
+	logs: []string{
+		"level=info msg=\"sending alerts received before the restart\" chat_id=132461234 alerts=1",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=132461234 err=\"chat not found in store\"",
+	},
+}, {
+	name: "IdempotencyPendingOrder",
+	options: []telegram.BotOption{withTestIdempotency(&telegram.Idempotency{
+		ChatID:      132461234,
+		GroupKey:    pendingMessage.GroupKey,
+		Fingerprint: "a1b2c3",
+		Status:      "firing",
+		At:          time.Now().Add(-time.Minute),
+		Message:     &pendingMessage,
+	}, &telegram.Idempotency{
+		ChatID:      222,
+		GroupKey:    pendingMessage.GroupKey,
+		Fingerprint: "a1b2c3",
+		Status:      "firing",
+		At:          time.Now().Add(-2 * time.Minute),
+		Message:     &pendingMessage,
+	})},
+	replies: []reply{},
+	logs: []string{
+		"level=info msg=\"sending alerts received before the restart\" chat_id=222 alerts=1",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=222 err=\"chat not found in store\"",
+		"level=info msg=\"sending alerts received before the restart\" chat_id=132461234 alerts=1",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=132461234 err=\"chat not found in store\"",
+	},
+}, {
+	name: "IdempotencyDone",
+	options: []telegram.BotOption{withTestIdempotency(&telegram.Idempotency{
+		ChatID:      132461234,
+		GroupKey:    pendingMessage.GroupKey,
+		Fingerprint: "a1b2c3",
+		Status:      "firing",
+		Done:        true,
+		At:          time.Now().Add(-time.Minute),
+	})},
+	replies: []reply{},
+	logs: []string{
+		"level=debug msg=\"skipped duplicate alerts\" chat_id=132461234 group_key=\"{}:{alertname=\\\"fire\\\"}\" alerts=1",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{ChatID: 132461234, Message: pendingMessage}}
+	},
+}}
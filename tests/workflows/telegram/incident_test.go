@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var incidentWorkflows = []workflow{{
+	name: "IncidentUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandIncident,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "Usage:\n" +
+			"/incident create <title> - Open an incident in this chat.\n" +
+			"/incident add <alertname|fingerprint> - Add firing alerts to the incident.\n" +
+			"/incident close - Close the incident.",
+	}},
+	counter: map[string]uint{telegram.CommandIncident: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/incident",
+	},
+}, {
+	name: "Incident",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandIncident + " create Everything is on fire",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandIncident + " add fire",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandIncident + " close",
+		},
+	}},
+	alerts: []*telegram.ChatAlert{{
+		ChatID:      int64(admin.ID),
+		Fingerprint: "a1b2c3",
+		Labels:      map[string]string{"alertname": "fire"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🚨 <b>Incident: Everything is on fire</b>\n<b>Opened by:</b> elliot\n<b>Alerts:</b> 0 (0 resolved)",
+	}, {
+		recipient: "edit:1",
+		message:   "🚨 <b>Incident: Everything is on fire</b>\n<b>Opened by:</b> elliot\n<b>Alerts:</b> 1 (0 resolved)\n🔥 fire",
+	}, {
+		recipient: "123",
+		message:   "Added 1 alert(s) to the incident.",
+	}, {
+		recipient: "edit:1",
+		message:   "✅ <b>Incident closed: Everything is on fire</b>\n<b>Duration:</b> less than a minute\n<b>Opened by:</b> elliot\n<b>Alerts:</b> 1 (0 resolved)\n🔥 fire",
+	}, {
+		recipient: "123",
+		message:   "Incident Everything is on fire closed after less than a minute with 1 alert(s), 0 resolved.",
+	}},
+	counter: map[string]uint{telegram.CommandIncident: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/incident create Everything is on fire\"",
+		"level=info msg=\"incident created\" title=\"Everything is on fire\" chat_id=123",
+		"level=debug msg=\"message received\" text=\"/incident add fire\"",
+		"level=debug msg=\"message received\" text=\"/incident close\"",
+		"level=info msg=\"incident closed\" title=\"Everything is on fire\" chat_id=123",
+	},
+}}
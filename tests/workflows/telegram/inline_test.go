@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func inlineAlerts(t *testing.T, r *http.Request) string {
+	require.Equal(t, "", r.URL.Query().Get("receiver"))
+	return fmt.Sprintf(
+		`[{"labels":{"alertname":"NodeDown","instance":"node-1"},"annotations":{},"startsAt":"%[1]s","status":{"state":"active","silencedBy":[],"inhibitedBy":[]}},`+
+			`{"labels":{"alertname":"PostgresDown","team":"db"},"annotations":{"summary":"Replica is down"},"startsAt":"%[1]s","status":{"state":"active","silencedBy":[],"inhibitedBy":[]}}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339),
+	)
+}
+
+var inlineWorkflows = []workflow{{
+	name:               "InlineQuery",
+	alertmanagerAlerts: inlineAlerts,
+	updates: []telebot.Update{{
+		Query: &telebot.Query{ID: "1", From: *admin, Text: "firing DB"},
+	}},
+	replies: []reply{{
+		recipient: "query:1",
+		message:   "🔥 PostgresDown: 🔥 <b>PostgresDown</b> 🔥\n<b>Labels:</b>\n    team: db\n<b>Annotations:</b>\n    summary: Replica is down\n<b>Duration:</b> 1 hour",
+	}},
+	logs: []string{""},
+}, {
+	name:               "InlineQueryNoMatch",
+	alertmanagerAlerts: inlineAlerts,
+	updates: []telebot.Update{{
+		Query: &telebot.Query{ID: "1", From: *admin, Text: "kafka"},
+	}},
+	replies: []reply{{
+		recipient: "query:1",
+		message:   "",
+	}},
+	logs: []string{""},
+}, {
+	name:               "InlineQueryForbidden",
+	alertmanagerAlerts: inlineAlerts,
+	updates: []telebot.Update{{
+		Query: &telebot.Query{ID: "1", From: *nobody, Text: "db"},
+	}},
+	replies: []reply{{
+		recipient: "query:1",
+		message:   "",
+	}},
+	logs: []string{
+		"level=info msg=\"dropping inline query from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestInvites enables invitations with the given tokens created by the admin.
+func withTestInvites(expiry time.Duration, tokens ...string) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewInviteStore(newTestKV(), "telegram/invites")
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if err := s.Put(&telegram.Invite{Token: token, CreatedBy: admin.ID, CreatedAt: time.Now().Add(-time.Hour)}); err != nil {
+				return err
+			}
+		}
+		return telegram.WithInvites(s, expiry)(b)
+	}
+}
+
+var inviteWorkflows = []workflow{{
+	name: "StartInvite",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandStart + " 0123abcd",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart + " 0123abcd",
+		},
+	}},
+	options: []telegram.BotOption{withTestInvites(24*time.Hour, "0123abcd")},
+	replies: []reply{{
+		recipient: "222",
+		message:   "Hey, John! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "This invitation isn't valid, ask an admin for a new one.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2},
+	logs: []string{
+		"level=info msg=\"invite used\" invited_by=123 chat_id=222",
+		"level=info msg=\"user subscribed\" username=nobody user_id=222 chat_id=222",
+		"level=info msg=\"invalid invite\" username=nobody user_id=222 chat_id=-1234",
+	},
+}, {
+	name: "StartInviteExpired",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandStart + " 0123abcd",
+		},
+	}},
+	options: []telegram.BotOption{withTestInvites(time.Minute, "0123abcd")},
+	replies: []reply{{
+		recipient: "222",
+		message:   "This invitation isn't valid, ask an admin for a new one.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"invalid invite\" username=nobody user_id=222 chat_id=222",
+	},
+}}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/libkv/store"
+)
+
+// testKV is an in-memory libkv store for the workflows.
+type testKV struct {
+	mu    sync.Mutex
+	pairs map[string][]byte
+}
+
+func newTestKV() *testKV {
+	return &testKV{pairs: map[string][]byte{}}
+}
+
+func (kv *testKV) Put(key string, value []byte, _ *store.WriteOptions) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.pairs[key] = value
+	return nil
+}
+
+func (kv *testKV) Get(key string) (*store.KVPair, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	value, ok := kv.pairs[key]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+	return &store.KVPair{Key: key, Value: value}, nil
+}
+
+func (kv *testKV) Delete(key string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.pairs, key)
+	return nil
+}
+
+func (kv *testKV) Exists(key string) (bool, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	_, ok := kv.pairs[key]
+	return ok, nil
+}
+
+func (kv *testKV) Watch(_ string, _ <-chan struct{}) (<-chan *store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (kv *testKV) WatchTree(_ string, _ <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (kv *testKV) NewLock(_ string, _ *store.LockOptions) (store.Locker, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (kv *testKV) List(prefix string) ([]*store.KVPair, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	var pairs []*store.KVPair
+	for key, value := range kv.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, &store.KVPair{Key: key, Value: value})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+func (kv *testKV) DeleteTree(prefix string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	for key := range kv.pairs {
+		if strings.HasPrefix(key, prefix) {
+			delete(kv.pairs, key)
+		}
+	}
+	return nil
+}
+
+func (kv *testKV) AtomicPut(key string, value []byte, _ *store.KVPair, _ *store.WriteOptions) (bool, *store.KVPair, error) {
+	if err := kv.Put(key, value, nil); err != nil {
+		return false, nil, err
+	}
+	return true, &store.KVPair{Key: key, Value: value}, nil
+}
+
+func (kv *testKV) AtomicDelete(key string, _ *store.KVPair) (bool, error) {
+	return true, kv.Delete(key)
+}
+
+func (kv *testKV) Close() {}
@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookNoisyLabels() webhook.Message {
+	return webhook.Message{Data: &template.Data{
+		Receiver: "telegram",
+		Status:   "firing",
+		Alerts: template.Alerts{{
+			Status: "firing",
+			Labels: template.KV{
+				"alertname":         "PodCrashLooping",
+				"namespace":         "monitoring",
+				"pod":               "prometheus-5d8f7b9c4-x2x7q",
+				"pod_template_hash": "5d8f7b9c4",
+				"__replica__":       "replica-0",
+			},
+			StartsAt: time.Now().Add(-time.Hour),
+		}},
+	}}
+}
+
+var labelsWorkflows = []workflow{{
+	name: "WebhookLabelDenylist",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithLabelFilter(nil, []string{"__.*", "pod_template_hash"}),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message: "🔥 <b>PodCrashLooping</b> 🔥\n<b>Labels:</b>\n    namespace: monitoring\n    pod: prometheus-5d8f7b9c4-x2x7q\n" +
+			"<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{ChatID: int64(admin.ID), Message: webhookNoisyLabels()}}
+	},
+}, {
+	name: "WebhookLabelAllowlist",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithLabelFilter([]string{"namespace", "pod.*"}, []string{"pod_template_hash"}),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message: "🔥 <b>PodCrashLooping</b> 🔥\n<b>Labels:</b>\n    namespace: monitoring\n    pod: prometheus-5d8f7b9c4-x2x7q\n" +
+			"<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{ChatID: int64(admin.ID), Message: webhookNoisyLabels()}}
+	},
+}}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageLimits(t *testing.T) {
+	tags := regexp.MustCompile(`<[^>]+>`)
+	visible := func(text string) int {
+		return len(utf16.Encode([]rune(html.UnescapeString(tags.ReplaceAllString(text, "")))))
+	}
+
+	for _, tc := range []struct {
+		name       string
+		alerts     int
+		annotation string
+		formatted  bool
+		truncated  bool
+	}{{
+		name:      "Fits",
+		alerts:    3,
+		formatted: true,
+	}, {
+		name:      "TooManyEntities",
+		alerts:    26,
+		formatted: false,
+	}, {
+		name:      "TooManyEntitiesTooLong",
+		alerts:    40,
+		formatted: false,
+		truncated: true,
+	}, {
+		name:       "TooLong",
+		alerts:     10,
+		annotation: strings.Repeat("<overflow> ", 60),
+		formatted:  true,
+		truncated:  true,
+	}, {
+		name:       "TooLongAlert",
+		alerts:     1,
+		annotation: strings.Repeat("😀", 5000),
+		formatted:  false,
+		truncated:  true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			chats := &telegramtest.ChatStore{}
+			require.NoError(t, chats.Add(chatFromUser(admin)))
+			tg, err := telegramtest.New()
+			require.NoError(t, err)
+			bot, err := telegram.NewBotWithTelegram(chats, tg, admin.ID,
+				telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			webhooks := make(chan alertmanager.TelegramWebhook, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- bot.Run(ctx, webhooks)
+			}()
+			<-tg.Started()
+
+			alerts := make(template.Alerts, 0, tc.alerts)
+			for i := 0; i < tc.alerts; i++ {
+				alerts = append(alerts, template.Alert{
+					Status:      "firing",
+					Labels:      template.KV{"alertname": "fire", "instance": fmt.Sprintf("node-%d", i)},
+					Annotations: template.KV{"message": "Something is on fire " + tc.annotation},
+					StartsAt:    time.Now().Add(-time.Hour),
+				})
+			}
+			webhooks <- alertmanager.TelegramWebhook{ChatID: int64(admin.ID), Message: webhook.Message{
+				Data:     &template.Data{Status: "firing", Alerts: alerts, GroupLabels: template.KV{"alertname": "fire"}},
+				Version:  "4",
+				GroupKey: `{}:{alertname="fire"}`,
+			}}
+			require.NoError(t, waitIdle(ctx, bot))
+			cancel()
+			require.NoError(t, <-done)
+
+			replies := tg.Replies()
+			require.Len(t, replies, 1)
+			text := replies[0].Message
+			require.LessOrEqual(t, visible(text), 4096)
+			require.Equal(t, tc.formatted, strings.Contains(text, "<b>fire</b>"), "formatted")
+			require.Equal(t, tc.truncated, strings.HasSuffix(text, "<b>[SNIP]</b>") || strings.HasSuffix(text, "…"), "truncated")
+			require.NotContains(t, text, "<overflow>", "values stay escaped")
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookSites(latitude string) func() []alertmanager.TelegramWebhook {
+	return func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{
+			ChatID: int64(admin.ID),
+			Message: webhook.Message{Data: &template.Data{
+				Receiver: "telegram",
+				Status:   "firing",
+				Alerts: template.Alerts{{
+					Status:   "firing",
+					Labels:   template.KV{"alertname": "SiteDown", "site": "berlin", "latitude": latitude, "longitude": "13.405"},
+					StartsAt: time.Now().Add(-time.Hour),
+				}, {
+					Status:   "firing",
+					Labels:   template.KV{"alertname": "PowerLoss", "site": "berlin", "latitude": latitude, "longitude": "13.405"},
+					StartsAt: time.Now().Add(-time.Hour),
+				}, {
+					Status:   "firing",
+					Labels:   template.KV{"alertname": "SiteDown", "site": "core"},
+					StartsAt: time.Now().Add(-time.Hour),
+				}},
+			}},
+		}}
+	}
+}
+
+var locationsWorkflows = []workflow{{
+	name: "Locations",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options:  []telegram.BotOption{telegram.WithLocations("latitude", "longitude")},
+	webhooks: webhookSites("52.52"),
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>SiteDown</b> 🔥\n<b>Labels:</b>\n    latitude: 52.52\n    longitude: 13.405\n    site: berlin\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n🔥 <b>PowerLoss</b> 🔥\n<b>Labels:</b>\n    latitude: 52.52\n    longitude: 13.405\n    site: berlin\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n🔥 <b>SiteDown</b> 🔥\n<b>Labels:</b>\n    site: core\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "location:52.52,13.405",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}, {
+	name: "LocationsInvalid",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options:  []telegram.BotOption{telegram.WithLocations("latitude", "longitude")},
+	webhooks: webhookSites("north"),
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>SiteDown</b> 🔥\n<b>Labels:</b>\n    latitude: north\n    longitude: 13.405\n    site: berlin\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n🔥 <b>PowerLoss</b> 🔥\n<b>Labels:</b>\n    latitude: north\n    longitude: 13.405\n    site: berlin\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n🔥 <b>SiteDown</b> 🔥\n<b>Labels:</b>\n    site: core\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"alert has an invalid location\" alertname=SiteDown err=\"latitude \\\"north\\\" isn't between -90 and 90\"",
+		"level=warn msg=\"alert has an invalid location\" alertname=PowerLoss err=\"latitude \\\"north\\\" isn't between -90 and 90\"",
+	},
+}}
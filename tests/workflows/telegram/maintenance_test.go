@@ -0,0 +1,243 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/calendar"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestMaintenance(remindBefore time.Duration, windows ...*telegram.Maintenance) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewMaintenanceStore(newTestKV(), "telegram/maintenance")
+		if err != nil {
+			return err
+		}
+		for _, m := range windows {
+			if err := s.Put(m); err != nil {
+				return err
+			}
+		}
+		return telegram.WithMaintenance(s, remindBefore)(b)
+	}
+}
+
+func maintenanceMessage(text string) telebot.Update {
+	return telebot.Update{Message: &telebot.Message{
+		Sender: admin,
+		Chat:   chatFromUser(admin),
+		Text:   text,
+	}}
+}
+
+// testCalendar returns its events after a delay, so that chats subscribe before the windows are announced.
+type testCalendar []calendar.Event
+
+func (c testCalendar) Events(context.Context) ([]calendar.Event, error) {
+	time.Sleep(50 * time.Millisecond)
+	return c, nil
+}
+
+var (
+	backupStartsAt = time.Now().Add(10 * time.Minute)
+	upgradeEndsAt  = time.Now().Add(10 * time.Minute)
+)
+
+var maintenanceWorkflows = []workflow{{
+	name: "MaintenanceAdd",
+	messages: []telebot.Update{
+		maintenanceMessage(telegram.CommandMaintenance + ` add "DB upgrade" 2030-07-01T22:00 4h instance=db-1`),
+		maintenanceMessage(telegram.CommandMaintenance),
+		maintenanceMessage(telegram.CommandMaintenance + " add backup 2030-07-01T22:00 1h team!=db instance=~db-.+"),
+		maintenanceMessage(telegram.CommandMaintenance + " add restore 2030-07-01T22:00 1h team"),
+		maintenanceMessage(telegram.CommandMaintenance + " remove db-upgrade"),
+	},
+	options: []telegram.BotOption{withTestMaintenance(15 * time.Minute)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Added the maintenance window DB upgrade from 2030-07-01T22:00 to 2030-07-02T02:00, the alerts matching {instance=\"db-1\"} will be silenced.",
+	}, {
+		recipient: "123",
+		message:   "Maintenance windows:\nDB upgrade (db-upgrade) 2030-07-01T22:00 - 2030-07-02T02:00 {instance=\"db-1\"}",
+	}, {
+		recipient: "123",
+		message:   "Added the maintenance window backup from 2030-07-01T22:00 to 2030-07-01T23:00, the alerts matching {team!=\"db\", instance=~\"db-.+\"} will be silenced.",
+	}, {
+		recipient: "123",
+		message:   "\"team\" isn't a valid matcher, e.g. instance=db-1 or team!=db.",
+	}, {
+		recipient: "123",
+		message:   "Removed the maintenance window DB upgrade.",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 5},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/maintenance add \\\"DB upgrade\\\" 2030-07-01T22:00 4h instance=db-1\"",
+		"level=info msg=\"maintenance window added\" id=db-upgrade username=elliot",
+		"level=debug msg=\"message received\" text=/maintenance",
+		"level=debug msg=\"message received\" text=\"/maintenance add backup 2030-07-01T22:00 1h team!=db instance=~db-.+\"",
+		"level=info msg=\"maintenance window added\" id=backup username=elliot",
+		"level=debug msg=\"message received\" text=\"/maintenance add restore 2030-07-01T22:00 1h team\"",
+		"level=debug msg=\"message received\" text=\"/maintenance remove db-upgrade\"",
+		"level=info msg=\"maintenance window removed\" id=db-upgrade username=elliot",
+	},
+}, {
+	name:     "MaintenanceLegacyMatchers",
+	messages: []telebot.Update{maintenanceMessage(telegram.CommandMaintenance)},
+	options: []telegram.BotOption{func(b *telegram.Bot) error {
+		// Windows stored before other matchers than equality were supported have a map of matchers.
+		kv := newTestKV()
+		if err := kv.Put("telegram/maintenance/db-upgrade", []byte(`{"id":"db-upgrade","name":"DB upgrade","matchers":{"team":"db","instance":"db-1"},"startsAt":"2030-07-01T22:00:00Z","endsAt":"2030-07-02T02:00:00Z","createdBy":"elliot"}`), nil); err != nil {
+			return err
+		}
+		s, err := telegram.NewMaintenanceStore(kv, "telegram/maintenance")
+		if err != nil {
+			return err
+		}
+		return telegram.WithMaintenance(s, 0)(b)
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Maintenance windows:\nDB upgrade (db-upgrade) " + time.Date(2030, 7, 1, 22, 0, 0, 0, time.UTC).Local().Format(telegram.MaintenanceLayout) + " - " + time.Date(2030, 7, 2, 2, 0, 0, 0, time.UTC).Local().Format(telegram.MaintenanceLayout) + " {instance=\"db-1\", team=\"db\"}",
+	}},
+	counter: map[string]uint{telegram.CommandMaintenance: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/maintenance",
+	},
+}, {
+	name:   "MaintenanceReminders",
+	runFor: 100 * time.Millisecond,
+	options: []telegram.BotOption{withTestMaintenance(15*time.Minute, &telegram.Maintenance{
+		ID:        "backup",
+		Name:      "Backup",
+		Matchers:  []string{`job="postgres"`},
+		StartsAt:  backupStartsAt,
+		EndsAt:    backupStartsAt.Add(time.Hour),
+		CreatedBy: "elliot",
+		ChatID:    int64(admin.ID),
+	}, &telegram.Maintenance{
+		ID:        "db-upgrade",
+		Name:      "DB upgrade",
+		Matchers:  []string{`instance="db-1"`, `team!="web"`},
+		StartsAt:  upgradeEndsAt.Add(-4 * time.Hour),
+		EndsAt:    upgradeEndsAt,
+		CreatedBy: "elliot",
+		ChatID:    int64(admin.ID),
+	})},
+	// != is only supported in silences since Alertmanager v0.22.
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"versionInfo":{"version":"0.22.2"}}`
+	},
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var s struct {
+			Matchers []struct {
+				Name    string `json:"name"`
+				Value   string `json:"value"`
+				IsEqual bool   `json:"isEqual"`
+			} `json:"matchers"`
+			CreatedBy string    `json:"createdBy"`
+			EndsAt    time.Time `json:"endsAt"`
+		}
+		require.NoError(t, json.Unmarshal(body, &s))
+		require.Len(t, s.Matchers, 2)
+		require.Equal(t, "instance", s.Matchers[0].Name)
+		require.Equal(t, "db-1", s.Matchers[0].Value)
+		require.True(t, s.Matchers[0].IsEqual)
+		require.Equal(t, "team", s.Matchers[1].Name)
+		require.Equal(t, "web", s.Matchers[1].Value)
+		require.False(t, s.Matchers[1].IsEqual)
+		require.Equal(t, "elliot", s.CreatedBy)
+		require.WithinDuration(t, upgradeEndsAt, s.EndsAt, time.Second)
+
+		return `{"silenceID":"7e9a"}`
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "🔧 The maintenance window Backup starts at " + backupStartsAt.Format(telegram.MaintenanceLayout) + ".",
+	}, {
+		recipient: "123",
+		message:   "🔧 The maintenance window DB upgrade ends at " + upgradeEndsAt.Format(telegram.MaintenanceLayout) + ".",
+	}},
+	logs: []string{
+		"level=info msg=\"maintenance window started\" id=db-upgrade silence_id=7e9a",
+	},
+}, {
+	name:     "MaintenanceSuppression",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{withTestMaintenance(0, &telegram.Maintenance{
+		ID:        "fire-drill",
+		Name:      "Fire drill",
+		Matchers:  []string{`alertname="fire"`},
+		StartsAt:  time.Now().Add(-time.Hour),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "elliot",
+		ChatID:    -1234,
+		SilenceID: "7e9a",
+	})},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	webhooks: webhookFilters,
+}, {
+	name:     "MaintenanceCalendar",
+	runFor:   200 * time.Millisecond,
+	messages: []telebot.Update{maintenanceMessage(telegram.CommandStart)},
+	options: []telegram.BotOption{
+		withTestMaintenance(15*time.Minute, &telegram.Maintenance{
+			ID:       "calendar-old",
+			Name:     "Cancelled upgrade",
+			Matchers: []string{`instance="db-2"`},
+			StartsAt: time.Now().Add(time.Hour),
+			EndsAt:   time.Now().Add(2 * time.Hour),
+			Source:   "calendar",
+		}),
+		telegram.WithMaintenanceCalendar(testCalendar{{
+			UID:         "a1b2c3@google.com",
+			Summary:     "DB upgrade",
+			Description: "Upgrading to Postgres 16.\ninstance=db-1",
+			Start:       backupStartsAt,
+			End:         backupStartsAt.Add(4 * time.Hour),
+		}, {
+			UID:     "d4e5f6@google.com",
+			Summary: "Team lunch",
+			Start:   backupStartsAt,
+			End:     backupStartsAt.Add(time.Hour),
+		}, {
+			UID:         "g7h8i9@google.com",
+			Summary:     "Network maintenance",
+			Description: "job=switch",
+			Start:       time.Now().Add(-2 * time.Hour),
+			End:         time.Now().Add(-time.Hour),
+		}}, time.Hour),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔧 The maintenance window DB upgrade starts at " + backupStartsAt.Format(telegram.MaintenanceLayout) + ".",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=info msg=\"maintenance window synced from calendar\" id=calendar-a1b2c3-google-com",
+		"level=info msg=\"maintenance window removed from calendar\" id=calendar-old",
+	},
+}}
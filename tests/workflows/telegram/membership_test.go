@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var membershipWorkflows = []workflow{{
+	name: "GroupRemovedAndAddedAgain",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender:   admin,
+			Chat:     wallboard,
+			UserLeft: telegramtest.Me,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats + " archived",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender:     admin,
+			Chat:       wallboard,
+			UserJoined: telegramtest.Me,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats,
+		},
+	}},
+	options: []telegram.BotOption{withTestChatArchive()},
+	replies: []reply{{
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Archived chats, restore them with /chats restore <id>:\n@Wallboard (-5678)",
+	}, {
+		recipient: "-5678",
+		message:   "Hey! I'm back and will keep you all up to date again, with the settings from before.\n/help",
+	}, {
+		recipient: "123",
+		message:   "Currently these chat have subscribed:\n@Wallboard",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandChats: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=info msg=\"group subscription archived, bot was removed\" chat_id=-5678",
+		"level=debug msg=\"message received\" text=\"/chats archived\"",
+		"level=info msg=\"group subscription restored\" chat_id=-5678 username=elliot",
+		"level=debug msg=\"message received\" text=/chats",
+	},
+}, {
+	name: "GroupAddedByForbiddenSender",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   wallboard,
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender:   admin,
+			Chat:     wallboard,
+			UserLeft: telegramtest.Me,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender:     nobody,
+			Chat:       wallboard,
+			UserJoined: telegramtest.Me,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender:     admin,
+			Chat:       wallboard,
+			UserJoined: nobody,
+		},
+	}},
+	options: []telegram.BotOption{withTestChatArchive()},
+	replies: []reply{{
+		recipient: "-5678",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-5678",
+		"level=info msg=\"group subscription archived, bot was removed\" chat_id=-5678",
+		"level=debug msg=\"not restoring group added by forbidden sender\" chat_id=-5678",
+	},
+}}
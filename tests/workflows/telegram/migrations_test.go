@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestChatMigrations() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewMigrationStore(newTestKV(), "telegram/migrations")
+		if err != nil {
+			return err
+		}
+		return telegram.WithChatMigrations(s)(b)
+	}
+}
+
+var migrateToSupergroup = telebot.Update{
+	Message: &telebot.Message{
+		Sender:    admin,
+		Chat:      &telebot.Chat{ID: -1234, Type: telebot.ChatGroup},
+		MigrateTo: -1001234,
+	},
+}
+
+var migrationsWorkflows = []workflow{{
+	name:     "ChatMigratedToSupergroup",
+	messages: []telebot.Update{filterStart, migrateToSupergroup},
+	options: []telegram.BotOption{
+		withTestChatMigrations(),
+		withTestFilters(&telegram.ChatFilter{
+			ChatID:   -1234,
+			Matchers: []string{`severity="critical"`, "alertname=fire"},
+		}),
+	},
+	webhooks: webhookFilters,
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1001234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=info msg=\"chat migrated to supergroup\" from=-1234 to=-1001234",
+	},
+}, {
+	name:     "ChatMigratedWithFilter",
+	messages: []telebot.Update{filterStart, migrateToSupergroup},
+	options: []telegram.BotOption{
+		withTestChatMigrations(),
+		withTestFilters(&telegram.ChatFilter{
+			ChatID:   -1234,
+			Matchers: []string{"severity=warning"},
+		}),
+	},
+	webhooks: webhookFilters,
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=info msg=\"chat migrated to supergroup\" from=-1234 to=-1001234",
+	},
+}}
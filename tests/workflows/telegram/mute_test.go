@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestMentions mentions users with an empty mute store.
+func withTestMentions() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewMuteStore(newTestKV(), "telegram/mutes")
+		if err != nil {
+			return err
+		}
+		return telegram.WithMentions(s)(b)
+	}
+}
+
+func webhookMentions() webhook.Message {
+	return webhook.Message{Data: &template.Data{
+		Receiver: "telegram",
+		Status:   "firing",
+		Alerts: template.Alerts{{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "fire", "severity": "critical"},
+			Annotations: template.KV{"message": "Something is on fire", "mentions": "@elliot @nobody"},
+			StartsAt:    time.Now().Add(-time.Hour),
+		}},
+	}}
+}
+
+var muteWorkflows = []workflow{{
+	name: "MuteMe",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandMute + " me",
+		},
+	}},
+	options: []telegram.BotOption{withTestMentions()},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "Alright, John! I won't mention you in this chat anymore.",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour\n\n@elliot",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandMute: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=\"/mute me\"",
+		"level=info msg=\"user changed mentions\" chat_id=-1234 username=nobody muted=true",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{ChatID: -1234, Message: webhookMentions()}}
+	},
+}, {
+	name: "MuteMePrivate",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandMute + " me",
+		},
+	}},
+	options: []telegram.BotOption{withTestMentions()},
+	replies: []reply{{
+		recipient: "222",
+		message:   "You're only mentioned in group chats.",
+	}},
+	counter: map[string]uint{telegram.CommandMute: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/mute me\"",
+	},
+}}
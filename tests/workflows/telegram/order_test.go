@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func webhookUnordered() []alertmanager.TelegramWebhook {
+	alert := func(name, severity string) template.Alert {
+		return template.Alert{
+			Status:   "firing",
+			Labels:   template.KV{"alertname": name, "severity": severity},
+			StartsAt: time.Now().Add(-time.Hour),
+		}
+	}
+	return []alertmanager.TelegramWebhook{{
+		ChatID: int64(admin.ID),
+		Message: webhook.Message{Data: &template.Data{
+			Receiver: "telegram",
+			Status:   "firing",
+			Alerts:   template.Alerts{alert("load", "warning"), alert("disk", "warning"), alert("fire", "critical")},
+		}},
+	}}
+}
+
+var orderWorkflows = []workflow{{
+	name: "AlertsSorted",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options:  []telegram.BotOption{telegram.WithAlertOrder([]string{telegram.SortBySeverity, telegram.SortByAlertname}, []string{"critical", "warning"}, "")},
+	webhooks: webhookUnordered,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message: "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n" +
+			"🔥 <b>disk</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n" +
+			"🔥 <b>load</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}, {
+	name: "AlertsGrouped",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options:  []telegram.BotOption{telegram.WithAlertOrder([]string{telegram.SortByAlertname}, nil, "severity")},
+	webhooks: webhookUnordered,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message: "<b>severity: warning</b>\n" +
+			"🔥 <b>disk</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n" +
+			"🔥 <b>load</b> 🔥\n<b>Labels:</b>\n    severity: warning\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n" +
+			"<b>severity: critical</b>\n" +
+			"🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}}
@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withPersistedOutage buffers alerts with an outage that started ago when the bot is created.
+func withPersistedOutage(ago time.Duration, chats map[int64]*telegram.OutageChat) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewOutageStore(newTestKV(), "telegram/outage")
+		if err != nil {
+			return err
+		}
+		if err := s.Put(&telegram.Outage{Since: time.Now().Add(-ago), Chats: chats}); err != nil {
+			return err
+		}
+		return telegram.WithOutageBuffer(20*time.Millisecond, s)(b)
+	}
+}
+
+// alertmanagerFiring only lists the fire alert of webhookFiring as firing.
+func alertmanagerFiring(t *testing.T, r *http.Request) string {
+	return fmt.Sprintf(
+		`[{"labels":{"alertname":"fire","severity":"critical"},"annotations":{"message":"Something is on fire"},"startsAt":"%s"}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339),
+	)
+}
+
+var outageWorkflows = []workflow{{
+	name:   "OutageSummary",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithOutageBuffer(20*time.Millisecond, nil),
+	},
+	unreachable: 1,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "⚠️ Telegram was unreachable for less than a minute, 3 alert(s) occurred, 1 still firing.\n🔥 fire\nSee /alerts for details.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"telegram is unreachable, buffering alerts\" err=\"Post \\\"https://api.telegram.org/sendMessage\\\": connection refused\"",
+		"level=info msg=\"telegram is reachable again\" duration=0s",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{
+			{ChatID: int64(admin.ID), Message: webhookFiring},
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("resolved")},
+		}
+	},
+	alertmanagerAlerts: alertmanagerFiring,
+}, {
+	name:   "OutageMaxAge",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithNotifyMaxAge(time.Hour),
+		telegram.WithOutageBuffer(20*time.Millisecond, nil),
+	},
+	unreachable: 1,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "⚠️ Telegram was unreachable for less than a minute, 2 alert(s) occurred, 1 still firing.\n🔥 fire\nSee /alerts for details.",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"telegram is unreachable, buffering alerts\" err=\"Post \\\"https://api.telegram.org/sendMessage\\\": connection refused\"",
+		"level=info msg=\"telegram is reachable again\" duration=0s",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{
+			{ChatID: int64(admin.ID), Message: webhookFiring},
+			// The flapping alert resolved in Alertmanager in the meantime and isn't sent.
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+		}
+	},
+	alertmanagerAlerts: alertmanagerFiring,
+}, {
+	name:   "OutageMaxAgeStale",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandID,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithNotifyMaxAge(10 * time.Minute),
+		withPersistedOutage(time.Hour, map[int64]*telegram.OutageChat{
+			int64(admin.ID): {
+				Alerts:   1,
+				Firing:   map[string]telegram.OutageAlert{"a": {Name: "flap"}},
+				Messages: []telegram.OutageMessage{{ReceivedAt: time.Now().Add(-30 * time.Minute), Message: webhookFlap("firing")}},
+			},
+		}),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Your ID is 123",
+	}, {
+		recipient: "123",
+		message:   "⚠️ Telegram was unreachable for 1 hour, 1 alert(s) occurred, 1 still firing.\n🔥 flap\nSee /alerts for details.",
+	}},
+	counter: map[string]uint{telegram.CommandID: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/id",
+		"level=info msg=\"telegram is reachable again\" duration=1h0m0s",
+	},
+}, {
+	name:   "OutagePersisted",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandID,
+		},
+	}},
+	options: []telegram.BotOption{
+		withPersistedOutage(18*time.Minute, map[int64]*telegram.OutageChat{
+			int64(admin.ID): {Alerts: 12, Firing: map[string]telegram.OutageAlert{"a": {Name: "DiskFull"}, "b": {Name: "HighLatency"}}},
+			-1234:           {Alerts: 2, Firing: map[string]telegram.OutageAlert{}},
+		}),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Your ID is 123",
+	}, {
+		recipient: "-1234",
+		message:   "⚠️ Telegram was unreachable for 18 minutes, 2 alert(s) occurred, 0 still firing.",
+	}, {
+		recipient: "123",
+		message:   "⚠️ Telegram was unreachable for 18 minutes, 12 alert(s) occurred, 2 still firing.\n🔥 DiskFull\n🔥 HighLatency\nSee /alerts for details.",
+	}},
+	counter: map[string]uint{telegram.CommandID: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/id",
+		"level=info msg=\"telegram is reachable again\" duration=18m0s",
+	},
+}}
@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputCommands(t *testing.T) {
+	alerts, err := telegram.NewAlertStore(newTestKV(), "telegram/alerts")
+	require.NoError(t, err)
+	require.NoError(t, alerts.Put(&telegram.ChatAlert{
+		ChatID:      -1234,
+		Fingerprint: "a1b2c3",
+		Labels:      map[string]string{"alertname": "fire"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}))
+	history, err := telegram.NewHistoryStore(newTestKV(), "telegram/history")
+	require.NoError(t, err)
+
+	tg, err := telegramtest.New()
+	require.NoError(t, err)
+	var commands []string
+	bot, err := telegram.NewBotWithTelegram(&telegramtest.ChatStore{}, tg, admin.ID,
+		telegram.WithAlertStore(alerts),
+		telegram.WithHistory(history, 0),
+		telegram.WithCommandEvent(func(command string) { commands = append(commands, command) }),
+		telegram.WithOutputCommands("mattermost", notify.NewMattermostCommands("t0ken"), -1234, []string{"alice", "bob"}),
+	)
+	require.NoError(t, err)
+	handler := telegram.HandleOutputCommands(log.NewNopLogger(), map[string]*telegram.Bot{"telegram": bot})
+
+	command := func(path, token, user, text string) (int, string) {
+		form := url.Values{"token": {token}, "user_name": {user}, "text": {text}}
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		var reply struct {
+			Text string `json:"text"`
+		}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&reply))
+		}
+		return w.Code, reply.Text
+	}
+
+	for _, tc := range []struct {
+		name  string
+		path  string
+		token string
+		user  string
+		text  string
+		code  int
+		reply string
+	}{{
+		name:  "Ack",
+		user:  "alice",
+		text:  "/ack fire",
+		reply: "Acknowledged 1 alert(s) of fire.",
+	}, {
+		name:  "Stats",
+		user:  "bob",
+		text:  "/stats 1d",
+		reply: "No alerts in the last 1 day! 🎉",
+	}, {
+		name:  "Help",
+		user:  "bob",
+		text:  "/help",
+		reply: "The commands available here are:\n/help - Show the available commands.\n",
+	}, {
+		name:  "TelegramOnly",
+		user:  "alice",
+		text:  "/start",
+		reply: "/start is only available in Telegram, send /help for the commands available here.",
+	}, {
+		name:  "Forbidden",
+		user:  "mallory",
+		text:  "/ack fire",
+		reply: "You aren't allowed to command the bot.",
+	}, {
+		name:  "NoCommand",
+		user:  "alice",
+		text:  "hello",
+		code:  http.StatusNoContent,
+		reply: "",
+	}, {
+		name:  "Unauthenticated",
+		token: "guessed",
+		user:  "alice",
+		text:  "/ack fire",
+		code:  http.StatusUnauthorized,
+	}, {
+		name: "UnknownOutput",
+		path: "/commands/telegram/webex",
+		user: "alice",
+		text: "/ack fire",
+		code: http.StatusNotFound,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.path == "" {
+				tc.path = "/commands/telegram/mattermost"
+			}
+			if tc.token == "" {
+				tc.token = "t0ken"
+			}
+			if tc.code == 0 {
+				tc.code = http.StatusOK
+			}
+			code, reply := command(tc.path, tc.token, tc.user, tc.text)
+			require.Equal(t, tc.code, code)
+			if tc.name == "Help" {
+				require.True(t, strings.HasPrefix(reply, tc.reply), reply)
+				require.Contains(t, reply, "/alerts - ")
+				require.NotContains(t, reply, "/start - ")
+				return
+			}
+			require.Equal(t, tc.reply, reply)
+		})
+	}
+
+	acked, err := alerts.List()
+	require.NoError(t, err)
+	require.Equal(t, "alice", acked[0].AckedBy)
+	require.Equal(t, []string{telegram.CommandAck, telegram.CommandStats, telegram.CommandHelp}, commands)
+	require.Empty(t, tg.Replies())
+}
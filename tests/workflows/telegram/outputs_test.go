@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/notify"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testOutput fails unless it's sent the expected message.
+type testOutput notify.Message
+
+func (o testOutput) Notify(_ context.Context, msg notify.Message) error {
+	if msg != notify.Message(o) {
+		return fmt.Errorf("unexpected message %+v", msg)
+	}
+	return nil
+}
+
+// brokenOutput always fails.
+type brokenOutput struct{}
+
+func (brokenOutput) Notify(context.Context, notify.Message) error {
+	return errors.New("connection refused")
+}
+
+func webhookRunbook() []alertmanager.TelegramWebhook {
+	return []alertmanager.TelegramWebhook{{
+		ChatID: int64(admin.ID),
+		Message: webhook.Message{Data: &template.Data{
+			Receiver: "telegram",
+			Status:   "firing",
+			Alerts: template.Alerts{{
+				Status:      "firing",
+				Labels:      template.KV{"alertname": "fire", "severity": "critical"},
+				Annotations: template.KV{"runbook": "https://example.com/runbook?a=1&b=2"},
+				StartsAt:    time.Now().Add(-time.Hour),
+			}},
+		}},
+	}}
+}
+
+var outputsWorkflows = []workflow{{
+	name: "Outputs",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithOutput("webex", testOutput{
+			Subject:  "[FIRING:1] fire",
+			HTML:     "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    runbook: https://example.com/runbook?a=1&amp;b=2\n<b>Duration:</b> 1 hour",
+			Markdown: "🔥 **fire** 🔥\n**Labels:**\n    severity: critical\n**Annotations:**\n    runbook: https://example.com/runbook?a=1&b=2\n**Duration:** 1 hour",
+		}),
+		telegram.WithOutput("mattermost", brokenOutput{}),
+	},
+	webhooks: webhookRunbook,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    runbook: https://example.com/runbook?a=1&amp;b=2\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=warn msg=\"failed to send alerts to output\" output=mattermost err=\"connection refused\"",
+	},
+}}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// webhooksFromPeers returns the webhook of the alert as sent by each of the peers of an Alertmanager cluster.
+func webhooksFromPeers(labels, annotations template.KV, peers ...string) []alertmanager.TelegramWebhook {
+	var webhooks []alertmanager.TelegramWebhook
+	for _, peer := range peers {
+		w := webhookAlert(labels, annotations)()[0]
+		w.Message.ExternalURL = peer
+		webhooks = append(webhooks, w)
+	}
+	return webhooks
+}
+
+var peerDedupWorkflows = []workflow{{
+	name:     "PeerDedup",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithPeerDedup(time.Minute)},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return append(
+			webhooksFromPeers(
+				template.KV{"alertname": "DiskFull", "service": "db"},
+				template.KV{"message": "Disk is full"},
+				"http://alertmanager-0:9093", "http://alertmanager-1:9093",
+			),
+			webhooksFromPeers(
+				template.KV{"alertname": "DiskFull", "service": "web"},
+				template.KV{"message": "Disk is full"},
+				"http://alertmanager-1:9093",
+			)...,
+		)
+	},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    service: db\n<b>Annotations:</b>\n    message: Disk is full\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    service: web\n<b>Annotations:</b>\n    message: Disk is full\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"dropping duplicate webhook of alertmanager peer\" group_key=\"{}:{alertname=\\\"DiskFull\\\"}\" status=firing peer=http://alertmanager-1:9093 first_peer=http://alertmanager-0:9093",
+	},
+}}
@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var permissionsWorkflows = []workflow{{
+	name: "CommandPermissionsRole",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandChats,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithCommandPermissions(
+		map[string][]string{telegram.CommandHelp: {"helpers"}},
+		map[string][]string{"helpers": {"@nobody"}},
+	)},
+	replies: []reply{{
+		recipient: "222",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}},
+	counter: map[string]uint{telegram.CommandHelp: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/help",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}, {
+	name: "CommandPermissionsSubscribed",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat: &telebot.Chat{
+				ID:   -1234,
+				Type: telebot.ChatGroup,
+			},
+			Text: telegram.CommandHelp,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: nobody,
+			Chat:   chatFromUser(nobody),
+			Text:   telegram.CommandHelp,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithCommandPermissions(
+		map[string][]string{telegram.CommandHelp: {telegram.PrincipalSubscribed}},
+		nil,
+	)},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   strings.TrimSpace(telegram.ResponseHelp),
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandHelp: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=/help",
+		"level=info msg=\"dropping message from forbidden sender\" sender_id=222 sender_username=nobody",
+	},
+}}
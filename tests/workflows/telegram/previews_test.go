@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestPreviews() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewPreviewStore(newTestKV(), "telegram/previews")
+		if err != nil {
+			return err
+		}
+		return telegram.WithLinkPreviews(s)(b)
+	}
+}
+
+var previewsWorkflows = []workflow{{
+	name: "PreviewsOff",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandPreviews + " off",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandPreviews,
+		},
+	}},
+	options: []telegram.BotOption{withTestPreviews()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Link previews are now off for this chat.",
+	}, {
+		recipient: "123",
+		message:   "Link previews are off for this chat.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandPreviews: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/previews off\"",
+		"level=info msg=\"link previews changed\" chat_id=123 off=true",
+		"level=debug msg=\"message received\" text=/previews",
+	},
+}, {
+	name: "PreviewsNotSubscribed",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandPreviews + " off",
+		},
+	}},
+	options: []telegram.BotOption{withTestPreviews()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This chat isn't subscribed.",
+	}},
+	counter: map[string]uint{telegram.CommandPreviews: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/previews off\"",
+	},
+}, {
+	name: "PreviewsUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandPreviews + " maybe",
+		},
+	}},
+	options: []telegram.BotOption{withTestPreviews()},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Usage: /previews on|off",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandPreviews: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/previews maybe\"",
+	},
+}}
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func callbackForgetMe(sender *telebot.User) telebot.Update {
+	return telebot.Update{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  sender,
+		Message: &telebot.Message{ID: 2, Chat: chatFromUser(admin)},
+		Data:    "\fforgetme|123",
+	}}
+}
+
+// withTestPurgedMutes mutes elliot in the group -1234 and migrates the private chat 123, to be purged by elliot.
+func withTestPurgedMutes() telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		mutes, err := telegram.NewMuteStore(newTestKV(), "telegram/mutes")
+		if err != nil {
+			return err
+		}
+		if err := mutes.Put(&telegram.ChatMutes{ChatID: -1234, Usernames: []string{"darlene", "Elliot"}}); err != nil {
+			return err
+		}
+		migrations, err := telegram.NewMigrationStore(newTestKV(), "telegram/migrations")
+		if err != nil {
+			return err
+		}
+		if err := migrations.Put(&telegram.ChatMigration{From: -4321, To: 123, At: now}); err != nil {
+			return err
+		}
+		if err := telegram.WithMentions(mutes)(b); err != nil {
+			return err
+		}
+		return telegram.WithChatMigrations(migrations)(b)
+	}
+}
+
+var purgeWorkflows = []workflow{{
+	name: "ForgetMe",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandForgetMe,
+		},
+	}},
+	alerts: []*telegram.ChatAlert{{
+		ChatID:      123,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "KafkaLag"},
+		StartsAt:    now.Add(-time.Hour),
+	}, {
+		ChatID:      -1234,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "KafkaLag"},
+		StartsAt:    now.Add(-time.Hour),
+		AckedAt:     now.Add(-30 * time.Minute),
+		AckedBy:     "Elliot",
+	}},
+	history: []*telegram.HistoryEntry{{
+		ChatID:      123,
+		Fingerprint: "b",
+		Labels:      map[string]string{"alertname": "KafkaLag", "team": "streaming"},
+		StartsAt:    now.Add(-3 * time.Hour),
+		EndsAt:      now.Add(-2 * time.Hour),
+	}},
+	deliveries: []*telegram.Delivery{{
+		GroupKey: `{}:{alertname="KafkaLag"}`,
+		ChatID:   123,
+		Status:   telegram.DeliverySent,
+		Attempts: 1,
+		Alerts:   1,
+		At:       now.Add(-time.Hour),
+	}},
+	updates: []telebot.Update{callbackForgetMe(admin), {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandChats,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandFind + " kafka",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "This removes your subscription, settings and the history of alerts sent to you, and your name from the alerts you acknowledged, the incidents you opened and the groups you muted. It can't be undone.",
+	}, {
+		recipient: "edit:2",
+		message:   "I removed your data.",
+	}, {
+		recipient: "123",
+		message:   "Currently no one is subscribed.",
+	}, {
+		recipient: "123",
+		message:   "No alerts match kafka.",
+	}},
+	counter: map[string]uint{
+		telegram.CommandStart:    1,
+		telegram.CommandForgetMe: 1,
+		telegram.CommandChats:    1,
+		telegram.CommandFind:     1,
+	},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=/forgetme",
+		"level=info msg=\"chat data purged\" chat_id=123 requested_by=\"user 123\" subscribed=true alerts=1 history=1 incidents=0 deliveries=1 groups=0 webhooks=0 acks=1 mutes=0 migrations=0",
+		"level=debug msg=\"message received\" text=/chats",
+		"level=debug msg=\"message received\" text=\"/find kafka\"",
+	},
+}, {
+	name: "ForgetMeMutes",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandForgetMe,
+		},
+	}},
+	options: []telegram.BotOption{withTestPurgedMutes()},
+	updates: []telebot.Update{callbackForgetMe(admin)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This removes your subscription, settings and the history of alerts sent to you, and your name from the alerts you acknowledged, the incidents you opened and the groups you muted. It can't be undone.",
+	}, {
+		recipient: "edit:2",
+		message:   "I removed your data.",
+	}},
+	counter: map[string]uint{telegram.CommandForgetMe: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/forgetme",
+		"level=info msg=\"chat data purged\" chat_id=123 requested_by=\"user 123\" subscribed=false alerts=0 history=0 incidents=0 deliveries=0 groups=0 webhooks=0 acks=0 mutes=1 migrations=1",
+	},
+}, {
+	name: "ForgetMeOtherUser",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandForgetMe,
+		},
+	}},
+	updates: []telebot.Update{callbackForgetMe(nobody)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This removes your subscription, settings and the history of alerts sent to you, and your name from the alerts you acknowledged, the incidents you opened and the groups you muted. It can't be undone.",
+	}},
+	counter: map[string]uint{telegram.CommandForgetMe: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/forgetme",
+		"level=info msg=\"dropping forgetme confirmation of another user\" sender_id=222 sender_username=nobody",
+	},
+}}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var qrWorkflows = []workflow{{
+	name: "QR",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQR + " https://grafana.example.com/d/abc?var-instance=node-1",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "photo",
+	}},
+	counter: map[string]uint{telegram.CommandQR: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/qr https://grafana.example.com/d/abc?var-instance=node-1\"",
+	},
+}, {
+	name: "QRUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQR,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /qr <url>",
+	}},
+	counter: map[string]uint{telegram.CommandQR: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/qr",
+	},
+}, {
+	name: "QRTooLong",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQR + " " + strings.Repeat("x", 3000),
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This is too long for a QR code.",
+	}},
+	counter: map[string]uint{telegram.CommandQR: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/qr " + strings.Repeat("x", 3000) + "\"",
+	},
+}, {
+	name:     "QRTemplate",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{
+		telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl", "testdata/qr.tmpl"),
+		telegram.WithTemplateOverrides([]telegram.TemplateOverride{{Matchers: []string{"alertname=SlowDashboard"}, Template: "telegram.qr"}}),
+	},
+	webhooks: webhookAlert(
+		template.KV{"alertname": "SlowDashboard"},
+		template.KV{"dashboard": "https://grafana.example.com/d/abc?a=1&b=2"},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>SlowDashboard</b> https://grafana.example.com/d/abc?a=1&amp;b=2",
+	}, {
+		recipient: "-1234",
+		message:   "photo",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
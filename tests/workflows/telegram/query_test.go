@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var queryWorkflows = []workflow{{
+	name: "QueryUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQuery,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /query <promql>",
+	}},
+	counter: map[string]uint{telegram.CommandQuery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/query",
+	},
+}, {
+	name: "QueryEmpty",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQuery + " up == 0",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "The query returned no results.",
+	}},
+	counter: map[string]uint{telegram.CommandQuery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/query up == 0\"",
+	},
+}, {
+	name: "QueryVector",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandQuery + " up",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<pre>up{job=&#34;prometheus&#34;} =&gt; 1\nup{job=&#34;node&#34;} =&gt; 0</pre>",
+	}},
+	counter: map[string]uint{telegram.CommandQuery: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/query up\"",
+	},
+	prometheusQuery: func(t *testing.T, r *http.Request) string {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "up", r.Form.Get("query"))
+		return `{"status":"success","data":{"resultType":"vector","result":[` +
+			`{"metric":{"__name__":"up","job":"node"},"value":[1614000000,"0"]},` +
+			`{"metric":{"__name__":"up","job":"prometheus"},"value":[1614000000,"1"]}]}}`
+	},
+}}
@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// ackReactedMessage checks whether the alerts of the reacted message are acknowledged already.
+var ackReactedMessage = []telebot.Update{{
+	Message: &telebot.Message{
+		Sender:  admin,
+		Chat:    chatFromUser(admin),
+		Text:    telegram.CommandAck,
+		ReplyTo: &telebot.Message{ID: 42},
+	},
+}}
+
+var reactionsWorkflows = []workflow{{
+	name:    "ReactionAck",
+	options: []telegram.BotOption{telegram.WithReactionAck()},
+	alerts:  repliedAlerts,
+	reactions: []*telegram.Reaction{{
+		Chat:      chatFromUser(admin),
+		MessageID: 42,
+		Sender:    admin,
+		Emoji:     []string{"👍"},
+	}},
+	updates: ackReactedMessage,
+	replies: []reply{{
+		recipient: "123",
+		message:   "The message you replied to has no unacknowledged alerts.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+		"level=debug msg=\"message received\" text=/ack",
+	},
+}, {
+	name:    "ReactionOtherEmoji",
+	options: []telegram.BotOption{telegram.WithReactionAck()},
+	alerts:  repliedAlerts,
+	reactions: []*telegram.Reaction{{
+		Chat:      chatFromUser(admin),
+		MessageID: 42,
+		Sender:    admin,
+		Emoji:     []string{"🔥"},
+	}},
+	updates: ackReactedMessage,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}, {
+	name:    "ReactionForbidden",
+	options: []telegram.BotOption{telegram.WithReactionAck()},
+	alerts:  repliedAlerts,
+	reactions: []*telegram.Reaction{{
+		Chat:      chatFromUser(admin),
+		MessageID: 42,
+		Sender:    nobody,
+		Emoji:     []string{"👍"},
+	}},
+	updates: ackReactedMessage,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=info msg=\"dropping reaction from forbidden sender\" sender_id=222 sender_username=nobody",
+		"level=debug msg=\"message received\" text=/ack",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}, {
+	name:   "ReactionAckDisabled",
+	alerts: repliedAlerts,
+	reactions: []*telegram.Reaction{{
+		Chat:      chatFromUser(admin),
+		MessageID: 42,
+		Sender:    admin,
+		Emoji:     []string{"👍"},
+	}},
+	updates: ackReactedMessage,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}}
@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var reconcileAlerts = []*telegram.ChatAlert{{
+	ChatID:      int64(admin.ID),
+	Fingerprint: model.LabelSet{"alertname": "fire"}.Fingerprint().String(),
+	Receiver:    "telegram",
+	Labels:      map[string]string{"alertname": "fire"},
+	StartsAt:    time.Now().Add(-time.Hour),
+}, {
+	ChatID:      int64(admin.ID),
+	Fingerprint: model.LabelSet{"alertname": "db"}.Fingerprint().String(),
+	Receiver:    "telegram",
+	Labels:      map[string]string{"alertname": "db"},
+	StartsAt:    time.Now().Add(-time.Hour),
+}}
+
+// reconcileFiring lets only the fire alert still be firing in Alertmanager.
+func reconcileFiring(t *testing.T, r *http.Request) string {
+	return fmt.Sprintf(`[{"labels":{"alertname":"fire"},"annotations":{},"startsAt":"%s"}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339),
+	)
+}
+
+var reconcileWorkflows = []workflow{{
+	name: "ReconcileResolved",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " db",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " fire",
+		},
+	}},
+	alerts:             reconcileAlerts,
+	options:            []telegram.BotOption{telegram.WithReconciliation()},
+	alertmanagerAlerts: reconcileFiring,
+	replies: []reply{{
+		recipient: "123",
+		message:   "No unacknowledged alert matches db.",
+	}, {
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 2},
+	logs: []string{
+		"level=info msg=\"alerts reconciled with alertmanager\" alerts=2 resolved=1",
+		"level=debug msg=\"message received\" text=\"/ack db\"",
+		"level=debug msg=\"message received\" text=\"/ack fire\"",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}, {
+	name: "ReconcileDisabled",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAck + " db",
+		},
+	}},
+	alerts:             reconcileAlerts,
+	alertmanagerAlerts: reconcileFiring,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of db.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/ack db\"",
+		"level=info msg=\"alerts acknowledged\" alertname=db count=1 username=elliot",
+	},
+}}
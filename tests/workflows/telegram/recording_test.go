@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func TestRecordingReplay(t *testing.T) {
+	recordedAt := time.Now().Add(-10 * time.Minute)
+	payload, err := json.Marshal(webhook.Message{
+		Data: &template.Data{
+			Status: "firing",
+			Alerts: template.Alerts{{
+				Status:   "firing",
+				Labels:   template.KV{"alertname": "NodeDown"},
+				StartsAt: recordedAt.Add(-time.Hour),
+			}},
+			GroupLabels: template.KV{"alertname": "NodeDown"},
+		},
+		Version:  "4",
+		GroupKey: `{}:{alertname="NodeDown"}`,
+	})
+	require.NoError(t, err)
+
+	events := []telegram.RecordedEvent{
+		{At: recordedAt, Tenant: "telegram", Chats: []*telebot.Chat{{ID: -1234, Type: telebot.ChatGroup, Title: "sre"}}},
+		{At: recordedAt, Tenant: "telegram", Webhook: &telegram.ReceivedWebhook{ReceivedAt: recordedAt, ChatID: -1234, Payload: payload}},
+		{At: recordedAt, Tenant: "telegram", Update: &telebot.Update{Message: &telebot.Message{Sender: admin, Chat: chatFromUser(admin), Text: telegram.CommandID}}},
+		// Events of other tenants aren't replayed.
+		{At: recordedAt, Tenant: "team-a", Update: &telebot.Update{Message: &telebot.Message{Sender: admin, Chat: chatFromUser(admin), Text: telegram.CommandID}}},
+	}
+
+	// The replay is recorded again, it has to record the same events.
+	recording := &bytes.Buffer{}
+	replayer, err := telegram.NewReplayTelegram()
+	require.NoError(t, err)
+	bot, err := telegram.NewBotWithTelegram(&telegramtest.ChatStore{}, replayer, admin.ID,
+		telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
+		telegram.WithRecorder(telegram.NewRecorder(recording), "telegram"),
+	)
+	require.NoError(t, err)
+
+	replayed, err := bot.ReplayRecording(context.Background(), replayer, "telegram", events)
+	require.NoError(t, err)
+	require.Len(t, replayed, 2)
+	require.Equal(t, "-1234", replayed[0].Recipient)
+	require.Contains(t, replayed[0].Text, "<b>NodeDown</b>")
+	require.Contains(t, replayed[0].Text, "<b>Duration:</b> 1 hour", "alerts are as old as when they were recorded")
+	require.Equal(t, telegram.SentMessage{Recipient: "123", Text: "Your ID is 123"}, replayed[1])
+
+	rerecorded, err := telegram.ReadRecording(recording)
+	require.NoError(t, err)
+	require.Len(t, rerecorded, 5)
+	require.Equal(t, []*telebot.Chat{{ID: -1234, Type: telebot.ChatGroup, Title: "sre"}}, rerecorded[0].Chats)
+	require.Equal(t, int64(-1234), rerecorded[1].Webhook.ChatID)
+	require.Equal(t, replayed[0], *rerecorded[2].Sent)
+	require.Equal(t, telegram.CommandID, rerecorded[3].Update.Message.Text)
+	require.Equal(t, replayed[1], *rerecorded[4].Sent)
+	for _, e := range rerecorded {
+		require.Equal(t, "telegram", e.Tenant)
+	}
+
+	require.Empty(t, telegram.DiffSent(replayed, replayed))
+	require.Equal(t, []string{
+		"-   Your ID is 321",
+		"+   Your ID is 123",
+	}, telegram.DiffSent(
+		[]telegram.SentMessage{replayed[0], {Recipient: "123", Text: "Your ID is 321"}},
+		replayed,
+	))
+}
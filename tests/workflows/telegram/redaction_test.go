@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var redactionRules = []telegram.RedactionRule{
+	{Regexp: `(password|token)=\S+`, Replacement: "${1}=***"},
+	{Regexp: `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`},
+}
+
+var redactionWorkflows = []workflow{{
+	name:     "Redaction",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithRedaction(redactionRules)},
+	webhooks: webhookAlert(
+		template.KV{"alertname": "LoginFailed", "service": "db"},
+		template.KV{"message": "psql -U bob@example.com password=hunter2 failed", "runbook": "https://wiki/login"},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>LoginFailed</b> 🔥\n<b>Labels:</b>\n    service: db\n<b>Annotations:</b>\n    message: psql -U [REDACTED] password=*** failed\n    runbook: https://wiki/login\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
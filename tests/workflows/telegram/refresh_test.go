@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var refreshWorkflows = []workflow{{
+	name: "ChatRefresh",
+	messages: []telebot.Update{filterStart, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   filterStart.Message.Chat,
+			Text:   telegram.CommandMute + " me",
+		},
+	}},
+	options: []telegram.BotOption{withTestMentions(), telegram.WithChatRefresh(30 * time.Millisecond)},
+	chats: []*telebot.Chat{
+		{ID: -1234, Type: telebot.ChatGroup, Title: "sre"},
+		{ID: 123, Type: telebot.ChatPrivate, FirstName: "Elliot", LastName: "Alderson", Username: "mr_robot"},
+	},
+	runFor: 100 * time.Millisecond,
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "Alright, Elliot! I won't mention you in this chat anymore.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2, telegram.CommandMute: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/mute me\"",
+		"level=info msg=\"user changed mentions\" chat_id=-1234 username=elliot muted=true",
+		"level=info msg=\"chat refreshed\" chat_id=-1234 username= title=sre",
+		"level=info msg=\"chat refreshed\" chat_id=123 username=mr_robot title=",
+		"level=info msg=\"renamed muted user\" chat_id=-1234 from=elliot to=mr_robot",
+	},
+}}
@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/common/model"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func withTestReminders(policies map[string][]time.Duration) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewReminderStore(newTestKV(), "telegram/reminders")
+		if err != nil {
+			return err
+		}
+		return telegram.WithReminders(policies, s)(b)
+	}
+}
+
+var reminderLabels = model.LabelSet{"alertname": "fire", "severity": "critical"}
+
+var remindersWorkflows = []workflow{{
+	name:   "Reminder",
+	runFor: 100 * time.Millisecond,
+	alerts: []*telegram.ChatAlert{{
+		ChatID:      int64(admin.ID),
+		Fingerprint: reminderLabels.Fingerprint().String(),
+		Receiver:    "telegram",
+		Labels:      map[string]string{"alertname": "fire", "severity": "critical"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}},
+	options: []telegram.BotOption{withTestReminders(map[string][]time.Duration{
+		telegram.DefaultReminderPolicy: {time.Hour * 24},
+		"critical":                     {10 * time.Millisecond, 20 * time.Millisecond},
+	})},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		return fmt.Sprintf(`[{"labels":{"alertname":"fire","severity":"critical"},"annotations":{},"startsAt":"%s"}]`,
+			time.Now().Add(-time.Hour).Format(time.RFC3339),
+		)
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "⏳ fire is still firing after 1 hour.\n/ack fire",
+	}},
+	logs: []string{
+		"level=info msg=\"reminded of alert\" alertname=fire chat_id=123 reminder=2",
+	},
+}, {
+	name:   "RemindersOff",
+	runFor: 100 * time.Millisecond,
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReminders + " off",
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReminders,
+		},
+	}},
+	options: []telegram.BotOption{withTestReminders(map[string][]time.Duration{
+		telegram.DefaultReminderPolicy: {time.Hour},
+	})},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Reminders are now off for this chat.",
+	}, {
+		recipient: "123",
+		message:   "Reminders are off for this chat.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandReminders: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/reminders off\"",
+		"level=info msg=\"reminders changed\" chat_id=123 off=true",
+		"level=debug msg=\"message received\" text=/reminders",
+	},
+}}
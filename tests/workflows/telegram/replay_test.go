@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var replayWorkflows = []workflow{{
+	name: "Replay",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithWebhookBuffer(10, nil),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "Replaying 1 webhook(s).",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandReplay: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/replay 5\"",
+		"level=info msg=\"replaying webhooks\" count=1 username=elliot",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{ChatID: int64(admin.ID), Message: webhookFiring}}
+	},
+	updates: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReplay + " 5",
+		},
+	}},
+}, {
+	name: "ReplayNotEnabled",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandReplay,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Replaying webhooks isn't enabled.",
+	}},
+	counter: map[string]uint{telegram.CommandReplay: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/replay",
+	},
+}}
+
+func receivedWebhooks(webhooks ...telegram.ReceivedWebhook) telegram.BotWebhookStore {
+	s, err := telegram.NewWebhookStore(newTestKV(), "telegram/webhooks")
+	if err != nil {
+		panic(err)
+	}
+	if err := s.Put(webhooks); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+var lastWebhookWorkflows = []workflow{{
+	name: "LastWebhook",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandLastWebhook + " 2",
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithWebhookBuffer(10, receivedWebhooks(telegram.ReceivedWebhook{
+			ReceivedAt: time.Now().Add(-time.Hour),
+			ChatID:     -1234,
+			Payload:    []byte(`{"receiver":"telegram","status":"resolved","alerts":[]}`),
+		}, telegram.ReceivedWebhook{
+			ReceivedAt: time.Now().Add(-5 * time.Minute),
+			Group:      "sre",
+			Payload:    []byte(`{"receiver":"sre","status":"firing","alerts":[]}`),
+		})),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message: "Received 1 hour ago for chat -1234:\n<pre>{\n  &#34;receiver&#34;: &#34;telegram&#34;,\n" +
+			"  &#34;status&#34;: &#34;resolved&#34;,\n  &#34;alerts&#34;: []\n}</pre>",
+	}, {
+		recipient: "123",
+		message: "Received 5 minutes ago for group sre:\n<pre>{\n  &#34;receiver&#34;: &#34;sre&#34;,\n" +
+			"  &#34;status&#34;: &#34;firing&#34;,\n  &#34;alerts&#34;: []\n}</pre>",
+	}},
+	counter: map[string]uint{telegram.CommandLastWebhook: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/lastwebhook 2\"",
+	},
+}, {
+	name: "LastWebhookNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandLastWebhook,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithWebhookBuffer(10, receivedWebhooks()),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "I haven't received any webhooks yet.",
+	}},
+	counter: map[string]uint{telegram.CommandLastWebhook: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/lastwebhook",
+	},
+}}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var repliedAlerts = []*telegram.ChatAlert{{
+	ChatID:      int64(admin.ID),
+	Fingerprint: "a1b2c3",
+	Labels:      map[string]string{"alertname": "fire", "instance": "node-1"},
+	StartsAt:    time.Now().Add(-time.Hour),
+	MessageID:   42,
+}, {
+	ChatID:      int64(admin.ID),
+	Fingerprint: "d4e5f6",
+	Labels:      map[string]string{"alertname": "smoke"},
+	StartsAt:    time.Now().Add(-time.Hour),
+	MessageID:   43,
+}}
+
+var repliesWorkflows = []workflow{{
+	name: "ReplyAck",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:  admin,
+			Chat:    chatFromUser(admin),
+			Text:    telegram.CommandAck,
+			ReplyTo: &telebot.Message{ID: 42},
+		},
+	}},
+	alerts: repliedAlerts,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Acknowledged 1 alert(s) of fire.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+		"level=info msg=\"alerts acknowledged\" alertname=fire count=1 username=elliot",
+	},
+}, {
+	name: "ReplyAckUnknownMessage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:  admin,
+			Chat:    chatFromUser(admin),
+			Text:    telegram.CommandAck,
+			ReplyTo: &telebot.Message{ID: 7},
+		},
+	}},
+	alerts: repliedAlerts,
+	replies: []reply{{
+		recipient: "123",
+		message:   "The message you replied to has no unacknowledged alerts.",
+	}},
+	counter: map[string]uint{telegram.CommandAck: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ack",
+	},
+}, {
+	name: "ReplySilence",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:  admin,
+			Chat:    chatFromUser(admin),
+			Text:    telegram.CommandSilence + " 2h",
+			ReplyTo: &telebot.Message{ID: 42},
+		},
+	}},
+	alerts: repliedAlerts,
+	alertmanagerSilences: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, http.MethodPost, r.Method)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var s struct {
+			Matchers []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"matchers"`
+			StartsAt time.Time `json:"startsAt"`
+			EndsAt   time.Time `json:"endsAt"`
+		}
+		require.NoError(t, json.Unmarshal(body, &s))
+		require.Len(t, s.Matchers, 2)
+		require.Equal(t, 2*time.Hour, s.EndsAt.Sub(s.StartsAt))
+
+		return `{"silenceID":"7e9a"}`
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Silenced fire for 2 hours.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence 2h\"",
+		"level=info msg=\"silence created\" id=7e9a alertname=fire username=elliot",
+	},
+}, {
+	name: "SilenceWithoutReply",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandSilence,
+		},
+	}},
+	alerts: repliedAlerts,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: reply to an alert with /silence [duration], e.g. /silence 2h.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/silence",
+	},
+}, {
+	name: "ReplySilenceInvalidDuration",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender:  admin,
+			Chat:    chatFromUser(admin),
+			Text:    telegram.CommandSilence + " soon",
+			ReplyTo: &telebot.Message{ID: 43},
+		},
+	}},
+	alerts: repliedAlerts,
+	replies: []reply{{
+		recipient: "123",
+		message:   "\"soon\" isn't a valid duration, e.g. 30m, 2h or 1d.",
+	}},
+	counter: map[string]uint{telegram.CommandSilence: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/silence soon\"",
+	},
+}}
@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func routesStatus(t *testing.T, r *http.Request) string {
+	return `{"config":{"original":"route:\n  receiver: admin\n  group_by: [alertname]\n  routes:\n  - receiver: db\n    match:\n      team: db\n    continue: true\n  - receiver: pager\n    match_re:\n      severity: critical|page\nreceivers:\n- name: admin\n- name: db\n- name: pager"}}`
+}
+
+var routesWorkflows = []workflow{{
+	name: "Routes",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>Routing tree:</b>\n<pre>admin group_by: alertname\n├── db {team=&#34;db&#34;} continue\n└── pager {severity=~&#34;critical|page&#34;}\n</pre>",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/routes",
+	},
+	alertmanagerStatus: routesStatus,
+}, {
+	name: "RoutesTest",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes + " test team=db severity=critical",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alerts with {severity=&#34;critical&#34;, team=&#34;db&#34;} are sent to:</b>\n" +
+			"➡️ <b>db</b> matched by {team=&#34;db&#34;}, grouped by alertname\n" +
+			"➡️ <b>pager</b> matched by {severity=~&#34;critical|page&#34;}, grouped by alertname",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/routes test team=db severity=critical\"",
+	},
+	alertmanagerStatus: routesStatus,
+}, {
+	name: "RoutesTestInvalid",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRoutes + " test critical",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "invalid label \"critical\", expected name=value\nUsage: /routes [test <name=value>...]",
+	}},
+	counter: map[string]uint{telegram.CommandRoutes: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/routes test critical\"",
+	},
+	alertmanagerStatus: routesStatus,
+}}
@@ -0,0 +1,25 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+)
+
+var selfMonitoringWorkflows = []workflow{{
+	name:    "SelfMonitoringSendFailures",
+	runFor:  100 * time.Millisecond,
+	options: []telegram.BotOption{telegram.WithSelfMonitoring(20*time.Millisecond, 0, nil)},
+	logs: []string{
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=132461234 err=\"chat not found in store\"",
+		"level=warn msg=\"self-monitoring alert firing\" alertname=BotSendFailures",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=123 err=\"chat not found in store\"",
+		"level=info msg=\"self-monitoring alert resolved\" alertname=BotSendFailures",
+		"level=warn msg=\"chat is not subscribed for alerts\" chat_id=123 err=\"chat not found in store\"",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		webhookFiring.Alerts[0].StartsAt = time.Now().Add(-time.Hour)
+		return []alertmanager.TelegramWebhook{{ChatID: 132461234, Message: webhookFiring}}
+	},
+}}
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var shadowWorkflows = []workflow{{
+	name:     "ShadowTemplateOverride",
+	messages: []telebot.Update{filterStart},
+	options: []telegram.BotOption{telegram.WithShadow(-5678, telegram.WithTemplateOverrides([]telegram.TemplateOverride{{
+		Matchers: []string{"alertname=NodeDown"},
+		Template: "telegram.compact",
+	}}))},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "namespace": "shop"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-5678",
+		message:   "🧪 <b>Shadow of chat -1234</b>\n🔥 <b>NodeDown</b> in shop",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    namespace: shop\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "ShadowLabelFilter",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithShadow(-5678, telegram.WithLabelFilter(nil, []string{"node"}))},
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-5678",
+		message:   "🧪 <b>Shadow of chat -1234</b>\n🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// withTestSharding shares the chats of bot-0 with bot-1, whose last heartbeat was age ago.
+// The chat -1234 is assigned to bot-1 as long as it's a member.
+func withTestSharding(age time.Duration) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewShardMemberStore(newTestKV(), "telegram/shards")
+		if err != nil {
+			return err
+		}
+		if err := s.Put(&telegram.ShardMember{ID: "bot-1", SeenAt: time.Now().Add(-age)}); err != nil {
+			return err
+		}
+		return telegram.WithSharding(s, "bot-0", time.Minute)(b)
+	}
+}
+
+var shardsWorkflows = []workflow{{
+	name:     "ShardOfOtherReplica",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{withTestSharding(time.Second)},
+	webhooks: webhookAlert(template.KV{"alertname": "DiskFull", "service": "db"}, template.KV{"message": "Disk is full"}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=info msg=\"shard members changed\" members=bot-0,bot-1 count=2",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "ShardMemberExpired",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{withTestSharding(time.Hour)},
+	webhooks: webhookAlert(template.KV{"alertname": "DiskFull", "service": "db"}, template.KV{"message": "Disk is full"}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>DiskFull</b> 🔥\n<b>Labels:</b>\n    service: db\n<b>Annotations:</b>\n    message: Disk is full\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
@@ -0,0 +1,223 @@
+package telegram
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var now = time.Now()
+
+var statsWorkflows = []workflow{{
+	name: "StatsNone",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStats,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No alerts in the last 1 week! 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandStats: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/stats",
+	},
+}, {
+	name: "StatsInvalidRange",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStats + " forever",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /stats [range], e.g. /stats 7d",
+	}},
+	counter: map[string]uint{telegram.CommandStats: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/stats forever\"",
+	},
+}, {
+	name: "Stats",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStats + " 1d",
+		},
+	}},
+	history: []*telegram.HistoryEntry{{
+		ChatID:      123,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "fire", "namespace": "monitoring"},
+		StartsAt:    now.Add(-3 * time.Hour),
+		EndsAt:      now.Add(-2 * time.Hour),
+		AckedAt:     now.Add(-3*time.Hour + 10*time.Minute),
+	}, {
+		// The same alert sent to another chat is only counted once.
+		ChatID:      -1234,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "fire", "namespace": "monitoring"},
+		StartsAt:    now.Add(-3 * time.Hour),
+		EndsAt:      now.Add(-2 * time.Hour),
+	}, {
+		ChatID:      123,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "fire", "namespace": "monitoring"},
+		StartsAt:    now.Add(-time.Hour),
+	}, {
+		ChatID:      123,
+		Fingerprint: "b",
+		Labels:      map[string]string{"alertname": "disk", "namespace": "db"},
+		StartsAt:    now.Add(-90 * time.Minute),
+		EndsAt:      now.Add(-time.Hour),
+	}, {
+		// Outside of the range.
+		ChatID:      123,
+		Fingerprint: "c",
+		Labels:      map[string]string{"alertname": "old"},
+		StartsAt:    now.Add(-72 * time.Hour),
+		EndsAt:      now.Add(-48 * time.Hour),
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alert statistics for the last 1 day</b>\nAlerts: 3 (1 acknowledged, 2 resolved)\n\n" +
+			"<b>Top alerts:</b>\n2× fire, firing 2 hours\n1× disk, firing 30 minutes\n\n" +
+			"<b>Noisiest namespaces:</b>\n2× monitoring\n1× db\n\n" +
+			"<b>Mean time to acknowledge:</b> 10 minutes\n<b>Mean time to resolve:</b> 45 minutes\n\n" +
+			"<b>MTTA / MTTR by alert:</b>\nfire: 10 minutes / 1 hour\ndisk: - / 30 minutes",
+	}},
+	counter: map[string]uint{telegram.CommandStats: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/stats 1d\"",
+	},
+}}
+
+var myStatsWorkflows = []workflow{{
+	name: "MyStats",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMyStats,
+		},
+	}},
+	history: []*telegram.HistoryEntry{{
+		ChatID:      123,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "fire", "severity": "critical"},
+		StartsAt:    now.Add(-3 * time.Hour),
+	}, {
+		// Alerts sent to other chats aren't counted.
+		ChatID:      -1234,
+		Fingerprint: "a",
+		Labels:      map[string]string{"alertname": "fire", "severity": "critical"},
+		StartsAt:    now.Add(-3 * time.Hour),
+	}, {
+		ChatID:      123,
+		Fingerprint: "b",
+		Labels:      map[string]string{"alertname": "disk", "severity": "warning"},
+		StartsAt:    now.Add(-72 * time.Hour),
+	}, {
+		ChatID:      123,
+		Fingerprint: "b",
+		Labels:      map[string]string{"alertname": "disk", "severity": "warning"},
+		StartsAt:    now.Add(-20 * 24 * time.Hour),
+	}, {
+		ChatID:      123,
+		Fingerprint: "c",
+		Labels:      map[string]string{"alertname": "backup"},
+		StartsAt:    now.Add(-10 * 24 * time.Hour),
+	}, {
+		// Older than a month.
+		ChatID:      123,
+		Fingerprint: "d",
+		Labels:      map[string]string{"alertname": "old"},
+		StartsAt:    now.Add(-40 * 24 * time.Hour),
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "<b>Alerts sent to this chat in the last day / week / month</b>\nAll: 1 / 2 / 4\n\n" +
+			"<b>By severity:</b>\nwarning: 0 / 1 / 2\ncritical: 1 / 1 / 1\nnone: 0 / 0 / 1\n\n" +
+			"<b>Top alerts:</b>\ndisk: 0 / 1 / 2\nbackup: 0 / 0 / 1\nfire: 1 / 1 / 1",
+	}},
+	counter: map[string]uint{telegram.CommandMyStats: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/mystats",
+	},
+}, {
+	name: "MyStatsNoAlerts",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandMyStats,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "This chat wasn't sent any alerts in the last month! 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandMyStats: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/mystats",
+	},
+}}
+
+func TestResolveEventsOnce(t *testing.T) {
+	group := &telebot.Chat{ID: -1234, Type: telebot.ChatGroup, Title: "ops"}
+	chats := &telegramtest.ChatStore{}
+	require.NoError(t, chats.Add(chatFromUser(admin)))
+	require.NoError(t, chats.Add(group))
+	history, err := telegram.NewHistoryStore(newTestKV(), "telegram/history")
+	require.NoError(t, err)
+	tg, err := telegramtest.New()
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		resolved []string
+	)
+	bot, err := telegram.NewBotWithTelegram(chats, tg, admin.ID,
+		telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
+		telegram.WithHistory(history, 0),
+		telegram.WithResolveEvent(func(alertname string, d time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			resolved = append(resolved, alertname)
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks := make(chan alertmanager.TelegramWebhook, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.Run(ctx, webhooks)
+	}()
+	<-tg.Started()
+
+	m := webhookFlap("resolved")
+	webhooks <- alertmanager.TelegramWebhook{ChatID: int64(admin.ID), Message: m}
+	webhooks <- alertmanager.TelegramWebhook{ChatID: group.ID, Message: m}
+	require.NoError(t, waitIdle(ctx, bot))
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Len(t, tg.Replies(), 2)
+	require.Equal(t, []string{"flap"}, resolved)
+}
@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testStatuspage only knows the incident inc1 of the component api.
+type testStatuspage struct{}
+
+func (testStatuspage) CreateIncident(_ context.Context, name, body string, components []string) (string, error) {
+	if name != "The API is down" || body != "We're investigating the issue." || len(components) != 1 || components[0] != "api" {
+		return "", fmt.Errorf("unexpected incident %q %q %v", name, body, components)
+	}
+	return "inc1", nil
+}
+
+func (testStatuspage) UpdateIncident(_ context.Context, id, _ string) error {
+	if id != "inc1" {
+		return fmt.Errorf("unexpected incident %s", id)
+	}
+	return nil
+}
+
+func (testStatuspage) ResolveIncident(_ context.Context, id, _ string, components []string) error {
+	if id != "inc1" || len(components) != 1 || components[0] != "api" {
+		return fmt.Errorf("unexpected incident %s %v", id, components)
+	}
+	return nil
+}
+
+func withTestStatuspage(incidents ...*telegram.StatuspageIncident) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewStatuspageStore(newTestKV(), "telegram/statuspage")
+		if err != nil {
+			return err
+		}
+		for _, i := range incidents {
+			if err := s.Put(i); err != nil {
+				return err
+			}
+		}
+		return telegram.WithStatuspage(testStatuspage{}, s)(b)
+	}
+}
+
+func webhookStatuspage(status string) func() []alertmanager.TelegramWebhook {
+	return func() []alertmanager.TelegramWebhook {
+		alert := template.Alert{
+			Status:      status,
+			Labels:      template.KV{"alertname": "APIDown", telegram.StatuspageComponentLabel: "api"},
+			Annotations: template.KV{"summary": "The API is down"},
+			StartsAt:    time.Now().Add(-time.Hour),
+			Fingerprint: "5a6b7c8d",
+		}
+		if status == "resolved" {
+			alert.EndsAt = time.Now().Add(-2 * time.Minute)
+		}
+		return []alertmanager.TelegramWebhook{{
+			ChatID: int64(admin.ID),
+			Message: webhook.Message{Data: &template.Data{
+				Receiver: "telegram",
+				Status:   status,
+				Alerts:   template.Alerts{alert},
+			}},
+		}}
+	}
+}
+
+func statuspageMessage(sender *telebot.User, text string) telebot.Update {
+	return telebot.Update{Message: &telebot.Message{
+		Sender: sender,
+		Chat:   chatFromUser(sender),
+		Text:   text,
+	}}
+}
+
+var statuspageWorkflows = []workflow{{
+	name:     "StatuspageConfirm",
+	messages: []telebot.Update{statuspageMessage(admin, telegram.CommandStart)},
+	options:  []telegram.BotOption{withTestStatuspage()},
+	webhooks: webhookStatuspage("firing"),
+	updates: []telebot.Update{
+		statuspageMessage(admin, telegram.CommandStatuspage),
+		statuspageMessage(admin, telegram.CommandStatuspage+" confirm api"),
+		statuspageMessage(admin, telegram.CommandStatuspage+" confirm api"),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🚦 APIDown affects the Statuspage component api.\nCreate the public incident \"The API is down\" with /statuspage confirm api",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>APIDown</b> 🔥\n<b>Labels:</b>\n    statuspage_component: api\n<b>Annotations:</b>\n    summary: The API is down\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "Statuspage incidents:\napi: waiting for /statuspage confirm api, The API is down (APIDown)",
+	}, {
+		recipient: "123",
+		message:   "Created the Statuspage incident inc1 \"The API is down\" for api.",
+	}, {
+		recipient: "123",
+		message:   "The incident inc1 of api was confirmed by elliot already.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandStatuspage: 3},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=info msg=\"statuspage incident proposed\" component=api",
+		"level=debug msg=\"message received\" text=/statuspage",
+		"level=debug msg=\"message received\" text=\"/statuspage confirm api\"",
+		"level=info msg=\"statuspage incident created\" component=api id=inc1 username=elliot",
+		"level=debug msg=\"message received\" text=\"/statuspage confirm api\"",
+	},
+}, {
+	name:     "StatuspageResolve",
+	messages: []telebot.Update{statuspageMessage(admin, telegram.CommandStart)},
+	options: []telegram.BotOption{withTestStatuspage(&telegram.StatuspageIncident{
+		Component:   "api",
+		IncidentID:  "inc1",
+		Name:        "The API is down",
+		Body:        "We're investigating the issue.",
+		Alerts:      map[string]string{"5a6b7c8d": "APIDown"},
+		ProposedAt:  time.Now().Add(-time.Hour),
+		ConfirmedBy: "elliot",
+	})},
+	webhooks: webhookStatuspage("resolved"),
+	updates:  []telebot.Update{statuspageMessage(admin, telegram.CommandStatuspage)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "✅ <b>APIDown</b> ✅\n<b>Labels:</b>\n    statuspage_component: api\n<b>Annotations:</b>\n    summary: The API is down\n<b>Duration:</b> 58 minutes\n<b>Ended:</b> 2 minutes",
+	}, {
+		recipient: "123",
+		message:   "No alerts affect Statuspage components.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandStatuspage: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=info msg=\"statuspage incident resolved\" component=api id=inc1",
+		"level=debug msg=\"message received\" text=/statuspage",
+	},
+}, {
+	name: "StatuspageConfirmNotAdmin",
+	messages: []telebot.Update{
+		statuspageMessage(nobody, telegram.CommandStatuspage+" confirm api"),
+	},
+	options: []telegram.BotOption{withTestStatuspage(), telegram.WithAuthorizer(testAuthorizer{nobody.ID: true})},
+	replies: []reply{{
+		recipient: "222",
+		message:   "Only admins can confirm Statuspage incidents.",
+	}},
+	counter: map[string]uint{telegram.CommandStatuspage: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/statuspage confirm api\"",
+	},
+}}
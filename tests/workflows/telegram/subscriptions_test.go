@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+)
+
+var subscriptionWorkflows = []workflow{{
+	name: "SubscriptionDeclared",
+	options: []telegram.BotOption{
+		withTestFilters(),
+		telegram.WithSubscriptions([]telegram.Subscription{{
+			ChatID:   -1234,
+			Title:    "sre",
+			Matchers: []string{"severity=critical"},
+		}}, false),
+	},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    message: Something is on fire\n<b>Duration:</b> 1 hour",
+	}},
+	logs: []string{
+		"level=info msg=\"subscriptions reconciled\" subscribed=1 unsubscribed=0",
+	},
+	webhooks: webhookFilters,
+}, {
+	name: "SubscriptionFiltered",
+	options: []telegram.BotOption{
+		withTestFilters(),
+		telegram.WithSubscriptions([]telegram.Subscription{{
+			ChatID:   -1234,
+			Matchers: []string{"severity=warning"},
+		}}, true),
+	},
+	logs: []string{
+		"level=info msg=\"subscriptions reconciled\" subscribed=1 unsubscribed=0",
+	},
+	webhooks: webhookFilters,
+}}
@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// suppressedAlerts returns a NodeDown alert inhibiting a silenced KubePodNotReady alert.
+func suppressedAlerts(t *testing.T, r *http.Request) string {
+	nodeDown := model.LabelSet{"alertname": "NodeDown", "node": "node-3"}.Fingerprint().String()
+	return fmt.Sprintf(
+		`[{"labels":{"alertname":"NodeDown","node":"node-3"},"annotations":{},"startsAt":"%[1]s","status":{"state":"active","silencedBy":[],"inhibitedBy":[]}},`+
+			`{"labels":{"alertname":"KubePodNotReady","node":"node-3"},"annotations":{},"startsAt":"%[1]s","status":{"state":"suppressed","silencedBy":["34f5f82b"],"inhibitedBy":["%[2]s"]}}]`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339),
+		nodeDown,
+	)
+}
+
+var suppressionWorkflows = []workflow{{
+	name: "AlertsSuppressed",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandAlerts + " silenced",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour\n\n" +
+			"🔥 <b>KubePodNotReady</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n    inhibited_by: NodeDown\n    silenced_by: 34f5f82b\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandAlerts: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/alerts silenced\"",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "true", r.URL.Query().Get("silenced"))
+		return suppressedAlerts(t, r)
+	},
+	alertmanagerStatus: func(t *testing.T, r *http.Request) string {
+		return `{"config":{"original":"route:\n  receiver: admin\nreceivers:\n- name: admin\n  webhook_configs:\n  - send_resolved: true\n    url: http://localhost:8080/webhooks/telegram/123"}}`
+	},
+}, {
+	name:     "WebhookSuppressed",
+	messages: []telebot.Update{filterStart},
+	options:  []telegram.BotOption{telegram.WithSuppressionStatus()},
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>KubePodNotReady</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n    inhibited_by: NodeDown\n    silenced_by: 34f5f82b\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+	alertmanagerAlerts: func(t *testing.T, r *http.Request) string {
+		require.Equal(t, "telegram", r.URL.Query().Get("receiver"))
+		require.Equal(t, "true", r.URL.Query().Get("silenced"))
+		return suppressedAlerts(t, r)
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		labels := template.KV{"alertname": "KubePodNotReady", "node": "node-3"}
+		return []alertmanager.TelegramWebhook{{ChatID: -1234, Message: webhook.Message{
+			Data: &template.Data{
+				Receiver: "telegram",
+				Status:   "firing",
+				Alerts: template.Alerts{{
+					Status:      "firing",
+					Labels:      labels,
+					StartsAt:    time.Now().Add(-time.Hour),
+					Fingerprint: model.LabelSet{"alertname": "KubePodNotReady", "node": "node-3"}.Fingerprint().String(),
+				}},
+				GroupLabels:  template.KV{"alertname": "KubePodNotReady"},
+				CommonLabels: labels,
+			},
+			Version:  "4",
+			GroupKey: `{}:{alertname="KubePodNotReady"}`,
+		}}}
+	},
+}}
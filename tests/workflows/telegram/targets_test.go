@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const testTargets = `{"status":"success","data":{"activeTargets":[` +
+	`{"labels":{"instance":"localhost:9090","job":"prometheus"},"scrapeUrl":"http://localhost:9090/metrics","lastError":"","health":"up"},` +
+	`{"labels":{"instance":"node-1:9100","job":"node"},"scrapeUrl":"http://node-1:9100/metrics","lastError":"","health":"up"},` +
+	`{"labels":{"instance":"node-2:9100","job":"node"},"scrapeUrl":"http://node-2:9100/metrics","lastError":"connection refused","health":"down"}` +
+	`],"droppedTargets":[]}}`
+
+var targetsWorkflows = []workflow{{
+	name: "TargetsByJob",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandTargets,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>Targets by job (up/total):</b>\n❌ node: 1/2\n✅ prometheus: 1/1",
+	}},
+	counter: map[string]uint{telegram.CommandTargets: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/targets",
+	},
+	prometheusTargets: func(t *testing.T, r *http.Request) string {
+		return testTargets
+	},
+}, {
+	name: "TargetsDown",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandTargets + " down",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "<b>Down targets (1 of 3):</b>\n❌ node http://node-2:9100/metrics\n    connection refused",
+	}},
+	counter: map[string]uint{telegram.CommandTargets: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/targets down\"",
+	},
+	prometheusTargets: func(t *testing.T, r *http.Request) string {
+		return testTargets
+	},
+}, {
+	name: "TargetsAllUp",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandTargets + " down",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "All targets are up! 🎉",
+	}},
+	counter: map[string]uint{telegram.CommandTargets: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/targets down\"",
+	},
+}, {
+	name: "RulesUsage",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRules,
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: /rules <name>",
+	}},
+	counter: map[string]uint{telegram.CommandRules: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/rules",
+	},
+}, {
+	name: "RulesNotFound",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRules + " Fire",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message:   "No rule named Fire found.",
+	}},
+	counter: map[string]uint{telegram.CommandRules: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rules Fire\"",
+	},
+}, {
+	name: "RulesAlerting",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandRules + " Fire",
+		},
+	}},
+	replies: []reply{{
+		recipient: "123",
+		message: "🔔 <b>Fire</b> (example)\n<pre>vector(666) &gt; 1</pre>\n<b>For:</b> 5m0s\n" +
+			"<b>Health:</b> ok, <b>Active alerts:</b> 0\n" +
+			"<b>Labels:</b>\n    severity: critical\n" +
+			"<b>Annotations:</b>\n    message: Something is on fire",
+	}},
+	counter: map[string]uint{telegram.CommandRules: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=\"/rules Fire\"",
+	},
+	prometheusRules: func(t *testing.T, r *http.Request) string {
+		return `{"status":"success","data":{"groups":[{"name":"example","file":"example.yaml","interval":30,"rules":[` +
+			`{"name":"Fire","query":"vector(666) > 1","duration":300,"labels":{"severity":"critical"},` +
+			`"annotations":{"message":"Something is on fire"},"alerts":[],"health":"ok","lastError":"","type":"alerting"},` +
+			`{"record":"job:up:sum","query":"sum by(job) (up)","health":"ok","type":"recording"}]}]}}`
+	},
+}}
@@ -3,7 +3,6 @@ package telegram
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -13,7 +12,9 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/prometheus"
 	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
 	"github.com/prometheus/alertmanager/notify/webhook"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/stretchr/testify/require"
@@ -26,10 +27,29 @@ type workflow struct {
 	replies  []reply
 	logs     []string
 	counter  map[string]uint
-
-	webhooks           func() []alertmanager.TelegramWebhook
-	alertmanagerAlerts func(t *testing.T, r *http.Request) string
-	alertmanagerStatus func(t *testing.T, r *http.Request) string
+	alerts   []*telegram.ChatAlert
+	history  []*telegram.HistoryEntry
+	// deliveries are put into the delivery store before the bot runs.
+	deliveries []*telegram.Delivery
+	options    []telegram.BotOption
+	// unreachable is the number of sends that fail once the messages are handled, as if Telegram was down.
+	unreachable int32
+	// updates are sent after the webhooks, e.g. callbacks of buttons in alert messages.
+	updates []telebot.Update
+	// runFor is how long the bot runs before the replies are compared, so that timers like reminders fire.
+	runFor time.Duration
+	// chats are the chats as Telegram knows them when the bot looks them up.
+	chats []*telebot.Chat
+	// reactions are handled after the messages.
+	reactions []*telegram.Reaction
+
+	webhooks             func() []alertmanager.TelegramWebhook
+	alertmanagerAlerts   func(t *testing.T, r *http.Request) string
+	alertmanagerStatus   func(t *testing.T, r *http.Request) string
+	alertmanagerSilences func(t *testing.T, r *http.Request) string
+	prometheusQuery      func(t *testing.T, r *http.Request) string
+	prometheusTargets    func(t *testing.T, r *http.Request) string
+	prometheusRules      func(t *testing.T, r *http.Request) string
 }
 
 var (
@@ -114,41 +134,15 @@ func chatFromUser(user *telebot.User) *telebot.Chat {
 	}
 }
 
-type reply struct {
-	recipient, message string
-}
-
-type testStore struct {
-	// not thread safe - lol
-	chats map[int64]*telebot.Chat
-}
-
-func (t *testStore) List() ([]*telebot.Chat, error) {
-	chats := make([]*telebot.Chat, 0, len(t.chats))
-	for _, chat := range t.chats {
-		chats = append(chats, chat)
-	}
-	return chats, nil
-}
-
-func (t *testStore) Get(id telebot.ChatID) (*telebot.Chat, error) {
-	chat, ok := t.chats[int64(id)]
-	if !ok {
-		return nil, telegram.ChatNotFoundErr
-	}
-	return chat, nil
+// waitIdle waits until the bot handled the updates and webhooks sent to it.
+func waitIdle(ctx context.Context, bot *telegram.Bot) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return bot.WaitIdle(ctx)
 }
 
-func (t *testStore) Add(c *telebot.Chat) error {
-	if t.chats == nil {
-		t.chats = make(map[int64]*telebot.Chat)
-	}
-	t.chats[c.ID] = c
-	return nil
-}
-
-func (t *testStore) Remove(_ *telebot.Chat) error {
-	return nil
+type reply struct {
+	recipient, message string
 }
 
 type testCommandCounter struct {
@@ -159,57 +153,15 @@ func (c *testCommandCounter) Count(command string) {
 	c.counter[command]++
 }
 
-// wraps telebot to intercept sent messages.
-type testTelegram struct {
-	bot     *telebot.Bot
-	replies []reply
-}
-
-func (t *testTelegram) Start() {
-	t.bot.Start()
-}
-
-func (t *testTelegram) Stop() {
-	t.bot.Stop()
-}
-
-func (t *testTelegram) Send(to telebot.Recipient, message interface{}, _ ...interface{}) (*telebot.Message, error) {
-	text, ok := message.(string)
-	if !ok {
-		return nil, fmt.Errorf("message is not a string")
-	}
-	t.replies = append(t.replies, reply{recipient: to.Recipient(), message: text})
-	return nil, nil
-}
-
-func (t *testTelegram) Notify(_ telebot.Recipient, _ telebot.ChatAction) error {
-	return nil // nop
-}
-
-func (t *testTelegram) Handle(endpoint interface{}, handler interface{}) {
-	t.bot.Handle(endpoint, handler)
-}
-
-type testPoller struct {
-	updates chan telebot.Update
-	done    chan struct{}
-}
-
-func (t *testPoller) Poll(_ *telebot.Bot, updates chan telebot.Update, stop chan struct{}) {
-	for {
-		select {
-		case upd := <-t.updates:
-			updates <- upd
-		case <-stop:
-			return
-		}
-	}
-}
-
 func TestWorkflows(t *testing.T) {
 	var testAlertmanagerAlerts func(t *testing.T, r *http.Request) string
 	var testAlertmanagerStatus func(t *testing.T, r *http.Request) string
+	var testAlertmanagerSilences func(t *testing.T, r *http.Request) string
+	var testPrometheusQuery func(t *testing.T, r *http.Request) string
+	var testPrometheusTargets func(t *testing.T, r *http.Request) string
+	var testPrometheusRules func(t *testing.T, r *http.Request) string
 	var am *alertmanager.Client
+	var pm *prometheus.Client
 	{
 		m := http.NewServeMux()
 		m.HandleFunc("/api/v2/alerts", func(w http.ResponseWriter, r *http.Request) {
@@ -228,6 +180,39 @@ func TestWorkflows(t *testing.T) {
 			w.Header().Set("Content-Type", "application/json")
 			_, _ = w.Write([]byte(data))
 		})
+		m.HandleFunc("/api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+			data := "[]"
+			if testAlertmanagerSilences != nil {
+				data = testAlertmanagerSilences(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
+
+		m.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+			data := `{"status":"success","data":{"resultType":"vector","result":[]}}`
+			if testPrometheusQuery != nil {
+				data = testPrometheusQuery(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
+		m.HandleFunc("/api/v1/targets", func(w http.ResponseWriter, r *http.Request) {
+			data := `{"status":"success","data":{"activeTargets":[],"droppedTargets":[]}}`
+			if testPrometheusTargets != nil {
+				data = testPrometheusTargets(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
+		m.HandleFunc("/api/v1/rules", func(w http.ResponseWriter, r *http.Request) {
+			data := `{"status":"success","data":{"groups":[]}}`
+			if testPrometheusRules != nil {
+				data = testPrometheusRules(t, r)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(data))
+		})
 
 		server := httptest.NewServer(m)
 		defer server.Close()
@@ -236,6 +221,8 @@ func TestWorkflows(t *testing.T) {
 		require.NoError(t, err)
 		am, err = alertmanager.NewClient(amURL)
 		require.NoError(t, err)
+		pm, err = prometheus.NewClient(amURL)
+		require.NoError(t, err)
 	}
 
 	workflows = append(workflows, alertsWorkflows...)
@@ -246,51 +233,157 @@ func TestWorkflows(t *testing.T) {
 	workflows = append(workflows, stopWorkflows...)
 	workflows = append(workflows, statusWorkflows...)
 	workflows = append(workflows, webhookWorkflows...)
+	workflows = append(workflows, ackWorkflows...)
+	workflows = append(workflows, flappingWorkflows...)
+	workflows = append(workflows, statsWorkflows...)
+	workflows = append(workflows, myStatsWorkflows...)
+	workflows = append(workflows, incidentWorkflows...)
+	workflows = append(workflows, queryWorkflows...)
+	workflows = append(workflows, targetsWorkflows...)
+	workflows = append(workflows, labelsWorkflows...)
+	workflows = append(workflows, detailsWorkflows...)
+	workflows = append(workflows, groupsWorkflows...)
+	workflows = append(workflows, groupCommandWorkflows...)
+	workflows = append(workflows, replayWorkflows...)
+	workflows = append(workflows, lastWebhookWorkflows...)
+	workflows = append(workflows, deliveryWorkflows...)
+	workflows = append(workflows, outageWorkflows...)
+	workflows = append(workflows, workersWorkflows...)
+	workflows = append(workflows, muteWorkflows...)
+	workflows = append(workflows, approvalWorkflows...)
+	workflows = append(workflows, inviteWorkflows...)
+	workflows = append(workflows, banWorkflows...)
+	workflows = append(workflows, authzWorkflows...)
+	workflows = append(workflows, permissionsWorkflows...)
+	workflows = append(workflows, deepLinkWorkflows...)
+	workflows = append(workflows, filterWorkflows...)
+	workflows = append(workflows, subscriptionWorkflows...)
+	workflows = append(workflows, watchdogWorkflows...)
+	workflows = append(workflows, deadMansSwitchWorkflows...)
+	workflows = append(workflows, selfMonitoringWorkflows...)
+	workflows = append(workflows, remindersWorkflows...)
+	workflows = append(workflows, correlationWorkflows...)
+	workflows = append(workflows, suppressionWorkflows...)
+	workflows = append(workflows, routesWorkflows...)
+	workflows = append(workflows, aliasesWorkflows...)
+	workflows = append(workflows, editsWorkflows...)
+	workflows = append(workflows, repliesWorkflows...)
+	workflows = append(workflows, inlineWorkflows...)
+	workflows = append(workflows, findWorkflows...)
+	workflows = append(workflows, templatesWorkflows...)
+	workflows = append(workflows, escapeWorkflows...)
+	workflows = append(workflows, previewsWorkflows...)
+	workflows = append(workflows, broadcastWorkflows...)
+	workflows = append(workflows, reconcileWorkflows...)
+	workflows = append(workflows, idempotencyWorkflows...)
+	workflows = append(workflows, enrichmentWorkflows...)
+	workflows = append(workflows, redactionWorkflows...)
+	workflows = append(workflows, peerDedupWorkflows...)
+	workflows = append(workflows, clustersWorkflows...)
+	workflows = append(workflows, shadowWorkflows...)
+	workflows = append(workflows, canaryWorkflows...)
+	workflows = append(workflows, configVersionsWorkflows...)
+	workflows = append(workflows, purgeWorkflows...)
+	workflows = append(workflows, maintenanceWorkflows...)
+	workflows = append(workflows, ticketsWorkflows...)
+	workflows = append(workflows, statuspageWorkflows...)
+	workflows = append(workflows, outputsWorkflows...)
+	workflows = append(workflows, voiceWorkflows...)
+	workflows = append(workflows, locationsWorkflows...)
+	workflows = append(workflows, qrWorkflows...)
+	workflows = append(workflows, chatModesWorkflows...)
+	workflows = append(workflows, archiveWorkflows...)
+	workflows = append(workflows, membershipWorkflows...)
+	workflows = append(workflows, migrationsWorkflows...)
+	workflows = append(workflows, refreshWorkflows...)
+	workflows = append(workflows, orderWorkflows...)
+	workflows = append(workflows, reactionsWorkflows...)
+	workflows = append(workflows, handledWorkflows...)
+	workflows = append(workflows, shardsWorkflows...)
+	workflows = append(workflows, digestWorkflows...)
 
 	for _, w := range workflows {
 		t.Run(w.name, func(t *testing.T) {
 			testAlertmanagerAlerts = w.alertmanagerAlerts
 			testAlertmanagerStatus = w.alertmanagerStatus
+			testAlertmanagerSilences = w.alertmanagerSilences
+			testPrometheusQuery = w.prometheusQuery
+			testPrometheusTargets = w.prometheusTargets
+			testPrometheusRules = w.prometheusRules
 
 			ctx, cancel := context.WithCancel(context.Background())
 			logs := &bytes.Buffer{}
 
-			poller := &testPoller{
-				updates: make(chan telebot.Update, 2),
-				done:    make(chan struct{}, 1),
-			}
-			tb, err := telebot.NewBot(telebot.Settings{
-				Offline: true,
-				Poller:  poller,
-			})
+			tg, err := telegramtest.New()
 			require.NoError(t, err)
-
-			testStore := &testStore{}
-			testTelegram := &testTelegram{bot: tb}
+			for _, chat := range w.chats {
+				tg.SetChat(chat)
+			}
 			counter := testCommandCounter{counter: map[string]uint{}}
 
-			bot, err := telegram.NewBotWithTelegram(testStore, testTelegram, admin.ID,
+			alertStore, err := telegram.NewAlertStore(newTestKV(), "telegram/alerts")
+			require.NoError(t, err)
+			for _, a := range w.alerts {
+				require.NoError(t, alertStore.Put(a))
+			}
+			historyStore, err := telegram.NewHistoryStore(newTestKV(), "telegram/history")
+			require.NoError(t, err)
+			for _, e := range w.history {
+				require.NoError(t, historyStore.Put(e))
+			}
+			incidentStore, err := telegram.NewIncidentStore(newTestKV(), "telegram/incidents")
+			require.NoError(t, err)
+			groupStore, err := telegram.NewChatGroupStore(newTestKV(), "telegram/groups")
+			require.NoError(t, err)
+			deliveryStore, err := telegram.NewDeliveryStore(newTestKV(), "telegram/deliveries")
+			require.NoError(t, err)
+			for _, d := range w.deliveries {
+				require.NoError(t, deliveryStore.Put(d))
+			}
+
+			opts := append([]telegram.BotOption{
 				telegram.WithLogger(log.NewLogfmtLogger(logs)),
+				telegram.WithAlertStore(alertStore),
+				telegram.WithHistory(historyStore, 0),
+				telegram.WithIncidents(incidentStore),
+				telegram.WithChatGroupStore(groupStore),
+				telegram.WithDeliveries(deliveryStore, 0),
 				telegram.WithCommandEvent(counter.Count),
 				telegram.WithAlertmanager(am),
+				telegram.WithPrometheus(pm),
 				telegram.WithTemplates(&url.URL{Host: "localhost"}, "../../../default.tmpl"),
 				telegram.WithStartTime(time.Now().Add(-time.Minute)),
 				telegram.WithRevision("bot"),
-			)
+			}, w.options...)
+
+			bot, err := telegram.NewBotWithTelegram(&telegramtest.ChatStore{}, tg, admin.ID, opts...)
 			require.NoError(t, err)
 
 			webhooks := make(chan alertmanager.TelegramWebhook, 10)
 
 			// Run the bot in the background and tests in foreground.
+			done := make(chan error, 1)
 			go func(ctx context.Context) {
-				require.NoError(t, bot.Run(ctx, webhooks))
+				done <- bot.Run(ctx, webhooks)
 			}(ctx)
+			select {
+			case <-tg.Started():
+			case err := <-done:
+				require.NoError(t, err)
+				t.Fatal("the bot stopped before it started")
+			}
 
 			for i, update := range w.messages {
 				update.ID = i
 				update.Message.ID = i
-				poller.updates <- update
-				time.Sleep(time.Millisecond)
+				tg.Process(update)
+			}
+			for _, r := range w.reactions {
+				tg.React(r)
+			}
+
+			if w.unreachable > 0 {
+				tg.SetUnreachable(w.unreachable)
 			}
 
 			if w.webhooks != nil {
@@ -299,13 +392,28 @@ func TestWorkflows(t *testing.T) {
 				}
 			}
 
-			// TODO: Don't sleep but block somehow different
-			time.Sleep(100 * time.Millisecond)
+			for i, update := range w.updates {
+				// The webhooks are processed in the background, e.g. the alert messages of callbacks have to be sent before.
+				require.NoError(t, waitIdle(ctx, bot))
+				update.ID = len(w.messages) + i
+				if update.Message != nil {
+					update.Message.ID = update.ID
+				}
+				tg.Process(update)
+			}
+
+			time.Sleep(w.runFor)
+			require.NoError(t, waitIdle(ctx, bot))
 
-			require.Len(t, testTelegram.replies, len(w.replies))
+			// Stop the bot before looking at the replies and logs, so that it doesn't write them anymore.
+			cancel()
+			require.NoError(t, <-done)
+
+			replies := tg.Replies()
+			require.Len(t, replies, len(w.replies))
 			for i, reply := range w.replies {
-				require.Equal(t, reply.recipient, testTelegram.replies[i].recipient)
-				require.Equal(t, reply.message, strings.TrimSpace(testTelegram.replies[i].message))
+				require.Equal(t, reply.recipient, replies[i].Recipient)
+				require.Equal(t, reply.message, strings.TrimSpace(replies[i].Message))
 			}
 
 			logLines := strings.Split(strings.TrimSpace(logs.String()), "\n")
@@ -319,8 +427,6 @@ func TestWorkflows(t *testing.T) {
 			for command, count := range counter.counter {
 				require.Equal(t, w.counter[command], count)
 			}
-
-			cancel()
 		})
 	}
 }
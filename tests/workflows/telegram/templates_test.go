@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var templateOverrides = []telegram.BotOption{telegram.WithTemplateOverrides([]telegram.TemplateOverride{{
+	Matchers: []string{`alertname="KubePodCrashLooping"`},
+	Template: "telegram.compact",
+}})}
+
+// webhookAlert returns a webhook with a single firing alert for the chat -1234.
+func webhookAlert(labels, annotations template.KV) func() []alertmanager.TelegramWebhook {
+	return func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{ChatID: -1234, Message: webhook.Message{
+			Data: &template.Data{
+				Receiver: "telegram",
+				Status:   "firing",
+				Alerts: template.Alerts{{
+					Status:      "firing",
+					Labels:      labels,
+					Annotations: annotations,
+					StartsAt:    time.Now().Add(-time.Hour),
+				}},
+				GroupLabels:  template.KV{"alertname": labels["alertname"]},
+				CommonLabels: labels,
+			},
+			Version:  "4",
+			GroupKey: `{}:{alertname="` + labels["alertname"] + `"}`,
+		}}}
+	}
+}
+
+var templatesWorkflows = []workflow{{
+	name:     "TemplateOverride",
+	messages: []telebot.Update{filterStart},
+	options:  templateOverrides,
+	webhooks: webhookAlert(
+		template.KV{"alertname": "KubePodCrashLooping", "namespace": "shop", "pod": "api-7d9f"},
+		template.KV{"summary": "Pod is restarting"},
+	),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>KubePodCrashLooping</b> in shop: Pod is restarting",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}, {
+	name:     "TemplateOverrideFallback",
+	messages: []telebot.Update{filterStart},
+	options:  templateOverrides,
+	webhooks: webhookAlert(template.KV{"alertname": "NodeDown", "node": "node-3"}, template.KV{}),
+	replies: []reply{{
+		recipient: "-1234",
+		message:   "Hey! I will now keep you all up to date!\n/help",
+	}, {
+		recipient: "-1234",
+		message:   "🔥 <b>NodeDown</b> 🔥\n<b>Labels:</b>\n    node: node-3\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=-1234",
+	},
+}}
@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testTracker opens the ticket at url if the title and body are the expected ones.
+type testTracker struct {
+	title string
+	body  string
+	url   string
+}
+
+func (t testTracker) CreateIssue(_ context.Context, title, body string) (string, error) {
+	if title != t.title {
+		return "", fmt.Errorf("unexpected title %q", title)
+	}
+	if !strings.Contains(body, t.body) {
+		return "", fmt.Errorf("unexpected body %q", body)
+	}
+	return t.url, nil
+}
+
+func ticketReply(replyTo int) telebot.Update {
+	return telebot.Update{Message: &telebot.Message{
+		Sender:  admin,
+		Chat:    chatFromUser(admin),
+		Text:    telegram.CommandTicket,
+		ReplyTo: &telebot.Message{ID: replyTo, Text: "🔥 fire 🔥"},
+	}}
+}
+
+var ticketsWorkflows = []workflow{{
+	name:     "TicketReply",
+	messages: []telebot.Update{ticketReply(42), ticketReply(42)},
+	alerts:   repliedAlerts,
+	history: []*telegram.HistoryEntry{{
+		ChatID:      repliedAlerts[0].ChatID,
+		Fingerprint: repliedAlerts[0].Fingerprint,
+		Labels:      repliedAlerts[0].Labels,
+		StartsAt:    repliedAlerts[0].StartsAt,
+	}},
+	options: []telegram.BotOption{telegram.WithTickets(testTracker{
+		title: "fire (instance=node-1)",
+		body:  "🔥 fire 🔥\n\nLabels:\nalertname=fire\ninstance=node-1",
+		url:   "https://jira.example.com/browse/OPS-42",
+	})},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Created the ticket https://jira.example.com/browse/OPS-42 for fire.",
+	}, {
+		recipient: "123",
+		message:   "fire already has the ticket https://jira.example.com/browse/OPS-42.",
+	}},
+	counter: map[string]uint{telegram.CommandTicket: 2},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ticket",
+		"level=info msg=\"ticket created\" alertname=fire url=https://jira.example.com/browse/OPS-42 username=elliot",
+		"level=debug msg=\"message received\" text=/ticket",
+	},
+}, {
+	name: "TicketWithoutReply",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandTicket,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithTickets(testTracker{})},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Usage: reply to an alert with /ticket.",
+	}},
+	counter: map[string]uint{telegram.CommandTicket: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ticket",
+	},
+}, {
+	name:     "TicketNotEnabled",
+	messages: []telebot.Update{ticketReply(42)},
+	alerts:   repliedAlerts,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Opening tickets for alerts isn't enabled.",
+	}},
+	counter: map[string]uint{telegram.CommandTicket: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/ticket",
+	},
+}, {
+	name: "TicketButton",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{telegram.WithTickets(testTracker{
+		title: "SlowQuery",
+		body:  "Labels:\nalertname=SlowQuery\n\nStarted at ",
+		url:   "https://github.com/example/infrastructure/issues/7",
+	})},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>SlowQuery</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n    description: SELECT 1\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "Created the ticket https://github.com/example/infrastructure/issues/7 for SlowQuery.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=info msg=\"ticket created\" alertname=SlowQuery url=https://github.com/example/infrastructure/issues/7 username=elliot",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{{
+			ChatID:  int64(admin.ID),
+			Message: webhookLongAnnotation("SELECT 1"),
+		}}
+	},
+	updates: []telebot.Update{{Callback: &telebot.Callback{
+		ID:      "1",
+		Sender:  admin,
+		Message: &telebot.Message{Chat: chatFromUser(admin), Text: "🔥 SlowQuery 🔥"},
+		Data:    "\fticket|4a5b6c7d8e9f0a1b",
+	}}},
+}}
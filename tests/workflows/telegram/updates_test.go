@@ -0,0 +1,118 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram/telegramtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetStore(t *testing.T) {
+	offsets, err := telegram.NewOffsetStore(newTestKV(), "telegram/updates")
+	require.NoError(t, err)
+
+	updateID, err := offsets.Get()
+	require.NoError(t, err)
+	require.Equal(t, 0, updateID)
+
+	require.NoError(t, offsets.Put(4242))
+	updateID, err = offsets.Get()
+	require.NoError(t, err)
+	require.Equal(t, 4242, updateID)
+}
+
+// offsetEvents records the IDs of the updates put in the store and the updates processed in order.
+type offsetEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *offsetEvents) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *offsetEvents) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+func (e *offsetEvents) Get() (int, error) {
+	return 0, nil
+}
+
+func (e *offsetEvents) Put(updateID int) error {
+	e.add(fmt.Sprintf("offset %d", updateID))
+	return nil
+}
+
+func TestUpdateOffsets(t *testing.T) {
+	var polled int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":42,"is_bot":true,"first_name":"Alertmanager","username":"alertmanager_bot"}}`))
+		case "getUpdates":
+			if atomic.AddInt32(&polled, 1) > 1 {
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":[
+				{"update_id":10,"message":{"message_id":1,"from":{"id":123,"first_name":"Elliot"},"chat":{"id":-2,"type":"group"},"text":"/id"}},
+				{"update_id":11,"message":{"message_id":2,"from":{"id":123,"first_name":"Elliot"},"chat":{"id":123,"type":"private"},"text":"/id"}}
+			]}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":3,"chat":{"id":123,"type":"private"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	events := &offsetEvents{}
+	// The update of the group is handled last, though it was received first.
+	release := make(chan struct{})
+	bot, err := telegram.NewBotWithURL(&telegramtest.ChatStore{}, server.URL, "t0ken", admin.ID,
+		telegram.WithPolling(0, 10*time.Millisecond),
+		telegram.WithCommandWorkers(2),
+		telegram.WithUpdateOffsets(events),
+		telegram.WithProcessedEvent(func(p telegram.Processed) {
+			if p.ChatID == -2 {
+				select {
+				case <-release:
+				case <-time.After(5 * time.Second):
+				}
+			}
+			events.add(fmt.Sprintf("processed %d", p.ChatID))
+			if p.ChatID == 123 {
+				close(release)
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		_ = bot.Run(ctx, make(chan alertmanager.TelegramWebhook))
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	// The ID of the last update is only put once the updates before it are handled too.
+	require.Eventually(t, func() bool { return len(events.list()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"processed 123", "processed -2", "offset 11"}, events.list())
+}
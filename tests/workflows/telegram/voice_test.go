@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// testSpeaker returns the text as audio.
+type testSpeaker struct{}
+
+func (testSpeaker) Synthesize(_ context.Context, text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+// brokenSpeaker always fails.
+type brokenSpeaker struct{}
+
+func (brokenSpeaker) Synthesize(context.Context, string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func withTestVoice(speaker telegram.Speaker) telegram.BotOption {
+	return func(b *telegram.Bot) error {
+		s, err := telegram.NewVoiceStore(newTestKV(), "telegram/voice")
+		if err != nil {
+			return err
+		}
+		return telegram.WithVoice(speaker, s, []string{"critical"})(b)
+	}
+}
+
+var voiceWorkflows = []workflow{{
+	name: "VoiceOn",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandVoice + " on",
+		},
+	}},
+	options:  []telegram.BotOption{withTestVoice(testSpeaker{})},
+	webhooks: webhookRunbook,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Voice messages are now on for this chat.",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    runbook: https://example.com/runbook?a=1&amp;b=2\n<b>Duration:</b> 1 hour",
+	}, {
+		recipient: "123",
+		message:   "voice:Alert fire is firing with severity critical.",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandVoice: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/voice on\"",
+		"level=info msg=\"voice messages changed\" chat_id=123 on=true",
+	},
+}, {
+	name: "VoiceOff",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandVoice,
+		},
+	}},
+	options:  []telegram.BotOption{withTestVoice(testSpeaker{})},
+	webhooks: webhookRunbook,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Voice messages are off for this chat.",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    runbook: https://example.com/runbook?a=1&amp;b=2\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandVoice: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=/voice",
+	},
+}, {
+	name: "VoiceSpeakerFails",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandVoice + " on",
+		},
+	}},
+	options:  []telegram.BotOption{withTestVoice(brokenSpeaker{})},
+	webhooks: webhookRunbook,
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Voice messages are now on for this chat.",
+	}, {
+		recipient: "123",
+		message:   "🔥 <b>fire</b> 🔥\n<b>Labels:</b>\n    severity: critical\n<b>Annotations:</b>\n    runbook: https://example.com/runbook?a=1&amp;b=2\n<b>Duration:</b> 1 hour",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1, telegram.CommandVoice: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=\"/voice on\"",
+		"level=info msg=\"voice messages changed\" chat_id=123 on=true",
+		"level=warn msg=\"failed to synthesize voice message\" chat_id=123 err=\"connection refused\"",
+	},
+}}
@@ -0,0 +1,47 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+)
+
+func webhookWatchdog() []alertmanager.TelegramWebhook {
+	return []alertmanager.TelegramWebhook{{ChatID: -1234, Message: webhook.Message{
+		Data: &template.Data{
+			Receiver: "telegram",
+			Status:   "firing",
+			Alerts: template.Alerts{{
+				Status:   "firing",
+				Labels:   template.KV{"alertname": "Watchdog", "severity": "none"},
+				StartsAt: time.Now().Add(-time.Hour),
+			}},
+			GroupLabels:  template.KV{"alertname": "Watchdog"},
+			CommonLabels: template.KV{"alertname": "Watchdog", "severity": "none"},
+		},
+		Version:  "4",
+		GroupKey: `{}:{alertname="Watchdog"}`,
+	}}}
+}
+
+var watchdogWorkflows = []workflow{{
+	name:    "WatchdogMissing",
+	runFor:  100 * time.Millisecond,
+	options: []telegram.BotOption{telegram.WithWatchdog("Watchdog", 20*time.Millisecond)},
+	replies: []reply{{
+		recipient: "123",
+		message:   "⚠️ The Watchdog alert hasn't been received for less than a minute, the alerting pipeline might be broken.",
+	}},
+	logs: []string{
+		"level=warn msg=\"watchdog alert missing\" alertname=Watchdog",
+	},
+}, {
+	name:     "WatchdogReceived",
+	runFor:   100 * time.Millisecond,
+	options:  []telegram.BotOption{telegram.WithWatchdog("Watchdog", time.Hour)},
+	logs:     []string{""},
+	webhooks: webhookWatchdog,
+}}
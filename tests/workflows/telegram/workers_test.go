@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	flapFiring   = "🔥 <b>flap</b> 🔥\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 1 hour"
+	flapResolved = "✅ <b>flap</b> ✅\n<b>Labels:</b>\n<b>Annotations:</b>\n<b>Duration:</b> 58 minutes\n<b>Ended:</b> 2 minutes"
+)
+
+var workersWorkflows = []workflow{{
+	name: "SendWorkersOrdering",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithSendWorkers(4),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   flapFiring,
+	}, {
+		recipient: "123",
+		message:   flapResolved,
+	}, {
+		recipient: "123",
+		message:   flapFiring,
+	}, {
+		recipient: "123",
+		message:   flapResolved,
+	}},
+	counter: map[string]uint{telegram.CommandStart: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+	webhooks: func() []alertmanager.TelegramWebhook {
+		return []alertmanager.TelegramWebhook{
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("resolved")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("firing")},
+			{ChatID: int64(admin.ID), Message: webhookFlap("resolved")},
+		}
+	},
+}, {
+	name: "CommandWorkersOrdering",
+	messages: []telebot.Update{{
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStop,
+		},
+	}, {
+		Message: &telebot.Message{
+			Sender: admin,
+			Chat:   chatFromUser(admin),
+			Text:   telegram.CommandStart,
+		},
+	}},
+	options: []telegram.BotOption{
+		telegram.WithCommandWorkers(4),
+	},
+	replies: []reply{{
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}, {
+		recipient: "123",
+		message:   "Alright, Elliot! I won't talk to you again.\n/help",
+	}, {
+		recipient: "123",
+		message:   "Hey, Elliot! I will now keep you up to date!\n/help",
+	}},
+	counter: map[string]uint{telegram.CommandStart: 2, telegram.CommandStop: 1},
+	logs: []string{
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+		"level=debug msg=\"message received\" text=/stop",
+		"level=info msg=\"user unsubscribed\" username=elliot user_id=123",
+		"level=debug msg=\"message received\" text=/start",
+		"level=info msg=\"user subscribed\" username=elliot user_id=123 chat_id=123",
+	},
+}}